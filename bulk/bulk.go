@@ -0,0 +1,470 @@
+// Package bulk validates lists of email addresses in the background. A
+// Runner accepts a list as a Job, runs each address through a sequence of
+// checks, such as syntax, MX, and disposable-domain checks, on a bounded
+// pool of workers, and tracks the progress of every item. Job.Status and
+// Job.Items report progress for polling, and Job.Next lets streaming
+// consumers receive item results as they complete.
+package bulk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/idgen"
+	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+)
+
+// Default Runner limits.
+const (
+	// DefaultWorkers is the default number of addresses a job checks
+	// concurrently.
+	DefaultWorkers = 8
+
+	// DefaultMaxItems is the default maximum number of addresses in a job.
+	DefaultMaxItems = 10000
+
+	// DefaultCheckTimeout is the default time allowed for each check of an
+	// address.
+	DefaultCheckTimeout = 10 * time.Second
+
+	// DefaultRetention is the default time a finished job stays available.
+	DefaultRetention = time.Hour
+)
+
+var (
+	// ErrNoAddresses is returned by Submit for an empty list.
+	ErrNoAddresses = errors.New("no addresses to validate")
+
+	// ErrTooManyAddresses is returned by Submit for a list longer than the
+	// Runner accepts.
+	ErrTooManyAddresses = errors.New("too many addresses")
+
+	// ErrJobNotFound is returned by Runner.Job for an unknown or expired
+	// job.
+	ErrJobNotFound = errors.New("bulk job not found")
+)
+
+// State is the state of a job.
+type State string
+
+// Job states.
+const (
+	StateRunning  State = "RUNNING"
+	StateDone     State = "DONE"
+	StateCanceled State = "CANCELED"
+)
+
+// ItemStatus is the outcome of checking one address.
+type ItemStatus string
+
+// Item outcomes.
+const (
+	// ItemPending is an address not checked yet.
+	ItemPending ItemStatus = "PENDING"
+	// ItemValid is an address that passed every check.
+	ItemValid ItemStatus = "VALID"
+	// ItemInvalid is an address a check rejected.
+	ItemInvalid ItemStatus = "INVALID"
+	// ItemError is an address a check could not be completed for.
+	ItemError ItemStatus = "ERROR"
+)
+
+// Item is the result of checking one address of a job.
+type Item struct {
+	// Index is the position of the address in the submitted list.
+	Index   int        `json:"index"`
+	Address string     `json:"address"`
+	Status  ItemStatus `json:"status"`
+
+	// Check names the check that rejected the address or failed, and
+	// Detail describes why.
+	Check  string `json:"check,omitempty"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Status summarizes the progress of a job.
+type Status struct {
+	ID    string `json:"id"`
+	State State  `json:"state"`
+
+	Total     int `json:"total"`
+	Completed int `json:"completed"`
+	Valid     int `json:"valid"`
+	Invalid   int `json:"invalid"`
+	Errors    int `json:"errors"`
+
+	CreatedAt  time.Time `json:"created_at"`
+	FinishedAt time.Time `json:"finished_at,omitzero"`
+}
+
+// Job is a list of addresses being checked. Its methods are safe for
+// concurrent use.
+type Job struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu     sync.Mutex
+	status Status
+	items  []Item
+
+	// completed holds item indexes in completion order, for Next.
+	completed []int
+
+	// changed is closed and replaced whenever an item completes or the
+	// job finishes.
+	changed chan struct{}
+}
+
+// ID returns the job's ID.
+func (j *Job) ID() string {
+	return j.status.ID
+}
+
+// Status returns the current progress of the job.
+func (j *Job) Status() Status {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.status
+}
+
+// Items returns the results of all items so far, in the order of the
+// submitted list. Items not checked yet are ItemPending.
+func (j *Job) Items() []Item {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	items := make([]Item, len(j.items))
+	copy(items, j.items)
+
+	return items
+}
+
+// Next returns the items completed after the first cursor items, in
+// completion order, and the cursor to pass to the next call. Start with a
+// cursor of zero. Next blocks until an item completes, and returns io.EOF
+// once the job has finished and every completed item has been returned.
+func (j *Job) Next(ctx context.Context, cursor int) ([]Item, int, error) {
+	for {
+		j.mu.Lock()
+		if cursor < len(j.completed) {
+			items := make([]Item, 0, len(j.completed)-cursor)
+			for _, i := range j.completed[cursor:] {
+				items = append(items, j.items[i])
+			}
+			j.mu.Unlock()
+			return items, cursor + len(items), nil
+		}
+		finished := j.status.State != StateRunning
+		changed := j.changed
+		j.mu.Unlock()
+
+		if finished {
+			return nil, cursor, io.EOF
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil, cursor, fmt.Errorf("context error: %w", ctx.Err())
+		}
+	}
+}
+
+// Done returns a channel closed when the job finishes.
+func (j *Job) Done() <-chan struct{} {
+	return j.done
+}
+
+// Cancel stops the job. Items not checked yet stay ItemPending.
+func (j *Job) Cancel() {
+	j.cancel()
+}
+
+// complete records the result of an item.
+func (j *Job) complete(item Item) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.items[item.Index] = item
+	j.completed = append(j.completed, item.Index)
+	j.status.Completed++
+	switch item.Status {
+	case ItemValid:
+		j.status.Valid++
+	case ItemInvalid:
+		j.status.Invalid++
+	case ItemError:
+		j.status.Errors++
+	}
+
+	close(j.changed)
+	j.changed = make(chan struct{})
+}
+
+// finish records that the job ended in state at the given time.
+func (j *Job) finish(state State, at time.Time) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.status.State = state
+	j.status.FinishedAt = at
+
+	close(j.changed)
+	j.changed = make(chan struct{})
+	close(j.done)
+}
+
+// Runner runs bulk jobs and keeps them for lookup until their retention
+// passes.
+type Runner struct {
+	checks       []Check
+	workers      int
+	maxItems     int
+	checkTimeout time.Duration
+	retention    time.Duration
+	logger       *slog.Logger
+	clock        token.Clock
+	ids          *idgen.Generator
+
+	mu   sync.Mutex
+	jobs map[string]*Job
+}
+
+// Option is a functional option for configuring Runner.
+type Option func(*Runner)
+
+// WithWorkers sets how many addresses a job checks concurrently.
+func WithWorkers(n int) Option {
+	return func(r *Runner) {
+		if n > 0 {
+			r.workers = n
+		}
+	}
+}
+
+// WithMaxItems sets the maximum number of addresses in a job.
+func WithMaxItems(n int) Option {
+	return func(r *Runner) {
+		if n > 0 {
+			r.maxItems = n
+		}
+	}
+}
+
+// WithCheckTimeout sets the time allowed for each check of an address.
+func WithCheckTimeout(d time.Duration) Option {
+	return func(r *Runner) {
+		if d > 0 {
+			r.checkTimeout = d
+		}
+	}
+}
+
+// WithRetention sets how long a finished job stays available from Job.
+func WithRetention(d time.Duration) Option {
+	return func(r *Runner) {
+		if d > 0 {
+			r.retention = d
+		}
+	}
+}
+
+// WithLogger sets a custom logger for Runner.
+func WithLogger(logger *slog.Logger) Option {
+	return func(r *Runner) {
+		r.logger = logger
+	}
+}
+
+// WithClock sets the clock used for job timestamps and retention.
+func WithClock(clock token.Clock) Option {
+	return func(r *Runner) {
+		r.clock = clock
+	}
+}
+
+// New creates a Runner checking every address with checks, in order. An
+// address is valid if it passes them all; checking stops at the first check
+// that rejects it or fails.
+func New(checks []Check, opts ...Option) *Runner {
+	r := &Runner{
+		checks:       checks,
+		workers:      DefaultWorkers,
+		maxItems:     DefaultMaxItems,
+		checkTimeout: DefaultCheckTimeout,
+		retention:    DefaultRetention,
+		logger:       slog.Default(),
+		clock:        token.SystemClock,
+		jobs:         make(map[string]*Job),
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	r.ids = idgen.New(idgen.WithPrefix("bulk_"), idgen.WithClock(r.clock))
+
+	return r
+}
+
+// Submit starts a job checking addresses and returns it without waiting for
+// it. The job runs until it finishes or is canceled, independently of ctx.
+func (r *Runner) Submit(ctx context.Context, addresses []string) (*Job, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("context error: %w", err)
+	}
+
+	if len(addresses) == 0 {
+		return nil, ErrNoAddresses
+	}
+	if len(addresses) > r.maxItems {
+		return nil, fmt.Errorf("%w: %d, at most %d", ErrTooManyAddresses, len(addresses), r.maxItems)
+	}
+
+	id, err := r.ids.New()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate job ID: %w", err)
+	}
+
+	jobCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	job := &Job{
+		cancel: cancel,
+		done:   make(chan struct{}),
+		status: Status{
+			ID:        id,
+			State:     StateRunning,
+			Total:     len(addresses),
+			CreatedAt: r.clock.Now(),
+		},
+		items:   make([]Item, len(addresses)),
+		changed: make(chan struct{}),
+	}
+	for i, address := range addresses {
+		job.items[i] = Item{Index: i, Address: strings.TrimSpace(address), Status: ItemPending}
+	}
+
+	r.mu.Lock()
+	r.prune()
+	r.jobs[id] = job
+	r.mu.Unlock()
+
+	r.logger.Info("bulk job started",
+		"job_id", id,
+		"total", len(addresses))
+
+	go r.run(jobCtx, job)
+
+	return job, nil
+}
+
+// Job returns the job with the given ID, or ErrJobNotFound.
+func (r *Runner) Job(id string) (*Job, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.prune()
+
+	job, ok := r.jobs[id]
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+
+	return job, nil
+}
+
+// prune forgets jobs whose retention has passed. r.mu must be held.
+func (r *Runner) prune() {
+	cutoff := r.clock.Now().Add(-r.retention)
+	for id, job := range r.jobs {
+		status := job.Status()
+		if status.State != StateRunning && status.FinishedAt.Before(cutoff) {
+			delete(r.jobs, id)
+		}
+	}
+}
+
+// run checks the items of job on the worker pool until all are done or ctx
+// is canceled.
+func (r *Runner) run(ctx context.Context, job *Job) {
+	defer job.cancel()
+
+	indexes := make(chan int)
+	go func() {
+		defer close(indexes)
+		for i := range job.items {
+			select {
+			case indexes <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for range min(r.workers, len(job.items)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				item, ok := r.check(ctx, job.items[i])
+				if !ok {
+					return
+				}
+				job.complete(item)
+			}
+		}()
+	}
+	wg.Wait()
+
+	state := StateDone
+	if job.Status().Completed < len(job.items) {
+		state = StateCanceled
+	}
+	job.finish(state, r.clock.Now())
+
+	status := job.Status()
+	r.logger.Info("bulk job finished",
+		"job_id", status.ID,
+		"state", string(status.State),
+		"completed", status.Completed,
+		"valid", status.Valid,
+		"invalid", status.Invalid,
+		"errors", status.Errors)
+}
+
+// check runs the checks on an item. It reports false if ctx was canceled
+// before the item was checked.
+func (r *Runner) check(ctx context.Context, item Item) (Item, bool) {
+	for _, c := range r.checks {
+		checkCtx, cancel := context.WithTimeout(ctx, r.checkTimeout)
+		err := c.Run(checkCtx, item.Address)
+		cancel()
+
+		if err == nil {
+			continue
+		}
+		if ctx.Err() != nil {
+			return item, false
+		}
+
+		item.Status = ItemError
+		if errors.Is(err, ErrRejected) {
+			item.Status = ItemInvalid
+		}
+		item.Check = c.Name
+		item.Detail = err.Error()
+
+		return item, true
+	}
+
+	item.Status = ItemValid
+
+	return item, true
+}
@@ -0,0 +1,233 @@
+package bulk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/netip"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/check/disposable"
+	"github.com/jaeyeom/email-validator-grpc-mcp/check/dns"
+)
+
+// fakeClock is a settable clock.
+type fakeClock struct {
+	now atomic.Int64
+}
+
+func (c *fakeClock) Now() time.Time {
+	return time.Unix(0, c.now.Load())
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now.Add(int64(d))
+}
+
+// fakeResolver serves MX records from a map. Domains missing from it do
+// not exist.
+type fakeResolver map[string][]*net.MX
+
+func (r fakeResolver) LookupMX(_ context.Context, name string) ([]*net.MX, error) {
+	records, ok := r[name]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+	if records == nil {
+		return nil, &net.DNSError{Err: "server misbehaving", Name: name, IsTemporary: true}
+	}
+
+	return records, nil
+}
+
+func (r fakeResolver) LookupNetIP(_ context.Context, _, host string) ([]netip.Addr, error) {
+	return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
+
+var resolver = dns.New(dns.WithResolver(fakeResolver{
+	"example.com":    {{Host: "mx.example.com.", Pref: 10}},
+	"nomail.example": {{Host: ".", Pref: 0}},
+	"broken.example": nil,
+}))
+
+var disposables = disposable.New(disposable.WithoutBaseline(), disposable.WithDomains("mailinator.com"))
+
+func TestChecks(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		check   Check
+		address string
+		want    error
+	}{
+		{Syntax(), "user@example.com", nil},
+		{Syntax(), "not an address", ErrRejected},
+		{Syntax(), "User <user@example.com>", ErrRejected},
+		{MX(resolver), "user@EXAMPLE.com", nil},
+		{MX(resolver), "user@missing.example", ErrRejected},
+		{MX(resolver), "user@nomail.example", ErrRejected},
+		{Disposable(disposables), "user@example.com", nil},
+		{Disposable(disposables), "user@Mailinator.com", ErrRejected},
+		{Disposable(disposables), "user@eu.mailinator.com", ErrRejected},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.check.Name+"/"+tt.address, func(t *testing.T) {
+			t.Parallel()
+
+			if err := tt.check.Run(context.Background(), tt.address); !errors.Is(err, tt.want) || (tt.want == nil) != (err == nil) {
+				t.Errorf("Run(%q) error = %v, want %v", tt.address, err, tt.want)
+			}
+		})
+	}
+
+	// Lookup failures are not rejections.
+	if err := MX(resolver).Run(context.Background(), "user@broken.example"); err == nil || errors.Is(err, ErrRejected) {
+		t.Errorf("Run() with failing lookup error = %v, want a non-rejection error", err)
+	}
+}
+
+func newRunner(opts ...Option) *Runner {
+	checks := []Check{Syntax(), Disposable(disposables), MX(resolver)}
+
+	return New(checks, append([]Option{WithLogger(slog.New(slog.DiscardHandler))}, opts...)...)
+}
+
+func TestRunner_Submit(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	r := newRunner(WithWorkers(3))
+
+	addresses := []string{
+		"a@example.com",
+		"bad address",
+		" b@example.com ",
+		"c@mailinator.com",
+		"d@broken.example",
+		"e@missing.example",
+	}
+	job, err := r.Submit(ctx, addresses)
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+
+	// Stream items as they complete.
+	var streamed []int
+	for cursor := 0; ; {
+		var items []Item
+		items, cursor, err = job.Next(ctx, cursor)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next() error = %v", err)
+		}
+		for _, item := range items {
+			streamed = append(streamed, item.Index)
+		}
+	}
+	sort.Ints(streamed)
+	if fmt.Sprint(streamed) != "[0 1 2 3 4 5]" {
+		t.Errorf("Next() streamed items %v, want each item once", streamed)
+	}
+
+	status := job.Status()
+	if status.State != StateDone || status.Total != 6 || status.Completed != 6 ||
+		status.Valid != 2 || status.Invalid != 3 || status.Errors != 1 {
+		t.Errorf("Status() = %+v, want 2 valid, 3 invalid, 1 error", status)
+	}
+
+	want := []struct {
+		status ItemStatus
+		check  string
+	}{
+		{ItemValid, ""},
+		{ItemInvalid, "syntax"},
+		{ItemValid, ""},
+		{ItemInvalid, "disposable"},
+		{ItemError, "mx"},
+		{ItemInvalid, "mx"},
+	}
+	for i, item := range job.Items() {
+		if item.Status != want[i].status || item.Check != want[i].check {
+			t.Errorf("Items()[%d] = %+v, want %s by %q", i, item, want[i].status, want[i].check)
+		}
+	}
+	if got := job.Items()[2].Address; got != "b@example.com" {
+		t.Errorf("Items()[2].Address = %q, want it trimmed", got)
+	}
+
+	if got, err := r.Job(job.ID()); err != nil || got != job {
+		t.Errorf("Job() = %v, %v, want the submitted job", got, err)
+	}
+}
+
+func TestRunner_Cancel(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	started := make(chan struct{}, 1)
+	block := Check{
+		Name: "block",
+		Run: func(ctx context.Context, _ string) error {
+			started <- struct{}{}
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	}
+	r := New([]Check{block}, WithWorkers(1), WithLogger(slog.New(slog.DiscardHandler)))
+
+	job, err := r.Submit(ctx, []string{"a@example.com", "b@example.com"})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	<-started
+	job.Cancel()
+	<-job.Done()
+
+	status := job.Status()
+	if status.State != StateCanceled || status.Completed != 0 {
+		t.Errorf("Status() = %+v, want canceled with nothing completed", status)
+	}
+	for _, item := range job.Items() {
+		if item.Status != ItemPending {
+			t.Errorf("item %d status = %s, want %s", item.Index, item.Status, ItemPending)
+		}
+	}
+	if _, _, err := job.Next(ctx, 0); !errors.Is(err, io.EOF) {
+		t.Errorf("Next() of canceled job error = %v, want %v", err, io.EOF)
+	}
+}
+
+func TestRunner_Limits(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	clock := &fakeClock{}
+	r := newRunner(WithMaxItems(2), WithRetention(time.Minute), WithClock(clock))
+
+	if _, err := r.Submit(ctx, nil); !errors.Is(err, ErrNoAddresses) {
+		t.Errorf("Submit(nil) error = %v, want %v", err, ErrNoAddresses)
+	}
+	if _, err := r.Submit(ctx, []string{"a", "b", "c"}); !errors.Is(err, ErrTooManyAddresses) {
+		t.Errorf("Submit() of 3 addresses error = %v, want %v", err, ErrTooManyAddresses)
+	}
+
+	job, err := r.Submit(ctx, []string{"a@example.com"})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	<-job.Done()
+
+	clock.Advance(2 * time.Minute)
+	if _, err := r.Job(job.ID()); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Job() after retention error = %v, want %v", err, ErrJobNotFound)
+	}
+}
@@ -0,0 +1,123 @@
+package bulk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/check/disposable"
+	"github.com/jaeyeom/email-validator-grpc-mcp/check/dns"
+	"github.com/jaeyeom/email-validator-grpc-mcp/check/smtpprobe"
+	"github.com/jaeyeom/email-validator-grpc-mcp/check/syntax"
+)
+
+// ErrRejected is wrapped by errors of checks that ran and found the address
+// undeliverable, as opposed to checks that could not run.
+var ErrRejected = errors.New("address rejected")
+
+// Check is a single test of an address.
+type Check struct {
+	// Name identifies the check in item results.
+	Name string
+
+	// Run tests the address. It returns nil if the address passes, an error
+	// wrapping ErrRejected if it fails, and any other error if the check
+	// could not be completed.
+	Run func(ctx context.Context, address string) error
+}
+
+// Syntax rejects addresses that syntax.Check finds issues with.
+func Syntax() Check {
+	return Check{
+		Name: "syntax",
+		Run: func(_ context.Context, address string) error {
+			if err := syntax.Check(address).Err(); err != nil {
+				return fmt.Errorf("%w: %w", ErrRejected, err)
+			}
+
+			return nil
+		},
+	}
+}
+
+// MX rejects addresses whose domain has no mail server: it does not exist,
+// has neither MX nor address records, or publishes a null MX record (RFC
+// 7505) declaring that it accepts no mail. Lookup failures are errors, not
+// rejections.
+func MX(checker *dns.Checker) Check {
+	return Check{
+		Name: "mx",
+		Run: func(ctx context.Context, address string) error {
+			res, err := checker.CheckAddress(ctx, address)
+			switch {
+			case err != nil:
+				return fmt.Errorf("mx lookup failed: %w", err)
+			case res.NullMX:
+				return fmt.Errorf("%w: domain %s accepts no mail", ErrRejected, res.Domain)
+			case !res.HasMailServer:
+				return fmt.Errorf("%w: domain %s has no mail server", ErrRejected, res.Domain)
+			}
+
+			return nil
+		},
+	}
+}
+
+// Mailbox rejects addresses whose mail server reports that the mailbox does
+// not exist. Probes that decide nothing pass. Since probing is slow and
+// some providers penalize it, Mailbox is not among the DefaultChecks.
+func Mailbox(prober *smtpprobe.Prober) Check {
+	return Check{
+		Name: "mailbox",
+		Run: func(ctx context.Context, address string) error {
+			res, err := prober.Probe(ctx, address)
+			if err != nil {
+				return fmt.Errorf("mailbox probe failed: %w", err)
+			}
+			if res.Verdict == smtpprobe.VerdictUndeliverable {
+				return fmt.Errorf("%w: mailbox does not exist: %s", ErrRejected, res.Message)
+			}
+
+			return nil
+		},
+	}
+}
+
+// Disposable rejects addresses at the disposable domains of list or their
+// subdomains.
+func Disposable(list *disposable.List) Check {
+	return Check{
+		Name: "disposable",
+		Run: func(_ context.Context, address string) error {
+			if match, ok := list.Contains(Domain(address)); ok {
+				return fmt.Errorf("%w: %s is a disposable domain", ErrRejected, match)
+			}
+
+			return nil
+		},
+	}
+}
+
+// DefaultChecks returns the syntax, disposable-domain, and MX checks, with
+// disposable domains of the baseline list and mail servers looked up by a
+// dns.Checker using net.DefaultResolver. The local checks come first,
+// so addresses they reject cost no DNS lookup.
+func DefaultChecks() []Check {
+	return []Check{
+		Syntax(),
+		Disposable(disposable.New()),
+		MX(dns.New()),
+	}
+}
+
+// Domain returns the lower-cased domain of an address, or "" if it has
+// none.
+func Domain(address string) string {
+	i := strings.LastIndexByte(address, '@')
+	if i < 0 {
+		return ""
+	}
+
+	return strings.ToLower(address[i+1:])
+}
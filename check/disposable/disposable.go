@@ -0,0 +1,330 @@
+// Package disposable detects addresses at disposable email domains, the
+// throwaway inboxes that let anyone receive a verification message without
+// owning a real mailbox. A List starts from a baseline list embedded in the
+// binary and merges the domains of any number of Providers, such as a list
+// published at a URL, refreshing them periodically with RunRefresh.
+package disposable
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/emailaddr"
+)
+
+// DefaultMaxListSize is the default maximum size, in bytes, of a list
+// fetched by a URLProvider.
+const DefaultMaxListSize = 16 << 20
+
+// ErrListTooLarge is returned by URLProvider when the list exceeds its
+// maximum size.
+var ErrListTooLarge = errors.New("disposable domain list too large")
+
+//go:embed domains.txt
+var baseline string
+
+// Provider supplies disposable domains.
+type Provider interface {
+	// Domains returns the current list of disposable domains.
+	Domains(ctx context.Context) ([]string, error)
+}
+
+// ProviderFunc adapts a function to the Provider interface.
+type ProviderFunc func(ctx context.Context) ([]string, error)
+
+// Domains calls f.
+func (f ProviderFunc) Domains(ctx context.Context) ([]string, error) {
+	return f(ctx)
+}
+
+// Baseline returns the domains of the embedded baseline list.
+func Baseline() []string {
+	domains, _ := Parse(strings.NewReader(baseline))
+	return domains
+}
+
+// Parse reads a domain list: one domain per line, with blank lines and
+// lines starting with "#" ignored. Domains are lower-cased.
+func Parse(r io.Reader) ([]string, error) {
+	var domains []string
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		domains = append(domains, strings.TrimSuffix(strings.ToLower(line), "."))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read domain list: %w", err)
+	}
+
+	return domains, nil
+}
+
+// URLProvider fetches a domain list in the format of Parse over HTTP.
+type URLProvider struct {
+	url     string
+	client  *http.Client
+	maxSize int64
+}
+
+// NewURLProvider creates a URLProvider fetching url with client, or with
+// http.DefaultClient if client is nil.
+func NewURLProvider(url string, client *http.Client) *URLProvider {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &URLProvider{url: url, client: client, maxSize: DefaultMaxListSize}
+}
+
+// Domains fetches and parses the list.
+func (p *URLProvider) Domains(ctx context.Context) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch domain list: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch domain list: %s", resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, p.maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read domain list: %w", err)
+	}
+	if int64(len(body)) > p.maxSize {
+		return nil, fmt.Errorf("%w: more than %d bytes", ErrListTooLarge, p.maxSize)
+	}
+
+	return Parse(bytes.NewReader(body))
+}
+
+// Result is the outcome of checking an address.
+type Result struct {
+	// Domain is the ASCII domain checked.
+	Domain string `json:"domain"`
+
+	Disposable bool `json:"disposable"`
+
+	// Match is the listed domain that Domain is or is a subdomain of.
+	Match string `json:"match,omitempty"`
+}
+
+// List is a set of disposable domains. It is safe for concurrent use.
+type List struct {
+	providers []Provider
+	baseline  bool
+	fixed     []string
+	allowed   map[string]struct{}
+	logger    *slog.Logger
+
+	mu sync.RWMutex
+	// provided holds the last domains each provider returned successfully.
+	provided [][]string
+	domains  map[string]struct{}
+}
+
+// Option is a functional option for configuring List.
+type Option func(*List)
+
+// WithProviders adds providers whose domains are loaded by Refresh.
+func WithProviders(providers ...Provider) Option {
+	return func(l *List) {
+		l.providers = append(l.providers, providers...)
+	}
+}
+
+// WithDomains adds fixed disposable domains.
+func WithDomains(domains ...string) Option {
+	return func(l *List) {
+		l.fixed = append(l.fixed, domains...)
+	}
+}
+
+// WithoutBaseline leaves out the embedded baseline list.
+func WithoutBaseline() Option {
+	return func(l *List) {
+		l.baseline = false
+	}
+}
+
+// WithAllowed exempts domains, and their subdomains, that a provider lists
+// by mistake.
+func WithAllowed(domains ...string) Option {
+	return func(l *List) {
+		for _, d := range domains {
+			l.allowed[strings.ToLower(d)] = struct{}{}
+		}
+	}
+}
+
+// WithLogger sets a custom logger for List.
+func WithLogger(logger *slog.Logger) Option {
+	return func(l *List) {
+		l.logger = logger
+	}
+}
+
+// New creates a List holding the baseline and fixed domains. The domains of
+// providers are added by Refresh.
+func New(opts ...Option) *List {
+	l := &List{
+		baseline: true,
+		allowed:  make(map[string]struct{}),
+		logger:   slog.Default(),
+	}
+
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	if l.baseline {
+		l.fixed = append(Baseline(), l.fixed...)
+	}
+	l.provided = make([][]string, len(l.providers))
+	l.rebuild()
+
+	return l
+}
+
+// rebuild recomputes the domain set. l.mu must be held for writing, or l
+// not yet shared.
+func (l *List) rebuild() {
+	domains := make(map[string]struct{}, len(l.fixed))
+	for _, d := range l.fixed {
+		domains[strings.ToLower(d)] = struct{}{}
+	}
+	for _, list := range l.provided {
+		for _, d := range list {
+			domains[d] = struct{}{}
+		}
+	}
+
+	l.domains = domains
+}
+
+// Refresh reloads the domains of every provider. A provider that fails
+// keeps its previous domains; the errors of all failed providers are
+// returned together.
+func (l *List) Refresh(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("context error: %w", err)
+	}
+
+	provided := make([][]string, len(l.providers))
+	failed := make([]bool, len(l.providers))
+	var errs []error
+	for i, p := range l.providers {
+		domains, err := p.Domains(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("provider %d: %w", i, err))
+			failed[i] = true
+			continue
+		}
+		provided[i] = make([]string, len(domains))
+		for j, d := range domains {
+			provided[i][j] = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(d)), ".")
+		}
+	}
+
+	l.mu.Lock()
+	for i, domains := range provided {
+		if !failed[i] {
+			l.provided[i] = domains
+		}
+	}
+	l.rebuild()
+	size := len(l.domains)
+	l.mu.Unlock()
+
+	l.logger.Debug("disposable domain list refreshed",
+		"domains", size,
+		"failed_providers", len(errs))
+
+	return errors.Join(errs...)
+}
+
+// RunRefresh runs Refresh every interval until ctx is done, and returns
+// the context's error. A failed refresh is logged and retried at the next
+// interval.
+func (l *List) RunRefresh(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("context error: %w", ctx.Err())
+		case <-ticker.C:
+			if err := l.Refresh(ctx); err != nil && ctx.Err() == nil {
+				l.logger.Error("disposable domain list refresh failed", "error", err)
+			}
+		}
+	}
+}
+
+// Len returns the number of listed domains.
+func (l *List) Len() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return len(l.domains)
+}
+
+// Contains reports whether domain or one of its parent domains is listed,
+// and returns the listed domain.
+func (l *List) Contains(domain string) (string, bool) {
+	domain = strings.TrimSuffix(strings.ToLower(domain), ".")
+
+	for d := domain; d != ""; {
+		if _, ok := l.allowed[d]; ok {
+			return "", false
+		}
+		_, d, _ = strings.Cut(d, ".")
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	for d := domain; d != ""; {
+		if _, ok := l.domains[d]; ok {
+			return d, true
+		}
+		_, d, _ = strings.Cut(d, ".")
+	}
+
+	return "", false
+}
+
+// Check checks the domain of an email address, converting an
+// internationalized domain to ASCII first.
+func (l *List) Check(addr string) (*Result, error) {
+	normalized, err := emailaddr.New().Normalize(addr)
+	if err != nil {
+		return nil, fmt.Errorf("cannot check address: %w", err)
+	}
+
+	res := &Result{Domain: normalized[strings.LastIndexByte(normalized, '@')+1:]}
+	res.Match, res.Disposable = l.Contains(res.Domain)
+
+	return res, nil
+}
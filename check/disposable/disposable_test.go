@@ -0,0 +1,146 @@
+package disposable
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestBaseline(t *testing.T) {
+	t.Parallel()
+
+	domains := Baseline()
+	if len(domains) < 100 {
+		t.Errorf("Baseline() has %d domains, want at least 100", len(domains))
+	}
+	if !slices.IsSorted(domains) {
+		t.Error("Baseline() is not sorted")
+	}
+	if slices.Contains(domains, "gmail.com") {
+		t.Error("Baseline() lists gmail.com")
+	}
+}
+
+func TestList_Check(t *testing.T) {
+	t.Parallel()
+
+	l := New(WithDomains("Throwaway.Example"), WithAllowed("good.mailinator.com"))
+
+	tests := []struct {
+		addr  string
+		match string
+	}{
+		{"user@example.com", ""},
+		{"user@gmail.com", ""},
+		{"user@mailinator.com", "mailinator.com"},
+		{"User@MAILINATOR.COM", "mailinator.com"},
+		{"user@eu.mailinator.com", "mailinator.com"},
+		{"user@good.mailinator.com", ""},
+		{"user@throwaway.example", "throwaway.example"},
+		{"user@notmailinator.com.example", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := l.Check(tt.addr)
+			if err != nil {
+				t.Fatalf("Check() error = %v", err)
+			}
+			if got.Disposable != (tt.match != "") || got.Match != tt.match {
+				t.Errorf("Check(%q) = %+v, want match %q", tt.addr, got, tt.match)
+			}
+		})
+	}
+
+	if _, err := l.Check("not an address"); err == nil {
+		t.Error("Check() of a non-address succeeded")
+	}
+}
+
+func TestList_Refresh(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	var fail bool
+	lists := [][]string{{"First.Example"}, {"second.example"}}
+	provider := ProviderFunc(func(context.Context) ([]string, error) {
+		if fail {
+			return nil, errors.New("unavailable")
+		}
+		domains := lists[0]
+		lists = lists[1:]
+		return domains, nil
+	})
+	l := New(WithoutBaseline(), WithProviders(provider), WithLogger(slog.New(slog.DiscardHandler)))
+
+	if l.Len() != 0 {
+		t.Fatalf("Len() before refresh = %d, want 0", l.Len())
+	}
+
+	if err := l.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if _, ok := l.Contains("first.example"); !ok {
+		t.Error("Contains() after refresh = false, want true")
+	}
+
+	// A failed refresh keeps the previous domains.
+	fail = true
+	if err := l.Refresh(ctx); err == nil {
+		t.Error("Refresh() with failing provider succeeded")
+	}
+	if _, ok := l.Contains("first.example"); !ok {
+		t.Error("Contains() after failed refresh = false, want true")
+	}
+
+	// A successful refresh replaces them.
+	fail = false
+	if err := l.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	_, first := l.Contains("first.example")
+	_, second := l.Contains("second.example")
+	if first || !second || l.Len() != 1 {
+		t.Errorf("after second refresh, first %v, second %v, Len() %d; want only second", first, second, l.Len())
+	}
+}
+
+func TestURLProvider(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/list.txt":
+			fmt.Fprint(w, "# comment\nlisted.example\n\n  Other.Example.  \n")
+		case "/large.txt":
+			fmt.Fprint(w, strings.Repeat("a.example\n", 10))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	ctx := context.Background()
+	got, err := NewURLProvider(srv.URL+"/list.txt", srv.Client()).Domains(ctx)
+	if err != nil || !slices.Equal(got, []string{"listed.example", "other.example"}) {
+		t.Errorf("Domains() = %v, %v", got, err)
+	}
+
+	if _, err := NewURLProvider(srv.URL+"/missing.txt", srv.Client()).Domains(ctx); err == nil {
+		t.Error("Domains() of a missing list succeeded")
+	}
+
+	p := NewURLProvider(srv.URL+"/large.txt", srv.Client())
+	p.maxSize = 50
+	if _, err := p.Domains(ctx); !errors.Is(err, ErrListTooLarge) {
+		t.Errorf("Domains() of a large list error = %v, want %v", err, ErrListTooLarge)
+	}
+}
@@ -0,0 +1,289 @@
+// Package dns checks whether the domain of an email address can receive
+// mail. A domain can if it publishes MX records other than a null MX (RFC
+// 7505), or, lacking MX records, has an address record that serves as an
+// implicit MX (RFC 5321 section 5.1). Results are cached, and concurrent
+// checks of one domain share a single lookup.
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/emailaddr"
+	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+)
+
+// Default cache settings.
+const (
+	// DefaultTTL is how long a domain found to have a mail server is
+	// cached.
+	DefaultTTL = time.Hour
+
+	// DefaultNegativeTTL is how long a domain found to have no mail server
+	// is cached. It is shorter than DefaultTTL so that newly configured
+	// domains are picked up soon.
+	DefaultNegativeTTL = 5 * time.Minute
+
+	// DefaultCacheSize is the default maximum number of cached domains.
+	DefaultCacheSize = 10000
+)
+
+// Resolver looks up DNS records. *net.Resolver implements it.
+type Resolver interface {
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+	LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error)
+}
+
+// Result describes the mail servers of a domain.
+type Result struct {
+	// Domain is the ASCII domain checked.
+	Domain string `json:"domain"`
+
+	// HasMailServer reports whether the domain can receive mail.
+	HasMailServer bool `json:"has_mail_server"`
+
+	// MailServers are the hosts mail is delivered to, in preference order:
+	// the MX hosts, or the domain itself for an implicit MX.
+	MailServers []string `json:"mail_servers,omitempty"`
+
+	// ImplicitMX reports that the domain has no MX records and receives
+	// mail at its own address.
+	ImplicitMX bool `json:"implicit_mx,omitempty"`
+
+	// NullMX reports that the domain declares that it accepts no mail.
+	NullMX bool `json:"null_mx,omitempty"`
+}
+
+// clone returns a copy of r that shares no memory with it.
+func (r *Result) clone() *Result {
+	c := *r
+	c.MailServers = slices.Clone(r.MailServers)
+
+	return &c
+}
+
+// entry is a cached result.
+type entry struct {
+	result  *Result
+	expires time.Time
+}
+
+// call is a lookup in flight.
+type call struct {
+	done   chan struct{}
+	result *Result
+	err    error
+}
+
+// Checker checks domains for mail servers. It is safe for concurrent use.
+type Checker struct {
+	resolver    Resolver
+	clock       token.Clock
+	ttl         time.Duration
+	negativeTTL time.Duration
+	cacheSize   int
+
+	mu       sync.Mutex
+	cache    map[string]entry
+	inflight map[string]*call
+}
+
+// Option is a functional option for configuring Checker.
+type Option func(*Checker)
+
+// WithResolver sets the resolver. The default is net.DefaultResolver.
+func WithResolver(resolver Resolver) Option {
+	return func(c *Checker) {
+		c.resolver = resolver
+	}
+}
+
+// WithClock sets the clock cache entries expire by.
+func WithClock(clock token.Clock) Option {
+	return func(c *Checker) {
+		c.clock = clock
+	}
+}
+
+// WithTTL sets how long results are cached: ttl for domains with a mail
+// server and negativeTTL for domains without one. Zero disables caching of
+// that kind of result.
+func WithTTL(ttl, negativeTTL time.Duration) Option {
+	return func(c *Checker) {
+		c.ttl = max(ttl, 0)
+		c.negativeTTL = max(negativeTTL, 0)
+	}
+}
+
+// WithCacheSize sets the maximum number of cached domains.
+func WithCacheSize(size int) Option {
+	return func(c *Checker) {
+		if size > 0 {
+			c.cacheSize = size
+		}
+	}
+}
+
+// New creates a Checker.
+func New(opts ...Option) *Checker {
+	c := &Checker{
+		resolver:    net.DefaultResolver,
+		clock:       token.SystemClock,
+		ttl:         DefaultTTL,
+		negativeTTL: DefaultNegativeTTL,
+		cacheSize:   DefaultCacheSize,
+		cache:       make(map[string]entry),
+		inflight:    make(map[string]*call),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// CheckAddress checks the domain of an email address, converting an
+// internationalized domain to ASCII first.
+func (c *Checker) CheckAddress(ctx context.Context, addr string) (*Result, error) {
+	normalized, err := emailaddr.New().Normalize(addr)
+	if err != nil {
+		return nil, fmt.Errorf("cannot check address: %w", err)
+	}
+
+	return c.Check(ctx, normalized[strings.LastIndexByte(normalized, '@')+1:])
+}
+
+// Check checks an ASCII domain. A domain that does not exist has no mail
+// server; an error is returned only when the lookup itself fails, and such
+// failures are not cached.
+func (c *Checker) Check(ctx context.Context, domain string) (*Result, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("context error: %w", err)
+	}
+
+	domain = strings.TrimSuffix(strings.ToLower(domain), ".")
+
+	c.mu.Lock()
+	if e, ok := c.cache[domain]; ok && c.clock.Now().Before(e.expires) {
+		c.mu.Unlock()
+		return e.result.clone(), nil
+	}
+	if cl, ok := c.inflight[domain]; ok {
+		c.mu.Unlock()
+		select {
+		case <-cl.done:
+		case <-ctx.Done():
+			return nil, fmt.Errorf("context error: %w", ctx.Err())
+		}
+		if errors.Is(cl.err, context.Canceled) || errors.Is(cl.err, context.DeadlineExceeded) {
+			// The caller that made the lookup gave up; make our own.
+			return c.Check(ctx, domain)
+		}
+		if cl.err != nil {
+			return nil, cl.err
+		}
+		return cl.result.clone(), nil
+	}
+	cl := &call{done: make(chan struct{})}
+	c.inflight[domain] = cl
+	c.mu.Unlock()
+
+	cl.result, cl.err = c.lookup(ctx, domain)
+
+	c.mu.Lock()
+	delete(c.inflight, domain)
+	if cl.err == nil {
+		c.store(domain, cl.result)
+	}
+	c.mu.Unlock()
+	close(cl.done)
+
+	if cl.err != nil {
+		return nil, cl.err
+	}
+
+	return cl.result.clone(), nil
+}
+
+// store caches a result. c.mu must be held.
+func (c *Checker) store(domain string, result *Result) {
+	ttl := c.ttl
+	if !result.HasMailServer {
+		ttl = c.negativeTTL
+	}
+	if ttl == 0 {
+		return
+	}
+
+	now := c.clock.Now()
+	if len(c.cache) >= c.cacheSize {
+		for d, e := range c.cache {
+			if !now.Before(e.expires) {
+				delete(c.cache, d)
+			}
+		}
+	}
+	if len(c.cache) >= c.cacheSize {
+		// Evict an arbitrary entry; map iteration order is random.
+		for d := range c.cache {
+			delete(c.cache, d)
+			break
+		}
+	}
+
+	c.cache[domain] = entry{result: result, expires: now.Add(ttl)}
+}
+
+// lookup resolves the mail servers of a domain.
+func (c *Checker) lookup(ctx context.Context, domain string) (*Result, error) {
+	res := &Result{Domain: domain}
+
+	records, err := c.resolver.LookupMX(ctx, domain)
+	switch {
+	case isNotFound(err):
+		// No MX records, or no domain; the address lookup tells which.
+	case err != nil:
+		return nil, fmt.Errorf("mx lookup failed: %w", err)
+	case len(records) == 1 && (records[0].Host == "." || records[0].Host == ""):
+		res.NullMX = true
+		return res, nil
+	case len(records) > 0:
+		records = slices.Clone(records)
+		slices.SortStableFunc(records, func(a, b *net.MX) int {
+			return int(a.Pref) - int(b.Pref)
+		})
+		for _, mx := range records {
+			res.MailServers = append(res.MailServers, strings.TrimSuffix(mx.Host, "."))
+		}
+		res.HasMailServer = true
+		return res, nil
+	}
+
+	addrs, err := c.resolver.LookupNetIP(ctx, "ip", domain)
+	switch {
+	case isNotFound(err):
+		return res, nil
+	case err != nil:
+		return nil, fmt.Errorf("address lookup failed: %w", err)
+	case len(addrs) > 0:
+		res.HasMailServer = true
+		res.ImplicitMX = true
+		res.MailServers = []string{domain}
+	}
+
+	return res, nil
+}
+
+// isNotFound reports whether err is a lookup finding no records.
+func isNotFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
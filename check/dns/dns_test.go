@@ -0,0 +1,202 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeClock is a settable clock.
+type fakeClock struct {
+	now atomic.Int64
+}
+
+func (c *fakeClock) Now() time.Time {
+	return time.Unix(0, c.now.Load())
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now.Add(int64(d))
+}
+
+// fakeResolver serves records from maps and counts MX lookups. Names
+// missing from both maps do not exist; names mapped to nil records fail.
+type fakeResolver struct {
+	mx      map[string][]*net.MX
+	addrs   map[string][]netip.Addr
+	lookups atomic.Int32
+
+	// gate, if set, blocks MX lookups until it is closed.
+	gate chan struct{}
+}
+
+func notFound(name string) error {
+	return &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+func (r *fakeResolver) LookupMX(_ context.Context, name string) ([]*net.MX, error) {
+	r.lookups.Add(1)
+	if r.gate != nil {
+		<-r.gate
+	}
+
+	records, ok := r.mx[name]
+	switch {
+	case !ok:
+		return nil, notFound(name)
+	case records == nil:
+		return nil, &net.DNSError{Err: "server misbehaving", Name: name, IsTemporary: true}
+	}
+
+	return records, nil
+}
+
+func (r *fakeResolver) LookupNetIP(_ context.Context, _, host string) ([]netip.Addr, error) {
+	addrs, ok := r.addrs[host]
+	if !ok {
+		return nil, notFound(host)
+	}
+
+	return addrs, nil
+}
+
+func newResolver() *fakeResolver {
+	return &fakeResolver{
+		mx: map[string][]*net.MX{
+			"example.com":    {{Host: "mx2.example.com.", Pref: 20}, {Host: "mx1.example.com.", Pref: 10}},
+			"nomail.example": {{Host: ".", Pref: 0}},
+			"broken.example": nil,
+		},
+		addrs: map[string][]netip.Addr{
+			"implicit.example": {netip.MustParseAddr("192.0.2.1")},
+		},
+	}
+}
+
+func TestChecker_Check(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		domain string
+		want   Result
+	}{
+		{"Example.COM.", Result{Domain: "example.com", HasMailServer: true, MailServers: []string{"mx1.example.com", "mx2.example.com"}}},
+		{"nomail.example", Result{Domain: "nomail.example", NullMX: true}},
+		{"implicit.example", Result{Domain: "implicit.example", HasMailServer: true, MailServers: []string{"implicit.example"}, ImplicitMX: true}},
+		{"missing.example", Result{Domain: "missing.example"}},
+	}
+
+	c := New(WithResolver(newResolver()))
+	for _, tt := range tests {
+		t.Run(tt.domain, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := c.Check(context.Background(), tt.domain)
+			if err != nil {
+				t.Fatalf("Check() error = %v", err)
+			}
+			if fmt.Sprintf("%+v", *got) != fmt.Sprintf("%+v", tt.want) {
+				t.Errorf("Check() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+
+	if _, err := c.Check(context.Background(), "broken.example"); err == nil {
+		t.Error("Check() with failing lookup succeeded")
+	}
+}
+
+func TestChecker_CheckAddress(t *testing.T) {
+	t.Parallel()
+
+	r := newResolver()
+	r.mx["xn--bcher-kva.example"] = []*net.MX{{Host: "mx.xn--bcher-kva.example.", Pref: 10}}
+	c := New(WithResolver(r))
+
+	got, err := c.CheckAddress(context.Background(), "user@Bücher.example")
+	if err != nil || !got.HasMailServer || got.Domain != "xn--bcher-kva.example" {
+		t.Errorf("CheckAddress() = %+v, %v, want the punycode domain with a mail server", got, err)
+	}
+	if _, err := c.CheckAddress(context.Background(), "not an address"); err == nil {
+		t.Error("CheckAddress() of a non-address succeeded")
+	}
+}
+
+func TestChecker_Cache(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	clock := &fakeClock{}
+	r := newResolver()
+	c := New(WithResolver(r), WithClock(clock), WithTTL(time.Hour, time.Minute))
+
+	check := func(domain string, wantLookups int32) {
+		t.Helper()
+		if _, err := c.Check(ctx, domain); err != nil {
+			t.Fatalf("Check(%q) error = %v", domain, err)
+		}
+		if got := r.lookups.Load(); got != wantLookups {
+			t.Errorf("after Check(%q), %d lookups, want %d", domain, got, wantLookups)
+		}
+	}
+
+	check("example.com", 1)
+	check("example.com", 1)
+	check("missing.example", 2)
+	check("missing.example", 2)
+
+	// Negative results expire sooner.
+	clock.Advance(2 * time.Minute)
+	check("missing.example", 3)
+	check("example.com", 3)
+
+	clock.Advance(time.Hour)
+	check("example.com", 4)
+
+	// Failures are not cached.
+	for range 2 {
+		if _, err := c.Check(ctx, "broken.example"); err == nil {
+			t.Error("Check() with failing lookup succeeded")
+		}
+	}
+	if got := r.lookups.Load(); got != 6 {
+		t.Errorf("after failing checks, %d lookups, want 6", got)
+	}
+}
+
+func TestChecker_ConcurrentLookups(t *testing.T) {
+	t.Parallel()
+
+	r := newResolver()
+	r.gate = make(chan struct{})
+	c := New(WithResolver(r))
+
+	const callers = 5
+	var wg sync.WaitGroup
+	for range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if got, err := c.Check(context.Background(), "example.com"); err != nil || !got.HasMailServer {
+				t.Errorf("Check() = %+v, %v", got, err)
+			}
+		}()
+	}
+
+	// Let the callers queue up behind the first lookup.
+	for r.lookups.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(r.gate)
+	wg.Wait()
+
+	if got := r.lookups.Load(); got != 1 {
+		t.Errorf("%d concurrent checks made %d lookups, want 1", callers, got)
+	}
+}
@@ -0,0 +1,455 @@
+// Package smtpprobe checks whether a mailbox exists by asking the mail
+// server of its domain. A probe connects to the highest-preference mail
+// server, issues EHLO, MAIL FROM, and RCPT TO for the address, and quits
+// without sending a message; the reply to RCPT TO is classified as
+// deliverable, undeliverable, or unknown.
+//
+// Probing is optional and imprecise: many servers accept every recipient,
+// defer unknown senders (greylisting), or block probes from addresses
+// without a mail reputation. Callers should treat VerdictUnknown as no
+// information rather than as a failure. Probes are bounded by a timeout and
+// by a limit on concurrent probes per domain, so that bulk checks do not
+// look like an attack on a single provider.
+//
+// Mail servers are named by the MX records of the probed domain, which
+// whoever registers the domain controls. So that probes cannot be pointed at
+// services on the prober's own network, mail servers at loopback, private,
+// link-local, and other non-public addresses are refused unless
+// WithPrivateAddresses allows them.
+package smtpprobe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/netip"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/check/dns"
+	"github.com/jaeyeom/email-validator-grpc-mcp/redact"
+)
+
+// Default Prober settings.
+const (
+	// DefaultTimeout is the default time allowed for a probe, from the
+	// mail server lookup to QUIT.
+	DefaultTimeout = 10 * time.Second
+
+	// DefaultMaxPerDomain is the default number of concurrent probes of
+	// addresses at one domain.
+	DefaultMaxPerDomain = 2
+
+	// DefaultMaxServers is the default number of mail servers tried when
+	// the preferred ones cannot be reached.
+	DefaultMaxServers = 2
+
+	// DefaultHelloName is the default name sent with EHLO.
+	DefaultHelloName = "localhost"
+
+	// DefaultPort is the SMTP port probed.
+	DefaultPort = "25"
+)
+
+// ErrNonPublicAddress is returned, wrapped, when a mail server resolves to
+// an address that may not be probed.
+var ErrNonPublicAddress = errors.New("mail server address is not public")
+
+// Verdict classifies a mailbox.
+type Verdict string
+
+// Probe verdicts.
+const (
+	// VerdictDeliverable is a mailbox the server accepted mail for.
+	VerdictDeliverable Verdict = "DELIVERABLE"
+	// VerdictUndeliverable is a mailbox the server rejected as
+	// nonexistent, or an address whose domain has no mail server.
+	VerdictUndeliverable Verdict = "UNDELIVERABLE"
+	// VerdictUnknown is a mailbox the probe could not decide on: the server
+	// could not be reached, deferred the recipient, or refused the probe.
+	VerdictUnknown Verdict = "UNKNOWN"
+)
+
+// Result is the outcome of probing an address.
+type Result struct {
+	Address string  `json:"address"`
+	Verdict Verdict `json:"verdict"`
+
+	// MailServer is the host that gave the verdict, or the last one tried.
+	MailServer string `json:"mail_server,omitempty"`
+
+	// Code and Message are the SMTP reply that decided the verdict, if
+	// any.
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+
+	// Greylisted reports that the server temporarily deferred the
+	// recipient, as greylisting servers do for unknown senders.
+	Greylisted bool `json:"greylisted,omitempty"`
+}
+
+// Dialer opens network connections. *net.Dialer implements it.
+type Dialer interface {
+	DialContext(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+// Prober probes mailboxes. It is safe for concurrent use.
+type Prober struct {
+	checker      *dns.Checker
+	dialer       Dialer
+	logger       *slog.Logger
+	helloName    string
+	mailFrom     string
+	port         string
+	timeout      time.Duration
+	maxPerDomain int
+	maxServers   int
+	privateAddrs bool
+
+	mu    sync.Mutex
+	slots map[string]*slot
+}
+
+// slot limits the concurrent probes of one domain.
+type slot struct {
+	sem   chan struct{}
+	users int
+}
+
+// Option is a functional option for configuring Prober.
+type Option func(*Prober)
+
+// WithDNSChecker sets the checker mail servers are looked up with. The
+// default is dns.New().
+func WithDNSChecker(checker *dns.Checker) Option {
+	return func(p *Prober) {
+		p.checker = checker
+	}
+}
+
+// WithDialer sets the dialer connections are made with. It is used as is:
+// refusing non-public addresses is up to it, and WithPrivateAddresses has
+// no effect.
+func WithDialer(dialer Dialer) Option {
+	return func(p *Prober) {
+		p.dialer = dialer
+	}
+}
+
+// WithPrivateAddresses sets whether mail servers at loopback, private,
+// link-local, and other non-public addresses may be probed, such as for a
+// deployment validating addresses of an internal mail system. They are
+// refused by default.
+func WithPrivateAddresses(allow bool) Option {
+	return func(p *Prober) {
+		p.privateAddrs = allow
+	}
+}
+
+// WithLogger sets a custom logger for Prober.
+func WithLogger(logger *slog.Logger) Option {
+	return func(p *Prober) {
+		p.logger = logger
+	}
+}
+
+// WithHelloName sets the name sent with EHLO. Servers may refuse probes
+// from a name that does not resolve to the connecting address, so it should
+// be the public host name of the machine probing.
+func WithHelloName(name string) Option {
+	return func(p *Prober) {
+		p.helloName = name
+	}
+}
+
+// WithMailFrom sets the sender of MAIL FROM. The default is the null
+// sender, "<>", which some servers refuse; an address at a domain the
+// prober's host is allowed to send for gives better results.
+func WithMailFrom(address string) Option {
+	return func(p *Prober) {
+		p.mailFrom = address
+	}
+}
+
+// WithPort sets the port mail servers are probed on.
+func WithPort(port string) Option {
+	return func(p *Prober) {
+		p.port = port
+	}
+}
+
+// WithTimeout sets the time allowed for a probe.
+func WithTimeout(d time.Duration) Option {
+	return func(p *Prober) {
+		if d > 0 {
+			p.timeout = d
+		}
+	}
+}
+
+// WithMaxPerDomain sets the number of concurrent probes of addresses at
+// one domain. Further probes wait for a slot.
+func WithMaxPerDomain(n int) Option {
+	return func(p *Prober) {
+		if n > 0 {
+			p.maxPerDomain = n
+		}
+	}
+}
+
+// WithMaxServers sets the number of mail servers tried, in preference
+// order, when the preferred ones cannot be reached.
+func WithMaxServers(n int) Option {
+	return func(p *Prober) {
+		if n > 0 {
+			p.maxServers = n
+		}
+	}
+}
+
+// New creates a Prober.
+func New(opts ...Option) *Prober {
+	p := &Prober{
+		logger:       slog.Default(),
+		helloName:    DefaultHelloName,
+		port:         DefaultPort,
+		timeout:      DefaultTimeout,
+		maxPerDomain: DefaultMaxPerDomain,
+		maxServers:   DefaultMaxServers,
+		slots:        make(map[string]*slot),
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	if p.checker == nil {
+		p.checker = dns.New()
+	}
+
+	if p.dialer == nil {
+		dialer := &net.Dialer{}
+		if !p.privateAddrs {
+			dialer.Control = refuseNonPublic
+		}
+		p.dialer = dialer
+	}
+
+	return p
+}
+
+// refuseNonPublic is a net.Dialer Control function refusing to connect to
+// addresses that are not public unicast addresses. It runs after the host
+// name is resolved, so a name cannot resolve to one address when checked
+// and another when dialed.
+func refuseNonPublic(_, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrNonPublicAddress, address)
+	}
+
+	addr := addrPort.Addr().Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return fmt.Errorf("%w: %s", ErrNonPublicAddress, addr)
+	}
+
+	return nil
+}
+
+// Probe probes the mailbox of addr. Unreachable servers and refused probes
+// give VerdictUnknown; an error is returned only for a malformed address, a
+// failed mail server lookup, or a done context.
+func (p *Prober) Probe(ctx context.Context, addr string) (*Result, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("context error: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	servers, err := p.checker.CheckAddress(ctx, addr)
+	if err != nil {
+		return nil, fmt.Errorf("cannot probe address: %w", err)
+	}
+
+	res := &Result{Address: addr, Verdict: VerdictUnknown}
+	if !servers.HasMailServer {
+		res.Verdict = VerdictUndeliverable
+		res.Message = "domain has no mail server"
+		return res, nil
+	}
+
+	release, err := p.acquire(ctx, servers.Domain)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	for i, host := range servers.MailServers {
+		if i == p.maxServers {
+			break
+		}
+
+		res.MailServer = host
+		decided, err := p.probeServer(ctx, host, addr, res)
+		if decided {
+			break
+		}
+
+		p.logger.Debug("mail server probe failed",
+			"mail_server", host,
+			"email", redact.Email(addr),
+			"error", err)
+		res.Message = err.Error()
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	p.logger.Debug("mailbox probed",
+		"email", redact.Email(addr),
+		"mail_server", res.MailServer,
+		"verdict", res.Verdict,
+		"code", res.Code)
+
+	return res, nil
+}
+
+// probeServer runs the SMTP dialogue with one mail server. It reports
+// whether the server replied to RCPT TO, filling in res if so; otherwise
+// the next server may be tried.
+func (p *Prober) probeServer(ctx context.Context, host, addr string, res *Result) (bool, error) {
+	conn, err := p.dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, p.port))
+	if err != nil {
+		return false, fmt.Errorf("connect failed: %w", err)
+	}
+	defer conn.Close()
+
+	// Bound every read and write by the probe's deadline, and abort them
+	// when the context is canceled.
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return false, fmt.Errorf("cannot set deadline: %w", err)
+		}
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return false, fmt.Errorf("greeting failed: %w", err)
+	}
+	defer client.Close()
+
+	if err := client.Hello(p.helloName); err != nil {
+		return false, fmt.Errorf("EHLO failed: %w", err)
+	}
+	if err := client.Mail(p.mailFrom); err != nil {
+		return false, fmt.Errorf("MAIL FROM failed: %w", err)
+	}
+
+	err = client.Rcpt(addr)
+	var reply *textproto.Error
+	switch {
+	case err == nil:
+		res.Verdict = VerdictDeliverable
+		res.Code = 250
+		res.Message = ""
+	case errors.As(err, &reply):
+		res.Code = reply.Code
+		res.Message = reply.Msg
+		res.Verdict, res.Greylisted = classify(reply.Code, reply.Msg)
+	default:
+		return false, fmt.Errorf("RCPT TO failed: %w", err)
+	}
+
+	// The verdict is in; a failed QUIT does not change it.
+	_ = client.Quit()
+
+	return true, nil
+}
+
+// classify classifies a rejected RCPT TO reply. Permanent failures mean
+// the mailbox does not exist only when the reply says so: a basic code of
+// 550, 551, or 553 without an enhanced status code (RFC 3463), or an
+// enhanced status of 5.1.x (addressing). Other permanent failures, such as
+// 5.7.x policy rejections of the prober, decide nothing.
+func classify(code int, msg string) (Verdict, bool) {
+	switch {
+	case code >= 400 && code < 500:
+		return VerdictUnknown, true
+	case code < 500 || code >= 600:
+		return VerdictUnknown, false
+	}
+
+	if class, subject, ok := enhancedStatus(msg); ok {
+		if class == "5" && subject == "1" {
+			return VerdictUndeliverable, false
+		}
+		return VerdictUnknown, false
+	}
+
+	switch code {
+	case 550, 551, 553:
+		return VerdictUndeliverable, false
+	default:
+		return VerdictUnknown, false
+	}
+}
+
+// enhancedStatus returns the class and subject of the enhanced status code
+// leading msg, such as "5" and "1" for "5.1.1 User unknown".
+func enhancedStatus(msg string) (class, subject string, ok bool) {
+	code, _, _ := strings.Cut(strings.TrimSpace(msg), " ")
+	parts := strings.Split(code, ".")
+	if len(parts) != 3 {
+		return "", "", false
+	}
+	for _, part := range parts {
+		if part == "" || strings.Trim(part, "0123456789") != "" {
+			return "", "", false
+		}
+	}
+
+	return parts[0], parts[1], true
+}
+
+// acquire waits for a probe slot of domain and returns the function
+// releasing it.
+func (p *Prober) acquire(ctx context.Context, domain string) (func(), error) {
+	p.mu.Lock()
+	s, ok := p.slots[domain]
+	if !ok {
+		s = &slot{sem: make(chan struct{}, p.maxPerDomain)}
+		p.slots[domain] = s
+	}
+	s.users++
+	p.mu.Unlock()
+
+	leave := func() {
+		p.mu.Lock()
+		s.users--
+		if s.users == 0 {
+			delete(p.slots, domain)
+		}
+		p.mu.Unlock()
+	}
+
+	select {
+	case s.sem <- struct{}{}:
+	case <-ctx.Done():
+		leave()
+		return nil, fmt.Errorf("context error: %w", ctx.Err())
+	}
+
+	return func() {
+		<-s.sem
+		leave()
+	}, nil
+}
@@ -0,0 +1,374 @@
+package smtpprobe
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/netip"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/check/dns"
+)
+
+// fakeResolver serves MX records from a map. Domains missing from it do
+// not exist.
+type fakeResolver map[string][]*net.MX
+
+func (r fakeResolver) LookupMX(_ context.Context, name string) ([]*net.MX, error) {
+	records, ok := r[name]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+
+	return records, nil
+}
+
+func (r fakeResolver) LookupNetIP(_ context.Context, _, host string) ([]netip.Addr, error) {
+	return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
+
+// fakeServer is a scripted SMTP server reached over net.Pipe. Recipients
+// missing from replies are rejected as unknown users.
+type fakeServer struct {
+	// replies maps recipients to RCPT TO replies.
+	replies map[string]string
+
+	// greeting, if set, replaces the 220 greeting.
+	greeting string
+
+	// silent makes the server never greet.
+	silent bool
+
+	// gate, if set, delays the greeting until it is closed.
+	gate chan struct{}
+
+	// active counts open client connections, and peak the most open at
+	// once.
+	active, peak atomic.Int32
+}
+
+// clientConn is the client end of a connection to a fakeServer.
+type clientConn struct {
+	net.Conn
+	server *fakeServer
+	once   sync.Once
+}
+
+func (c *clientConn) Close() error {
+	c.once.Do(func() { c.server.active.Add(-1) })
+	return c.Conn.Close()
+}
+
+func (s *fakeServer) connect() net.Conn {
+	n := s.active.Add(1)
+	for {
+		peak := s.peak.Load()
+		if n <= peak || s.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+
+	client, conn := net.Pipe()
+	go s.serve(conn)
+
+	return &clientConn{Conn: client, server: s}
+}
+
+func (s *fakeServer) serve(conn net.Conn) {
+	defer conn.Close()
+
+	if s.silent {
+		_, _ = bufio.NewReader(conn).ReadString('\n')
+		return
+	}
+	if s.gate != nil {
+		<-s.gate
+	}
+
+	w := bufio.NewWriter(conn)
+	reply := func(line string) {
+		_, _ = w.WriteString(line + "\r\n")
+		_ = w.Flush()
+	}
+
+	if s.greeting != "" {
+		reply(s.greeting)
+		return
+	}
+	reply("220 mx.example.com ESMTP")
+
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.TrimSpace(line)
+		switch upper := strings.ToUpper(cmd); {
+		case strings.HasPrefix(upper, "EHLO"):
+			reply("250 mx.example.com")
+		case strings.HasPrefix(upper, "MAIL FROM:"):
+			reply("250 2.1.0 OK")
+		case strings.HasPrefix(upper, "RCPT TO:"):
+			to := strings.Trim(cmd[len("RCPT TO:"):], "<>")
+			if r, ok := s.replies[to]; ok {
+				reply(r)
+			} else {
+				reply("550 5.1.1 User unknown")
+			}
+		case upper == "QUIT":
+			reply("221 2.0.0 Bye")
+			return
+		default:
+			reply("502 5.5.2 Command not recognized")
+		}
+	}
+}
+
+// fakeDialer connects to fake servers by host. Hosts missing from it
+// refuse connections.
+type fakeDialer map[string]*fakeServer
+
+func (d fakeDialer) DialContext(_ context.Context, _, address string) (net.Conn, error) {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	server, ok := d[host]
+	if !ok {
+		return nil, errors.New("connection refused")
+	}
+
+	return server.connect(), nil
+}
+
+var resolver = fakeResolver{
+	"example.com":     {{Host: "mx1.example.com.", Pref: 10}, {Host: "mx2.example.com.", Pref: 20}},
+	"down.example":    {{Host: "mx1.down.example.", Pref: 10}, {Host: "mx2.down.example.", Pref: 20}},
+	"nomail.example":  {{Host: ".", Pref: 0}},
+	"blocked.example": {{Host: "mx.blocked.example.", Pref: 10}},
+}
+
+func newProber(dialer Dialer, opts ...Option) *Prober {
+	return New(append([]Option{
+		WithDNSChecker(dns.New(dns.WithResolver(resolver))),
+		WithDialer(dialer),
+		WithLogger(slog.New(slog.DiscardHandler)),
+	}, opts...)...)
+}
+
+func TestProber_Probe(t *testing.T) {
+	t.Parallel()
+
+	server := &fakeServer{replies: map[string]string{
+		"user@example.com":        "250 2.1.5 OK",
+		"grey@example.com":        "451 4.7.1 Greylisted, try again later",
+		"policy@example.com":      "550 5.7.1 Client host rejected",
+		"legacy@example.com":      "550 No such user here",
+		"unavailable@example.com": "554 Transaction failed",
+	}}
+	p := newProber(fakeDialer{
+		"mx1.example.com":    server,
+		"mx2.down.example":   server,
+		"mx.blocked.example": {greeting: "554 No SMTP service here"},
+	})
+
+	tests := []struct {
+		addr       string
+		want       Verdict
+		greylisted bool
+		server     string
+	}{
+		{"user@example.com", VerdictDeliverable, false, "mx1.example.com"},
+		{"nobody@example.com", VerdictUndeliverable, false, "mx1.example.com"},
+		{"legacy@example.com", VerdictUndeliverable, false, "mx1.example.com"},
+		{"grey@example.com", VerdictUnknown, true, "mx1.example.com"},
+		{"policy@example.com", VerdictUnknown, false, "mx1.example.com"},
+		{"unavailable@example.com", VerdictUnknown, false, "mx1.example.com"},
+		{"user@nomail.example", VerdictUndeliverable, false, ""},
+		{"user@missing.example", VerdictUndeliverable, false, ""},
+		{"user@blocked.example", VerdictUnknown, false, "mx.blocked.example"},
+		// The first server is down; the second answers.
+		{"user@down.example", VerdictUndeliverable, false, "mx2.down.example"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := p.Probe(context.Background(), tt.addr)
+			if err != nil {
+				t.Fatalf("Probe() error = %v", err)
+			}
+			if got.Verdict != tt.want || got.Greylisted != tt.greylisted || got.MailServer != tt.server {
+				t.Errorf("Probe() = %+v, want %s greylisted %v from %q", got, tt.want, tt.greylisted, tt.server)
+			}
+		})
+	}
+
+	if _, err := p.Probe(context.Background(), "not an address"); err == nil {
+		t.Error("Probe() of a non-address succeeded")
+	}
+}
+
+func TestProber_Timeout(t *testing.T) {
+	t.Parallel()
+
+	p := newProber(fakeDialer{"mx1.example.com": {silent: true}}, WithTimeout(50*time.Millisecond))
+
+	start := time.Now()
+	got, err := p.Probe(context.Background(), "user@example.com")
+	if err != nil || got.Verdict != VerdictUnknown {
+		t.Errorf("Probe() of silent server = %+v, %v, want %s", got, err, VerdictUnknown)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Probe() took %s, want it bounded by the timeout", elapsed)
+	}
+}
+
+func TestProber_MaxPerDomain(t *testing.T) {
+	t.Parallel()
+
+	server := &fakeServer{gate: make(chan struct{})}
+	p := newProber(fakeDialer{"mx1.example.com": server}, WithMaxPerDomain(2))
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := p.Probe(context.Background(), "user@example.com"); err != nil {
+				t.Errorf("Probe() error = %v", err)
+			}
+		}()
+	}
+
+	for server.active.Load() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(server.gate)
+	wg.Wait()
+
+	if peak := server.peak.Load(); peak != 2 {
+		t.Errorf("peak concurrent probes = %d, want 2", peak)
+	}
+	if len(p.slots) != 0 {
+		t.Errorf("%d domain slots left after probes, want 0", len(p.slots))
+	}
+}
+
+func TestProber_PrivateAddresses(t *testing.T) {
+	t.Parallel()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	server := &fakeServer{replies: map[string]string{"user@internal.example": "250 2.1.5 OK"}}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	checker := dns.New(dns.WithResolver(fakeResolver{
+		"internal.example": {{Host: "127.0.0.1.", Pref: 10}},
+	}))
+
+	tests := []struct {
+		name  string
+		allow bool
+		want  Verdict
+	}{
+		{"refused by default", false, VerdictUnknown},
+		{"allowed", true, VerdictDeliverable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			p := New(WithDNSChecker(checker), WithPort(port), WithPrivateAddresses(tt.allow),
+				WithLogger(slog.New(slog.DiscardHandler)))
+
+			got, err := p.Probe(context.Background(), "user@internal.example")
+			if err != nil {
+				t.Fatalf("Probe() error = %v", err)
+			}
+			if got.Verdict != tt.want {
+				t.Errorf("Probe() = %+v, want %s", got, tt.want)
+			}
+			if refused := strings.Contains(got.Message, ErrNonPublicAddress.Error()); refused == tt.allow {
+				t.Errorf("Probe() message = %q, want refused %v", got.Message, !tt.allow)
+			}
+		})
+	}
+}
+
+func TestRefuseNonPublic(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		address string
+		refused bool
+	}{
+		{"93.184.215.14:25", false},
+		{"[2606:2800:21f:cb07:6820:80da:af6b:8b2c]:25", false},
+		{"127.0.0.1:25", true},
+		{"[::1]:25", true},
+		{"10.1.2.3:25", true},
+		{"172.16.0.1:25", true},
+		{"192.168.1.1:25", true},
+		{"169.254.169.254:25", true},
+		{"[fe80::1]:25", true},
+		{"[fc00::1]:25", true},
+		{"[::ffff:127.0.0.1]:25", true},
+		{"0.0.0.0:25", true},
+	}
+
+	for _, tt := range tests {
+		err := refuseNonPublic("tcp", tt.address, nil)
+		if refused := errors.Is(err, ErrNonPublicAddress); refused != tt.refused {
+			t.Errorf("refuseNonPublic(%q) = %v, want refused %v", tt.address, err, tt.refused)
+		}
+	}
+}
+
+func TestClassify(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		code       int
+		msg        string
+		want       Verdict
+		greylisted bool
+	}{
+		{450, "4.2.0 Greylisted", VerdictUnknown, true},
+		{550, "5.1.1 User unknown", VerdictUndeliverable, false},
+		{550, "5.7.1 Relaying denied", VerdictUnknown, false},
+		{553, "Mailbox name not allowed", VerdictUndeliverable, false},
+		{552, "Mailbox full", VerdictUnknown, false},
+		{554, "5.1.10 Recipient address rejected", VerdictUndeliverable, false},
+	}
+
+	for _, tt := range tests {
+		verdict, greylisted := classify(tt.code, tt.msg)
+		if verdict != tt.want || greylisted != tt.greylisted {
+			t.Errorf("classify(%d, %q) = %s, %v, want %s, %v", tt.code, tt.msg, verdict, greylisted, tt.want, tt.greylisted)
+		}
+	}
+}
@@ -0,0 +1,291 @@
+// Package suggest proposes corrections for mistyped email domains, such as
+// "gmail.com" for "gmial.com", so that a UI can ask "did you mean ...?"
+// before a verification message is sent to a domain that will never
+// receive it.
+//
+// A domain is compared with a ranked list of popular mail domains by
+// restricted Damerau-Levenshtein distance, which counts a swap of adjacent
+// characters as one edit. Ties go to the higher-ranked domain. A top-level domain that
+// is not a known one, as in "example.con", is corrected first.
+package suggest
+
+import (
+	"strings"
+)
+
+// DefaultMaxDistance is the default maximum number of edits a suggestion
+// may be away from the domain typed.
+const DefaultMaxDistance = 2
+
+// DefaultDomains are popular mail domains, most used first.
+var DefaultDomains = []string{
+	"gmail.com",
+	"yahoo.com",
+	"hotmail.com",
+	"outlook.com",
+	"icloud.com",
+	"aol.com",
+	"live.com",
+	"msn.com",
+	"me.com",
+	"googlemail.com",
+	"hotmail.co.uk",
+	"yahoo.co.uk",
+	"yahoo.co.jp",
+	"yahoo.fr",
+	"hotmail.fr",
+	"live.co.uk",
+	"comcast.net",
+	"verizon.net",
+	"att.net",
+	"sbcglobal.net",
+	"bellsouth.net",
+	"cox.net",
+	"charter.net",
+	"earthlink.net",
+	"ymail.com",
+	"rocketmail.com",
+	"protonmail.com",
+	"proton.me",
+	"fastmail.com",
+	"zoho.com",
+	"mail.com",
+	"email.com",
+	"gmx.com",
+	"gmx.de",
+	"web.de",
+	"t-online.de",
+	"orange.fr",
+	"free.fr",
+	"laposte.net",
+	"libero.it",
+	"btinternet.com",
+	"sky.com",
+	"yandex.ru",
+	"mail.ru",
+	"naver.com",
+	"daum.net",
+	"hanmail.net",
+	"qq.com",
+	"163.com",
+}
+
+// DefaultTLDs are common top-level domains, most used first.
+var DefaultTLDs = []string{
+	"com", "net", "org", "edu", "gov", "co", "io", "me", "us", "uk",
+	"de", "fr", "jp", "kr", "cn", "ru", "br", "in", "it", "es", "nl",
+	"ca", "au", "ch", "se", "no", "dk", "fi", "pl", "be", "at", "nz",
+	"ie", "mx", "ar", "info", "biz", "mil", "int", "ai", "app", "dev",
+	"xyz", "email", "online", "site",
+}
+
+// Suggestion is a proposed correction of an address.
+type Suggestion struct {
+	// Address is the corrected address, with the local part as typed.
+	Address string `json:"address"`
+
+	// Domain is the corrected domain.
+	Domain string `json:"domain"`
+
+	// Distance is the number of edits from the domain typed.
+	Distance int `json:"distance"`
+}
+
+// Suggester proposes domain corrections. It is safe for concurrent use.
+type Suggester struct {
+	domains     []string
+	known       map[string]struct{}
+	tlds        []string
+	knownTLDs   map[string]struct{}
+	maxDistance int
+}
+
+// Option is a functional option for configuring Suggester.
+type Option func(*Suggester)
+
+// WithDomains sets the ranked list of domains suggested, most used first.
+func WithDomains(domains ...string) Option {
+	return func(s *Suggester) {
+		s.domains = domains
+	}
+}
+
+// WithTLDs sets the ranked list of top-level domains that mistyped ones
+// are corrected to. Top-level domains missing from it are assumed mistyped
+// when one in it is a single edit away.
+func WithTLDs(tlds ...string) Option {
+	return func(s *Suggester) {
+		s.tlds = tlds
+	}
+}
+
+// WithMaxDistance sets the maximum number of edits a suggestion may be away
+// from the domain typed. Short domains allow fewer edits regardless, since
+// any short name is a few edits from many others.
+func WithMaxDistance(n int) Option {
+	return func(s *Suggester) {
+		if n > 0 {
+			s.maxDistance = n
+		}
+	}
+}
+
+// New creates a Suggester.
+func New(opts ...Option) *Suggester {
+	s := &Suggester{
+		domains:     DefaultDomains,
+		tlds:        DefaultTLDs,
+		maxDistance: DefaultMaxDistance,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.known = toSet(s.domains)
+	s.knownTLDs = toSet(s.tlds)
+
+	return s
+}
+
+// toSet returns the lower-cased members of list as a set.
+func toSet(list []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(list))
+	for _, item := range list {
+		set[strings.ToLower(item)] = struct{}{}
+	}
+
+	return set
+}
+
+// defaultSuggester is a Suggester with the default options.
+var defaultSuggester = New()
+
+// Suggest suggests a correction of addr with the default options.
+func Suggest(addr string) *Suggestion {
+	return defaultSuggester.Suggest(addr)
+}
+
+// Suggest returns a correction of the domain of addr, or nil if it has no
+// domain, its domain is a listed one, or nothing listed is close enough.
+func (s *Suggester) Suggest(addr string) *Suggestion {
+	at := strings.LastIndexByte(addr, '@')
+	if at < 0 {
+		return nil
+	}
+	local := addr[:at]
+	domain := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(addr[at+1:])), ".")
+	if domain == "" {
+		return nil
+	}
+	if _, ok := s.known[domain]; ok {
+		return nil
+	}
+
+	candidate, dist := s.fixTLD(domain)
+	if _, ok := s.known[candidate]; !ok {
+		if best, d := s.closest(candidate); best != "" {
+			candidate, dist = best, dist+d
+		}
+	}
+
+	if candidate == domain {
+		return nil
+	}
+
+	return &Suggestion{Address: local + "@" + candidate, Domain: candidate, Distance: dist}
+}
+
+// fixTLD corrects an unknown top-level domain of domain to a known one a
+// single edit away, returning the corrected domain and the edits made.
+func (s *Suggester) fixTLD(domain string) (string, int) {
+	dot := strings.LastIndexByte(domain, '.')
+	if dot < 0 {
+		return domain, 0
+	}
+
+	tld := domain[dot+1:]
+	if _, ok := s.knownTLDs[tld]; ok {
+		return domain, 0
+	}
+	for _, known := range s.tlds {
+		if distance(tld, known) == 1 {
+			return domain[:dot+1] + known, 1
+		}
+	}
+
+	return domain, 0
+}
+
+// closest returns the highest-ranked listed domain closest to domain
+// within the allowed distance, and its distance, or "" if there is none.
+func (s *Suggester) closest(domain string) (string, int) {
+	best, bestDist := "", 0
+	for _, known := range s.domains {
+		allowed := s.allowed(known)
+		if allowed == 0 || abs(len(known)-len(domain)) > allowed {
+			continue
+		}
+		if d := distance(domain, known); d <= allowed && (best == "" || d < bestDist) {
+			best, bestDist = known, d
+		}
+	}
+
+	return best, bestDist
+}
+
+// allowed returns the number of edits allowed to reach domain: none for
+// domains of six characters or fewer, such as "qq.com", one up to nine,
+// such as "gmail.com", and otherwise the maximum.
+func (s *Suggester) allowed(domain string) int {
+	switch n := len(domain); {
+	case n <= 6:
+		return 0
+	case n <= 9:
+		return min(1, s.maxDistance)
+	default:
+		return s.maxDistance
+	}
+}
+
+// distance returns the optimal string alignment distance between a and b:
+// the number of insertions, deletions, substitutions, and transpositions
+// of adjacent characters that turn one into the other.
+func distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+
+	// Three rows of the dynamic programming matrix: two rows back, the
+	// previous row, and the current one.
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+
+	return prev[len(rb)]
+}
+
+// abs returns the absolute value of n.
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+
+	return n
+}
@@ -0,0 +1,84 @@
+package suggest
+
+import (
+	"testing"
+)
+
+func TestSuggest(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		addr string
+		want string
+	}{
+		{"user@gmial.com", "user@gmail.com"},
+		{"user@gmai.com", "user@gmail.com"},
+		{"User.Name@GMAIL.CMO", "User.Name@gmail.com"},
+		{"user@gmaill.con", "user@gmail.com"},
+		{"user@hotmial.com", "user@hotmail.com"},
+		{"user@hotmail.co.uj", "user@hotmail.co.uk"},
+		{"user@yaho.com", "user@yahoo.com"},
+		{"user@outlok.com", "user@outlook.com"},
+		{"user@example.con", "user@example.com"},
+		{"user@example.nett", "user@example.net"},
+		{"user@gmail.com", ""},
+		{"user@example.com", ""},
+		{"user@example.io", ""},
+		{"user@qa.com", ""},
+		{"user@mail.com", ""},
+		{"user@email.com", ""},
+		{"not an address", ""},
+		{"user@", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			t.Parallel()
+
+			got := Suggest(tt.addr)
+			switch {
+			case tt.want == "" && got != nil:
+				t.Errorf("Suggest(%q) = %+v, want none", tt.addr, got)
+			case tt.want != "" && (got == nil || got.Address != tt.want):
+				t.Errorf("Suggest(%q) = %+v, want %q", tt.addr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSuggester_Options(t *testing.T) {
+	t.Parallel()
+
+	s := New(WithDomains("corp-mail.example"), WithTLDs("example"), WithMaxDistance(1))
+	if got := s.Suggest("user@crop-mail.exampel"); got == nil || got.Domain != "corp-mail.example" || got.Distance != 2 {
+		t.Errorf("Suggest() with custom lists = %+v, want corp-mail.example at distance 2", got)
+	}
+	if got := s.Suggest("user@gmial.com"); got != nil {
+		t.Errorf("Suggest() of unlisted domain = %+v, want none", got)
+	}
+}
+
+func TestDistance(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"gmail", "gmail", 0},
+		{"gmial", "gmail", 1},
+		{"gmai", "gmail", 1},
+		{"gmaill", "gmail", 1},
+		{"gnail", "gmail", 1},
+		{"kitten", "sitting", 3},
+		{"ca", "abc", 3},
+	}
+
+	for _, tt := range tests {
+		if got := distance(tt.a, tt.b); got != tt.want {
+			t.Errorf("distance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,376 @@
+// Package syntax checks email addresses against the syntax of RFC 5321 and
+// RFC 5322. Rather than a yes or no answer, a check returns every issue
+// found, each tied to the part of the address it concerns, so callers can
+// tell users what is wrong.
+//
+// The accepted syntax is the SMTP mailbox of RFC 5321 section 4.1.2: a
+// dot-atom or quoted-string local part and a domain, optionally an address
+// literal. Comments, folding white space, and obsolete forms that RFC 5322
+// allows in message headers are rejected, since they cannot be used in an
+// SMTP envelope.
+package syntax
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+	"unicode/utf8"
+)
+
+// Length limits, in octets.
+const (
+	// MaxAddressLength is the longest address that fits the 256-octet
+	// forward-path of RFC 5321 section 4.5.3.1.3 with its angle brackets.
+	MaxAddressLength = 254
+
+	// MaxLocalLength is the longest local part (RFC 5321 section
+	// 4.5.3.1.1).
+	MaxLocalLength = 64
+
+	// MaxDomainLength is the longest domain (RFC 1035 section 2.3.4).
+	MaxDomainLength = 253
+
+	// MaxLabelLength is the longest domain label (RFC 1035 section 2.3.4).
+	MaxLabelLength = 63
+)
+
+// ErrInvalid is wrapped by the errors of Result.Err.
+var ErrInvalid = errors.New("invalid email address syntax")
+
+// Part is the part of an address an issue concerns.
+type Part string
+
+// Address parts.
+const (
+	PartAddress Part = "address"
+	PartLocal   Part = "local"
+	PartDomain  Part = "domain"
+)
+
+// Code identifies a kind of issue.
+type Code string
+
+// Issue codes.
+const (
+	CodeEmpty        Code = "EMPTY"
+	CodeMissingAt    Code = "MISSING_AT"
+	CodeTooLong      Code = "TOO_LONG"
+	CodeInvalidChar  Code = "INVALID_CHARACTER"
+	CodeInvalidUTF8  Code = "INVALID_UTF8"
+	CodeDotPlacement Code = "DOT_PLACEMENT"
+	CodeBadQuoting   Code = "BAD_QUOTING"
+	CodeQuoted       Code = "QUOTED_NOT_ALLOWED"
+	CodeLabelEmpty   Code = "EMPTY_LABEL"
+	CodeLabelTooLong Code = "LABEL_TOO_LONG"
+	CodeLabelHyphen  Code = "LABEL_HYPHEN"
+	CodeNumericTLD   Code = "NUMERIC_TLD"
+	CodeNoTLD        Code = "NO_TLD"
+	CodeBadLiteral   Code = "BAD_ADDRESS_LITERAL"
+	CodeLiteral      Code = "ADDRESS_LITERAL_NOT_ALLOWED"
+)
+
+// Issue is one problem with an address.
+type Issue struct {
+	Part    Part   `json:"part"`
+	Code    Code   `json:"code"`
+	Message string `json:"message"`
+}
+
+// Result is the outcome of checking an address.
+type Result struct {
+	Address string `json:"address"`
+
+	// Local and Domain are the parts of the address on either side of the
+	// last "@", or empty if it has none.
+	Local  string `json:"local,omitempty"`
+	Domain string `json:"domain,omitempty"`
+
+	Issues []Issue `json:"issues,omitempty"`
+}
+
+// Valid reports whether no issue was found.
+func (r *Result) Valid() bool {
+	return len(r.Issues) == 0
+}
+
+// Err returns nil for a valid address, and otherwise an *Error wrapping
+// ErrInvalid.
+func (r *Result) Err() error {
+	if r.Valid() {
+		return nil
+	}
+
+	return &Error{Result: r}
+}
+
+// add records an issue.
+func (r *Result) add(part Part, code Code, format string, args ...any) {
+	r.Issues = append(r.Issues, Issue{Part: part, Code: code, Message: fmt.Sprintf(format, args...)})
+}
+
+// Error reports the issues of an invalid address.
+type Error struct {
+	Result *Result
+}
+
+// Error returns the messages of the issues.
+func (e *Error) Error() string {
+	msgs := make([]string, len(e.Result.Issues))
+	for i, issue := range e.Result.Issues {
+		msgs[i] = issue.Message
+	}
+
+	return ErrInvalid.Error() + ": " + strings.Join(msgs, "; ")
+}
+
+// Unwrap returns ErrInvalid.
+func (e *Error) Unwrap() error {
+	return ErrInvalid
+}
+
+// Checker checks addresses with a set of syntax options.
+type Checker struct {
+	quoted     bool
+	literal    bool
+	utf8       bool
+	requireTLD bool
+}
+
+// Option is a functional option for configuring Checker.
+type Option func(*Checker)
+
+// WithQuotedLocal sets whether quoted local parts, such as
+// "\"john doe\"@example.com", are accepted. They are by default.
+func WithQuotedLocal(allow bool) Option {
+	return func(c *Checker) {
+		c.quoted = allow
+	}
+}
+
+// WithAddressLiteral sets whether address literals, such as
+// "user@[192.0.2.1]", are accepted. They are not by default.
+func WithAddressLiteral(allow bool) Option {
+	return func(c *Checker) {
+		c.literal = allow
+	}
+}
+
+// WithUTF8 sets whether non-ASCII characters are accepted in the local part
+// and domain, as SMTPUTF8 (RFC 6531) allows. They are by default.
+func WithUTF8(allow bool) Option {
+	return func(c *Checker) {
+		c.utf8 = allow
+	}
+}
+
+// WithRequireTLD sets whether domains must have at least two labels, such
+// as "example.com" rather than "localhost". They must by default, since a
+// single-label domain cannot be reached on the public Internet.
+func WithRequireTLD(require bool) Option {
+	return func(c *Checker) {
+		c.requireTLD = require
+	}
+}
+
+// New creates a Checker. Without options, it accepts quoted local parts
+// and UTF-8, and rejects address literals and single-label domains.
+func New(opts ...Option) *Checker {
+	c := &Checker{
+		quoted:     true,
+		utf8:       true,
+		requireTLD: true,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// defaultChecker is a Checker with the default options.
+var defaultChecker = New()
+
+// Check checks addr with the default options.
+func Check(addr string) *Result {
+	return defaultChecker.Check(addr)
+}
+
+// Check checks addr, reporting every issue found. Surrounding white space
+// is an issue; callers accepting user input should trim it first.
+func (c *Checker) Check(addr string) *Result {
+	r := &Result{Address: addr}
+
+	if addr == "" {
+		r.add(PartAddress, CodeEmpty, "address is empty")
+		return r
+	}
+	if !utf8.ValidString(addr) {
+		r.add(PartAddress, CodeInvalidUTF8, "address is not valid UTF-8")
+		return r
+	}
+	if len(addr) > MaxAddressLength {
+		r.add(PartAddress, CodeTooLong, "address is longer than %d octets", MaxAddressLength)
+	}
+
+	at := strings.LastIndexByte(addr, '@')
+	if at < 0 {
+		r.add(PartAddress, CodeMissingAt, "address has no @")
+		return r
+	}
+	r.Local, r.Domain = addr[:at], addr[at+1:]
+
+	c.checkLocal(r, r.Local)
+	c.checkDomain(r, r.Domain)
+
+	return r
+}
+
+// checkLocal checks the local part.
+func (c *Checker) checkLocal(r *Result, local string) {
+	if local == "" {
+		r.add(PartLocal, CodeEmpty, "local part is empty")
+		return
+	}
+	if len(local) > MaxLocalLength {
+		r.add(PartLocal, CodeTooLong, "local part is longer than %d octets", MaxLocalLength)
+	}
+
+	if strings.HasPrefix(local, `"`) {
+		if !c.quoted {
+			r.add(PartLocal, CodeQuoted, "quoted local parts are not allowed")
+			return
+		}
+		c.checkQuoted(r, local)
+		return
+	}
+
+	if strings.HasPrefix(local, ".") || strings.HasSuffix(local, ".") || strings.Contains(local, "..") {
+		r.add(PartLocal, CodeDotPlacement, "local part cannot start or end with a dot or contain consecutive dots")
+	}
+
+	for _, ch := range local {
+		if ch != '.' && !isAtext(ch) && (ch < utf8.RuneSelf || !c.utf8) {
+			r.add(PartLocal, CodeInvalidChar, "local part contains %q; quote it or remove the character", ch)
+			return
+		}
+	}
+}
+
+// checkQuoted checks a quoted-string local part.
+func (c *Checker) checkQuoted(r *Result, local string) {
+	if len(local) < 2 || !strings.HasSuffix(local, `"`) {
+		r.add(PartLocal, CodeBadQuoting, "quoted local part is not terminated")
+		return
+	}
+
+	inner := local[1 : len(local)-1]
+	for i := 0; i < len(inner); i++ {
+		ch := inner[i]
+		switch {
+		case ch == '\\':
+			i++
+			if i == len(inner) || inner[i] < ' ' || inner[i] > '~' {
+				r.add(PartLocal, CodeBadQuoting, "quoted local part has an invalid escape")
+				return
+			}
+		case ch == '"':
+			r.add(PartLocal, CodeBadQuoting, "quoted local part has an unescaped quote")
+			return
+		case ch >= utf8.RuneSelf:
+			if !c.utf8 {
+				r.add(PartLocal, CodeInvalidChar, "local part contains non-ASCII characters")
+				return
+			}
+		case ch < ' ' || ch > '~':
+			r.add(PartLocal, CodeInvalidChar, "quoted local part contains a control character")
+			return
+		}
+	}
+}
+
+// checkDomain checks the domain.
+func (c *Checker) checkDomain(r *Result, domain string) {
+	if domain == "" {
+		r.add(PartDomain, CodeEmpty, "domain is empty")
+		return
+	}
+
+	if strings.HasPrefix(domain, "[") {
+		c.checkLiteral(r, domain)
+		return
+	}
+
+	if len(domain) > MaxDomainLength {
+		r.add(PartDomain, CodeTooLong, "domain is longer than %d octets", MaxDomainLength)
+	}
+
+	labels := strings.Split(domain, ".")
+	for _, label := range labels {
+		switch {
+		case label == "":
+			r.add(PartDomain, CodeLabelEmpty, "domain has an empty label")
+			return
+		case len(label) > MaxLabelLength:
+			r.add(PartDomain, CodeLabelTooLong, "domain label %q is longer than %d octets", label, MaxLabelLength)
+		case strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-"):
+			r.add(PartDomain, CodeLabelHyphen, "domain label %q starts or ends with a hyphen", label)
+		}
+
+		for _, ch := range label {
+			if !isLetDig(ch) && ch != '-' && (ch < utf8.RuneSelf || !c.utf8) {
+				r.add(PartDomain, CodeInvalidChar, "domain contains %q", ch)
+				return
+			}
+		}
+	}
+
+	if len(labels) < 2 {
+		if c.requireTLD {
+			r.add(PartDomain, CodeNoTLD, "domain has no top-level domain")
+		}
+		return
+	}
+
+	tld := labels[len(labels)-1]
+	if strings.Trim(tld, "0123456789") == "" {
+		r.add(PartDomain, CodeNumericTLD, "top-level domain %q is numeric; use an address literal for IP addresses", tld)
+	}
+}
+
+// checkLiteral checks an address literal, "[IPv4]" or "[IPv6:IPv6]".
+func (c *Checker) checkLiteral(r *Result, domain string) {
+	if !c.literal {
+		r.add(PartDomain, CodeLiteral, "address literals are not allowed")
+		return
+	}
+
+	inner, ok := strings.CutSuffix(domain[1:], "]")
+	if !ok {
+		r.add(PartDomain, CodeBadLiteral, "address literal is not terminated")
+		return
+	}
+
+	if v6, ok := strings.CutPrefix(inner, "IPv6:"); ok {
+		if ip, err := netip.ParseAddr(v6); err != nil || !ip.Is6() || ip.Zone() != "" {
+			r.add(PartDomain, CodeBadLiteral, "address literal is not a valid IPv6 address")
+		}
+		return
+	}
+
+	if ip, err := netip.ParseAddr(inner); err != nil || !ip.Is4() {
+		r.add(PartDomain, CodeBadLiteral, "address literal is not a valid IPv4 address")
+	}
+}
+
+// isAtext reports whether ch is an atext character of RFC 5322 section
+// 3.2.3.
+func isAtext(ch rune) bool {
+	return isLetDig(ch) || strings.ContainsRune("!#$%&'*+-/=?^_`{|}~", ch)
+}
+
+// isLetDig reports whether ch is an ASCII letter or digit.
+func isLetDig(ch rune) bool {
+	return ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch >= '0' && ch <= '9'
+}
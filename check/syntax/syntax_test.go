@@ -0,0 +1,118 @@
+package syntax
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		addr string
+		want []Code
+	}{
+		{"user@example.com", nil},
+		{"first.last+tag@sub.example.co.uk", nil},
+		{"!#$%&'*+-/=?^_`{|}~@example.com", nil},
+		{`"john doe"@example.com`, nil},
+		{`"a\"b@c"@example.com`, nil},
+		{"用户@例子.广告", nil},
+		{"user@xn--bcher-kva.example", nil},
+		{"", []Code{CodeEmpty}},
+		{"user.example.com", []Code{CodeMissingAt}},
+		{"@example.com", []Code{CodeEmpty}},
+		{"user@", []Code{CodeEmpty}},
+		{".user@example.com", []Code{CodeDotPlacement}},
+		{"first..last@example.com", []Code{CodeDotPlacement}},
+		{"john doe@example.com", []Code{CodeInvalidChar}},
+		{`"unterminated@example.com`, []Code{CodeBadQuoting}},
+		{`"a"b"@example.com`, []Code{CodeBadQuoting}},
+		{strings.Repeat("a", 65) + "@example.com", []Code{CodeTooLong}},
+		{"user@example..com", []Code{CodeLabelEmpty}},
+		{"user@-example.com", []Code{CodeLabelHyphen}},
+		{"user@" + strings.Repeat("a", 64) + ".com", []Code{CodeLabelTooLong}},
+		{"user@exa_mple.com", []Code{CodeInvalidChar}},
+		{"user@localhost", []Code{CodeNoTLD}},
+		{"user@192.0.2.1", []Code{CodeNumericTLD}},
+		{"user@[192.0.2.1]", []Code{CodeLiteral}},
+		{" user@example.com", []Code{CodeInvalidChar}},
+		{".a..@-x", []Code{CodeDotPlacement, CodeLabelHyphen, CodeNoTLD}},
+		{strings.Repeat("a", 64) + "@" + strings.Repeat(strings.Repeat("b", 60)+".", 4) + "com", []Code{CodeTooLong}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			t.Parallel()
+
+			r := Check(tt.addr)
+			var got []Code
+			for _, issue := range r.Issues {
+				got = append(got, issue.Code)
+			}
+			if strings.Join(codes(got), ",") != strings.Join(codes(tt.want), ",") {
+				t.Errorf("Check(%q) issues = %+v, want codes %v", tt.addr, r.Issues, tt.want)
+			}
+			if r.Valid() != (len(tt.want) == 0) {
+				t.Errorf("Check(%q).Valid() = %v", tt.addr, r.Valid())
+			}
+		})
+	}
+}
+
+// codes converts codes to strings for comparison.
+func codes(cs []Code) []string {
+	out := make([]string, len(cs))
+	for i, c := range cs {
+		out[i] = string(c)
+	}
+
+	return out
+}
+
+func TestChecker_Options(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		opts  []Option
+		addr  string
+		valid bool
+	}{
+		{"literal allowed", []Option{WithAddressLiteral(true)}, "user@[192.0.2.1]", true},
+		{"IPv6 literal", []Option{WithAddressLiteral(true)}, "user@[IPv6:2001:db8::1]", true},
+		{"bad literal", []Option{WithAddressLiteral(true)}, "user@[IPv6:192.0.2.1]", false},
+		{"quoted disallowed", []Option{WithQuotedLocal(false)}, `"john doe"@example.com`, false},
+		{"ASCII only", []Option{WithUTF8(false)}, "用户@example.com", false},
+		{"ASCII only domain", []Option{WithUTF8(false)}, "user@例子.广告", false},
+		{"single label allowed", []Option{WithRequireTLD(false)}, "user@localhost", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if r := New(tt.opts...).Check(tt.addr); r.Valid() != tt.valid {
+				t.Errorf("Check(%q) = %+v, want valid %v", tt.addr, r, tt.valid)
+			}
+		})
+	}
+}
+
+func TestResult_Err(t *testing.T) {
+	t.Parallel()
+
+	if err := Check("user@example.com").Err(); err != nil {
+		t.Errorf("Err() of valid address = %v, want nil", err)
+	}
+
+	err := Check("user@localhost").Err()
+	var syntaxErr *Error
+	if !errors.Is(err, ErrInvalid) || !errors.As(err, &syntaxErr) {
+		t.Fatalf("Err() = %v, want an *Error wrapping %v", err, ErrInvalid)
+	}
+	if issue := syntaxErr.Result.Issues[0]; issue.Part != PartDomain || issue.Code != CodeNoTLD {
+		t.Errorf("Err() issue = %+v, want domain %s", issue, CodeNoTLD)
+	}
+}
@@ -0,0 +1,228 @@
+// Command evctl is an operator tool for the email validation service.
+//
+// Usage:
+//
+//	evctl doctor [-redis-addr host:port] [-signing-key-file path] [-json]
+//	evctl plan -qps n [-tokens-per-validation n] [-ttl d] [-verify-qps n] [-codec json|protobuf] [-key-prefix p]
+//	evctl repair-index -redis-addr host:port [-codec json|protobuf] [-key-prefix p] [-hash-tags] [-dry-run]
+//
+// The doctor subcommand exercises the configured components and prints a
+// pass/fail report with remediation hints. It exits with status 1 if any
+// check fails.
+//
+// The plan subcommand estimates the Redis memory, key count, and traffic of
+// a workload, so clusters can be sized before launch.
+//
+// The repair-index subcommand rebuilds the Redis validation ID index from
+// the token records and prints the drift it found. With -dry-run it only
+// reports the drift, and exits with status 1 if there is any. Records the
+// codec cannot decode are reported and left alone.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/doctor"
+	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+	"github.com/jaeyeom/email-validator-grpc-mcp/token/codec/protobuf"
+	redisstorage "github.com/jaeyeom/email-validator-grpc-mcp/token/storage/redis"
+	"github.com/redis/go-redis/v9"
+)
+
+func main() {
+	os.Exit(run(context.Background(), os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes the command line and returns the process exit status.
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprintln(stderr, usage)
+		return 2
+	}
+
+	switch args[0] {
+	case "doctor":
+		return runDoctor(ctx, args[1:], stdout, stderr)
+	case "plan":
+		return runPlan(args[1:], stdout, stderr)
+	case "repair-index":
+		return runRepairIndex(ctx, args[1:], stdout, stderr)
+	default:
+		fmt.Fprintln(stderr, usage)
+		return 2
+	}
+}
+
+// usage is printed for a missing or unknown subcommand.
+const usage = "usage: evctl doctor|plan|repair-index [flags]"
+
+// runDoctor implements the doctor subcommand.
+func runDoctor(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	redisAddr := fs.String("redis-addr", "", "address of the Redis token storage to check")
+	signingKeyFile := fs.String("signing-key-file", "", "file containing the link token signing key")
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	// Keep storage logging out of the report
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	var storage token.Storage
+	if *redisAddr != "" {
+		client := redis.NewClient(&redis.Options{Addr: *redisAddr})
+		defer client.Close()
+		storage = redisstorage.New(client, redisstorage.WithLogger(logger))
+	}
+
+	var signingKey []byte
+	if *signingKeyFile != "" {
+		data, err := os.ReadFile(*signingKeyFile)
+		if err != nil {
+			fmt.Fprintf(stderr, "evctl: %v\n", err)
+			return 2
+		}
+		signingKey = []byte(strings.TrimSpace(string(data)))
+	}
+
+	report := doctor.Run(ctx,
+		doctor.StorageRoundTrip("redis", storage),
+		doctor.SigningKey(signingKey),
+	)
+
+	write := report.WriteText
+	if *asJSON {
+		write = report.WriteJSON
+	}
+	if err := write(stdout); err != nil {
+		fmt.Fprintf(stderr, "evctl: %v\n", err)
+		return 2
+	}
+
+	if !report.OK() {
+		return 1
+	}
+
+	return 0
+}
+
+// runPlan implements the plan subcommand.
+func runPlan(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("plan", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	qps := fs.Float64("qps", 0, "validations started per second")
+	tokensPerValidation := fs.Float64("tokens-per-validation", 1, "average tokens issued per validation, including resends")
+	ttl := fs.Duration("ttl", 24*time.Hour, "token TTL")
+	verifyQPS := fs.Float64("verify-qps", 0, "verification requests per second")
+	codecName := fs.String("codec", "json", "token record codec: json or protobuf")
+	keyPrefix := fs.String("key-prefix", "", "Redis key prefix")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	codec, err := parseCodec(*codecName)
+	if err != nil {
+		fmt.Fprintf(stderr, "evctl: %v\n", err)
+		return 2
+	}
+
+	storage := redisstorage.New(nil, redisstorage.WithCodec(codec), redisstorage.WithKeyPrefix(*keyPrefix))
+	plan, err := storage.EstimateCapacity(redisstorage.CapacityInput{
+		ValidationsPerSecond:   *qps,
+		TokensPerValidation:    *tokensPerValidation,
+		TokenTTL:               *ttl,
+		VerificationsPerSecond: *verifyQPS,
+	})
+	if err != nil {
+		fmt.Fprintf(stderr, "evctl: %v\n", err)
+		return 2
+	}
+
+	fmt.Fprint(stdout, plan)
+
+	return 0
+}
+
+// runRepairIndex implements the repair-index subcommand.
+func runRepairIndex(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("repair-index", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	redisAddr := fs.String("redis-addr", "", "address of the Redis token storage to repair")
+	codecName := fs.String("codec", "json", "token record codec: json or protobuf")
+	keyPrefix := fs.String("key-prefix", "", "Redis key prefix")
+	hashTags := fs.Bool("hash-tags", false, "keys use Redis Cluster hash tags")
+	dryRun := fs.Bool("dry-run", false, "report drift without repairing it")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if *redisAddr == "" {
+		fmt.Fprintln(stderr, "evctl: -redis-addr is required")
+		return 2
+	}
+
+	codec, err := parseCodec(*codecName)
+	if err != nil {
+		fmt.Fprintf(stderr, "evctl: %v\n", err)
+		return 2
+	}
+
+	client := redis.NewClient(&redis.Options{Addr: *redisAddr})
+	defer client.Close()
+
+	opts := []redisstorage.Option{
+		redisstorage.WithCodec(codec),
+		redisstorage.WithKeyPrefix(*keyPrefix),
+		redisstorage.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+	}
+	if *hashTags {
+		opts = append(opts, redisstorage.WithHashTags())
+	}
+	storage := redisstorage.New(client, opts...)
+
+	reconcile := storage.RepairIndex
+	if *dryRun {
+		reconcile = storage.CheckIndex
+	}
+	report, err := reconcile(ctx)
+	if err != nil {
+		fmt.Fprintf(stderr, "evctl: %v\n", err)
+		return 2
+	}
+
+	fmt.Fprintf(stdout, "tokens scanned:     %d\n", report.TokensScanned)
+	fmt.Fprintf(stdout, "missing entries:    %d\n", report.MissingEntries)
+	fmt.Fprintf(stdout, "stale entries:      %d\n", report.StaleEntries)
+	fmt.Fprintf(stdout, "unreadable records: %d\n", report.UnreadableRecords)
+
+	switch {
+	case *dryRun && report.Drift() > 0:
+		fmt.Fprintln(stdout, "index drift found; rerun without -dry-run to repair it")
+		return 1
+	case !*dryRun && report.Drift() > 0:
+		fmt.Fprintln(stdout, "index repaired")
+	}
+
+	return 0
+}
+
+// parseCodec returns the token record codec named by a -codec flag.
+func parseCodec(name string) (token.Codec, error) {
+	switch name {
+	case "json":
+		return token.JSONCodec, nil
+	case "protobuf":
+		return protobuf.New(), nil
+	default:
+		return nil, fmt.Errorf("unknown codec %q", name)
+	}
+}
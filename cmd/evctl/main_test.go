@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+	redisstorage "github.com/jaeyeom/email-validator-grpc-mcp/token/storage/redis"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestRun_Usage(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"no subcommand", nil},
+		{"unknown subcommand", []string{"serve"}},
+		{"doctor unknown flag", []string{"doctor", "-verbose"}},
+		{"plan unknown flag", []string{"plan", "-verbose"}},
+		{"plan malformed flag", []string{"plan", "-qps", "many"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var stdout, stderr bytes.Buffer
+			if code := run(context.Background(), tt.args, &stdout, &stderr); code != 2 {
+				t.Errorf("run() = %d, want 2", code)
+			}
+			if stdout.Len() != 0 {
+				t.Errorf("stdout = %q, want empty", stdout.String())
+			}
+			if stderr.Len() == 0 {
+				t.Error("stderr is empty, want usage")
+			}
+		})
+	}
+}
+
+func TestRun_Doctor(t *testing.T) {
+	t.Parallel()
+
+	mr := miniredis.RunT(t)
+	down := miniredis.RunT(t)
+	downAddr := down.Addr()
+	down.Close()
+
+	dir := t.TempDir()
+	writeKey := func(name, key string) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(key+"\n"), 0o600); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+		return path
+	}
+	goodKey := writeKey("good", strings.Repeat("k", token.MinSigningKeyLength))
+	shortKey := writeKey("short", "short")
+
+	tests := []struct {
+		name     string
+		args     []string
+		wantCode int
+		wantOut  []string
+	}{
+		{"nothing configured", []string{"doctor"}, 0, []string{"[SKIP] storage round trip: redis", "[SKIP] link token signing key"}},
+		{"json", []string{"doctor", "-json"}, 0, []string{`"name": "storage round trip: redis"`, `"status": "SKIP"`}},
+		{"redis reachable", []string{"doctor", "-redis-addr", mr.Addr()}, 0, []string{"[PASS] storage round trip: redis"}},
+		{"redis down", []string{"doctor", "-redis-addr", downAddr}, 1, []string{"[FAIL] storage round trip: redis", "hint: "}},
+		{"signing key", []string{"doctor", "-signing-key-file", goodKey}, 0, []string{"[PASS] link token signing key"}},
+		{"short signing key", []string{"doctor", "-signing-key-file", shortKey}, 1, []string{"[FAIL] link token signing key"}},
+		{"missing signing key file", []string{"doctor", "-signing-key-file", filepath.Join(dir, "missing")}, 2, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var stdout, stderr bytes.Buffer
+			if code := run(context.Background(), tt.args, &stdout, &stderr); code != tt.wantCode {
+				t.Errorf("run() = %d, want %d; stderr: %s", code, tt.wantCode, stderr.String())
+			}
+			for _, want := range tt.wantOut {
+				if !strings.Contains(stdout.String(), want) {
+					t.Errorf("output %q does not contain %q", stdout.String(), want)
+				}
+			}
+		})
+	}
+}
+
+func TestRun_Plan(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		args     []string
+		wantCode int
+		wantOut  []string
+	}{
+		{"defaults", []string{"plan"}, 0, []string{"live tokens:      0\n"}},
+		{"workload", []string{"plan", "-qps", "10", "-ttl", "1h"}, 0, []string{"live tokens:      36000\n", "keys:             72000\n", "memory:"}},
+		{"tokens per validation", []string{"plan", "-qps", "10", "-ttl", "1h", "-tokens-per-validation", "2"}, 0, []string{"live tokens:      72000\n"}},
+		{"protobuf codec", []string{"plan", "-qps", "10", "-codec", "protobuf"}, 0, []string{"record size:"}},
+		{"unknown codec", []string{"plan", "-codec", "xml"}, 2, nil},
+		{"negative rate", []string{"plan", "-qps", "-1"}, 2, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var stdout, stderr bytes.Buffer
+			if code := run(context.Background(), tt.args, &stdout, &stderr); code != tt.wantCode {
+				t.Errorf("run() = %d, want %d; stderr: %s", code, tt.wantCode, stderr.String())
+			}
+			for _, want := range tt.wantOut {
+				if !strings.Contains(stdout.String(), want) {
+					t.Errorf("output %q does not contain %q", stdout.String(), want)
+				}
+			}
+			if tt.wantCode != 0 && stdout.Len() != 0 {
+				t.Errorf("stdout = %q, want empty on failure", stdout.String())
+			}
+		})
+	}
+}
+
+func TestRun_RepairIndex(t *testing.T) {
+	t.Parallel()
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	ctx := context.Background()
+	storage := redisstorage.New(client)
+	if err := storage.Store(ctx, token.New("repair-a", token.TypeLink, "validation-repair", time.Hour)); err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+	// Drop the token from its index
+	mr.Del("validation:validation-repair")
+
+	tests := []struct {
+		name     string
+		args     []string
+		wantCode int
+		wantOut  string
+	}{
+		{"missing address", []string{"repair-index"}, 2, ""},
+		{"unknown codec", []string{"repair-index", "-redis-addr", mr.Addr(), "-codec", "xml"}, 2, ""},
+		{"dry run finds drift", []string{"repair-index", "-redis-addr", mr.Addr(), "-dry-run"}, 1, "missing entries:    1"},
+		{"repair", []string{"repair-index", "-redis-addr", mr.Addr()}, 0, "index repaired"},
+		{"dry run after repair", []string{"repair-index", "-redis-addr", mr.Addr(), "-dry-run"}, 0, "missing entries:    0"},
+	}
+
+	// The cases run in order against the same Redis
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		if code := run(ctx, tt.args, &stdout, &stderr); code != tt.wantCode {
+			t.Errorf("%s: run() = %d, want %d; stderr: %s", tt.name, code, tt.wantCode, stderr.String())
+		}
+		if !strings.Contains(stdout.String(), tt.wantOut) {
+			t.Errorf("%s: output %q does not contain %q", tt.name, stdout.String(), tt.wantOut)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+// Package ctxkeys provides typed context keys and accessors for request-scoped
+// identity shared across interceptors, the token manager, hooks, and storage
+// decorators.
+package ctxkeys
+
+import "context"
+
+// key is an unexported type for context keys defined in this package, which
+// prevents collisions with keys defined in other packages.
+type key int
+
+const (
+	tenantKey key = iota
+	callerKey
+	requestIDKey
+)
+
+// WithTenant returns a copy of ctx carrying the given tenant ID.
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantKey, tenantID)
+}
+
+// TenantFrom returns the tenant ID stored in ctx, if any.
+func TenantFrom(ctx context.Context) (string, bool) {
+	tenantID, ok := ctx.Value(tenantKey).(string)
+	return tenantID, ok && tenantID != ""
+}
+
+// WithCaller returns a copy of ctx carrying the identity of the authenticated
+// caller, such as an API key ID or service account name.
+func WithCaller(ctx context.Context, caller string) context.Context {
+	return context.WithValue(ctx, callerKey, caller)
+}
+
+// CallerFrom returns the caller identity stored in ctx, if any.
+func CallerFrom(ctx context.Context) (string, bool) {
+	caller, ok := ctx.Value(callerKey).(string)
+	return caller, ok && caller != ""
+}
+
+// WithRequestID returns a copy of ctx carrying the given request ID.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+// RequestIDFrom returns the request ID stored in ctx, if any.
+func RequestIDFrom(ctx context.Context) (string, bool) {
+	requestID, ok := ctx.Value(requestIDKey).(string)
+	return requestID, ok && requestID != ""
+}
+
+// LogAttrs returns slog key-value pairs for every identity value present in
+// ctx, suitable for passing to slog.Logger.With.
+func LogAttrs(ctx context.Context) []any {
+	var attrs []any
+
+	if tenantID, ok := TenantFrom(ctx); ok {
+		attrs = append(attrs, "tenant_id", tenantID)
+	}
+
+	if caller, ok := CallerFrom(ctx); ok {
+		attrs = append(attrs, "caller", caller)
+	}
+
+	if requestID, ok := RequestIDFrom(ctx); ok {
+		attrs = append(attrs, "request_id", requestID)
+	}
+
+	return attrs
+}
@@ -0,0 +1,60 @@
+package ctxkeys
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestAccessors(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	if _, ok := TenantFrom(ctx); ok {
+		t.Error("TenantFrom() on empty context reported a tenant")
+	}
+	if _, ok := CallerFrom(ctx); ok {
+		t.Error("CallerFrom() on empty context reported a caller")
+	}
+	if _, ok := RequestIDFrom(ctx); ok {
+		t.Error("RequestIDFrom() on empty context reported a request ID")
+	}
+
+	ctx = WithTenant(ctx, "tenant-1")
+	ctx = WithCaller(ctx, "api-key-7")
+	ctx = WithRequestID(ctx, "req-42")
+
+	if got, ok := TenantFrom(ctx); !ok || got != "tenant-1" {
+		t.Errorf("TenantFrom() = %q, %v, want tenant-1, true", got, ok)
+	}
+	if got, ok := CallerFrom(ctx); !ok || got != "api-key-7" {
+		t.Errorf("CallerFrom() = %q, %v, want api-key-7, true", got, ok)
+	}
+	if got, ok := RequestIDFrom(ctx); !ok || got != "req-42" {
+		t.Errorf("RequestIDFrom() = %q, %v, want req-42, true", got, ok)
+	}
+}
+
+func TestEmptyValuesAreAbsent(t *testing.T) {
+	t.Parallel()
+
+	ctx := WithTenant(context.Background(), "")
+	if _, ok := TenantFrom(ctx); ok {
+		t.Error("TenantFrom() reported an empty tenant as present")
+	}
+}
+
+func TestLogAttrs(t *testing.T) {
+	t.Parallel()
+
+	if attrs := LogAttrs(context.Background()); len(attrs) != 0 {
+		t.Errorf("LogAttrs() on empty context = %v, want none", attrs)
+	}
+
+	ctx := WithCaller(WithTenant(context.Background(), "tenant-1"), "api-key-7")
+	want := []any{"tenant_id", "tenant-1", "caller", "api-key-7"}
+	if got := LogAttrs(ctx); !slices.Equal(got, want) {
+		t.Errorf("LogAttrs() = %v, want %v", got, want)
+	}
+}
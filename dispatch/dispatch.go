@@ -0,0 +1,295 @@
+// Package dispatch runs Manager lifecycle hooks asynchronously while keeping
+// the events of each validation in order.
+//
+// Hooks passed to token.WithHooks or validation.WithHooks run on the
+// request goroutine. Integrations
+// such as webhooks or event publishers are better run in the background, but
+// running each event on its own goroutine lets a "verified" event overtake
+// the "created" event of the same validation. A Dispatcher queues events per
+// validation and delivers each queue serially, so events of one validation
+// are delivered in the order the Manager fired them while different
+// validations proceed concurrently.
+package dispatch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"sync"
+	"sync/atomic"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+	"github.com/jaeyeom/email-validator-grpc-mcp/validation"
+)
+
+// DefaultBufferSize is the default number of events a single validation's
+// queue holds before firing a hook blocks.
+const DefaultBufferSize = 64
+
+// ErrClosed is returned by Close when the Dispatcher is already closed.
+var ErrClosed = errors.New("dispatcher closed")
+
+// Option is a functional option for configuring Dispatcher.
+type Option func(*Dispatcher)
+
+// WithLogger sets the logger used to report dropped events.
+func WithLogger(logger *slog.Logger) Option {
+	return func(d *Dispatcher) {
+		d.logger = logger
+	}
+}
+
+// WithBufferSize sets how many undelivered events a single validation's
+// queue holds. When a queue is full, firing a hook blocks until the queue
+// has room or the hook's context is done, in which case the event is
+// dropped.
+func WithBufferSize(size int) Option {
+	return func(d *Dispatcher) {
+		if size > 0 {
+			d.bufferSize = size
+		}
+	}
+}
+
+// Dispatcher delivers hook events asynchronously, in order per validation.
+type Dispatcher struct {
+	hooks      token.Hooks
+	logger     *slog.Logger
+	bufferSize int
+
+	mu     sync.Mutex
+	queues map[string]*queue
+	closed bool
+	wg     sync.WaitGroup
+
+	dropped atomic.Int64
+}
+
+// queue holds the undelivered events of one validation. A queue exists
+// while it has a worker delivering its events.
+type queue struct {
+	events []event
+
+	// space is closed and replaced whenever an event is taken from the
+	// queue, waking callers blocked on a full queue.
+	space chan struct{}
+}
+
+// event is a hook call waiting to be delivered.
+type event struct {
+	ctx context.Context
+	// key is the key of the queue the event belongs to.
+	key          string
+	validationID string
+	call         func(context.Context)
+}
+
+// New creates a Dispatcher that delivers events to hooks.
+func New(hooks token.Hooks, opts ...Option) *Dispatcher {
+	d := &Dispatcher{
+		hooks:      hooks,
+		logger:     slog.Default(),
+		bufferSize: DefaultBufferSize,
+		queues:     make(map[string]*queue),
+	}
+
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	return d
+}
+
+// Hooks returns Manager hooks that queue events for delivery to the hooks
+// the Dispatcher was created with. Only hooks that are set are queued.
+func (d *Dispatcher) Hooks() token.Hooks {
+	return token.Hooks{
+		OnCreated:     d.enqueuer(d.hooks.OnCreated),
+		OnVerified:    d.enqueuer(d.hooks.OnVerified),
+		OnExpired:     d.enqueuer(d.hooks.OnExpired),
+		OnInvalidated: d.enqueuer(d.hooks.OnInvalidated),
+	}
+}
+
+// enqueuer returns a hook that queues calls to hook, or nil if hook is nil.
+func (d *Dispatcher) enqueuer(hook token.HookFunc) token.HookFunc {
+	if hook == nil {
+		return nil
+	}
+
+	return func(ctx context.Context, t *token.Token) {
+		t = clone(t)
+		d.enqueue(ctx, event{
+			// The request may finish before the hook runs, but the hook
+			// still needs the request's values, such as the tenant.
+			ctx:          context.WithoutCancel(ctx),
+			key:          queueKey(t),
+			validationID: t.ValidationID,
+			call:         func(ctx context.Context) { hook(ctx, t) },
+		})
+	}
+}
+
+// ValidationHooks returns validation Manager hooks that queue events for
+// delivery to hooks. They share the queues of the token hooks, so the events
+// of a validation and of its tokens are delivered in the order they fired.
+// Only hooks that are set are queued.
+func (d *Dispatcher) ValidationHooks(hooks validation.Hooks) validation.Hooks {
+	return validation.Hooks{
+		OnCreated:  d.validationEnqueuer(hooks.OnCreated),
+		OnVerified: d.validationEnqueuer(hooks.OnVerified),
+		OnExpired:  d.validationEnqueuer(hooks.OnExpired),
+	}
+}
+
+// validationEnqueuer returns a hook that queues calls to hook, or nil if hook
+// is nil.
+func (d *Dispatcher) validationEnqueuer(hook validation.HookFunc) validation.HookFunc {
+	if hook == nil {
+		return nil
+	}
+
+	return func(ctx context.Context, v *validation.Validation) {
+		v = v.Clone()
+		d.enqueue(ctx, event{
+			ctx:          context.WithoutCancel(ctx),
+			key:          "validation:" + v.ID,
+			validationID: v.ID,
+			call:         func(ctx context.Context) { hook(ctx, v) },
+		})
+	}
+}
+
+// queueKey returns the key of the queue an event belongs to. Some events
+// carry only the token value and type; they are ordered per token instead.
+func queueKey(t *token.Token) string {
+	if t.ValidationID != "" {
+		return "validation:" + t.ValidationID
+	}
+
+	return fmt.Sprintf("token:%s:%d", t.Value, t.Type)
+}
+
+// enqueue appends ev to its queue, starting a worker for the queue if it has
+// none, and blocks while the queue is full.
+func (d *Dispatcher) enqueue(ctx context.Context, ev event) {
+	for {
+		d.mu.Lock()
+		if d.closed {
+			d.mu.Unlock()
+			d.drop(ev, ErrClosed)
+			return
+		}
+
+		q := d.queues[ev.key]
+		if q == nil {
+			q = &queue{space: make(chan struct{})}
+			d.queues[ev.key] = q
+			d.wg.Add(1)
+			go d.work(ev.key, q)
+		}
+
+		if len(q.events) < d.bufferSize {
+			q.events = append(q.events, ev)
+			d.mu.Unlock()
+			return
+		}
+
+		space := q.space
+		d.mu.Unlock()
+
+		select {
+		case <-space:
+		case <-ctx.Done():
+			d.drop(ev, ctx.Err())
+			return
+		}
+	}
+}
+
+// work delivers the events of q in order until it is empty.
+func (d *Dispatcher) work(key string, q *queue) {
+	defer d.wg.Done()
+
+	for {
+		d.mu.Lock()
+		if len(q.events) == 0 {
+			delete(d.queues, key)
+			d.mu.Unlock()
+			return
+		}
+
+		ev := q.events[0]
+		q.events[0] = event{}
+		q.events = q.events[1:]
+		close(q.space)
+		q.space = make(chan struct{})
+		d.mu.Unlock()
+
+		ev.call(ev.ctx)
+	}
+}
+
+// drop records an event that could not be queued.
+func (d *Dispatcher) drop(ev event, err error) {
+	d.dropped.Add(1)
+	d.logger.Warn("dropped hook event",
+		"validation_id", ev.validationID,
+		"error", err)
+}
+
+// Dropped returns the number of events dropped because their queue stayed
+// full until the hook's context was done, or because the Dispatcher was
+// closed.
+func (d *Dispatcher) Dropped() int64 {
+	return d.dropped.Load()
+}
+
+// Pending returns the number of queued events not yet being delivered.
+func (d *Dispatcher) Pending() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	n := 0
+	for _, q := range d.queues {
+		n += len(q.events)
+	}
+
+	return n
+}
+
+// Close stops accepting events and waits until the queued ones have been
+// delivered or ctx is done. Events fired after Close are dropped.
+func (d *Dispatcher) Close(ctx context.Context) error {
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return ErrClosed
+	}
+	d.closed = true
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("context error: %w", ctx.Err())
+	}
+}
+
+// clone returns a copy of t, so hooks running later see the token as it was
+// when the event fired.
+func clone(t *token.Token) *token.Token {
+	c := *t
+	c.Metadata = maps.Clone(t.Metadata)
+
+	return &c
+}
@@ -0,0 +1,283 @@
+package dispatch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+	"github.com/jaeyeom/email-validator-grpc-mcp/token/storage/memory"
+	"github.com/jaeyeom/email-validator-grpc-mcp/validation"
+)
+
+// recorder collects delivered events per validation.
+type recorder struct {
+	mu     sync.Mutex
+	events map[string][]string
+}
+
+func (l *recorder) hook(kind string) token.HookFunc {
+	return func(_ context.Context, t *token.Token) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		if l.events == nil {
+			l.events = make(map[string][]string)
+		}
+		l.events[t.ValidationID] = append(l.events[t.ValidationID], kind+":"+t.Value)
+	}
+}
+
+func (l *recorder) get(validationID string) []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return slices.Clone(l.events[validationID])
+}
+
+func TestDispatcher_OrderPerValidation(t *testing.T) {
+	t.Parallel()
+
+	const validations, perValidation = 20, 50
+
+	var delivered recorder
+	record := delivered.hook("event")
+	d := New(token.Hooks{
+		OnCreated: func(ctx context.Context, tkn *token.Token) {
+			// Vary delivery time so that unordered delivery would show
+			n, _ := strconv.Atoi(tkn.Value)
+			if n%7 == 0 {
+				time.Sleep(time.Millisecond)
+			}
+			record(ctx, tkn)
+		},
+	}, WithBufferSize(8))
+	hooks := d.Hooks()
+
+	var wg sync.WaitGroup
+	for v := range validations {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range perValidation {
+				hooks.OnCreated(context.Background(), &token.Token{
+					Value:        strconv.Itoa(i),
+					ValidationID: fmt.Sprintf("validation-%d", v),
+				})
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err := d.Close(context.Background()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	for v := range validations {
+		got := delivered.get(fmt.Sprintf("validation-%d", v))
+		if len(got) != perValidation {
+			t.Fatalf("validation-%d received %d events, want %d", v, len(got), perValidation)
+		}
+		for i, e := range got {
+			if want := "event:" + strconv.Itoa(i); e != want {
+				t.Fatalf("validation-%d event %d = %q, want %q", v, i, e, want)
+			}
+		}
+	}
+	if d.Dropped() != 0 {
+		t.Errorf("Dropped() = %d, want 0", d.Dropped())
+	}
+}
+
+func TestDispatcher_ValidationsDeliveredConcurrently(t *testing.T) {
+	t.Parallel()
+
+	otherDelivered := make(chan struct{})
+	d := New(token.Hooks{
+		OnCreated: func(_ context.Context, tkn *token.Token) {
+			if tkn.ValidationID == "slow" {
+				<-otherDelivered
+				return
+			}
+			close(otherDelivered)
+		},
+	})
+	hooks := d.Hooks()
+
+	hooks.OnCreated(context.Background(), &token.Token{ValidationID: "slow"})
+	hooks.OnCreated(context.Background(), &token.Token{ValidationID: "fast"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := d.Close(ctx); err != nil {
+		t.Fatalf("Close() error = %v, a slow validation blocked another", err)
+	}
+}
+
+func TestDispatcher_BoundedBuffer(t *testing.T) {
+	t.Parallel()
+
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	d := New(token.Hooks{
+		OnVerified: func(context.Context, *token.Token) {
+			select {
+			case started <- struct{}{}:
+			default:
+			}
+			<-release
+		},
+	}, WithBufferSize(1))
+	hooks := d.Hooks()
+	tkn := &token.Token{ValidationID: "validation-full"}
+
+	// The first event is being delivered and the second fills the buffer
+	hooks.OnVerified(context.Background(), tkn)
+	<-started
+	hooks.OnVerified(context.Background(), tkn)
+	if got := d.Pending(); got != 1 {
+		t.Errorf("Pending() = %d, want 1", got)
+	}
+
+	// A third blocks until its context is done and is then dropped
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	hooks.OnVerified(ctx, tkn)
+	if got := d.Dropped(); got != 1 {
+		t.Errorf("Dropped() = %d, want 1", got)
+	}
+
+	// A blocked caller proceeds once the queue has room
+	queued := make(chan struct{})
+	go func() {
+		hooks.OnVerified(context.Background(), tkn)
+		close(queued)
+	}()
+	release <- struct{}{}
+	<-queued
+
+	close(release)
+	if err := d.Close(context.Background()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if got := d.Dropped(); got != 1 {
+		t.Errorf("Dropped() after Close() = %d, want 1", got)
+	}
+}
+
+func TestDispatcher_Close(t *testing.T) {
+	t.Parallel()
+
+	var delivered recorder
+	d := New(token.Hooks{OnCreated: delivered.hook("created")})
+	hooks := d.Hooks()
+
+	if hooks.OnVerified != nil {
+		t.Error("Hooks() set OnVerified, want nil for an unset hook")
+	}
+
+	hooks.OnCreated(context.Background(), &token.Token{Value: "before", ValidationID: "v"})
+	if err := d.Close(context.Background()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	hooks.OnCreated(context.Background(), &token.Token{Value: "after", ValidationID: "v"})
+
+	if got, want := delivered.get("v"), []string{"created:before"}; !slices.Equal(got, want) {
+		t.Errorf("delivered = %v, want %v", got, want)
+	}
+	if got := d.Dropped(); got != 1 {
+		t.Errorf("Dropped() = %d, want 1", got)
+	}
+	if err := d.Close(context.Background()); !errors.Is(err, ErrClosed) {
+		t.Errorf("second Close() error = %v, want %v", err, ErrClosed)
+	}
+}
+
+func TestDispatcher_ManagerHooks(t *testing.T) {
+	t.Parallel()
+
+	var delivered recorder
+	onCreated := delivered.hook("created")
+	d := New(token.Hooks{
+		// A slow integration must not let the verification overtake it
+		OnCreated: func(ctx context.Context, tkn *token.Token) {
+			time.Sleep(10 * time.Millisecond)
+			onCreated(ctx, tkn)
+		},
+		OnVerified:    delivered.hook("verified"),
+		OnInvalidated: delivered.hook("invalidated"),
+	})
+	manager := token.NewManager(memory.New(), token.WithHooks(d.Hooks()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	tkn, err := manager.CreateLinkToken(ctx, "validation-manager")
+	if err != nil {
+		t.Fatalf("CreateLinkToken() error = %v", err)
+	}
+	if _, err := manager.VerifyToken(ctx, tkn.Value, token.TypeLink); err != nil {
+		t.Fatalf("VerifyToken() error = %v", err)
+	}
+	if err := manager.InvalidateValidation(ctx, "validation-manager"); err != nil {
+		t.Fatalf("InvalidateValidation() error = %v", err)
+	}
+
+	// Delivery does not depend on the request context
+	cancel()
+	if err := d.Close(context.Background()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	want := []string{"created:" + tkn.Value, "verified:" + tkn.Value, "invalidated:"}
+	if got := delivered.get("validation-manager"); !slices.Equal(got, want) {
+		t.Errorf("delivered = %v, want %v", got, want)
+	}
+}
+
+func TestDispatcher_ValidationHooks(t *testing.T) {
+	t.Parallel()
+
+	var delivered recorder
+	onCreated := delivered.hook("token")
+	d := New(token.Hooks{
+		// The validation event must wait for the token event of the same
+		// validation
+		OnCreated: func(ctx context.Context, tkn *token.Token) {
+			time.Sleep(10 * time.Millisecond)
+			onCreated(ctx, tkn)
+		},
+	})
+	record := func(kind string) validation.HookFunc {
+		return func(ctx context.Context, v *validation.Validation) {
+			delivered.hook(kind)(ctx, &token.Token{Value: v.State.String(), ValidationID: v.ID})
+		}
+	}
+	hooks := d.ValidationHooks(validation.Hooks{
+		OnCreated:  record("validation"),
+		OnVerified: record("validation"),
+	})
+	if hooks.OnExpired != nil {
+		t.Error("ValidationHooks() set OnExpired, want nil for an unset hook")
+	}
+
+	ctx := context.Background()
+	d.Hooks().OnCreated(ctx, &token.Token{Value: "tkn", ValidationID: "v"})
+	v := &validation.Validation{ID: "v", State: validation.StatePending}
+	hooks.OnCreated(ctx, v)
+	// Hooks see the validation as it was when the event fired
+	v.State = validation.StateVerified
+	hooks.OnVerified(ctx, v)
+
+	if err := d.Close(ctx); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	want := []string{"token:tkn", "validation:PENDING", "validation:VERIFIED"}
+	if got := delivered.get("v"); !slices.Equal(got, want) {
+		t.Errorf("delivered = %v, want %v", got, want)
+	}
+}
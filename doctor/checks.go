@@ -0,0 +1,122 @@
+package doctor
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+	"github.com/jaeyeom/email-validator-grpc-mcp/token/codec/encryption"
+)
+
+// probeTTL is the lifetime of probe tokens written by StorageRoundTrip, so
+// that a probe left behind by an interrupted check expires on its own.
+const probeTTL = time.Minute
+
+// StorageRoundTrip stores, retrieves, and consumes a probe token, checking
+// that the backend is reachable and honors single use.
+func StorageRoundTrip(name string, s token.Storage) Check {
+	return Check{
+		Name: "storage round trip: " + name,
+		Hint: "check that the storage backend is running, reachable from this host, and that its credentials are correct",
+		Run: func(ctx context.Context) error {
+			if s == nil {
+				return fmt.Errorf("%w: no storage configured", ErrSkipped)
+			}
+
+			suffix, err := randomHex(8)
+			if err != nil {
+				return err
+			}
+
+			probe := token.New("doctor-"+suffix, token.TypeLink, "doctor-"+suffix, probeTTL)
+			if err := s.Store(ctx, probe); err != nil {
+				return fmt.Errorf("store failed: %w", err)
+			}
+
+			got, err := s.Retrieve(ctx, probe.Value, probe.Type)
+			if err != nil {
+				return fmt.Errorf("retrieve failed: %w", err)
+			}
+			if got.ValidationID != probe.ValidationID {
+				return fmt.Errorf("retrieve returned validation ID %q, want %q", got.ValidationID, probe.ValidationID)
+			}
+
+			if _, err := s.Consume(ctx, probe.Value, probe.Type); err != nil {
+				return fmt.Errorf("consume failed: %w", err)
+			}
+			if _, err := s.Retrieve(ctx, probe.Value, probe.Type); !errors.Is(err, token.ErrTokenNotFound) {
+				return fmt.Errorf("consumed token still retrievable: %w", err)
+			}
+
+			return nil
+		},
+	}
+}
+
+// SigningKey checks that a link token signing key is long enough and signs
+// tokens that verify.
+func SigningKey(key []byte) Check {
+	return Check{
+		Name: "link token signing key",
+		Hint: fmt.Sprintf("provide a random key of at least %d bytes, for example from `openssl rand 32`", token.MinSigningKeyLength),
+		Run: func(_ context.Context) error {
+			if key == nil {
+				return fmt.Errorf("%w: no signing key configured", ErrSkipped)
+			}
+
+			signer, err := token.NewSignedGenerator(key)
+			if err != nil {
+				return fmt.Errorf("invalid signing key: %w", err)
+			}
+
+			value, err := signer.Sign(token.New("", token.TypeLink, "doctor", probeTTL))
+			if err != nil {
+				return fmt.Errorf("sign failed: %w", err)
+			}
+			if _, err := signer.Verify(value); err != nil {
+				return fmt.Errorf("verify failed: %w", err)
+			}
+
+			return nil
+		},
+	}
+}
+
+// EncryptionKeys checks that the current encryption key seals tokens that
+// can be opened again.
+func EncryptionKeys(keys encryption.KeyProvider) Check {
+	return Check{
+		Name: "token encryption keys",
+		Hint: "configure a current key ID that names a 16, 24, or 32 byte AES key",
+		Run: func(_ context.Context) error {
+			if keys == nil {
+				return fmt.Errorf("%w: token encryption not configured", ErrSkipped)
+			}
+
+			codec := encryption.New(nil, keys)
+			data, err := codec.Marshal(token.New("doctor", token.TypeLink, "doctor", probeTTL))
+			if err != nil {
+				return fmt.Errorf("encrypt failed: %w", err)
+			}
+			if _, err := codec.Unmarshal(data); err != nil {
+				return fmt.Errorf("decrypt failed: %w", err)
+			}
+
+			return nil
+		},
+	}
+}
+
+// randomHex returns n random bytes, hex encoded.
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate random bytes: %w", err)
+	}
+
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,133 @@
+// Package doctor runs self-diagnostic checks against a configured stack and
+// reports, for each one, whether it passed and how to fix it if not.
+package doctor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// DefaultCheckTimeout is the default time allowed for each check.
+const DefaultCheckTimeout = 5 * time.Second
+
+// ErrSkipped is returned by a check that does not apply to the current
+// configuration.
+var ErrSkipped = errors.New("check skipped")
+
+// Status is the outcome of a check.
+type Status string
+
+// Check outcomes.
+const (
+	StatusPass Status = "PASS"
+	StatusFail Status = "FAIL"
+	StatusSkip Status = "SKIP"
+)
+
+// Check is a single diagnostic.
+type Check struct {
+	// Name identifies the check in the report.
+	Name string
+
+	// Hint suggests how to fix a failure.
+	Hint string
+
+	// Run performs the check. It returns nil on success and ErrSkipped if
+	// the check does not apply.
+	Run func(ctx context.Context) error
+}
+
+// Result is the outcome of one check.
+type Result struct {
+	Name     string        `json:"name"`
+	Status   Status        `json:"status"`
+	Detail   string        `json:"detail,omitempty"`
+	Hint     string        `json:"hint,omitempty"`
+	Duration time.Duration `json:"duration_ns"`
+}
+
+// Report collects check results in the order the checks ran.
+type Report struct {
+	Results []Result `json:"results"`
+}
+
+// OK reports whether no check failed.
+func (r Report) OK() bool {
+	for _, res := range r.Results {
+		if res.Status == StatusFail {
+			return false
+		}
+	}
+
+	return true
+}
+
+// WriteText writes a human-readable report to w.
+func (r Report) WriteText(w io.Writer) error {
+	for _, res := range r.Results {
+		line := fmt.Sprintf("[%s] %s (%s)", res.Status, res.Name, res.Duration.Round(time.Millisecond))
+		if res.Detail != "" {
+			line += ": " + res.Detail
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return fmt.Errorf("failed to write report: %w", err)
+		}
+		if res.Status == StatusFail && res.Hint != "" {
+			if _, err := fmt.Fprintf(w, "       hint: %s\n", res.Hint); err != nil {
+				return fmt.Errorf("failed to write report: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// WriteJSON writes the report to w as JSON.
+func (r Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(r); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+
+	return nil
+}
+
+// Run runs checks in order, giving each DefaultCheckTimeout. Every check
+// runs even if an earlier one failed, so a single report shows all
+// problems.
+func Run(ctx context.Context, checks ...Check) Report {
+	report := Report{Results: make([]Result, 0, len(checks))}
+
+	for _, check := range checks {
+		report.Results = append(report.Results, run(ctx, check))
+	}
+
+	return report
+}
+
+// run runs a single check.
+func run(ctx context.Context, check Check) Result {
+	ctx, cancel := context.WithTimeout(ctx, DefaultCheckTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check.Run(ctx)
+	res := Result{Name: check.Name, Status: StatusPass, Duration: time.Since(start)}
+
+	switch {
+	case errors.Is(err, ErrSkipped):
+		res.Status = StatusSkip
+		res.Detail = err.Error()
+	case err != nil:
+		res.Status = StatusFail
+		res.Detail = err.Error()
+		res.Hint = check.Hint
+	}
+
+	return res
+}
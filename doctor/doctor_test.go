@@ -0,0 +1,101 @@
+package doctor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+	"github.com/jaeyeom/email-validator-grpc-mcp/token/codec/encryption"
+	"github.com/jaeyeom/email-validator-grpc-mcp/token/storage/memory"
+)
+
+// brokenStorage fails every Store call.
+type brokenStorage struct {
+	token.Storage
+}
+
+func (brokenStorage) Store(context.Context, *token.Token) error {
+	return errors.New("connection refused")
+}
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	report := Run(context.Background(),
+		Check{Name: "ok", Run: func(context.Context) error { return nil }},
+		Check{Name: "bad", Hint: "fix it", Run: func(context.Context) error { return errors.New("boom") }},
+		Check{Name: "n/a", Run: func(context.Context) error { return ErrSkipped }},
+	)
+
+	want := []Status{StatusPass, StatusFail, StatusSkip}
+	if len(report.Results) != len(want) {
+		t.Fatalf("Run() returned %d results, want %d", len(report.Results), len(want))
+	}
+	for i, res := range report.Results {
+		if res.Status != want[i] {
+			t.Errorf("Results[%d].Status = %v, want %v", i, res.Status, want[i])
+		}
+	}
+	if report.OK() {
+		t.Error("Report.OK() = true, want false")
+	}
+
+	var text bytes.Buffer
+	if err := report.WriteText(&text); err != nil {
+		t.Fatalf("WriteText() error = %v", err)
+	}
+	if !strings.Contains(text.String(), "[FAIL] bad") || !strings.Contains(text.String(), "hint: fix it") {
+		t.Errorf("WriteText() = %q, want the failure and its hint", text.String())
+	}
+
+	var decoded Report
+	var js bytes.Buffer
+	if err := report.WriteJSON(&js); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+	if err := json.Unmarshal(js.Bytes(), &decoded); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if decoded.Results[1].Hint != "fix it" {
+		t.Errorf("decoded hint = %q, want %q", decoded.Results[1].Hint, "fix it")
+	}
+}
+
+func TestChecks(t *testing.T) {
+	t.Parallel()
+
+	keys, err := encryption.NewStaticKeys("k1", map[string][]byte{"k1": make([]byte, 32)})
+	if err != nil {
+		t.Fatalf("NewStaticKeys() error = %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		check Check
+		want  Status
+	}{
+		{name: "memory storage", check: StorageRoundTrip("memory", memory.New()), want: StatusPass},
+		{name: "broken storage", check: StorageRoundTrip("broken", brokenStorage{memory.New()}), want: StatusFail},
+		{name: "no storage", check: StorageRoundTrip("none", nil), want: StatusSkip},
+		{name: "good signing key", check: SigningKey(make([]byte, token.MinSigningKeyLength)), want: StatusPass},
+		{name: "short signing key", check: SigningKey([]byte("short")), want: StatusFail},
+		{name: "no signing key", check: SigningKey(nil), want: StatusSkip},
+		{name: "encryption keys", check: EncryptionKeys(keys), want: StatusPass},
+		{name: "no encryption keys", check: EncryptionKeys(nil), want: StatusSkip},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			report := Run(context.Background(), tt.check)
+			if got := report.Results[0]; got.Status != tt.want {
+				t.Errorf("Status = %v (%s), want %v", got.Status, got.Detail, tt.want)
+			}
+		})
+	}
+}
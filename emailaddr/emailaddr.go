@@ -0,0 +1,179 @@
+// Package emailaddr canonicalizes email addresses, so that addresses
+// delivering to the same mailbox compare equal. Normalize lower-cases the
+// address, converts internationalized domain names to their ASCII
+// (punycode) form, and applies the folding rules of well-known mailbox
+// providers, such as Gmail ignoring dots and "+tag" suffixes.
+//
+// Normalized addresses are keys for deduplication, not addresses to send
+// to: a message must still go to the address as entered.
+package emailaddr
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Length limits of RFC 5321 and RFC 1035.
+const (
+	maxLocalLength  = 64
+	maxDomainLength = 253
+	maxLabelLength  = 63
+)
+
+// ErrInvalid is returned for strings that are not email addresses.
+var ErrInvalid = errors.New("invalid email address")
+
+// Provider describes how a mailbox provider folds equivalent addresses.
+type Provider struct {
+	// Domains are the ASCII domains the provider serves.
+	Domains []string
+
+	// CanonicalDomain, if set, replaces any of Domains, for providers
+	// serving the same mailboxes under several domains.
+	CanonicalDomain string
+
+	// IgnoreDots removes dots from the local part.
+	IgnoreDots bool
+
+	// TagSeparator, if set, starts a tag at the end of the local part that
+	// the provider ignores, such as "+" in "user+news@example.com".
+	TagSeparator string
+}
+
+// Well-known providers.
+var (
+	Gmail = Provider{
+		Domains:         []string{"gmail.com", "googlemail.com"},
+		CanonicalDomain: "gmail.com",
+		IgnoreDots:      true,
+		TagSeparator:    "+",
+	}
+
+	Outlook = Provider{
+		Domains:      []string{"outlook.com", "hotmail.com", "live.com"},
+		TagSeparator: "+",
+	}
+
+	Fastmail = Provider{
+		Domains:      []string{"fastmail.com"},
+		TagSeparator: "+",
+	}
+
+	Proton = Provider{
+		Domains:      []string{"proton.me", "protonmail.com"},
+		TagSeparator: "+",
+	}
+
+	Yahoo = Provider{
+		Domains:      []string{"yahoo.com"},
+		TagSeparator: "-",
+	}
+)
+
+// DefaultProviders are the providers the package-level Normalize applies.
+var DefaultProviders = []Provider{Gmail, Outlook, Fastmail, Proton, Yahoo}
+
+// Normalizer normalizes addresses with a set of provider rules.
+type Normalizer struct {
+	providers map[string]Provider
+}
+
+// New creates a Normalizer applying the rules of providers. Without
+// providers, only case and domain encoding are normalized.
+func New(providers ...Provider) *Normalizer {
+	n := &Normalizer{providers: make(map[string]Provider)}
+	for _, p := range providers {
+		for _, d := range p.Domains {
+			n.providers[strings.ToLower(d)] = p
+		}
+	}
+
+	return n
+}
+
+// defaultNormalizer applies DefaultProviders.
+var defaultNormalizer = New(DefaultProviders...)
+
+// Normalize normalizes addr with DefaultProviders.
+func Normalize(addr string) (string, error) {
+	return defaultNormalizer.Normalize(addr)
+}
+
+// Normalize returns the canonical form of addr, or an error wrapping
+// ErrInvalid. The local part is lower-cased, which RFC 5321 leaves to the
+// receiving server but every major provider does. The domain is
+// lower-cased and converted to ASCII, with full-width and ideographic dots
+// read as dots; no other Unicode mapping is applied.
+func (n *Normalizer) Normalize(addr string) (string, error) {
+	addr = strings.TrimSpace(addr)
+
+	at := strings.LastIndexByte(addr, '@')
+	if at < 0 {
+		return "", fmt.Errorf("%w: missing @", ErrInvalid)
+	}
+	local, domain := strings.ToLower(addr[:at]), addr[at+1:]
+
+	domain, err := normalizeDomain(domain)
+	if err != nil {
+		return "", err
+	}
+
+	if p, ok := n.providers[domain]; ok {
+		if p.TagSeparator != "" {
+			local, _, _ = strings.Cut(local, p.TagSeparator)
+		}
+		if p.IgnoreDots {
+			local = strings.ReplaceAll(local, ".", "")
+		}
+		if p.CanonicalDomain != "" {
+			domain = p.CanonicalDomain
+		}
+	}
+
+	if local == "" {
+		return "", fmt.Errorf("%w: empty local part", ErrInvalid)
+	}
+	if len(local) > maxLocalLength {
+		return "", fmt.Errorf("%w: local part longer than %d bytes", ErrInvalid, maxLocalLength)
+	}
+
+	return local + "@" + domain, nil
+}
+
+// domainDots replaces the dots UTS #46 treats as label separators.
+var domainDots = strings.NewReplacer("。", ".", "．", ".", "｡", ".")
+
+// normalizeDomain returns the lower-cased ASCII form of a domain.
+func normalizeDomain(domain string) (string, error) {
+	domain = strings.TrimSuffix(domainDots.Replace(strings.ToLower(domain)), ".")
+	if domain == "" {
+		return "", fmt.Errorf("%w: empty domain", ErrInvalid)
+	}
+
+	labels := strings.Split(domain, ".")
+	for i, label := range labels {
+		if label == "" {
+			return "", fmt.Errorf("%w: empty domain label", ErrInvalid)
+		}
+		if strings.ContainsAny(label, " \t@") {
+			return "", fmt.Errorf("%w: bad character in domain", ErrInvalid)
+		}
+
+		ascii, err := toASCII(label)
+		if err != nil {
+			return "", fmt.Errorf("%w: %w", ErrInvalid, err)
+		}
+		if len(ascii) > maxLabelLength {
+			return "", fmt.Errorf("%w: domain label longer than %d bytes", ErrInvalid, maxLabelLength)
+		}
+		labels[i] = ascii
+	}
+
+	domain = strings.Join(labels, ".")
+	if len(domain) > maxDomainLength {
+		return "", fmt.Errorf("%w: domain longer than %d bytes", ErrInvalid, maxDomainLength)
+	}
+
+	return domain, nil
+}
@@ -0,0 +1,90 @@
+package emailaddr
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		addr    string
+		want    string
+		wantErr bool
+	}{
+		{"User@Example.COM", "user@example.com", false},
+		{"  user@example.com. ", "user@example.com", false},
+		{"first.last+news@example.com", "first.last+news@example.com", false},
+		{"First.Last+news@GoogleMail.com", "firstlast@gmail.com", false},
+		{"f.i.r.s.t@gmail.com", "first@gmail.com", false},
+		{"user+tag@outlook.com", "user@outlook.com", false},
+		{"first.last@outlook.com", "first.last@outlook.com", false},
+		{"user-tag@yahoo.com", "user@yahoo.com", false},
+		{"user@bücher.example", "user@xn--bcher-kva.example", false},
+		{"user@BÜCHER.example", "user@xn--bcher-kva.example", false},
+		{"user@español。com", "user@xn--espaol-zwa.com", false},
+		{"user@xn--bcher-kva.example", "user@xn--bcher-kva.example", false},
+		{"\"quoted@local\"@example.com", "\"quoted@local\"@example.com", false},
+		{"no-at-sign", "", true},
+		{"@example.com", "", true},
+		{"user@", "", true},
+		{"user@example..com", "", true},
+		{"+tag@gmail.com", "", true},
+		{strings.Repeat("a", 65) + "@example.com", "", true},
+		{"user@" + strings.Repeat("a", 64) + ".com", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := Normalize(tt.addr)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalid) {
+					t.Errorf("Normalize(%q) = %q, %v, want %v", tt.addr, got, err, ErrInvalid)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("Normalize(%q) = %q, %v, want %q", tt.addr, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizer_Providers(t *testing.T) {
+	t.Parallel()
+
+	// Without provider rules, only case and encoding are normalized.
+	if got, err := New().Normalize("First.Last+tag@Gmail.com"); err != nil || got != "first.last+tag@gmail.com" {
+		t.Errorf("Normalize() without providers = %q, %v", got, err)
+	}
+
+	corp := Provider{Domains: []string{"corp.example", "mail.corp.example"}, CanonicalDomain: "corp.example", TagSeparator: "_"}
+	if got, err := New(corp).Normalize("J.Doe_x@Mail.Corp.Example"); err != nil || got != "j.doe@corp.example" {
+		t.Errorf("Normalize() with custom provider = %q, %v", got, err)
+	}
+}
+
+func TestEncodePunycode(t *testing.T) {
+	t.Parallel()
+
+	// Samples from RFC 3492 section 7.1.
+	tests := []struct {
+		in, want string
+	}{
+		{"bücher", "bcher-kva"},
+		{"münchen", "mnchen-3ya"},
+		{"他们为什么不说中文", "ihqwcrb4cv8a8dqg056pqjye"},
+		{"3年b組金八先生", "3b-ww4c5e180e575a65lsy2b"},
+		{"そのスピードで", "d9juau41awczczp"},
+	}
+
+	for _, tt := range tests {
+		if got, err := encodePunycode(tt.in); err != nil || got != tt.want {
+			t.Errorf("encodePunycode(%q) = %q, %v, want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package emailaddr
+
+import (
+	"errors"
+	"math"
+	"strings"
+)
+
+// errOverflow is returned when a label is too long to encode.
+var errOverflow = errors.New("punycode overflow")
+
+// Punycode parameters, from RFC 3492 section 5.
+const (
+	base        = 36
+	tMin        = 1
+	tMax        = 26
+	skew        = 38
+	damp        = 700
+	initialBias = 72
+	initialN    = 128
+)
+
+// toASCII converts a domain label to its ASCII form: the label itself if it
+// is ASCII, otherwise "xn--" followed by its punycode encoding.
+func toASCII(label string) (string, error) {
+	ascii := true
+	for i := range len(label) {
+		if label[i] >= 0x80 {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return label, nil
+	}
+
+	encoded, err := encodePunycode(label)
+	if err != nil {
+		return "", err
+	}
+
+	return "xn--" + encoded, nil
+}
+
+// encodePunycode encodes s as described in RFC 3492 section 6.3.
+func encodePunycode(s string) (string, error) {
+	runes := []rune(s)
+
+	var out strings.Builder
+	for _, r := range runes {
+		if r < 0x80 {
+			out.WriteRune(r)
+		}
+	}
+	b := out.Len()
+	h := b
+	if b > 0 {
+		out.WriteByte('-')
+	}
+
+	n, delta, bias := initialN, 0, initialBias
+	for h < len(runes) {
+		m := math.MaxInt32
+		for _, r := range runes {
+			if int(r) >= n && int(r) < m {
+				m = int(r)
+			}
+		}
+
+		if (m - n) > (math.MaxInt32-delta)/(h+1) {
+			return "", errOverflow
+		}
+		delta += (m - n) * (h + 1)
+		n = m
+
+		for _, r := range runes {
+			if int(r) < n {
+				delta++
+				if delta == math.MaxInt32 {
+					return "", errOverflow
+				}
+			}
+			if int(r) != n {
+				continue
+			}
+
+			q := delta
+			for k := base; ; k += base {
+				t := min(max(k-bias, tMin), tMax)
+				if q < t {
+					break
+				}
+				out.WriteByte(digit(t + (q-t)%(base-t)))
+				q = (q - t) / (base - t)
+			}
+			out.WriteByte(digit(q))
+
+			bias = adapt(delta, h+1, h == b)
+			delta = 0
+			h++
+		}
+
+		delta++
+		n++
+	}
+
+	return out.String(), nil
+}
+
+// adapt is the bias adaptation function of RFC 3492 section 6.1.
+func adapt(delta, numPoints int, first bool) int {
+	if first {
+		delta /= damp
+	} else {
+		delta /= 2
+	}
+	delta += delta / numPoints
+
+	k := 0
+	for delta > ((base-tMin)*tMax)/2 {
+		delta /= base - tMin
+		k += base
+	}
+
+	return k + (base-tMin+1)*delta/(delta+skew)
+}
+
+// digits are the basic code points of digit values 0 to 35.
+const digits = "abcdefghijklmnopqrstuvwxyz0123456789"
+
+// digit returns the basic code point of a digit value.
+func digit(d int) byte {
+	return digits[d]
+}
@@ -5,6 +5,7 @@ go 1.24.2
 require (
 	github.com/alicebob/miniredis/v2 v2.35.0
 	github.com/redis/go-redis/v9 v9.10.0
+	google.golang.org/protobuf v1.36.6
 )
 
 require (
@@ -0,0 +1,385 @@
+// Package idgen generates validation IDs. IDs are typed with a prefix, such
+// as "val_", so they are recognizable in logs, and embed their creation time
+// so they sort roughly by age. The body is a UUIDv7, a ULID, or a KSUID.
+package idgen
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+)
+
+// DefaultPrefix is the default prefix of validation IDs.
+const DefaultPrefix = "val_"
+
+// DefaultMaxAttempts is the default number of IDs NewUnique tries before
+// giving up.
+const DefaultMaxAttempts = 3
+
+var (
+	// ErrInvalidID is returned by Parse for IDs this generator could not
+	// have produced.
+	ErrInvalidID = errors.New("invalid ID")
+
+	// ErrCollision is returned by NewUnique when every generated ID was
+	// already in use.
+	ErrCollision = errors.New("generated ID already in use")
+)
+
+// Scheme is the format of the body of an ID.
+type Scheme int
+
+const (
+	// SchemeUUIDv7 renders IDs as RFC 9562 version 7 UUIDs in lower-case
+	// hyphenated form, with a millisecond timestamp and 74 random bits.
+	SchemeUUIDv7 Scheme = iota
+	// SchemeULID renders IDs as ULIDs: 26 characters of Crockford's base32
+	// holding a millisecond timestamp and 80 random bits.
+	SchemeULID
+	// SchemeKSUID renders IDs as KSUIDs: 27 base62 characters holding a
+	// timestamp in seconds and 128 random bits.
+	SchemeKSUID
+)
+
+// String returns the name of the scheme.
+func (s Scheme) String() string {
+	switch s {
+	case SchemeUUIDv7:
+		return "uuidv7"
+	case SchemeULID:
+		return "ulid"
+	case SchemeKSUID:
+		return "ksuid"
+	default:
+		return fmt.Sprintf("Scheme(%d)", int(s))
+	}
+}
+
+// ExistsFunc reports whether an ID is already in use.
+type ExistsFunc func(ctx context.Context, id string) (bool, error)
+
+// Option is a functional option for configuring Generator.
+type Option func(*Generator)
+
+// WithScheme sets the format of ID bodies. The default is SchemeUUIDv7.
+func WithScheme(scheme Scheme) Option {
+	return func(g *Generator) {
+		g.scheme = scheme
+	}
+}
+
+// WithPrefix sets the prefix of IDs. An empty prefix yields bare IDs.
+func WithPrefix(prefix string) Option {
+	return func(g *Generator) {
+		g.prefix = prefix
+	}
+}
+
+// WithClock sets the clock whose time IDs embed.
+func WithClock(clock token.Clock) Option {
+	return func(g *Generator) {
+		g.clock = clock
+	}
+}
+
+// WithMaxAttempts sets how many IDs NewUnique tries before giving up.
+func WithMaxAttempts(attempts int) Option {
+	return func(g *Generator) {
+		if attempts > 0 {
+			g.maxAttempts = attempts
+		}
+	}
+}
+
+// Generator generates IDs of one scheme and prefix.
+type Generator struct {
+	scheme      Scheme
+	prefix      string
+	clock       token.Clock
+	maxAttempts int
+}
+
+// New creates a Generator of "val_"-prefixed UUIDv7 IDs unless configured
+// otherwise.
+func New(opts ...Option) *Generator {
+	g := &Generator{
+		scheme:      SchemeUUIDv7,
+		prefix:      DefaultPrefix,
+		clock:       token.SystemClock,
+		maxAttempts: DefaultMaxAttempts,
+	}
+
+	for _, opt := range opts {
+		opt(g)
+	}
+
+	return g
+}
+
+// New returns a new ID.
+func (g *Generator) New() (string, error) {
+	now := g.clock.Now()
+
+	var body string
+	var err error
+	switch g.scheme {
+	case SchemeUUIDv7:
+		body, err = newUUIDv7(now)
+	case SchemeULID:
+		body, err = newULID(now)
+	case SchemeKSUID:
+		body, err = newKSUID(now)
+	default:
+		return "", fmt.Errorf("unknown ID scheme %s", g.scheme)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return g.prefix + body, nil
+}
+
+// NewUnique returns a new ID for which exists reports false. Random
+// collisions are vanishingly rare, so repeated collisions usually mean the
+// clock or random source is broken, and NewUnique gives up with
+// ErrCollision after the configured number of attempts.
+func (g *Generator) NewUnique(ctx context.Context, exists ExistsFunc) (string, error) {
+	for range g.maxAttempts {
+		if err := ctx.Err(); err != nil {
+			return "", fmt.Errorf("context error: %w", err)
+		}
+
+		id, err := g.New()
+		if err != nil {
+			return "", err
+		}
+
+		taken, err := exists(ctx, id)
+		if err != nil {
+			return "", fmt.Errorf("failed to check ID %s: %w", id, err)
+		}
+		if !taken {
+			return id, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w after %d attempts", ErrCollision, g.maxAttempts)
+}
+
+// Parse checks that id has the generator's prefix and scheme and returns the
+// time embedded in it.
+func (g *Generator) Parse(id string) (time.Time, error) {
+	body, ok := strings.CutPrefix(id, g.prefix)
+	if !ok {
+		return time.Time{}, fmt.Errorf("%w: missing prefix %q", ErrInvalidID, g.prefix)
+	}
+
+	var t time.Time
+	var err error
+	switch g.scheme {
+	case SchemeUUIDv7:
+		t, err = parseUUIDv7(body)
+	case SchemeULID:
+		t, err = parseULID(body)
+	case SchemeKSUID:
+		t, err = parseKSUID(body)
+	default:
+		err = fmt.Errorf("unknown ID scheme %s", g.scheme)
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %w", ErrInvalidID, err)
+	}
+
+	return t, nil
+}
+
+// StorageExists returns an ExistsFunc reporting IDs that already have tokens
+// in s.
+func StorageExists(s token.Storage) ExistsFunc {
+	return func(ctx context.Context, id string) (bool, error) {
+		tokens, err := s.ListByValidationID(ctx, id)
+		if err != nil {
+			return false, fmt.Errorf("failed to list tokens: %w", err)
+		}
+
+		return len(tokens) > 0, nil
+	}
+}
+
+// randomBytes fills b from crypto/rand.
+func randomBytes(b []byte) error {
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Errorf("failed to generate random bytes: %w", err)
+	}
+
+	return nil
+}
+
+// putMillis writes the low 48 bits of ms big-endian into b[0:6].
+func putMillis(b []byte, ms int64) {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], uint64(ms))
+	copy(b[:6], buf[2:])
+}
+
+// millis reads a 48-bit big-endian millisecond timestamp from b[0:6].
+func millis(b []byte) time.Time {
+	var buf [8]byte
+	copy(buf[2:], b[:6])
+
+	return time.UnixMilli(int64(binary.BigEndian.Uint64(buf[:])))
+}
+
+// newUUIDv7 returns a version 7 UUID for now in canonical hyphenated form.
+func newUUIDv7(now time.Time) (string, error) {
+	var u [16]byte
+	if err := randomBytes(u[6:]); err != nil {
+		return "", err
+	}
+
+	putMillis(u[:], now.UnixMilli())
+	u[6] = (u[6] & 0x0f) | 0x70 // Version 7
+	u[8] = (u[8] & 0x3f) | 0x80 // RFC 9562 variant
+
+	h := hex.EncodeToString(u[:])
+
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:], nil
+}
+
+// parseUUIDv7 returns the time of a version 7 UUID.
+func parseUUIDv7(s string) (time.Time, error) {
+	if len(s) != 36 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return time.Time{}, errors.New("not a hyphenated UUID")
+	}
+	if strings.ToLower(s) != s {
+		return time.Time{}, errors.New("UUID is not lower-case")
+	}
+
+	u, err := hex.DecodeString(strings.ReplaceAll(s, "-", ""))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("bad UUID hex: %w", err)
+	}
+	if u[6]>>4 != 7 || u[8]>>6 != 2 {
+		return time.Time{}, errors.New("not a version 7 UUID")
+	}
+
+	return millis(u), nil
+}
+
+// crockfordAlphabet is Crockford's base32 alphabet used by ULIDs.
+const crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ulidLength is the length of an encoded ULID.
+const ulidLength = 26
+
+// newULID returns a ULID for now.
+func newULID(now time.Time) (string, error) {
+	var u [16]byte
+	if err := randomBytes(u[6:]); err != nil {
+		return "", err
+	}
+	putMillis(u[:], now.UnixMilli())
+
+	// 26 characters hold 130 bits; the 128-bit value is right-aligned
+	out := make([]byte, ulidLength)
+	for i := range ulidLength {
+		bit := i*5 - 2
+		var v int
+		for j := range 5 {
+			if b := bit + j; b >= 0 && u[b/8]&(0x80>>(b%8)) != 0 {
+				v |= 0x10 >> j
+			}
+		}
+		out[i] = crockfordAlphabet[v]
+	}
+
+	return string(out), nil
+}
+
+// parseULID returns the time of a ULID.
+func parseULID(s string) (time.Time, error) {
+	if len(s) != ulidLength {
+		return time.Time{}, fmt.Errorf("ULID has %d characters, want %d", len(s), ulidLength)
+	}
+	if strings.IndexByte("01234567", s[0]) < 0 {
+		return time.Time{}, errors.New("ULID overflows 128 bits")
+	}
+
+	var u [16]byte
+	for i := range ulidLength {
+		v := strings.IndexByte(crockfordAlphabet, s[i])
+		if v < 0 {
+			return time.Time{}, fmt.Errorf("bad ULID character %q", s[i])
+		}
+		for j := range 5 {
+			if b := i*5 - 2 + j; b >= 0 && v&(0x10>>j) != 0 {
+				u[b/8] |= 0x80 >> (b % 8)
+			}
+		}
+	}
+
+	return millis(u[:]), nil
+}
+
+// KSUID parameters.
+const (
+	ksuidEpoch    = 1400000000 // 2014-05-13T16:53:20Z
+	ksuidLength   = 27
+	ksuidAlphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+)
+
+// newKSUID returns a KSUID for now.
+func newKSUID(now time.Time) (string, error) {
+	var k [20]byte
+	if err := randomBytes(k[4:]); err != nil {
+		return "", err
+	}
+
+	seconds := now.Unix() - ksuidEpoch
+	if seconds < 0 || seconds > 1<<32-1 {
+		return "", fmt.Errorf("time %s outside the KSUID range", now)
+	}
+	binary.BigEndian.PutUint32(k[:4], uint32(seconds))
+
+	out := make([]byte, ksuidLength)
+	n := new(big.Int).SetBytes(k[:])
+	base, digit := big.NewInt(62), new(big.Int)
+	for i := ksuidLength - 1; i >= 0; i-- {
+		n.DivMod(n, base, digit)
+		out[i] = ksuidAlphabet[digit.Int64()]
+	}
+
+	return string(out), nil
+}
+
+// parseKSUID returns the time of a KSUID.
+func parseKSUID(s string) (time.Time, error) {
+	if len(s) != ksuidLength {
+		return time.Time{}, fmt.Errorf("KSUID has %d characters, want %d", len(s), ksuidLength)
+	}
+	n := new(big.Int)
+	base := big.NewInt(62)
+	for i := range ksuidLength {
+		v := strings.IndexByte(ksuidAlphabet, s[i])
+		if v < 0 {
+			return time.Time{}, fmt.Errorf("bad KSUID character %q", s[i])
+		}
+		n.Mul(n, base).Add(n, big.NewInt(int64(v)))
+	}
+	if n.BitLen() > 160 {
+		return time.Time{}, errors.New("KSUID overflows 160 bits")
+	}
+
+	var k [20]byte
+	n.FillBytes(k[:])
+
+	return time.Unix(int64(binary.BigEndian.Uint32(k[:4]))+ksuidEpoch, 0), nil
+}
@@ -0,0 +1,161 @@
+package idgen
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+	"github.com/jaeyeom/email-validator-grpc-mcp/token/storage/memory"
+)
+
+func TestGenerator_Schemes(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scheme    Scheme
+		pattern   *regexp.Regexp
+		precision time.Duration
+	}{
+		{SchemeUUIDv7, regexp.MustCompile(`^val_[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`), time.Millisecond},
+		{SchemeULID, regexp.MustCompile(`^val_[0-7][0-9A-HJKMNP-TV-Z]{25}$`), time.Millisecond},
+		{SchemeKSUID, regexp.MustCompile(`^val_[0-9A-Za-z]{27}$`), time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.scheme.String(), func(t *testing.T) {
+			t.Parallel()
+
+			now := time.Date(2025, 6, 1, 12, 30, 15, 123456789, time.UTC)
+			g := New(WithScheme(tt.scheme), WithClock(token.ClockFunc(func() time.Time { return now })))
+
+			var ids []string
+			for range 20 {
+				id, err := g.New()
+				if err != nil {
+					t.Fatalf("New() error = %v", err)
+				}
+				if !tt.pattern.MatchString(id) {
+					t.Fatalf("New() = %q, does not match %s", id, tt.pattern)
+				}
+
+				got, err := g.Parse(id)
+				if err != nil {
+					t.Fatalf("Parse(%q) error = %v", id, err)
+				}
+				if want := now.Truncate(tt.precision); !got.Equal(want) {
+					t.Errorf("Parse(%q) = %v, want %v", id, got, want)
+				}
+
+				ids = append(ids, id)
+				now = now.Add(1100 * time.Millisecond)
+			}
+
+			if !slices.IsSorted(ids) {
+				t.Errorf("IDs generated over time are not sorted: %v", ids)
+			}
+		})
+	}
+}
+
+func TestGenerator_ParseKnownIDs(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scheme Scheme
+		id     string
+		want   time.Time
+	}{
+		{SchemeUUIDv7, "017f22e2-79b0-7cc3-98c4-dc0c0c07398f", time.UnixMilli(0x017f22e279b0)},
+		{SchemeULID, "01ARZ3NDEKTSV4RRFFQ69G5FAV", time.UnixMilli(1469922850259)},
+		{SchemeKSUID, "0ujtsYcgvSTl8PAuAdqWYSMnLOv", time.Date(2017, 10, 10, 4, 0, 47, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		g := New(WithScheme(tt.scheme), WithPrefix(""))
+		got, err := g.Parse(tt.id)
+		if err != nil {
+			t.Errorf("Parse(%q) error = %v", tt.id, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("Parse(%q) = %v, want %v", tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestGenerator_ParseInvalid(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		scheme Scheme
+		id     string
+	}{
+		{"caller string", SchemeUUIDv7, "my-validation"},
+		{"missing prefix", SchemeUUIDv7, "017f22e2-79b0-7cc3-98c4-dc0c0c07398f"},
+		{"wrong uuid version", SchemeUUIDv7, "val_017f22e2-79b0-4cc3-98c4-dc0c0c07398f"},
+		{"upper-case uuid", SchemeUUIDv7, "val_017F22E2-79B0-7CC3-98C4-DC0C0C07398F"},
+		{"ulid overflow", SchemeULID, "val_81ARZ3NDEKTSV4RRFFQ69G5FAV"},
+		{"ulid bad character", SchemeULID, "val_01ARZ3NDEKTSV4RRFFQ69G5FAU"},
+		{"ksuid too short", SchemeKSUID, "val_0ujtsYcgvSTl8PAuAdqWYSMnLO"},
+		{"ksuid overflow", SchemeKSUID, "val_zzzzzzzzzzzzzzzzzzzzzzzzzzz"},
+		{"ksuid as ulid", SchemeULID, "val_0ujtsYcgvSTl8PAuAdqWYSMnLOv"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := New(WithScheme(tt.scheme)).Parse(tt.id); !errors.Is(err, ErrInvalidID) {
+				t.Errorf("Parse(%q) error = %v, want %v", tt.id, err, ErrInvalidID)
+			}
+		})
+	}
+}
+
+func TestGenerator_NewUnique(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	storage := memory.New()
+	g := New(WithPrefix("v_"))
+
+	id, err := g.NewUnique(ctx, StorageExists(storage))
+	if err != nil {
+		t.Fatalf("NewUnique() error = %v", err)
+	}
+	if !strings.HasPrefix(id, "v_") {
+		t.Errorf("NewUnique() = %q, want prefix v_", id)
+	}
+
+	// Collisions are retried
+	calls := 0
+	_, err = g.NewUnique(ctx, func(context.Context, string) (bool, error) {
+		calls++
+		return calls < 2, nil
+	})
+	if err != nil || calls != 2 {
+		t.Errorf("NewUnique() with one collision = %v after %d checks, want success after 2", err, calls)
+	}
+
+	// Persistent collisions give up
+	_, err = New(WithMaxAttempts(2)).NewUnique(ctx, func(context.Context, string) (bool, error) {
+		return true, nil
+	})
+	if !errors.Is(err, ErrCollision) {
+		t.Errorf("NewUnique() error = %v, want %v", err, ErrCollision)
+	}
+
+	// IDs with tokens are taken
+	if _, err := token.NewManager(storage).CreateLinkToken(ctx, id); err != nil {
+		t.Fatalf("CreateLinkToken() error = %v", err)
+	}
+	if taken, err := StorageExists(storage)(ctx, id); err != nil || !taken {
+		t.Errorf("StorageExists()(%q) = %v, %v, want true", id, taken, err)
+	}
+}
@@ -0,0 +1,140 @@
+// Package journal provides an opt-in, PII-redacted record of recent requests
+// and their decisions, intended for support tooling investigating individual
+// customer issues.
+package journal
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/ctxkeys"
+	"github.com/jaeyeom/email-validator-grpc-mcp/redact"
+)
+
+// DefaultCapacity is the default number of entries retained per tenant.
+const DefaultCapacity = 100
+
+// Entry is a single journaled request and the decision taken for it.
+type Entry struct {
+	Time      time.Time `json:"time"`
+	TenantID  string    `json:"tenant_id,omitempty"`
+	Operation string    `json:"operation"`
+	// Subject identifies what the request was about, such as an email
+	// address or token value. It must be redacted before recording.
+	Subject      string `json:"subject,omitempty"`
+	ValidationID string `json:"validation_id,omitempty"`
+	Decision     string `json:"decision"`
+	Detail       string `json:"detail,omitempty"`
+}
+
+// Journal records entries and returns the most recent ones per tenant.
+type Journal interface {
+	// Record appends an entry to the journal.
+	Record(ctx context.Context, entry Entry) error
+
+	// Recent returns up to limit entries for a tenant, newest first.
+	Recent(ctx context.Context, tenantID string, limit int) ([]Entry, error)
+}
+
+// Prepare fills in the time and tenant of an entry from ctx when they are
+// not already set. Journal implementations call it from Record.
+func Prepare(ctx context.Context, entry Entry) Entry {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+
+	if entry.TenantID == "" {
+		entry.TenantID, _ = ctxkeys.TenantFrom(ctx)
+	}
+
+	return entry
+}
+
+// RedactEmail masks an email address with redact.Email.
+func RedactEmail(addr string) string {
+	return redact.Email(addr)
+}
+
+// RedactToken masks a secret value with redact.Token.
+func RedactToken(value string) string {
+	return redact.Token(value)
+}
+
+// Ring is an in-memory Journal keeping a fixed number of entries per tenant.
+type Ring struct {
+	mu       sync.Mutex
+	capacity int
+	tenants  map[string]*ring
+}
+
+// ring is a fixed-size circular buffer of entries.
+type ring struct {
+	entries []Entry
+	next    int
+	full    bool
+}
+
+// NewRing creates an in-memory journal retaining up to capacity entries per
+// tenant. A non-positive capacity uses DefaultCapacity.
+func NewRing(capacity int) *Ring {
+	if capacity <= 0 {
+		capacity = DefaultCapacity
+	}
+
+	return &Ring{
+		capacity: capacity,
+		tenants:  make(map[string]*ring),
+	}
+}
+
+// Record appends an entry, overwriting the oldest entry for the tenant when
+// the buffer is full.
+func (r *Ring) Record(ctx context.Context, entry Entry) error {
+	entry = Prepare(ctx, entry)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	buf, ok := r.tenants[entry.TenantID]
+	if !ok {
+		buf = &ring{entries: make([]Entry, r.capacity)}
+		r.tenants[entry.TenantID] = buf
+	}
+
+	buf.entries[buf.next] = entry
+	buf.next = (buf.next + 1) % r.capacity
+	if buf.next == 0 {
+		buf.full = true
+	}
+
+	return nil
+}
+
+// Recent returns up to limit entries for a tenant, newest first. A
+// non-positive limit returns all retained entries.
+func (r *Ring) Recent(_ context.Context, tenantID string, limit int) ([]Entry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	buf, ok := r.tenants[tenantID]
+	if !ok {
+		return nil, nil
+	}
+
+	size := buf.next
+	if buf.full {
+		size = r.capacity
+	}
+	if limit <= 0 || limit > size {
+		limit = size
+	}
+
+	result := make([]Entry, 0, limit)
+	for i := 1; i <= limit; i++ {
+		idx := (buf.next - i + r.capacity) % r.capacity
+		result = append(result, buf.entries[idx])
+	}
+
+	return result, nil
+}
@@ -0,0 +1,90 @@
+package journal
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/ctxkeys"
+)
+
+func TestRing_Recent(t *testing.T) {
+	t.Parallel()
+
+	ctx := ctxkeys.WithTenant(context.Background(), "tenant-1")
+	r := NewRing(3)
+
+	for _, op := range []string{"op-1", "op-2", "op-3", "op-4"} {
+		if err := r.Record(ctx, Entry{Operation: op, Decision: "verified"}); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+	}
+
+	entries, err := r.Recent(ctx, "tenant-1", 0)
+	if err != nil {
+		t.Fatalf("Recent() error = %v", err)
+	}
+
+	want := []string{"op-4", "op-3", "op-2"}
+	if len(entries) != len(want) {
+		t.Fatalf("Recent() returned %d entries, want %d", len(entries), len(want))
+	}
+	for i, e := range entries {
+		if e.Operation != want[i] {
+			t.Errorf("Recent()[%d].Operation = %q, want %q", i, e.Operation, want[i])
+		}
+		if e.TenantID != "tenant-1" {
+			t.Errorf("Recent()[%d].TenantID = %q, want tenant-1", i, e.TenantID)
+		}
+		if e.Time.IsZero() {
+			t.Errorf("Recent()[%d].Time was not set", i)
+		}
+	}
+
+	limited, _ := r.Recent(ctx, "tenant-1", 1)
+	if len(limited) != 1 || limited[0].Operation != "op-4" {
+		t.Errorf("Recent() with limit 1 = %v, want [op-4]", limited)
+	}
+}
+
+func TestRing_TenantIsolation(t *testing.T) {
+	t.Parallel()
+
+	r := NewRing(10)
+	_ = r.Record(context.Background(), Entry{TenantID: "tenant-a", Operation: "op-a"})
+	_ = r.Record(context.Background(), Entry{TenantID: "tenant-b", Operation: "op-b"})
+
+	entries, _ := r.Recent(context.Background(), "tenant-a", 0)
+	if len(entries) != 1 || entries[0].Operation != "op-a" {
+		t.Errorf("Recent(tenant-a) = %v, want only op-a", entries)
+	}
+
+	if entries, _ := r.Recent(context.Background(), "tenant-c", 0); len(entries) != 0 {
+		t.Errorf("Recent(unknown tenant) = %v, want none", entries)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		fn   func(string) string
+		in   string
+		want string
+	}{
+		{"email", RedactEmail, "jane.doe@example.com", "j***@example.com"},
+		{"email without at", RedactEmail, "1234", "****"},
+		{"short token", RedactToken, "123456", "******"},
+		{"long token", RedactToken, "abcdefghijklmnopqrstuvwxyz", "abcd...wxyz"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := tt.fn(tt.in); got != tt.want {
+				t.Errorf("redact(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
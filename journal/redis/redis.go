@@ -0,0 +1,123 @@
+// Package redis provides a Redis stream-backed request journal shared by all
+// service replicas.
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/journal"
+	"github.com/redis/go-redis/v9"
+)
+
+// Journal stores entries in one Redis stream per tenant, trimmed to an
+// approximate maximum length.
+type Journal struct {
+	client   *redis.Client
+	capacity int64
+	logger   *slog.Logger
+}
+
+// Option is a functional option for configuring Journal.
+type Option func(*Journal)
+
+// WithLogger sets a custom logger for Journal.
+func WithLogger(logger *slog.Logger) Option {
+	return func(j *Journal) {
+		j.logger = logger
+	}
+}
+
+// WithCapacity sets the approximate number of entries retained per tenant.
+func WithCapacity(capacity int) Option {
+	return func(j *Journal) {
+		if capacity > 0 {
+			j.capacity = int64(capacity)
+		}
+	}
+}
+
+// New creates a new Redis stream-backed journal.
+func New(client *redis.Client, opts ...Option) *Journal {
+	j := &Journal{
+		client:   client,
+		capacity: journal.DefaultCapacity,
+		logger:   slog.Default(),
+	}
+
+	for _, opt := range opts {
+		opt(j)
+	}
+
+	return j
+}
+
+// Record appends an entry to the tenant's stream.
+func (j *Journal) Record(ctx context.Context, entry journal.Entry) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("context error: %w", err)
+	}
+
+	entry = journal.Prepare(ctx, entry)
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal journal entry: %w", err)
+	}
+
+	err = j.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: streamKey(entry.TenantID),
+		MaxLen: j.capacity,
+		Approx: true,
+		Values: map[string]any{"entry": data},
+	}).Err()
+	if err != nil {
+		j.logger.Error("failed to record journal entry", "error", err)
+		return fmt.Errorf("failed to record journal entry: %w", err)
+	}
+
+	return nil
+}
+
+// Recent returns up to limit entries for a tenant, newest first. A
+// non-positive limit returns all retained entries.
+func (j *Journal) Recent(ctx context.Context, tenantID string, limit int) ([]journal.Entry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("context error: %w", err)
+	}
+
+	count := int64(limit)
+	if count <= 0 {
+		count = j.capacity
+	}
+
+	messages, err := j.client.XRevRangeN(ctx, streamKey(tenantID), "+", "-", count).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read journal entries: %w", err)
+	}
+
+	entries := make([]journal.Entry, 0, len(messages))
+	for _, msg := range messages {
+		raw, ok := msg.Values["entry"].(string)
+		if !ok {
+			continue
+		}
+
+		var entry journal.Entry
+		if err := json.Unmarshal([]byte(raw), &entry); err != nil {
+			j.logger.Warn("skipping malformed journal entry", "id", msg.ID, "error", err)
+			continue
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// streamKey returns the stream key for a tenant.
+func streamKey(tenantID string) string {
+	return fmt.Sprintf("journal:%s", tenantID)
+}
@@ -0,0 +1,61 @@
+package redis
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/jaeyeom/email-validator-grpc-mcp/journal"
+	"github.com/redis/go-redis/v9"
+)
+
+func setupMiniRedis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	t.Helper()
+
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("Failed to start miniredis: %v", err)
+	}
+
+	client := redis.NewClient(&redis.Options{
+		Addr: mr.Addr(),
+	})
+
+	return mr, client
+}
+
+func TestJournal_RecordAndRecent(t *testing.T) {
+	t.Parallel()
+
+	mr, client := setupMiniRedis(t)
+	defer mr.Close()
+
+	ctx := context.Background()
+	j := New(client)
+
+	for _, op := range []string{"op-1", "op-2", "op-3"} {
+		err := j.Record(ctx, journal.Entry{TenantID: "tenant-1", Operation: op, Decision: "rejected", Detail: "not_found"})
+		if err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+	}
+
+	entries, err := j.Recent(ctx, "tenant-1", 2)
+	if err != nil {
+		t.Fatalf("Recent() error = %v", err)
+	}
+
+	if len(entries) != 2 {
+		t.Fatalf("Recent() returned %d entries, want 2", len(entries))
+	}
+	if entries[0].Operation != "op-3" || entries[1].Operation != "op-2" {
+		t.Errorf("Recent() = [%s %s], want [op-3 op-2]", entries[0].Operation, entries[1].Operation)
+	}
+	if entries[0].Detail != "not_found" {
+		t.Errorf("Recent()[0].Detail = %q, want not_found", entries[0].Detail)
+	}
+
+	if other, _ := j.Recent(ctx, "tenant-2", 0); len(other) != 0 {
+		t.Errorf("Recent(tenant-2) = %v, want none", other)
+	}
+}
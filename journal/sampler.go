@@ -0,0 +1,123 @@
+package journal
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sync/atomic"
+)
+
+// Decisions recorded by the token Manager.
+const (
+	DecisionVerified = "verified"
+	DecisionRejected = "rejected"
+)
+
+// SamplerOption is a functional option for configuring Sampler.
+type SamplerOption func(*Sampler)
+
+// WithAlwaysRecord records in full every entry for which keep returns true,
+// such as administrative actions or security events, in addition to
+// rejections.
+func WithAlwaysRecord(keep func(Entry) bool) SamplerOption {
+	return func(s *Sampler) {
+		s.always = keep
+	}
+}
+
+// Sampler is a Journal that records only a fraction of routine entries, to
+// bound journal storage at high request rates. Rejections, and entries
+// selected with WithAlwaysRecord, are always recorded.
+//
+// Sampling is decided per validation rather than per entry, so a sampled
+// validation keeps all of its routine entries. Entries without a validation
+// ID are sampled by subject.
+type Sampler struct {
+	journal   Journal
+	threshold uint64
+	always    func(Entry) bool
+
+	recorded atomic.Int64
+	skipped  atomic.Int64
+}
+
+// NewSampler creates a Sampler recording about rate of the routine entries,
+// between 0 and 1, to j. Reads are passed through to j.
+func NewSampler(j Journal, rate float64, opts ...SamplerOption) *Sampler {
+	s := &Sampler{
+		journal:   j,
+		threshold: threshold(rate),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// threshold converts a sampling rate to a bound on 32-bit hashes.
+func threshold(rate float64) uint64 {
+	switch {
+	case rate <= 0 || math.IsNaN(rate):
+		return 0
+	case rate >= 1:
+		return math.MaxUint32 + 1
+	default:
+		return uint64(rate * (math.MaxUint32 + 1))
+	}
+}
+
+// Record passes entry to the underlying journal if it must be kept or is
+// sampled, and otherwise drops it.
+func (s *Sampler) Record(ctx context.Context, entry Entry) error {
+	entry = Prepare(ctx, entry)
+	if !s.keep(entry) {
+		s.skipped.Add(1)
+		return nil
+	}
+
+	s.recorded.Add(1)
+
+	if err := s.journal.Record(ctx, entry); err != nil {
+		return fmt.Errorf("failed to record sampled journal entry: %w", err)
+	}
+
+	return nil
+}
+
+// keep reports whether entry is recorded.
+func (s *Sampler) keep(entry Entry) bool {
+	if entry.Decision != DecisionVerified {
+		return true
+	}
+	if s.always != nil && s.always(entry) {
+		return true
+	}
+
+	key := entry.ValidationID
+	if key == "" {
+		key = entry.Subject
+	}
+
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(entry.TenantID + "\x00" + key))
+
+	return uint64(h.Sum32()) < s.threshold
+}
+
+// Recent returns recorded entries from the underlying journal.
+func (s *Sampler) Recent(ctx context.Context, tenantID string, limit int) ([]Entry, error) {
+	entries, err := s.journal.Recent(ctx, tenantID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sampled journal entries: %w", err)
+	}
+
+	return entries, nil
+}
+
+// Stats returns the number of entries recorded and skipped so far.
+func (s *Sampler) Stats() (recorded, skipped int64) {
+	return s.recorded.Load(), s.skipped.Load()
+}
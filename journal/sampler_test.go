@@ -0,0 +1,88 @@
+package journal
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/ctxkeys"
+)
+
+func TestSampler_Record(t *testing.T) {
+	t.Parallel()
+
+	ctx := ctxkeys.WithTenant(context.Background(), "tenant-1")
+	ring := NewRing(10000)
+	s := NewSampler(ring, 0.1, WithAlwaysRecord(func(e Entry) bool {
+		return e.Operation == "InvalidateValidation"
+	}))
+
+	const validations = 1000
+	for i := range validations {
+		validationID := fmt.Sprintf("validation-%d", i)
+		for _, op := range []string{"CreateLinkToken", "VerifyToken"} {
+			if err := s.Record(ctx, Entry{Operation: op, ValidationID: validationID, Decision: DecisionVerified}); err != nil {
+				t.Fatalf("Record() error = %v", err)
+			}
+		}
+	}
+	for _, e := range []Entry{
+		{Operation: "VerifyToken", ValidationID: "validation-1", Decision: DecisionRejected, Detail: "not_found"},
+		{Operation: "InvalidateValidation", ValidationID: "validation-2", Decision: DecisionVerified},
+	} {
+		if err := s.Record(ctx, e); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+	}
+
+	entries, err := s.Recent(ctx, "tenant-1", 0)
+	if err != nil {
+		t.Fatalf("Recent() error = %v", err)
+	}
+
+	// Rejections and always-recorded entries are kept in full
+	if entries[0].Operation != "InvalidateValidation" || entries[1].Decision != DecisionRejected {
+		t.Errorf("Recent() newest entries = %+v, want the invalidation and the rejection", entries[:2])
+	}
+
+	// Routine entries are kept or dropped per validation
+	perValidation := make(map[string]int)
+	for _, e := range entries[2:] {
+		perValidation[e.ValidationID]++
+	}
+	for id, n := range perValidation {
+		if n != 2 {
+			t.Errorf("validation %s has %d of 2 routine entries", id, n)
+		}
+	}
+	if n := len(perValidation); n < validations/20 || n > validations/5 {
+		t.Errorf("%d of %d validations sampled, want about 10%%", n, validations)
+	}
+
+	recorded, skipped := s.Stats()
+	if recorded != int64(len(entries)) || recorded+skipped != 2*validations+2 {
+		t.Errorf("Stats() = %d recorded, %d skipped, want %d recorded of %d", recorded, skipped, len(entries), 2*validations+2)
+	}
+}
+
+func TestSampler_Rates(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	for _, tt := range []struct {
+		rate float64
+		want int
+	}{
+		{0, 0},
+		{1, 100},
+	} {
+		ring := NewRing(1000)
+		s := NewSampler(ring, tt.rate)
+		for i := range 100 {
+			_ = s.Record(ctx, Entry{Operation: "VerifyToken", ValidationID: fmt.Sprint(i), Decision: DecisionVerified})
+		}
+		if entries, _ := s.Recent(ctx, "", 0); len(entries) != tt.want {
+			t.Errorf("rate %v recorded %d of 100 entries, want %d", tt.rate, len(entries), tt.want)
+		}
+	}
+}
@@ -0,0 +1,302 @@
+// Package policy decides which email domains validations may be started
+// for. Operators configure rules of glob patterns, such as "example.com",
+// "*.example.com", or "*.zip" for a whole top-level domain, each allowing
+// or blocking the domains it matches. Rules can be replaced at runtime, so
+// an admin API can manage them without restarting the service.
+//
+// Allow rules take precedence over block rules, so a broad block such as
+// "*.example" can make exceptions for "mail.example". Domains matching no
+// rule get the default action, which allows them unless the policy is an
+// allowlist.
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/emailaddr"
+)
+
+var (
+	// ErrInvalidRule is returned when a rule has an empty or malformed
+	// pattern or an unknown action.
+	ErrInvalidRule = errors.New("invalid policy rule")
+
+	// ErrRejected is wrapped by the errors of Decision.Err.
+	ErrRejected = errors.New("domain rejected by policy")
+)
+
+// Action is what a rule does with the domains it matches.
+type Action string
+
+// Rule actions.
+const (
+	ActionAllow Action = "ALLOW"
+	ActionBlock Action = "BLOCK"
+)
+
+// Code identifies why a domain was rejected.
+type Code string
+
+// Rejection codes.
+const (
+	// CodeBlocked is a domain matching a block rule.
+	CodeBlocked Code = "DOMAIN_BLOCKED"
+	// CodeNotAllowed is a domain matching no rule of an allowlist.
+	CodeNotAllowed Code = "DOMAIN_NOT_ALLOWED"
+)
+
+// Rule allows or blocks the domains matching a pattern.
+type Rule struct {
+	// Pattern is a glob pattern, in the syntax of path.Match, matched
+	// against the lower-case ASCII domain. Internationalized domains are
+	// matched in their "xn--" form.
+	Pattern string `json:"pattern"`
+
+	Action Action `json:"action"`
+
+	// Reason is an optional note shown in rejections, such as "known spam
+	// source".
+	Reason string `json:"reason,omitempty"`
+}
+
+// validate checks the rule and returns it with its pattern lower-cased.
+func (r Rule) validate() (Rule, error) {
+	r.Pattern = strings.ToLower(strings.TrimSpace(r.Pattern))
+	switch {
+	case r.Pattern == "":
+		return r, fmt.Errorf("%w: empty pattern", ErrInvalidRule)
+	case strings.ContainsAny(r.Pattern, "@/"):
+		return r, fmt.Errorf("%w: pattern %q must match a domain", ErrInvalidRule, r.Pattern)
+	case r.Action != ActionAllow && r.Action != ActionBlock:
+		return r, fmt.Errorf("%w: unknown action %q", ErrInvalidRule, r.Action)
+	}
+	if _, err := path.Match(r.Pattern, ""); err != nil {
+		return r, fmt.Errorf("%w: pattern %q: %w", ErrInvalidRule, r.Pattern, err)
+	}
+
+	return r, nil
+}
+
+// Decision is the outcome of evaluating a domain.
+type Decision struct {
+	Domain  string `json:"domain"`
+	Allowed bool   `json:"allowed"`
+
+	// Code is why the domain was rejected, and empty if it was allowed.
+	Code Code `json:"code,omitempty"`
+
+	// Rule is the rule that decided, or nil if the default action did.
+	Rule *Rule `json:"rule,omitempty"`
+}
+
+// Err returns nil for an allowed domain, and otherwise an *Error wrapping
+// ErrRejected.
+func (d *Decision) Err() error {
+	if d.Allowed {
+		return nil
+	}
+
+	return &Error{Decision: d}
+}
+
+// Error reports a rejected domain.
+type Error struct {
+	Decision *Decision
+}
+
+// Error describes the rejection.
+func (e *Error) Error() string {
+	d := e.Decision
+	msg := fmt.Sprintf("%s: %s (%s)", ErrRejected, d.Domain, d.Code)
+	if d.Rule != nil {
+		msg += fmt.Sprintf(" by rule %q", d.Rule.Pattern)
+		if d.Rule.Reason != "" {
+			msg += ": " + d.Rule.Reason
+		}
+	}
+
+	return msg
+}
+
+// Unwrap returns ErrRejected.
+func (e *Error) Unwrap() error {
+	return ErrRejected
+}
+
+// Policy evaluates domains against rules. It is safe for concurrent use,
+// including changing the rules while domains are evaluated.
+type Policy struct {
+	logger *slog.Logger
+
+	mu            sync.RWMutex
+	rules         []Rule
+	defaultAction Action
+}
+
+// Option is a functional option for configuring Policy.
+type Option func(*Policy)
+
+// WithLogger sets a custom logger for Policy.
+func WithLogger(logger *slog.Logger) Option {
+	return func(p *Policy) {
+		p.logger = logger
+	}
+}
+
+// New creates a Policy that allows every domain until rules are set.
+func New(opts ...Option) *Policy {
+	p := &Policy{
+		logger:        slog.Default(),
+		defaultAction: ActionAllow,
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+// SetRules replaces the rules. If any rule is invalid, the rules are left
+// unchanged and the error is returned.
+func (p *Policy) SetRules(rules []Rule) error {
+	validated := make([]Rule, len(rules))
+	for i, r := range rules {
+		v, err := r.validate()
+		if err != nil {
+			return err
+		}
+		validated[i] = v
+	}
+
+	p.mu.Lock()
+	p.rules = validated
+	p.mu.Unlock()
+
+	p.logger.Info("domain policy rules replaced", "rules", len(validated))
+
+	return nil
+}
+
+// AddRule adds a rule, replacing any rule with the same pattern.
+func (p *Policy) AddRule(rule Rule) error {
+	rule, err := rule.validate()
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	i := slices.IndexFunc(p.rules, func(r Rule) bool { return r.Pattern == rule.Pattern })
+	if i >= 0 {
+		p.rules[i] = rule
+	} else {
+		p.rules = append(p.rules, rule)
+	}
+	p.mu.Unlock()
+
+	p.logger.Info("domain policy rule set",
+		"pattern", rule.Pattern,
+		"action", rule.Action)
+
+	return nil
+}
+
+// RemoveRule removes the rule with pattern, and reports whether there was
+// one.
+func (p *Policy) RemoveRule(pattern string) bool {
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+
+	p.mu.Lock()
+	n := len(p.rules)
+	p.rules = slices.DeleteFunc(p.rules, func(r Rule) bool { return r.Pattern == pattern })
+	removed := len(p.rules) < n
+	p.mu.Unlock()
+
+	if removed {
+		p.logger.Info("domain policy rule removed", "pattern", pattern)
+	}
+
+	return removed
+}
+
+// Rules returns a copy of the rules.
+func (p *Policy) Rules() []Rule {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return slices.Clone(p.rules)
+}
+
+// SetDefaultAction sets what happens to domains matching no rule. With
+// ActionBlock, the policy is an allowlist.
+func (p *Policy) SetDefaultAction(action Action) error {
+	if action != ActionAllow && action != ActionBlock {
+		return fmt.Errorf("%w: unknown action %q", ErrInvalidRule, action)
+	}
+
+	p.mu.Lock()
+	p.defaultAction = action
+	p.mu.Unlock()
+
+	p.logger.Info("domain policy default action set", "action", action)
+
+	return nil
+}
+
+// DefaultAction returns what happens to domains matching no rule.
+func (p *Policy) DefaultAction() Action {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.defaultAction
+}
+
+// Evaluate decides on an ASCII domain.
+func (p *Policy) Evaluate(domain string) *Decision {
+	domain = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var blocked *Rule
+	for i := range p.rules {
+		r := &p.rules[i]
+		if matched, _ := path.Match(r.Pattern, domain); !matched {
+			continue
+		}
+		if r.Action == ActionAllow {
+			rule := *r
+			return &Decision{Domain: domain, Allowed: true, Rule: &rule}
+		}
+		if blocked == nil {
+			blocked = r
+		}
+	}
+
+	switch {
+	case blocked != nil:
+		rule := *blocked
+		return &Decision{Domain: domain, Code: CodeBlocked, Rule: &rule}
+	case p.defaultAction == ActionBlock:
+		return &Decision{Domain: domain, Code: CodeNotAllowed}
+	default:
+		return &Decision{Domain: domain, Allowed: true}
+	}
+}
+
+// EvaluateAddress decides on the domain of an email address, converting an
+// internationalized domain to ASCII first.
+func (p *Policy) EvaluateAddress(addr string) (*Decision, error) {
+	normalized, err := emailaddr.New().Normalize(addr)
+	if err != nil {
+		return nil, fmt.Errorf("cannot evaluate address: %w", err)
+	}
+
+	return p.Evaluate(normalized[strings.LastIndexByte(normalized, '@')+1:]), nil
+}
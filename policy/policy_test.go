@@ -0,0 +1,150 @@
+package policy
+
+import (
+	"errors"
+	"log/slog"
+	"testing"
+)
+
+func newPolicy(t *testing.T, rules ...Rule) *Policy {
+	t.Helper()
+
+	p := New(WithLogger(slog.New(slog.DiscardHandler)))
+	if err := p.SetRules(rules); err != nil {
+		t.Fatalf("SetRules() error = %v", err)
+	}
+
+	return p
+}
+
+func TestPolicy_Evaluate(t *testing.T) {
+	t.Parallel()
+
+	p := newPolicy(t,
+		Rule{Pattern: "*.zip", Action: ActionBlock},
+		Rule{Pattern: "spam.example", Action: ActionBlock, Reason: "known spam source"},
+		Rule{Pattern: "*.spam.example", Action: ActionBlock},
+		Rule{Pattern: "*.example", Action: ActionBlock},
+		Rule{Pattern: "Mail.Example", Action: ActionAllow},
+	)
+
+	tests := []struct {
+		domain  string
+		allowed bool
+		pattern string
+	}{
+		{"example.com", true, ""},
+		{"files.zip", false, "*.zip"},
+		{"SPAM.example.", false, "spam.example"},
+		{"a.b.spam.example", false, "*.spam.example"},
+		{"other.example", false, "*.example"},
+		{"mail.example", true, "mail.example"},
+		{"zip.com", true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.domain, func(t *testing.T) {
+			t.Parallel()
+
+			got := p.Evaluate(tt.domain)
+			pattern := ""
+			if got.Rule != nil {
+				pattern = got.Rule.Pattern
+			}
+			if got.Allowed != tt.allowed || pattern != tt.pattern {
+				t.Errorf("Evaluate(%q) = %+v, want allowed %v by %q", tt.domain, got, tt.allowed, tt.pattern)
+			}
+			if !got.Allowed && got.Code != CodeBlocked {
+				t.Errorf("Evaluate(%q) code = %s, want %s", tt.domain, got.Code, CodeBlocked)
+			}
+		})
+	}
+}
+
+func TestPolicy_Allowlist(t *testing.T) {
+	t.Parallel()
+
+	p := newPolicy(t, Rule{Pattern: "*.corp.example", Action: ActionAllow}, Rule{Pattern: "corp.example", Action: ActionAllow})
+	if err := p.SetDefaultAction(ActionBlock); err != nil {
+		t.Fatalf("SetDefaultAction() error = %v", err)
+	}
+
+	if d := p.Evaluate("eu.corp.example"); !d.Allowed {
+		t.Errorf("Evaluate() of allowed domain = %+v", d)
+	}
+	d := p.Evaluate("gmail.com")
+	if d.Allowed || d.Code != CodeNotAllowed || d.Rule != nil {
+		t.Errorf("Evaluate() of unlisted domain = %+v, want %s", d, CodeNotAllowed)
+	}
+
+	var policyErr *Error
+	if err := d.Err(); !errors.Is(err, ErrRejected) || !errors.As(err, &policyErr) || policyErr.Decision != d {
+		t.Errorf("Err() = %v, want an *Error wrapping %v", err, ErrRejected)
+	}
+}
+
+func TestPolicy_RuntimeChanges(t *testing.T) {
+	t.Parallel()
+
+	p := newPolicy(t)
+	if d := p.Evaluate("spam.example"); !d.Allowed {
+		t.Fatalf("Evaluate() without rules = %+v, want allowed", d)
+	}
+
+	if err := p.AddRule(Rule{Pattern: "spam.example", Action: ActionBlock}); err != nil {
+		t.Fatalf("AddRule() error = %v", err)
+	}
+	if d := p.Evaluate("spam.example"); d.Allowed {
+		t.Errorf("Evaluate() after AddRule() = %+v, want blocked", d)
+	}
+
+	// Adding a rule for the same pattern replaces it.
+	if err := p.AddRule(Rule{Pattern: "SPAM.example", Action: ActionAllow}); err != nil {
+		t.Fatalf("AddRule() error = %v", err)
+	}
+	if rules := p.Rules(); len(rules) != 1 || rules[0].Action != ActionAllow {
+		t.Errorf("Rules() after replacing = %+v, want one allow rule", rules)
+	}
+
+	if !p.RemoveRule("spam.example") || p.RemoveRule("spam.example") {
+		t.Error("RemoveRule() did not remove the rule exactly once")
+	}
+
+	// Invalid rules leave the rules unchanged.
+	if err := p.SetRules([]Rule{{Pattern: "ok.example", Action: ActionBlock}, {Pattern: "[", Action: ActionBlock}}); !errors.Is(err, ErrInvalidRule) {
+		t.Errorf("SetRules() with bad pattern error = %v, want %v", err, ErrInvalidRule)
+	}
+	if len(p.Rules()) != 0 {
+		t.Errorf("Rules() after failed SetRules() = %+v, want none", p.Rules())
+	}
+}
+
+func TestRule_Validate(t *testing.T) {
+	t.Parallel()
+
+	invalid := []Rule{
+		{Pattern: "", Action: ActionBlock},
+		{Pattern: "user@example.com", Action: ActionBlock},
+		{Pattern: "[a-", Action: ActionBlock},
+		{Pattern: "example.com", Action: "DENY"},
+	}
+	for _, r := range invalid {
+		if _, err := r.validate(); !errors.Is(err, ErrInvalidRule) {
+			t.Errorf("validate(%+v) error = %v, want %v", r, err, ErrInvalidRule)
+		}
+	}
+}
+
+func TestPolicy_EvaluateAddress(t *testing.T) {
+	t.Parallel()
+
+	p := newPolicy(t, Rule{Pattern: "xn--bcher-kva.*", Action: ActionBlock})
+
+	d, err := p.EvaluateAddress("user@Bücher.example")
+	if err != nil || d.Allowed || d.Domain != "xn--bcher-kva.example" {
+		t.Errorf("EvaluateAddress() = %+v, %v, want the punycode domain blocked", d, err)
+	}
+	if _, err := p.EvaluateAddress("not an address"); err == nil {
+		t.Error("EvaluateAddress() of a non-address succeeded")
+	}
+}
@@ -0,0 +1,29 @@
+// Package redact masks personal data and secrets, such as email addresses
+// and token values, before they are logged or recorded. It has no
+// dependencies, so any package can use it without pulling in the journal or
+// the token packages.
+package redact
+
+import "strings"
+
+// Email masks the local part of an email address, keeping only its first
+// character and the domain, e.g. "j***@example.com". A string without an
+// "@" is masked as a token.
+func Email(addr string) string {
+	at := strings.LastIndexByte(addr, '@')
+	if at <= 0 {
+		return Token(addr)
+	}
+
+	return addr[:1] + "***" + addr[at:]
+}
+
+// Token masks a secret value, keeping only its first and last four
+// characters. Values too short to reveal anything safely are fully masked.
+func Token(value string) string {
+	if len(value) <= 12 {
+		return strings.Repeat("*", len(value))
+	}
+
+	return value[:4] + "..." + value[len(value)-4:]
+}
@@ -0,0 +1,30 @@
+package redact
+
+import "testing"
+
+func TestRedact(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		fn   func(string) string
+		in   string
+		want string
+	}{
+		{"email", Email, "jane.doe@example.com", "j***@example.com"},
+		{"email without at", Email, "1234", "****"},
+		{"email with empty local part", Email, "@example.com", "************"},
+		{"short token", Token, "123456", "******"},
+		{"long token", Token, "abcdefghijklmnopqrstuvwxyz", "abcd...wxyz"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := tt.fn(tt.in); got != tt.want {
+				t.Errorf("%s(%q) = %q, want %q", tt.name, tt.in, got, tt.want)
+			}
+		})
+	}
+}
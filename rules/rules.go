@@ -0,0 +1,306 @@
+// Package rules decides, by ordered rules, whether a validation may be
+// started for a recipient. Each rule has conditions on the address, its
+// domain, and its deliverability score, and an action: allow the
+// validation, deny it, or let it proceed with a step-up, for callers to ask
+// for further proof before trusting the address.
+//
+// Rules are evaluated in order and the first whose conditions all match
+// decides; an address matching no rule is allowed. Each tenant has its own
+// rules, replaceable at runtime so an admin API can manage them, and
+// tenants without rules of their own use those of the empty tenant ID.
+// Decisions are recorded in a journal when one is configured.
+package rules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/ctxkeys"
+	"github.com/jaeyeom/email-validator-grpc-mcp/emailaddr"
+	"github.com/jaeyeom/email-validator-grpc-mcp/journal"
+	"github.com/jaeyeom/email-validator-grpc-mcp/redact"
+	"github.com/jaeyeom/email-validator-grpc-mcp/score"
+)
+
+// ErrInvalidRule is returned when a rule has a malformed condition or an
+// unknown action.
+var ErrInvalidRule = errors.New("invalid recipient rule")
+
+// Action is what a rule does with the recipients it matches.
+type Action string
+
+// Rule actions.
+const (
+	ActionAllow  Action = "ALLOW"
+	ActionDeny   Action = "DENY"
+	ActionStepUp Action = "STEP_UP"
+)
+
+// journalOperation is the operation of the journal entries of decisions.
+const journalOperation = "EvaluateRecipient"
+
+// Rule is a set of conditions and the action taken on recipients matching
+// all of them. A rule without conditions matches every recipient, which
+// makes it a catch-all when placed last.
+type Rule struct {
+	// Name identifies the rule in decisions and the journal.
+	Name string `json:"name"`
+
+	// Domains are glob patterns, in the syntax of path.Match, matched
+	// against the lower-case ASCII domain of the address. The condition
+	// holds if any pattern matches.
+	Domains []string `json:"domains,omitempty"`
+
+	// Pattern is a regular expression matched against the trimmed,
+	// lower-cased address.
+	Pattern string `json:"pattern,omitempty"`
+
+	// MinScore and MaxScore bound the deliverability score of the address,
+	// inclusively. A score condition does not hold for an address that was
+	// not scored.
+	MinScore *int `json:"min_score,omitempty"`
+	MaxScore *int `json:"max_score,omitempty"`
+
+	Action Action `json:"action"`
+
+	// Reason is an optional note shown in decisions, such as "suspected
+	// fraud ring".
+	Reason string `json:"reason,omitempty"`
+}
+
+// compiledRule is a validated rule with its pattern compiled.
+type compiledRule struct {
+	Rule
+	pattern *regexp.Regexp
+}
+
+// clone returns a copy of r that shares no memory with it.
+func (r Rule) clone() Rule {
+	r.Domains = slices.Clone(r.Domains)
+	if r.MinScore != nil {
+		minScore := *r.MinScore
+		r.MinScore = &minScore
+	}
+	if r.MaxScore != nil {
+		maxScore := *r.MaxScore
+		r.MaxScore = &maxScore
+	}
+
+	return r
+}
+
+// compile checks the rule and returns a copy of it with its domain
+// patterns lower-cased and its pattern compiled.
+func (r Rule) compile() (compiledRule, error) {
+	c := compiledRule{Rule: r.clone()}
+	for i, domain := range r.Domains {
+		domain = strings.ToLower(strings.TrimSpace(domain))
+		if domain == "" || strings.ContainsAny(domain, "@/") {
+			return c, fmt.Errorf("%w: %q: domain %q must be a domain pattern", ErrInvalidRule, r.Name, domain)
+		}
+		if _, err := path.Match(domain, ""); err != nil {
+			return c, fmt.Errorf("%w: %q: domain %q: %w", ErrInvalidRule, r.Name, domain, err)
+		}
+		c.Domains[i] = domain
+	}
+
+	if r.Pattern != "" {
+		pattern, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return c, fmt.Errorf("%w: %q: %w", ErrInvalidRule, r.Name, err)
+		}
+		c.pattern = pattern
+	}
+
+	switch {
+	case r.MinScore != nil && r.MaxScore != nil && *r.MinScore > *r.MaxScore:
+		return c, fmt.Errorf("%w: %q: min score %d above max score %d", ErrInvalidRule, r.Name, *r.MinScore, *r.MaxScore)
+	case r.Action != ActionAllow && r.Action != ActionDeny && r.Action != ActionStepUp:
+		return c, fmt.Errorf("%w: %q: unknown action %q", ErrInvalidRule, r.Name, r.Action)
+	}
+
+	return c, nil
+}
+
+// matches reports whether every condition of the rule holds for a
+// recipient.
+func (c *compiledRule) matches(address, domain string, s *score.Result) bool {
+	if len(c.Domains) > 0 && !slices.ContainsFunc(c.Domains, func(pattern string) bool {
+		matched, _ := path.Match(pattern, domain)
+		return matched
+	}) {
+		return false
+	}
+
+	if c.pattern != nil && !c.pattern.MatchString(address) {
+		return false
+	}
+
+	if c.MinScore != nil && (s == nil || s.Score < *c.MinScore) {
+		return false
+	}
+	if c.MaxScore != nil && (s == nil || s.Score > *c.MaxScore) {
+		return false
+	}
+
+	return true
+}
+
+// Input is what rules are evaluated on.
+type Input struct {
+	Address string
+
+	// Score is the deliverability score of the address, or nil if it was
+	// not scored.
+	Score *score.Result
+}
+
+// Decision is the outcome of evaluating a recipient.
+type Decision struct {
+	Action Action `json:"action"`
+
+	// Rule is the rule that decided, or nil if no rule matched.
+	Rule *Rule `json:"rule,omitempty"`
+}
+
+// Engine evaluates recipients against the rules of their tenant. It is safe
+// for concurrent use, including changing the rules while recipients are
+// evaluated.
+type Engine struct {
+	logger  *slog.Logger
+	journal journal.Journal
+
+	mu    sync.RWMutex
+	rules map[string][]compiledRule
+}
+
+// Option is a functional option for configuring Engine.
+type Option func(*Engine)
+
+// WithLogger sets a custom logger for Engine.
+func WithLogger(logger *slog.Logger) Option {
+	return func(e *Engine) {
+		e.logger = logger
+	}
+}
+
+// WithJournal records every decision in j, with the address redacted.
+func WithJournal(j journal.Journal) Option {
+	return func(e *Engine) {
+		e.journal = j
+	}
+}
+
+// New creates an Engine that allows every recipient until rules are set.
+func New(opts ...Option) *Engine {
+	e := &Engine{
+		logger: slog.Default(),
+		rules:  make(map[string][]compiledRule),
+	}
+
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	return e
+}
+
+// SetRules replaces the rules of a tenant, or the default rules for an
+// empty tenantID. Setting no rules makes the tenant use the default rules.
+// If any rule is invalid, the rules are left unchanged and the error is
+// returned.
+func (e *Engine) SetRules(tenantID string, rules []Rule) error {
+	compiled := make([]compiledRule, len(rules))
+	for i, r := range rules {
+		c, err := r.compile()
+		if err != nil {
+			return err
+		}
+		compiled[i] = c
+	}
+
+	e.mu.Lock()
+	if len(compiled) == 0 {
+		delete(e.rules, tenantID)
+	} else {
+		e.rules[tenantID] = compiled
+	}
+	e.mu.Unlock()
+
+	e.logger.Info("recipient rules replaced",
+		"tenant_id", tenantID,
+		"rules", len(compiled))
+
+	return nil
+}
+
+// Rules returns a copy of the rules of a tenant, not including the default
+// rules it falls back to.
+func (e *Engine) Rules(tenantID string) []Rule {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	rules := make([]Rule, 0, len(e.rules[tenantID]))
+	for _, c := range e.rules[tenantID] {
+		rules = append(rules, c.clone())
+	}
+
+	return rules
+}
+
+// Evaluate decides on a recipient with the rules of the tenant in ctx.
+func (e *Engine) Evaluate(ctx context.Context, in Input) *Decision {
+	address := strings.ToLower(strings.TrimSpace(in.Address))
+	var domain string
+	if normalized, err := emailaddr.Normalize(address); err == nil {
+		domain = normalized[strings.LastIndexByte(normalized, '@')+1:]
+	}
+
+	tenantID, _ := ctxkeys.TenantFrom(ctx)
+
+	e.mu.RLock()
+	rules, ok := e.rules[tenantID]
+	if !ok {
+		rules = e.rules[""]
+	}
+	decision := &Decision{Action: ActionAllow}
+	for i := range rules {
+		if rules[i].matches(address, domain, in.Score) {
+			rule := rules[i].clone()
+			decision = &Decision{Action: rule.Action, Rule: &rule}
+			break
+		}
+	}
+	e.mu.RUnlock()
+
+	e.record(ctx, address, decision)
+
+	return decision
+}
+
+// record journals a decision when a journal is configured.
+func (e *Engine) record(ctx context.Context, address string, d *Decision) {
+	if e.journal == nil {
+		return
+	}
+
+	entry := journal.Entry{
+		Operation: journalOperation,
+		Subject:   redact.Email(address),
+		Decision:  strings.ToLower(string(d.Action)),
+	}
+	if d.Rule != nil {
+		entry.Detail = d.Rule.Name
+	}
+
+	if err := e.journal.Record(ctx, entry); err != nil {
+		e.logger.Warn("failed to record journal entry", "error", err)
+	}
+}
@@ -0,0 +1,196 @@
+package rules
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/ctxkeys"
+	"github.com/jaeyeom/email-validator-grpc-mcp/journal"
+	"github.com/jaeyeom/email-validator-grpc-mcp/score"
+)
+
+func newEngine(t *testing.T, opts ...Option) *Engine {
+	t.Helper()
+
+	return New(append([]Option{WithLogger(slog.New(slog.DiscardHandler))}, opts...)...)
+}
+
+func intPtr(n int) *int {
+	return &n
+}
+
+func TestEngine_Evaluate(t *testing.T) {
+	t.Parallel()
+
+	e := newEngine(t)
+	if err := e.SetRules("", []Rule{
+		{Name: "staff", Domains: []string{"Corp.Example"}, Action: ActionAllow},
+		{Name: "test accounts", Pattern: `^test\+`, Action: ActionDeny},
+		{Name: "risky tld", Domains: []string{"*.zip", "*.mov"}, Action: ActionDeny, Reason: "abused tlds"},
+		{Name: "low score", MaxScore: intPtr(30), Action: ActionDeny},
+		{Name: "middling score", MinScore: intPtr(31), MaxScore: intPtr(60), Action: ActionStepUp},
+	}); err != nil {
+		t.Fatalf("SetRules() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		in         Input
+		wantAction Action
+		wantRule   string
+	}{
+		{"first match wins", Input{Address: "test+1@corp.example", Score: &score.Result{Score: 10}}, ActionAllow, "staff"},
+		{"pattern", Input{Address: " Test+1@example.com"}, ActionDeny, "test accounts"},
+		{"any domain pattern", Input{Address: "user@files.mov"}, ActionDeny, "risky tld"},
+		{"score below max", Input{Address: "user@example.com", Score: &score.Result{Score: 30}}, ActionDeny, "low score"},
+		{"score in range", Input{Address: "user@example.com", Score: &score.Result{Score: 45}}, ActionStepUp, "middling score"},
+		{"score above range", Input{Address: "user@example.com", Score: &score.Result{Score: 90}}, ActionAllow, ""},
+		{"not scored", Input{Address: "user@example.com"}, ActionAllow, ""},
+		{"malformed address", Input{Address: "not an address"}, ActionAllow, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			d := e.Evaluate(context.Background(), tt.in)
+			if d.Action != tt.wantAction {
+				t.Errorf("Evaluate() action = %s, want %s", d.Action, tt.wantAction)
+			}
+			var rule string
+			if d.Rule != nil {
+				rule = d.Rule.Name
+			}
+			if rule != tt.wantRule {
+				t.Errorf("Evaluate() rule = %q, want %q", rule, tt.wantRule)
+			}
+		})
+	}
+}
+
+func TestEngine_Tenants(t *testing.T) {
+	t.Parallel()
+
+	e := newEngine(t)
+	if err := e.SetRules("", []Rule{{Name: "default", Action: ActionDeny}}); err != nil {
+		t.Fatalf("SetRules() error = %v", err)
+	}
+	if err := e.SetRules("acme", []Rule{{Name: "acme", Action: ActionStepUp}}); err != nil {
+		t.Fatalf("SetRules() error = %v", err)
+	}
+
+	in := Input{Address: "user@example.com"}
+	tests := []struct {
+		ctx  context.Context
+		want Action
+	}{
+		{context.Background(), ActionDeny},
+		{ctxkeys.WithTenant(context.Background(), "acme"), ActionStepUp},
+		{ctxkeys.WithTenant(context.Background(), "other"), ActionDeny},
+	}
+	for _, tt := range tests {
+		if got := e.Evaluate(tt.ctx, in).Action; got != tt.want {
+			tenantID, _ := ctxkeys.TenantFrom(tt.ctx)
+			t.Errorf("Evaluate() for tenant %q = %s, want %s", tenantID, got, tt.want)
+		}
+	}
+
+	// Clearing a tenant's rules falls back to the default rules
+	if err := e.SetRules("acme", nil); err != nil {
+		t.Fatalf("SetRules() error = %v", err)
+	}
+	if got := e.Evaluate(ctxkeys.WithTenant(context.Background(), "acme"), in).Action; got != ActionDeny {
+		t.Errorf("Evaluate() after clearing = %s, want %s", got, ActionDeny)
+	}
+	if rules := e.Rules("acme"); len(rules) != 0 {
+		t.Errorf("Rules() = %v, want none", rules)
+	}
+}
+
+func TestEngine_SetRulesInvalid(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		rule Rule
+	}{
+		{"unknown action", Rule{Name: "r", Action: "QUARANTINE"}},
+		{"empty domain", Rule{Name: "r", Domains: []string{" "}, Action: ActionDeny}},
+		{"address as domain", Rule{Name: "r", Domains: []string{"user@example.com"}, Action: ActionDeny}},
+		{"malformed domain", Rule{Name: "r", Domains: []string{"[example.com"}, Action: ActionDeny}},
+		{"malformed pattern", Rule{Name: "r", Pattern: "(", Action: ActionDeny}},
+		{"empty score range", Rule{Name: "r", MinScore: intPtr(50), MaxScore: intPtr(40), Action: ActionDeny}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			e := newEngine(t)
+			if err := e.SetRules("", []Rule{{Name: "kept", Action: ActionStepUp}}); err != nil {
+				t.Fatalf("SetRules() error = %v", err)
+			}
+			if err := e.SetRules("", []Rule{tt.rule}); !errors.Is(err, ErrInvalidRule) {
+				t.Errorf("SetRules() error = %v, want %v", err, ErrInvalidRule)
+			}
+			if rules := e.Rules(""); len(rules) != 1 || rules[0].Name != "kept" {
+				t.Errorf("Rules() = %v, want the earlier rules unchanged", rules)
+			}
+		})
+	}
+}
+
+func TestEngine_RulesAreCopied(t *testing.T) {
+	t.Parallel()
+
+	e := newEngine(t)
+	rules := []Rule{{Name: "r", Domains: []string{"example.com"}, MaxScore: intPtr(50), Action: ActionDeny}}
+	if err := e.SetRules("", rules); err != nil {
+		t.Fatalf("SetRules() error = %v", err)
+	}
+
+	rules[0].Domains[0] = "other.example"
+	*rules[0].MaxScore = 0
+	got := e.Rules("")
+	got[0].Domains[0] = "other.example"
+
+	d := e.Evaluate(context.Background(), Input{Address: "user@example.com", Score: &score.Result{Score: 50}})
+	if d.Action != ActionDeny {
+		t.Errorf("Evaluate() = %s after modifying copies, want %s", d.Action, ActionDeny)
+	}
+}
+
+func TestEngine_Journal(t *testing.T) {
+	t.Parallel()
+
+	j := journal.NewRing(10)
+	e := newEngine(t, WithJournal(j))
+	if err := e.SetRules("", []Rule{{Name: "risky tld", Domains: []string{"*.zip"}, Action: ActionDeny}}); err != nil {
+		t.Fatalf("SetRules() error = %v", err)
+	}
+
+	ctx := ctxkeys.WithTenant(context.Background(), "acme")
+	e.Evaluate(ctx, Input{Address: "user@example.com"})
+	e.Evaluate(ctx, Input{Address: "user@files.zip"})
+
+	entries, err := j.Recent(ctx, "acme", 0)
+	if err != nil {
+		t.Fatalf("Recent() error = %v", err)
+	}
+	want := []journal.Entry{
+		{Operation: journalOperation, Subject: "u***@files.zip", Decision: "deny", Detail: "risky tld"},
+		{Operation: journalOperation, Subject: "u***@example.com", Decision: "allow"},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("Recent() = %v, want %d entries", entries, len(want))
+	}
+	for i, entry := range entries {
+		entry.Time = want[i].Time
+		entry.TenantID = ""
+		if entry != want[i] {
+			t.Errorf("entry %d = %+v, want %+v", i, entry, want[i])
+		}
+	}
+}
@@ -0,0 +1,249 @@
+// Package score combines the results of address checks into a single
+// deliverability score from 0 to 100, with reason codes explaining it.
+//
+// A score starts from a base and is adjusted by each signal available:
+// a mail server and a deliverable mailbox raise it; a disposable domain, a
+// role account, a catch-all domain, and similar doubts lower it. Definite
+// failures, such as invalid syntax, a domain without a mail server, or a
+// mailbox the server rejects, score 0 regardless of other signals. Signals
+// that were not checked do not affect the score, so a cheap check of syntax
+// and MX records gives a middling score that a mailbox probe can raise.
+package score
+
+import (
+	"strings"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/check/disposable"
+	"github.com/jaeyeom/email-validator-grpc-mcp/check/dns"
+	"github.com/jaeyeom/email-validator-grpc-mcp/check/smtpprobe"
+	"github.com/jaeyeom/email-validator-grpc-mcp/check/syntax"
+)
+
+// Score bounds.
+const (
+	Min = 0
+	Max = 100
+)
+
+// Code identifies a reason for a score. Codes are part of the API; new ones
+// may be added, but existing ones are not renamed.
+type Code string
+
+// Reason codes.
+const (
+	// CodeInvalidSyntax is an address with syntax issues.
+	CodeInvalidSyntax Code = "INVALID_SYNTAX"
+	// CodeNoMailServer is a domain that cannot receive mail.
+	CodeNoMailServer Code = "NO_MAIL_SERVER"
+	// CodeMailServer is a domain with a mail server.
+	CodeMailServer Code = "MAIL_SERVER"
+	// CodeImplicitMX is a domain without MX records that receives mail at
+	// its own address, an unusual setup for a mail domain.
+	CodeImplicitMX Code = "IMPLICIT_MX"
+	// CodeDisposable is a disposable domain.
+	CodeDisposable Code = "DISPOSABLE"
+	// CodeRoleAccount is an address of a role, such as "admin@", rather
+	// than a person.
+	CodeRoleAccount Code = "ROLE_ACCOUNT"
+	// CodeCatchAll is a domain that accepts mail for any mailbox, so a probe
+	// cannot tell whether the mailbox exists.
+	CodeCatchAll Code = "CATCH_ALL"
+	// CodeMailboxDeliverable is a mailbox the mail server accepted.
+	CodeMailboxDeliverable Code = "MAILBOX_DELIVERABLE"
+	// CodeMailboxUndeliverable is a mailbox the mail server rejected.
+	CodeMailboxUndeliverable Code = "MAILBOX_UNDELIVERABLE"
+	// CodeGreylisted is a mailbox the mail server deferred.
+	CodeGreylisted Code = "GREYLISTED"
+)
+
+// Weights are the adjustments each signal makes to a score.
+type Weights struct {
+	// Base is the score before adjustments.
+	Base int `json:"base"`
+
+	// MailServer is added for a domain with a mail server, and ImplicitMX
+	// subtracted if it has no MX records.
+	MailServer int `json:"mail_server"`
+	ImplicitMX int `json:"implicit_mx"`
+
+	// MailboxDeliverable is added for a mailbox the server accepted, unless
+	// the domain is a catch-all.
+	MailboxDeliverable int `json:"mailbox_deliverable"`
+
+	// The rest are subtracted.
+	Greylisted  int `json:"greylisted"`
+	Disposable  int `json:"disposable"`
+	RoleAccount int `json:"role_account"`
+	CatchAll    int `json:"catch_all"`
+}
+
+// DefaultWeights score a valid address with a mail server 70, and 100 once
+// a probe finds its mailbox.
+var DefaultWeights = Weights{
+	Base:               40,
+	MailServer:         30,
+	ImplicitMX:         10,
+	MailboxDeliverable: 30,
+	Greylisted:         5,
+	Disposable:         40,
+	RoleAccount:        15,
+	CatchAll:           20,
+}
+
+// DefaultRoleNames are local parts of role accounts.
+var DefaultRoleNames = []string{
+	"abuse", "admin", "administrator", "billing", "contact", "enquiries",
+	"help", "hello", "hostmaster", "info", "inquiries", "jobs", "marketing",
+	"no-reply", "noreply", "office", "postmaster", "privacy", "root",
+	"sales", "security", "support", "team", "webmaster",
+}
+
+// Signals are the check results of an address. Nil results were not
+// checked and do not affect the score.
+type Signals struct {
+	// Address is the address checked; its local part decides whether it is
+	// a role account.
+	Address string
+
+	Syntax     *syntax.Result
+	MailServer *dns.Result
+	Disposable *disposable.Result
+	Probe      *smtpprobe.Result
+
+	// CatchAll reports whether the domain accepts mail for any mailbox, as
+	// found by probing a mailbox that cannot exist.
+	CatchAll *bool
+}
+
+// Reason is one factor of a score.
+type Reason struct {
+	Code Code `json:"code"`
+
+	// Impact is the adjustment made to the score; for definite failures,
+	// it is what took the score to Min.
+	Impact int `json:"impact"`
+}
+
+// Result is a deliverability score: the base weight plus the impacts of
+// the reasons, clamped to Min and Max.
+type Result struct {
+	Score   int      `json:"score"`
+	Reasons []Reason `json:"reasons,omitempty"`
+}
+
+// Has reports whether the score has a reason with code.
+func (r *Result) Has(code Code) bool {
+	for _, reason := range r.Reasons {
+		if reason.Code == code {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Scorer scores signals. It is safe for concurrent use.
+type Scorer struct {
+	weights Weights
+	roles   map[string]struct{}
+}
+
+// Option is a functional option for configuring Scorer.
+type Option func(*Scorer)
+
+// WithWeights sets the weights of signals.
+func WithWeights(weights Weights) Option {
+	return func(s *Scorer) {
+		s.weights = weights
+	}
+}
+
+// WithRoleNames sets the local parts of role accounts.
+func WithRoleNames(names ...string) Option {
+	return func(s *Scorer) {
+		s.roles = make(map[string]struct{}, len(names))
+		for _, name := range names {
+			s.roles[strings.ToLower(name)] = struct{}{}
+		}
+	}
+}
+
+// New creates a Scorer.
+func New(opts ...Option) *Scorer {
+	s := &Scorer{weights: DefaultWeights}
+	WithRoleNames(DefaultRoleNames...)(s)
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Score scores signals.
+func (s *Scorer) Score(signals Signals) *Result {
+	w := s.weights
+	score := w.Base
+	var reasons []Reason
+
+	adjust := func(code Code, impact int) {
+		score += impact
+		reasons = append(reasons, Reason{Code: code, Impact: impact})
+	}
+	fail := func(code Code) *Result {
+		return &Result{Score: Min, Reasons: append(reasons, Reason{Code: code, Impact: Min - score})}
+	}
+
+	if signals.Syntax != nil && !signals.Syntax.Valid() {
+		return fail(CodeInvalidSyntax)
+	}
+
+	if mx := signals.MailServer; mx != nil {
+		if !mx.HasMailServer {
+			return fail(CodeNoMailServer)
+		}
+		adjust(CodeMailServer, w.MailServer)
+		if mx.ImplicitMX {
+			adjust(CodeImplicitMX, -w.ImplicitMX)
+		}
+	}
+
+	catchAll := signals.CatchAll != nil && *signals.CatchAll
+	if probe := signals.Probe; probe != nil {
+		switch {
+		case probe.Verdict == smtpprobe.VerdictUndeliverable && !catchAll:
+			return fail(CodeMailboxUndeliverable)
+		case probe.Verdict == smtpprobe.VerdictDeliverable && !catchAll:
+			adjust(CodeMailboxDeliverable, w.MailboxDeliverable)
+		case probe.Greylisted:
+			adjust(CodeGreylisted, -w.Greylisted)
+		}
+	}
+	if catchAll {
+		adjust(CodeCatchAll, -w.CatchAll)
+	}
+
+	if signals.Disposable != nil && signals.Disposable.Disposable {
+		adjust(CodeDisposable, -w.Disposable)
+	}
+
+	if s.isRole(signals.Address) {
+		adjust(CodeRoleAccount, -w.RoleAccount)
+	}
+
+	return &Result{Score: min(max(score, Min), Max), Reasons: reasons}
+}
+
+// isRole reports whether the local part of addr, without a "+" tag, is a
+// role name.
+func (s *Scorer) isRole(addr string) bool {
+	at := strings.LastIndexByte(addr, '@')
+	if at < 0 {
+		return false
+	}
+
+	local, _, _ := strings.Cut(strings.ToLower(addr[:at]), "+")
+	_, ok := s.roles[local]
+
+	return ok
+}
@@ -0,0 +1,115 @@
+package score
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/check/disposable"
+	"github.com/jaeyeom/email-validator-grpc-mcp/check/dns"
+	"github.com/jaeyeom/email-validator-grpc-mcp/check/smtpprobe"
+	"github.com/jaeyeom/email-validator-grpc-mcp/check/syntax"
+)
+
+func TestScorer_Score(t *testing.T) {
+	t.Parallel()
+
+	yes := true
+	mx := &dns.Result{Domain: "example.com", HasMailServer: true, MailServers: []string{"mx.example.com"}}
+	valid := syntax.Check("user@example.com")
+
+	tests := []struct {
+		name    string
+		signals Signals
+		want    int
+		codes   []Code
+	}{
+		{"nothing checked", Signals{Address: "user@example.com"}, 40, nil},
+		{"syntax and MX", Signals{Address: "user@example.com", Syntax: valid, MailServer: mx}, 70, []Code{CodeMailServer}},
+		{
+			"deliverable",
+			Signals{Address: "user@example.com", Syntax: valid, MailServer: mx, Probe: &smtpprobe.Result{Verdict: smtpprobe.VerdictDeliverable}},
+			100, []Code{CodeMailServer, CodeMailboxDeliverable},
+		},
+		{
+			"catch-all",
+			Signals{Address: "user@example.com", MailServer: mx, Probe: &smtpprobe.Result{Verdict: smtpprobe.VerdictDeliverable}, CatchAll: &yes},
+			50, []Code{CodeMailServer, CodeCatchAll},
+		},
+		{
+			"greylisted",
+			Signals{Address: "user@example.com", MailServer: mx, Probe: &smtpprobe.Result{Verdict: smtpprobe.VerdictUnknown, Greylisted: true}},
+			65, []Code{CodeMailServer, CodeGreylisted},
+		},
+		{
+			"implicit MX",
+			Signals{Address: "user@example.com", MailServer: &dns.Result{HasMailServer: true, ImplicitMX: true}},
+			60, []Code{CodeMailServer, CodeImplicitMX},
+		},
+		{
+			"disposable role",
+			Signals{Address: "Admin+x@mailinator.com", MailServer: mx, Disposable: &disposable.Result{Disposable: true}},
+			15, []Code{CodeMailServer, CodeDisposable, CodeRoleAccount},
+		},
+		{"invalid syntax", Signals{Address: "user@localhost", Syntax: syntax.Check("user@localhost"), MailServer: mx}, 0, []Code{CodeInvalidSyntax}},
+		{"no mail server", Signals{Address: "user@example.com", MailServer: &dns.Result{NullMX: true}}, 0, []Code{CodeNoMailServer}},
+		{
+			"undeliverable",
+			Signals{Address: "user@example.com", MailServer: mx, Probe: &smtpprobe.Result{Verdict: smtpprobe.VerdictUndeliverable}},
+			0, []Code{CodeMailServer, CodeMailboxUndeliverable},
+		},
+	}
+
+	s := New()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := s.Score(tt.signals)
+			var codes []Code
+			sum := DefaultWeights.Base
+			for _, r := range got.Reasons {
+				codes = append(codes, r.Code)
+				sum += r.Impact
+			}
+			if got.Score != tt.want || !slices.Equal(codes, tt.codes) {
+				t.Errorf("Score() = %+v, want %d with %v", got, tt.want, tt.codes)
+			}
+			if sum != got.Score {
+				t.Errorf("Score() impacts sum to %d, want %d", sum, got.Score)
+			}
+		})
+	}
+}
+
+func TestScorer_Options(t *testing.T) {
+	t.Parallel()
+
+	weights := DefaultWeights
+	weights.Base = 90
+	weights.MailServer = 20
+	s := New(WithWeights(weights), WithRoleNames("ops"))
+
+	got := s.Score(Signals{Address: "ops@example.com", MailServer: &dns.Result{HasMailServer: true}})
+	if want := 90 + 20 - weights.RoleAccount; got.Score != want || !got.Has(CodeRoleAccount) {
+		t.Errorf("Score() with custom options = %+v, want %d", got, want)
+	}
+	if got := New(WithWeights(weights)).Score(Signals{MailServer: &dns.Result{HasMailServer: true}}); got.Score != Max {
+		t.Errorf("Score() above the maximum = %+v, want %d", got, Max)
+	}
+	if s.Score(Signals{Address: "admin@example.com"}).Has(CodeRoleAccount) {
+		t.Error("Score() flagged a role name replaced by WithRoleNames")
+	}
+}
+
+func TestResult_JSON(t *testing.T) {
+	t.Parallel()
+
+	got, err := json.Marshal(New().Score(Signals{Address: "user@example.com", MailServer: &dns.Result{HasMailServer: true}}))
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if want := `{"score":70,"reasons":[{"code":"MAIL_SERVER","impact":30}]}`; string(got) != want {
+		t.Errorf("Marshal() = %s, want %s", got, want)
+	}
+}
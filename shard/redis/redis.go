@@ -0,0 +1,143 @@
+// Package redis provides a Redis-backed shard membership registry.
+package redis
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultKey is the default sorted set key holding member heartbeats.
+const DefaultKey = "shard:members"
+
+// DefaultMemberTTL is how long a member stays live after its last heartbeat.
+const DefaultMemberTTL = 30 * time.Second
+
+// Membership tracks live replicas in a Redis sorted set scored by the time of
+// their last heartbeat. Members that miss heartbeats for longer than the
+// member TTL are dropped from the list.
+type Membership struct {
+	client    *redis.Client
+	key       string
+	memberTTL time.Duration
+	logger    *slog.Logger
+}
+
+// Option is a functional option for configuring Membership.
+type Option func(*Membership)
+
+// WithLogger sets a custom logger for Membership.
+func WithLogger(logger *slog.Logger) Option {
+	return func(m *Membership) {
+		m.logger = logger
+	}
+}
+
+// WithKey sets the sorted set key used to store members.
+func WithKey(key string) Option {
+	return func(m *Membership) {
+		m.key = key
+	}
+}
+
+// WithMemberTTL sets how long a member remains live without a heartbeat.
+func WithMemberTTL(ttl time.Duration) Option {
+	return func(m *Membership) {
+		m.memberTTL = ttl
+	}
+}
+
+// New creates a new Redis-backed membership registry.
+func New(client *redis.Client, opts ...Option) *Membership {
+	m := &Membership{
+		client:    client,
+		key:       DefaultKey,
+		memberTTL: DefaultMemberTTL,
+		logger:    slog.Default(),
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m
+}
+
+// Heartbeat registers member as live, or refreshes its liveness.
+func (m *Membership) Heartbeat(ctx context.Context, member string) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("context error: %w", err)
+	}
+
+	err := m.client.ZAdd(ctx, m.key, redis.Z{
+		Score:  float64(time.Now().UnixMilli()),
+		Member: member,
+	}).Err()
+	if err != nil {
+		return fmt.Errorf("failed to record heartbeat: %w", err)
+	}
+
+	return nil
+}
+
+// Leave removes member from the registry immediately.
+func (m *Membership) Leave(ctx context.Context, member string) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("context error: %w", err)
+	}
+
+	if err := m.client.ZRem(ctx, m.key, member).Err(); err != nil {
+		return fmt.Errorf("failed to remove member: %w", err)
+	}
+
+	m.logger.Debug("shard member left", "member", member)
+
+	return nil
+}
+
+// Members returns all members whose last heartbeat is within the member TTL.
+// Stale members are pruned as a side effect.
+func (m *Membership) Members(ctx context.Context) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("context error: %w", err)
+	}
+
+	cutoff := strconv.FormatInt(time.Now().Add(-m.memberTTL).UnixMilli(), 10)
+
+	pipe := m.client.TxPipeline()
+	pipe.ZRemRangeByScore(ctx, m.key, "-inf", "("+cutoff)
+	members := pipe.ZRange(ctx, m.key, 0, -1)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to list members: %w", err)
+	}
+
+	return members.Val(), nil
+}
+
+// KeepAlive sends heartbeats for member every interval until ctx is done,
+// then removes the member from the registry.
+func (m *Membership) KeepAlive(ctx context.Context, member string, interval time.Duration) error {
+	if err := m.Heartbeat(ctx, member); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			// Use a fresh context so the member is removed even though ctx is done.
+			return m.Leave(context.WithoutCancel(ctx), member)
+		case <-ticker.C:
+			if err := m.Heartbeat(ctx, member); err != nil {
+				m.logger.Warn("shard heartbeat failed", "member", member, "error", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package redis
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/jaeyeom/email-validator-grpc-mcp/shard"
+	"github.com/redis/go-redis/v9"
+)
+
+func setupMiniRedis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	t.Helper()
+
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("Failed to start miniredis: %v", err)
+	}
+
+	client := redis.NewClient(&redis.Options{
+		Addr: mr.Addr(),
+	})
+
+	return mr, client
+}
+
+func TestMembership_HeartbeatAndLeave(t *testing.T) {
+	t.Parallel()
+
+	mr, client := setupMiniRedis(t)
+	defer mr.Close()
+
+	ctx := context.Background()
+	m := New(client)
+
+	for _, member := range []string{"replica-a", "replica-b"} {
+		if err := m.Heartbeat(ctx, member); err != nil {
+			t.Fatalf("Heartbeat() error = %v", err)
+		}
+	}
+
+	members, err := m.Members(ctx)
+	if err != nil {
+		t.Fatalf("Members() error = %v", err)
+	}
+	slices.Sort(members)
+	if !slices.Equal(members, []string{"replica-a", "replica-b"}) {
+		t.Errorf("Members() = %v, want [replica-a replica-b]", members)
+	}
+
+	if err := m.Leave(ctx, "replica-a"); err != nil {
+		t.Fatalf("Leave() error = %v", err)
+	}
+
+	members, err = m.Members(ctx)
+	if err != nil {
+		t.Fatalf("Members() error = %v", err)
+	}
+	if !slices.Equal(members, []string{"replica-b"}) {
+		t.Errorf("Members() after Leave = %v, want [replica-b]", members)
+	}
+}
+
+func TestMembership_PrunesStaleMembers(t *testing.T) {
+	t.Parallel()
+
+	mr, client := setupMiniRedis(t)
+	defer mr.Close()
+
+	ctx := context.Background()
+	m := New(client, WithMemberTTL(time.Minute))
+
+	// Simulate a replica whose last heartbeat is older than the TTL
+	stale := float64(time.Now().Add(-2 * time.Minute).UnixMilli())
+	if err := client.ZAdd(ctx, DefaultKey, redis.Z{Score: stale, Member: "replica-stale"}).Err(); err != nil {
+		t.Fatalf("Failed to seed stale member: %v", err)
+	}
+	if err := m.Heartbeat(ctx, "replica-live"); err != nil {
+		t.Fatalf("Heartbeat() error = %v", err)
+	}
+
+	members, err := m.Members(ctx)
+	if err != nil {
+		t.Fatalf("Members() error = %v", err)
+	}
+	if !slices.Equal(members, []string{"replica-live"}) {
+		t.Errorf("Members() = %v, want [replica-live]", members)
+	}
+}
+
+func TestMembership_WithRouter(t *testing.T) {
+	t.Parallel()
+
+	mr, client := setupMiniRedis(t)
+	defer mr.Close()
+
+	ctx := context.Background()
+	m := New(client, WithKey("test:members"))
+
+	for _, member := range []string{"replica-a", "replica-b", "replica-c"} {
+		if err := m.Heartbeat(ctx, member); err != nil {
+			t.Fatalf("Heartbeat() error = %v", err)
+		}
+	}
+
+	owner, err := shard.NewRouter("replica-a", m).Owner(ctx, "validation-123")
+	if err != nil {
+		t.Fatalf("Owner() error = %v", err)
+	}
+
+	want := shard.Pick([]string{"replica-a", "replica-b", "replica-c"}, "validation-123")
+	if owner != want {
+		t.Errorf("Owner() = %q, want %q", owner, want)
+	}
+}
@@ -0,0 +1,140 @@
+// Package shard provides deterministic assignment of validation IDs to
+// validator replicas so background work can be partitioned without
+// double-processing.
+package shard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+)
+
+// ErrNoMembers is returned when the membership source reports no live members.
+var ErrNoMembers = errors.New("no shard members available")
+
+// Membership provides the current list of live replicas.
+type Membership interface {
+	// Members returns the identifiers of all live replicas.
+	Members(ctx context.Context) ([]string, error)
+}
+
+// StaticMembership is a fixed member list, useful for tests and deployments
+// with a known set of replicas.
+type StaticMembership []string
+
+// Members returns the static member list.
+func (s StaticMembership) Members(_ context.Context) ([]string, error) {
+	return s, nil
+}
+
+// Router maps keys to members using rendezvous (highest random weight)
+// hashing. When a member joins or leaves, only the keys owned by that member
+// move.
+type Router struct {
+	self       string
+	membership Membership
+	logger     *slog.Logger
+}
+
+// Option is a functional option for configuring Router.
+type Option func(*Router)
+
+// WithLogger sets a custom logger for Router.
+func WithLogger(logger *slog.Logger) Option {
+	return func(r *Router) {
+		r.logger = logger
+	}
+}
+
+// NewRouter creates a new Router for the replica identified by self.
+func NewRouter(self string, membership Membership, opts ...Option) *Router {
+	r := &Router{
+		self:       self,
+		membership: membership,
+		logger:     slog.Default(),
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// Owner returns the member responsible for the given validation ID.
+func (r *Router) Owner(ctx context.Context, validationID string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", fmt.Errorf("context error: %w", err)
+	}
+
+	members, err := r.membership.Members(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to list shard members: %w", err)
+	}
+
+	owner := Pick(members, validationID)
+	if owner == "" {
+		return "", ErrNoMembers
+	}
+
+	return owner, nil
+}
+
+// Owns reports whether this replica is responsible for the given validation ID.
+func (r *Router) Owns(ctx context.Context, validationID string) (bool, error) {
+	owner, err := r.Owner(ctx, validationID)
+	if err != nil {
+		return false, err
+	}
+
+	r.logger.Debug("shard owner resolved",
+		"validation_id", validationID,
+		"owner", owner,
+		"self", r.self)
+
+	return owner == r.self, nil
+}
+
+// Pick returns the member with the highest rendezvous weight for key, or an
+// empty string if members is empty. Ties are broken by member name so the
+// result does not depend on the order of members.
+func Pick(members []string, key string) string {
+	var (
+		best       string
+		bestWeight uint64
+	)
+
+	for _, member := range members {
+		w := weight(member, key)
+		if best == "" || w > bestWeight || (w == bestWeight && member < best) {
+			best = member
+			bestWeight = w
+		}
+	}
+
+	return best
+}
+
+// weight computes the rendezvous weight of key on member.
+func weight(member, key string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(member))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(key))
+
+	return mix64(h.Sum64())
+}
+
+// mix64 is the splitmix64 finalizer, used to spread FNV output evenly across
+// the 64-bit range.
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+
+	return x
+}
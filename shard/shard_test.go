@@ -0,0 +1,99 @@
+package shard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestPick_Deterministic(t *testing.T) {
+	t.Parallel()
+
+	members := []string{"replica-a", "replica-b", "replica-c"}
+	reversed := []string{"replica-c", "replica-b", "replica-a"}
+
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("validation-%d", i)
+		if got, want := Pick(reversed, key), Pick(members, key); got != want {
+			t.Errorf("Pick(%q) depends on member order: got %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestPick_Empty(t *testing.T) {
+	t.Parallel()
+
+	if got := Pick(nil, "validation-1"); got != "" {
+		t.Errorf("Pick() with no members = %q, want empty", got)
+	}
+}
+
+func TestPick_MinimalMovement(t *testing.T) {
+	t.Parallel()
+
+	before := []string{"replica-a", "replica-b", "replica-c", "replica-d"}
+	after := []string{"replica-a", "replica-b", "replica-c"}
+
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("validation-%d", i)
+		owner := Pick(before, key)
+		if owner == "replica-d" {
+			continue
+		}
+		// Keys not owned by the removed member must stay where they were
+		if got := Pick(after, key); got != owner {
+			t.Errorf("key %q moved from %q to %q after unrelated member left", key, owner, got)
+		}
+	}
+}
+
+func TestPick_Distribution(t *testing.T) {
+	t.Parallel()
+
+	members := []string{"replica-a", "replica-b", "replica-c", "replica-d"}
+	counts := make(map[string]int)
+
+	const total = 10000
+	for i := 0; i < total; i++ {
+		counts[Pick(members, fmt.Sprintf("validation-%d", i))]++
+	}
+
+	expected := total / len(members)
+	for _, m := range members {
+		if counts[m] < expected*8/10 || counts[m] > expected*12/10 {
+			t.Errorf("member %q owns %d keys, want about %d", m, counts[m], expected)
+		}
+	}
+}
+
+func TestRouter_Owns(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	members := StaticMembership{"replica-a", "replica-b", "replica-c"}
+
+	owners := 0
+	for _, self := range members {
+		owns, err := NewRouter(self, members).Owns(ctx, "validation-123")
+		if err != nil {
+			t.Fatalf("Owns() error = %v", err)
+		}
+		if owns {
+			owners++
+		}
+	}
+
+	if owners != 1 {
+		t.Errorf("validation owned by %d replicas, want exactly 1", owners)
+	}
+}
+
+func TestRouter_NoMembers(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewRouter("replica-a", StaticMembership{}).Owner(context.Background(), "validation-123")
+	if !errors.Is(err, ErrNoMembers) {
+		t.Errorf("Owner() error = %v, want %v", err, ErrNoMembers)
+	}
+}
@@ -0,0 +1,299 @@
+// Package sla tracks, per tenant, how quickly validations are completed
+// after their tokens are issued, and raises alerts when a tenant falls below
+// a configured completion target.
+package sla
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/ctxkeys"
+	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+)
+
+// DefaultSampleSize is the default number of recent validations kept per
+// tenant.
+const DefaultSampleSize = 1000
+
+// DefaultWindows are the default completion windows reported in Stats.
+var DefaultWindows = []time.Duration{time.Minute, 5 * time.Minute, time.Hour}
+
+// Stats summarizes recent validations for a tenant.
+type Stats struct {
+	// Samples is the number of resolved validations the stats cover.
+	Samples int
+
+	// Verified is how many of them were verified.
+	Verified int
+
+	// Median is the median time to verification among verified samples.
+	Median time.Duration
+
+	// WithinWindow maps each configured window to the fraction of samples
+	// verified within it.
+	WithinWindow map[time.Duration]float64
+}
+
+// Threshold is a completion target: at least MinRatio of validations must
+// be verified within Window, judged once MinSamples are available.
+type Threshold struct {
+	Window     time.Duration
+	MinRatio   float64
+	MinSamples int
+}
+
+// AlertFunc is called when a tenant starts or stops meeting a threshold.
+type AlertFunc func(tenantID string, threshold Threshold, stats Stats, breached bool)
+
+// Tracker computes per-tenant completion stats online. A validation is
+// resolved either when it is verified or, unverified, once it has been
+// pending longer than the largest window or threshold window.
+type Tracker struct {
+	mu      sync.Mutex
+	pending map[pendingKey]time.Time
+	tenants map[string]*tenant
+
+	windows    []time.Duration
+	horizon    time.Duration
+	lastSweep  time.Time
+	sampleSize int
+	thresholds []Threshold
+	alert      AlertFunc
+	clock      token.Clock
+}
+
+// pendingKey identifies a started validation.
+type pendingKey struct {
+	tenantID     string
+	validationID string
+}
+
+// tenant holds a ring of recent samples and alert state.
+type tenant struct {
+	// samples holds time to verification, or -1 for unverified.
+	samples  []time.Duration
+	next     int
+	breached []bool
+}
+
+// Option is a functional option for configuring Tracker.
+type Option func(*Tracker)
+
+// WithWindows sets the completion windows reported in Stats.
+func WithWindows(windows ...time.Duration) Option {
+	return func(t *Tracker) {
+		if len(windows) > 0 {
+			t.windows = slices.Clone(windows)
+		}
+	}
+}
+
+// WithSampleSize sets how many recent validations are kept per tenant.
+func WithSampleSize(size int) Option {
+	return func(t *Tracker) {
+		if size > 0 {
+			t.sampleSize = size
+		}
+	}
+}
+
+// WithAlert calls fn whenever a tenant starts or stops meeting one of the
+// thresholds. Alerts are edge-triggered and run with the tracker locked, so
+// fn must return quickly.
+func WithAlert(fn AlertFunc, thresholds ...Threshold) Option {
+	return func(t *Tracker) {
+		t.alert = fn
+		t.thresholds = slices.Clone(thresholds)
+	}
+}
+
+// WithClock sets the clock used to time validations.
+func WithClock(clock token.Clock) Option {
+	return func(t *Tracker) {
+		t.clock = clock
+	}
+}
+
+// New creates a Tracker.
+func New(opts ...Option) *Tracker {
+	t := &Tracker{
+		pending:    make(map[pendingKey]time.Time),
+		tenants:    make(map[string]*tenant),
+		windows:    DefaultWindows,
+		sampleSize: DefaultSampleSize,
+		clock:      token.SystemClock,
+	}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	t.horizon = slices.Max(t.windows)
+	for _, th := range t.thresholds {
+		t.horizon = max(t.horizon, th.Window)
+	}
+
+	return t
+}
+
+// Hooks returns Manager hooks that feed the tracker. The tenant is taken
+// from the context with ctxkeys.TenantFrom.
+func (t *Tracker) Hooks() token.Hooks {
+	return token.Hooks{
+		OnCreated: func(ctx context.Context, tkn *token.Token) {
+			tenantID, _ := ctxkeys.TenantFrom(ctx)
+			t.Started(tenantID, tkn.ValidationID)
+		},
+		OnVerified: func(ctx context.Context, tkn *token.Token) {
+			tenantID, _ := ctxkeys.TenantFrom(ctx)
+			t.Completed(tenantID, tkn.ValidationID)
+		},
+	}
+}
+
+// Started records that a validation began. Only the first start of a
+// validation counts, so resending does not reset its clock.
+func (t *Tracker) Started(tenantID, validationID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.clock.Now()
+	t.expire(now, false)
+
+	key := pendingKey{tenantID: tenantID, validationID: validationID}
+	if _, ok := t.pending[key]; !ok {
+		t.pending[key] = now
+	}
+}
+
+// Completed records that a validation was verified. Validations that were
+// not started through the tracker, or already resolved, are ignored.
+func (t *Tracker) Completed(tenantID, validationID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.clock.Now()
+	t.expire(now, false)
+
+	key := pendingKey{tenantID: tenantID, validationID: validationID}
+	started, ok := t.pending[key]
+	if !ok {
+		return
+	}
+
+	delete(t.pending, key)
+	t.resolve(tenantID, now.Sub(started))
+}
+
+// Snapshot returns the current stats for a tenant.
+func (t *Tracker) Snapshot(tenantID string) Stats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.expire(t.clock.Now(), true)
+
+	return t.stats(t.tenants[tenantID])
+}
+
+// sweepInterval bounds how often pending validations are scanned for
+// expiry while recording events.
+const sweepInterval = time.Second
+
+// expire resolves validations pending longer than the tracking horizon as
+// unverified. Scans are rate limited to sweepInterval unless force is set.
+func (t *Tracker) expire(now time.Time, force bool) {
+	if !force && now.Sub(t.lastSweep) < sweepInterval {
+		return
+	}
+	t.lastSweep = now
+
+	for key, started := range t.pending {
+		if now.Sub(started) > t.horizon {
+			delete(t.pending, key)
+			t.resolve(key.tenantID, -1)
+		}
+	}
+}
+
+// resolve adds a sample for a tenant and evaluates its thresholds.
+func (t *Tracker) resolve(tenantID string, elapsed time.Duration) {
+	ten, ok := t.tenants[tenantID]
+	if !ok {
+		ten = &tenant{breached: make([]bool, len(t.thresholds))}
+		t.tenants[tenantID] = ten
+	}
+
+	if len(ten.samples) < t.sampleSize {
+		ten.samples = append(ten.samples, elapsed)
+	} else {
+		ten.samples[ten.next] = elapsed
+		ten.next = (ten.next + 1) % t.sampleSize
+	}
+
+	if t.alert == nil {
+		return
+	}
+
+	for i, th := range t.thresholds {
+		if len(ten.samples) < th.MinSamples {
+			continue
+		}
+
+		breached := ratioWithin(ten.samples, th.Window) < th.MinRatio
+		if breached != ten.breached[i] {
+			ten.breached[i] = breached
+			t.alert(tenantID, th, t.stats(ten), breached)
+		}
+	}
+}
+
+// stats summarizes a tenant's samples.
+func (t *Tracker) stats(ten *tenant) Stats {
+	stats := Stats{WithinWindow: make(map[time.Duration]float64, len(t.windows))}
+	if ten == nil || len(ten.samples) == 0 {
+		return stats
+	}
+
+	var verified []time.Duration
+	for _, d := range ten.samples {
+		if d >= 0 {
+			verified = append(verified, d)
+		}
+	}
+
+	stats.Samples = len(ten.samples)
+	stats.Verified = len(verified)
+
+	if len(verified) > 0 {
+		slices.Sort(verified)
+		mid := len(verified) / 2
+		stats.Median = verified[mid]
+		if len(verified)%2 == 0 {
+			stats.Median = (verified[mid-1] + verified[mid]) / 2
+		}
+	}
+
+	for _, w := range t.windows {
+		stats.WithinWindow[w] = ratioWithin(ten.samples, w)
+	}
+
+	return stats
+}
+
+// ratioWithin returns the fraction of samples verified within window.
+func ratioWithin(samples []time.Duration, window time.Duration) float64 {
+	if len(samples) == 0 {
+		return 0
+	}
+
+	within := 0
+	for _, d := range samples {
+		if d >= 0 && d <= window {
+			within++
+		}
+	}
+
+	return float64(within) / float64(len(samples))
+}
@@ -0,0 +1,101 @@
+package sla
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/ctxkeys"
+	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+)
+
+func TestTracker_Stats(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	tracker := New(
+		WithWindows(time.Minute, 10*time.Minute),
+		WithClock(token.ClockFunc(func() time.Time { return now })),
+	)
+
+	tracker.Started("tenant-a", "v1")
+	tracker.Started("tenant-a", "v2")
+	tracker.Started("tenant-a", "v3")
+	tracker.Started("tenant-b", "v1")
+
+	now = now.Add(30 * time.Second)
+	tracker.Completed("tenant-a", "v1")
+
+	// A resend does not restart the clock
+	tracker.Started("tenant-a", "v2")
+	now = now.Add(4*time.Minute + 30*time.Second)
+	tracker.Completed("tenant-a", "v2")
+
+	// v3 is never verified and resolves after the largest window
+	now = now.Add(time.Hour)
+	got := tracker.Snapshot("tenant-a")
+
+	if got.Samples != 3 || got.Verified != 2 {
+		t.Fatalf("Snapshot() samples/verified = %d/%d, want 3/2", got.Samples, got.Verified)
+	}
+	if want := (30*time.Second + 5*time.Minute) / 2; got.Median != want {
+		t.Errorf("Snapshot() median = %v, want %v", got.Median, want)
+	}
+	if got.WithinWindow[time.Minute] != 1.0/3 || got.WithinWindow[10*time.Minute] != 2.0/3 {
+		t.Errorf("Snapshot() within windows = %v, want 1/3 and 2/3", got.WithinWindow)
+	}
+
+	if other := tracker.Snapshot("tenant-b"); other.Samples != 1 || other.Verified != 0 {
+		t.Errorf("Snapshot(tenant-b) = %+v, want one unverified sample", other)
+	}
+}
+
+func TestTracker_Alerts(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	var alerts []bool
+	tracker := New(
+		WithClock(token.ClockFunc(func() time.Time { return now })),
+		WithAlert(func(tenantID string, _ Threshold, _ Stats, breached bool) {
+			if tenantID != "tenant-a" {
+				t.Errorf("alert for tenant %q, want tenant-a", tenantID)
+			}
+			alerts = append(alerts, breached)
+		}, Threshold{Window: time.Minute, MinRatio: 0.5, MinSamples: 2}),
+	)
+
+	complete := func(validationID string, after time.Duration) {
+		tracker.Started("tenant-a", validationID)
+		now = now.Add(after)
+		tracker.Completed("tenant-a", validationID)
+	}
+
+	complete("slow-1", 5*time.Minute) // below MinSamples, no alert yet
+	complete("slow-2", 5*time.Minute) // 0/2 within a minute: breached
+	complete("slow-3", 5*time.Minute) // still breached, no repeat alert
+	complete("fast-1", time.Second)   // 1/4
+	complete("fast-2", time.Second)   // 2/5
+	complete("fast-3", time.Second)   // 3/6: recovered
+
+	want := []bool{true, false}
+	if len(alerts) != len(want) || alerts[0] != want[0] || alerts[1] != want[1] {
+		t.Errorf("alerts = %v, want %v", alerts, want)
+	}
+}
+
+func TestTracker_Hooks(t *testing.T) {
+	t.Parallel()
+
+	tracker := New()
+	hooks := tracker.Hooks()
+	ctx := ctxkeys.WithTenant(context.Background(), "tenant-a")
+
+	tkn := &token.Token{ValidationID: "v1"}
+	hooks.OnCreated(ctx, tkn)
+	hooks.OnVerified(ctx, tkn)
+
+	if got := tracker.Snapshot("tenant-a"); got.Verified != 1 {
+		t.Errorf("Snapshot() verified = %d, want 1", got.Verified)
+	}
+}
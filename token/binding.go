@@ -0,0 +1,176 @@
+package token
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+)
+
+// ErrEmailMismatch is returned when a token is presented for an email
+// address other than the one it was issued for.
+var ErrEmailMismatch = errors.New("token is bound to a different email address")
+
+// Metadata keys under which a token's bound email address is stored.
+const (
+	MetadataKeyEmail     = "email"
+	MetadataKeyEmailHash = "email_sha256"
+)
+
+// EmailPrivacy controls how a bound email address is stored with a token.
+type EmailPrivacy int
+
+const (
+	// EmailHashed stores a SHA-256 digest of the normalized address.
+	EmailHashed EmailPrivacy = iota
+	// EmailPlaintext stores the normalized address itself.
+	EmailPlaintext
+)
+
+// WithEmailPrivacy sets how email addresses bound with CreateOptions.Email
+// are stored. The default is EmailHashed. Tokens bound under either mode
+// can be checked regardless of the current setting.
+func WithEmailPrivacy(privacy EmailPrivacy) ManagerOption {
+	return func(m *Manager) {
+		m.emailPrivacy = privacy
+	}
+}
+
+// normalizeEmail canonicalizes an address for binding. Addresses are
+// compared case-insensitively.
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// emailDigest returns the hex-encoded SHA-256 digest of a normalized address.
+func emailDigest(email string) string {
+	sum := sha256.Sum256([]byte(normalizeEmail(email)))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// metadataFor returns the metadata to store for a token created with opts,
+// adding the email binding when an address is given.
+func (m *Manager) metadataFor(opts CreateOptions) map[string]string {
+	if opts.Email == "" {
+		return opts.Metadata
+	}
+
+	metadata := maps.Clone(opts.Metadata)
+	if metadata == nil {
+		metadata = make(map[string]string, 1)
+	}
+
+	if m.emailPrivacy == EmailPlaintext {
+		metadata[MetadataKeyEmail] = normalizeEmail(opts.Email)
+	} else {
+		metadata[MetadataKeyEmailHash] = emailDigest(opts.Email)
+	}
+
+	return metadata
+}
+
+// checkEmail rejects a token bound to an address other than email. Tokens
+// created without a binding are accepted.
+func (m *Manager) checkEmail(ctx context.Context, token *Token, email string) error {
+	var match bool
+
+	if bound, ok := token.Metadata[MetadataKeyEmail]; ok {
+		match = bound == normalizeEmail(email)
+	} else if digest, ok := token.Metadata[MetadataKeyEmailHash]; ok {
+		match = subtle.ConstantTimeCompare([]byte(digest), []byte(emailDigest(email))) == 1
+	} else {
+		return nil
+	}
+
+	if !match {
+		m.log(ctx).Warn("token presented for a different email address",
+			"token_type", token.Type,
+			"validation_id", token.ValidationID)
+		return ErrEmailMismatch
+	}
+
+	return nil
+}
+
+// VerifyTokenForEmail is like VerifyToken but also rejects the token with
+// ErrEmailMismatch if it was issued for an address other than email.
+func (m *Manager) VerifyTokenForEmail(ctx context.Context, tokenValue string, tokenType Type, email string) (*Token, error) {
+	tokenValue = m.normalize(tokenValue, tokenType)
+	token, err := m.verifyToken(ctx, tokenValue, tokenType)
+	if err == nil {
+		if err = m.checkEmail(ctx, token, email); err != nil {
+			token = nil
+		}
+	}
+	m.record(ctx, "VerifyTokenForEmail", tokenValue, validationIDOf(token), err)
+
+	if err == nil {
+		fire(ctx, m.hooks.OnVerified, token)
+	}
+
+	return token, err
+}
+
+// VerifyAndConsumeForEmail is like VerifyAndConsume but first rejects the
+// token with ErrEmailMismatch if it was issued for an address other than
+// email. A token presented for the wrong address is not consumed, so the
+// rightful recipient can still use it.
+func (m *Manager) VerifyAndConsumeForEmail(ctx context.Context, tokenValue string, tokenType Type, email string) (*Token, error) {
+	tokenValue = m.normalize(tokenValue, tokenType)
+	token, err := m.verifyAndConsumeForEmail(ctx, tokenValue, tokenType, email)
+	m.record(ctx, "VerifyAndConsumeForEmail", tokenValue, validationIDOf(token), err)
+
+	if err == nil {
+		fire(ctx, m.hooks.OnVerified, token)
+	}
+
+	return token, err
+}
+
+// verifyAndConsumeForEmail implements VerifyAndConsumeForEmail without
+// journaling.
+func (m *Manager) verifyAndConsumeForEmail(ctx context.Context, tokenValue string, tokenType Type, email string) (*Token, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("context error: %w", err)
+	}
+
+	if tokenValue == "" {
+		return nil, ErrEmptyTokenValue
+	}
+
+	// Checking the binding before the atomic consume leaves a token
+	// presented for the wrong address in place. The pre-check is best
+	// effort: it misses tokens it cannot read, such as on a storage error
+	// or in a legacy format, so the consumed token is checked again and
+	// restored if it is bound to another address.
+	if !m.isStateless(tokenType) {
+		token, err := m.storage.Retrieve(ctx, tokenValue, tokenType)
+		if err == nil {
+			if err := m.checkEmail(ctx, token, email); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	token, err := m.verifyAndConsume(ctx, tokenValue, tokenType)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.checkEmail(ctx, token, email); err != nil {
+		if restoreErr := m.storage.Store(ctx, token); restoreErr != nil {
+			m.log(ctx).Error("failed to restore token consumed for a different email address",
+				"error", restoreErr,
+				"token_type", token.Type,
+				"validation_id", token.ValidationID)
+		}
+		return nil, err
+	}
+
+	return token, nil
+}
@@ -0,0 +1,50 @@
+package token
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Codec serializes tokens for storage backends that persist them as bytes.
+type Codec interface {
+	Marshal(t *Token) ([]byte, error)
+	Unmarshal(data []byte) (*Token, error)
+}
+
+// KeyedCodec is a Codec that can bind a serialized token to the storage key
+// it is written under, so that a record copied under another key fails to
+// decode. Storage backends that know the key of each record use these
+// methods in place of Marshal and Unmarshal when their codec implements
+// them.
+type KeyedCodec interface {
+	Codec
+	MarshalKeyed(t *Token, key string) ([]byte, error)
+	UnmarshalKeyed(data []byte, key string) (*Token, error)
+}
+
+// JSONCodec encodes tokens as JSON. It is the default for storage backends
+// that accept a Codec.
+var JSONCodec Codec = jsonCodec{}
+
+// jsonCodec implements Codec with encoding/json.
+type jsonCodec struct{}
+
+// Marshal encodes t as JSON.
+func (jsonCodec) Marshal(t *Token) ([]byte, error) {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return nil, fmt.Errorf("json codec: %w", err)
+	}
+
+	return data, nil
+}
+
+// Unmarshal decodes a JSON-encoded token.
+func (jsonCodec) Unmarshal(data []byte) (*Token, error) {
+	var t Token
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("json codec: %w", err)
+	}
+
+	return &t, nil
+}
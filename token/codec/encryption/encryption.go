@@ -0,0 +1,274 @@
+// Package encryption provides a token.Codec that encrypts serialized tokens
+// with AES-GCM before they reach a persistent backend.
+//
+// Each ciphertext starts with a header naming the key that sealed it, so keys
+// can be rotated: new tokens are sealed with the provider's current key while
+// tokens sealed with older keys stay readable as long as the provider still
+// knows them.
+//
+// Storage backends that know the key of each record, such as the Redis
+// backend, seal tokens with MarshalKeyed, which authenticates the record key
+// along with the header, so a ciphertext copied under another token's key
+// fails to decrypt.
+//
+// Only record contents are encrypted. Backends name records after the
+// tokens they hold: the Redis backend stores each token under a key
+// containing its value and lists those keys in the validation index. Anyone
+// able to list keys in the backend therefore learns every live token value,
+// and can verify with it, without the encryption key. The codec protects
+// bound email addresses and metadata at rest; access to the backend itself
+// must still be restricted.
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+)
+
+// Format versions, the first byte of every ciphertext.
+const (
+	// formatVersion is a ciphertext authenticating only its header.
+	formatVersion = 1
+
+	// formatKeyedVersion is a ciphertext authenticating its header and the
+	// key of the record holding it.
+	formatKeyedVersion = 2
+)
+
+// maxKeyIDLength is the longest key ID that fits in the header.
+const maxKeyIDLength = 255
+
+// Errors returned by the encryption codec.
+var (
+	ErrUnknownKey    = errors.New("unknown encryption key")
+	ErrInvalidKey    = errors.New("encryption key must be 16, 24, or 32 bytes")
+	ErrInvalidKeyID  = errors.New("encryption key ID must be 1 to 255 bytes")
+	ErrMalformed     = errors.New("malformed encrypted token")
+	ErrDecryptFailed = errors.New("failed to decrypt token")
+	ErrNoCurrentKey  = errors.New("current encryption key not found")
+)
+
+// KeyProvider supplies AES keys by ID.
+type KeyProvider interface {
+	// CurrentKey returns the ID and key used to encrypt new tokens.
+	CurrentKey() (id string, key []byte, err error)
+
+	// Key returns the key with the given ID, or ErrUnknownKey.
+	Key(id string) ([]byte, error)
+}
+
+// StaticKeys is a KeyProvider backed by a fixed set of keys.
+type StaticKeys struct {
+	current string
+	keys    map[string][]byte
+}
+
+// NewStaticKeys returns a provider that encrypts with the key named current
+// and decrypts with any key in keys. Keys must be 16, 24, or 32 bytes long
+// to select AES-128, AES-192, or AES-256.
+func NewStaticKeys(current string, keys map[string][]byte) (*StaticKeys, error) {
+	for id, key := range keys {
+		if len(id) == 0 || len(id) > maxKeyIDLength {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidKeyID, id)
+		}
+		if _, err := aes.NewCipher(key); err != nil {
+			return nil, fmt.Errorf("%w: key %q", ErrInvalidKey, id)
+		}
+	}
+
+	if _, ok := keys[current]; !ok {
+		return nil, fmt.Errorf("%w: %q", ErrNoCurrentKey, current)
+	}
+
+	return &StaticKeys{current: current, keys: maps.Clone(keys)}, nil
+}
+
+// CurrentKey returns the key used for encryption.
+func (s *StaticKeys) CurrentKey() (string, []byte, error) {
+	return s.current, s.keys[s.current], nil
+}
+
+// Key returns the key with the given ID.
+func (s *StaticKeys) Key(id string) ([]byte, error) {
+	key, ok := s.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, id)
+	}
+
+	return key, nil
+}
+
+// Codec wraps another codec and encrypts its output. The ciphertext layout
+// is: version byte, key ID length byte, key ID, 12-byte nonce, then the
+// sealed payload. The header, and the record key for MarshalKeyed, are
+// authenticated along with the payload.
+type Codec struct {
+	inner token.Codec
+	keys  KeyProvider
+}
+
+// New returns a codec that encrypts the output of inner with keys from
+// keys. A nil inner codec defaults to token.JSONCodec.
+func New(inner token.Codec, keys KeyProvider) *Codec {
+	if inner == nil {
+		inner = token.JSONCodec
+	}
+
+	return &Codec{inner: inner, keys: keys}
+}
+
+// Marshal serializes t with the inner codec and encrypts the result with
+// the current key.
+func (c *Codec) Marshal(t *token.Token) ([]byte, error) {
+	return c.seal(t, formatVersion, nil)
+}
+
+// MarshalKeyed is like Marshal, but binds the result to the record key it
+// is stored under, so only UnmarshalKeyed with the same key decrypts it.
+func (c *Codec) MarshalKeyed(t *token.Token, key string) ([]byte, error) {
+	return c.seal(t, formatKeyedVersion, []byte(key))
+}
+
+// Unmarshal decrypts data with the key named in its header and decodes the
+// plaintext with the inner codec. Data sealed by MarshalKeyed cannot be
+// decrypted without its record key.
+func (c *Codec) Unmarshal(data []byte) (*token.Token, error) {
+	if len(data) > 0 && data[0] == formatKeyedVersion {
+		return nil, fmt.Errorf("%w: token is bound to its record key", ErrDecryptFailed)
+	}
+
+	return c.open(data, nil)
+}
+
+// UnmarshalKeyed decrypts data read from the record key, which must be the
+// key it was sealed for. Data sealed by Marshal, which is bound to no key,
+// is accepted too, so records written before the backend bound them stay
+// readable until they expire.
+func (c *Codec) UnmarshalKeyed(data []byte, key string) (*token.Token, error) {
+	return c.open(data, []byte(key))
+}
+
+// seal serializes t and encrypts it with the current key, authenticating the
+// header and recordKey.
+func (c *Codec) seal(t *token.Token, version byte, recordKey []byte) ([]byte, error) {
+	plaintext, err := c.inner.Marshal(t)
+	if err != nil {
+		return nil, fmt.Errorf("inner codec: %w", err)
+	}
+
+	id, key, err := c.keys.CurrentKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current key: %w", err)
+	}
+	if len(id) == 0 || len(id) > maxKeyIDLength {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidKeyID, id)
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 0, 2+len(id)+aead.NonceSize())
+	header = append(header, version, byte(len(id)))
+	header = append(header, id...)
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	out := append(header, nonce...)
+
+	return aead.Seal(out, nonce, plaintext, additionalData(header, recordKey)), nil
+}
+
+// open decrypts data with the key named in its header and decodes the
+// plaintext with the inner codec. The record key is authenticated only for
+// data in the keyed format.
+func (c *Codec) open(data, recordKey []byte) (*token.Token, error) {
+	id, err := KeyID(data)
+	if err != nil {
+		return nil, err
+	}
+
+	header := data[:2+len(id)]
+	if header[0] == formatVersion {
+		recordKey = nil
+	}
+
+	key, err := c.keys.Key(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get key: %w", err)
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	rest := data[len(header):]
+	if len(rest) < aead.NonceSize()+aead.Overhead() {
+		return nil, ErrMalformed
+	}
+
+	nonce, sealed := rest[:aead.NonceSize()], rest[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, sealed, additionalData(header, recordKey))
+	if err != nil {
+		return nil, fmt.Errorf("%w: key %q", ErrDecryptFailed, id)
+	}
+
+	t, err := c.inner.Unmarshal(plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("inner codec: %w", err)
+	}
+
+	return t, nil
+}
+
+// additionalData returns the data authenticated with a ciphertext: its
+// header followed by the record key, if any.
+func additionalData(header, recordKey []byte) []byte {
+	if recordKey == nil {
+		return header
+	}
+
+	return append(slices.Clip(header), recordKey...)
+}
+
+// KeyID returns the ID of the key that encrypted data, so callers can find
+// tokens that still need re-encryption after a rotation.
+func KeyID(data []byte) (string, error) {
+	if len(data) < 2 || (data[0] != formatVersion && data[0] != formatKeyedVersion) {
+		return "", ErrMalformed
+	}
+
+	idLen := int(data[1])
+	if idLen == 0 || len(data) < 2+idLen {
+		return "", ErrMalformed
+	}
+
+	return string(data[2 : 2+idLen]), nil
+}
+
+// newAEAD returns an AES-GCM AEAD for key.
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, ErrInvalidKey
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+
+	return aead, nil
+}
@@ -0,0 +1,249 @@
+package encryption
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+	"github.com/jaeyeom/email-validator-grpc-mcp/token/codec/protobuf"
+)
+
+func testToken() *token.Token {
+	created := time.Date(2025, 3, 1, 12, 30, 0, 0, time.UTC)
+
+	return &token.Token{
+		Value:        "123456",
+		Type:         token.TypeCode,
+		CreatedAt:    created,
+		ValidUntil:   created.Add(10 * time.Minute),
+		ValidationID: "validation-123",
+		Metadata:     map[string]string{"email": "user@example.com"},
+	}
+}
+
+func mustKeys(t *testing.T, current string, keys map[string][]byte) *StaticKeys {
+	t.Helper()
+
+	p, err := NewStaticKeys(current, keys)
+	if err != nil {
+		t.Fatalf("NewStaticKeys() error = %v", err)
+	}
+
+	return p
+}
+
+func TestCodec_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		inner token.Codec
+		key   []byte
+	}{
+		{name: "json AES-128", inner: nil, key: bytes.Repeat([]byte{1}, 16)},
+		{name: "json AES-256", inner: token.JSONCodec, key: bytes.Repeat([]byte{2}, 32)},
+		{name: "protobuf AES-192", inner: protobuf.New(), key: bytes.Repeat([]byte{3}, 24)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			codec := New(tt.inner, mustKeys(t, "k1", map[string][]byte{"k1": tt.key}))
+			want := testToken()
+
+			data, err := codec.Marshal(want)
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			if bytes.Contains(data, []byte("user@example.com")) {
+				t.Error("Marshal() output contains plaintext metadata")
+			}
+
+			got, err := codec.Unmarshal(data)
+			if err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Unmarshal() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestCodec_Rotation(t *testing.T) {
+	t.Parallel()
+
+	oldKey := bytes.Repeat([]byte{1}, 32)
+	newKey := bytes.Repeat([]byte{2}, 32)
+
+	before := New(nil, mustKeys(t, "2024", map[string][]byte{"2024": oldKey}))
+	data, err := before.Marshal(testToken())
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+
+	after := New(nil, mustKeys(t, "2025", map[string][]byte{"2024": oldKey, "2025": newKey}))
+	if _, err := after.Unmarshal(data); err != nil {
+		t.Fatalf("Unmarshal() of token sealed with retired key error = %v", err)
+	}
+
+	fresh, err := after.Marshal(testToken())
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if id, _ := KeyID(fresh); id != "2025" {
+		t.Errorf("KeyID() = %q, want %q", id, "2025")
+	}
+	if id, _ := KeyID(data); id != "2024" {
+		t.Errorf("KeyID() = %q, want %q", id, "2024")
+	}
+
+	retired := New(nil, mustKeys(t, "2025", map[string][]byte{"2025": newKey}))
+	if _, err := retired.Unmarshal(data); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Unmarshal() error = %v, want %v", err, ErrUnknownKey)
+	}
+}
+
+func TestCodec_Tampering(t *testing.T) {
+	t.Parallel()
+
+	keys := mustKeys(t, "k1", map[string][]byte{
+		"k1": bytes.Repeat([]byte{1}, 16),
+		"k2": bytes.Repeat([]byte{2}, 16),
+	})
+	codec := New(nil, keys)
+
+	data, err := codec.Marshal(testToken())
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+
+	flipped := bytes.Clone(data)
+	flipped[len(flipped)-1] ^= 0xff
+
+	// Relabeling the header must fail even when the named key exists.
+	relabeled := bytes.Clone(data)
+	relabeled[3] = '2'
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr error
+	}{
+		{name: "flipped ciphertext", data: flipped, wantErr: ErrDecryptFailed},
+		{name: "relabeled key ID", data: relabeled, wantErr: ErrDecryptFailed},
+		{name: "truncated", data: data[:10], wantErr: ErrMalformed},
+		{name: "empty", data: nil, wantErr: ErrMalformed},
+		{name: "wrong version", data: append([]byte{9}, data[1:]...), wantErr: ErrMalformed},
+		{name: "plain JSON", data: []byte(`{"value":"x"}`), wantErr: ErrMalformed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := codec.Unmarshal(tt.data); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Unmarshal() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCodec_Keyed(t *testing.T) {
+	t.Parallel()
+
+	codec := New(nil, mustKeys(t, "k1", map[string][]byte{"k1": bytes.Repeat([]byte{1}, 16)}))
+	want := testToken()
+
+	data, err := codec.MarshalKeyed(want, "token:123456:1")
+	if err != nil {
+		t.Fatalf("MarshalKeyed() error = %v", err)
+	}
+	if id, err := KeyID(data); err != nil || id != "k1" {
+		t.Errorf("KeyID() = %q, %v, want k1", id, err)
+	}
+
+	got, err := codec.UnmarshalKeyed(data, "token:123456:1")
+	if err != nil {
+		t.Fatalf("UnmarshalKeyed() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("UnmarshalKeyed() = %+v, want %+v", got, want)
+	}
+
+	// A record copied under another key, or downgraded to the unkeyed
+	// format, must not decrypt.
+	downgraded := append([]byte{formatVersion}, data[1:]...)
+	tests := []struct {
+		name string
+		open func() (*token.Token, error)
+	}{
+		{"other key", func() (*token.Token, error) { return codec.UnmarshalKeyed(data, "token:654321:1") }},
+		{"no key", func() (*token.Token, error) { return codec.Unmarshal(data) }},
+		{"downgraded", func() (*token.Token, error) { return codec.UnmarshalKeyed(downgraded, "token:123456:1") }},
+	}
+	for _, tt := range tests {
+		if _, err := tt.open(); !errors.Is(err, ErrDecryptFailed) {
+			t.Errorf("%s: error = %v, want %v", tt.name, err, ErrDecryptFailed)
+		}
+	}
+
+	// Records sealed before keys were bound stay readable.
+	unkeyed, err := codec.Marshal(want)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if _, err := codec.UnmarshalKeyed(unkeyed, "token:123456:1"); err != nil {
+		t.Errorf("UnmarshalKeyed() of unkeyed record error = %v", err)
+	}
+}
+
+func TestCodec_UniqueNonces(t *testing.T) {
+	t.Parallel()
+
+	codec := New(nil, mustKeys(t, "k1", map[string][]byte{"k1": bytes.Repeat([]byte{1}, 16)}))
+
+	a, err := codec.Marshal(testToken())
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	b, err := codec.Marshal(testToken())
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+
+	if bytes.Equal(a, b) {
+		t.Error("Marshal() produced identical ciphertexts for the same token")
+	}
+}
+
+func TestNewStaticKeys(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		current string
+		keys    map[string][]byte
+		wantErr error
+	}{
+		{name: "valid", current: "a", keys: map[string][]byte{"a": make([]byte, 32)}},
+		{name: "short key", current: "a", keys: map[string][]byte{"a": make([]byte, 10)}, wantErr: ErrInvalidKey},
+		{name: "empty key ID", current: "", keys: map[string][]byte{"": make([]byte, 16)}, wantErr: ErrInvalidKeyID},
+		{name: "missing current", current: "b", keys: map[string][]byte{"a": make([]byte, 16)}, wantErr: ErrNoCurrentKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := NewStaticKeys(tt.current, tt.keys)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("NewStaticKeys() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
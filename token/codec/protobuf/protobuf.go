@@ -0,0 +1,235 @@
+// Package protobuf provides a token.Codec that encodes tokens in the
+// Protocol Buffers wire format. The encoding is compact and, unlike JSON
+// timestamps, unambiguous for clients in other languages that read the same
+// keys. It matches the following schema:
+//
+//	message Token {
+//	  string value = 1;
+//	  int32 type = 2;
+//	  google.protobuf.Timestamp created_at = 3;
+//	  google.protobuf.Timestamp valid_until = 4;
+//	  string validation_id = 5;
+//	  map<string, string> metadata = 6;
+//	}
+package protobuf
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Field numbers of the Token message.
+const (
+	fieldValue        protowire.Number = 1
+	fieldType         protowire.Number = 2
+	fieldCreatedAt    protowire.Number = 3
+	fieldValidUntil   protowire.Number = 4
+	fieldValidationID protowire.Number = 5
+	fieldMetadata     protowire.Number = 6
+)
+
+// Field numbers of google.protobuf.Timestamp and of map entries.
+const (
+	fieldSeconds protowire.Number = 1
+	fieldNanos   protowire.Number = 2
+	fieldKey     protowire.Number = 1
+	fieldMapVal  protowire.Number = 2
+)
+
+// ErrMalformed is returned when data is not a valid encoded token.
+var ErrMalformed = errors.New("malformed protobuf token")
+
+// Codec encodes tokens in the Protocol Buffers wire format.
+type Codec struct{}
+
+// New returns a protobuf token codec.
+func New() Codec {
+	return Codec{}
+}
+
+// Marshal encodes t. Zero-valued fields are omitted, as in proto3.
+func (Codec) Marshal(t *token.Token) ([]byte, error) {
+	if t == nil {
+		return nil, token.ErrTokenNil
+	}
+
+	var b []byte
+	b = appendString(b, fieldValue, t.Value)
+	if t.Type != 0 {
+		b = protowire.AppendTag(b, fieldType, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(int64(t.Type)))
+	}
+	b = appendTimestamp(b, fieldCreatedAt, t.CreatedAt)
+	b = appendTimestamp(b, fieldValidUntil, t.ValidUntil)
+	b = appendString(b, fieldValidationID, t.ValidationID)
+
+	for k, v := range t.Metadata {
+		var entry []byte
+		entry = appendString(entry, fieldKey, k)
+		entry = appendString(entry, fieldMapVal, v)
+		b = protowire.AppendTag(b, fieldMetadata, protowire.BytesType)
+		b = protowire.AppendBytes(b, entry)
+	}
+
+	return b, nil
+}
+
+// Unmarshal decodes a token. Unknown fields are skipped so the schema can
+// grow without breaking older readers.
+func (Codec) Unmarshal(data []byte) (*token.Token, error) {
+	var t token.Token
+
+	err := consumeFields(data, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch {
+		case num == fieldValue && typ == protowire.BytesType:
+			v, n := protowire.ConsumeString(b)
+			t.Value = v
+			return n, nil
+		case num == fieldType && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			t.Type = token.Type(int32(v))
+			return n, nil
+		case num == fieldCreatedAt && typ == protowire.BytesType:
+			return consumeTimestamp(b, &t.CreatedAt)
+		case num == fieldValidUntil && typ == protowire.BytesType:
+			return consumeTimestamp(b, &t.ValidUntil)
+		case num == fieldValidationID && typ == protowire.BytesType:
+			v, n := protowire.ConsumeString(b)
+			t.ValidationID = v
+			return n, nil
+		case num == fieldMetadata && typ == protowire.BytesType:
+			return consumeMapEntry(b, &t.Metadata)
+		default:
+			return protowire.ConsumeFieldValue(num, typ, b), nil
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+// consumeFields calls fn for each field in data. fn returns the number of
+// bytes of the field value it consumed, or a negative protowire error code.
+func consumeFields(data []byte, fn func(num protowire.Number, typ protowire.Type, b []byte) (int, error)) error {
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return fmt.Errorf("%w: %w", ErrMalformed, protowire.ParseError(n))
+		}
+		data = data[n:]
+
+		n, err := fn(num, typ, data)
+		if err != nil {
+			return err
+		}
+		if n < 0 {
+			return fmt.Errorf("%w: field %d: %w", ErrMalformed, num, protowire.ParseError(n))
+		}
+		data = data[n:]
+	}
+
+	return nil
+}
+
+// appendString appends a non-empty string field.
+func appendString(b []byte, num protowire.Number, s string) []byte {
+	if s == "" {
+		return b
+	}
+
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+
+	return protowire.AppendString(b, s)
+}
+
+// appendTimestamp appends a non-zero time as a google.protobuf.Timestamp.
+func appendTimestamp(b []byte, num protowire.Number, t time.Time) []byte {
+	if t.IsZero() {
+		return b
+	}
+
+	var ts []byte
+	if secs := t.Unix(); secs != 0 {
+		ts = protowire.AppendTag(ts, fieldSeconds, protowire.VarintType)
+		ts = protowire.AppendVarint(ts, uint64(secs))
+	}
+	if nanos := t.Nanosecond(); nanos != 0 {
+		ts = protowire.AppendTag(ts, fieldNanos, protowire.VarintType)
+		ts = protowire.AppendVarint(ts, uint64(nanos))
+	}
+
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+
+	return protowire.AppendBytes(b, ts)
+}
+
+// consumeTimestamp decodes a google.protobuf.Timestamp into dst in UTC.
+func consumeTimestamp(b []byte, dst *time.Time) (int, error) {
+	ts, n := protowire.ConsumeBytes(b)
+	if n < 0 {
+		return n, nil
+	}
+
+	var secs, nanos int64
+	err := consumeFields(ts, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		if typ != protowire.VarintType || (num != fieldSeconds && num != fieldNanos) {
+			return protowire.ConsumeFieldValue(num, typ, b), nil
+		}
+
+		v, n := protowire.ConsumeVarint(b)
+		if num == fieldSeconds {
+			secs = int64(v)
+		} else {
+			nanos = int64(int32(v))
+		}
+
+		return n, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	*dst = time.Unix(secs, nanos).UTC()
+
+	return n, nil
+}
+
+// consumeMapEntry decodes a map<string, string> entry into dst.
+func consumeMapEntry(b []byte, dst *map[string]string) (int, error) {
+	entry, n := protowire.ConsumeBytes(b)
+	if n < 0 {
+		return n, nil
+	}
+
+	var key, value string
+	err := consumeFields(entry, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		if typ != protowire.BytesType || (num != fieldKey && num != fieldMapVal) {
+			return protowire.ConsumeFieldValue(num, typ, b), nil
+		}
+
+		v, n := protowire.ConsumeString(b)
+		if num == fieldKey {
+			key = v
+		} else {
+			value = v
+		}
+
+		return n, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if *dst == nil {
+		*dst = make(map[string]string)
+	}
+	(*dst)[key] = value
+
+	return n, nil
+}
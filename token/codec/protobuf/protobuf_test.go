@@ -0,0 +1,112 @@
+package protobuf
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestCodec_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	created := time.Date(2025, 3, 1, 12, 30, 0, 123456789, time.UTC)
+
+	tests := []struct {
+		name  string
+		token *token.Token
+	}{
+		{
+			name: "code token with metadata",
+			token: &token.Token{
+				Value:        "123456",
+				Type:         token.TypeCode,
+				CreatedAt:    created,
+				ValidUntil:   created.Add(10 * time.Minute),
+				ValidationID: "validation-123",
+				Metadata:     map[string]string{"locale": "ko-KR", "campaign_id": "spring"},
+			},
+		},
+		{
+			name: "link token with zero type",
+			token: &token.Token{
+				Value:        "link-token",
+				Type:         token.TypeLink,
+				CreatedAt:    created,
+				ValidUntil:   created.Add(24 * time.Hour),
+				ValidationID: "validation-456",
+			},
+		},
+		{
+			name: "pre-epoch timestamp",
+			token: &token.Token{
+				Value:        "old",
+				Type:         token.TypeCode,
+				CreatedAt:    time.Date(1969, 12, 31, 23, 59, 59, 500, time.UTC),
+				ValidationID: "validation-789",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			codec := New()
+			data, err := codec.Marshal(tt.token)
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+
+			got, err := codec.Unmarshal(data)
+			if err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.token) {
+				t.Errorf("Unmarshal(Marshal()) = %+v, want %+v", got, tt.token)
+			}
+		})
+	}
+}
+
+func TestCodec_SkipsUnknownFields(t *testing.T) {
+	t.Parallel()
+
+	codec := New()
+	data, err := codec.Marshal(&token.Token{Value: "abc", ValidationID: "validation-123"})
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+
+	data = protowire.AppendTag(data, 99, protowire.VarintType)
+	data = protowire.AppendVarint(data, 42)
+
+	got, err := codec.Unmarshal(data)
+	if err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if got.Value != "abc" || got.ValidationID != "validation-123" {
+		t.Errorf("Unmarshal() = %+v, want value and validation ID preserved", got)
+	}
+}
+
+func TestCodec_Malformed(t *testing.T) {
+	t.Parallel()
+
+	codec := New()
+	data, err := codec.Marshal(&token.Token{Value: "abc", ValidationID: "validation-123"})
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+
+	if _, err := codec.Unmarshal(data[:len(data)-1]); !errors.Is(err, ErrMalformed) {
+		t.Errorf("Unmarshal() of truncated data error = %v, want %v", err, ErrMalformed)
+	}
+	if _, err := codec.Marshal(nil); !errors.Is(err, token.ErrTokenNil) {
+		t.Errorf("Marshal(nil) error = %v, want %v", err, token.ErrTokenNil)
+	}
+}
@@ -0,0 +1,59 @@
+package token
+
+import (
+	"context"
+	"errors"
+)
+
+// HookFunc is called with the token involved in a lifecycle transition.
+type HookFunc func(ctx context.Context, t *Token)
+
+// Hooks are callbacks a Manager invokes on token lifecycle transitions, for
+// example to emit events, metrics, or webhooks. Any field may be nil. Hooks
+// run synchronously on the calling goroutine after the transition has
+// succeeded, so they should return quickly and must not call back into the
+// Manager. Slow integrations can be run in the background, in order per
+// validation, with a dispatch.Dispatcher.
+type Hooks struct {
+	// OnCreated is called after a token has been issued.
+	OnCreated HookFunc
+
+	// OnVerified is called after a token has been verified, including by
+	// VerifyCodeToken and VerifyAndConsume.
+	OnVerified HookFunc
+
+	// OnExpired is called when an expired token is presented for
+	// verification. Tokens expiring unused are not reported. When the
+	// storage backend detects the expiry, the token carries only its value,
+	// type, expiry time, and, if the backend reports it, validation ID.
+	OnExpired HookFunc
+
+	// OnInvalidated is called after tokens have been invalidated. For
+	// InvalidateToken and InvalidateTokens, it is called once per token,
+	// which carries only its value and type; when a whole validation is
+	// invalidated, explicitly or because too many code attempts failed, the
+	// token carries only the validation ID.
+	OnInvalidated HookFunc
+}
+
+// WithHooks sets the lifecycle hooks for the Manager.
+func WithHooks(hooks Hooks) ManagerOption {
+	return func(m *Manager) {
+		m.hooks = hooks
+	}
+}
+
+// fire calls hook if it is set.
+func fire(ctx context.Context, hook HookFunc, t *Token) {
+	if hook != nil {
+		hook(ctx, t)
+	}
+}
+
+// fireExpired calls the OnExpired hook when err reports an expired token.
+func (m *Manager) fireExpired(ctx context.Context, err error, tokenValue string, tokenType Type) {
+	var expired *TokenExpiredError
+	if errors.As(err, &expired) {
+		fire(ctx, m.hooks.OnExpired, &Token{Value: tokenValue, Type: tokenType, ValidationID: expired.ValidationID, ValidUntil: expired.ExpiredAt})
+	}
+}
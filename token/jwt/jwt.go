@@ -0,0 +1,191 @@
+// Package jwt provides a token.Signer that issues link tokens as HS256-signed
+// JSON Web Tokens, allowing stateless verification through the same
+// token.Manager API.
+package jwt
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+)
+
+// Errors returned when a JWT is well-formed but not acceptable.
+var (
+	ErrUnsupportedAlgorithm = errors.New("unsupported JWT algorithm")
+	ErrInvalidClaims        = errors.New("invalid JWT claims")
+)
+
+// header is the JOSE header of a token being verified.
+type header struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+}
+
+// claims is the JWT claim set of issued tokens.
+type claims struct {
+	Issuer    string `json:"iss,omitempty"`
+	Subject   string `json:"sub"`
+	Audience  string `json:"aud,omitempty"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+	ID        string `json:"jti"`
+	TokenType int    `json:"evt"`
+}
+
+// encodedHeader is the base64url encoding of {"alg":"HS256","typ":"JWT"}.
+const encodedHeader = "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9"
+
+// Signer issues and verifies HS256 JWTs carrying the validation ID as the
+// subject claim.
+type Signer struct {
+	key      []byte
+	issuer   string
+	audience string
+}
+
+// Option is a functional option for configuring Signer.
+type Option func(*Signer)
+
+// WithIssuer sets the iss claim written to tokens and required on
+// verification.
+func WithIssuer(issuer string) Option {
+	return func(s *Signer) {
+		s.issuer = issuer
+	}
+}
+
+// WithAudience sets the aud claim written to tokens and required on
+// verification.
+func WithAudience(audience string) Option {
+	return func(s *Signer) {
+		s.audience = audience
+	}
+}
+
+// New creates a new JWT signer using key for HMAC-SHA256. The key must be at
+// least token.MinSigningKeyLength bytes.
+func New(key []byte, opts ...Option) (*Signer, error) {
+	if len(key) < token.MinSigningKeyLength {
+		return nil, token.ErrSigningKeyTooShort
+	}
+
+	s := &Signer{key: bytes.Clone(key)}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s, nil
+}
+
+// Sign encodes t into a signed JWT.
+func (s *Signer) Sign(t *token.Token) (string, error) {
+	if t == nil {
+		return "", token.ErrTokenNil
+	}
+
+	if t.ValidationID == "" {
+		return "", token.ErrEmptyValidationID
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", fmt.Errorf("failed to generate random bytes: %w", err)
+	}
+
+	payload, err := json.Marshal(claims{
+		Issuer:    s.issuer,
+		Subject:   t.ValidationID,
+		Audience:  s.audience,
+		IssuedAt:  t.CreatedAt.Unix(),
+		ExpiresAt: t.ValidUntil.Unix(),
+		ID:        base64.RawURLEncoding.EncodeToString(id),
+		TokenType: int(t.Type),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal JWT claims: %w", err)
+	}
+
+	signingInput := encodedHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(s.mac(signingInput)), nil
+}
+
+// Verify checks the signature, algorithm, issuer, and audience of value and
+// returns the token it encodes. Expiry is left to the caller.
+func (s *Signer) Verify(value string) (*token.Token, error) {
+	parts := strings.Split(value, ".")
+	if len(parts) != 3 {
+		return nil, token.ErrMalformedSignedToken
+	}
+
+	rawHeader, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", token.ErrMalformedSignedToken, err)
+	}
+
+	var h header
+	if err := json.Unmarshal(rawHeader, &h); err != nil {
+		return nil, fmt.Errorf("%w: %w", token.ErrMalformedSignedToken, err)
+	}
+
+	if h.Alg != "HS256" {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedAlgorithm, h.Alg)
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", token.ErrMalformedSignedToken, err)
+	}
+
+	if !hmac.Equal(sig, s.mac(parts[0]+"."+parts[1])) {
+		return nil, token.ErrInvalidSignature
+	}
+
+	rawClaims, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", token.ErrMalformedSignedToken, err)
+	}
+
+	var c claims
+	if err := json.Unmarshal(rawClaims, &c); err != nil {
+		return nil, fmt.Errorf("%w: %w", token.ErrMalformedSignedToken, err)
+	}
+
+	if c.Issuer != s.issuer {
+		return nil, fmt.Errorf("%w: unexpected issuer %q", ErrInvalidClaims, c.Issuer)
+	}
+
+	if c.Audience != s.audience {
+		return nil, fmt.Errorf("%w: unexpected audience %q", ErrInvalidClaims, c.Audience)
+	}
+
+	if c.Subject == "" || c.ExpiresAt == 0 {
+		return nil, fmt.Errorf("%w: missing subject or expiry", ErrInvalidClaims)
+	}
+
+	return &token.Token{
+		Value:        value,
+		Type:         token.Type(c.TokenType),
+		CreatedAt:    time.Unix(c.IssuedAt, 0),
+		ValidUntil:   time.Unix(c.ExpiresAt, 0),
+		ValidationID: c.Subject,
+	}, nil
+}
+
+// mac computes the HMAC-SHA256 of the JWT signing input.
+func (s *Signer) mac(signingInput string) []byte {
+	h := hmac.New(sha256.New, s.key)
+	h.Write([]byte(signingInput))
+
+	return h.Sum(nil)
+}
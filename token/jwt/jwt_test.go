@@ -0,0 +1,114 @@
+package jwt
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+	"github.com/jaeyeom/email-validator-grpc-mcp/token/storage/memory"
+)
+
+var testKey = []byte("0123456789abcdef0123456789abcdef")
+
+func TestSigner_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	s, err := New(testKey, WithIssuer("email-validator"), WithAudience("example-app"))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	original := token.New("", token.TypeLink, "validation-123", time.Hour)
+
+	value, err := s.Sign(original)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+
+	if parts := strings.Split(value, "."); len(parts) != 3 {
+		t.Fatalf("Sign() = %q, want three JWT segments", value)
+	}
+
+	got, err := s.Verify(value)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+
+	if got.ValidationID != "validation-123" || got.Type != token.TypeLink {
+		t.Errorf("Verify() = %+v, want link token for validation-123", got)
+	}
+	if got.ValidUntil.Unix() != original.ValidUntil.Unix() {
+		t.Errorf("Verify() valid until = %v, want %v", got.ValidUntil, original.ValidUntil)
+	}
+}
+
+func TestSigner_Rejects(t *testing.T) {
+	t.Parallel()
+
+	s, _ := New(testKey, WithIssuer("email-validator"), WithAudience("example-app"))
+	otherAudience, _ := New(testKey, WithIssuer("email-validator"), WithAudience("other-app"))
+	otherKey, _ := New([]byte("fedcba9876543210fedcba9876543210"), WithIssuer("email-validator"), WithAudience("example-app"))
+
+	value, err := s.Sign(token.New("", token.TypeLink, "validation-123", time.Hour))
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+
+	parts := strings.Split(value, ".")
+	noneHeader := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`))
+
+	tests := []struct {
+		name    string
+		signer  *Signer
+		value   string
+		wantErr error
+	}{
+		{"wrong key", otherKey, value, token.ErrInvalidSignature},
+		{"wrong audience", otherAudience, value, ErrInvalidClaims},
+		{"alg none", s, noneHeader + "." + parts[1] + ".", ErrUnsupportedAlgorithm},
+		{"two segments", s, parts[0] + "." + parts[1], token.ErrMalformedSignedToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := tt.signer.Verify(tt.value); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNew_KeyTooShort(t *testing.T) {
+	t.Parallel()
+
+	if _, err := New([]byte("short")); !errors.Is(err, token.ErrSigningKeyTooShort) {
+		t.Errorf("New() error = %v, want %v", err, token.ErrSigningKeyTooShort)
+	}
+}
+
+func TestSigner_WithManager(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	s, _ := New(testKey, WithIssuer("email-validator"))
+	manager := token.NewManager(memory.New(), token.WithLinkTokenSigner(s))
+
+	linkToken, err := manager.CreateLinkToken(ctx, "validation-jwt")
+	if err != nil {
+		t.Fatalf("CreateLinkToken() error = %v", err)
+	}
+
+	got, err := manager.VerifyToken(ctx, linkToken.Value, token.TypeLink)
+	if err != nil {
+		t.Fatalf("VerifyToken() error = %v", err)
+	}
+	if got.ValidationID != "validation-jwt" {
+		t.Errorf("VerifyToken() validation ID = %q, want validation-jwt", got.ValidationID)
+	}
+}
@@ -0,0 +1,137 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// LegacyFormat describes a token value format being phased out. While its
+// overlap window is open, the Manager accepts tokens that were issued in the
+// legacy format, so verification emails sent before a format or prefix
+// change keep working.
+type LegacyFormat struct {
+	// Name identifies the format in logs and FormatStats.
+	Name string
+
+	// Until closes the overlap window. The format is tried only while the
+	// Manager's clock is before Until, so a zero Until disables it.
+	Until time.Time
+
+	// Lookup maps a token value as presented by the user to the value the
+	// legacy format stored it under, and reports false if the value cannot
+	// be in this format. If nil, the presented value is looked up unchanged,
+	// which accepts tokens lacking the current prefix or normalization.
+	Lookup func(tokenValue string, tokenType Type) (string, bool)
+}
+
+// FormatStats counts successful verifications by the token format they
+// matched, to track the progress of a format migration. Once Legacy counts
+// stop growing, the legacy formats can be removed.
+type FormatStats struct {
+	// Current counts tokens found in the current format.
+	Current int64
+
+	// Legacy counts tokens found in each legacy format, by name.
+	Legacy map[string]int64
+}
+
+// WithLegacyFormats makes verification fall back to each of formats, in
+// order, when a token is not found in the current format.
+func WithLegacyFormats(formats ...LegacyFormat) ManagerOption {
+	return func(m *Manager) {
+		m.legacyFormats = formats
+		m.legacyHits = make([]atomic.Int64, len(formats))
+	}
+}
+
+// FormatStats returns the number of verified tokens found in the current
+// format and in each legacy format.
+func (m *Manager) FormatStats() FormatStats {
+	stats := FormatStats{
+		Current: m.currentHits.Load(),
+		Legacy:  make(map[string]int64, len(m.legacyFormats)),
+	}
+
+	for i, format := range m.legacyFormats {
+		stats.Legacy[format.Name] += m.legacyHits[i].Load()
+	}
+
+	return stats
+}
+
+// fetchFunc reads or consumes a token by its value.
+type fetchFunc func(ctx context.Context, tokenValue string, tokenType Type) (*Token, error)
+
+// find looks up a presented token in the current format with fetch. If it
+// is not found there, each legacy format with an open overlap window is
+// tried with fetchLegacy, which reads storage even for link tokens that are
+// now signed. The current format's error is returned if no format matches.
+func (m *Manager) find(ctx context.Context, tokenValue string, tokenType Type, fetch, fetchLegacy fetchFunc) (*Token, error) {
+	token, err := m.findCurrent(ctx, m.normalize(tokenValue, tokenType), tokenType, fetch)
+	if err == nil {
+		m.currentHits.Add(1)
+		return token, nil
+	}
+
+	if !errors.Is(err, ErrTokenNotFound) && !errors.Is(err, ErrTokenPrefixMismatch) && !errors.Is(err, ErrStatelessToken) {
+		return nil, err
+	}
+
+	now := m.clock.Now()
+	for i, format := range m.legacyFormats {
+		if !now.Before(format.Until) {
+			continue
+		}
+
+		value := tokenValue
+		if format.Lookup != nil {
+			var ok bool
+			if value, ok = format.Lookup(tokenValue, tokenType); !ok {
+				continue
+			}
+		}
+
+		token, legacyErr := m.findLegacy(ctx, value, tokenType, fetchLegacy)
+		if errors.Is(legacyErr, ErrTokenNotFound) {
+			continue
+		}
+		if legacyErr != nil {
+			return nil, legacyErr
+		}
+
+		m.legacyHits[i].Add(1)
+		m.log(ctx).Info("token matched legacy format",
+			"format", format.Name,
+			"token_type", tokenType,
+			"validation_id", token.ValidationID,
+			"overlap_until", format.Until)
+
+		return token, nil
+	}
+
+	return nil, err
+}
+
+// findCurrent looks up a token in the current format.
+func (m *Manager) findCurrent(ctx context.Context, tokenValue string, tokenType Type, fetch fetchFunc) (*Token, error) {
+	if err := m.checkPrefix(ctx, tokenValue, tokenType); err != nil {
+		return nil, err
+	}
+
+	if err := m.checkRevoked(ctx, tokenValue, tokenType); err != nil {
+		return nil, err
+	}
+
+	return fetch(ctx, tokenValue, tokenType)
+}
+
+// findLegacy looks up a token under its legacy stored value.
+func (m *Manager) findLegacy(ctx context.Context, tokenValue string, tokenType Type, fetch fetchFunc) (*Token, error) {
+	if err := m.checkRevoked(ctx, tokenValue, tokenType); err != nil {
+		return nil, err
+	}
+
+	return fetch(ctx, tokenValue, tokenType)
+}
@@ -6,20 +6,60 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"maps"
+	"strings"
+	"sync/atomic"
 	"time"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/ctxkeys"
+	"github.com/jaeyeom/email-validator-grpc-mcp/journal"
+	"github.com/jaeyeom/email-validator-grpc-mcp/redact"
 )
 
 // Manager provides a high-level interface for token operations.
 type Manager struct {
-	generator *Generator
+	generator TokenGenerator
 	storage   Storage
 	logger    *slog.Logger
 
 	// Default TTL values
 	linkTokenTTL time.Duration
 	codeTokenTTL time.Duration
+
+	// maxCodeAttempts is the number of failed code verifications allowed per
+	// validation before its tokens are invalidated. Zero disables the limit.
+	maxCodeAttempts int
+
+	// journal optionally records verification decisions for support tooling.
+	journal journal.Journal
+
+	// clock provides the current time for token creation and expiry checks.
+	clock Clock
+
+	// linkSigner, when set, issues stateless link tokens that are verified
+	// without a storage roundtrip.
+	linkSigner Signer
+	// hooks are invoked on token lifecycle transitions.
+	hooks Hooks
+
+	// revocations, when set, lists revoked tokens rejected on verification.
+	revocations RevocationList
+
+	// emailPrivacy controls how bound email addresses are stored.
+	emailPrivacy EmailPrivacy
+
+	// legacyFormats are tried on verification when a token is not found in
+	// the current format. currentHits and legacyHits count the tokens found
+	// in each format.
+	legacyFormats []LegacyFormat
+	currentHits   atomic.Int64
+	legacyHits    []atomic.Int64
 }
 
+// DefaultMaxCodeAttempts is the default number of failed code verification
+// attempts allowed per validation.
+const DefaultMaxCodeAttempts = 5
+
 // ManagerOption is a functional option for configuring Manager.
 type ManagerOption func(*Manager)
 
@@ -51,8 +91,44 @@ func WithCodeTokenTTL(ttl time.Duration) ManagerOption {
 	}
 }
 
+// WithMaxCodeAttempts sets how many failed code verification attempts are
+// allowed per validation before its tokens are invalidated. A value of zero
+// or less disables attempt limiting.
+func WithMaxCodeAttempts(n int) ManagerOption {
+	return func(m *Manager) {
+		m.maxCodeAttempts = max(n, 0)
+	}
+}
+
+// WithJournal records every verification request and its decision in j, with
+// token values redacted.
+func WithJournal(j journal.Journal) ManagerOption {
+	return func(m *Manager) {
+		m.journal = j
+	}
+}
+
+// WithLinkTokenSigner makes the Manager issue self-contained link tokens
+// signed by s instead of storing random link tokens. Such tokens are verified
+// without touching storage, so deployments using only link validation do not
+// need a shared storage backend. Code tokens are unaffected. Stateless link
+// tokens cannot be invalidated individually or consumed; they remain valid
+// until they expire.
+func WithLinkTokenSigner(s Signer) ManagerOption {
+	return func(m *Manager) {
+		m.linkSigner = s
+	}
+}
+
+// WithClock sets the clock used for token creation and expiry checks.
+func WithClock(clock Clock) ManagerOption {
+	return func(m *Manager) {
+		m.clock = clock
+	}
+}
+
 // WithGenerator sets a custom token generator for the Manager.
-func WithGenerator(generator *Generator) ManagerOption {
+func WithGenerator(generator TokenGenerator) ManagerOption {
 	return func(m *Manager) {
 		m.generator = generator
 	}
@@ -66,6 +142,9 @@ func NewManager(storage Storage, opts ...ManagerOption) *Manager {
 		logger:       slog.Default(),
 		linkTokenTTL: 24 * time.Hour,   // Default 24 hours for link tokens
 		codeTokenTTL: 10 * time.Minute, // Default 10 minutes for code tokens
+
+		maxCodeAttempts: DefaultMaxCodeAttempts,
+		clock:           SystemClock,
 	}
 
 	for _, opt := range opts {
@@ -75,27 +154,240 @@ func NewManager(storage Storage, opts ...ManagerOption) *Manager {
 	return m
 }
 
+// log returns the manager's logger annotated with the tenant, caller, and
+// request ID carried by ctx.
+func (m *Manager) log(ctx context.Context) *slog.Logger {
+	if attrs := ctxkeys.LogAttrs(ctx); len(attrs) > 0 {
+		return m.logger.With(attrs...)
+	}
+
+	return m.logger
+}
+
+// CreateOptions customizes the creation of a single token.
+type CreateOptions struct {
+	// TTL overrides the default TTL for the token type when positive.
+	TTL time.Duration
+
+	// Metadata is stored alongside the token. Stateless link tokens cannot
+	// carry metadata.
+	Metadata map[string]string
+
+	// Email binds the token to the address it is sent to, so that
+	// VerifyTokenForEmail and VerifyAndConsumeForEmail reject it for any
+	// other address. It is stored in Metadata according to the manager's
+	// EmailPrivacy, so stateless link tokens cannot be bound.
+	Email string
+}
+
 // CreateLinkToken generates and stores a new link token for email validation.
 func (m *Manager) CreateLinkToken(ctx context.Context, validationID string) (*Token, error) {
-	return m.createToken(ctx, TypeLink, validationID, m.linkTokenTTL)
+	return m.createToken(ctx, TypeLink, validationID, m.linkTokenTTL, nil)
+}
+
+// CreateOrGetLinkToken returns the unexpired link token for a validation
+// that expires last, creating one only when none exists. Resending a
+// validation email with it keeps previously sent links working. The lookup
+// and creation are not atomic, so concurrent callers may each create a
+// token. With a link token signer every call issues a new stateless token,
+// which does not invalidate earlier ones.
+func (m *Manager) CreateOrGetLinkToken(ctx context.Context, validationID string) (*Token, error) {
+	return m.CreateOrGetLinkTokenWithOptions(ctx, validationID, CreateOptions{})
+}
+
+// CreateOrGetLinkTokenWithOptions is like CreateOrGetLinkToken, but a token
+// it has to create is customized by opts. An existing token is returned as
+// it is.
+func (m *Manager) CreateOrGetLinkTokenWithOptions(ctx context.Context, validationID string, opts CreateOptions) (*Token, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("context error: %w", err)
+	}
+
+	if validationID == "" {
+		return nil, ErrEmptyValidationID
+	}
+
+	if !m.isStateless(TypeLink) {
+		tokens, err := m.storage.ListByValidationID(ctx, validationID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tokens from storage: %w", err)
+		}
+
+		now := m.clock.Now()
+
+		var latest *Token
+		for _, t := range tokens {
+			if t.Type != TypeLink || t.IsExpiredAt(now) {
+				continue
+			}
+			if latest == nil || t.ValidUntil.After(latest.ValidUntil) {
+				latest = t
+			}
+		}
+
+		if latest != nil {
+			m.log(ctx).Debug("reusing existing link token",
+				"validation_id", validationID,
+				"expires_at", latest.ValidUntil)
+			return latest, nil
+		}
+	}
+
+	return m.CreateTokenWithOptions(ctx, TypeLink, validationID, opts)
 }
 
 // CreateCodeToken generates and stores a new code token for email validation.
 func (m *Manager) CreateCodeToken(ctx context.Context, validationID string) (*Token, error) {
-	return m.createToken(ctx, TypeCode, validationID, m.codeTokenTTL)
+	return m.createToken(ctx, TypeCode, validationID, m.codeTokenTTL, nil)
 }
 
 // CreateTokenWithTTL generates and stores a new token with a custom TTL.
 func (m *Manager) CreateTokenWithTTL(ctx context.Context, tokenType Type, validationID string, ttl time.Duration) (*Token, error) {
-	return m.createToken(ctx, tokenType, validationID, ttl)
+	return m.createToken(ctx, tokenType, validationID, ttl, nil)
+}
+
+// CreateTokenWithOptions generates and stores a new token customized by opts.
+func (m *Manager) CreateTokenWithOptions(ctx context.Context, tokenType Type, validationID string, opts CreateOptions) (*Token, error) {
+	ttl := opts.TTL
+	if ttl <= 0 {
+		ttl = m.defaultTTL(tokenType)
+	}
+
+	return m.createToken(ctx, tokenType, validationID, ttl, m.metadataFor(opts))
+}
+
+// defaultTTL returns the configured TTL for a token type.
+func (m *Manager) defaultTTL(tokenType Type) time.Duration {
+	if tokenType == TypeCode {
+		return m.codeTokenTTL
+	}
+
+	return m.linkTokenTTL
+}
+
+// TokenRequest describes one token to create with CreateTokens.
+type TokenRequest struct {
+	Type         Type
+	ValidationID string
+	Options      CreateOptions
+}
+
+// CreateTokens generates and stores one token per request. When the storage
+// backend implements BatchStorage, all tokens are written in a single
+// roundtrip. Requests are validated before anything is stored, so an invalid
+// request fails the whole batch.
+func (m *Manager) CreateTokens(ctx context.Context, requests []TokenRequest) ([]*Token, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("context error: %w", err)
+	}
+
+	tokens := make([]*Token, 0, len(requests))
+	stored := make([]*Token, 0, len(requests))
+
+	for i, req := range requests {
+		ttl := req.Options.TTL
+		if ttl <= 0 {
+			ttl = m.defaultTTL(req.Type)
+		}
+
+		token, err := m.newToken(ctx, req.Type, req.ValidationID, ttl, m.metadataFor(req.Options))
+		if err != nil {
+			return nil, fmt.Errorf("request %d: %w", i, err)
+		}
+
+		tokens = append(tokens, token)
+		if !m.isStateless(req.Type) {
+			stored = append(stored, token)
+		}
+	}
+
+	if err := m.storeAll(ctx, stored); err != nil {
+		m.log(ctx).Error("failed to store token batch",
+			"error", err,
+			"count", len(stored))
+		return nil, fmt.Errorf("failed to store tokens: %w", err)
+	}
+
+	m.log(ctx).Info("token batch created successfully",
+		"count", len(tokens))
+
+	for _, token := range tokens {
+		fire(ctx, m.hooks.OnCreated, token)
+	}
+
+	return tokens, nil
+}
+
+// storeAll stores tokens using BatchStorage when the backend supports it.
+func (m *Manager) storeAll(ctx context.Context, tokens []*Token) error {
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	if batch, ok := m.storage.(BatchStorage); ok {
+		return batch.StoreBatch(ctx, tokens)
+	}
+
+	return StoreEach(ctx, tokens, m.storage.Store)
+}
+
+// deleteAll deletes tokens using BatchStorage when the backend supports it.
+func (m *Manager) deleteAll(ctx context.Context, refs []TokenRef) error {
+	if len(refs) == 0 {
+		return nil
+	}
+
+	if batch, ok := m.storage.(BatchStorage); ok {
+		return batch.DeleteBatch(ctx, refs)
+	}
+
+	return DeleteEach(ctx, refs, m.storage.Delete)
+}
+
+// isStateless reports whether tokens of the given type are issued by the
+// link signer rather than stored.
+func (m *Manager) isStateless(tokenType Type) bool {
+	return tokenType == TypeLink && m.linkSigner != nil
 }
 
 // createToken is the internal method that generates and stores tokens.
-func (m *Manager) createToken(ctx context.Context, tokenType Type, validationID string, ttl time.Duration) (*Token, error) {
+func (m *Manager) createToken(ctx context.Context, tokenType Type, validationID string, ttl time.Duration, metadata map[string]string) (*Token, error) {
 	if err := ctx.Err(); err != nil {
 		return nil, fmt.Errorf("context error: %w", err)
 	}
 
+	token, err := m.newToken(ctx, tokenType, validationID, ttl, metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.isStateless(tokenType) {
+		fire(ctx, m.hooks.OnCreated, token)
+		return token, nil
+	}
+
+	// Store the token
+	if err := m.storage.Store(ctx, token); err != nil {
+		m.log(ctx).Error("failed to store token",
+			"error", err,
+			"token_type", tokenType,
+			"validation_id", validationID)
+		return nil, fmt.Errorf("failed to store token: %w", err)
+	}
+
+	m.log(ctx).Info("token created successfully",
+		"token_type", tokenType,
+		"validation_id", validationID,
+		"expires_at", token.ValidUntil)
+
+	fire(ctx, m.hooks.OnCreated, token)
+
+	return token, nil
+}
+
+// newToken validates the request and generates a token without storing it.
+// Stateless link tokens are signed and returned as-is.
+func (m *Manager) newToken(ctx context.Context, tokenType Type, validationID string, ttl time.Duration, metadata map[string]string) (*Token, error) {
 	if validationID == "" {
 		return nil, ErrEmptyValidationID
 	}
@@ -104,6 +396,14 @@ func (m *Manager) createToken(ctx context.Context, tokenType Type, validationID
 		return nil, fmt.Errorf("invalid TTL: must be positive duration")
 	}
 
+	if m.isStateless(tokenType) {
+		if len(metadata) > 0 {
+			return nil, fmt.Errorf("%w: metadata cannot be attached", ErrStatelessToken)
+		}
+
+		return m.createSignedToken(ctx, validationID, ttl)
+	}
+
 	// Generate the token value
 	var tokenValue string
 	var err error
@@ -118,7 +418,7 @@ func (m *Manager) createToken(ctx context.Context, tokenType Type, validationID
 	}
 
 	if err != nil {
-		m.logger.Error("failed to generate token",
+		m.log(ctx).Error("failed to generate token",
 			"error", err,
 			"token_type", tokenType,
 			"validation_id", validationID)
@@ -126,27 +426,153 @@ func (m *Manager) createToken(ctx context.Context, tokenType Type, validationID
 	}
 
 	// Create the token struct
-	token := New(tokenValue, tokenType, validationID, ttl)
+	token := NewAt(tokenValue, tokenType, validationID, ttl, m.clock.Now())
+	token.Metadata = maps.Clone(metadata)
 
-	// Store the token
-	if err := m.storage.Store(ctx, token); err != nil {
-		m.logger.Error("failed to store token",
+	return token, nil
+}
+
+// createSignedToken issues a stateless link token using the configured signer.
+func (m *Manager) createSignedToken(ctx context.Context, validationID string, ttl time.Duration) (*Token, error) {
+	token := NewAt("", TypeLink, validationID, ttl, m.clock.Now())
+
+	value, err := m.linkSigner.Sign(token)
+	if err != nil {
+		m.log(ctx).Error("failed to sign token",
 			"error", err,
-			"token_type", tokenType,
 			"validation_id", validationID)
-		return nil, fmt.Errorf("failed to store token: %w", err)
+		return nil, fmt.Errorf("failed to sign token: %w", err)
 	}
 
-	m.logger.Info("token created successfully",
-		"token_type", tokenType,
+	token.Value = value
+
+	m.log(ctx).Info("signed token created successfully",
+		"token_type", TypeLink,
 		"validation_id", validationID,
 		"expires_at", token.ValidUntil)
 
 	return token, nil
 }
 
-// VerifyToken retrieves and validates a token, checking its existence, type, and expiration.
+// normalize canonicalizes a token value when the generator accepts several
+// spellings of the same token.
+func (m *Manager) normalize(tokenValue string, tokenType Type) string {
+	switch tokenType {
+	case TypeLink:
+		if normalizer, ok := m.generator.(LinkTokenNormalizer); ok {
+			return normalizer.NormalizeLinkToken(tokenValue)
+		}
+	case TypeCode:
+		if normalizer, ok := m.generator.(CodeTokenNormalizer); ok {
+			return normalizer.NormalizeCodeToken(tokenValue)
+		}
+	}
+
+	return tokenValue
+}
+
+// checkPrefix rejects token values that lack the prefix the generator puts
+// on tokens of the given type. Stateless link tokens are not prefixed.
+func (m *Manager) checkPrefix(ctx context.Context, tokenValue string, tokenType Type) error {
+	prefixer, ok := m.generator.(TokenPrefixer)
+	if !ok || m.isStateless(tokenType) {
+		return nil
+	}
+
+	if prefix := prefixer.TokenPrefix(tokenType); !strings.HasPrefix(tokenValue, prefix) {
+		m.log(ctx).Warn("token prefix mismatch",
+			"token_type", tokenType,
+			"expected_prefix", prefix)
+		return fmt.Errorf("%w: expected %q", ErrTokenPrefixMismatch, prefix)
+	}
+
+	return nil
+}
+
+// retrieve looks up a token, decoding it with the link signer instead of
+// reading storage when it is a stateless link token.
+func (m *Manager) retrieve(ctx context.Context, tokenValue string, tokenType Type) (*Token, error) {
+	if tokenType == TypeLink && m.linkSigner != nil {
+		token, err := m.linkSigner.Verify(tokenValue)
+		if err != nil {
+			return nil, fmt.Errorf("failed to verify token signature: %w", err)
+		}
+
+		return token, nil
+	}
+
+	return m.retrieveStored(ctx, tokenValue, tokenType)
+}
+
+// retrieveStored looks up a token in storage.
+func (m *Manager) retrieveStored(ctx context.Context, tokenValue string, tokenType Type) (*Token, error) {
+	token, err := m.storage.Retrieve(ctx, tokenValue, tokenType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve token from storage: %w", err)
+	}
+
+	return token, nil
+}
+
+// consume removes a token from storage and returns it. Stateless link tokens
+// cannot be consumed.
+func (m *Manager) consume(ctx context.Context, tokenValue string, tokenType Type) (*Token, error) {
+	if tokenType == TypeLink && m.linkSigner != nil {
+		return nil, ErrStatelessToken
+	}
+
+	return m.consumeStored(ctx, tokenValue, tokenType)
+}
+
+// consumeStored removes a token from storage and returns it.
+func (m *Manager) consumeStored(ctx context.Context, tokenValue string, tokenType Type) (*Token, error) {
+	token, err := m.storage.Consume(ctx, tokenValue, tokenType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume token from storage: %w", err)
+	}
+
+	return token, nil
+}
+
+// VerifyToken retrieves and validates a token, checking its existence, type,
+// and expiration. While code attempts are limited, code tokens are rejected
+// with ErrCodeNeedsValidation: a code must be verified with VerifyCodeToken,
+// which counts failed attempts against its validation.
 func (m *Manager) VerifyToken(ctx context.Context, tokenValue string, tokenType Type) (*Token, error) {
+	token, err := m.verifyToken(ctx, tokenValue, tokenType)
+	m.record(ctx, "VerifyToken", m.normalize(tokenValue, tokenType), validationIDOf(token), err)
+
+	if err == nil {
+		fire(ctx, m.hooks.OnVerified, token)
+	}
+
+	return token, err
+}
+
+// verifyToken implements VerifyToken without journaling.
+func (m *Manager) verifyToken(ctx context.Context, tokenValue string, tokenType Type) (*Token, error) {
+	if err := m.checkUnlimitedCode(ctx, tokenType); err != nil {
+		return nil, err
+	}
+
+	return m.verify(ctx, tokenValue, tokenType)
+}
+
+// checkUnlimitedCode rejects code tokens presented without their validation
+// ID while attempts are limited, since failed guesses could not be counted.
+func (m *Manager) checkUnlimitedCode(ctx context.Context, tokenType Type) error {
+	if tokenType != TypeCode || m.maxCodeAttempts == 0 {
+		return nil
+	}
+
+	m.log(ctx).Warn("code token presented without its validation ID")
+
+	return ErrCodeNeedsValidation
+}
+
+// verify retrieves and checks a token. It is the lookup shared by VerifyToken
+// and VerifyCodeToken.
+func (m *Manager) verify(ctx context.Context, tokenValue string, tokenType Type) (*Token, error) {
 	if err := ctx.Err(); err != nil {
 		return nil, fmt.Errorf("context error: %w", err)
 	}
@@ -155,48 +581,230 @@ func (m *Manager) VerifyToken(ctx context.Context, tokenValue string, tokenType
 		return nil, ErrEmptyTokenValue
 	}
 
-	// Retrieve the token from storage
-	token, err := m.storage.Retrieve(ctx, tokenValue, tokenType)
+	// Retrieve the token, falling back to legacy formats
+	token, err := m.find(ctx, tokenValue, tokenType, m.retrieve, m.retrieveStored)
+	tokenValue = m.normalize(tokenValue, tokenType)
 	if err != nil {
 		// Log verification attempt for security auditing
-		m.logger.Warn("token verification failed",
-			"token_value", tokenValue,
+		m.log(ctx).Warn("token verification failed",
+			"token_value", redact.Token(tokenValue),
 			"token_type", tokenType,
 			"error", err)
-		return nil, fmt.Errorf("failed to retrieve token from storage: %w", err)
+		m.fireExpired(ctx, err, tokenValue, tokenType)
+		return nil, err
 	}
 
 	// Additional verification checks
 	if token.Type != tokenType {
-		m.logger.Warn("token type mismatch during verification",
+		m.log(ctx).Warn("token type mismatch during verification",
 			"expected_type", tokenType,
 			"actual_type", token.Type,
 			"validation_id", token.ValidationID)
-		return nil, fmt.Errorf("token type mismatch: expected %d, got %d", tokenType, token.Type)
+		return nil, fmt.Errorf("%w: expected %d, got %d", ErrTokenTypeMismatch, tokenType, token.Type)
 	}
 
 	// The storage backend already handles expiration checking,
 	// but we double-check here for additional security
-	if token.IsExpired() {
-		m.logger.Warn("expired token detected during verification",
+	if token.IsExpiredAt(m.clock.Now()) {
+		m.log(ctx).Warn("expired token detected during verification",
+			"token_type", tokenType,
+			"validation_id", token.ValidationID,
+			"expired_at", token.ValidUntil)
+		fire(ctx, m.hooks.OnExpired, token)
+		return nil, &TokenExpiredError{
+			TokenValue:   tokenValue,
+			TokenType:    tokenType,
+			ExpiredAt:    token.ValidUntil,
+			ValidationID: token.ValidationID,
+		}
+	}
+
+	m.log(ctx).Info("token verified successfully",
+		"token_type", tokenType,
+		"validation_id", token.ValidationID)
+
+	return token, nil
+}
+
+// VerifyCodeToken verifies a code entered by the user for the given
+// validation. Each failed attempt is counted, and once the configured maximum
+// is reached all tokens of the validation are invalidated and
+// ErrAttemptsExceeded is returned, which bounds brute forcing of short codes.
+func (m *Manager) VerifyCodeToken(ctx context.Context, validationID, code string) (*Token, error) {
+	token, err := m.verifyCodeToken(ctx, validationID, code)
+	m.record(ctx, "VerifyCodeToken", code, validationID, err)
+
+	if err == nil {
+		fire(ctx, m.hooks.OnVerified, token)
+	}
+
+	return token, err
+}
+
+// verifyCodeToken implements VerifyCodeToken without journaling.
+func (m *Manager) verifyCodeToken(ctx context.Context, validationID, code string) (*Token, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("context error: %w", err)
+	}
+
+	if validationID == "" {
+		return nil, ErrEmptyValidationID
+	}
+
+	if code == "" {
+		return nil, ErrEmptyTokenValue
+	}
+
+	token, err := m.verify(ctx, code, TypeCode)
+	if err == nil && token.ValidationID == validationID {
+		return token, nil
+	}
+
+	if err == nil {
+		// The code exists but belongs to another validation
+		err = ErrTokenNotFound
+	}
+
+	if m.maxCodeAttempts == 0 {
+		return nil, err
+	}
+
+	attempts, incErr := m.storage.IncrementAttempts(ctx, validationID, m.codeTokenTTL)
+	if incErr != nil {
+		m.log(ctx).Error("failed to record verification attempt",
+			"error", incErr,
+			"validation_id", validationID)
+		return nil, fmt.Errorf("failed to record verification attempt: %w", incErr)
+	}
+
+	if attempts < m.maxCodeAttempts {
+		return nil, err
+	}
+
+	m.log(ctx).Warn("code verification attempts exceeded, invalidating validation",
+		"validation_id", validationID,
+		"attempts", attempts,
+		"max_attempts", m.maxCodeAttempts)
+
+	if delErr := m.storage.DeleteByValidationID(ctx, validationID); delErr != nil {
+		return nil, fmt.Errorf("failed to invalidate validation tokens: %w", delErr)
+	}
+
+	fire(ctx, m.hooks.OnInvalidated, &Token{ValidationID: validationID})
+
+	return nil, ErrAttemptsExceeded
+}
+
+// VerifyAndConsume verifies a token and removes it from storage in one atomic
+// step, so a verification link or code cannot be used more than once. Unlike
+// calling VerifyToken followed by InvalidateToken, concurrent callers
+// presenting the same token are guaranteed that at most one succeeds. Like
+// VerifyToken, it rejects code tokens while code attempts are limited.
+func (m *Manager) VerifyAndConsume(ctx context.Context, tokenValue string, tokenType Type) (*Token, error) {
+	token, err := m.verifyAndConsume(ctx, tokenValue, tokenType)
+	m.record(ctx, "VerifyAndConsume", m.normalize(tokenValue, tokenType), validationIDOf(token), err)
+
+	if err == nil {
+		fire(ctx, m.hooks.OnVerified, token)
+	}
+
+	return token, err
+}
+
+// verifyAndConsume implements VerifyAndConsume without journaling.
+func (m *Manager) verifyAndConsume(ctx context.Context, tokenValue string, tokenType Type) (*Token, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("context error: %w", err)
+	}
+
+	if err := m.checkUnlimitedCode(ctx, tokenType); err != nil {
+		return nil, err
+	}
+
+	if tokenValue == "" {
+		return nil, ErrEmptyTokenValue
+	}
+
+	token, err := m.find(ctx, tokenValue, tokenType, m.consume, m.consumeStored)
+	tokenValue = m.normalize(tokenValue, tokenType)
+	if err != nil {
+		m.log(ctx).Warn("token consumption failed",
+			"token_value", redact.Token(tokenValue),
+			"token_type", tokenType,
+			"error", err)
+		m.fireExpired(ctx, err, tokenValue, tokenType)
+		return nil, err
+	}
+
+	if token.Type != tokenType {
+		m.log(ctx).Warn("token type mismatch during consumption",
+			"expected_type", tokenType,
+			"actual_type", token.Type,
+			"validation_id", token.ValidationID)
+		return nil, fmt.Errorf("%w: expected %d, got %d", ErrTokenTypeMismatch, tokenType, token.Type)
+	}
+
+	if token.IsExpiredAt(m.clock.Now()) {
+		m.log(ctx).Warn("expired token detected during consumption",
 			"token_type", tokenType,
 			"validation_id", token.ValidationID,
 			"expired_at", token.ValidUntil)
+		fire(ctx, m.hooks.OnExpired, token)
 		return nil, &TokenExpiredError{
-			TokenValue: tokenValue,
-			TokenType:  tokenType,
-			ExpiredAt:  token.ValidUntil,
+			TokenValue:   tokenValue,
+			TokenType:    tokenType,
+			ExpiredAt:    token.ValidUntil,
+			ValidationID: token.ValidationID,
 		}
 	}
 
-	m.logger.Info("token verified successfully",
+	m.log(ctx).Info("token verified and consumed successfully",
 		"token_type", tokenType,
 		"validation_id", token.ValidationID)
 
 	return token, nil
 }
 
+// ExtendTokenTTL pushes back the expiry of an unexpired token by extraTTL,
+// for flows that resend an existing link or code with extended validity.
+func (m *Manager) ExtendTokenTTL(ctx context.Context, tokenValue string, tokenType Type, extraTTL time.Duration) (*Token, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("context error: %w", err)
+	}
+
+	if tokenValue == "" {
+		return nil, ErrEmptyTokenValue
+	}
+
+	tokenValue = m.normalize(tokenValue, tokenType)
+
+	if extraTTL <= 0 {
+		return nil, fmt.Errorf("invalid TTL extension: must be positive duration")
+	}
+
+	if tokenType == TypeLink && m.linkSigner != nil {
+		return nil, ErrStatelessToken
+	}
+
+	token, err := m.storage.ExtendTTL(ctx, tokenValue, tokenType, extraTTL)
+	if err != nil {
+		m.log(ctx).Warn("failed to extend token TTL",
+			"error", err,
+			"token_type", tokenType)
+		return nil, fmt.Errorf("failed to extend token TTL: %w", err)
+	}
+
+	m.log(ctx).Info("token TTL extended successfully",
+		"token_type", tokenType,
+		"validation_id", token.ValidationID,
+		"expires_at", token.ValidUntil)
+
+	return token, nil
+}
+
 // InvalidateToken removes a token from storage, effectively invalidating it.
+// Stateless link tokens are not stored, so it returns ErrStatelessToken for
+// them; they can be rejected before they expire only with Revoke.
 func (m *Manager) InvalidateToken(ctx context.Context, tokenValue string, tokenType Type) error {
 	if err := ctx.Err(); err != nil {
 		return fmt.Errorf("context error: %w", err)
@@ -206,18 +814,63 @@ func (m *Manager) InvalidateToken(ctx context.Context, tokenValue string, tokenT
 		return ErrEmptyTokenValue
 	}
 
+	if m.isStateless(tokenType) {
+		return ErrStatelessToken
+	}
+
+	tokenValue = m.normalize(tokenValue, tokenType)
+
 	err := m.storage.Delete(ctx, tokenValue, tokenType)
 	if err != nil {
-		m.logger.Error("failed to invalidate token",
+		m.log(ctx).Error("failed to invalidate token",
 			"error", err,
-			"token_value", tokenValue,
+			"token_value", redact.Token(tokenValue),
 			"token_type", tokenType)
 		return fmt.Errorf("failed to invalidate token: %w", err)
 	}
 
-	m.logger.Info("token invalidated successfully",
+	m.log(ctx).Info("token invalidated successfully",
 		"token_type", tokenType,
-		"token_value", tokenValue)
+		"token_value", redact.Token(tokenValue))
+
+	fire(ctx, m.hooks.OnInvalidated, &Token{Value: tokenValue, Type: tokenType})
+
+	return nil
+}
+
+// InvalidateTokens removes many tokens from storage. When the storage
+// backend implements BatchStorage, they are deleted in a single roundtrip.
+// References are validated before anything is deleted, so an empty token
+// value or a stateless link token fails the whole batch.
+func (m *Manager) InvalidateTokens(ctx context.Context, refs []TokenRef) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("context error: %w", err)
+	}
+
+	normalized := make([]TokenRef, len(refs))
+	for i, ref := range refs {
+		if ref.Value == "" {
+			return fmt.Errorf("token %d: %w", i, ErrEmptyTokenValue)
+		}
+		if m.isStateless(ref.Type) {
+			return fmt.Errorf("token %d: %w", i, ErrStatelessToken)
+		}
+		normalized[i] = TokenRef{Value: m.normalize(ref.Value, ref.Type), Type: ref.Type}
+	}
+
+	if err := m.deleteAll(ctx, normalized); err != nil {
+		m.log(ctx).Error("failed to invalidate token batch",
+			"error", err,
+			"count", len(refs))
+		return fmt.Errorf("failed to invalidate tokens: %w", err)
+	}
+
+	m.log(ctx).Info("token batch invalidated successfully",
+		"count", len(refs))
+
+	for _, ref := range normalized {
+		fire(ctx, m.hooks.OnInvalidated, &Token{Value: ref.Value, Type: ref.Type})
+	}
 
 	return nil
 }
@@ -234,18 +887,55 @@ func (m *Manager) InvalidateValidation(ctx context.Context, validationID string)
 
 	err := m.storage.DeleteByValidationID(ctx, validationID)
 	if err != nil {
-		m.logger.Error("failed to invalidate validation tokens",
+		m.log(ctx).Error("failed to invalidate validation tokens",
 			"error", err,
 			"validation_id", validationID)
 		return fmt.Errorf("failed to invalidate validation tokens: %w", err)
 	}
 
-	m.logger.Info("validation tokens invalidated successfully",
+	m.log(ctx).Info("validation tokens invalidated successfully",
 		"validation_id", validationID)
 
+	fire(ctx, m.hooks.OnInvalidated, &Token{ValidationID: validationID})
+
 	return nil
 }
 
+// InvalidateTokensOfType removes the tokens of one type associated with a
+// validation ID, leaving its other tokens and its attempt counter in place,
+// for example to replace its codes while its links keep working.
+func (m *Manager) InvalidateTokensOfType(ctx context.Context, validationID string, tokenType Type) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("context error: %w", err)
+	}
+
+	if validationID == "" {
+		return ErrEmptyValidationID
+	}
+
+	if m.isStateless(tokenType) {
+		return ErrStatelessToken
+	}
+
+	tokens, err := m.storage.ListByValidationID(ctx, validationID)
+	if err != nil {
+		return fmt.Errorf("failed to list tokens from storage: %w", err)
+	}
+
+	var refs []TokenRef
+	for _, t := range tokens {
+		if t.Type == tokenType {
+			refs = append(refs, TokenRef{Value: t.Value, Type: t.Type})
+		}
+	}
+
+	if len(refs) == 0 {
+		return nil
+	}
+
+	return m.InvalidateTokens(ctx, refs)
+}
+
 // GetTokenInfo retrieves token information without performing full verification.
 // This is useful for debugging and administrative purposes.
 func (m *Manager) GetTokenInfo(ctx context.Context, tokenValue string, tokenType Type) (*Token, error) {
@@ -257,15 +947,56 @@ func (m *Manager) GetTokenInfo(ctx context.Context, tokenValue string, tokenType
 		return nil, ErrEmptyTokenValue
 	}
 
-	token, err := m.storage.Retrieve(ctx, tokenValue, tokenType)
+	tokenValue = m.normalize(tokenValue, tokenType)
+
+	token, err := m.retrieve(ctx, tokenValue, tokenType)
 	if err != nil {
-		return nil, fmt.Errorf("failed to retrieve token info from storage: %w", err)
+		return nil, err
 	}
 
-	m.logger.Debug("token info retrieved",
+	m.log(ctx).Debug("token info retrieved",
 		"token_type", tokenType,
 		"validation_id", token.ValidationID,
-		"expired", token.IsExpired())
+		"expired", token.IsExpiredAt(m.clock.Now()))
 
 	return token, nil
 }
+
+// record journals a verification decision when a journal is configured.
+// Error details are reduced to a short classification because error
+// messages may contain token values.
+func (m *Manager) record(ctx context.Context, operation, tokenValue, validationID string, err error) {
+	if m.journal == nil {
+		return
+	}
+
+	entry := journal.Entry{
+		Operation:    operation,
+		Subject:      redact.Token(tokenValue),
+		ValidationID: validationID,
+		Decision:     journal.DecisionVerified,
+	}
+
+	if err != nil {
+		entry.Decision = journal.DecisionRejected
+		entry.Detail = rejectionDetail(err)
+	}
+
+	if jErr := m.journal.Record(ctx, entry); jErr != nil {
+		m.log(ctx).Warn("failed to record journal entry", "error", jErr)
+	}
+}
+
+// rejectionDetail classifies a verification error without exposing its message.
+func rejectionDetail(err error) string {
+	return strings.ToLower(string(ReasonOf(err)))
+}
+
+// validationIDOf returns the validation ID of t, or an empty string if t is nil.
+func validationIDOf(t *Token) string {
+	if t == nil {
+		return ""
+	}
+
+	return t.ValidationID
+}
@@ -1,15 +1,31 @@
 package managertest
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/jaeyeom/email-validator-grpc-mcp/ctxkeys"
+	"github.com/jaeyeom/email-validator-grpc-mcp/journal"
 	"github.com/jaeyeom/email-validator-grpc-mcp/token"
 	"github.com/jaeyeom/email-validator-grpc-mcp/token/storage/memory"
 )
 
+// verify verifies tkn the way its type must be: a code with its validation,
+// and a link by itself.
+func verify(ctx context.Context, manager *token.Manager, tkn *token.Token) (*token.Token, error) {
+	if tkn.Type == token.TypeCode {
+		return manager.VerifyCodeToken(ctx, tkn.ValidationID, tkn.Value)
+	}
+
+	return manager.VerifyToken(ctx, tkn.Value, tkn.Type)
+}
+
 func TestManager_CreateLinkToken(t *testing.T) {
 	ctx := context.Background()
 	storage := memory.New()
@@ -201,6 +217,423 @@ func TestManager_VerifyToken_ExpiredToken(t *testing.T) {
 	}
 }
 
+func TestManager_VerifyAndConsume(t *testing.T) {
+	ctx := context.Background()
+	manager := token.NewManager(memory.New())
+
+	linkToken, err := manager.CreateLinkToken(ctx, "test-validation-consume")
+	if err != nil {
+		t.Fatalf("Failed to create test token: %v", err)
+	}
+	codeToken, err := manager.CreateCodeToken(ctx, "test-validation-consume")
+	if err != nil {
+		t.Fatalf("Failed to create test token: %v", err)
+	}
+
+	// Codes are verified with their validation, where attempts are counted
+	if _, err := manager.VerifyAndConsume(ctx, codeToken.Value, token.TypeCode); !errors.Is(err, token.ErrCodeNeedsValidation) {
+		t.Errorf("VerifyAndConsume() of a code error = %v, want %v", err, token.ErrCodeNeedsValidation)
+	}
+
+	tok, err := manager.VerifyAndConsume(ctx, linkToken.Value, token.TypeLink)
+	if err != nil {
+		t.Fatalf("VerifyAndConsume() error = %v", err)
+	}
+	if tok.ValidationID != "test-validation-consume" {
+		t.Errorf("VerifyAndConsume() validation ID = %v, want test-validation-consume", tok.ValidationID)
+	}
+
+	// The token must not be usable a second time
+	if _, err := manager.VerifyAndConsume(ctx, linkToken.Value, token.TypeLink); !errors.Is(err, token.ErrTokenNotFound) {
+		t.Errorf("second VerifyAndConsume() error = %v, want %v", err, token.ErrTokenNotFound)
+	}
+	if _, err := manager.VerifyToken(ctx, linkToken.Value, token.TypeLink); err == nil {
+		t.Error("VerifyToken() should fail after the token was consumed")
+	}
+}
+
+func TestManager_VerifyToken_CodeGuesses(t *testing.T) {
+	ctx := context.Background()
+	manager := token.NewManager(memory.New(), token.WithMaxCodeAttempts(3))
+
+	codeToken, err := manager.CreateCodeToken(ctx, "test-validation-guesses")
+	if err != nil {
+		t.Fatalf("CreateCodeToken() failed: %v", err)
+	}
+
+	// Guesses without the validation ID could not be counted, so none is
+	// looked up, not even the right code
+	for _, guess := range []string{"000000", "000001", "000002", "000003", codeToken.Value} {
+		if _, err := manager.VerifyToken(ctx, guess, token.TypeCode); !errors.Is(err, token.ErrCodeNeedsValidation) {
+			t.Fatalf("VerifyToken(%q) error = %v, want %v", guess, err, token.ErrCodeNeedsValidation)
+		}
+		if _, err := manager.VerifyAndConsume(ctx, guess, token.TypeCode); !errors.Is(err, token.ErrCodeNeedsValidation) {
+			t.Fatalf("VerifyAndConsume(%q) error = %v, want %v", guess, err, token.ErrCodeNeedsValidation)
+		}
+	}
+
+	// Guesses with the validation ID lock it out
+	wrong := "wrong-code"
+	for i := 1; i < 3; i++ {
+		if _, err := manager.VerifyCodeToken(ctx, "test-validation-guesses", wrong); !errors.Is(err, token.ErrTokenNotFound) {
+			t.Fatalf("attempt %d: VerifyCodeToken() error = %v, want %v", i, err, token.ErrTokenNotFound)
+		}
+	}
+	if _, err := manager.VerifyCodeToken(ctx, "test-validation-guesses", wrong); !errors.Is(err, token.ErrAttemptsExceeded) {
+		t.Fatalf("final attempt: VerifyCodeToken() error = %v, want %v", err, token.ErrAttemptsExceeded)
+	}
+	if _, err := manager.VerifyCodeToken(ctx, "test-validation-guesses", codeToken.Value); err == nil {
+		t.Error("VerifyCodeToken() succeeded after the lockout")
+	}
+
+	// Without attempt limiting, codes are looked up as before
+	unlimited := token.NewManager(memory.New(), token.WithMaxCodeAttempts(0))
+	codeToken, err = unlimited.CreateCodeToken(ctx, "test-validation-unlimited")
+	if err != nil {
+		t.Fatalf("CreateCodeToken() failed: %v", err)
+	}
+	if _, err := unlimited.VerifyToken(ctx, codeToken.Value, token.TypeCode); err != nil {
+		t.Errorf("VerifyToken() without attempt limiting failed: %v", err)
+	}
+}
+
+func TestManager_VerifyCodeToken_AttemptLimit(t *testing.T) {
+	ctx := context.Background()
+	manager := token.NewManager(memory.New(), token.WithMaxCodeAttempts(3))
+
+	validationID := "test-validation-attempts"
+	codeToken, err := manager.CreateCodeToken(ctx, validationID)
+	if err != nil {
+		t.Fatalf("Failed to create test token: %v", err)
+	}
+
+	wrong := "not-the-code"
+	for i := 1; i < 3; i++ {
+		if _, err := manager.VerifyCodeToken(ctx, validationID, wrong); !errors.Is(err, token.ErrTokenNotFound) {
+			t.Fatalf("attempt %d: VerifyCodeToken() error = %v, want %v", i, err, token.ErrTokenNotFound)
+		}
+	}
+
+	if _, err := manager.VerifyCodeToken(ctx, validationID, wrong); !errors.Is(err, token.ErrAttemptsExceeded) {
+		t.Fatalf("final attempt: VerifyCodeToken() error = %v, want %v", err, token.ErrAttemptsExceeded)
+	}
+
+	// The correct code no longer works once attempts are exhausted
+	if _, err := manager.VerifyCodeToken(ctx, validationID, codeToken.Value); err == nil {
+		t.Error("VerifyCodeToken() succeeded after attempts were exceeded")
+	}
+}
+
+func TestManager_VerifyCodeToken(t *testing.T) {
+	ctx := context.Background()
+	manager := token.NewManager(memory.New())
+
+	codeToken, err := manager.CreateCodeToken(ctx, "test-validation-code")
+	if err != nil {
+		t.Fatalf("Failed to create test token: %v", err)
+	}
+
+	if _, err := manager.VerifyCodeToken(ctx, "other-validation", codeToken.Value); err == nil {
+		t.Error("VerifyCodeToken() accepted a code issued for another validation")
+	}
+
+	tok, err := manager.VerifyCodeToken(ctx, "test-validation-code", codeToken.Value)
+	if err != nil {
+		t.Fatalf("VerifyCodeToken() error = %v", err)
+	}
+	if tok.Value != codeToken.Value {
+		t.Errorf("VerifyCodeToken() value = %v, want %v", tok.Value, codeToken.Value)
+	}
+}
+
+func TestManager_WithClock(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	clock := token.ClockFunc(func() time.Time { return now })
+
+	storage := memory.New(memory.WithClock(clock))
+	manager := token.NewManager(storage, token.WithClock(clock), token.WithCodeTokenTTL(10*time.Minute))
+
+	codeToken, err := manager.CreateCodeToken(ctx, "test-validation-clock")
+	if err != nil {
+		t.Fatalf("CreateCodeToken() failed: %v", err)
+	}
+	if !codeToken.CreatedAt.Equal(now) {
+		t.Errorf("CreatedAt = %v, want %v", codeToken.CreatedAt, now)
+	}
+
+	now = now.Add(9 * time.Minute)
+	if _, err := manager.VerifyCodeToken(ctx, "test-validation-clock", codeToken.Value); err != nil {
+		t.Errorf("VerifyCodeToken() before expiry failed: %v", err)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, err := manager.VerifyCodeToken(ctx, "test-validation-clock", codeToken.Value); !token.IsTokenExpiredError(err) {
+		t.Errorf("VerifyCodeToken() after expiry error = %v, want TokenExpiredError", err)
+	}
+}
+
+func TestManager_ExtendTokenTTL(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	clock := token.ClockFunc(func() time.Time { return now })
+	manager := token.NewManager(memory.New(memory.WithClock(clock)), token.WithClock(clock))
+
+	linkToken, err := manager.CreateTokenWithTTL(ctx, token.TypeLink, "test-validation-extend", time.Hour)
+	if err != nil {
+		t.Fatalf("CreateTokenWithTTL() failed: %v", err)
+	}
+
+	if _, err := manager.ExtendTokenTTL(ctx, linkToken.Value, token.TypeLink, 0); err == nil {
+		t.Error("ExtendTokenTTL() accepted a non-positive extension")
+	}
+
+	extended, err := manager.ExtendTokenTTL(ctx, linkToken.Value, token.TypeLink, 2*time.Hour)
+	if err != nil {
+		t.Fatalf("ExtendTokenTTL() failed: %v", err)
+	}
+	if want := linkToken.ValidUntil.Add(2 * time.Hour); !extended.ValidUntil.Equal(want) {
+		t.Errorf("ExtendTokenTTL() valid until = %v, want %v", extended.ValidUntil, want)
+	}
+
+	now = now.Add(2 * time.Hour)
+	if _, err := manager.VerifyToken(ctx, linkToken.Value, token.TypeLink); err != nil {
+		t.Errorf("VerifyToken() after extension failed: %v", err)
+	}
+}
+
+func TestManager_CreateTokenWithOptions(t *testing.T) {
+	ctx := context.Background()
+	manager := token.NewManager(memory.New())
+
+	metadata := map[string]string{
+		"requester_ip": "203.0.113.7",
+		"locale":       "ko-KR",
+		"campaign_id":  "spring-2025",
+	}
+
+	created, err := manager.CreateTokenWithOptions(ctx, token.TypeCode, "test-validation-metadata", token.CreateOptions{
+		TTL:      5 * time.Minute,
+		Metadata: metadata,
+	})
+	if err != nil {
+		t.Fatalf("CreateTokenWithOptions() failed: %v", err)
+	}
+
+	// Later changes to the caller's map must not affect the stored token
+	metadata["locale"] = "en-US"
+
+	tok, err := manager.VerifyCodeToken(ctx, "test-validation-metadata", created.Value)
+	if err != nil {
+		t.Fatalf("VerifyCodeToken() failed: %v", err)
+	}
+	if tok.Metadata["locale"] != "ko-KR" || tok.Metadata["campaign_id"] != "spring-2025" {
+		t.Errorf("VerifyToken() metadata = %v, want original metadata", tok.Metadata)
+	}
+
+	if ttl := created.ValidUntil.Sub(created.CreatedAt); ttl != 5*time.Minute {
+		t.Errorf("CreateTokenWithOptions() TTL = %v, want 5m", ttl)
+	}
+
+	defaulted, err := manager.CreateTokenWithOptions(ctx, token.TypeCode, "test-validation-metadata", token.CreateOptions{})
+	if err != nil {
+		t.Fatalf("CreateTokenWithOptions() failed: %v", err)
+	}
+	if ttl := defaulted.ValidUntil.Sub(defaulted.CreatedAt); ttl != 10*time.Minute {
+		t.Errorf("CreateTokenWithOptions() default TTL = %v, want 10m", ttl)
+	}
+}
+
+func TestManager_CreateOrGetLinkToken(t *testing.T) {
+	ctx := context.Background()
+	manager := token.NewManager(memory.New())
+
+	first, err := manager.CreateOrGetLinkToken(ctx, "test-validation-idempotent")
+	if err != nil {
+		t.Fatalf("CreateOrGetLinkToken() failed: %v", err)
+	}
+
+	second, err := manager.CreateOrGetLinkToken(ctx, "test-validation-idempotent")
+	if err != nil {
+		t.Fatalf("CreateOrGetLinkToken() failed: %v", err)
+	}
+	if second.Value != first.Value {
+		t.Errorf("CreateOrGetLinkToken() = %q, want existing token %q", second.Value, first.Value)
+	}
+
+	// Once the link is consumed a new one is issued
+	if _, err := manager.VerifyAndConsume(ctx, first.Value, token.TypeLink); err != nil {
+		t.Fatalf("VerifyAndConsume() failed: %v", err)
+	}
+	third, err := manager.CreateOrGetLinkToken(ctx, "test-validation-idempotent")
+	if err != nil {
+		t.Fatalf("CreateOrGetLinkToken() failed: %v", err)
+	}
+	if third.Value == first.Value {
+		t.Error("CreateOrGetLinkToken() returned a consumed token")
+	}
+}
+
+func TestManager_CreateTokens(t *testing.T) {
+	ctx := context.Background()
+	manager := token.NewManager(memory.New())
+
+	created, err := manager.CreateTokens(ctx, []token.TokenRequest{
+		{Type: token.TypeLink, ValidationID: "test-validation-batch"},
+		{Type: token.TypeCode, ValidationID: "test-validation-batch", Options: token.CreateOptions{TTL: time.Minute}},
+	})
+	if err != nil {
+		t.Fatalf("CreateTokens() failed: %v", err)
+	}
+	if len(created) != 2 {
+		t.Fatalf("CreateTokens() returned %d tokens, want 2", len(created))
+	}
+
+	for _, tkn := range created {
+		if _, err := verify(ctx, manager, tkn); err != nil {
+			t.Errorf("verify(%v) failed: %v", tkn.Type, err)
+		}
+	}
+	if ttl := created[1].ValidUntil.Sub(created[1].CreatedAt); ttl != time.Minute {
+		t.Errorf("CreateTokens() code TTL = %v, want 1m", ttl)
+	}
+
+	// An invalid request fails the batch before anything is stored
+	_, err = manager.CreateTokens(ctx, []token.TokenRequest{
+		{Type: token.TypeCode, ValidationID: "test-validation-rejected"},
+		{Type: token.TypeCode, ValidationID: ""},
+	})
+	if !errors.Is(err, token.ErrEmptyValidationID) {
+		t.Fatalf("CreateTokens() error = %v, want %v", err, token.ErrEmptyValidationID)
+	}
+}
+
+func TestManager_InvalidateTokens(t *testing.T) {
+	ctx := context.Background()
+	var invalidated []string
+	manager := token.NewManager(memory.New(), token.WithHooks(token.Hooks{
+		OnInvalidated: func(_ context.Context, tkn *token.Token) {
+			invalidated = append(invalidated, tkn.Value)
+		},
+	}))
+
+	created, err := manager.CreateTokens(ctx, []token.TokenRequest{
+		{Type: token.TypeLink, ValidationID: "test-validation-bulk"},
+		{Type: token.TypeCode, ValidationID: "test-validation-bulk"},
+	})
+	if err != nil {
+		t.Fatalf("CreateTokens() failed: %v", err)
+	}
+
+	refs := make([]token.TokenRef, len(created))
+	for i, tkn := range created {
+		refs[i] = token.TokenRef{Value: tkn.Value, Type: tkn.Type}
+	}
+
+	// An empty value fails the batch before anything is deleted
+	if err := manager.InvalidateTokens(ctx, append(refs, token.TokenRef{Type: token.TypeLink})); !errors.Is(err, token.ErrEmptyTokenValue) {
+		t.Fatalf("InvalidateTokens() error = %v, want %v", err, token.ErrEmptyTokenValue)
+	}
+	if _, err := manager.VerifyToken(ctx, created[0].Value, created[0].Type); err != nil {
+		t.Fatalf("VerifyToken() after rejected InvalidateTokens() failed: %v", err)
+	}
+
+	if err := manager.InvalidateTokens(ctx, refs); err != nil {
+		t.Fatalf("InvalidateTokens() failed: %v", err)
+	}
+	for _, tkn := range created {
+		if _, err := verify(ctx, manager, tkn); !errors.Is(err, token.ErrTokenNotFound) {
+			t.Errorf("verify(%v) after InvalidateTokens() error = %v, want %v", tkn.Type, err, token.ErrTokenNotFound)
+		}
+	}
+	if len(invalidated) != len(created) {
+		t.Errorf("OnInvalidated called %d times, want %d", len(invalidated), len(created))
+	}
+}
+
+func TestManager_VerifyTokenDetailed(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := token.ClockFunc(func() time.Time { return now })
+	storage := memory.New(memory.WithClock(clock))
+	manager := token.NewManager(storage,
+		token.WithClock(clock),
+		token.WithMaxCodeAttempts(1),
+	)
+
+	link, err := manager.CreateLinkToken(ctx, "test-validation-detailed")
+	if err != nil {
+		t.Fatalf("CreateLinkToken() failed: %v", err)
+	}
+	code, err := manager.CreateCodeToken(ctx, "test-validation-detailed")
+	if err != nil {
+		t.Fatalf("CreateCodeToken() failed: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		result func() token.VerificationResult
+		want   token.Reason
+	}{
+		{
+			name:   "verified",
+			result: func() token.VerificationResult { return manager.VerifyTokenDetailed(ctx, link.Value, token.TypeLink) },
+			want:   token.ReasonVerified,
+		},
+		{
+			name:   "wrong type",
+			result: func() token.VerificationResult { return manager.VerifyTokenDetailed(ctx, code.Value, token.TypeLink) },
+			want:   token.ReasonNotFound,
+		},
+		{
+			name:   "code without validation",
+			result: func() token.VerificationResult { return manager.VerifyTokenDetailed(ctx, link.Value, token.TypeCode) },
+			want:   token.ReasonInvalidRequest,
+		},
+		{
+			name:   "not found",
+			result: func() token.VerificationResult { return manager.VerifyTokenDetailed(ctx, "missing", token.TypeLink) },
+			want:   token.ReasonNotFound,
+		},
+		{
+			name:   "invalid request",
+			result: func() token.VerificationResult { return manager.VerifyTokenDetailed(ctx, "", token.TypeLink) },
+			want:   token.ReasonInvalidRequest,
+		},
+		{
+			name: "attempts exceeded",
+			result: func() token.VerificationResult {
+				return manager.VerifyCodeTokenDetailed(ctx, "test-validation-detailed", "wrong")
+			},
+			want: token.ReasonAttemptsExceeded,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := tt.result()
+			if result.Reason != tt.want {
+				t.Errorf("Reason = %v, want %v (err: %v)", result.Reason, tt.want, result.Err)
+			}
+			if result.Verified() != (result.Err == nil) || result.Verified() != (result.Token != nil) {
+				t.Errorf("result = %+v is inconsistent", result)
+			}
+		})
+	}
+
+	// An expired code counts as a failed attempt, which with a single
+	// attempt allowed would report the lockout instead
+	manager = token.NewManager(storage, token.WithClock(clock))
+	code, err = manager.CreateCodeToken(ctx, "test-validation-expiring")
+	if err != nil {
+		t.Fatalf("CreateCodeToken() failed: %v", err)
+	}
+	now = now.Add(time.Hour)
+	if result := manager.VerifyCodeTokenDetailed(ctx, "test-validation-expiring", code.Value); result.Reason != token.ReasonExpired {
+		t.Errorf("Reason = %v, want %v", result.Reason, token.ReasonExpired)
+	}
+}
+
 func TestManager_InvalidateValidation(t *testing.T) {
 	ctx := context.Background()
 	storage := memory.New()
@@ -278,6 +711,503 @@ func TestManager_WithOptions(t *testing.T) {
 	}
 }
 
+// sequenceGenerator is a deterministic TokenGenerator for tests.
+type sequenceGenerator struct {
+	next int
+}
+
+func (g *sequenceGenerator) GenerateLinkToken() (string, error) {
+	g.next++
+	return fmt.Sprintf("link-%d", g.next), nil
+}
+
+func (g *sequenceGenerator) GenerateCodeToken() (string, error) {
+	g.next++
+	return fmt.Sprintf("%06d", g.next), nil
+}
+
+func TestManager_WithCustomGenerator(t *testing.T) {
+	ctx := context.Background()
+	manager := token.NewManager(memory.New(), token.WithGenerator(&sequenceGenerator{}))
+
+	link, err := manager.CreateLinkToken(ctx, "test-custom-generator")
+	if err != nil {
+		t.Fatalf("CreateLinkToken() failed: %v", err)
+	}
+	if link.Value != "link-1" {
+		t.Errorf("CreateLinkToken() value = %q, want %q", link.Value, "link-1")
+	}
+
+	code, err := manager.CreateCodeToken(ctx, "test-custom-generator")
+	if err != nil {
+		t.Fatalf("CreateCodeToken() failed: %v", err)
+	}
+	if code.Value != "000002" {
+		t.Errorf("CreateCodeToken() value = %q, want %q", code.Value, "000002")
+	}
+
+	if _, err := manager.VerifyCodeToken(ctx, "test-custom-generator", "000002"); err != nil {
+		t.Errorf("VerifyCodeToken() failed: %v", err)
+	}
+}
+
+func TestManager_CaseInsensitiveLinkTokens(t *testing.T) {
+	ctx := context.Background()
+	generator := token.NewGenerator().WithLinkTokenEncoding(token.EncodingBase32Crockford)
+	manager := token.NewManager(memory.New(), token.WithGenerator(generator))
+
+	created, err := manager.CreateLinkToken(ctx, "test-crockford")
+	if err != nil {
+		t.Fatalf("CreateLinkToken() failed: %v", err)
+	}
+
+	typed := strings.ToLower(created.Value)
+	tok, err := manager.VerifyToken(ctx, typed, token.TypeLink)
+	if err != nil {
+		t.Fatalf("VerifyToken(%q) failed: %v", typed, err)
+	}
+	if tok.Value != created.Value {
+		t.Errorf("VerifyToken() value = %q, want %q", tok.Value, created.Value)
+	}
+}
+
+func TestManager_TypedCodeTokens(t *testing.T) {
+	ctx := context.Background()
+	generator := token.NewGenerator().
+		WithCodeCharset(token.CharsetAlnumNoAmbiguous).
+		WithCodeTokenLength(6).
+		WithCodeGrouping(3)
+	manager := token.NewManager(memory.New(), token.WithGenerator(generator), token.WithMaxCodeAttempts(1))
+
+	for _, typing := range []struct {
+		name string
+		fn   func(string) string
+	}{
+		{"without separator", func(code string) string { return strings.ReplaceAll(code, token.CodeGroupSeparator, "") }},
+		{"lower case", strings.ToLower},
+	} {
+		validationID := "test-typed-" + strings.ReplaceAll(typing.name, " ", "-")
+		created, err := manager.CreateCodeToken(ctx, validationID)
+		if err != nil {
+			t.Fatalf("CreateCodeToken() failed: %v", err)
+		}
+
+		// A single allowed attempt shows the typed spelling does not burn one
+		typed := typing.fn(created.Value)
+		tok, err := manager.VerifyCodeToken(ctx, validationID, typed)
+		if err != nil {
+			t.Fatalf("%s: VerifyCodeToken(%q) failed: %v", typing.name, typed, err)
+		}
+		if tok.Value != created.Value {
+			t.Errorf("%s: VerifyCodeToken() value = %q, want %q", typing.name, tok.Value, created.Value)
+		}
+	}
+}
+
+func TestManager_TokenPrefixes(t *testing.T) {
+	ctx := context.Background()
+	generator := token.NewGenerator().WithLinkTokenPrefix("evl_").WithCodeTokenPrefix("evc_")
+	manager := token.NewManager(memory.New(), token.WithGenerator(generator))
+
+	created, err := manager.CreateLinkToken(ctx, "test-prefixes")
+	if err != nil {
+		t.Fatalf("CreateLinkToken() failed: %v", err)
+	}
+	if _, err := manager.VerifyToken(ctx, created.Value, token.TypeLink); err != nil {
+		t.Errorf("VerifyToken() failed: %v", err)
+	}
+
+	// A code-prefixed value presented as a link token is rejected up front
+	_, err = manager.VerifyToken(ctx, "evc_"+strings.TrimPrefix(created.Value, "evl_"), token.TypeLink)
+	if !errors.Is(err, token.ErrTokenPrefixMismatch) {
+		t.Errorf("VerifyToken() error = %v, want %v", err, token.ErrTokenPrefixMismatch)
+	}
+}
+
+func TestManager_WithHooks(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := token.ClockFunc(func() time.Time { return now })
+
+	var events []string
+	record := func(name string) token.HookFunc {
+		return func(_ context.Context, t *token.Token) {
+			events = append(events, name+":"+t.ValidationID)
+		}
+	}
+
+	manager := token.NewManager(memory.New(memory.WithClock(clock)),
+		token.WithClock(clock),
+		token.WithHooks(token.Hooks{
+			OnCreated:     record("created"),
+			OnVerified:    record("verified"),
+			OnExpired:     record("expired"),
+			OnInvalidated: record("invalidated"),
+		}),
+	)
+
+	link, err := manager.CreateLinkToken(ctx, "hooks-1")
+	if err != nil {
+		t.Fatalf("CreateLinkToken() failed: %v", err)
+	}
+	if _, err := manager.VerifyToken(ctx, link.Value, token.TypeLink); err != nil {
+		t.Fatalf("VerifyToken() failed: %v", err)
+	}
+	if err := manager.InvalidateValidation(ctx, "hooks-1"); err != nil {
+		t.Fatalf("InvalidateValidation() failed: %v", err)
+	}
+
+	code, err := manager.CreateCodeToken(ctx, "hooks-2")
+	if err != nil {
+		t.Fatalf("CreateCodeToken() failed: %v", err)
+	}
+	now = now.Add(time.Hour)
+	if _, err := manager.VerifyCodeToken(ctx, "hooks-2", code.Value); !token.IsTokenExpiredError(err) {
+		t.Fatalf("VerifyCodeToken() error = %v, want expired", err)
+	}
+
+	want := []string{"created:hooks-1", "verified:hooks-1", "invalidated:hooks-1", "created:hooks-2", "expired:hooks-2"}
+	if strings.Join(events, ",") != strings.Join(want, ",") {
+		t.Errorf("hook events = %v, want %v", events, want)
+	}
+}
+
+func TestManager_Revoke(t *testing.T) {
+	ctx := context.Background()
+	signer, err := token.NewSignedGenerator([]byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatalf("NewSignedGenerator() failed: %v", err)
+	}
+
+	manager := token.NewManager(memory.New(),
+		token.WithLinkTokenSigner(signer),
+		token.WithRevocationList(memory.NewRevocationList(nil)),
+	)
+
+	link, err := manager.CreateLinkToken(ctx, "test-validation-revoke")
+	if err != nil {
+		t.Fatalf("CreateLinkToken() failed: %v", err)
+	}
+	code, err := manager.CreateCodeToken(ctx, "test-validation-revoke")
+	if err != nil {
+		t.Fatalf("CreateCodeToken() failed: %v", err)
+	}
+
+	for _, tkn := range []*token.Token{link, code} {
+		if err := manager.Revoke(ctx, tkn.Value, tkn.Type, "incident-42"); err != nil {
+			t.Fatalf("Revoke(%v) failed: %v", tkn.Type, err)
+		}
+		if _, err := verify(ctx, manager, tkn); !errors.Is(err, token.ErrTokenRevoked) {
+			t.Errorf("verify(%v) error = %v, want %v", tkn.Type, err, token.ErrTokenRevoked)
+		}
+	}
+
+	unconfigured := token.NewManager(memory.New())
+	if err := unconfigured.Revoke(ctx, code.Value, token.TypeCode, "incident-42"); !errors.Is(err, token.ErrRevocationNotEnabled) {
+		t.Errorf("Revoke() without list error = %v, want %v", err, token.ErrRevocationNotEnabled)
+	}
+}
+
+func TestManager_EmailBinding(t *testing.T) {
+	ctx := context.Background()
+
+	for _, privacy := range []token.EmailPrivacy{token.EmailHashed, token.EmailPlaintext} {
+		manager := token.NewManager(memory.New(), token.WithEmailPrivacy(privacy))
+
+		tkn, err := manager.CreateTokenWithOptions(ctx, token.TypeLink, "test-validation-email", token.CreateOptions{
+			Email: "Alice@Example.com",
+		})
+		if err != nil {
+			t.Fatalf("CreateTokenWithOptions() failed: %v", err)
+		}
+
+		_, plain := tkn.Metadata[token.MetadataKeyEmail]
+		if plain != (privacy == token.EmailPlaintext) {
+			t.Errorf("privacy %v: plaintext email stored = %v", privacy, plain)
+		}
+
+		if _, err := manager.VerifyTokenForEmail(ctx, tkn.Value, token.TypeLink, "bob@example.com"); !errors.Is(err, token.ErrEmailMismatch) {
+			t.Errorf("privacy %v: VerifyTokenForEmail() with other address error = %v, want %v", privacy, err, token.ErrEmailMismatch)
+		}
+		if _, err := manager.VerifyAndConsumeForEmail(ctx, tkn.Value, token.TypeLink, "bob@example.com"); !errors.Is(err, token.ErrEmailMismatch) {
+			t.Errorf("privacy %v: VerifyAndConsumeForEmail() with other address error = %v, want %v", privacy, err, token.ErrEmailMismatch)
+		}
+		if _, err := manager.VerifyTokenForEmail(ctx, tkn.Value, token.TypeLink, " alice@example.com"); err != nil {
+			t.Errorf("privacy %v: VerifyTokenForEmail() error = %v", privacy, err)
+		}
+
+		// The mismatched consume above must not have used up the token
+		if _, err := manager.VerifyAndConsumeForEmail(ctx, tkn.Value, token.TypeLink, "alice@example.com"); err != nil {
+			t.Errorf("privacy %v: VerifyAndConsumeForEmail() error = %v", privacy, err)
+		}
+	}
+
+	// Tokens created without an address are not checked
+	manager := token.NewManager(memory.New())
+	unbound, err := manager.CreateLinkToken(ctx, "test-validation-unbound")
+	if err != nil {
+		t.Fatalf("CreateLinkToken() failed: %v", err)
+	}
+	if _, err := manager.VerifyTokenForEmail(ctx, unbound.Value, token.TypeLink, "anyone@example.com"); err != nil {
+		t.Errorf("VerifyTokenForEmail() of unbound token error = %v", err)
+	}
+
+	if got := token.ReasonOf(token.ErrEmailMismatch); got != token.ReasonEmailMismatch {
+		t.Errorf("ReasonOf(ErrEmailMismatch) = %v, want %v", got, token.ReasonEmailMismatch)
+	}
+}
+
+// unreadableStorage fails every Retrieve, as a storage backend does during
+// a transient outage.
+type unreadableStorage struct {
+	token.Storage
+}
+
+func (unreadableStorage) Retrieve(context.Context, string, token.Type) (*token.Token, error) {
+	return nil, errors.New("storage unavailable")
+}
+
+func TestManager_EmailBindingFailsClosed(t *testing.T) {
+	ctx := context.Background()
+	storage := memory.New()
+
+	// Tokens issued before link tokens were presented with a "v1." prefix
+	before := token.NewManager(storage)
+	legacy, err := before.CreateTokenWithOptions(ctx, token.TypeLink, "test-validation-email", token.CreateOptions{
+		Email: "alice@example.com",
+	})
+	if err != nil {
+		t.Fatalf("CreateTokenWithOptions() failed: %v", err)
+	}
+	current, err := before.CreateTokenWithOptions(ctx, token.TypeLink, "test-validation-email", token.CreateOptions{
+		Email: "alice@example.com",
+	})
+	if err != nil {
+		t.Fatalf("CreateTokenWithOptions() failed: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		manager *token.Manager
+		value   string
+	}{
+		{
+			name:    "retrieve error",
+			manager: token.NewManager(unreadableStorage{storage}),
+			value:   current.Value,
+		},
+		{
+			name: "legacy format",
+			manager: token.NewManager(storage, token.WithLegacyFormats(token.LegacyFormat{
+				Name:  "v1",
+				Until: time.Now().Add(time.Hour),
+				Lookup: func(tokenValue string, _ token.Type) (string, bool) {
+					return strings.CutPrefix(tokenValue, "v1.")
+				},
+			})),
+			value: "v1." + legacy.Value,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.manager.VerifyAndConsumeForEmail(ctx, tt.value, token.TypeLink, "bob@example.com"); !errors.Is(err, token.ErrEmailMismatch) {
+				t.Fatalf("VerifyAndConsumeForEmail() with other address error = %v, want %v", err, token.ErrEmailMismatch)
+			}
+
+			// The token is restored for the rightful recipient
+			got, err := tt.manager.VerifyAndConsumeForEmail(ctx, tt.value, token.TypeLink, "alice@example.com")
+			if err != nil {
+				t.Fatalf("VerifyAndConsumeForEmail() error = %v", err)
+			}
+			if got.ValidationID != "test-validation-email" {
+				t.Errorf("VerifyAndConsumeForEmail() validation ID = %q", got.ValidationID)
+			}
+		})
+	}
+}
+
+func TestManager_LogsContextIdentity(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	manager := token.NewManager(memory.New(), token.WithManagerLogger(logger))
+
+	ctx := ctxkeys.WithCaller(ctxkeys.WithTenant(context.Background(), "tenant-1"), "api-key-7")
+	if _, err := manager.CreateLinkToken(ctx, "test-validation-ctx"); err != nil {
+		t.Fatalf("CreateLinkToken() failed: %v", err)
+	}
+
+	logs := buf.String()
+	for _, want := range []string{"tenant_id=tenant-1", "caller=api-key-7"} {
+		if !strings.Contains(logs, want) {
+			t.Errorf("manager logs missing %q, got: %s", want, logs)
+		}
+	}
+}
+
+func TestManager_WithJournal(t *testing.T) {
+	ctx := ctxkeys.WithTenant(context.Background(), "tenant-1")
+	j := journal.NewRing(10)
+	manager := token.NewManager(memory.New(), token.WithJournal(j))
+
+	linkToken, err := manager.CreateLinkToken(ctx, "test-validation-journal")
+	if err != nil {
+		t.Fatalf("CreateLinkToken() failed: %v", err)
+	}
+
+	if _, err := manager.VerifyToken(ctx, linkToken.Value, token.TypeLink); err != nil {
+		t.Fatalf("VerifyToken() failed: %v", err)
+	}
+	if _, err := manager.VerifyToken(ctx, "missing-token-value", token.TypeLink); err == nil {
+		t.Fatal("VerifyToken() succeeded for a missing token")
+	}
+
+	entries, err := j.Recent(ctx, "tenant-1", 0)
+	if err != nil {
+		t.Fatalf("Recent() failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("journal has %d entries, want 2", len(entries))
+	}
+
+	if entries[0].Decision != "rejected" || entries[0].Detail != "not_found" {
+		t.Errorf("latest entry = %+v, want rejected/not_found", entries[0])
+	}
+	if entries[1].Decision != "verified" || entries[1].ValidationID != "test-validation-journal" {
+		t.Errorf("first entry = %+v, want verified for test-validation-journal", entries[1])
+	}
+	for _, e := range entries {
+		if strings.Contains(e.Subject, linkToken.Value) || strings.Contains(e.Subject, "missing-token-value") {
+			t.Errorf("journal entry leaks token value: %q", e.Subject)
+		}
+	}
+}
+
+func TestManager_WithLinkTokenSigner(t *testing.T) {
+	ctx := context.Background()
+	storage := memory.New()
+
+	signer, err := token.NewSignedGenerator([]byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatalf("NewSignedGenerator() failed: %v", err)
+	}
+	manager := token.NewManager(storage, token.WithLinkTokenSigner(signer))
+
+	linkToken, err := manager.CreateLinkToken(ctx, "test-validation-signed")
+	if err != nil {
+		t.Fatalf("CreateLinkToken() failed: %v", err)
+	}
+
+	// Stateless tokens are never written to storage
+	if _, err := storage.Retrieve(ctx, linkToken.Value, token.TypeLink); !errors.Is(err, token.ErrTokenNotFound) {
+		t.Errorf("storage.Retrieve() error = %v, want %v", err, token.ErrTokenNotFound)
+	}
+
+	tok, err := manager.VerifyToken(ctx, linkToken.Value, token.TypeLink)
+	if err != nil {
+		t.Fatalf("VerifyToken() failed: %v", err)
+	}
+	if tok.ValidationID != "test-validation-signed" {
+		t.Errorf("VerifyToken() validation ID = %v, want test-validation-signed", tok.ValidationID)
+	}
+
+	if _, err := manager.VerifyToken(ctx, linkToken.Value+"x", token.TypeLink); err == nil {
+		t.Error("VerifyToken() accepted a tampered signed token")
+	}
+
+	expired, err := manager.CreateTokenWithTTL(ctx, token.TypeLink, "test-validation-signed", time.Nanosecond)
+	if err != nil {
+		t.Fatalf("CreateTokenWithTTL() failed: %v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if _, err := manager.VerifyToken(ctx, expired.Value, token.TypeLink); !token.IsTokenExpiredError(err) {
+		t.Errorf("VerifyToken() error = %v, want TokenExpiredError", err)
+	}
+
+	if _, err := manager.VerifyAndConsume(ctx, linkToken.Value, token.TypeLink); !errors.Is(err, token.ErrStatelessToken) {
+		t.Errorf("VerifyAndConsume() error = %v, want %v", err, token.ErrStatelessToken)
+	}
+
+	// Stateless tokens cannot be invalidated, so they must not appear to be
+	var invalidated int
+	hooked := token.NewManager(storage, token.WithLinkTokenSigner(signer), token.WithHooks(token.Hooks{
+		OnInvalidated: func(context.Context, *token.Token) { invalidated++ },
+	}))
+	if err := hooked.InvalidateToken(ctx, linkToken.Value, token.TypeLink); !errors.Is(err, token.ErrStatelessToken) {
+		t.Errorf("InvalidateToken() error = %v, want %v", err, token.ErrStatelessToken)
+	}
+	if err := hooked.InvalidateTokens(ctx, []token.TokenRef{{Value: linkToken.Value, Type: token.TypeLink}}); !errors.Is(err, token.ErrStatelessToken) {
+		t.Errorf("InvalidateTokens() error = %v, want %v", err, token.ErrStatelessToken)
+	}
+	if invalidated != 0 {
+		t.Errorf("OnInvalidated called %d times for stateless tokens", invalidated)
+	}
+	if _, err := hooked.VerifyToken(ctx, linkToken.Value, token.TypeLink); err != nil {
+		t.Errorf("VerifyToken() after failed invalidation error = %v", err)
+	}
+}
+
+func TestManager_LegacyFormats(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	clock := token.ClockFunc(func() time.Time { return now })
+	storage := memory.New(memory.WithClock(clock))
+
+	// Tokens issued before link tokens gained a prefix
+	before := token.NewManager(storage, token.WithClock(clock))
+	legacyLink, err := before.CreateLinkToken(ctx, "test-validation-legacy")
+	if err != nil {
+		t.Fatalf("CreateLinkToken() failed: %v", err)
+	}
+	consumed, err := before.CreateLinkToken(ctx, "test-validation-legacy")
+	if err != nil {
+		t.Fatalf("CreateLinkToken() failed: %v", err)
+	}
+
+	manager := token.NewManager(storage,
+		token.WithClock(clock),
+		token.WithGenerator(token.NewGenerator().WithLinkTokenPrefix("evl_")),
+		token.WithLegacyFormats(token.LegacyFormat{
+			Name:  "unprefixed",
+			Until: now.Add(time.Hour),
+			Lookup: func(tokenValue string, tokenType token.Type) (string, bool) {
+				return tokenValue, tokenType == token.TypeLink && !strings.HasPrefix(tokenValue, "evl_")
+			},
+		}))
+
+	current, err := manager.CreateLinkToken(ctx, "test-validation-legacy")
+	if err != nil {
+		t.Fatalf("CreateLinkToken() failed: %v", err)
+	}
+
+	if _, err := manager.VerifyToken(ctx, current.Value, token.TypeLink); err != nil {
+		t.Errorf("VerifyToken() of current token failed: %v", err)
+	}
+	if _, err := manager.VerifyToken(ctx, legacyLink.Value, token.TypeLink); err != nil {
+		t.Errorf("VerifyToken() of legacy token failed: %v", err)
+	}
+	if _, err := manager.VerifyAndConsume(ctx, consumed.Value, token.TypeLink); err != nil {
+		t.Errorf("VerifyAndConsume() of legacy token failed: %v", err)
+	}
+	if _, err := manager.VerifyAndConsume(ctx, consumed.Value, token.TypeLink); !errors.Is(err, token.ErrTokenPrefixMismatch) {
+		t.Errorf("second VerifyAndConsume() error = %v, want %v", err, token.ErrTokenPrefixMismatch)
+	}
+	if _, err := manager.VerifyToken(ctx, "evl_missing", token.TypeLink); !errors.Is(err, token.ErrTokenNotFound) {
+		t.Errorf("VerifyToken() of unknown token error = %v, want %v", err, token.ErrTokenNotFound)
+	}
+
+	stats := manager.FormatStats()
+	if stats.Current != 1 || stats.Legacy["unprefixed"] != 2 {
+		t.Errorf("FormatStats() = %+v, want 1 current and 2 unprefixed", stats)
+	}
+
+	// Legacy tokens are rejected once the overlap window closes
+	now = now.Add(2 * time.Hour)
+	if _, err := manager.VerifyToken(ctx, legacyLink.Value, token.TypeLink); !errors.Is(err, token.ErrTokenPrefixMismatch) {
+		t.Errorf("VerifyToken() after overlap window error = %v, want %v", err, token.ErrTokenPrefixMismatch)
+	}
+}
+
 // BenchmarkManager_CreateAndVerifyToken benchmarks the complete token lifecycle.
 func BenchmarkManager_CreateAndVerifyToken(b *testing.B) {
 	ctx := context.Background()
@@ -0,0 +1,85 @@
+package token
+
+import (
+	"context"
+	"errors"
+)
+
+// Reason is a machine-readable verification outcome.
+type Reason string
+
+// Verification reasons.
+const (
+	ReasonVerified         Reason = "VERIFIED"
+	ReasonNotFound         Reason = "NOT_FOUND"
+	ReasonExpired          Reason = "EXPIRED"
+	ReasonTypeMismatch     Reason = "TYPE_MISMATCH"
+	ReasonAttemptsExceeded Reason = "ATTEMPTS_EXCEEDED"
+	ReasonRevoked          Reason = "REVOKED"
+	ReasonPrefixMismatch   Reason = "PREFIX_MISMATCH"
+	ReasonEmailMismatch    Reason = "EMAIL_MISMATCH"
+	ReasonInvalidRequest   Reason = "INVALID_REQUEST"
+	// ReasonError means verification could not be completed, for example
+	// because the storage backend failed. The token may still be valid.
+	ReasonError Reason = "ERROR"
+)
+
+// VerificationResult is the structured outcome of a verification.
+type VerificationResult struct {
+	// Token is the verified token, or nil if verification failed.
+	Token *Token
+
+	// Reason classifies the outcome.
+	Reason Reason
+
+	// Err is the underlying error, or nil if the token was verified.
+	Err error
+}
+
+// Verified reports whether the token was verified.
+func (r VerificationResult) Verified() bool {
+	return r.Reason == ReasonVerified
+}
+
+// ReasonOf classifies a verification error. A nil error is ReasonVerified.
+func ReasonOf(err error) Reason {
+	switch {
+	case err == nil:
+		return ReasonVerified
+	case errors.Is(err, ErrTokenNotFound):
+		return ReasonNotFound
+	case IsTokenExpiredError(err):
+		return ReasonExpired
+	case errors.Is(err, ErrTokenTypeMismatch):
+		return ReasonTypeMismatch
+	case errors.Is(err, ErrAttemptsExceeded):
+		return ReasonAttemptsExceeded
+	case errors.Is(err, ErrTokenRevoked):
+		return ReasonRevoked
+	case errors.Is(err, ErrTokenPrefixMismatch):
+		return ReasonPrefixMismatch
+	case errors.Is(err, ErrEmailMismatch):
+		return ReasonEmailMismatch
+	case errors.Is(err, ErrEmptyTokenValue), errors.Is(err, ErrEmptyValidationID), errors.Is(err, ErrCodeNeedsValidation):
+		return ReasonInvalidRequest
+	default:
+		return ReasonError
+	}
+}
+
+// VerifyTokenDetailed is like VerifyToken but reports the outcome as a
+// VerificationResult, so callers can branch on Reason instead of matching
+// errors.
+func (m *Manager) VerifyTokenDetailed(ctx context.Context, tokenValue string, tokenType Type) VerificationResult {
+	token, err := m.VerifyToken(ctx, tokenValue, tokenType)
+
+	return VerificationResult{Token: token, Reason: ReasonOf(err), Err: err}
+}
+
+// VerifyCodeTokenDetailed is like VerifyCodeToken but reports the outcome as
+// a VerificationResult.
+func (m *Manager) VerifyCodeTokenDetailed(ctx context.Context, validationID, code string) VerificationResult {
+	token, err := m.VerifyCodeToken(ctx, validationID, code)
+
+	return VerificationResult{Token: token, Reason: ReasonOf(err), Err: err}
+}
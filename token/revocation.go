@@ -0,0 +1,116 @@
+package token
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Errors for token revocation.
+var (
+	ErrTokenRevoked         = errors.New("token has been revoked")
+	ErrRevocationNotEnabled = errors.New("token revocation is not enabled")
+)
+
+// Revoker revokes individual tokens before their natural expiry. Manager
+// implements it when configured with WithRevocationList.
+type Revoker interface {
+	Revoke(ctx context.Context, tokenValue string, tokenType Type, reason string) error
+}
+
+// RevocationList records digests of revoked tokens. Entries only need to be
+// kept until expiresAt, after which the token is rejected as expired anyway.
+type RevocationList interface {
+	// Add records a revoked token digest with the reason it was revoked.
+	Add(ctx context.Context, digest string, expiresAt time.Time, reason string) error
+
+	// Contains reports whether the digest has been revoked.
+	Contains(ctx context.Context, digest string) (bool, error)
+}
+
+// TokenDigest returns the hex-encoded SHA-256 digest identifying a token in a
+// RevocationList, so revoked token values are never stored in the clear.
+func TokenDigest(tokenValue string, tokenType Type) string {
+	sum := sha256.Sum256([]byte(strconv.Itoa(int(tokenType)) + ":" + tokenValue))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// WithRevocationList enables Manager.Revoke and makes verification reject
+// tokens recorded in list. It is required to revoke stateless signed tokens.
+func WithRevocationList(list RevocationList) ManagerOption {
+	return func(m *Manager) {
+		m.revocations = list
+	}
+}
+
+// Revoke rejects a token from now until it expires, recording the reason
+// for incident response. Stored tokens are also deleted from storage.
+// Returns ErrRevocationNotEnabled if no revocation list is configured.
+func (m *Manager) Revoke(ctx context.Context, tokenValue string, tokenType Type, reason string) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("context error: %w", err)
+	}
+
+	if tokenValue == "" {
+		return ErrEmptyTokenValue
+	}
+
+	if m.revocations == nil {
+		return ErrRevocationNotEnabled
+	}
+
+	tokenValue = m.normalize(tokenValue, tokenType)
+
+	token, err := m.retrieve(ctx, tokenValue, tokenType)
+	if err != nil {
+		return err
+	}
+
+	if err := m.revocations.Add(ctx, TokenDigest(tokenValue, tokenType), token.ValidUntil, reason); err != nil {
+		m.log(ctx).Error("failed to record token revocation",
+			"error", err,
+			"token_type", tokenType,
+			"validation_id", token.ValidationID)
+		return fmt.Errorf("failed to record token revocation: %w", err)
+	}
+
+	if !m.isStateless(tokenType) {
+		if err := m.storage.Delete(ctx, tokenValue, tokenType); err != nil && !errors.Is(err, ErrTokenNotFound) {
+			return fmt.Errorf("failed to delete revoked token: %w", err)
+		}
+	}
+
+	m.log(ctx).Warn("token revoked",
+		"token_type", tokenType,
+		"validation_id", token.ValidationID,
+		"reason", reason)
+
+	fire(ctx, m.hooks.OnInvalidated, token)
+
+	return nil
+}
+
+// checkRevoked rejects tokens recorded in the revocation list.
+func (m *Manager) checkRevoked(ctx context.Context, tokenValue string, tokenType Type) error {
+	if m.revocations == nil {
+		return nil
+	}
+
+	revoked, err := m.revocations.Contains(ctx, TokenDigest(tokenValue, tokenType))
+	if err != nil {
+		return fmt.Errorf("failed to check token revocation: %w", err)
+	}
+
+	if revoked {
+		m.log(ctx).Warn("revoked token presented",
+			"token_type", tokenType)
+		return ErrTokenRevoked
+	}
+
+	return nil
+}
@@ -0,0 +1,131 @@
+package token
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MinSigningKeyLength is the minimum accepted HMAC key length in bytes.
+const MinSigningKeyLength = 32
+
+// signedTokenVersion identifies the layout of signed token payloads.
+const signedTokenVersion byte = 1
+
+// signedNonceLength is the number of random bytes included in each signed
+// token so that tokens issued in the same second differ.
+const signedNonceLength = 8
+
+// Errors returned by signed token operations.
+var (
+	ErrInvalidSignature     = errors.New("invalid token signature")
+	ErrSigningKeyTooShort   = fmt.Errorf("signing key must be at least %d bytes", MinSigningKeyLength)
+	ErrMalformedSignedToken = errors.New("malformed signed token")
+)
+
+// Signer issues self-contained tokens that can be verified without a storage
+// lookup. The validation ID, type, and expiry are carried in the token value
+// itself.
+type Signer interface {
+	// Sign encodes the token's type, validation ID, and validity window into
+	// a signed token value.
+	Sign(t *Token) (string, error)
+
+	// Verify checks the signature of value and returns the token it encodes.
+	// Verify does not check expiry; callers compare ValidUntil themselves.
+	Verify(value string) (*Token, error)
+}
+
+// SignedGenerator produces stateless link tokens authenticated with
+// HMAC-SHA256. A token has the form payload.signature, both base64url
+// encoded, where the payload holds the token type, creation and expiry
+// times, a random nonce, and the validation ID.
+type SignedGenerator struct {
+	key []byte
+}
+
+// NewSignedGenerator creates a SignedGenerator using key for HMAC-SHA256.
+// The key must be at least MinSigningKeyLength bytes.
+func NewSignedGenerator(key []byte) (*SignedGenerator, error) {
+	if len(key) < MinSigningKeyLength {
+		return nil, ErrSigningKeyTooShort
+	}
+
+	return &SignedGenerator{key: bytes.Clone(key)}, nil
+}
+
+// Sign encodes t into a signed token value.
+func (g *SignedGenerator) Sign(t *Token) (string, error) {
+	if t == nil {
+		return "", ErrTokenNil
+	}
+
+	if t.ValidationID == "" {
+		return "", ErrEmptyValidationID
+	}
+
+	nonce := make([]byte, signedNonceLength)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate random bytes: %w", err)
+	}
+
+	payload := make([]byte, 0, 2+16+signedNonceLength+len(t.ValidationID))
+	payload = append(payload, signedTokenVersion, byte(t.Type))
+	payload = binary.BigEndian.AppendUint64(payload, uint64(t.CreatedAt.Unix()))
+	payload = binary.BigEndian.AppendUint64(payload, uint64(t.ValidUntil.Unix()))
+	payload = append(payload, nonce...)
+	payload = append(payload, t.ValidationID...)
+
+	return base64.RawURLEncoding.EncodeToString(payload) + "." +
+		base64.RawURLEncoding.EncodeToString(g.mac(payload)), nil
+}
+
+// Verify checks the signature of value and decodes the token it carries.
+func (g *SignedGenerator) Verify(value string) (*Token, error) {
+	encodedPayload, encodedSig, ok := strings.Cut(value, ".")
+	if !ok {
+		return nil, ErrMalformedSignedToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformedSignedToken, err)
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(encodedSig)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformedSignedToken, err)
+	}
+
+	if !hmac.Equal(sig, g.mac(payload)) {
+		return nil, ErrInvalidSignature
+	}
+
+	const headerLength = 2 + 16 + signedNonceLength
+	if len(payload) <= headerLength || payload[0] != signedTokenVersion {
+		return nil, ErrMalformedSignedToken
+	}
+
+	return &Token{
+		Value:        value,
+		Type:         Type(payload[1]),
+		CreatedAt:    time.Unix(int64(binary.BigEndian.Uint64(payload[2:10])), 0),
+		ValidUntil:   time.Unix(int64(binary.BigEndian.Uint64(payload[10:18])), 0),
+		ValidationID: string(payload[headerLength:]),
+	}, nil
+}
+
+// mac computes the HMAC-SHA256 of payload.
+func (g *SignedGenerator) mac(payload []byte) []byte {
+	h := hmac.New(sha256.New, g.key)
+	h.Write(payload)
+
+	return h.Sum(nil)
+}
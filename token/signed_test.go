@@ -0,0 +1,105 @@
+package token
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testSigningKey = bytes.Repeat([]byte("k"), MinSigningKeyLength)
+
+func TestNewSignedGenerator_KeyLength(t *testing.T) {
+	t.Parallel()
+
+	if _, err := NewSignedGenerator([]byte("short")); !errors.Is(err, ErrSigningKeyTooShort) {
+		t.Errorf("NewSignedGenerator() with short key error = %v, want %v", err, ErrSigningKeyTooShort)
+	}
+
+	if _, err := NewSignedGenerator(testSigningKey); err != nil {
+		t.Errorf("NewSignedGenerator() error = %v", err)
+	}
+}
+
+func TestSignedGenerator_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	g, err := NewSignedGenerator(testSigningKey)
+	if err != nil {
+		t.Fatalf("NewSignedGenerator() error = %v", err)
+	}
+
+	original := New("", TypeLink, "validation-123", time.Hour)
+
+	value, err := g.Sign(original)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+
+	if strings.ContainsAny(value, "+/=") {
+		t.Errorf("signed token %q is not URL-safe", value)
+	}
+
+	got, err := g.Verify(value)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+
+	if got.Value != value {
+		t.Errorf("Verify() value = %q, want %q", got.Value, value)
+	}
+	if got.Type != TypeLink {
+		t.Errorf("Verify() type = %v, want %v", got.Type, TypeLink)
+	}
+	if got.ValidationID != original.ValidationID {
+		t.Errorf("Verify() validation ID = %q, want %q", got.ValidationID, original.ValidationID)
+	}
+	if got.ValidUntil.Unix() != original.ValidUntil.Unix() {
+		t.Errorf("Verify() valid until = %v, want %v", got.ValidUntil, original.ValidUntil)
+	}
+
+	// Signing the same token twice yields different values
+	again, _ := g.Sign(original)
+	if again == value {
+		t.Error("Sign() produced identical values for two tokens")
+	}
+}
+
+func TestSignedGenerator_RejectsTampering(t *testing.T) {
+	t.Parallel()
+
+	g, _ := NewSignedGenerator(testSigningKey)
+	other, _ := NewSignedGenerator(bytes.Repeat([]byte("x"), MinSigningKeyLength))
+
+	value, err := g.Sign(New("", TypeLink, "validation-123", time.Hour))
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+
+	payload, sig, _ := strings.Cut(value, ".")
+	forged, _ := g.Sign(New("", TypeLink, "validation-999", time.Hour))
+	forgedPayload, _, _ := strings.Cut(forged, ".")
+
+	tests := []struct {
+		name    string
+		g       *SignedGenerator
+		value   string
+		wantErr error
+	}{
+		{"wrong key", other, value, ErrInvalidSignature},
+		{"swapped payload", g, forgedPayload + "." + sig, ErrInvalidSignature},
+		{"missing signature", g, payload, ErrMalformedSignedToken},
+		{"bad encoding", g, "!!!." + sig, ErrMalformedSignedToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := tt.g.Verify(tt.value); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
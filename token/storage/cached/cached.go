@@ -0,0 +1,371 @@
+// Package cached provides a token storage decorator that serves repeated
+// Retrieve calls from a short-lived in-process cache, so that verification
+// links opened several times, for example by mail scanners, do not each
+// cost a backend roundtrip.
+package cached
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+)
+
+// DefaultTTL is the default time a retrieved token is served from cache.
+const DefaultTTL = 5 * time.Second
+
+// DefaultMaxEntries is the default maximum number of cached tokens.
+const DefaultMaxEntries = 10000
+
+// Storage caches Retrieve results of a wrapped token.Storage. Mutations
+// made through it invalidate the affected entries. Mutations made by other
+// processes sharing the backend are only observed once entries expire, so
+// the TTL bounds how long a token deleted elsewhere may still be retrieved.
+// Consume always reaches the backend, so a token is never used twice.
+type Storage struct {
+	storage    token.Storage
+	logger     *slog.Logger
+	clock      token.Clock
+	ttl        time.Duration
+	maxEntries int
+
+	mu           sync.Mutex
+	entries      map[tokenKey]entry
+	validationID map[string]map[tokenKey]struct{}
+	hits         uint64
+	misses       uint64
+
+	// generation is bumped on every invalidation, so that a Retrieve racing
+	// with a mutation does not cache the token the mutation replaced.
+	generation uint64
+}
+
+// tokenKey is a composite key for token lookup.
+type tokenKey struct {
+	value string
+	typ   token.Type
+}
+
+// entry is a cached token.
+type entry struct {
+	token     *token.Token
+	expiresAt time.Time
+}
+
+// Stats reports cache effectiveness.
+type Stats struct {
+	Hits    uint64
+	Misses  uint64
+	Entries int
+}
+
+// Option is a functional option for configuring Storage.
+type Option func(*Storage)
+
+// WithLogger sets a custom logger for Storage.
+func WithLogger(logger *slog.Logger) Option {
+	return func(s *Storage) {
+		s.logger = logger
+	}
+}
+
+// WithClock sets the clock used for cache and token expiry.
+func WithClock(clock token.Clock) Option {
+	return func(s *Storage) {
+		s.clock = clock
+	}
+}
+
+// WithTTL sets how long a retrieved token is served from cache.
+func WithTTL(ttl time.Duration) Option {
+	return func(s *Storage) {
+		if ttl > 0 {
+			s.ttl = ttl
+		}
+	}
+}
+
+// WithMaxEntries sets the maximum number of cached tokens.
+func WithMaxEntries(n int) Option {
+	return func(s *Storage) {
+		if n > 0 {
+			s.maxEntries = n
+		}
+	}
+}
+
+// New returns s with an in-process cache in front of Retrieve.
+func New(s token.Storage, opts ...Option) *Storage {
+	c := &Storage{
+		storage:      s,
+		logger:       slog.Default(),
+		clock:        token.SystemClock,
+		ttl:          DefaultTTL,
+		maxEntries:   DefaultMaxEntries,
+		entries:      make(map[tokenKey]entry),
+		validationID: make(map[string]map[tokenKey]struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Store saves a token to the backend and drops any cached copy.
+func (s *Storage) Store(ctx context.Context, t *token.Token) error {
+	if err := s.storage.Store(ctx, t); err != nil {
+		return fmt.Errorf("backend store failed: %w", err)
+	}
+
+	s.invalidate(tokenKey{value: t.Value, typ: t.Type})
+
+	return nil
+}
+
+// StoreBatch saves tokens with the backend's StoreBatch when it has one,
+// and with token.StoreEach otherwise.
+func (s *Storage) StoreBatch(ctx context.Context, tokens []*token.Token) error {
+	batch, ok := s.storage.(token.BatchStorage)
+	if !ok {
+		return token.StoreEach(ctx, tokens, s.Store)
+	}
+
+	if err := batch.StoreBatch(ctx, tokens); err != nil {
+		return fmt.Errorf("backend store batch failed: %w", err)
+	}
+
+	for _, t := range tokens {
+		s.invalidate(tokenKey{value: t.Value, typ: t.Type})
+	}
+
+	return nil
+}
+
+// Retrieve returns a cached token when one is fresh, and reads the backend
+// otherwise. Only successful lookups are cached, and a cached token is
+// never served past its own expiry.
+func (s *Storage) Retrieve(ctx context.Context, tokenValue string, tokenType token.Type) (*token.Token, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("context error: %w", err)
+	}
+
+	key := tokenKey{value: tokenValue, typ: tokenType}
+	now := s.clock.Now()
+
+	s.mu.Lock()
+	if e, ok := s.entries[key]; ok {
+		if now.Before(e.expiresAt) && !e.token.IsExpiredAt(now) {
+			s.hits++
+			s.mu.Unlock()
+			return clone(e.token), nil
+		}
+		s.removeLocked(key)
+	}
+	s.misses++
+	generation := s.generation
+	s.mu.Unlock()
+
+	t, err := s.storage.Retrieve(ctx, tokenValue, tokenType)
+	if err != nil {
+		return nil, fmt.Errorf("backend retrieve failed: %w", err)
+	}
+
+	s.add(key, t, now, generation)
+
+	return t, nil
+}
+
+// Delete removes a token from the backend and the cache.
+func (s *Storage) Delete(ctx context.Context, tokenValue string, tokenType token.Type) error {
+	defer s.invalidate(tokenKey{value: tokenValue, typ: tokenType})
+
+	if err := s.storage.Delete(ctx, tokenValue, tokenType); err != nil {
+		return fmt.Errorf("backend delete failed: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteBatch removes tokens from the backend, with its DeleteBatch when it
+// has one and token.DeleteEach otherwise, and drops any cached copies.
+func (s *Storage) DeleteBatch(ctx context.Context, refs []token.TokenRef) error {
+	defer func() {
+		for _, ref := range refs {
+			s.invalidate(tokenKey{value: ref.Value, typ: ref.Type})
+		}
+	}()
+
+	batch, ok := s.storage.(token.BatchStorage)
+	if !ok {
+		if err := token.DeleteEach(ctx, refs, s.storage.Delete); err != nil {
+			return fmt.Errorf("backend delete failed: %w", err)
+		}
+		return nil
+	}
+
+	if err := batch.DeleteBatch(ctx, refs); err != nil {
+		return fmt.Errorf("backend delete batch failed: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteByValidationID removes a validation's tokens from the backend and
+// the cache.
+func (s *Storage) DeleteByValidationID(ctx context.Context, validationID string) error {
+	defer s.invalidateValidation(validationID)
+
+	if err := s.storage.DeleteByValidationID(ctx, validationID); err != nil {
+		return fmt.Errorf("backend delete by validation ID failed: %w", err)
+	}
+
+	return nil
+}
+
+// Consume takes the token from the backend and drops any cached copy.
+func (s *Storage) Consume(ctx context.Context, tokenValue string, tokenType token.Type) (*token.Token, error) {
+	defer s.invalidate(tokenKey{value: tokenValue, typ: tokenType})
+
+	t, err := s.storage.Consume(ctx, tokenValue, tokenType)
+	if err != nil {
+		return nil, fmt.Errorf("backend consume failed: %w", err)
+	}
+
+	return t, nil
+}
+
+// IncrementAttempts counts an attempt in the backend.
+func (s *Storage) IncrementAttempts(ctx context.Context, validationID string, ttl time.Duration) (int, error) {
+	n, err := s.storage.IncrementAttempts(ctx, validationID, ttl)
+	if err != nil {
+		return 0, fmt.Errorf("backend increment attempts failed: %w", err)
+	}
+
+	return n, nil
+}
+
+// ExtendTTL extends the token in the backend and drops any cached copy.
+func (s *Storage) ExtendTTL(ctx context.Context, tokenValue string, tokenType token.Type, extra time.Duration) (*token.Token, error) {
+	defer s.invalidate(tokenKey{value: tokenValue, typ: tokenType})
+
+	t, err := s.storage.ExtendTTL(ctx, tokenValue, tokenType, extra)
+	if err != nil {
+		return nil, fmt.Errorf("backend extend TTL failed: %w", err)
+	}
+
+	return t, nil
+}
+
+// ListByValidationID lists a validation's tokens from the backend.
+func (s *Storage) ListByValidationID(ctx context.Context, validationID string) ([]*token.Token, error) {
+	tokens, err := s.storage.ListByValidationID(ctx, validationID)
+	if err != nil {
+		return nil, fmt.Errorf("backend list failed: %w", err)
+	}
+
+	return tokens, nil
+}
+
+// Stats returns the cache's hit and miss counts and current size.
+func (s *Storage) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return Stats{Hits: s.hits, Misses: s.misses, Entries: len(s.entries)}
+}
+
+// add caches a copy of t, evicting entries when the cache is full. Nothing
+// is cached if an invalidation happened since generation was read.
+func (s *Storage) add(key tokenKey, t *token.Token, now time.Time, generation uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if generation != s.generation {
+		return
+	}
+
+	if len(s.entries) >= s.maxEntries {
+		s.evictLocked(now)
+	}
+
+	s.entries[key] = entry{token: clone(t), expiresAt: now.Add(s.ttl)}
+
+	keys, ok := s.validationID[t.ValidationID]
+	if !ok {
+		keys = make(map[tokenKey]struct{})
+		s.validationID[t.ValidationID] = keys
+	}
+	keys[key] = struct{}{}
+}
+
+// evictLocked removes expired entries, and arbitrary ones if that does not
+// free enough room.
+func (s *Storage) evictLocked(now time.Time) {
+	for key, e := range s.entries {
+		if !now.Before(e.expiresAt) {
+			s.removeLocked(key)
+		}
+	}
+
+	for key := range s.entries {
+		if len(s.entries) < s.maxEntries {
+			break
+		}
+		s.removeLocked(key)
+	}
+
+	s.logger.Debug("token cache evicted entries", "entries", len(s.entries))
+}
+
+// invalidate drops a cached token.
+func (s *Storage) invalidate(key tokenKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.generation++
+	s.removeLocked(key)
+}
+
+// invalidateValidation drops all cached tokens of a validation.
+func (s *Storage) invalidateValidation(validationID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.generation++
+	for key := range s.validationID[validationID] {
+		delete(s.entries, key)
+	}
+	delete(s.validationID, validationID)
+}
+
+// removeLocked drops a cached token and its index entry.
+func (s *Storage) removeLocked(key tokenKey) {
+	e, ok := s.entries[key]
+	if !ok {
+		return
+	}
+
+	delete(s.entries, key)
+
+	if keys, ok := s.validationID[e.token.ValidationID]; ok {
+		delete(keys, key)
+		if len(keys) == 0 {
+			delete(s.validationID, e.token.ValidationID)
+		}
+	}
+}
+
+// clone returns a copy of t that callers may modify without affecting the
+// cache.
+func clone(t *token.Token) *token.Token {
+	c := *t
+	c.Metadata = maps.Clone(t.Metadata)
+
+	return &c
+}
@@ -0,0 +1,248 @@
+package cached
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+	"github.com/jaeyeom/email-validator-grpc-mcp/token/storage/memory"
+)
+
+// countingStorage counts Retrieve calls that reach the backend.
+type countingStorage struct {
+	token.Storage
+	retrieves atomic.Int32
+}
+
+func (c *countingStorage) Retrieve(ctx context.Context, tokenValue string, tokenType token.Type) (*token.Token, error) {
+	c.retrieves.Add(1)
+	return c.Storage.Retrieve(ctx, tokenValue, tokenType)
+}
+
+// fakeClock is a settable clock.
+type fakeClock struct {
+	now atomic.Int64
+}
+
+func (c *fakeClock) Now() time.Time {
+	return time.Unix(0, c.now.Load())
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now.Add(int64(d))
+}
+
+func newClock() *fakeClock {
+	c := &fakeClock{}
+	c.now.Store(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC).UnixNano())
+
+	return c
+}
+
+func newToken(clock token.Clock, value, validationID string) *token.Token {
+	return token.NewAt(value, token.TypeLink, validationID, time.Hour, clock.Now())
+}
+
+func setup(t *testing.T, opts ...Option) (*Storage, *countingStorage, *fakeClock) {
+	t.Helper()
+
+	clock := newClock()
+	backend := &countingStorage{Storage: memory.New(memory.WithClock(clock))}
+
+	return New(backend, append([]Option{WithClock(clock)}, opts...)...), backend, clock
+}
+
+func TestStorage_Retrieve(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	s, backend, clock := setup(t, WithTTL(10*time.Second))
+
+	if err := s.Store(ctx, newToken(clock, "abc", "validation-1")); err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+
+	for range 3 {
+		if _, err := s.Retrieve(ctx, "abc", token.TypeLink); err != nil {
+			t.Fatalf("Retrieve() error = %v", err)
+		}
+	}
+	if got := backend.retrieves.Load(); got != 1 {
+		t.Errorf("backend retrieves = %d, want 1", got)
+	}
+	if stats := s.Stats(); stats.Hits != 2 || stats.Misses != 1 || stats.Entries != 1 {
+		t.Errorf("Stats() = %+v, want 2 hits, 1 miss, 1 entry", stats)
+	}
+
+	// Entries are refreshed once the cache TTL passes
+	clock.Advance(11 * time.Second)
+	if _, err := s.Retrieve(ctx, "abc", token.TypeLink); err != nil {
+		t.Fatalf("Retrieve() error = %v", err)
+	}
+	if got := backend.retrieves.Load(); got != 2 {
+		t.Errorf("backend retrieves after TTL = %d, want 2", got)
+	}
+
+	// Misses are not cached
+	for range 2 {
+		if _, err := s.Retrieve(ctx, "missing", token.TypeLink); !errors.Is(err, token.ErrTokenNotFound) {
+			t.Errorf("Retrieve() error = %v, want %v", err, token.ErrTokenNotFound)
+		}
+	}
+	if got := backend.retrieves.Load(); got != 4 {
+		t.Errorf("backend retrieves after misses = %d, want 4", got)
+	}
+}
+
+func TestStorage_Invalidation(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		mutate func(ctx context.Context, s *Storage) error
+	}{
+		{
+			name: "Delete",
+			mutate: func(ctx context.Context, s *Storage) error {
+				return s.Delete(ctx, "abc", token.TypeLink)
+			},
+		},
+		{
+			name: "DeleteByValidationID",
+			mutate: func(ctx context.Context, s *Storage) error {
+				return s.DeleteByValidationID(ctx, "validation-1")
+			},
+		},
+		{
+			name: "Consume",
+			mutate: func(ctx context.Context, s *Storage) error {
+				_, err := s.Consume(ctx, "abc", token.TypeLink)
+				return err
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			s, _, clock := setup(t)
+
+			if err := s.Store(ctx, newToken(clock, "abc", "validation-1")); err != nil {
+				t.Fatalf("Store() error = %v", err)
+			}
+			if _, err := s.Retrieve(ctx, "abc", token.TypeLink); err != nil {
+				t.Fatalf("Retrieve() error = %v", err)
+			}
+
+			if err := tt.mutate(ctx, s); err != nil {
+				t.Fatalf("%s() error = %v", tt.name, err)
+			}
+
+			if _, err := s.Retrieve(ctx, "abc", token.TypeLink); !errors.Is(err, token.ErrTokenNotFound) {
+				t.Errorf("Retrieve() after %s error = %v, want %v", tt.name, err, token.ErrTokenNotFound)
+			}
+		})
+	}
+}
+
+func TestStorage_ExtendTTL(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	s, _, clock := setup(t)
+
+	original := newToken(clock, "abc", "validation-1")
+	if err := s.Store(ctx, original); err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+	if _, err := s.Retrieve(ctx, "abc", token.TypeLink); err != nil {
+		t.Fatalf("Retrieve() error = %v", err)
+	}
+
+	if _, err := s.ExtendTTL(ctx, "abc", token.TypeLink, time.Hour); err != nil {
+		t.Fatalf("ExtendTTL() error = %v", err)
+	}
+
+	got, err := s.Retrieve(ctx, "abc", token.TypeLink)
+	if err != nil {
+		t.Fatalf("Retrieve() error = %v", err)
+	}
+	if want := original.ValidUntil.Add(time.Hour); !got.ValidUntil.Equal(want) {
+		t.Errorf("Retrieve().ValidUntil = %v, want %v", got.ValidUntil, want)
+	}
+}
+
+func TestStorage_NeverServesExpiredTokens(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	s, _, clock := setup(t, WithTTL(time.Hour))
+
+	short := token.NewAt("abc", token.TypeLink, "validation-1", time.Second, clock.Now())
+	if err := s.Store(ctx, short); err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+	if _, err := s.Retrieve(ctx, "abc", token.TypeLink); err != nil {
+		t.Fatalf("Retrieve() error = %v", err)
+	}
+
+	clock.Advance(2 * time.Second)
+	if _, err := s.Retrieve(ctx, "abc", token.TypeLink); !token.IsTokenExpiredError(err) {
+		t.Errorf("Retrieve() error = %v, want TokenExpiredError", err)
+	}
+}
+
+func TestStorage_MaxEntries(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	s, _, clock := setup(t, WithMaxEntries(2))
+
+	for _, value := range []string{"a", "b", "c"} {
+		if err := s.Store(ctx, newToken(clock, value, "validation-"+value)); err != nil {
+			t.Fatalf("Store() error = %v", err)
+		}
+		if _, err := s.Retrieve(ctx, value, token.TypeLink); err != nil {
+			t.Fatalf("Retrieve() error = %v", err)
+		}
+	}
+
+	if got := s.Stats().Entries; got != 2 {
+		t.Errorf("Stats().Entries = %d, want 2", got)
+	}
+}
+
+func TestStorage_ReturnsCopies(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	s, _, clock := setup(t)
+
+	tkn := newToken(clock, "abc", "validation-1")
+	tkn.Metadata = map[string]string{"locale": "en"}
+	if err := s.Store(ctx, tkn); err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+	if _, err := s.Retrieve(ctx, "abc", token.TypeLink); err != nil {
+		t.Fatalf("Retrieve() error = %v", err)
+	}
+
+	got, err := s.Retrieve(ctx, "abc", token.TypeLink)
+	if err != nil {
+		t.Fatalf("Retrieve() error = %v", err)
+	}
+	got.Metadata["locale"] = "fr"
+
+	again, err := s.Retrieve(ctx, "abc", token.TypeLink)
+	if err != nil {
+		t.Fatalf("Retrieve() error = %v", err)
+	}
+	if again.Metadata["locale"] != "en" {
+		t.Errorf("cached Metadata[locale] = %q, want %q", again.Metadata["locale"], "en")
+	}
+}
@@ -0,0 +1,358 @@
+// Package composite provides a failover token storage that writes to a
+// primary and a secondary backend and keeps serving from the secondary
+// while the primary is unavailable, such as during a Redis maintenance
+// window.
+package composite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+	"github.com/jaeyeom/email-validator-grpc-mcp/token/storage/retry"
+)
+
+// DefaultProbeInterval is the default time the primary backend is bypassed
+// after it fails, before it is tried again.
+const DefaultProbeInterval = 5 * time.Second
+
+// Storage writes every mutation to both backends and reads from the
+// primary. When the primary fails with an availability error, calls are
+// served by the secondary and mutations the primary missed are kept for
+// Reconcile. Until they have all been replayed, the secondary remains the
+// source of truth, so the primary never sees mutations out of order.
+// Errors that describe the token, such as a token not being found, are
+// returned as-is and never cause failover.
+type Storage struct {
+	primary   token.Storage
+	secondary token.Storage
+	logger    *slog.Logger
+	clock     token.Clock
+
+	probeInterval time.Duration
+	isUnavailable func(error) bool
+
+	mu        sync.Mutex
+	downUntil time.Time
+	pending   []operation
+
+	// reconcileMu serializes Reconcile so each mutation is replayed once.
+	reconcileMu sync.Mutex
+}
+
+// operation is a mutation the primary backend missed.
+type operation struct {
+	name  string
+	apply func(ctx context.Context, s token.Storage) error
+}
+
+// Option is a functional option for configuring Storage.
+type Option func(*Storage)
+
+// WithLogger sets a custom logger for Storage.
+func WithLogger(logger *slog.Logger) Option {
+	return func(s *Storage) {
+		s.logger = logger
+	}
+}
+
+// WithClock sets the clock used to time primary outages.
+func WithClock(clock token.Clock) Option {
+	return func(s *Storage) {
+		s.clock = clock
+	}
+}
+
+// WithProbeInterval sets how long the primary is bypassed after a failure
+// before it is tried again.
+func WithProbeInterval(interval time.Duration) Option {
+	return func(s *Storage) {
+		if interval > 0 {
+			s.probeInterval = interval
+		}
+	}
+}
+
+// WithUnavailable sets the function that decides whether an error from the
+// primary means it is unavailable. It defaults to retry.IsTransient.
+func WithUnavailable(fn func(error) bool) Option {
+	return func(s *Storage) {
+		s.isUnavailable = fn
+	}
+}
+
+// New creates a failover storage over primary and secondary.
+func New(primary, secondary token.Storage, opts ...Option) *Storage {
+	s := &Storage{
+		primary:       primary,
+		secondary:     secondary,
+		logger:        slog.Default(),
+		clock:         token.SystemClock,
+		probeInterval: DefaultProbeInterval,
+		isUnavailable: retry.IsTransient,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Store saves the token to both backends.
+func (s *Storage) Store(ctx context.Context, t *token.Token) error {
+	if t == nil {
+		return token.ErrTokenNil
+	}
+
+	stored := *t
+
+	return s.write(ctx, "Store", func(ctx context.Context, b token.Storage) error {
+		return b.Store(ctx, &stored)
+	})
+}
+
+// Retrieve reads the token from the primary, or from the secondary while
+// the primary is unavailable or awaiting Reconcile.
+func (s *Storage) Retrieve(ctx context.Context, tokenValue string, tokenType token.Type) (*token.Token, error) {
+	return read(ctx, s, "Retrieve", func(ctx context.Context, b token.Storage) (*token.Token, error) {
+		return b.Retrieve(ctx, tokenValue, tokenType)
+	})
+}
+
+// Delete removes the token from both backends.
+func (s *Storage) Delete(ctx context.Context, tokenValue string, tokenType token.Type) error {
+	return s.write(ctx, "Delete", deleteToken(tokenValue, tokenType))
+}
+
+// DeleteByValidationID removes the validation's tokens from both backends.
+func (s *Storage) DeleteByValidationID(ctx context.Context, validationID string) error {
+	return s.write(ctx, "DeleteByValidationID", func(ctx context.Context, b token.Storage) error {
+		return b.DeleteByValidationID(ctx, validationID)
+	})
+}
+
+// Consume takes the token from the primary, or from the secondary while the
+// primary is unavailable, and removes it from the other backend so it
+// cannot be consumed again after a failover.
+func (s *Storage) Consume(ctx context.Context, tokenValue string, tokenType token.Type) (*token.Token, error) {
+	remove := deleteToken(tokenValue, tokenType)
+
+	if s.usePrimary() {
+		t, err := s.primary.Consume(ctx, tokenValue, tokenType)
+		if err == nil {
+			if err := remove(ctx, s.secondary); err != nil {
+				s.logger.Warn("failed to remove consumed token from secondary backend", "error", err)
+			}
+			return t, nil
+		}
+		if !s.failed(err) {
+			return nil, fmt.Errorf("primary consume failed: %w", err)
+		}
+	}
+
+	t, err := s.secondary.Consume(ctx, tokenValue, tokenType)
+	if err != nil {
+		return nil, fmt.Errorf("secondary consume failed: %w", err)
+	}
+
+	s.keep("Delete", remove)
+
+	return t, nil
+}
+
+// IncrementAttempts counts the attempt in the primary, or in the secondary
+// while the primary is unavailable. Counts are not merged across backends.
+func (s *Storage) IncrementAttempts(ctx context.Context, validationID string, ttl time.Duration) (int, error) {
+	return read(ctx, s, "IncrementAttempts", func(ctx context.Context, b token.Storage) (int, error) {
+		return b.IncrementAttempts(ctx, validationID, ttl)
+	})
+}
+
+// ExtendTTL extends the token in both backends and returns the result from
+// the primary, or from the secondary while the primary is unavailable.
+func (s *Storage) ExtendTTL(ctx context.Context, tokenValue string, tokenType token.Type, extra time.Duration) (*token.Token, error) {
+	var extended *token.Token
+
+	err := s.write(ctx, "ExtendTTL", func(ctx context.Context, b token.Storage) error {
+		t, err := b.ExtendTTL(ctx, tokenValue, tokenType, extra)
+		if err == nil && extended == nil {
+			extended = t
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return extended, nil
+}
+
+// ListByValidationID lists tokens from the primary, or from the secondary
+// while the primary is unavailable or awaiting Reconcile.
+func (s *Storage) ListByValidationID(ctx context.Context, validationID string) ([]*token.Token, error) {
+	return read(ctx, s, "ListByValidationID", func(ctx context.Context, b token.Storage) ([]*token.Token, error) {
+		return b.ListByValidationID(ctx, validationID)
+	})
+}
+
+// Pending returns the number of mutations the primary missed.
+func (s *Storage) Pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.pending)
+}
+
+// Reconcile replays mutations the primary missed, in their original order,
+// and returns how many are still pending. It stops at the first failure so
+// that later mutations are not applied before earlier ones. Mutations made
+// while it runs are queued behind the ones being replayed. It is meant to
+// be run periodically by a background job.
+func (s *Storage) Reconcile(ctx context.Context) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, fmt.Errorf("context error: %w", err)
+	}
+
+	s.reconcileMu.Lock()
+	defer s.reconcileMu.Unlock()
+
+	replayed := 0
+	for ctx.Err() == nil {
+		s.mu.Lock()
+		if len(s.pending) == 0 {
+			s.mu.Unlock()
+			break
+		}
+		op := s.pending[0]
+		s.mu.Unlock()
+
+		if err := op.apply(ctx, s.primary); err != nil {
+			s.logger.Warn("failover reconciliation failed",
+				"operation", op.name,
+				"error", err)
+			break
+		}
+
+		s.mu.Lock()
+		s.pending = s.pending[1:]
+		s.downUntil = time.Time{}
+		s.mu.Unlock()
+		replayed++
+	}
+
+	remaining := s.Pending()
+
+	s.logger.Info("failover reconciliation finished",
+		"replayed", replayed,
+		"remaining", remaining)
+
+	return remaining, nil
+}
+
+// write applies a mutation to the primary, unless it is bypassed, and then
+// to the secondary. A mutation the primary could not take is kept for
+// Reconcile; it fails only if the secondary also fails, or if the primary
+// rejects it outright.
+func (s *Storage) write(ctx context.Context, name string, apply func(context.Context, token.Storage) error) error {
+	primaryOK := false
+	if s.usePrimary() {
+		err := apply(ctx, s.primary)
+		switch {
+		case err == nil:
+			primaryOK = true
+		case !s.failed(err):
+			return fmt.Errorf("primary %s failed: %w", name, err)
+		}
+	}
+
+	if err := apply(ctx, s.secondary); err != nil {
+		if !primaryOK {
+			return fmt.Errorf("secondary %s failed: %w", name, err)
+		}
+		// The primary holds the mutation; a failover before it reaches the
+		// secondary would serve stale data, which is logged but tolerated.
+		s.logger.Warn("failed to apply mutation to secondary backend",
+			"operation", name,
+			"error", err)
+	}
+
+	if !primaryOK {
+		s.keep(name, apply)
+	}
+
+	return nil
+}
+
+// read calls fn on the primary and falls back to the secondary when the
+// primary is unavailable or missing mutations.
+func read[T any](ctx context.Context, s *Storage, name string, fn func(context.Context, token.Storage) (T, error)) (T, error) {
+	if s.usePrimary() {
+		v, err := fn(ctx, s.primary)
+		if err == nil {
+			return v, nil
+		}
+		if !s.failed(err) {
+			return v, fmt.Errorf("primary %s failed: %w", name, err)
+		}
+	}
+
+	v, err := fn(ctx, s.secondary)
+	if err != nil {
+		return v, fmt.Errorf("secondary %s failed: %w", name, err)
+	}
+
+	return v, nil
+}
+
+// usePrimary reports whether the primary should be tried: it is not being
+// bypassed after a failure and has no mutations waiting for Reconcile.
+func (s *Storage) usePrimary() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.pending) == 0 && !s.clock.Now().Before(s.downUntil)
+}
+
+// failed reports whether err means the primary is unavailable, and if so
+// bypasses the primary for the probe interval.
+func (s *Storage) failed(err error) bool {
+	if !s.isUnavailable(err) {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.clock.Now().Before(s.downUntil) {
+		s.logger.Warn("primary storage unavailable, failing over to secondary",
+			"error", err,
+			"probe_interval", s.probeInterval)
+	}
+	s.downUntil = s.clock.Now().Add(s.probeInterval)
+
+	return true
+}
+
+// keep records a mutation the primary missed for Reconcile.
+func (s *Storage) keep(name string, apply func(context.Context, token.Storage) error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pending = append(s.pending, operation{name: name, apply: apply})
+}
+
+// deleteToken returns a mutation removing a token, treating a token that is
+// already absent as removed.
+func deleteToken(tokenValue string, tokenType token.Type) func(context.Context, token.Storage) error {
+	return func(ctx context.Context, b token.Storage) error {
+		if err := b.Delete(ctx, tokenValue, tokenType); err != nil && !errors.Is(err, token.ErrTokenNotFound) {
+			return err
+		}
+		return nil
+	}
+}
@@ -0,0 +1,180 @@
+package composite
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+	"github.com/jaeyeom/email-validator-grpc-mcp/token/storage/memory"
+)
+
+var errUnavailable = errors.New("backend unavailable")
+
+// flakyStorage fails every call while down is set.
+type flakyStorage struct {
+	token.Storage
+	down  atomic.Bool
+	calls atomic.Int32
+}
+
+func (f *flakyStorage) check() error {
+	f.calls.Add(1)
+	if f.down.Load() {
+		return errUnavailable
+	}
+
+	return nil
+}
+
+func (f *flakyStorage) Store(ctx context.Context, t *token.Token) error {
+	if err := f.check(); err != nil {
+		return err
+	}
+
+	return f.Storage.Store(ctx, t)
+}
+
+func (f *flakyStorage) Retrieve(ctx context.Context, tokenValue string, tokenType token.Type) (*token.Token, error) {
+	if err := f.check(); err != nil {
+		return nil, err
+	}
+
+	return f.Storage.Retrieve(ctx, tokenValue, tokenType)
+}
+
+func (f *flakyStorage) Delete(ctx context.Context, tokenValue string, tokenType token.Type) error {
+	if err := f.check(); err != nil {
+		return err
+	}
+
+	return f.Storage.Delete(ctx, tokenValue, tokenType)
+}
+
+func (f *flakyStorage) Consume(ctx context.Context, tokenValue string, tokenType token.Type) (*token.Token, error) {
+	if err := f.check(); err != nil {
+		return nil, err
+	}
+
+	return f.Storage.Consume(ctx, tokenValue, tokenType)
+}
+
+func newToken(value string) *token.Token {
+	return &token.Token{
+		Value:        value,
+		Type:         token.TypeLink,
+		ValidUntil:   time.Now().Add(time.Hour),
+		ValidationID: "validation-" + value,
+	}
+}
+
+func TestStorage_WritesBoth(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	primary, secondary := memory.New(), memory.New()
+	s := New(primary, secondary)
+
+	if err := s.Store(ctx, newToken("abc")); err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+	for name, b := range map[string]token.Storage{"primary": primary, "secondary": secondary} {
+		if _, err := b.Retrieve(ctx, "abc", token.TypeLink); err != nil {
+			t.Errorf("%s Retrieve() error = %v", name, err)
+		}
+	}
+
+	if _, err := s.Consume(ctx, "abc", token.TypeLink); err != nil {
+		t.Fatalf("Consume() error = %v", err)
+	}
+	if _, err := secondary.Retrieve(ctx, "abc", token.TypeLink); !errors.Is(err, token.ErrTokenNotFound) {
+		t.Errorf("secondary Retrieve() of consumed token error = %v, want %v", err, token.ErrTokenNotFound)
+	}
+
+	// Errors about the token itself do not cause failover
+	if _, err := s.Retrieve(ctx, "missing", token.TypeLink); !errors.Is(err, token.ErrTokenNotFound) {
+		t.Errorf("Retrieve() error = %v, want %v", err, token.ErrTokenNotFound)
+	}
+	if got := s.Pending(); got != 0 {
+		t.Errorf("Pending() = %d, want 0", got)
+	}
+}
+
+func TestStorage_Failover(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	primary := &flakyStorage{Storage: memory.New()}
+	secondary := memory.New()
+	s := New(primary, secondary, WithProbeInterval(time.Hour))
+
+	if err := s.Store(ctx, newToken("before")); err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+
+	primary.down.Store(true)
+
+	// Reads and writes keep working against the secondary
+	if _, err := s.Retrieve(ctx, "before", token.TypeLink); err != nil {
+		t.Fatalf("Retrieve() during outage error = %v", err)
+	}
+	if err := s.Store(ctx, newToken("during")); err != nil {
+		t.Fatalf("Store() during outage error = %v", err)
+	}
+	if _, err := s.Consume(ctx, "before", token.TypeLink); err != nil {
+		t.Fatalf("Consume() during outage error = %v", err)
+	}
+	if got := s.Pending(); got != 2 {
+		t.Errorf("Pending() = %d, want 2", got)
+	}
+
+	// The primary is bypassed rather than retried on every call
+	calls := primary.calls.Load()
+	if _, err := s.Retrieve(ctx, "during", token.TypeLink); err != nil {
+		t.Fatalf("Retrieve() during outage error = %v", err)
+	}
+	if got := primary.calls.Load(); got != calls {
+		t.Errorf("primary calls = %d, want %d while bypassed", got, calls)
+	}
+
+	if remaining, err := s.Reconcile(ctx); err != nil || remaining != 2 {
+		t.Errorf("Reconcile() while down = %d, %v, want 2, nil", remaining, err)
+	}
+
+	primary.down.Store(false)
+	if remaining, err := s.Reconcile(ctx); err != nil || remaining != 0 {
+		t.Fatalf("Reconcile() = %d, %v, want 0, nil", remaining, err)
+	}
+
+	if _, err := primary.Retrieve(ctx, "during", token.TypeLink); err != nil {
+		t.Errorf("primary Retrieve() of token stored during outage error = %v", err)
+	}
+	if _, err := primary.Retrieve(ctx, "before", token.TypeLink); !errors.Is(err, token.ErrTokenNotFound) {
+		t.Errorf("primary Retrieve() of token consumed during outage error = %v, want %v", err, token.ErrTokenNotFound)
+	}
+
+	// Single use holds across the failover
+	if _, err := s.Consume(ctx, "before", token.TypeLink); !errors.Is(err, token.ErrTokenNotFound) {
+		t.Errorf("Consume() of consumed token error = %v, want %v", err, token.ErrTokenNotFound)
+	}
+}
+
+func TestStorage_BothDown(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	primary := &flakyStorage{Storage: memory.New()}
+	secondary := &flakyStorage{Storage: memory.New()}
+	primary.down.Store(true)
+	secondary.down.Store(true)
+	s := New(primary, secondary)
+
+	if err := s.Store(ctx, newToken("abc")); !errors.Is(err, errUnavailable) {
+		t.Errorf("Store() error = %v, want %v", err, errUnavailable)
+	}
+	if got := s.Pending(); got != 0 {
+		t.Errorf("Pending() = %d, want 0 for a failed write", got)
+	}
+}
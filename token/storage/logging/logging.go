@@ -0,0 +1,194 @@
+// Package logging provides a token storage decorator that logs every
+// storage operation. Token values are redacted to their first and last four
+// characters, so logs can be correlated with a reported token without
+// exposing it.
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/redact"
+	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+)
+
+// Storage wraps a token.Storage and logs its operations.
+type Storage struct {
+	storage token.Storage
+	logger  *slog.Logger
+	level   slog.Level
+	clock   token.Clock
+}
+
+// Option is a functional option for configuring Storage.
+type Option func(*Storage)
+
+// WithLogger sets a custom logger for Storage.
+func WithLogger(logger *slog.Logger) Option {
+	return func(s *Storage) {
+		s.logger = logger
+	}
+}
+
+// WithLevel sets the level operations are logged at, slog.LevelDebug by
+// default. Failures other than missing or expired tokens are always logged
+// at slog.LevelWarn or above.
+func WithLevel(level slog.Level) Option {
+	return func(s *Storage) {
+		s.level = level
+	}
+}
+
+// WithClock sets the clock used to time operations.
+func WithClock(clock token.Clock) Option {
+	return func(s *Storage) {
+		s.clock = clock
+	}
+}
+
+// Wrap returns s decorated to log every operation.
+func Wrap(s token.Storage, opts ...Option) *Storage {
+	l := &Storage{
+		storage: s,
+		logger:  slog.Default(),
+		level:   slog.LevelDebug,
+		clock:   token.SystemClock,
+	}
+
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	return l
+}
+
+// Store saves a token.
+func (s *Storage) Store(ctx context.Context, t *token.Token) error {
+	var attrs []slog.Attr
+	if t != nil {
+		attrs = tokenAttrs(t.Value, t.Type)
+		attrs = append(attrs, slog.String("validation_id", t.ValidationID), slog.Time("valid_until", t.ValidUntil))
+	}
+
+	_, err := logged(ctx, s, "Store", attrs, func() (struct{}, error) {
+		return struct{}{}, s.storage.Store(ctx, t)
+	})
+
+	return err
+}
+
+// StoreBatch saves tokens with the wrapped storage's StoreBatch when it has
+// one, and with token.StoreEach otherwise.
+func (s *Storage) StoreBatch(ctx context.Context, tokens []*token.Token) error {
+	batch, ok := s.storage.(token.BatchStorage)
+	if !ok {
+		return token.StoreEach(ctx, tokens, s.Store)
+	}
+
+	_, err := logged(ctx, s, "StoreBatch", []slog.Attr{slog.Int("count", len(tokens))}, func() (struct{}, error) {
+		return struct{}{}, batch.StoreBatch(ctx, tokens)
+	})
+
+	return err
+}
+
+// Retrieve gets a token.
+func (s *Storage) Retrieve(ctx context.Context, tokenValue string, tokenType token.Type) (*token.Token, error) {
+	return logged(ctx, s, "Retrieve", tokenAttrs(tokenValue, tokenType), func() (*token.Token, error) {
+		return s.storage.Retrieve(ctx, tokenValue, tokenType)
+	})
+}
+
+// Delete removes a token.
+func (s *Storage) Delete(ctx context.Context, tokenValue string, tokenType token.Type) error {
+	_, err := logged(ctx, s, "Delete", tokenAttrs(tokenValue, tokenType), func() (struct{}, error) {
+		return struct{}{}, s.storage.Delete(ctx, tokenValue, tokenType)
+	})
+
+	return err
+}
+
+// DeleteBatch removes tokens with the wrapped storage's DeleteBatch when it
+// has one, and with token.DeleteEach otherwise.
+func (s *Storage) DeleteBatch(ctx context.Context, refs []token.TokenRef) error {
+	batch, ok := s.storage.(token.BatchStorage)
+	if !ok {
+		return token.DeleteEach(ctx, refs, s.Delete)
+	}
+
+	_, err := logged(ctx, s, "DeleteBatch", []slog.Attr{slog.Int("count", len(refs))}, func() (struct{}, error) {
+		return struct{}{}, batch.DeleteBatch(ctx, refs)
+	})
+
+	return err
+}
+
+// DeleteByValidationID removes a validation's tokens.
+func (s *Storage) DeleteByValidationID(ctx context.Context, validationID string) error {
+	_, err := logged(ctx, s, "DeleteByValidationID", []slog.Attr{slog.String("validation_id", validationID)}, func() (struct{}, error) {
+		return struct{}{}, s.storage.DeleteByValidationID(ctx, validationID)
+	})
+
+	return err
+}
+
+// Consume atomically takes a token.
+func (s *Storage) Consume(ctx context.Context, tokenValue string, tokenType token.Type) (*token.Token, error) {
+	return logged(ctx, s, "Consume", tokenAttrs(tokenValue, tokenType), func() (*token.Token, error) {
+		return s.storage.Consume(ctx, tokenValue, tokenType)
+	})
+}
+
+// IncrementAttempts counts a failed verification attempt.
+func (s *Storage) IncrementAttempts(ctx context.Context, validationID string, ttl time.Duration) (int, error) {
+	return logged(ctx, s, "IncrementAttempts", []slog.Attr{slog.String("validation_id", validationID)}, func() (int, error) {
+		return s.storage.IncrementAttempts(ctx, validationID, ttl)
+	})
+}
+
+// ExtendTTL extends a token's validity.
+func (s *Storage) ExtendTTL(ctx context.Context, tokenValue string, tokenType token.Type, extra time.Duration) (*token.Token, error) {
+	attrs := append(tokenAttrs(tokenValue, tokenType), slog.Duration("extra", extra))
+
+	return logged(ctx, s, "ExtendTTL", attrs, func() (*token.Token, error) {
+		return s.storage.ExtendTTL(ctx, tokenValue, tokenType, extra)
+	})
+}
+
+// ListByValidationID lists a validation's tokens.
+func (s *Storage) ListByValidationID(ctx context.Context, validationID string) ([]*token.Token, error) {
+	return logged(ctx, s, "ListByValidationID", []slog.Attr{slog.String("validation_id", validationID)}, func() ([]*token.Token, error) {
+		return s.storage.ListByValidationID(ctx, validationID)
+	})
+}
+
+// logged calls fn and logs its outcome. Missing and expired tokens are
+// expected outcomes and logged at the configured level; other failures at
+// slog.LevelWarn or above.
+func logged[T any](ctx context.Context, s *Storage, op string, attrs []slog.Attr, fn func() (T, error)) (T, error) {
+	start := s.clock.Now()
+	v, err := fn()
+
+	level := s.level
+	attrs = append(attrs, slog.String("operation", op), slog.Duration("duration", s.clock.Now().Sub(start)))
+	if err != nil {
+		attrs = append(attrs, slog.Any("error", err))
+		if token.ReasonOf(err) == token.ReasonError {
+			level = max(level, slog.LevelWarn)
+		}
+	}
+
+	s.logger.LogAttrs(ctx, level, "token storage operation", attrs...)
+
+	return v, err
+}
+
+// tokenAttrs returns the log attributes identifying a token, with its value
+// redacted.
+func tokenAttrs(tokenValue string, tokenType token.Type) []slog.Attr {
+	return []slog.Attr{
+		slog.String("token_value", redact.Token(tokenValue)),
+		slog.Int("token_type", int(tokenType)),
+	}
+}
@@ -0,0 +1,80 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+	"github.com/jaeyeom/email-validator-grpc-mcp/token/storage/memory"
+	"github.com/jaeyeom/email-validator-grpc-mcp/token/storagetest"
+)
+
+var errUnavailable = errors.New("backend unavailable")
+
+// failingStorage fails every Retrieve.
+type failingStorage struct {
+	token.Storage
+}
+
+func (failingStorage) Retrieve(context.Context, string, token.Type) (*token.Token, error) {
+	return nil, errUnavailable
+}
+
+func TestStorage_RedactsTokenValues(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	storage := Wrap(memory.New(memory.WithLogger(slog.New(slog.DiscardHandler))), WithLogger(logger))
+
+	const value = "abcd-0123456789-wxyz"
+	tkn := &token.Token{Value: value, Type: token.TypeLink, ValidUntil: time.Now().Add(10 * time.Millisecond), ValidationID: "validation-log"}
+	if err := storage.Store(ctx, tkn); err != nil {
+		t.Fatalf("Storage.Store() error = %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if _, err := storage.Retrieve(ctx, value, token.TypeLink); !token.IsTokenExpiredError(err) {
+		t.Fatalf("Storage.Retrieve() error = %v, want TokenExpiredError", err)
+	}
+
+	logs := buf.String()
+	if strings.Contains(logs, value) {
+		t.Errorf("logs contain the token value:\n%s", logs)
+	}
+	if got := strings.Count(logs, "token_value=abcd...wxyz"); got != 2 {
+		t.Errorf("logs contain %d redacted token values, want 2:\n%s", got, logs)
+	}
+	if !strings.Contains(logs, "level=DEBUG") || strings.Contains(logs, "level=WARN") {
+		t.Errorf("expired token not logged at debug level:\n%s", logs)
+	}
+}
+
+func TestStorage_FailuresLoggedAsWarnings(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+	storage := Wrap(failingStorage{Storage: memory.New()}, WithLogger(logger))
+
+	if _, err := storage.Retrieve(context.Background(), "value", token.TypeCode); !errors.Is(err, errUnavailable) {
+		t.Fatalf("Storage.Retrieve() error = %v, want %v", err, errUnavailable)
+	}
+
+	if logs := buf.String(); !strings.Contains(logs, "level=WARN") || !strings.Contains(logs, "operation=Retrieve") {
+		t.Errorf("backend failure not logged as a warning:\n%s", logs)
+	}
+}
+
+func TestStorage_Conformance(t *testing.T) {
+	t.Parallel()
+
+	storagetest.TestStorage(t, func() token.Storage {
+		return Wrap(memory.New(), WithLogger(slog.New(slog.DiscardHandler)))
+	})
+}
@@ -4,18 +4,43 @@ package memory
 import (
 	"context"
 	"fmt"
+	"hash/maphash"
 	"log/slog"
 	"sync"
+	"time"
 
 	"github.com/jaeyeom/email-validator-grpc-mcp/token"
 )
 
-// Storage provides an in-memory implementation for token storage.
+// DefaultShards is the default number of shards tokens are spread over.
+const DefaultShards = 32
+
+// Storage provides an in-memory implementation for token storage. Tokens,
+// validation ID indexes, and attempt counters are spread over shards by
+// hash, each with its own locks, so concurrent requests for different
+// tokens rarely contend.
 type Storage struct {
-	tokens       sync.Map // map[tokenKey]*token.Token
-	validationID sync.Map // map[string][]tokenKey
-	mu           sync.RWMutex
-	logger       *slog.Logger
+	shards []*shard
+	seed   maphash.Seed
+	logger *slog.Logger
+	clock  token.Clock
+
+	// numShards and maxTokens are the configured shard count and token cap.
+	numShards int
+	maxTokens int
+	onEvict   func(*token.Token)
+
+	// cleanupInterval, when positive, runs Cleanup in the background until
+	// Close closes stop.
+	cleanupInterval time.Duration
+	stop            chan struct{}
+	closeOnce       sync.Once
+}
+
+// attemptCounter tracks failed verification attempts for a validation.
+type attemptCounter struct {
+	count     int
+	expiresAt time.Time
 }
 
 // tokenKey is a composite key for token lookup.
@@ -34,19 +59,185 @@ func WithLogger(logger *slog.Logger) Option {
 	}
 }
 
+// WithClock sets the clock used for expiry checks.
+func WithClock(clock token.Clock) Option {
+	return func(s *Storage) {
+		s.clock = clock
+	}
+}
+
+// WithShards sets the number of shards, DefaultShards by default. More
+// shards reduce lock contention between concurrent requests; one shard
+// serializes all writes.
+func WithShards(n int) Option {
+	return func(s *Storage) {
+		s.numShards = max(n, 1)
+	}
+}
+
+// WithMaxTokens caps the number of stored tokens at about n. Each shard
+// holds its share of n and, when full, evicts its least recently stored or
+// retrieved token, so an abuse spike cannot grow memory without bound.
+// Eviction is exactly least recently used only with a single shard.
+// Evicted tokens can no longer be verified. A value of zero or less, the
+// default, disables the cap.
+func WithMaxTokens(n int) Option {
+	return func(s *Storage) {
+		s.maxTokens = max(n, 0)
+	}
+}
+
+// WithEvictionCallback sets a function called with each token evicted by
+// WithMaxTokens. It is called without locks held, so it may use the storage.
+func WithEvictionCallback(fn func(*token.Token)) Option {
+	return func(s *Storage) {
+		s.onEvict = fn
+	}
+}
+
+// WithCleanupInterval runs Cleanup every interval in a background goroutine,
+// so tokens that are never verified do not stay in memory after they expire.
+// Call Close to stop it.
+func WithCleanupInterval(interval time.Duration) Option {
+	return func(s *Storage) {
+		s.cleanupInterval = interval
+	}
+}
+
 // New creates a new in-memory token storage.
 func New(opts ...Option) *Storage {
 	s := &Storage{
-		logger: slog.Default(),
+		seed:      maphash.MakeSeed(),
+		logger:    slog.Default(),
+		clock:     token.SystemClock,
+		numShards: DefaultShards,
+		stop:      make(chan struct{}),
 	}
 
 	for _, opt := range opts {
 		opt(s)
 	}
 
+	perShard := 0
+	if s.maxTokens > 0 {
+		perShard = (s.maxTokens + s.numShards - 1) / s.numShards
+	}
+
+	s.shards = make([]*shard, s.numShards)
+	for i := range s.shards {
+		s.shards[i] = newShard(perShard)
+	}
+
+	if s.cleanupInterval > 0 {
+		go s.janitor()
+	}
+
 	return s
 }
 
+// shardOf returns the shard that str hashes to.
+func (s *Storage) shardOf(str string) *shard {
+	return s.shards[maphash.String(s.seed, str)%uint64(len(s.shards))]
+}
+
+// tokenShard returns the shard holding the token stored under key. Tokens
+// of different types with the same value share a shard.
+func (s *Storage) tokenShard(key tokenKey) *shard {
+	return s.shardOf(key.value)
+}
+
+// indexShard returns the shard holding the index and attempt counter of
+// validationID.
+func (s *Storage) indexShard(validationID string) *shard {
+	return s.shardOf(validationID)
+}
+
+// Close stops the background cleanup started by WithCleanupInterval. It is
+// safe to call more than once, and the storage stays usable afterwards.
+func (s *Storage) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.stop)
+	})
+
+	return nil
+}
+
+// janitor runs Cleanup every cleanupInterval until Close is called.
+func (s *Storage) janitor() {
+	ticker := time.NewTicker(s.cleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			// Cleanup only fails on a canceled context
+			_, _ = s.Cleanup(context.Background())
+		}
+	}
+}
+
+// Cleanup removes expired tokens and attempt counters, and prunes validation
+// ID index entries whose tokens are gone. It returns the number of tokens
+// removed.
+func (s *Storage) Cleanup(ctx context.Context) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, fmt.Errorf("context error: %w", err)
+	}
+
+	now := s.clock.Now()
+	removed := 0
+	for _, sh := range s.shards {
+		removed += len(sh.removeExpired(now))
+	}
+
+	pruned := 0
+	for _, sh := range s.shards {
+		pruned += s.pruneIndexes(sh, now)
+	}
+
+	s.logger.Debug("expired tokens cleaned up from memory",
+		"tokens_removed", removed,
+		"index_entries_pruned", pruned)
+
+	return removed, nil
+}
+
+// pruneIndexes drops the expired attempt counters of sh, and the index
+// entries of sh whose tokens are gone. It returns the number of index
+// entries dropped.
+func (s *Storage) pruneIndexes(sh *shard, now time.Time) int {
+	sh.indexMu.Lock()
+	defer sh.indexMu.Unlock()
+
+	for validationID, counter := range sh.attempts {
+		if now.After(counter.expiresAt) {
+			delete(sh.attempts, validationID)
+		}
+	}
+
+	pruned := 0
+	for validationID, keys := range sh.index {
+		live := keys[:0:0]
+		for _, key := range keys {
+			if _, ok := s.tokenShard(key).load(key); ok {
+				live = append(live, key)
+			}
+		}
+
+		switch {
+		case len(live) == 0:
+			delete(sh.index, validationID)
+		case len(live) < len(keys):
+			sh.index[validationID] = live
+		}
+		pruned += len(keys) - len(live)
+	}
+
+	return pruned
+}
+
 // Store saves a token to the in-memory storage.
 // Returns an error if the token is invalid.
 func (s *Storage) Store(ctx context.Context, t *token.Token) error {
@@ -58,27 +249,56 @@ func (s *Storage) Store(ctx context.Context, t *token.Token) error {
 		return fmt.Errorf("token validation failed: %w", err)
 	}
 
-	key := tokenKey{value: t.Value, typ: t.Type}
+	s.store(t)
+
+	return nil
+}
 
-	s.tokens.Store(key, t)
+// StoreBatch saves multiple tokens. All tokens are validated before any is
+// stored, so an invalid token leaves the storage unchanged.
+func (s *Storage) StoreBatch(ctx context.Context, tokens []*token.Token) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("context error: %w", err)
+	}
 
-	// Update the validation ID index
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	for i, t := range tokens {
+		if err := token.Validate(t); err != nil {
+			return fmt.Errorf("token %d validation failed: %w", i, err)
+		}
+	}
 
-	var keys []tokenKey
-	if val, ok := s.validationID.Load(t.ValidationID); ok {
-		keys, _ = val.([]tokenKey)
+	for _, t := range tokens {
+		s.store(t)
 	}
 
-	keys = append(keys, key)
-	s.validationID.Store(t.ValidationID, keys)
+	return nil
+}
+
+// store saves a validated token and indexes it, evicting tokens if the
+// storage is full.
+func (s *Storage) store(t *token.Token) {
+	key := tokenKey{value: t.Value, typ: t.Type}
+
+	evicted := s.tokenShard(key).store(key, t)
+
+	// Update the validation ID index
+	s.indexShard(t.ValidationID).addToIndex(t.ValidationID, key)
 
 	s.logger.Debug("token stored in memory",
 		"token_type", t.Type,
 		"validation_id", t.ValidationID)
 
-	return nil
+	for _, e := range evicted {
+		s.indexShard(e.ValidationID).removeFromIndex(e.ValidationID, tokenKey{value: e.Value, typ: e.Type})
+
+		s.logger.Debug("token evicted from memory",
+			"token_type", e.Type,
+			"validation_id", e.ValidationID)
+
+		if s.onEvict != nil {
+			s.onEvict(e)
+		}
+	}
 }
 
 // Retrieve gets a token from the in-memory storage.
@@ -90,30 +310,32 @@ func (s *Storage) Retrieve(ctx context.Context, tokenValue string, tokenType tok
 	}
 
 	key := tokenKey{value: tokenValue, typ: tokenType}
+	sh := s.tokenShard(key)
 
-	val, ok := s.tokens.Load(key)
+	t, ok := sh.load(key)
 	if !ok {
 		return nil, token.ErrTokenNotFound
 	}
 
-	t, ok := val.(*token.Token)
-	if !ok {
-		return nil, token.ErrInvalidTokenType
-	}
-
 	// Check if the token has expired
-	if t.IsExpired() {
-		// Delete the expired token
-		s.tokens.Delete(key)
+	if t.IsExpiredAt(s.clock.Now()) {
+		// Delete the expired token, unless it was replaced meanwhile
+		if _, ok := sh.remove(key, t); ok {
+			s.indexShard(t.ValidationID).removeFromIndex(t.ValidationID, key)
+		}
 		s.logger.Debug("expired token retrieved and deleted",
 			"token_type", t.Type,
 			"validation_id", t.ValidationID)
 		return nil, &token.TokenExpiredError{
-			TokenValue: tokenValue,
-			ExpiredAt:  t.ValidUntil,
+			TokenValue:   tokenValue,
+			TokenType:    tokenType,
+			ExpiredAt:    t.ValidUntil,
+			ValidationID: t.ValidationID,
 		}
 	}
 
+	sh.touch(key)
+
 	s.logger.Debug("token retrieved from memory",
 		"token_type", t.Type,
 		"validation_id", t.ValidationID)
@@ -130,55 +352,174 @@ func (s *Storage) Delete(ctx context.Context, tokenValue string, tokenType token
 
 	key := tokenKey{value: tokenValue, typ: tokenType}
 
-	// Get the token to find its validation ID
-	val, ok := s.tokens.Load(key)
+	s.delete(key)
+
+	return nil
+}
+
+// DeleteBatch removes multiple tokens. Tokens that do not exist are
+// ignored.
+func (s *Storage) DeleteBatch(ctx context.Context, refs []token.TokenRef) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("context error: %w", err)
+	}
+
+	for _, ref := range refs {
+		s.delete(tokenKey{value: ref.Value, typ: ref.Type})
+	}
+
+	return nil
+}
+
+// delete removes the token stored under key and unindexes it.
+func (s *Storage) delete(key tokenKey) {
+	t, ok := s.tokenShard(key).remove(key, nil)
 	if !ok {
 		// Token doesn't exist, nothing to delete
-		return nil
+		return
 	}
 
-	t, ok := val.(*token.Token)
-	if !ok {
-		return token.ErrInvalidTokenType
+	s.indexShard(t.ValidationID).removeFromIndex(t.ValidationID, key)
+
+	s.logger.Debug("token deleted from memory",
+		"token_type", t.Type,
+		"validation_id", t.ValidationID)
+}
+
+// ListByValidationID returns the unexpired tokens for a validation ID.
+func (s *Storage) ListByValidationID(ctx context.Context, validationID string) ([]*token.Token, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("context error: %w", err)
 	}
 
-	// Delete the token
-	s.tokens.Delete(key)
+	if validationID == "" {
+		return nil, token.ErrEmptyValidationID
+	}
 
-	// Update the validation ID index
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	tokens := []*token.Token{}
 
-	validationID := t.ValidationID
-	val, ok = s.validationID.Load(validationID)
-	if !ok {
-		return nil
+	now := s.clock.Now()
+	for _, key := range s.indexShard(validationID).indexed(validationID) {
+		t, ok := s.tokenShard(key).load(key)
+		if ok && !t.IsExpiredAt(now) {
+			tokens = append(tokens, t)
+		}
+	}
+
+	s.logger.Debug("tokens listed by validation ID",
+		"validation_id", validationID,
+		"count", len(tokens))
+
+	return tokens, nil
+}
+
+// Consume atomically retrieves and deletes a token from the in-memory storage.
+// Returns token.ErrTokenNotFound if the token does not exist or was already consumed.
+// Returns token.TokenExpiredError if the token exists but has expired.
+func (s *Storage) Consume(ctx context.Context, tokenValue string, tokenType token.Type) (*token.Token, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("context error: %w", err)
 	}
 
-	keys, ok := val.([]tokenKey)
+	key := tokenKey{value: tokenValue, typ: tokenType}
+
+	// Removing under the shard lock guarantees only one caller observes the token
+	t, ok := s.tokenShard(key).remove(key, nil)
 	if !ok {
-		return token.ErrInvalidTokenKeyType
+		return nil, token.ErrTokenNotFound
 	}
 
-	// Remove the key from the slice
-	var newKeys []tokenKey
-	for _, k := range keys {
-		if k.value != key.value || k.typ != key.typ {
-			newKeys = append(newKeys, k)
+	s.indexShard(t.ValidationID).removeFromIndex(t.ValidationID, key)
+
+	if t.IsExpiredAt(s.clock.Now()) {
+		s.logger.Debug("expired token consumed and deleted",
+			"token_type", t.Type,
+			"validation_id", t.ValidationID)
+		return nil, &token.TokenExpiredError{
+			TokenValue:   tokenValue,
+			TokenType:    tokenType,
+			ExpiredAt:    t.ValidUntil,
+			ValidationID: t.ValidationID,
 		}
 	}
 
-	if len(newKeys) > 0 {
-		s.validationID.Store(validationID, newKeys)
-	} else {
-		s.validationID.Delete(validationID)
+	s.logger.Debug("token consumed from memory",
+		"token_type", t.Type,
+		"validation_id", t.ValidationID)
+
+	return t, nil
+}
+
+// ExtendTTL atomically extends the expiry of a token in the in-memory storage.
+// Returns token.ErrTokenNotFound if the token does not exist.
+// Returns token.TokenExpiredError if the token has already expired.
+func (s *Storage) ExtendTTL(ctx context.Context, tokenValue string, tokenType token.Type, extra time.Duration) (*token.Token, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("context error: %w", err)
+	}
+
+	key := tokenKey{value: tokenValue, typ: tokenType}
+	sh := s.tokenShard(key)
+
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	t, ok := sh.tokens[key]
+	if !ok {
+		return nil, token.ErrTokenNotFound
 	}
 
-	s.logger.Debug("token deleted from memory",
+	if t.IsExpiredAt(s.clock.Now()) {
+		return nil, &token.TokenExpiredError{
+			TokenValue:   tokenValue,
+			TokenType:    tokenType,
+			ExpiredAt:    t.ValidUntil,
+			ValidationID: t.ValidationID,
+		}
+	}
+
+	// Replace rather than mutate, since callers may hold the old pointer
+	extended := *t
+	extended.ValidUntil = t.ValidUntil.Add(extra)
+	sh.tokens[key] = &extended
+
+	s.logger.Debug("token TTL extended in memory",
 		"token_type", t.Type,
-		"validation_id", t.ValidationID)
+		"validation_id", t.ValidationID,
+		"valid_until", extended.ValidUntil)
 
-	return nil
+	return &extended, nil
+}
+
+// IncrementAttempts records a failed verification attempt for a validation and
+// returns the number of failed attempts within the counter's lifetime.
+func (s *Storage) IncrementAttempts(ctx context.Context, validationID string, ttl time.Duration) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, fmt.Errorf("context error: %w", err)
+	}
+
+	if validationID == "" {
+		return 0, token.ErrEmptyValidationID
+	}
+
+	sh := s.indexShard(validationID)
+	sh.indexMu.Lock()
+	defer sh.indexMu.Unlock()
+
+	now := s.clock.Now()
+	counter, ok := sh.attempts[validationID]
+	if !ok || now.After(counter.expiresAt) {
+		counter = attemptCounter{expiresAt: now.Add(ttl)}
+	}
+
+	counter.count++
+	sh.attempts[validationID] = counter
+
+	s.logger.Debug("verification attempt recorded in memory",
+		"validation_id", validationID,
+		"attempts", counter.count)
+
+	return counter.count, nil
 }
 
 // DeleteByValidationID removes all tokens associated with a validation ID.
@@ -192,27 +533,25 @@ func (s *Storage) DeleteByValidationID(ctx context.Context, validationID string)
 		return token.ErrEmptyValidationID
 	}
 
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	sh := s.indexShard(validationID)
+	sh.indexMu.Lock()
+	defer sh.indexMu.Unlock()
+
+	delete(sh.attempts, validationID)
 
-	val, ok := s.validationID.Load(validationID)
+	keys, ok := sh.index[validationID]
 	if !ok {
 		// No tokens for this validation ID
 		return nil
 	}
 
-	keys, ok := val.([]tokenKey)
-	if !ok {
-		return token.ErrInvalidTokenKeyType
-	}
-
 	// Delete all tokens for this validation ID
 	for _, key := range keys {
-		s.tokens.Delete(key)
+		s.tokenShard(key).remove(key, nil)
 	}
 
 	// Remove the validation ID entry
-	s.validationID.Delete(validationID)
+	delete(sh.index, validationID)
 
 	s.logger.Debug("tokens deleted by validation ID",
 		"validation_id", validationID,
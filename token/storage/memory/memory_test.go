@@ -2,10 +2,17 @@ package memory
 
 import (
 	"context"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+	"github.com/jaeyeom/email-validator-grpc-mcp/token/storagetest"
 )
 
 func TestStorage_Store(t *testing.T) {
@@ -318,3 +325,382 @@ func TestStorage_DeleteByValidationID(t *testing.T) {
 		})
 	}
 }
+
+func TestStorage_Consume(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	storage := New()
+
+	tkn := &token.Token{
+		Value:        "test-token-consume",
+		Type:         token.TypeCode,
+		ValidUntil:   time.Now().Add(time.Hour),
+		ValidationID: "validation-consume",
+	}
+	if err := storage.Store(ctx, tkn); err != nil {
+		t.Fatalf("Storage.Store() error = %v", err)
+	}
+
+	got, err := storage.Consume(ctx, tkn.Value, tkn.Type)
+	if err != nil {
+		t.Fatalf("Storage.Consume() error = %v", err)
+	}
+	if got.ValidationID != tkn.ValidationID {
+		t.Errorf("Storage.Consume() validation ID = %v, want %v", got.ValidationID, tkn.ValidationID)
+	}
+
+	// A consumed token must not be retrievable or consumable again
+	if _, err := storage.Consume(ctx, tkn.Value, tkn.Type); err != token.ErrTokenNotFound {
+		t.Errorf("second Storage.Consume() error = %v, want %v", err, token.ErrTokenNotFound)
+	}
+	if _, err := storage.Retrieve(ctx, tkn.Value, tkn.Type); err != token.ErrTokenNotFound {
+		t.Errorf("Storage.Retrieve() after consume error = %v, want %v", err, token.ErrTokenNotFound)
+	}
+}
+
+func TestStorage_Consume_Concurrent(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	storage := New()
+
+	tkn := &token.Token{
+		Value:        "1234",
+		Type:         token.TypeCode,
+		ValidUntil:   time.Now().Add(time.Hour),
+		ValidationID: "validation-race",
+	}
+	if err := storage.Store(ctx, tkn); err != nil {
+		t.Fatalf("Storage.Store() error = %v", err)
+	}
+
+	const workers = 32
+	var (
+		wg        sync.WaitGroup
+		successes atomic.Int32
+	)
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := storage.Consume(ctx, tkn.Value, tkn.Type); err == nil {
+				successes.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := successes.Load(); n != 1 {
+		t.Errorf("Storage.Consume() succeeded %d times, want exactly 1", n)
+	}
+}
+
+func TestStorage_WithMaxTokens(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	var evicted []string
+	storage := New(WithShards(1), WithMaxTokens(2), WithEvictionCallback(func(t *token.Token) {
+		evicted = append(evicted, t.Value)
+	}))
+
+	store := func(value string) {
+		t.Helper()
+		tkn := &token.Token{
+			Value:        value,
+			Type:         token.TypeLink,
+			ValidUntil:   time.Now().Add(time.Hour),
+			ValidationID: "validation-lru",
+		}
+		if err := storage.Store(ctx, tkn); err != nil {
+			t.Fatalf("Storage.Store() error = %v", err)
+		}
+	}
+
+	store("a")
+	store("b")
+
+	// Retrieving a makes b the least recently used
+	if _, err := storage.Retrieve(ctx, "a", token.TypeLink); err != nil {
+		t.Fatalf("Storage.Retrieve() error = %v", err)
+	}
+	store("c")
+
+	if len(evicted) != 1 || evicted[0] != "b" {
+		t.Fatalf("evicted = %v, want [b]", evicted)
+	}
+	if _, err := storage.Retrieve(ctx, "b", token.TypeLink); err != token.ErrTokenNotFound {
+		t.Errorf("Storage.Retrieve() of evicted token error = %v, want %v", err, token.ErrTokenNotFound)
+	}
+	tokens, err := storage.ListByValidationID(ctx, "validation-lru")
+	if err != nil {
+		t.Fatalf("Storage.ListByValidationID() error = %v", err)
+	}
+	if len(tokens) != 2 {
+		t.Errorf("Storage.ListByValidationID() returned %d tokens, want 2", len(tokens))
+	}
+
+	// Deleted tokens free their slot without an eviction
+	if err := storage.Delete(ctx, "a", token.TypeLink); err != nil {
+		t.Fatalf("Storage.Delete() error = %v", err)
+	}
+	store("d")
+	if len(evicted) != 1 {
+		t.Errorf("evicted = %v after storing into a freed slot, want [b]", evicted)
+	}
+}
+
+func TestStorage_Cleanup(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	now := time.Now()
+	clock := token.ClockFunc(func() time.Time { return now })
+	storage := New(WithClock(clock))
+
+	for _, tkn := range []*token.Token{
+		{Value: "short", Type: token.TypeCode, ValidUntil: now.Add(time.Minute), ValidationID: "validation-mixed"},
+		{Value: "long", Type: token.TypeLink, ValidUntil: now.Add(time.Hour), ValidationID: "validation-mixed"},
+		{Value: "gone", Type: token.TypeCode, ValidUntil: now.Add(time.Minute), ValidationID: "validation-gone"},
+	} {
+		if err := storage.Store(ctx, tkn); err != nil {
+			t.Fatalf("Storage.Store() error = %v", err)
+		}
+	}
+	if _, err := storage.IncrementAttempts(ctx, "validation-gone", time.Minute); err != nil {
+		t.Fatalf("Storage.IncrementAttempts() error = %v", err)
+	}
+
+	now = now.Add(2 * time.Minute)
+	removed, err := storage.Cleanup(ctx)
+	if err != nil {
+		t.Fatalf("Storage.Cleanup() error = %v", err)
+	}
+	if removed != 2 {
+		t.Errorf("Storage.Cleanup() = %d, want 2", removed)
+	}
+
+	short := tokenKey{value: "short", typ: token.TypeCode}
+	if _, ok := storage.tokenShard(short).load(short); ok {
+		t.Error("expired token still stored")
+	}
+	if keys := storage.indexShard("validation-mixed").indexed("validation-mixed"); len(keys) != 1 {
+		t.Errorf("validation-mixed index = %v, want only the unexpired token", keys)
+	}
+	if keys := storage.indexShard("validation-gone").indexed("validation-gone"); len(keys) != 0 {
+		t.Errorf("validation-gone index = %v, want none", keys)
+	}
+	if _, ok := storage.indexShard("validation-gone").attempts["validation-gone"]; ok {
+		t.Error("expired attempt counter still stored")
+	}
+}
+
+func TestStorage_WithCleanupInterval(t *testing.T) {
+	t.Parallel()
+
+	storage := New(WithCleanupInterval(5 * time.Millisecond))
+	t.Cleanup(func() {
+		if err := storage.Close(); err != nil {
+			t.Errorf("Storage.Close() error = %v", err)
+		}
+	})
+
+	tkn := &token.Token{
+		Value:        "janitor",
+		Type:         token.TypeCode,
+		ValidUntil:   time.Now().Add(10 * time.Millisecond),
+		ValidationID: "validation-janitor",
+	}
+	if err := storage.Store(context.Background(), tkn); err != nil {
+		t.Fatalf("Storage.Store() error = %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if len(storage.indexShard("validation-janitor").indexed("validation-janitor")) == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("janitor did not remove the expired token")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestStorage_IncrementAttempts(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	storage := New()
+
+	for want := 1; want <= 3; want++ {
+		got, err := storage.IncrementAttempts(ctx, "validation-attempts", time.Hour)
+		if err != nil {
+			t.Fatalf("Storage.IncrementAttempts() error = %v", err)
+		}
+		if got != want {
+			t.Errorf("Storage.IncrementAttempts() = %d, want %d", got, want)
+		}
+	}
+
+	// Deleting the validation resets its counter
+	if err := storage.DeleteByValidationID(ctx, "validation-attempts"); err != nil {
+		t.Fatalf("Storage.DeleteByValidationID() error = %v", err)
+	}
+	if got, _ := storage.IncrementAttempts(ctx, "validation-attempts", time.Hour); got != 1 {
+		t.Errorf("Storage.IncrementAttempts() after delete = %d, want 1", got)
+	}
+
+	// An expired counter starts over
+	if _, err := storage.IncrementAttempts(ctx, "validation-short", time.Nanosecond); err != nil {
+		t.Fatalf("Storage.IncrementAttempts() error = %v", err)
+	}
+	time.Sleep(time.Millisecond)
+	if got, _ := storage.IncrementAttempts(ctx, "validation-short", time.Hour); got != 1 {
+		t.Errorf("Storage.IncrementAttempts() after expiry = %d, want 1", got)
+	}
+
+	if _, err := storage.IncrementAttempts(ctx, "", time.Hour); err != token.ErrEmptyValidationID {
+		t.Errorf("Storage.IncrementAttempts() with empty ID error = %v, want %v", err, token.ErrEmptyValidationID)
+	}
+}
+
+func TestStorage_ExtendTTL(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	now := time.Now()
+	storage := New(WithClock(token.ClockFunc(func() time.Time { return now })))
+
+	tkn := token.NewAt("test-token-extend", token.TypeLink, "validation-extend", time.Hour, now)
+	if err := storage.Store(ctx, tkn); err != nil {
+		t.Fatalf("Storage.Store() error = %v", err)
+	}
+
+	extended, err := storage.ExtendTTL(ctx, tkn.Value, tkn.Type, 30*time.Minute)
+	if err != nil {
+		t.Fatalf("Storage.ExtendTTL() error = %v", err)
+	}
+	if want := now.Add(90 * time.Minute); !extended.ValidUntil.Equal(want) {
+		t.Errorf("Storage.ExtendTTL() valid until = %v, want %v", extended.ValidUntil, want)
+	}
+	if !tkn.ValidUntil.Equal(now.Add(time.Hour)) {
+		t.Error("Storage.ExtendTTL() mutated the originally stored token")
+	}
+
+	now = now.Add(80 * time.Minute)
+	if _, err := storage.Retrieve(ctx, tkn.Value, tkn.Type); err != nil {
+		t.Errorf("Storage.Retrieve() after extension error = %v", err)
+	}
+
+	now = now.Add(20 * time.Minute)
+	if _, err := storage.ExtendTTL(ctx, tkn.Value, tkn.Type, time.Hour); !token.IsTokenExpiredError(err) {
+		t.Errorf("Storage.ExtendTTL() on expired token error = %v, want TokenExpiredError", err)
+	}
+
+	if _, err := storage.ExtendTTL(ctx, "missing", token.TypeLink, time.Hour); err != token.ErrTokenNotFound {
+		t.Errorf("Storage.ExtendTTL() on missing token error = %v, want %v", err, token.ErrTokenNotFound)
+	}
+}
+
+func TestStorage_ListByValidationID(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	now := time.Now()
+	storage := New(WithClock(token.ClockFunc(func() time.Time { return now })))
+
+	tokens := []*token.Token{
+		{Value: "list-link", Type: token.TypeLink, ValidUntil: now.Add(time.Hour), ValidationID: "validation-list"},
+		{Value: "list-code", Type: token.TypeCode, ValidUntil: now.Add(time.Minute), ValidationID: "validation-list"},
+		{Value: "other-link", Type: token.TypeLink, ValidUntil: now.Add(time.Hour), ValidationID: "validation-other"},
+	}
+	for _, tkn := range tokens {
+		if err := storage.Store(ctx, tkn); err != nil {
+			t.Fatalf("Storage.Store() error = %v", err)
+		}
+	}
+
+	got, err := storage.ListByValidationID(ctx, "validation-list")
+	if err != nil {
+		t.Fatalf("Storage.ListByValidationID() error = %v", err)
+	}
+	if len(got) != 2 {
+		t.Errorf("Storage.ListByValidationID() returned %d tokens, want 2", len(got))
+	}
+
+	// Expired tokens are left out
+	now = now.Add(30 * time.Minute)
+	got, err = storage.ListByValidationID(ctx, "validation-list")
+	if err != nil {
+		t.Fatalf("Storage.ListByValidationID() error = %v", err)
+	}
+	if len(got) != 1 || got[0].Value != "list-link" {
+		t.Errorf("Storage.ListByValidationID() after expiry = %v, want only list-link", got)
+	}
+
+	got, err = storage.ListByValidationID(ctx, "validation-missing")
+	if err != nil || len(got) != 0 {
+		t.Errorf("Storage.ListByValidationID() for unknown ID = %v, %v, want empty", got, err)
+	}
+}
+
+func TestStorage_Conformance(t *testing.T) {
+	t.Parallel()
+
+	storagetest.TestStorage(t, func() token.Storage {
+		return New()
+	})
+
+	t.Run("WithMaxTokens", func(t *testing.T) {
+		t.Parallel()
+
+		storagetest.TestStorage(t, func() token.Storage {
+			return New(WithMaxTokens(1000))
+		})
+	})
+
+	t.Run("WithShards", func(t *testing.T) {
+		t.Parallel()
+
+		storagetest.TestStorage(t, func() token.Storage {
+			return New(WithShards(1))
+		})
+	})
+}
+
+// BenchmarkStorage_Parallel runs a store, retrieve, and consume cycle from
+// at least 64 goroutines, comparing a single shard, which serializes writes
+// like a single lock, with the default sharding.
+func BenchmarkStorage_Parallel(b *testing.B) {
+	for _, shards := range []int{1, DefaultShards} {
+		b.Run(fmt.Sprintf("shards=%d", shards), func(b *testing.B) {
+			ctx := context.Background()
+			storage := New(WithShards(shards), WithLogger(slog.New(slog.DiscardHandler)))
+			var seq atomic.Int64
+
+			b.SetParallelism((64 + runtime.GOMAXPROCS(0) - 1) / runtime.GOMAXPROCS(0))
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					n := seq.Add(1)
+					tkn := &token.Token{
+						Value:        strconv.FormatInt(n, 36),
+						Type:         token.TypeCode,
+						ValidUntil:   time.Now().Add(time.Hour),
+						ValidationID: "validation-" + strconv.FormatInt(n%1024, 10),
+					}
+					if err := storage.Store(ctx, tkn); err != nil {
+						b.Fatal(err)
+					}
+					if _, err := storage.Retrieve(ctx, tkn.Value, tkn.Type); err != nil {
+						b.Fatal(err)
+					}
+					if _, err := storage.Consume(ctx, tkn.Value, tkn.Type); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		})
+	}
+}
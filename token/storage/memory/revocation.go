@@ -0,0 +1,68 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+)
+
+// RevocationList is an in-memory token.RevocationList. Expired entries are
+// dropped lazily when the list is modified.
+type RevocationList struct {
+	mu      sync.RWMutex
+	entries map[string]time.Time
+	clock   token.Clock
+}
+
+// NewRevocationList creates an empty in-memory revocation list. A nil clock
+// defaults to token.SystemClock.
+func NewRevocationList(clock token.Clock) *RevocationList {
+	if clock == nil {
+		clock = token.SystemClock
+	}
+
+	return &RevocationList{
+		entries: make(map[string]time.Time),
+		clock:   clock,
+	}
+}
+
+// Add records a revoked token digest until expiresAt.
+func (l *RevocationList) Add(ctx context.Context, digest string, expiresAt time.Time, _ string) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("context error: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.clock.Now()
+	for d, exp := range l.entries {
+		if !exp.After(now) {
+			delete(l.entries, d)
+		}
+	}
+
+	if expiresAt.After(l.entries[digest]) {
+		l.entries[digest] = expiresAt
+	}
+
+	return nil
+}
+
+// Contains reports whether the digest is revoked and not yet expired.
+func (l *RevocationList) Contains(ctx context.Context, digest string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, fmt.Errorf("context error: %w", err)
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	expiresAt, ok := l.entries[digest]
+
+	return ok && expiresAt.After(l.clock.Now()), nil
+}
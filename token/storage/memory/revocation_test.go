@@ -0,0 +1,35 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+)
+
+func TestRevocationList(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	now := time.Now()
+	list := NewRevocationList(token.ClockFunc(func() time.Time { return now }))
+
+	digest := token.TokenDigest("revoked-token", token.TypeLink)
+	if err := list.Add(ctx, digest, now.Add(time.Hour), "leaked"); err != nil {
+		t.Fatalf("RevocationList.Add() error = %v", err)
+	}
+
+	if revoked, err := list.Contains(ctx, digest); err != nil || !revoked {
+		t.Errorf("RevocationList.Contains() = %v, %v, want true", revoked, err)
+	}
+	if revoked, _ := list.Contains(ctx, token.TokenDigest("revoked-token", token.TypeCode)); revoked {
+		t.Error("RevocationList.Contains() = true for a different token type")
+	}
+
+	// Entries lapse with the token
+	now = now.Add(2 * time.Hour)
+	if revoked, _ := list.Contains(ctx, digest); revoked {
+		t.Error("RevocationList.Contains() = true after expiry")
+	}
+}
@@ -0,0 +1,176 @@
+package memory
+
+import (
+	"container/list"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+)
+
+// shard holds the tokens whose keys hash to it, and the validation ID
+// indexes and attempt counters of the validation IDs that hash to it.
+//
+// mu guards tokens and the recency list; indexMu guards index and attempts.
+// Code holding an indexMu may acquire the mu of any shard, but never the
+// other way round, so the two cannot deadlock.
+type shard struct {
+	mu     sync.RWMutex
+	tokens map[tokenKey]*token.Token
+
+	// maxTokens caps len(tokens) when positive. recent orders token keys
+	// from most to least recently used, and elements locates each key in it.
+	maxTokens int
+	recent    *list.List
+	elements  map[tokenKey]*list.Element
+
+	indexMu  sync.Mutex
+	index    map[string][]tokenKey
+	attempts map[string]attemptCounter
+}
+
+// newShard creates an empty shard holding at most maxTokens tokens, or any
+// number if maxTokens is zero.
+func newShard(maxTokens int) *shard {
+	sh := &shard{
+		tokens:    make(map[tokenKey]*token.Token),
+		maxTokens: maxTokens,
+		index:     make(map[string][]tokenKey),
+		attempts:  make(map[string]attemptCounter),
+	}
+
+	if maxTokens > 0 {
+		sh.recent = list.New()
+		sh.elements = make(map[tokenKey]*list.Element)
+	}
+
+	return sh
+}
+
+// load returns the token stored under key.
+func (sh *shard) load(key tokenKey) (*token.Token, bool) {
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+
+	t, ok := sh.tokens[key]
+
+	return t, ok
+}
+
+// store saves t under key and returns the tokens evicted to make room.
+func (sh *shard) store(key tokenKey, t *token.Token) []*token.Token {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	sh.tokens[key] = t
+	if sh.recent == nil {
+		return nil
+	}
+
+	if elem, ok := sh.elements[key]; ok {
+		sh.recent.MoveToFront(elem)
+	} else {
+		sh.elements[key] = sh.recent.PushFront(key)
+	}
+
+	var evicted []*token.Token
+	for sh.recent.Len() > sh.maxTokens {
+		oldest, _ := sh.recent.Remove(sh.recent.Back()).(tokenKey)
+		delete(sh.elements, oldest)
+		evicted = append(evicted, sh.tokens[oldest])
+		delete(sh.tokens, oldest)
+	}
+
+	return evicted
+}
+
+// touch marks key as the most recently used token, if it is still stored.
+func (sh *shard) touch(key tokenKey) {
+	if sh.recent == nil {
+		return
+	}
+
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	if elem, ok := sh.elements[key]; ok {
+		sh.recent.MoveToFront(elem)
+	}
+}
+
+// remove deletes and returns the token stored under key. If want is not
+// nil, the token is only removed if it is still want.
+func (sh *shard) remove(key tokenKey, want *token.Token) (*token.Token, bool) {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	t, ok := sh.tokens[key]
+	if !ok || (want != nil && t != want) {
+		return nil, false
+	}
+
+	sh.removeLocked(key)
+
+	return t, true
+}
+
+// removeLocked deletes the token stored under key. The caller must hold mu.
+func (sh *shard) removeLocked(key tokenKey) {
+	delete(sh.tokens, key)
+
+	if elem, ok := sh.elements[key]; ok {
+		sh.recent.Remove(elem)
+		delete(sh.elements, key)
+	}
+}
+
+// removeExpired deletes the tokens expired at now and returns them.
+func (sh *shard) removeExpired(now time.Time) []*token.Token {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	var expired []*token.Token
+	for key, t := range sh.tokens {
+		if t.IsExpiredAt(now) {
+			sh.removeLocked(key)
+			expired = append(expired, t)
+		}
+	}
+
+	return expired
+}
+
+// addToIndex records key under validationID.
+func (sh *shard) addToIndex(validationID string, key tokenKey) {
+	sh.indexMu.Lock()
+	defer sh.indexMu.Unlock()
+
+	if keys := sh.index[validationID]; !slices.Contains(keys, key) {
+		sh.index[validationID] = append(keys, key)
+	}
+}
+
+// removeFromIndex removes key from the index of validationID.
+func (sh *shard) removeFromIndex(validationID string, key tokenKey) {
+	sh.indexMu.Lock()
+	defer sh.indexMu.Unlock()
+
+	keys := slices.DeleteFunc(slices.Clone(sh.index[validationID]), func(k tokenKey) bool {
+		return k == key
+	})
+
+	if len(keys) > 0 {
+		sh.index[validationID] = keys
+	} else {
+		delete(sh.index, validationID)
+	}
+}
+
+// indexed returns a copy of the keys indexed under validationID.
+func (sh *shard) indexed(validationID string) []tokenKey {
+	sh.indexMu.Lock()
+	defer sh.indexMu.Unlock()
+
+	return slices.Clone(sh.index[validationID])
+}
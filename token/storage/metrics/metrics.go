@@ -0,0 +1,252 @@
+// Package metrics provides a token storage decorator that records the
+// count, latency, and outcome of every storage operation. Outcomes are
+// reported to a Recorder, which adapts them to Prometheus, OpenTelemetry, or
+// any other metrics system.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+)
+
+// Outcome classifies the result of a storage operation. Classes are coarse
+// so they can be used as metric labels.
+type Outcome string
+
+// Operation outcomes.
+const (
+	OutcomeOK       Outcome = "ok"
+	OutcomeNotFound Outcome = "not_found"
+	OutcomeExpired  Outcome = "expired"
+	OutcomeInvalid  Outcome = "invalid"
+	OutcomeCanceled Outcome = "canceled"
+	OutcomeError    Outcome = "error"
+)
+
+// OutcomeOf classifies the error returned by a storage operation. Expired
+// tokens are reported separately from missing ones, so a rise in users
+// verifying too late is visible.
+func OutcomeOf(err error) Outcome {
+	switch {
+	case err == nil:
+		return OutcomeOK
+	case errors.Is(err, token.ErrTokenNotFound):
+		return OutcomeNotFound
+	case token.IsTokenExpiredError(err):
+		return OutcomeExpired
+	case errors.Is(err, token.ErrInvalidToken),
+		errors.Is(err, token.ErrTokenNil),
+		errors.Is(err, token.ErrEmptyTokenValue),
+		errors.Is(err, token.ErrEmptyValidationID):
+		return OutcomeInvalid
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return OutcomeCanceled
+	default:
+		return OutcomeError
+	}
+}
+
+// Recorder receives one observation per storage operation. Implementations
+// must be safe for concurrent use and should not block.
+type Recorder interface {
+	// Observe records that the operation op, such as "Retrieve", finished
+	// with outcome after duration d.
+	Observe(ctx context.Context, op string, outcome Outcome, d time.Duration)
+}
+
+// RecorderFunc adapts a function to the Recorder interface.
+type RecorderFunc func(ctx context.Context, op string, outcome Outcome, d time.Duration)
+
+// Observe calls f.
+func (f RecorderFunc) Observe(ctx context.Context, op string, outcome Outcome, d time.Duration) {
+	f(ctx, op, outcome, d)
+}
+
+// Storage wraps a token.Storage and records metrics for its operations.
+type Storage struct {
+	storage  token.Storage
+	recorder Recorder
+	clock    token.Clock
+}
+
+// Option is a functional option for configuring Storage.
+type Option func(*Storage)
+
+// WithClock sets the clock used to time operations.
+func WithClock(clock token.Clock) Option {
+	return func(s *Storage) {
+		s.clock = clock
+	}
+}
+
+// Wrap returns s decorated to report every operation to r.
+func Wrap(s token.Storage, r Recorder, opts ...Option) *Storage {
+	m := &Storage{
+		storage:  s,
+		recorder: r,
+		clock:    token.SystemClock,
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m
+}
+
+// Store saves a token.
+func (s *Storage) Store(ctx context.Context, t *token.Token) error {
+	_, err := observe(ctx, s, "Store", func() (struct{}, error) {
+		return struct{}{}, s.storage.Store(ctx, t)
+	})
+
+	return err
+}
+
+// StoreBatch saves tokens with the wrapped storage's StoreBatch when it has
+// one, and with token.StoreEach otherwise.
+func (s *Storage) StoreBatch(ctx context.Context, tokens []*token.Token) error {
+	batch, ok := s.storage.(token.BatchStorage)
+	if !ok {
+		return token.StoreEach(ctx, tokens, s.Store)
+	}
+
+	_, err := observe(ctx, s, "StoreBatch", func() (struct{}, error) {
+		return struct{}{}, batch.StoreBatch(ctx, tokens)
+	})
+
+	return err
+}
+
+// Retrieve gets a token.
+func (s *Storage) Retrieve(ctx context.Context, tokenValue string, tokenType token.Type) (*token.Token, error) {
+	return observe(ctx, s, "Retrieve", func() (*token.Token, error) {
+		return s.storage.Retrieve(ctx, tokenValue, tokenType)
+	})
+}
+
+// Delete removes a token.
+func (s *Storage) Delete(ctx context.Context, tokenValue string, tokenType token.Type) error {
+	_, err := observe(ctx, s, "Delete", func() (struct{}, error) {
+		return struct{}{}, s.storage.Delete(ctx, tokenValue, tokenType)
+	})
+
+	return err
+}
+
+// DeleteBatch removes tokens with the wrapped storage's DeleteBatch when it
+// has one, and with token.DeleteEach otherwise.
+func (s *Storage) DeleteBatch(ctx context.Context, refs []token.TokenRef) error {
+	batch, ok := s.storage.(token.BatchStorage)
+	if !ok {
+		return token.DeleteEach(ctx, refs, s.Delete)
+	}
+
+	_, err := observe(ctx, s, "DeleteBatch", func() (struct{}, error) {
+		return struct{}{}, batch.DeleteBatch(ctx, refs)
+	})
+
+	return err
+}
+
+// DeleteByValidationID removes a validation's tokens.
+func (s *Storage) DeleteByValidationID(ctx context.Context, validationID string) error {
+	_, err := observe(ctx, s, "DeleteByValidationID", func() (struct{}, error) {
+		return struct{}{}, s.storage.DeleteByValidationID(ctx, validationID)
+	})
+
+	return err
+}
+
+// Consume atomically takes a token.
+func (s *Storage) Consume(ctx context.Context, tokenValue string, tokenType token.Type) (*token.Token, error) {
+	return observe(ctx, s, "Consume", func() (*token.Token, error) {
+		return s.storage.Consume(ctx, tokenValue, tokenType)
+	})
+}
+
+// IncrementAttempts counts a failed verification attempt.
+func (s *Storage) IncrementAttempts(ctx context.Context, validationID string, ttl time.Duration) (int, error) {
+	return observe(ctx, s, "IncrementAttempts", func() (int, error) {
+		return s.storage.IncrementAttempts(ctx, validationID, ttl)
+	})
+}
+
+// ExtendTTL extends a token's validity.
+func (s *Storage) ExtendTTL(ctx context.Context, tokenValue string, tokenType token.Type, extra time.Duration) (*token.Token, error) {
+	return observe(ctx, s, "ExtendTTL", func() (*token.Token, error) {
+		return s.storage.ExtendTTL(ctx, tokenValue, tokenType, extra)
+	})
+}
+
+// ListByValidationID lists a validation's tokens.
+func (s *Storage) ListByValidationID(ctx context.Context, validationID string) ([]*token.Token, error) {
+	return observe(ctx, s, "ListByValidationID", func() ([]*token.Token, error) {
+		return s.storage.ListByValidationID(ctx, validationID)
+	})
+}
+
+// observe calls fn and reports its duration and outcome.
+func observe[T any](ctx context.Context, s *Storage, op string, fn func() (T, error)) (T, error) {
+	start := s.clock.Now()
+	v, err := fn()
+	s.recorder.Observe(ctx, op, OutcomeOf(err), s.clock.Now().Sub(start))
+
+	return v, err
+}
+
+// OperationStats summarizes the observations of one operation.
+type OperationStats struct {
+	// Count is the number of calls by outcome.
+	Count map[Outcome]int64
+
+	// Total and Max are the summed and longest call durations.
+	Total time.Duration
+	Max   time.Duration
+}
+
+// Counters is a Recorder that keeps running totals in memory, for tests,
+// debug endpoints, and deployments without a metrics system.
+type Counters struct {
+	mu  sync.Mutex
+	ops map[string]*OperationStats
+}
+
+// NewCounters creates an empty Counters.
+func NewCounters() *Counters {
+	return &Counters{ops: make(map[string]*OperationStats)}
+}
+
+// Observe adds an observation to the totals of op.
+func (c *Counters) Observe(_ context.Context, op string, outcome Outcome, d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats, ok := c.ops[op]
+	if !ok {
+		stats = &OperationStats{Count: make(map[Outcome]int64)}
+		c.ops[op] = stats
+	}
+
+	stats.Count[outcome]++
+	stats.Total += d
+	stats.Max = max(stats.Max, d)
+}
+
+// Snapshot returns a copy of the totals of every operation observed so far.
+func (c *Counters) Snapshot() map[string]OperationStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	snapshot := make(map[string]OperationStats, len(c.ops))
+	for op, stats := range c.ops {
+		snapshot[op] = OperationStats{Count: maps.Clone(stats.Count), Total: stats.Total, Max: stats.Max}
+	}
+
+	return snapshot
+}
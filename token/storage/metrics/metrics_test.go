@@ -0,0 +1,92 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+	"github.com/jaeyeom/email-validator-grpc-mcp/token/storage/memory"
+	"github.com/jaeyeom/email-validator-grpc-mcp/token/storagetest"
+)
+
+func TestOutcomeOf(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		err  error
+		want Outcome
+	}{
+		{"nil", nil, OutcomeOK},
+		{"not found", token.ErrTokenNotFound, OutcomeNotFound},
+		{"expired", &token.TokenExpiredError{TokenValue: "x"}, OutcomeExpired},
+		{"invalid", token.ErrEmptyValidationID, OutcomeInvalid},
+		{"canceled", context.Canceled, OutcomeCanceled},
+		{"deadline", context.DeadlineExceeded, OutcomeCanceled},
+		{"backend", errors.New("connection refused"), OutcomeError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := OutcomeOf(tt.err); got != tt.want {
+				t.Errorf("OutcomeOf(%v) = %q, want %q", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStorage(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	now := time.Now()
+	backendClock := token.ClockFunc(func() time.Time { return now })
+	ticker := token.ClockFunc(func() time.Time {
+		now = now.Add(time.Millisecond)
+		return now
+	})
+	counters := NewCounters()
+	storage := Wrap(memory.New(memory.WithClock(backendClock)), counters, WithClock(ticker))
+
+	tkn := &token.Token{Value: "abc", Type: token.TypeLink, ValidUntil: now.Add(time.Minute), ValidationID: "validation-metrics"}
+	if err := storage.Store(ctx, tkn); err != nil {
+		t.Fatalf("Storage.Store() error = %v", err)
+	}
+	if _, err := storage.Retrieve(ctx, "abc", token.TypeLink); err != nil {
+		t.Fatalf("Storage.Retrieve() error = %v", err)
+	}
+	if _, err := storage.Retrieve(ctx, "missing", token.TypeLink); !errors.Is(err, token.ErrTokenNotFound) {
+		t.Fatalf("Storage.Retrieve() error = %v, want %v", err, token.ErrTokenNotFound)
+	}
+	now = now.Add(time.Hour)
+	if _, err := storage.Retrieve(ctx, "abc", token.TypeLink); !token.IsTokenExpiredError(err) {
+		t.Fatalf("Storage.Retrieve() error = %v, want TokenExpiredError", err)
+	}
+
+	snapshot := counters.Snapshot()
+	if got := snapshot["Store"].Count[OutcomeOK]; got != 1 {
+		t.Errorf("Store ok count = %d, want 1", got)
+	}
+	retrieve := snapshot["Retrieve"]
+	for outcome, want := range map[Outcome]int64{OutcomeOK: 1, OutcomeNotFound: 1, OutcomeExpired: 1} {
+		if got := retrieve.Count[outcome]; got != want {
+			t.Errorf("Retrieve %s count = %d, want %d", outcome, got, want)
+		}
+	}
+	// Each call spans one tick of the metrics clock
+	if retrieve.Total != 3*time.Millisecond || retrieve.Max != time.Millisecond {
+		t.Errorf("Retrieve durations = (%v, %v), want (3ms, 1ms)", retrieve.Total, retrieve.Max)
+	}
+}
+
+func TestStorage_Conformance(t *testing.T) {
+	t.Parallel()
+
+	storagetest.TestStorage(t, func() token.Storage {
+		return Wrap(memory.New(), NewCounters())
+	})
+}
@@ -0,0 +1,127 @@
+package redis
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+)
+
+// Approximate per-key memory overheads of Redis, in bytes: the main and
+// expires dictionary entries, the key and value objects, and allocator
+// rounding. They vary with the Redis version and allocator, so estimates
+// are for sizing, not exact accounting.
+const (
+	keyOverhead       = 72
+	setOverhead       = 48
+	setMemberOverhead = 24
+)
+
+// CapacityInput describes an expected workload.
+type CapacityInput struct {
+	// ValidationsPerSecond is the rate at which validations start.
+	ValidationsPerSecond float64
+
+	// TokensPerValidation is the average number of tokens issued per
+	// validation, including resends.
+	TokensPerValidation float64
+
+	// TokenTTL is how long tokens stay valid.
+	TokenTTL time.Duration
+
+	// VerificationsPerSecond is the rate of verification requests. Each
+	// reads a token record.
+	VerificationsPerSecond float64
+
+	// Sample is a representative token used to measure record sizes with
+	// the storage's codec. If nil, a link token with a 43-character value
+	// and no metadata is used.
+	Sample *token.Token
+}
+
+// CapacityPlan is the estimated steady-state footprint of a workload.
+type CapacityPlan struct {
+	// RecordBytes is the encoded size of one token record.
+	RecordBytes int
+
+	// LiveTokens and LiveValidations are the numbers of unexpired tokens and
+	// validation ID indexes held at steady state.
+	LiveTokens      int64
+	LiveValidations int64
+
+	// Keys is the number of Redis keys held at steady state.
+	Keys int64
+
+	// MemoryBytes is the estimated Redis memory used by those keys.
+	MemoryBytes int64
+
+	// WriteBytesPerSecond and ReadBytesPerSecond are the estimated token
+	// record traffic to and from Redis, excluding protocol overhead.
+	WriteBytesPerSecond float64
+	ReadBytesPerSecond  float64
+}
+
+// String formats the plan for operators.
+func (p CapacityPlan) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "record size:      %d bytes\n", p.RecordBytes)
+	fmt.Fprintf(&b, "live tokens:      %d\n", p.LiveTokens)
+	fmt.Fprintf(&b, "live validations: %d\n", p.LiveValidations)
+	fmt.Fprintf(&b, "keys:             %d\n", p.Keys)
+	fmt.Fprintf(&b, "memory:           %.1f MiB\n", float64(p.MemoryBytes)/(1<<20))
+	fmt.Fprintf(&b, "writes:           %.1f KiB/s\n", p.WriteBytesPerSecond/(1<<10))
+	fmt.Fprintf(&b, "reads:            %.1f KiB/s\n", p.ReadBytesPerSecond/(1<<10))
+
+	return b.String()
+}
+
+// EstimateCapacity estimates the Redis footprint of a workload stored with
+// the codec and key layout of s. Token records are measured by encoding
+// in.Sample; live counts follow from the rates and the TTL, as each token
+// and index lives about one TTL.
+func (s *Storage) EstimateCapacity(in CapacityInput) (CapacityPlan, error) {
+	if in.ValidationsPerSecond < 0 || in.TokensPerValidation < 0 || in.VerificationsPerSecond < 0 || in.TokenTTL < 0 {
+		return CapacityPlan{}, errors.New("capacity input must not be negative")
+	}
+
+	sample := in.Sample
+	if sample == nil {
+		now := time.Now()
+		sample = &token.Token{
+			Value:        strings.Repeat("x", 43),
+			Type:         token.TypeLink,
+			CreatedAt:    now,
+			ValidUntil:   now.Add(in.TokenTTL),
+			ValidationID: "val_00000000-0000-7000-8000-000000000000",
+		}
+	}
+
+	record, err := s.codec.Marshal(sample)
+	if err != nil {
+		return CapacityPlan{}, fmt.Errorf("failed to encode sample token: %w", err)
+	}
+
+	tokenKey := len(s.keys.token(sample.Value, sample.Type))
+	indexKey := len(s.keys.validation(sample.ValidationID))
+
+	ttl := in.TokenTTL.Seconds()
+	validations := int64(math.Ceil(in.ValidationsPerSecond * ttl))
+	tokens := int64(math.Ceil(in.ValidationsPerSecond * in.TokensPerValidation * ttl))
+
+	tokenBytes := int64(keyOverhead + tokenKey + len(record))
+	indexBytes := int64(keyOverhead + setOverhead + indexKey)
+	memberBytes := int64(setMemberOverhead + tokenKey)
+
+	return CapacityPlan{
+		RecordBytes:         len(record),
+		LiveTokens:          tokens,
+		LiveValidations:     validations,
+		Keys:                tokens + validations,
+		MemoryBytes:         tokens*(tokenBytes+memberBytes) + validations*indexBytes,
+		WriteBytesPerSecond: in.ValidationsPerSecond * in.TokensPerValidation * float64(len(record)+tokenKey),
+		ReadBytesPerSecond:  in.VerificationsPerSecond * float64(len(record)),
+	}, nil
+}
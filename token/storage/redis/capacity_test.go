@@ -0,0 +1,61 @@
+package redis
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+	"github.com/jaeyeom/email-validator-grpc-mcp/token/codec/protobuf"
+)
+
+func TestStorage_EstimateCapacity(t *testing.T) {
+	t.Parallel()
+
+	sample := &token.Token{
+		Value:        "sample-token-value",
+		Type:         token.TypeCode,
+		CreatedAt:    time.Unix(1700000000, 0),
+		ValidUntil:   time.Unix(1700000600, 0),
+		ValidationID: "validation-sample",
+	}
+	in := CapacityInput{
+		ValidationsPerSecond:   10,
+		TokensPerValidation:    1.5,
+		TokenTTL:               10 * time.Minute,
+		VerificationsPerSecond: 4,
+		Sample:                 sample,
+	}
+
+	storage := New(nil, WithKeyPrefix("ev:"))
+	plan, err := storage.EstimateCapacity(in)
+	if err != nil {
+		t.Fatalf("Storage.EstimateCapacity() error = %v", err)
+	}
+
+	record, _ := token.JSONCodec.Marshal(sample)
+	if plan.RecordBytes != len(record) {
+		t.Errorf("RecordBytes = %d, want %d", plan.RecordBytes, len(record))
+	}
+	if plan.LiveValidations != 6000 || plan.LiveTokens != 9000 || plan.Keys != 15000 {
+		t.Errorf("live validations/tokens/keys = %d/%d/%d, want 6000/9000/15000", plan.LiveValidations, plan.LiveTokens, plan.Keys)
+	}
+	if want := 4 * float64(len(record)); plan.ReadBytesPerSecond != want {
+		t.Errorf("ReadBytesPerSecond = %v, want %v", plan.ReadBytesPerSecond, want)
+	}
+	if floor := plan.LiveTokens * int64(len(record)); plan.MemoryBytes <= floor {
+		t.Errorf("MemoryBytes = %d, want more than the %d bytes of records alone", plan.MemoryBytes, floor)
+	}
+
+	compact, err := New(nil, WithKeyPrefix("ev:"), WithCodec(protobuf.New())).EstimateCapacity(in)
+	if err != nil {
+		t.Fatalf("Storage.EstimateCapacity() with protobuf error = %v", err)
+	}
+	if compact.MemoryBytes >= plan.MemoryBytes {
+		t.Errorf("protobuf MemoryBytes = %d, want less than JSON %d", compact.MemoryBytes, plan.MemoryBytes)
+	}
+
+	in.TokenTTL = -time.Second
+	if _, err := storage.EstimateCapacity(in); err == nil {
+		t.Error("Storage.EstimateCapacity() with negative TTL succeeded")
+	}
+}
@@ -0,0 +1,308 @@
+package redis
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+	"github.com/redis/go-redis/v9"
+)
+
+// setupMiniRedisCluster returns a cluster client for a miniredis server,
+// which presents itself as a single-node cluster owning every slot.
+func setupMiniRedisCluster(t *testing.T) (*miniredis.Miniredis, *redis.ClusterClient) {
+	t.Helper()
+
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("Failed to start miniredis: %v", err)
+	}
+
+	client := redis.NewClusterClient(&redis.ClusterOptions{
+		Addrs: []string{mr.Addr()},
+	})
+	t.Cleanup(func() { _ = client.Close() })
+
+	return mr, client
+}
+
+func TestStorage_ClusterClient(t *testing.T) {
+	t.Parallel()
+
+	mr, client := setupMiniRedisCluster(t)
+	defer mr.Close()
+
+	ctx := context.Background()
+	storage := New(client, WithKeyPrefix("ev:"), WithHashTags())
+
+	for _, value := range []string{"cluster-a", "cluster-b"} {
+		tkn := &token.Token{
+			Value:        value,
+			Type:         token.TypeLink,
+			ValidUntil:   time.Now().Add(time.Hour),
+			ValidationID: "validation-cluster",
+		}
+		if err := storage.Store(ctx, tkn); err != nil {
+			t.Fatalf("Storage.Store() error = %v", err)
+		}
+	}
+	if _, err := storage.IncrementAttempts(ctx, "validation-cluster", time.Hour); err != nil {
+		t.Fatalf("Storage.IncrementAttempts() error = %v", err)
+	}
+
+	indexKey := "ev:validation:{validation-cluster}"
+	attemptsKey := "ev:attempts:{validation-cluster}"
+	if !mr.Exists(indexKey) || !mr.Exists(attemptsKey) {
+		t.Fatalf("hash-tagged keys %q and %q do not both exist", indexKey, attemptsKey)
+	}
+	if a, b := keySlot(indexKey), keySlot(attemptsKey); a != b {
+		t.Errorf("index slot %d != attempts slot %d", a, b)
+	}
+
+	tokens, err := storage.ListByValidationID(ctx, "validation-cluster")
+	if err != nil || len(tokens) != 2 {
+		t.Fatalf("Storage.ListByValidationID() = %d tokens, %v, want 2", len(tokens), err)
+	}
+
+	if report, err := storage.CheckIndex(ctx); err != nil || report.TokensScanned != 2 || report.Drift() != 0 {
+		t.Errorf("Storage.CheckIndex() = %+v, %v, want two tokens and no drift", report, err)
+	}
+
+	if err := storage.DeleteByValidationID(ctx, "validation-cluster"); err != nil {
+		t.Fatalf("Storage.DeleteByValidationID() error = %v", err)
+	}
+	if keys := mr.Keys(); len(keys) != 0 {
+		t.Errorf("keys left after Storage.DeleteByValidationID() = %v", keys)
+	}
+}
+
+func TestStorage_MigrateToHashTags(t *testing.T) {
+	t.Parallel()
+
+	mr, client := setupMiniRedis(t)
+	defer mr.Close()
+
+	ctx := context.Background()
+	untagged := New(client, WithKeyPrefix("ev:"))
+
+	tkn := &token.Token{
+		Value:        "tagged",
+		Type:         token.TypeCode,
+		ValidUntil:   time.Now().Add(time.Hour),
+		ValidationID: "validation-tags",
+	}
+	if err := untagged.Store(ctx, tkn); err != nil {
+		t.Fatalf("Storage.Store() error = %v", err)
+	}
+	if _, err := untagged.IncrementAttempts(ctx, "validation-tags", time.Hour); err != nil {
+		t.Fatalf("Storage.IncrementAttempts() error = %v", err)
+	}
+
+	tagged := New(client, WithKeyPrefix("ev:"), WithHashTags())
+	report, err := tagged.MigrateKeyPrefix(ctx, "ev:")
+	if err != nil {
+		t.Fatalf("Storage.MigrateKeyPrefix() error = %v", err)
+	}
+	if want := (MigrationReport{Moved: 2}); report != want {
+		t.Errorf("Storage.MigrateKeyPrefix() = %+v, want %+v", report, want)
+	}
+
+	for _, key := range []string{"ev:token:tagged:1", "ev:validation:{validation-tags}", "ev:attempts:{validation-tags}"} {
+		if !mr.Exists(key) {
+			t.Errorf("key %q does not exist after migration", key)
+		}
+	}
+	if tokens, _ := tagged.ListByValidationID(ctx, "validation-tags"); len(tokens) != 1 {
+		t.Errorf("Storage.ListByValidationID() returned %d tokens, want 1", len(tokens))
+	}
+}
+
+// keySlot returns the Redis Cluster slot of key: the CRC16 of its hash tag,
+// or of the whole key if it has none. Miniredis answers CLUSTER KEYSLOT
+// with a constant, so tests compute slots themselves.
+func keySlot(key string) int {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			key = key[start+1 : start+1+end]
+		}
+	}
+
+	var crc uint16
+	for i := range len(key) {
+		crc ^= uint16(key[i]) << 8
+		for range 8 {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+
+	return int(crc) % 16384
+}
+
+// clusterShards are miniredis servers presented as a Redis Cluster whose
+// slots are split evenly between them, so that keys in different slots can
+// land on different servers as they do on a real cluster.
+type clusterShards struct {
+	servers []*miniredis.Miniredis
+	client  *redis.ClusterClient
+}
+
+// slotsPerShard is the number of slots each shard owns.
+const slotsPerShard = 16384 / 2
+
+func setupClusterShards(t *testing.T) *clusterShards {
+	t.Helper()
+
+	c := &clusterShards{}
+	var slots []redis.ClusterSlot
+	for i := range 2 {
+		mr := miniredis.RunT(t)
+		c.servers = append(c.servers, mr)
+		slots = append(slots, redis.ClusterSlot{
+			Start: i * slotsPerShard,
+			End:   (i+1)*slotsPerShard - 1,
+			Nodes: []redis.ClusterNode{{Addr: mr.Addr()}},
+		})
+	}
+
+	c.client = redis.NewClusterClient(&redis.ClusterOptions{
+		ClusterSlots: func(context.Context) ([]redis.ClusterSlot, error) {
+			return slots, nil
+		},
+	})
+	t.Cleanup(func() { _ = c.client.Close() })
+
+	return c
+}
+
+// shard returns the index of the server owning key.
+func (c *clusterShards) shard(key string) int {
+	return keySlot(key) / slotsPerShard
+}
+
+// checkOwners fails the test if any server holds a key of a slot it does
+// not own, which a multi-key command sent to the wrong server would leave.
+func (c *clusterShards) checkOwners(t *testing.T) {
+	t.Helper()
+
+	for i, mr := range c.servers {
+		for _, key := range mr.Keys() {
+			if owner := c.shard(key); owner != i {
+				t.Errorf("key %q is on shard %d, want shard %d", key, i, owner)
+			}
+		}
+	}
+}
+
+// keyCount returns the number of keys on all servers.
+func (c *clusterShards) keyCount() int {
+	n := 0
+	for _, mr := range c.servers {
+		n += len(mr.Keys())
+	}
+
+	return n
+}
+
+func TestStorage_MultiSlotCluster(t *testing.T) {
+	t.Parallel()
+
+	shards := setupClusterShards(t)
+	ctx := context.Background()
+	storage := New(shards.client, WithHashTags())
+
+	const validationID = "validation-shards"
+	now := time.Now()
+	tokens := []*token.Token{
+		{Value: "link-a", Type: token.TypeLink, ValidUntil: now.Add(time.Hour), ValidationID: validationID},
+		{Value: "code-b", Type: token.TypeCode, ValidUntil: now.Add(10 * time.Minute), ValidationID: validationID},
+	}
+	indexKey := storage.keys.validation(validationID)
+	split := false
+	for _, tkn := range tokens {
+		split = split || shards.shard(storage.keys.token(tkn.Value, tkn.Type)) != shards.shard(indexKey)
+	}
+	if !split {
+		t.Fatal("no token key hashes to another shard than the index; pick other test values")
+	}
+
+	if err := storage.StoreBatch(ctx, tokens); err != nil {
+		t.Fatalf("Storage.StoreBatch() error = %v", err)
+	}
+	if _, err := storage.IncrementAttempts(ctx, validationID, time.Hour); err != nil {
+		t.Fatalf("Storage.IncrementAttempts() error = %v", err)
+	}
+
+	// Extending a token lengthens the index on its own shard
+	extended, err := storage.ExtendTTL(ctx, "code-b", token.TypeCode, 2*time.Hour)
+	if err != nil {
+		t.Fatalf("Storage.ExtendTTL() error = %v", err)
+	}
+	if ttl := shards.client.TTL(ctx, indexKey).Val(); ttl < time.Until(extended.ValidUntil)-time.Minute {
+		t.Errorf("index TTL after Storage.ExtendTTL() = %v, want about %v", ttl, time.Until(extended.ValidUntil))
+	}
+
+	if got, err := storage.ListByValidationID(ctx, validationID); err != nil || len(got) != 2 {
+		t.Errorf("Storage.ListByValidationID() = %d tokens, %v, want 2", len(got), err)
+	}
+	if report, err := storage.CheckIndex(ctx); err != nil || report.TokensScanned != 2 || report.Drift() != 0 {
+		t.Errorf("Storage.CheckIndex() = %+v, %v, want two tokens and no drift", report, err)
+	}
+	shards.checkOwners(t)
+
+	if err := storage.DeleteBatch(ctx, []token.TokenRef{{Value: "link-a", Type: token.TypeLink}}); err != nil {
+		t.Fatalf("Storage.DeleteBatch() error = %v", err)
+	}
+	if err := storage.DeleteByValidationID(ctx, validationID); err != nil {
+		t.Fatalf("Storage.DeleteByValidationID() error = %v", err)
+	}
+	if n := shards.keyCount(); n != 0 {
+		t.Errorf("%d keys left after Storage.DeleteByValidationID()", n)
+	}
+}
+
+func TestStorage_MultiSlotMigration(t *testing.T) {
+	t.Parallel()
+
+	shards := setupClusterShards(t)
+	ctx := context.Background()
+	untagged := New(shards.client)
+
+	for _, value := range []string{"migrate-a", "migrate-b", "migrate-c"} {
+		tkn := &token.Token{
+			Value:        value,
+			Type:         token.TypeLink,
+			ValidUntil:   time.Now().Add(time.Hour),
+			ValidationID: "validation-migrate",
+		}
+		if err := untagged.Store(ctx, tkn); err != nil {
+			t.Fatalf("Storage.Store() error = %v", err)
+		}
+	}
+
+	tagged := New(shards.client, WithKeyPrefix("ev:"), WithHashTags())
+	report, err := tagged.MigrateKeyPrefix(ctx, "")
+	if err != nil {
+		t.Fatalf("Storage.MigrateKeyPrefix() error = %v", err)
+	}
+	if want := (MigrationReport{Moved: 4}); report != want {
+		t.Errorf("Storage.MigrateKeyPrefix() = %+v, want %+v", report, want)
+	}
+	shards.checkOwners(t)
+
+	if n := shards.keyCount(); n != 4 {
+		t.Errorf("%d keys after migration, want 4", n)
+	}
+	if ttl := shards.client.TTL(ctx, "ev:token:migrate-a:0").Val(); ttl <= 0 {
+		t.Errorf("migrated token TTL = %v, want its expiry kept", ttl)
+	}
+	if tokens, _ := tagged.ListByValidationID(ctx, "validation-migrate"); len(tokens) != 3 {
+		t.Errorf("Storage.ListByValidationID() returned %d tokens, want 3", len(tokens))
+	}
+}
@@ -0,0 +1,300 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+	"github.com/redis/go-redis/v9"
+)
+
+// scanBatchSize is the COUNT hint used when scanning keys.
+const scanBatchSize = 500
+
+// DefaultIndexRepairInterval is the suggested interval of RunIndexRepair.
+const DefaultIndexRepairInterval = 7 * 24 * time.Hour
+
+// IndexReport describes drift between the validation ID index and the token
+// records it is derived from.
+type IndexReport struct {
+	// TokensScanned is the number of token records read.
+	TokensScanned int
+
+	// MissingEntries counts tokens absent from their validation's index.
+	MissingEntries int
+
+	// StaleEntries counts index entries whose token no longer exists or
+	// belongs to another validation.
+	StaleEntries int
+
+	// UnreadableRecords counts token records the storage's codec could not
+	// decode, such as those written with another codec. Their index entries
+	// are left alone.
+	UnreadableRecords int
+}
+
+// Drift returns the total number of index entries that are wrong.
+func (r IndexReport) Drift() int {
+	return r.MissingEntries + r.StaleEntries
+}
+
+// CheckIndex compares the validation ID index against the token records
+// without modifying anything. It scans the whole keyspace, so run it off
+// the request path.
+func (s *Storage) CheckIndex(ctx context.Context) (IndexReport, error) {
+	return s.reconcileIndex(ctx, false)
+}
+
+// RepairIndex rebuilds the validation ID index from the token records,
+// adding missing entries and removing stale ones. Indexes that gain entries
+// have their expiry lengthened to that of their longest-lived token. It
+// returns the drift found before the repair.
+func (s *Storage) RepairIndex(ctx context.Context) (IndexReport, error) {
+	return s.reconcileIndex(ctx, true)
+}
+
+// IndexDrift returns the total drift found by CheckIndex and RepairIndex
+// since the storage was created, for export as a counter metric. A rising
+// count means index writes are failing or racing.
+func (s *Storage) IndexDrift() int64 {
+	return s.indexDrift.Load()
+}
+
+// RunIndexRepair runs RepairIndex every interval, such as
+// DefaultIndexRepairInterval, until ctx is done, and returns the context's
+// error. A failed pass is logged and retried at the next interval.
+func (s *Storage) RunIndexRepair(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("context error: %w", ctx.Err())
+		case <-ticker.C:
+			if _, err := s.RepairIndex(ctx); err != nil && ctx.Err() == nil {
+				s.logger.Error("validation ID index repair failed", "error", err)
+			}
+		}
+	}
+}
+
+// reconcileIndex measures index drift and optionally repairs it.
+func (s *Storage) reconcileIndex(ctx context.Context, repair bool) (IndexReport, error) {
+	if err := ctx.Err(); err != nil {
+		return IndexReport{}, fmt.Errorf("context error: %w", err)
+	}
+
+	var report IndexReport
+
+	// Derive the expected index from the token records
+	want := make(map[string]map[string]bool)
+	expiry := make(map[string]time.Time)
+
+	err := s.scan(ctx, s.keys.pattern(tokenKeyPrefix), func(keys []string) error {
+		values, err := s.getMany(ctx, keys)
+		if err != nil {
+			return fmt.Errorf("failed to read tokens: %w", err)
+		}
+
+		for i, value := range values {
+			data, ok := value.(string)
+			if !ok {
+				continue
+			}
+
+			var t token.Token
+			if err := s.decode([]byte(data), keys[i], &t); err != nil {
+				report.UnreadableRecords++
+				s.logger.Warn("skipping unreadable token record", "error", err)
+				continue
+			}
+
+			report.TokensScanned++
+			if want[t.ValidationID] == nil {
+				want[t.ValidationID] = make(map[string]bool)
+			}
+			want[t.ValidationID][keys[i]] = true
+			if t.ValidUntil.After(expiry[t.ValidationID]) {
+				expiry[t.ValidationID] = t.ValidUntil
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+
+	// Compare with the existing index
+	seen := make(map[string]bool)
+	err = s.scan(ctx, s.keys.pattern(validationKeyPrefix), func(keys []string) error {
+		for _, indexKey := range keys {
+			validationID := s.keys.validationID(indexKey, validationKeyPrefix)
+			seen[validationID] = true
+
+			members, err := s.client.SMembers(ctx, indexKey).Result()
+			if err != nil {
+				return fmt.Errorf("failed to read validation ID index: %w", err)
+			}
+
+			var candidates []string
+			for _, member := range members {
+				if !want[validationID][member] {
+					candidates = append(candidates, member)
+				}
+			}
+
+			stale, err := s.confirmStale(ctx, validationID, candidates)
+			if err != nil {
+				return err
+			}
+			report.StaleEntries += len(stale)
+
+			if repair && len(stale) > 0 {
+				if err := s.client.SRem(ctx, indexKey, stale...).Err(); err != nil {
+					return fmt.Errorf("failed to remove stale index entries: %w", err)
+				}
+			}
+
+			if err := s.addMissing(ctx, &report, repair, validationID, members, want[validationID], expiry[validationID]); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+
+	// Validations whose index set is missing entirely
+	for validationID, keys := range want {
+		if seen[validationID] {
+			continue
+		}
+		if err := s.addMissing(ctx, &report, repair, validationID, nil, keys, expiry[validationID]); err != nil {
+			return report, err
+		}
+	}
+
+	s.indexDrift.Add(int64(report.Drift()))
+
+	s.logger.Info("validation ID index checked",
+		"tokens_scanned", report.TokensScanned,
+		"missing_entries", report.MissingEntries,
+		"stale_entries", report.StaleEntries,
+		"unreadable_records", report.UnreadableRecords,
+		"repaired", repair)
+
+	return report, nil
+}
+
+// confirmStale re-reads candidate token keys and returns those that still
+// do not belong to the validation, so tokens stored while the scan was
+// running are not dropped from the index. Records that cannot be decoded
+// are not stale: the codec may be misconfigured.
+func (s *Storage) confirmStale(ctx context.Context, validationID string, candidates []string) ([]any, error) {
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	values, err := s.getMany(ctx, candidates)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tokens: %w", err)
+	}
+
+	var stale []any
+	for i, value := range values {
+		if data, ok := value.(string); ok {
+			var t token.Token
+			if err := s.decode([]byte(data), candidates[i], &t); err != nil || t.ValidationID == validationID {
+				continue
+			}
+		}
+		stale = append(stale, candidates[i])
+	}
+
+	return stale, nil
+}
+
+// addMissing counts, and when repairing adds, token keys absent from a
+// validation's index.
+func (s *Storage) addMissing(ctx context.Context, report *IndexReport, repair bool, validationID string, members []string, want map[string]bool, expiresAt time.Time) error {
+	present := make(map[string]bool, len(members))
+	for _, member := range members {
+		present[member] = true
+	}
+
+	var missing []any
+	for key := range want {
+		if !present[key] {
+			missing = append(missing, key)
+		}
+	}
+	report.MissingEntries += len(missing)
+
+	if !repair || len(missing) == 0 {
+		return nil
+	}
+
+	indexKey := s.keys.validation(validationID)
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.SAdd(ctx, indexKey, missing...)
+		expireIndex(ctx, pipe, indexKey, expiresAt.Sub(s.clock.Now()))
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to repair validation ID index: %w", err)
+	}
+
+	return nil
+}
+
+// scan calls fn with batches of keys matching pattern. On Redis Cluster
+// every master is scanned; fn is never called concurrently.
+func (s *Storage) scan(ctx context.Context, pattern string, fn func(keys []string) error) error {
+	cluster, ok := s.client.(*redis.ClusterClient)
+	if !ok {
+		return scanNode(ctx, s.client, pattern, fn)
+	}
+
+	var mu sync.Mutex
+	err := cluster.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+		return scanNode(ctx, node, pattern, func(keys []string) error {
+			mu.Lock()
+			defer mu.Unlock()
+
+			return fn(keys)
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to scan cluster: %w", err)
+	}
+
+	return nil
+}
+
+// scanNode calls fn with batches of keys matching pattern on one server.
+func scanNode(ctx context.Context, client redis.Cmdable, pattern string, fn func(keys []string) error) error {
+	var cursor uint64
+	for {
+		keys, next, err := client.Scan(ctx, cursor, pattern, scanBatchSize).Result()
+		if err != nil {
+			return fmt.Errorf("failed to scan %q: %w", pattern, err)
+		}
+
+		if len(keys) > 0 {
+			if err := fn(keys); err != nil {
+				return err
+			}
+		}
+
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
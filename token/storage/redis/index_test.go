@@ -0,0 +1,98 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+)
+
+func TestStorage_RepairIndex(t *testing.T) {
+	t.Parallel()
+
+	mr, client := setupMiniRedis(t)
+	defer mr.Close()
+
+	ctx := context.Background()
+	storage := New(client)
+
+	for _, value := range []string{"index-a", "index-b"} {
+		tkn := &token.Token{
+			Value:        value,
+			Type:         token.TypeLink,
+			ValidUntil:   time.Now().Add(time.Hour),
+			ValidationID: "validation-index",
+		}
+		if err := storage.Store(ctx, tkn); err != nil {
+			t.Fatalf("Storage.Store() error = %v", err)
+		}
+	}
+
+	// Corrupt the index: drop one entry and add a dangling one
+	client.SRem(ctx, "validation:validation-index", "token:index-a:0")
+	client.SAdd(ctx, "validation:validation-index", "token:gone:0")
+	client.SAdd(ctx, "validation:validation-orphan", "token:gone:1")
+
+	report, err := storage.CheckIndex(ctx)
+	if err != nil {
+		t.Fatalf("Storage.CheckIndex() error = %v", err)
+	}
+	want := IndexReport{TokensScanned: 2, MissingEntries: 1, StaleEntries: 2}
+	if report != want {
+		t.Errorf("Storage.CheckIndex() = %+v, want %+v", report, want)
+	}
+
+	// Checking does not modify the index
+	if again, _ := storage.CheckIndex(ctx); again != want {
+		t.Errorf("second Storage.CheckIndex() = %+v, want %+v", again, want)
+	}
+
+	if report, err := storage.RepairIndex(ctx); err != nil || report != want {
+		t.Fatalf("Storage.RepairIndex() = %+v, %v, want %+v", report, err, want)
+	}
+
+	if report, _ := storage.CheckIndex(ctx); report.Drift() != 0 {
+		t.Errorf("Storage.CheckIndex() after repair = %+v, want no drift", report)
+	}
+	if mr.Exists("validation:validation-orphan") {
+		t.Error("orphaned validation index still exists after repair")
+	}
+	if drift := storage.IndexDrift(); drift != 3*int64(want.Drift()) {
+		t.Errorf("Storage.IndexDrift() = %d, want %d", drift, 3*want.Drift())
+	}
+
+	if err := storage.DeleteByValidationID(ctx, "validation-index"); err != nil {
+		t.Fatalf("Storage.DeleteByValidationID() error = %v", err)
+	}
+	if _, err := storage.Retrieve(ctx, "index-a", token.TypeLink); err != token.ErrTokenNotFound {
+		t.Errorf("Storage.Retrieve() after delete error = %v, want %v", err, token.ErrTokenNotFound)
+	}
+}
+
+func TestStorage_RepairIndexUnreadable(t *testing.T) {
+	t.Parallel()
+
+	mr, client := setupMiniRedis(t)
+	defer mr.Close()
+
+	ctx := context.Background()
+	storage := New(client)
+
+	// A record written with another codec is neither indexed nor dropped
+	if err := mr.Set("token:foreign:0", "\x01not json"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	client.SAdd(ctx, "validation:validation-foreign", "token:foreign:0")
+
+	report, err := storage.RepairIndex(ctx)
+	if err != nil {
+		t.Fatalf("Storage.RepairIndex() error = %v", err)
+	}
+	if want := (IndexReport{UnreadableRecords: 1}); report != want {
+		t.Errorf("Storage.RepairIndex() = %+v, want %+v", report, want)
+	}
+	if ok, _ := mr.SIsMember("validation:validation-foreign", "token:foreign:0"); !ok {
+		t.Error("index entry of unreadable record removed by repair")
+	}
+}
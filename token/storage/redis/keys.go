@@ -0,0 +1,107 @@
+package redis
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+)
+
+// Key prefixes for the records kept in Redis, relative to the namespace.
+const (
+	tokenKeyPrefix      = "token:"
+	validationKeyPrefix = "validation:"
+	attemptsKeyPrefix   = "attempts:"
+	revokedKeyPrefix    = "revoked:"
+)
+
+// recordPrefixes lists the prefixes of every kind of record.
+var recordPrefixes = []string{tokenKeyPrefix, validationKeyPrefix, attemptsKeyPrefix, revokedKeyPrefix}
+
+// keyspace builds keys within a namespace, so several services or
+// environments can share one Redis instance.
+type keyspace struct {
+	prefix string
+
+	// hashTags wraps validation IDs in Redis Cluster hash tags, so that the
+	// keys of one validation hash to the same slot.
+	hashTags bool
+}
+
+// tag returns validationID as it appears in keys.
+func (k keyspace) tag(validationID string) string {
+	if k.hashTags {
+		return "{" + validationID + "}"
+	}
+
+	return validationID
+}
+
+// untag returns the validation ID from a key suffix written with or without
+// hash tags.
+func untag(suffix string) string {
+	if inner, ok := strings.CutPrefix(suffix, "{"); ok {
+		if id, ok := strings.CutSuffix(inner, "}"); ok {
+			return id
+		}
+	}
+
+	return suffix
+}
+
+// validationID returns the validation ID of an index or attempts key with
+// the given record prefix.
+func (k keyspace) validationID(key, recordPrefix string) string {
+	return untag(strings.TrimPrefix(key, k.prefix+recordPrefix))
+}
+
+// token returns the key holding a serialized token.
+func (k keyspace) token(tokenValue string, tokenType token.Type) string {
+	return fmt.Sprintf("%s%s%s:%d", k.prefix, tokenKeyPrefix, tokenValue, tokenType)
+}
+
+// record returns key relative to the namespace, which stays the same when
+// records are moved to another prefix.
+func (k keyspace) record(key string) string {
+	return strings.TrimPrefix(key, k.prefix)
+}
+
+// validation returns the key of the set indexing a validation's tokens.
+func (k keyspace) validation(validationID string) string {
+	return k.prefix + validationKeyPrefix + k.tag(validationID)
+}
+
+// attempts returns the key counting failed attempts for a validation.
+func (k keyspace) attempts(validationID string) string {
+	return k.prefix + attemptsKeyPrefix + k.tag(validationID)
+}
+
+// revoked returns the key marking a revoked token digest.
+func (k keyspace) revoked(digest string) string {
+	return k.prefix + revokedKeyPrefix + digest
+}
+
+// pattern returns a SCAN pattern matching all records with the given
+// record prefix.
+func (k keyspace) pattern(recordPrefix string) string {
+	return k.prefix + recordPrefix + "*"
+}
+
+// rename returns the key in k of a record stored under key in old, or "" if
+// key is not a record of old.
+func (k keyspace) rename(old keyspace, key string) string {
+	for _, recordPrefix := range recordPrefixes {
+		suffix, ok := strings.CutPrefix(key, old.prefix+recordPrefix)
+		if !ok {
+			continue
+		}
+
+		if recordPrefix == validationKeyPrefix || recordPrefix == attemptsKeyPrefix {
+			suffix = k.tag(untag(suffix))
+		}
+
+		return k.prefix + recordPrefix + suffix
+	}
+
+	return ""
+}
@@ -0,0 +1,123 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// MaintenanceReport describes the work done by one Maintenance pass.
+type MaintenanceReport struct {
+	// IndexesScanned is the number of validation ID indexes read.
+	IndexesScanned int
+
+	// MembersScanned is the number of index entries checked.
+	MembersScanned int
+
+	// DanglingRemoved counts index entries removed because their token key
+	// had expired or been deleted.
+	DanglingRemoved int
+
+	// IndexesRemoved counts indexes that were left empty and so removed.
+	IndexesRemoved int
+}
+
+// Maintenance removes validation ID index entries whose token keys have
+// already expired or been deleted, which happens when tokens expire by TTL
+// before their index does. Unlike RepairIndex it only reads the indexes,
+// not every token, so it is cheap enough to run on a schedule; see
+// RunMaintenance.
+func (s *Storage) Maintenance(ctx context.Context) (MaintenanceReport, error) {
+	if err := ctx.Err(); err != nil {
+		return MaintenanceReport{}, fmt.Errorf("context error: %w", err)
+	}
+
+	var report MaintenanceReport
+	err := s.scan(ctx, s.keys.pattern(validationKeyPrefix), func(keys []string) error {
+		for _, indexKey := range keys {
+			if err := s.pruneIndex(ctx, &report, indexKey); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+
+	s.logger.Info("redis maintenance completed",
+		"indexes_scanned", report.IndexesScanned,
+		"members_scanned", report.MembersScanned,
+		"dangling_removed", report.DanglingRemoved,
+		"indexes_removed", report.IndexesRemoved)
+
+	return report, nil
+}
+
+// pruneIndex removes the entries of one index whose token keys are gone.
+func (s *Storage) pruneIndex(ctx context.Context, report *MaintenanceReport, indexKey string) error {
+	members, err := s.client.SMembers(ctx, indexKey).Result()
+	if err != nil {
+		return fmt.Errorf("failed to read validation ID index: %w", err)
+	}
+	report.IndexesScanned++
+	report.MembersScanned += len(members)
+
+	if len(members) == 0 {
+		return nil
+	}
+
+	exists := make([]*redis.IntCmd, len(members))
+	_, err = s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, member := range members {
+			exists[i] = pipe.Exists(ctx, member)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to check indexed tokens: %w", err)
+	}
+
+	var dangling []any
+	for i, cmd := range exists {
+		if cmd.Val() == 0 {
+			dangling = append(dangling, members[i])
+		}
+	}
+	if len(dangling) == 0 {
+		return nil
+	}
+
+	// A set left without members is deleted by Redis
+	if err := s.client.SRem(ctx, indexKey, dangling...).Err(); err != nil {
+		return fmt.Errorf("failed to remove dangling index entries: %w", err)
+	}
+	report.DanglingRemoved += len(dangling)
+	if len(dangling) == len(members) {
+		report.IndexesRemoved++
+	}
+
+	return nil
+}
+
+// RunMaintenance runs Maintenance every interval until ctx is done, and
+// returns the context's error. A failed pass is logged and retried at the
+// next interval.
+func (s *Storage) RunMaintenance(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("context error: %w", ctx.Err())
+		case <-ticker.C:
+			if _, err := s.Maintenance(ctx); err != nil && ctx.Err() == nil {
+				s.logger.Error("redis maintenance failed", "error", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+)
+
+func TestStorage_Maintenance(t *testing.T) {
+	t.Parallel()
+
+	mr, client := setupMiniRedis(t)
+	defer mr.Close()
+
+	ctx := context.Background()
+	storage := New(client)
+
+	// validation-short loses one token by TTL; validation-gone loses all
+	for _, tkn := range []*token.Token{
+		{Value: "expire", Type: token.TypeCode, ValidUntil: time.Now().Add(time.Minute), ValidationID: "validation-short"},
+		{Value: "keep", Type: token.TypeLink, ValidUntil: time.Now().Add(time.Hour), ValidationID: "validation-short"},
+		{Value: "gone", Type: token.TypeCode, ValidUntil: time.Now().Add(time.Hour), ValidationID: "validation-gone"},
+	} {
+		if err := storage.Store(ctx, tkn); err != nil {
+			t.Fatalf("Storage.Store() error = %v", err)
+		}
+	}
+	mr.FastForward(2 * time.Minute)
+	mr.Del("token:gone:1")
+
+	report, err := storage.Maintenance(ctx)
+	if err != nil {
+		t.Fatalf("Storage.Maintenance() error = %v", err)
+	}
+	want := MaintenanceReport{IndexesScanned: 2, MembersScanned: 3, DanglingRemoved: 2, IndexesRemoved: 1}
+	if report != want {
+		t.Errorf("Storage.Maintenance() = %+v, want %+v", report, want)
+	}
+
+	if members, _ := client.SMembers(ctx, "validation:validation-short").Result(); len(members) != 1 || members[0] != "token:keep:0" {
+		t.Errorf("validation-short index = %v, want [token:keep:0]", members)
+	}
+	if mr.Exists("validation:validation-gone") {
+		t.Error("empty validation-gone index still exists")
+	}
+
+	// A second pass finds nothing to do
+	if report, _ := storage.Maintenance(ctx); report.DanglingRemoved != 0 {
+		t.Errorf("second Storage.Maintenance() = %+v, want nothing removed", report)
+	}
+}
+
+func TestStorage_RunMaintenance(t *testing.T) {
+	t.Parallel()
+
+	mr, client := setupMiniRedis(t)
+	defer mr.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	storage := New(client)
+
+	client.SAdd(ctx, "validation:validation-run", "token:missing:0")
+
+	done := make(chan error, 1)
+	go func() { done <- storage.RunMaintenance(ctx, 5*time.Millisecond) }()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for mr.Exists("validation:validation-run") {
+		if time.Now().After(deadline) {
+			t.Fatal("RunMaintenance() did not remove the dangling index")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("RunMaintenance() error = %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,199 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// MigrationReport describes the keys moved by MigrateKeyPrefix.
+type MigrationReport struct {
+	// Moved is the number of keys renamed into the new namespace.
+	Moved int
+
+	// Skipped counts keys left in place because a key with the new name
+	// already exists.
+	Skipped int
+}
+
+// MigrateKeyPrefix moves the records written under oldPrefix, such as ""
+// for keys like "token:*" written before WithKeyPrefix was configured, into
+// the namespace and key layout of s. It also adds or removes hash tags when
+// WithHashTags has been turned on or off, even if the prefix is unchanged.
+// Keys keep their expiry, and validation ID indexes are rewritten to refer
+// to the new token keys. Keys whose new name is already taken are skipped
+// rather than overwritten.
+//
+// Records written concurrently under the old prefix may be missed, so run
+// it while writers using the old prefix are stopped. It is safe to run
+// again to pick up stragglers.
+func (s *Storage) MigrateKeyPrefix(ctx context.Context, oldPrefix string) (MigrationReport, error) {
+	if err := ctx.Err(); err != nil {
+		return MigrationReport{}, fmt.Errorf("context error: %w", err)
+	}
+
+	var report MigrationReport
+	old := keyspace{prefix: oldPrefix}
+	for _, recordPrefix := range recordPrefixes {
+		err := s.scan(ctx, old.pattern(recordPrefix), func(keys []string) error {
+			for _, key := range keys {
+				newKey := s.keys.rename(old, key)
+				if newKey == key {
+					continue
+				}
+
+				var result moveResult
+				var err error
+				if recordPrefix == validationKeyPrefix {
+					result, err = s.moveIndex(ctx, old, key, newKey)
+				} else {
+					result, err = s.moveKey(ctx, key, newKey)
+				}
+				if err != nil {
+					return err
+				}
+
+				switch result {
+				case moved:
+					report.Moved++
+				case skipped:
+					report.Skipped++
+				case gone:
+				}
+			}
+
+			return nil
+		})
+		if err != nil {
+			return report, err
+		}
+	}
+
+	s.logger.Info("key prefix migrated",
+		"old_prefix", oldPrefix,
+		"new_prefix", s.keys.prefix,
+		"moved", report.Moved,
+		"skipped", report.Skipped)
+
+	return report, nil
+}
+
+// moveResult is the outcome of moving one key.
+type moveResult int
+
+const (
+	moved   moveResult = iota // the key was renamed
+	skipped                   // the new name was taken
+	gone                      // the key expired or was deleted meanwhile
+)
+
+// moveKey renames key to newKey unless newKey exists. RENAMENX keeps the
+// expiry of the key. On Redis Cluster, the two keys generally hash to
+// different slots, where RENAMENX fails, so the key is copied instead.
+func (s *Storage) moveKey(ctx context.Context, key, newKey string) (moveResult, error) {
+	if _, ok := s.client.(*redis.ClusterClient); ok {
+		return s.copyKey(ctx, key, newKey)
+	}
+
+	ok, err := s.client.RenameNX(ctx, key, newKey).Result()
+	if err == nil {
+		if ok {
+			return moved, nil
+		}
+		return skipped, nil
+	}
+
+	// RENAMENX fails if the key expired after it was scanned
+	if n, existsErr := s.client.Exists(ctx, key).Result(); existsErr == nil && n == 0 {
+		return gone, nil
+	}
+
+	return skipped, fmt.Errorf("failed to move key %s: %w", key, err)
+}
+
+// copyKey moves the string record key to newKey unless newKey exists, by
+// copying its value and expiry and then deleting it, so that the keys may
+// be in different cluster slots.
+func (s *Storage) copyKey(ctx context.Context, key, newKey string) (moveResult, error) {
+	value, err := s.client.Get(ctx, key).Result()
+	if err == redis.Nil {
+		return gone, nil
+	}
+	if err != nil {
+		return skipped, fmt.Errorf("failed to read key %s: %w", key, err)
+	}
+
+	ttl, err := s.client.PTTL(ctx, key).Result()
+	if err != nil {
+		return skipped, fmt.Errorf("failed to read expiry of key %s: %w", key, err)
+	}
+	if ttl == -2*time.Nanosecond {
+		// The key expired after it was read
+		return gone, nil
+	}
+
+	// A negative TTL means the key has no expiry, which SET NX keeps
+	ok, err := s.client.SetNX(ctx, newKey, value, max(ttl, 0)).Result()
+	if err != nil {
+		return skipped, fmt.Errorf("failed to copy key %s: %w", key, err)
+	}
+	if !ok {
+		return skipped, nil
+	}
+
+	if err := s.client.Del(ctx, key).Err(); err != nil {
+		return moved, fmt.Errorf("failed to delete moved key %s: %w", key, err)
+	}
+
+	return moved, nil
+}
+
+// moveIndex moves a validation ID index to newKey unless newKey exists,
+// rewriting its members from token keys under old to token keys under the
+// namespace of s.
+func (s *Storage) moveIndex(ctx context.Context, old keyspace, key, newKey string) (moveResult, error) {
+	exists, err := s.client.Exists(ctx, newKey).Result()
+	if err != nil {
+		return skipped, fmt.Errorf("failed to check validation ID index %s: %w", newKey, err)
+	}
+	if exists > 0 {
+		return skipped, nil
+	}
+
+	members, err := s.client.SMembers(ctx, key).Result()
+	if err != nil {
+		return skipped, fmt.Errorf("failed to read validation ID index %s: %w", key, err)
+	}
+	if len(members) == 0 {
+		return gone, nil
+	}
+
+	ttl, err := s.client.PTTL(ctx, key).Result()
+	if err != nil {
+		return skipped, fmt.Errorf("failed to read expiry of validation ID index %s: %w", key, err)
+	}
+
+	rewritten := make([]any, 0, len(members))
+	for _, member := range members {
+		if renamed := s.keys.rename(old, member); renamed != "" {
+			member = renamed
+		}
+		rewritten = append(rewritten, member)
+	}
+
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.SAdd(ctx, newKey, rewritten...)
+		if ttl > 0 {
+			pipe.PExpire(ctx, newKey, ttl)
+		}
+		pipe.Del(ctx, key)
+		return nil
+	})
+	if err != nil {
+		return skipped, fmt.Errorf("failed to move validation ID index %s: %w", key, err)
+	}
+
+	return moved, nil
+}
@@ -0,0 +1,121 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+)
+
+func TestStorage_WithKeyPrefix(t *testing.T) {
+	t.Parallel()
+
+	mr, client := setupMiniRedis(t)
+	defer mr.Close()
+
+	ctx := context.Background()
+	a := New(client, WithKeyPrefix("service-a:"))
+	b := New(client, WithKeyPrefix("service-b:"))
+
+	tkn := &token.Token{
+		Value:        "shared-value",
+		Type:         token.TypeLink,
+		ValidUntil:   time.Now().Add(time.Hour),
+		ValidationID: "validation-shared",
+	}
+	if err := a.Store(ctx, tkn); err != nil {
+		t.Fatalf("Storage.Store() error = %v", err)
+	}
+	if _, err := a.IncrementAttempts(ctx, "validation-shared", time.Hour); err != nil {
+		t.Fatalf("Storage.IncrementAttempts() error = %v", err)
+	}
+
+	for _, key := range []string{
+		"service-a:token:shared-value:0",
+		"service-a:validation:validation-shared",
+		"service-a:attempts:validation-shared",
+	} {
+		if !mr.Exists(key) {
+			t.Errorf("key %q does not exist", key)
+		}
+	}
+
+	if _, err := b.Retrieve(ctx, "shared-value", token.TypeLink); err == nil {
+		t.Error("Storage.Retrieve() under another prefix found the token")
+	}
+	if n, _ := b.IncrementAttempts(ctx, "validation-shared", time.Hour); n != 1 {
+		t.Errorf("Storage.IncrementAttempts() under another prefix = %d, want 1", n)
+	}
+	if report, _ := a.CheckIndex(ctx); report.TokensScanned != 1 || report.Drift() != 0 {
+		t.Errorf("Storage.CheckIndex() = %+v, want one token and no drift", report)
+	}
+}
+
+func TestStorage_MigrateKeyPrefix(t *testing.T) {
+	t.Parallel()
+
+	mr, client := setupMiniRedis(t)
+	defer mr.Close()
+
+	ctx := context.Background()
+	legacy := New(client)
+
+	for _, value := range []string{"migrate-a", "migrate-b"} {
+		tkn := &token.Token{
+			Value:        value,
+			Type:         token.TypeCode,
+			ValidUntil:   time.Now().Add(time.Hour),
+			ValidationID: "validation-migrate",
+		}
+		if err := legacy.Store(ctx, tkn); err != nil {
+			t.Fatalf("Storage.Store() error = %v", err)
+		}
+	}
+	if _, err := legacy.IncrementAttempts(ctx, "validation-migrate", time.Hour); err != nil {
+		t.Fatalf("Storage.IncrementAttempts() error = %v", err)
+	}
+
+	storage := New(client, WithKeyPrefix("emailvalidator:"))
+
+	// A key already present under the new prefix is not overwritten
+	client.Set(ctx, "emailvalidator:attempts:validation-migrate", "5", time.Hour)
+
+	report, err := storage.MigrateKeyPrefix(ctx, "")
+	if err != nil {
+		t.Fatalf("Storage.MigrateKeyPrefix() error = %v", err)
+	}
+	want := MigrationReport{Moved: 3, Skipped: 1}
+	if report != want {
+		t.Errorf("Storage.MigrateKeyPrefix() = %+v, want %+v", report, want)
+	}
+
+	if mr.Exists("token:migrate-a:1") {
+		t.Error("old token key still exists after migration")
+	}
+	if ttl := mr.TTL("emailvalidator:token:migrate-a:1"); ttl <= 0 {
+		t.Errorf("migrated token TTL = %v, want positive", ttl)
+	}
+	if ttl := mr.TTL("emailvalidator:validation:validation-migrate"); ttl <= 0 {
+		t.Errorf("migrated index TTL = %v, want positive", ttl)
+	}
+
+	tokens, err := storage.ListByValidationID(ctx, "validation-migrate")
+	if err != nil {
+		t.Fatalf("Storage.ListByValidationID() error = %v", err)
+	}
+	if len(tokens) != 2 {
+		t.Errorf("Storage.ListByValidationID() returned %d tokens, want 2", len(tokens))
+	}
+	if _, err := storage.Consume(ctx, "migrate-b", token.TypeCode); err != nil {
+		t.Errorf("Storage.Consume() error = %v", err)
+	}
+	if n, _ := storage.IncrementAttempts(ctx, "validation-migrate", time.Hour); n != 6 {
+		t.Errorf("Storage.IncrementAttempts() = %d, want 6", n)
+	}
+
+	// Running again only finds the skipped key
+	if report, err := storage.MigrateKeyPrefix(ctx, ""); err != nil || report != (MigrationReport{Skipped: 1}) {
+		t.Errorf("second Storage.MigrateKeyPrefix() = %+v, %v, want one skipped key", report, err)
+	}
+}
@@ -1,21 +1,36 @@
 // Package redis provides a Redis-backed implementation of token storage.
+//
+// Storage accepts any redis.UniversalClient, so it runs against a single
+// server, Redis Sentinel through redis.NewFailoverClient, or Redis Cluster
+// through redis.NewClusterClient. On Redis Cluster, enable WithHashTags so
+// that the keys of a validation share a slot. Token keys are looked up by
+// token value alone and hash to their own slots, so no operation writes a
+// token and its validation index in one transaction; each command or
+// transaction touches a single slot.
 package redis
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log/slog"
+	"sync/atomic"
 	"time"
 
+	"github.com/jaeyeom/email-validator-grpc-mcp/redact"
 	"github.com/jaeyeom/email-validator-grpc-mcp/token"
 	"github.com/redis/go-redis/v9"
 )
 
 // Storage provides a Redis-backed implementation for token storage.
 type Storage struct {
-	client *redis.Client
+	client redis.UniversalClient
 	logger *slog.Logger
+	clock  token.Clock
+	codec  token.Codec
+	keys   keyspace
+
+	// indexDrift counts the index drift found by every index check.
+	indexDrift atomic.Int64
 }
 
 // Option is a functional option for configuring Storage.
@@ -29,17 +44,60 @@ func WithLogger(logger *slog.Logger) Option {
 }
 
 // WithRedisClient sets a custom Redis client for Storage.
-func WithRedisClient(client *redis.Client) Option {
+func WithRedisClient(client redis.UniversalClient) Option {
 	return func(s *Storage) {
 		s.client = client
 	}
 }
 
+// WithCodec sets how tokens are serialized in Redis. The default is
+// token.JSONCodec. All clients sharing the same keys must use the same codec.
+// A token.KeyedCodec is given each record's key relative to the key prefix,
+// so records stay readable after MigrateKeyPrefix.
+func WithCodec(codec token.Codec) Option {
+	return func(s *Storage) {
+		s.codec = codec
+	}
+}
+
+// WithKeyPrefix namespaces every key written by Storage with prefix, such as
+// "emailvalidator:", so several services or environments can share one
+// Redis instance. Existing keys can be moved with MigrateKeyPrefix.
+func WithKeyPrefix(prefix string) Option {
+	return func(s *Storage) {
+		s.keys.prefix = prefix
+	}
+}
+
+// WithHashTags wraps validation IDs in keys in Redis Cluster hash tags, as
+// in "validation:{id}", so that a validation's index and attempt counter
+// hash to the same slot. Token keys cannot carry the validation ID, since
+// they are looked up by token value alone, so they hash independently of
+// the index and are always written by separate commands. The key prefix
+// must not contain braces, or it would become the hash tag of every key.
+// Existing keys can be moved to the tagged layout with MigrateKeyPrefix.
+func WithHashTags() Option {
+	return func(s *Storage) {
+		s.keys.hashTags = true
+	}
+}
+
+// WithClock sets the clock used for expiry checks and for computing key TTLs.
+// Redis still expires keys by its own clock, so a clock running ahead of real
+// time makes tokens look expired before Redis removes them.
+func WithClock(clock token.Clock) Option {
+	return func(s *Storage) {
+		s.clock = clock
+	}
+}
+
 // New creates a new Redis-backed token storage.
-func New(client *redis.Client, opts ...Option) *Storage {
+func New(client redis.UniversalClient, opts ...Option) *Storage {
 	s := &Storage{
 		client: client,
 		logger: slog.Default(),
+		clock:  token.SystemClock,
+		codec:  token.JSONCodec,
 	}
 
 	for _, opt := range opts {
@@ -49,6 +107,74 @@ func New(client *redis.Client, opts ...Option) *Storage {
 	return s
 }
 
+// encode serializes t for the record at key using the configured codec,
+// binding it to the key if the codec is a token.KeyedCodec.
+func (s *Storage) encode(t *token.Token, key string) ([]byte, error) {
+	if keyed, ok := s.codec.(token.KeyedCodec); ok {
+		data, err := keyed.MarshalKeyed(t, s.keys.record(key))
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode token: %w", err)
+		}
+		return data, nil
+	}
+
+	data, err := s.codec.Marshal(t)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode token: %w", err)
+	}
+
+	return data, nil
+}
+
+// decode deserializes the token stored at key into t using the configured
+// codec.
+func (s *Storage) decode(data []byte, key string, t *token.Token) error {
+	var decoded *token.Token
+	var err error
+	if keyed, ok := s.codec.(token.KeyedCodec); ok {
+		decoded, err = keyed.UnmarshalKeyed(data, s.keys.record(key))
+	} else {
+		decoded, err = s.codec.Unmarshal(data)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to decode token: %w", err)
+	}
+
+	*t = *decoded
+
+	return nil
+}
+
+// getMany returns the values of keys, with nil for missing keys, like MGET.
+// The keys are read with pipelined GETs because token keys generally hash
+// to different Redis Cluster slots, where MGET would fail.
+func (s *Storage) getMany(ctx context.Context, keys []string) ([]any, error) {
+	cmds := make([]*redis.StringCmd, len(keys))
+	_, err := s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			cmds[i] = pipe.Get(ctx, key)
+		}
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("failed to read keys: %w", err)
+	}
+
+	values := make([]any, len(keys))
+	for i, cmd := range cmds {
+		value, err := cmd.Result()
+		switch {
+		case err == redis.Nil:
+		case err != nil:
+			return nil, fmt.Errorf("failed to read key: %w", err)
+		default:
+			values[i] = value
+		}
+	}
+
+	return values, nil
+}
+
 // Store saves a token to Redis.
 // The token is stored with a composite key and will expire according to its ValidUntil field.
 func (s *Storage) Store(ctx context.Context, t *token.Token) error {
@@ -60,35 +186,38 @@ func (s *Storage) Store(ctx context.Context, t *token.Token) error {
 		return fmt.Errorf("token validation failed: %w", err)
 	}
 
-	// Serialize token to JSON
-	data, err := json.Marshal(t)
+	// Serialize token
+	key := s.keys.token(t.Value, t.Type)
+	data, err := s.encode(t, key)
 	if err != nil {
 		return fmt.Errorf("failed to marshal token: %w", err)
 	}
 
 	// Calculate TTL based on token expiration
-	now := time.Now()
+	now := s.clock.Now()
 	if t.ValidUntil.Before(now) {
 		return token.ErrInvalidToken
 	}
 	ttl := t.ValidUntil.Sub(now)
 
 	// Store token in Redis with expiration
-	key := fmt.Sprintf("token:%s:%d", t.Value, t.Type)
 	err = s.client.Set(ctx, key, data, ttl).Err()
 	if err != nil {
 		return fmt.Errorf("failed to store token in Redis: %w", err)
 	}
 
 	// Store validation ID index
-	validationKey := fmt.Sprintf("validation:%s", t.ValidationID)
-	err = s.client.SAdd(ctx, validationKey, key).Err()
+	indexKey := s.keys.validation(t.ValidationID)
+	err = s.client.SAdd(ctx, indexKey, key).Err()
 	if err != nil {
 		return fmt.Errorf("failed to store validation ID index: %w", err)
 	}
 
 	// Set expiration on validation ID index
-	err = s.client.ExpireAt(ctx, validationKey, t.ValidUntil).Err()
+	_, err = s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		expireIndex(ctx, pipe, indexKey, ttl)
+		return nil
+	})
 	if err != nil {
 		return fmt.Errorf("failed to set expiration on validation ID index: %w", err)
 	}
@@ -100,6 +229,75 @@ func (s *Storage) Store(ctx context.Context, t *token.Token) error {
 	return nil
 }
 
+// expireIndex queues lengthening the expiry of a validation ID index to
+// ttl. It never shortens it, since the index covers the validation's other
+// tokens too. EXPIRE GT treats a key without expiry as never expiring, so a
+// new index gets its expiry from EXPIRE NX.
+func expireIndex(ctx context.Context, pipe redis.Pipeliner, indexKey string, ttl time.Duration) {
+	pipe.ExpireNX(ctx, indexKey, ttl)
+	pipe.ExpireGT(ctx, indexKey, ttl)
+}
+
+// StoreBatch saves multiple tokens in a single MULTI/EXEC transaction.
+// All tokens are validated before anything is written, so an invalid token
+// leaves Redis unchanged. On Redis Cluster, the client splits the
+// transaction by slot, so the batch is atomic only within each slot.
+func (s *Storage) StoreBatch(ctx context.Context, tokens []*token.Token) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("context error: %w", err)
+	}
+
+	now := s.clock.Now()
+	payloads := make([][]byte, len(tokens))
+	indexExpiry := make(map[string]time.Time)
+
+	for i, t := range tokens {
+		if err := token.Validate(t); err != nil {
+			return fmt.Errorf("token %d validation failed: %w", i, err)
+		}
+
+		if t.ValidUntil.Before(now) {
+			return fmt.Errorf("token %d: %w", i, token.ErrInvalidToken)
+		}
+
+		data, err := s.encode(t, s.keys.token(t.Value, t.Type))
+		if err != nil {
+			return fmt.Errorf("failed to marshal token %d: %w", i, err)
+		}
+		payloads[i] = data
+
+		if t.ValidUntil.After(indexExpiry[t.ValidationID]) {
+			indexExpiry[t.ValidationID] = t.ValidUntil
+		}
+	}
+
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, t := range tokens {
+			key := s.keys.token(t.Value, t.Type)
+			pipe.Set(ctx, key, payloads[i], t.ValidUntil.Sub(now))
+			pipe.SAdd(ctx, s.keys.validation(t.ValidationID), key)
+		}
+
+		for validationID, expiresAt := range indexExpiry {
+			expireIndex(ctx, pipe, s.keys.validation(validationID), expiresAt.Sub(now))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store token batch in Redis: %w", err)
+	}
+
+	s.logger.Debug("token batch stored in Redis",
+		"count", len(tokens))
+
+	return nil
+}
+
 // Retrieve gets a token from Redis by its value and type.
 // Returns token.ErrTokenNotFound if the token does not exist.
 // Returns token.TokenExpiredError if the token exists but has expired.
@@ -109,14 +307,14 @@ func (s *Storage) Retrieve(ctx context.Context, tokenValue string, tokenType tok
 	}
 
 	// Construct the key
-	key := fmt.Sprintf("token:%s:%d", tokenValue, tokenType)
+	key := s.keys.token(tokenValue, tokenType)
 
 	// Get token data from Redis
 	data, err := s.client.Get(ctx, key).Bytes()
 	if err != nil {
 		if err == redis.Nil {
 			s.logger.Debug("token not found in Redis",
-				"token_value", tokenValue,
+				"token_value", redact.Token(tokenValue),
 				"token_type", tokenType)
 			return nil, token.ErrTokenNotFound
 		}
@@ -126,22 +324,23 @@ func (s *Storage) Retrieve(ctx context.Context, tokenValue string, tokenType tok
 
 	// Deserialize token
 	var t token.Token
-	if err := json.Unmarshal(data, &t); err != nil {
+	if err := s.decode(data, key, &t); err != nil {
 		s.logger.Error("failed to unmarshal token", "error", err)
 		return nil, fmt.Errorf("failed to unmarshal token: %w", err)
 	}
 
 	// Check if token has expired
-	if t.IsExpired() {
+	if t.IsExpiredAt(s.clock.Now()) {
 		// Delete expired token
 		s.client.Del(ctx, key)
 		s.logger.Debug("expired token retrieved and deleted",
 			"token_type", t.Type,
 			"validation_id", t.ValidationID)
 		return nil, &token.TokenExpiredError{
-			TokenValue: tokenValue,
-			TokenType:  tokenType,
-			ExpiredAt:  t.ValidUntil,
+			TokenValue:   tokenValue,
+			TokenType:    tokenType,
+			ExpiredAt:    t.ValidUntil,
+			ValidationID: t.ValidationID,
 		}
 	}
 
@@ -159,7 +358,7 @@ func (s *Storage) Delete(ctx context.Context, tokenValue string, tokenType token
 	}
 
 	// Construct the key
-	key := fmt.Sprintf("token:%s:%d", tokenValue, tokenType)
+	key := s.keys.token(tokenValue, tokenType)
 
 	// Get the token to find its validation ID
 	data, err := s.client.Get(ctx, key).Bytes()
@@ -167,7 +366,7 @@ func (s *Storage) Delete(ctx context.Context, tokenValue string, tokenType token
 		if err == redis.Nil {
 			// Token doesn't exist, nothing to delete
 			s.logger.Debug("token not found for deletion",
-				"token_value", tokenValue,
+				"token_value", redact.Token(tokenValue),
 				"token_type", tokenType)
 			return nil
 		}
@@ -177,14 +376,14 @@ func (s *Storage) Delete(ctx context.Context, tokenValue string, tokenType token
 
 	// Deserialize token to get validation ID
 	var t token.Token
-	if err := json.Unmarshal(data, &t); err != nil {
+	if err := s.decode(data, key, &t); err != nil {
 		s.logger.Error("failed to unmarshal token for deletion", "error", err)
 		return fmt.Errorf("failed to unmarshal token for deletion: %w", err)
 	}
 
 	// Remove token from validation ID index
-	validationKey := fmt.Sprintf("validation:%s", t.ValidationID)
-	err = s.client.SRem(ctx, validationKey, key).Err()
+	indexKey := s.keys.validation(t.ValidationID)
+	err = s.client.SRem(ctx, indexKey, key).Err()
 	if err != nil && err != redis.Nil {
 		s.logger.Error("failed to remove token from validation index", "error", err)
 		return fmt.Errorf("failed to remove token from validation index: %w", err)
@@ -204,6 +403,71 @@ func (s *Storage) Delete(ctx context.Context, tokenValue string, tokenType token
 	return nil
 }
 
+// DeleteBatch removes multiple tokens in two roundtrips: pipelined reads to
+// find the tokens' validation IDs, then a MULTI/EXEC transaction deleting
+// the tokens and their index entries, which on Redis Cluster is split by
+// slot. Tokens that do not exist are ignored, and unreadable records are
+// deleted without touching any index.
+func (s *Storage) DeleteBatch(ctx context.Context, refs []token.TokenRef) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("context error: %w", err)
+	}
+
+	if len(refs) == 0 {
+		return nil
+	}
+
+	keys := make([]string, len(refs))
+	for i, ref := range refs {
+		keys[i] = s.keys.token(ref.Value, ref.Type)
+	}
+
+	values, err := s.getMany(ctx, keys)
+	if err != nil {
+		return fmt.Errorf("failed to read tokens for deletion: %w", err)
+	}
+
+	indexed := make(map[string][]any)
+	var existing []string
+	for i, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			continue
+		}
+		existing = append(existing, keys[i])
+
+		var t token.Token
+		if err := s.decode([]byte(data), keys[i], &t); err != nil {
+			s.logger.Warn("deleting unreadable token record", "error", err)
+			continue
+		}
+		indexKey := s.keys.validation(t.ValidationID)
+		indexed[indexKey] = append(indexed[indexKey], keys[i])
+	}
+
+	if len(existing) == 0 {
+		return nil
+	}
+
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for indexKey, members := range indexed {
+			pipe.SRem(ctx, indexKey, members...)
+		}
+		for _, key := range existing {
+			pipe.Del(ctx, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete token batch from Redis: %w", err)
+	}
+
+	s.logger.Debug("token batch deleted from Redis",
+		"count", len(existing))
+
+	return nil
+}
+
 // DeleteByValidationID removes all tokens associated with a validation ID.
 // This operation is idempotent and will not return an error if no tokens exist for the validation ID.
 func (s *Storage) DeleteByValidationID(ctx context.Context, validationID string) error {
@@ -216,8 +480,8 @@ func (s *Storage) DeleteByValidationID(ctx context.Context, validationID string)
 	}
 
 	// Get all token keys for this validation ID
-	validationKey := fmt.Sprintf("validation:%s", validationID)
-	keys, err := s.client.SMembers(ctx, validationKey).Result()
+	indexKey := s.keys.validation(validationID)
+	keys, err := s.client.SMembers(ctx, indexKey).Result()
 	if err != nil {
 		if err == redis.Nil {
 			// No tokens for this validation ID
@@ -228,19 +492,15 @@ func (s *Storage) DeleteByValidationID(ctx context.Context, validationID string)
 		return fmt.Errorf("failed to get token keys for validation ID: %w", err)
 	}
 
-	if len(keys) == 0 {
-		// No tokens for this validation ID
-		s.logger.Debug("no tokens found for validation ID", "validation_id", validationID)
-		return nil
-	}
-
 	// Delete all tokens
 	pipe := s.client.Pipeline()
 	for _, key := range keys {
 		pipe.Del(ctx, key)
 	}
-	// Delete the validation ID index
-	pipe.Del(ctx, validationKey)
+	// Delete the validation ID index and attempt counter. The pipeline is
+	// not a transaction, so the token keys may hash to other slots
+	pipe.Del(ctx, indexKey)
+	pipe.Del(ctx, s.keys.attempts(validationID))
 
 	// Execute pipeline
 	_, err = pipe.Exec(ctx)
@@ -255,3 +515,239 @@ func (s *Storage) DeleteByValidationID(ctx context.Context, validationID string)
 
 	return nil
 }
+
+// ListByValidationID returns the unexpired tokens for a validation ID. Index
+// entries whose token keys have already expired in Redis are skipped.
+func (s *Storage) ListByValidationID(ctx context.Context, validationID string) ([]*token.Token, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("context error: %w", err)
+	}
+
+	if validationID == "" {
+		return nil, token.ErrEmptyValidationID
+	}
+
+	keys, err := s.client.SMembers(ctx, s.keys.validation(validationID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get validation ID index: %w", err)
+	}
+
+	tokens := []*token.Token{}
+	if len(keys) == 0 {
+		return tokens, nil
+	}
+
+	values, err := s.getMany(ctx, keys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve tokens from Redis: %w", err)
+	}
+
+	now := s.clock.Now()
+	for i, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			// The token key expired or was deleted
+			continue
+		}
+
+		var t token.Token
+		if err := s.decode([]byte(data), keys[i], &t); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal token: %w", err)
+		}
+
+		if !t.IsExpiredAt(now) {
+			tokens = append(tokens, &t)
+		}
+	}
+
+	s.logger.Debug("tokens listed by validation ID",
+		"validation_id", validationID,
+		"count", len(tokens))
+
+	return tokens, nil
+}
+
+// Consume atomically retrieves and deletes a token from Redis using GETDEL.
+// GETDEL is a single command, so no other client can read or consume the
+// token between the read and the delete, and of any number of concurrent
+// callers at most one receives the token. Expiry is checked after the
+// delete, so an expired token is removed as well. The validation index is
+// updated afterwards; if that fails the token stays consumed and the stale
+// index entry is left for RepairIndex.
+// Returns token.ErrTokenNotFound if the token does not exist or was already consumed.
+// Returns token.TokenExpiredError if the token exists but has expired.
+func (s *Storage) Consume(ctx context.Context, tokenValue string, tokenType token.Type) (*token.Token, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("context error: %w", err)
+	}
+
+	// Construct the key
+	key := s.keys.token(tokenValue, tokenType)
+
+	// GETDEL guarantees only one caller observes the token
+	data, err := s.client.GetDel(ctx, key).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			s.logger.Debug("token not found for consumption",
+				"token_value", redact.Token(tokenValue),
+				"token_type", tokenType)
+			return nil, token.ErrTokenNotFound
+		}
+		s.logger.Error("failed to consume token from Redis", "error", err)
+		return nil, fmt.Errorf("failed to consume token from Redis: %w", err)
+	}
+
+	// Deserialize token
+	var t token.Token
+	if err := s.decode(data, key, &t); err != nil {
+		s.logger.Error("failed to unmarshal consumed token", "error", err)
+		return nil, fmt.Errorf("failed to unmarshal token: %w", err)
+	}
+
+	// Remove token from validation ID index
+	indexKey := s.keys.validation(t.ValidationID)
+	err = s.client.SRem(ctx, indexKey, key).Err()
+	if err != nil && err != redis.Nil {
+		// The token is already gone, so failing here would reject a
+		// verification that can never be retried
+		s.logger.Warn("failed to remove consumed token from validation index",
+			"error", err,
+			"validation_id", t.ValidationID)
+	}
+
+	if t.IsExpiredAt(s.clock.Now()) {
+		s.logger.Debug("expired token consumed and deleted",
+			"token_type", t.Type,
+			"validation_id", t.ValidationID)
+		return nil, &token.TokenExpiredError{
+			TokenValue:   tokenValue,
+			TokenType:    tokenType,
+			ExpiredAt:    t.ValidUntil,
+			ValidationID: t.ValidationID,
+		}
+	}
+
+	s.logger.Debug("token consumed from Redis",
+		"token_type", t.Type,
+		"validation_id", t.ValidationID)
+
+	return &t, nil
+}
+
+// IncrementAttempts records a failed verification attempt for a validation and
+// returns the number of failed attempts within the counter's lifetime.
+// The counter expiry is set on the first attempt and not extended afterwards.
+func (s *Storage) IncrementAttempts(ctx context.Context, validationID string, ttl time.Duration) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, fmt.Errorf("context error: %w", err)
+	}
+
+	if validationID == "" {
+		return 0, token.ErrEmptyValidationID
+	}
+
+	key := s.keys.attempts(validationID)
+
+	pipe := s.client.TxPipeline()
+	incr := pipe.Incr(ctx, key)
+	pipe.ExpireNX(ctx, key, ttl)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		s.logger.Error("failed to record verification attempt", "error", err, "validation_id", validationID)
+		return 0, fmt.Errorf("failed to record verification attempt: %w", err)
+	}
+
+	s.logger.Debug("verification attempt recorded in Redis",
+		"validation_id", validationID,
+		"attempts", incr.Val())
+
+	return int(incr.Val()), nil
+}
+
+// maxExtendRetries bounds optimistic transaction retries in ExtendTTL.
+const maxExtendRetries = 5
+
+// ExtendTTL atomically extends the expiry of a token in Redis. The read and
+// rewrite run in an optimistic WATCH/MULTI transaction on the token key, so
+// a concurrent delete or update of the token causes a retry rather than a
+// lost write. The validation index hashes to another slot on Redis Cluster,
+// so its expiry is lengthened beforehand by a separate command; if the
+// token is not rewritten, the index merely lives longer than needed.
+// Returns token.ErrTokenNotFound if the token does not exist.
+// Returns token.TokenExpiredError if the token has already expired.
+func (s *Storage) ExtendTTL(ctx context.Context, tokenValue string, tokenType token.Type, extra time.Duration) (*token.Token, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("context error: %w", err)
+	}
+
+	key := s.keys.token(tokenValue, tokenType)
+
+	var extended token.Token
+	txf := func(tx *redis.Tx) error {
+		data, err := tx.Get(ctx, key).Bytes()
+		if err != nil {
+			if err == redis.Nil {
+				return token.ErrTokenNotFound
+			}
+			return fmt.Errorf("failed to retrieve token for extension: %w", err)
+		}
+
+		var t token.Token
+		if err := s.decode(data, key, &t); err != nil {
+			return fmt.Errorf("failed to unmarshal token: %w", err)
+		}
+
+		now := s.clock.Now()
+		if t.IsExpiredAt(now) {
+			return &token.TokenExpiredError{
+				TokenValue:   tokenValue,
+				TokenType:    tokenType,
+				ExpiredAt:    t.ValidUntil,
+				ValidationID: t.ValidationID,
+			}
+		}
+
+		t.ValidUntil = t.ValidUntil.Add(extra)
+		ttl := t.ValidUntil.Sub(now)
+
+		data, err = s.encode(&t, key)
+		if err != nil {
+			return fmt.Errorf("failed to marshal token: %w", err)
+		}
+
+		// Only ever lengthen the index expiry, since it covers other tokens too
+		indexKey := s.keys.validation(t.ValidationID)
+		if err := s.client.ExpireGT(ctx, indexKey, ttl).Err(); err != nil {
+			return fmt.Errorf("failed to extend validation ID index: %w", err)
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, key, data, ttl)
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to store extended token: %w", err)
+		}
+
+		extended = t
+
+		return nil
+	}
+
+	for range maxExtendRetries {
+		err := s.client.Watch(ctx, txf, key)
+		if err == nil {
+			s.logger.Debug("token TTL extended in Redis",
+				"token_type", extended.Type,
+				"validation_id", extended.ValidationID,
+				"valid_until", extended.ValidUntil)
+			return &extended, nil
+		}
+
+		if err != redis.TxFailedErr {
+			return nil, err
+		}
+	}
+
+	return nil, fmt.Errorf("failed to extend token TTL: %w", redis.TxFailedErr)
+}
@@ -2,11 +2,18 @@ package redis
 
 import (
 	"context"
+	"errors"
+	"maps"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/alicebob/miniredis/v2"
 	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+	"github.com/jaeyeom/email-validator-grpc-mcp/token/codec/encryption"
+	"github.com/jaeyeom/email-validator-grpc-mcp/token/codec/protobuf"
+	"github.com/jaeyeom/email-validator-grpc-mcp/token/storagetest"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -27,6 +34,17 @@ func setupMiniRedis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
 	return mr, client
 }
 
+func TestStorage_Conformance(t *testing.T) {
+	t.Parallel()
+
+	storagetest.TestStorage(t, func() token.Storage {
+		mr, client := setupMiniRedis(t)
+		t.Cleanup(mr.Close)
+
+		return New(client)
+	})
+}
+
 func TestStorage_Store(t *testing.T) {
 	t.Parallel()
 
@@ -361,3 +379,459 @@ func TestStorage_DeleteByValidationID(t *testing.T) {
 		})
 	}
 }
+
+func TestStorage_Consume(t *testing.T) {
+	t.Parallel()
+
+	mr, client := setupMiniRedis(t)
+	defer mr.Close()
+
+	ctx := context.Background()
+	storage := New(client)
+
+	tkn := &token.Token{
+		Value:        "test-token-consume",
+		Type:         token.TypeCode,
+		ValidUntil:   time.Now().Add(time.Hour),
+		ValidationID: "validation-consume",
+	}
+	if err := storage.Store(ctx, tkn); err != nil {
+		t.Fatalf("Storage.Store() error = %v", err)
+	}
+
+	got, err := storage.Consume(ctx, tkn.Value, tkn.Type)
+	if err != nil {
+		t.Fatalf("Storage.Consume() error = %v", err)
+	}
+	if got.ValidationID != tkn.ValidationID {
+		t.Errorf("Storage.Consume() validation ID = %v, want %v", got.ValidationID, tkn.ValidationID)
+	}
+
+	if _, err := storage.Consume(ctx, tkn.Value, tkn.Type); err != token.ErrTokenNotFound {
+		t.Errorf("second Storage.Consume() error = %v, want %v", err, token.ErrTokenNotFound)
+	}
+
+	// The validation index must no longer reference the consumed token
+	members, err := client.SMembers(ctx, "validation:"+tkn.ValidationID).Result()
+	if err != nil {
+		t.Fatalf("SMembers() error = %v", err)
+	}
+	if len(members) != 0 {
+		t.Errorf("validation index still contains %v after consume", members)
+	}
+}
+
+func TestStorage_ConsumeConcurrent(t *testing.T) {
+	t.Parallel()
+
+	mr, client := setupMiniRedis(t)
+	defer mr.Close()
+
+	ctx := context.Background()
+	storage := New(client)
+
+	tkn := &token.Token{
+		Value:        "123456",
+		Type:         token.TypeCode,
+		ValidUntil:   time.Now().Add(time.Hour),
+		ValidationID: "validation-race",
+	}
+	if err := storage.Store(ctx, tkn); err != nil {
+		t.Fatalf("Storage.Store() error = %v", err)
+	}
+
+	const callers = 20
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := storage.Consume(ctx, tkn.Value, tkn.Type)
+			if err != nil && err != token.ErrTokenNotFound {
+				t.Errorf("Storage.Consume() error = %v", err)
+				return
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err == nil {
+				succeeded++
+			}
+		}()
+	}
+	wg.Wait()
+
+	if succeeded != 1 {
+		t.Errorf("%d of %d concurrent Storage.Consume() calls succeeded, want 1", succeeded, callers)
+	}
+}
+
+func TestStorage_ConsumeIndexFailure(t *testing.T) {
+	t.Parallel()
+
+	mr, client := setupMiniRedis(t)
+	defer mr.Close()
+
+	ctx := context.Background()
+	storage := New(client)
+
+	tkn := &token.Token{
+		Value:        "test-token-index-failure",
+		Type:         token.TypeLink,
+		ValidUntil:   time.Now().Add(time.Hour),
+		ValidationID: "validation-index-failure",
+	}
+	if err := storage.Store(ctx, tkn); err != nil {
+		t.Fatalf("Storage.Store() error = %v", err)
+	}
+
+	// Make the index update fail with WRONGTYPE
+	client.Del(ctx, "validation:validation-index-failure")
+	client.Set(ctx, "validation:validation-index-failure", "not-a-set", time.Hour)
+
+	if _, err := storage.Consume(ctx, tkn.Value, tkn.Type); err != nil {
+		t.Errorf("Storage.Consume() error = %v, want success once the token is consumed", err)
+	}
+	if mr.Exists("token:test-token-index-failure:0") {
+		t.Error("token still exists after Storage.Consume()")
+	}
+}
+
+func TestStorage_IncrementAttempts(t *testing.T) {
+	t.Parallel()
+
+	mr, client := setupMiniRedis(t)
+	defer mr.Close()
+
+	ctx := context.Background()
+	storage := New(client)
+
+	for want := 1; want <= 3; want++ {
+		got, err := storage.IncrementAttempts(ctx, "validation-attempts", time.Minute)
+		if err != nil {
+			t.Fatalf("Storage.IncrementAttempts() error = %v", err)
+		}
+		if got != want {
+			t.Errorf("Storage.IncrementAttempts() = %d, want %d", got, want)
+		}
+	}
+
+	if ttl := mr.TTL("attempts:validation-attempts"); ttl <= 0 || ttl > time.Minute {
+		t.Errorf("attempt counter TTL = %v, want within (0, 1m]", ttl)
+	}
+
+	// The counter expires with its TTL
+	mr.FastForward(2 * time.Minute)
+	if got, _ := storage.IncrementAttempts(ctx, "validation-attempts", time.Minute); got != 1 {
+		t.Errorf("Storage.IncrementAttempts() after expiry = %d, want 1", got)
+	}
+
+	// Deleting the validation resets its counter
+	if err := storage.DeleteByValidationID(ctx, "validation-attempts"); err != nil {
+		t.Fatalf("Storage.DeleteByValidationID() error = %v", err)
+	}
+	if mr.Exists("attempts:validation-attempts") {
+		t.Error("attempt counter still exists after DeleteByValidationID")
+	}
+}
+
+func TestStorage_WithClock(t *testing.T) {
+	t.Parallel()
+
+	mr, client := setupMiniRedis(t)
+	defer mr.Close()
+
+	ctx := context.Background()
+	now := time.Now()
+	storage := New(client, WithClock(token.ClockFunc(func() time.Time { return now })))
+
+	tkn := token.NewAt("test-token-clock", token.TypeLink, "validation-clock", time.Hour, now)
+	if err := storage.Store(ctx, tkn); err != nil {
+		t.Fatalf("Storage.Store() error = %v", err)
+	}
+
+	// Advancing the injected clock expires the token without waiting on Redis
+	now = now.Add(2 * time.Hour)
+	if _, err := storage.Retrieve(ctx, tkn.Value, tkn.Type); !token.IsTokenExpiredError(err) {
+		t.Errorf("Storage.Retrieve() error = %v, want TokenExpiredError", err)
+	}
+}
+
+func TestStorage_ExtendTTL(t *testing.T) {
+	t.Parallel()
+
+	mr, client := setupMiniRedis(t)
+	defer mr.Close()
+
+	ctx := context.Background()
+	storage := New(client)
+
+	tkn := token.New("test-token-extend", token.TypeLink, "validation-extend", time.Hour)
+	if err := storage.Store(ctx, tkn); err != nil {
+		t.Fatalf("Storage.Store() error = %v", err)
+	}
+
+	extended, err := storage.ExtendTTL(ctx, tkn.Value, tkn.Type, time.Hour)
+	if err != nil {
+		t.Fatalf("Storage.ExtendTTL() error = %v", err)
+	}
+	if want := tkn.ValidUntil.Add(time.Hour); !extended.ValidUntil.Equal(want) {
+		t.Errorf("Storage.ExtendTTL() valid until = %v, want %v", extended.ValidUntil, want)
+	}
+
+	if ttl := mr.TTL("token:test-token-extend:0"); ttl <= time.Hour {
+		t.Errorf("token key TTL = %v, want more than 1h", ttl)
+	}
+	if ttl := mr.TTL("validation:validation-extend"); ttl <= time.Hour {
+		t.Errorf("validation index TTL = %v, want more than 1h", ttl)
+	}
+
+	// The token survives past its original expiry
+	mr.FastForward(90 * time.Minute)
+	if _, err := storage.Retrieve(ctx, tkn.Value, tkn.Type); err != nil {
+		t.Errorf("Storage.Retrieve() after original expiry error = %v", err)
+	}
+
+	if _, err := storage.ExtendTTL(ctx, "missing", token.TypeLink, time.Hour); err != token.ErrTokenNotFound {
+		t.Errorf("Storage.ExtendTTL() on missing token error = %v, want %v", err, token.ErrTokenNotFound)
+	}
+}
+
+func TestStorage_Metadata(t *testing.T) {
+	t.Parallel()
+
+	mr, client := setupMiniRedis(t)
+	defer mr.Close()
+
+	ctx := context.Background()
+	storage := New(client)
+
+	tkn := token.New("test-token-metadata", token.TypeLink, "validation-metadata", time.Hour)
+	tkn.Metadata = map[string]string{"locale": "ko-KR", "campaign_id": "spring-2025"}
+	if err := storage.Store(ctx, tkn); err != nil {
+		t.Fatalf("Storage.Store() error = %v", err)
+	}
+
+	got, err := storage.Retrieve(ctx, tkn.Value, tkn.Type)
+	if err != nil {
+		t.Fatalf("Storage.Retrieve() error = %v", err)
+	}
+	if !maps.Equal(got.Metadata, tkn.Metadata) {
+		t.Errorf("Storage.Retrieve() metadata = %v, want %v", got.Metadata, tkn.Metadata)
+	}
+}
+
+func TestStorage_StoreBatch(t *testing.T) {
+	t.Parallel()
+
+	mr, client := setupMiniRedis(t)
+	defer mr.Close()
+
+	ctx := context.Background()
+	storage := New(client)
+
+	tokens := []*token.Token{
+		{Value: "batch-link", Type: token.TypeLink, ValidUntil: time.Now().Add(time.Hour), ValidationID: "validation-batch"},
+		{Value: "123456", Type: token.TypeCode, ValidUntil: time.Now().Add(time.Hour), ValidationID: "validation-batch"},
+	}
+	if err := storage.StoreBatch(ctx, tokens); err != nil {
+		t.Fatalf("Storage.StoreBatch() error = %v", err)
+	}
+
+	for _, tkn := range tokens {
+		if _, err := storage.Retrieve(ctx, tkn.Value, tkn.Type); err != nil {
+			t.Errorf("Storage.Retrieve(%q) error = %v", tkn.Value, err)
+		}
+	}
+
+	if err := storage.DeleteByValidationID(ctx, "validation-batch"); err != nil {
+		t.Fatalf("Storage.DeleteByValidationID() error = %v", err)
+	}
+	if _, err := storage.Retrieve(ctx, "batch-link", token.TypeLink); err != token.ErrTokenNotFound {
+		t.Errorf("Storage.Retrieve() after delete error = %v, want %v", err, token.ErrTokenNotFound)
+	}
+
+	// An invalid token rejects the whole batch
+	invalid := []*token.Token{
+		{Value: "batch-ok", Type: token.TypeLink, ValidUntil: time.Now().Add(time.Hour), ValidationID: "validation-invalid"},
+		{Value: "", Type: token.TypeLink, ValidUntil: time.Now().Add(time.Hour), ValidationID: "validation-invalid"},
+	}
+	if err := storage.StoreBatch(ctx, invalid); err == nil {
+		t.Fatal("Storage.StoreBatch() with invalid token error = nil, want error")
+	}
+	if _, err := storage.Retrieve(ctx, "batch-ok", token.TypeLink); err != token.ErrTokenNotFound {
+		t.Errorf("Storage.Retrieve() after rejected batch error = %v, want %v", err, token.ErrTokenNotFound)
+	}
+}
+
+func TestStorage_IndexExpiry(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		store func(ctx context.Context, s *Storage, tkn *token.Token) error
+	}{
+		{"Store", func(ctx context.Context, s *Storage, tkn *token.Token) error { return s.Store(ctx, tkn) }},
+		{"StoreBatch", func(ctx context.Context, s *Storage, tkn *token.Token) error {
+			return s.StoreBatch(ctx, []*token.Token{tkn})
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			mr, client := setupMiniRedis(t)
+			defer mr.Close()
+
+			ctx := context.Background()
+			storage := New(client)
+
+			link := &token.Token{Value: "index-link", Type: token.TypeLink, ValidUntil: time.Now().Add(24 * time.Hour), ValidationID: "validation-index"}
+			code := &token.Token{Value: "123456", Type: token.TypeCode, ValidUntil: time.Now().Add(15 * time.Minute), ValidationID: "validation-index"}
+			if err := tt.store(ctx, storage, link); err != nil {
+				t.Fatalf("storing link token error = %v", err)
+			}
+			if err := tt.store(ctx, storage, code); err != nil {
+				t.Fatalf("storing code token error = %v", err)
+			}
+
+			// The shorter-lived code token must not shorten the index expiry
+			if ttl := mr.TTL("validation:validation-index"); ttl <= time.Hour {
+				t.Errorf("validation index TTL = %v, want the link token's 24h", ttl)
+			}
+
+			mr.FastForward(time.Hour)
+			tokens, err := storage.ListByValidationID(ctx, "validation-index")
+			if err != nil || len(tokens) != 1 || tokens[0].Value != link.Value {
+				t.Errorf("Storage.ListByValidationID() after code expiry = %v, %v, want the link token", tokens, err)
+			}
+		})
+	}
+}
+
+func TestStorage_ListByValidationID(t *testing.T) {
+	t.Parallel()
+
+	mr, client := setupMiniRedis(t)
+	defer mr.Close()
+
+	ctx := context.Background()
+	now := time.Now()
+	storage := New(client, WithClock(token.ClockFunc(func() time.Time { return now })))
+
+	tokens := []*token.Token{
+		{Value: "list-link", Type: token.TypeLink, ValidUntil: now.Add(time.Hour), ValidationID: "validation-list"},
+		{Value: "list-code", Type: token.TypeCode, ValidUntil: now.Add(time.Minute), ValidationID: "validation-list"},
+		{Value: "other-link", Type: token.TypeLink, ValidUntil: now.Add(time.Hour), ValidationID: "validation-other"},
+	}
+	for _, tkn := range tokens {
+		if err := storage.Store(ctx, tkn); err != nil {
+			t.Fatalf("Storage.Store() error = %v", err)
+		}
+	}
+
+	got, err := storage.ListByValidationID(ctx, "validation-list")
+	if err != nil {
+		t.Fatalf("Storage.ListByValidationID() error = %v", err)
+	}
+	if len(got) != 2 {
+		t.Errorf("Storage.ListByValidationID() returned %d tokens, want 2", len(got))
+	}
+
+	// Expired tokens are left out
+	now = now.Add(30 * time.Minute)
+	got, err = storage.ListByValidationID(ctx, "validation-list")
+	if err != nil {
+		t.Fatalf("Storage.ListByValidationID() error = %v", err)
+	}
+	if len(got) != 1 || got[0].Value != "list-link" {
+		t.Errorf("Storage.ListByValidationID() after expiry = %v, want only list-link", got)
+	}
+
+	got, err = storage.ListByValidationID(ctx, "validation-missing")
+	if err != nil || len(got) != 0 {
+		t.Errorf("Storage.ListByValidationID() for unknown ID = %v, %v, want empty", got, err)
+	}
+}
+
+func TestStorage_WithCodec(t *testing.T) {
+	t.Parallel()
+
+	mr, client := setupMiniRedis(t)
+	defer mr.Close()
+
+	ctx := context.Background()
+	storage := New(client, WithCodec(protobuf.New()))
+
+	tkn := &token.Token{
+		Value:        "test-token-codec",
+		Type:         token.TypeCode,
+		CreatedAt:    time.Now(),
+		ValidUntil:   time.Now().Add(time.Hour),
+		ValidationID: "validation-codec",
+		Metadata:     map[string]string{"locale": "ko-KR"},
+	}
+	if err := storage.Store(ctx, tkn); err != nil {
+		t.Fatalf("Storage.Store() error = %v", err)
+	}
+
+	raw, err := mr.Get("token:test-token-codec:1")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if strings.HasPrefix(raw, "{") {
+		t.Errorf("stored token %q looks like JSON, want protobuf", raw)
+	}
+
+	got, err := storage.Retrieve(ctx, tkn.Value, tkn.Type)
+	if err != nil {
+		t.Fatalf("Storage.Retrieve() error = %v", err)
+	}
+	if !got.ValidUntil.Equal(tkn.ValidUntil) || got.Metadata["locale"] != "ko-KR" {
+		t.Errorf("Storage.Retrieve() = %+v, want %+v", got, tkn)
+	}
+}
+
+func TestStorage_WithKeyedCodec(t *testing.T) {
+	t.Parallel()
+
+	mr, client := setupMiniRedis(t)
+	defer mr.Close()
+
+	keys, err := encryption.NewStaticKeys("k1", map[string][]byte{"k1": make([]byte, 32)})
+	if err != nil {
+		t.Fatalf("NewStaticKeys() error = %v", err)
+	}
+
+	ctx := context.Background()
+	storage := New(client, WithCodec(encryption.New(nil, keys)), WithKeyPrefix("app:"))
+
+	for _, value := range []string{"keyed-a", "keyed-b"} {
+		tkn := token.New(value, token.TypeLink, "validation-keyed", time.Hour)
+		if err := storage.Store(ctx, tkn); err != nil {
+			t.Fatalf("Storage.Store() error = %v", err)
+		}
+	}
+
+	if tokens, err := storage.ListByValidationID(ctx, "validation-keyed"); err != nil || len(tokens) != 2 {
+		t.Fatalf("Storage.ListByValidationID() = %v, %v, want 2 tokens", tokens, err)
+	}
+
+	// A record copied under another token's key does not decrypt
+	raw, err := mr.Get("app:token:keyed-a:0")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if err := mr.Set("app:token:keyed-b:0", raw); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if _, err := storage.Retrieve(ctx, "keyed-b", token.TypeLink); !errors.Is(err, encryption.ErrDecryptFailed) {
+		t.Errorf("Storage.Retrieve() of copied record error = %v, want %v", err, encryption.ErrDecryptFailed)
+	}
+	if _, err := storage.Retrieve(ctx, "keyed-a", token.TypeLink); err != nil {
+		t.Errorf("Storage.Retrieve() error = %v", err)
+	}
+}
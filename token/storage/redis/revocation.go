@@ -0,0 +1,81 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+	"github.com/redis/go-redis/v9"
+)
+
+// RevocationList is a Redis-backed token.RevocationList. Each revoked digest
+// is a key holding the revocation reason that expires with the token.
+type RevocationList struct {
+	client redis.UniversalClient
+	clock  token.Clock
+	keys   keyspace
+}
+
+// RevocationOption is a functional option for configuring RevocationList.
+type RevocationOption func(*RevocationList)
+
+// WithRevocationKeyPrefix namespaces revocation keys like WithKeyPrefix
+// does for Storage.
+func WithRevocationKeyPrefix(prefix string) RevocationOption {
+	return func(l *RevocationList) {
+		l.keys.prefix = prefix
+	}
+}
+
+// NewRevocationList creates a revocation list stored in Redis. A nil clock
+// defaults to token.SystemClock.
+func NewRevocationList(client redis.UniversalClient, clock token.Clock, opts ...RevocationOption) *RevocationList {
+	if clock == nil {
+		clock = token.SystemClock
+	}
+
+	l := &RevocationList{
+		client: client,
+		clock:  clock,
+	}
+
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	return l
+}
+
+// Add records a revoked token digest until expiresAt. Tokens that have
+// already expired are not recorded.
+func (l *RevocationList) Add(ctx context.Context, digest string, expiresAt time.Time, reason string) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("context error: %w", err)
+	}
+
+	ttl := expiresAt.Sub(l.clock.Now())
+	if ttl <= 0 {
+		return nil
+	}
+
+	if err := l.client.Set(ctx, l.keys.revoked(digest), reason, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to store revoked token in Redis: %w", err)
+	}
+
+	return nil
+}
+
+// Contains reports whether the digest has been revoked.
+func (l *RevocationList) Contains(ctx context.Context, digest string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, fmt.Errorf("context error: %w", err)
+	}
+
+	n, err := l.client.Exists(ctx, l.keys.revoked(digest)).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to check revoked token in Redis: %w", err)
+	}
+
+	return n > 0, nil
+}
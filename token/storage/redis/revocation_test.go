@@ -0,0 +1,46 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+)
+
+func TestRevocationList(t *testing.T) {
+	t.Parallel()
+
+	mr, client := setupMiniRedis(t)
+	defer mr.Close()
+
+	ctx := context.Background()
+	list := NewRevocationList(client, nil)
+
+	digest := token.TokenDigest("revoked-token", token.TypeLink)
+	if err := list.Add(ctx, digest, time.Now().Add(time.Hour), "leaked"); err != nil {
+		t.Fatalf("RevocationList.Add() error = %v", err)
+	}
+
+	if revoked, err := list.Contains(ctx, digest); err != nil || !revoked {
+		t.Errorf("RevocationList.Contains() = %v, %v, want true", revoked, err)
+	}
+	if reason, _ := client.Get(ctx, "revoked:"+digest).Result(); reason != "leaked" {
+		t.Errorf("stored reason = %q, want %q", reason, "leaked")
+	}
+
+	// Entries lapse with the token
+	mr.FastForward(2 * time.Hour)
+	if revoked, _ := list.Contains(ctx, digest); revoked {
+		t.Error("RevocationList.Contains() = true after expiry")
+	}
+
+	// Already expired tokens are not recorded
+	expired := token.TokenDigest("expired-token", token.TypeLink)
+	if err := list.Add(ctx, expired, time.Now().Add(-time.Minute), "leaked"); err != nil {
+		t.Fatalf("RevocationList.Add() error = %v", err)
+	}
+	if revoked, _ := list.Contains(ctx, expired); revoked {
+		t.Error("RevocationList.Contains() = true for an expired token")
+	}
+}
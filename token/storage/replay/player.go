@@ -0,0 +1,185 @@
+package replay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+)
+
+// ErrUnexpectedCall is returned by a Player for a call that does not match
+// the next recorded one.
+var ErrUnexpectedCall = errors.New("unexpected storage call")
+
+// Player is a token.Storage that replays a recording. Each call must match
+// the next recorded call's operation and arguments; stored tokens are
+// compared by value, type, validation ID, and metadata, ignoring times.
+type Player struct {
+	mu       sync.Mutex
+	calls    []Call
+	next     int
+	failures []error
+}
+
+// NewPlayer returns a Player replaying calls in order.
+func NewPlayer(calls []Call) *Player {
+	return &Player{calls: slices.Clone(calls)}
+}
+
+// Err reports calls that did not match the recording and recorded calls
+// that were never made. Tests should check it once the code under test has
+// finished.
+func (p *Player) Err() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	errs := slices.Clone(p.failures)
+	if remaining := len(p.calls) - p.next; remaining > 0 {
+		errs = append(errs, fmt.Errorf("%d recorded calls not replayed, next is %s", remaining, p.calls[p.next].Op))
+	}
+
+	return errors.Join(errs...)
+}
+
+// play matches got against the next recorded call and returns it.
+func (p *Player) play(got Call) (Call, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.next >= len(p.calls) {
+		err := fmt.Errorf("%w: %s after end of recording", ErrUnexpectedCall, got.Op)
+		p.failures = append(p.failures, err)
+		return Call{}, err
+	}
+
+	want := p.calls[p.next]
+	if !matches(want, got) {
+		err := fmt.Errorf("%w: call %d is %s, recording has %s", ErrUnexpectedCall, p.next, describe(got), describe(want))
+		p.failures = append(p.failures, err)
+		return Call{}, err
+	}
+
+	p.next++
+
+	return want, nil
+}
+
+// matches reports whether got has the same operation and arguments as want.
+func matches(want, got Call) bool {
+	if want.Op != got.Op || want.Value != got.Value || want.Type != got.Type ||
+		want.ValidationID != got.ValidationID || want.Duration != got.Duration {
+		return false
+	}
+
+	if want.Token == nil || got.Token == nil {
+		return want.Token == got.Token
+	}
+
+	return want.Token.Value == got.Token.Value &&
+		want.Token.Type == got.Token.Type &&
+		want.Token.ValidationID == got.Token.ValidationID &&
+		maps.Equal(want.Token.Metadata, got.Token.Metadata)
+}
+
+// describe formats a call for mismatch messages.
+func describe(c Call) string {
+	if c.Token != nil {
+		return fmt.Sprintf("%s(%q, %d, %q)", c.Op, c.Token.Value, c.Token.Type, c.Token.ValidationID)
+	}
+
+	return fmt.Sprintf("%s(value=%q, type=%d, validation_id=%q, duration=%s)", c.Op, c.Value, c.Type, c.ValidationID, c.Duration)
+}
+
+// Store replays a Store call.
+func (p *Player) Store(_ context.Context, t *token.Token) error {
+	c, err := p.play(Call{Op: OpStore, Token: t})
+	if err != nil {
+		return err
+	}
+
+	return c.Error.decode(c)
+}
+
+// Retrieve replays a Retrieve call.
+func (p *Player) Retrieve(_ context.Context, tokenValue string, tokenType token.Type) (*token.Token, error) {
+	return p.playToken(Call{Op: OpRetrieve, Value: tokenValue, Type: tokenType})
+}
+
+// Delete replays a Delete call.
+func (p *Player) Delete(_ context.Context, tokenValue string, tokenType token.Type) error {
+	c, err := p.play(Call{Op: OpDelete, Value: tokenValue, Type: tokenType})
+	if err != nil {
+		return err
+	}
+
+	return c.Error.decode(c)
+}
+
+// DeleteByValidationID replays a DeleteByValidationID call.
+func (p *Player) DeleteByValidationID(_ context.Context, validationID string) error {
+	c, err := p.play(Call{Op: OpDeleteByValidationID, ValidationID: validationID})
+	if err != nil {
+		return err
+	}
+
+	return c.Error.decode(c)
+}
+
+// Consume replays a Consume call.
+func (p *Player) Consume(_ context.Context, tokenValue string, tokenType token.Type) (*token.Token, error) {
+	return p.playToken(Call{Op: OpConsume, Value: tokenValue, Type: tokenType})
+}
+
+// IncrementAttempts replays an IncrementAttempts call.
+func (p *Player) IncrementAttempts(_ context.Context, validationID string, ttl time.Duration) (int, error) {
+	c, err := p.play(Call{Op: OpIncrementAttempts, ValidationID: validationID, Duration: ttl})
+	if err != nil {
+		return 0, err
+	}
+	if err := c.Error.decode(c); err != nil {
+		return 0, err
+	}
+
+	return c.Count, nil
+}
+
+// ExtendTTL replays an ExtendTTL call.
+func (p *Player) ExtendTTL(_ context.Context, tokenValue string, tokenType token.Type, extra time.Duration) (*token.Token, error) {
+	return p.playToken(Call{Op: OpExtendTTL, Value: tokenValue, Type: tokenType, Duration: extra})
+}
+
+// ListByValidationID replays a ListByValidationID call.
+func (p *Player) ListByValidationID(_ context.Context, validationID string) ([]*token.Token, error) {
+	c, err := p.play(Call{Op: OpListByValidationID, ValidationID: validationID})
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Error.decode(c); err != nil {
+		return nil, err
+	}
+
+	tokens := make([]*token.Token, 0, len(c.Results))
+	for _, t := range c.Results {
+		tokens = append(tokens, clone(t))
+	}
+
+	return tokens, nil
+}
+
+// playToken replays a call that returns a single token.
+func (p *Player) playToken(got Call) (*token.Token, error) {
+	c, err := p.play(got)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Error.decode(c); err != nil {
+		return nil, err
+	}
+
+	return clone(c.Result), nil
+}
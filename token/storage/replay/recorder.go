@@ -0,0 +1,119 @@
+package replay
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+)
+
+// Recorder is a token.Storage that passes calls to a backend and records
+// them in order.
+type Recorder struct {
+	storage token.Storage
+
+	mu    sync.Mutex
+	calls []Call
+}
+
+// NewRecorder returns a Recorder wrapping s.
+func NewRecorder(s token.Storage) *Recorder {
+	return &Recorder{storage: s}
+}
+
+// Calls returns the calls recorded so far.
+func (r *Recorder) Calls() []Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return slices.Clone(r.calls)
+}
+
+// record appends c with the outcome err and returns err unchanged, so the
+// caller sees exactly what the backend returned.
+func (r *Recorder) record(c Call, err error) error {
+	c.Error = encodeError(err)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.calls = append(r.calls, c)
+
+	return err
+}
+
+// Store records a Store call.
+func (r *Recorder) Store(ctx context.Context, t *token.Token) error {
+	err := r.storage.Store(ctx, t)
+
+	return r.record(Call{Op: OpStore, Token: clone(t)}, err)
+}
+
+// Retrieve records a Retrieve call.
+func (r *Recorder) Retrieve(ctx context.Context, tokenValue string, tokenType token.Type) (*token.Token, error) {
+	t, err := r.storage.Retrieve(ctx, tokenValue, tokenType)
+
+	return t, r.record(Call{Op: OpRetrieve, Value: tokenValue, Type: tokenType, Result: clone(t)}, err)
+}
+
+// Delete records a Delete call.
+func (r *Recorder) Delete(ctx context.Context, tokenValue string, tokenType token.Type) error {
+	err := r.storage.Delete(ctx, tokenValue, tokenType)
+
+	return r.record(Call{Op: OpDelete, Value: tokenValue, Type: tokenType}, err)
+}
+
+// DeleteByValidationID records a DeleteByValidationID call.
+func (r *Recorder) DeleteByValidationID(ctx context.Context, validationID string) error {
+	err := r.storage.DeleteByValidationID(ctx, validationID)
+
+	return r.record(Call{Op: OpDeleteByValidationID, ValidationID: validationID}, err)
+}
+
+// Consume records a Consume call.
+func (r *Recorder) Consume(ctx context.Context, tokenValue string, tokenType token.Type) (*token.Token, error) {
+	t, err := r.storage.Consume(ctx, tokenValue, tokenType)
+
+	return t, r.record(Call{Op: OpConsume, Value: tokenValue, Type: tokenType, Result: clone(t)}, err)
+}
+
+// IncrementAttempts records an IncrementAttempts call.
+func (r *Recorder) IncrementAttempts(ctx context.Context, validationID string, ttl time.Duration) (int, error) {
+	n, err := r.storage.IncrementAttempts(ctx, validationID, ttl)
+
+	return n, r.record(Call{Op: OpIncrementAttempts, ValidationID: validationID, Duration: ttl, Count: n}, err)
+}
+
+// ExtendTTL records an ExtendTTL call.
+func (r *Recorder) ExtendTTL(ctx context.Context, tokenValue string, tokenType token.Type, extra time.Duration) (*token.Token, error) {
+	t, err := r.storage.ExtendTTL(ctx, tokenValue, tokenType, extra)
+
+	return t, r.record(Call{Op: OpExtendTTL, Value: tokenValue, Type: tokenType, Duration: extra, Result: clone(t)}, err)
+}
+
+// ListByValidationID records a ListByValidationID call.
+func (r *Recorder) ListByValidationID(ctx context.Context, validationID string) ([]*token.Token, error) {
+	tokens, err := r.storage.ListByValidationID(ctx, validationID)
+
+	results := make([]*token.Token, 0, len(tokens))
+	for _, t := range tokens {
+		results = append(results, clone(t))
+	}
+
+	return tokens, r.record(Call{Op: OpListByValidationID, ValidationID: validationID, Results: results}, err)
+}
+
+// clone returns a copy of t, or nil if t is nil.
+func clone(t *token.Token) *token.Token {
+	if t == nil {
+		return nil
+	}
+
+	c := *t
+	c.Metadata = maps.Clone(t.Metadata)
+
+	return &c
+}
@@ -0,0 +1,170 @@
+// Package replay records the calls made to a token storage backend and
+// replays them deterministically, so that tests of higher layers can run
+// against behavior captured from a real backend without running it.
+//
+// A Recorder wraps a backend during an integration run and Save writes the
+// captured calls as JSON lines. A Player loaded from that file implements
+// token.Storage by checking each call against the next recorded one and
+// returning the recorded result. Recordings only replay faithfully when the
+// code under test makes the same calls, so it should use a deterministic
+// token generator and clock.
+package replay
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+)
+
+// Operation names used in recordings.
+const (
+	OpStore                = "Store"
+	OpRetrieve             = "Retrieve"
+	OpDelete               = "Delete"
+	OpDeleteByValidationID = "DeleteByValidationID"
+	OpConsume              = "Consume"
+	OpIncrementAttempts    = "IncrementAttempts"
+	OpExtendTTL            = "ExtendTTL"
+	OpListByValidationID   = "ListByValidationID"
+)
+
+// Call is one recorded storage call: its arguments and its outcome.
+type Call struct {
+	Op string `json:"op"`
+
+	// Arguments. Store records the stored token in Token.
+	Value        string        `json:"value,omitempty"`
+	Type         token.Type    `json:"type,omitempty"`
+	ValidationID string        `json:"validation_id,omitempty"`
+	Duration     time.Duration `json:"duration,omitempty"`
+	Token        *token.Token  `json:"token,omitempty"`
+
+	// Outcome.
+	Result  *token.Token   `json:"result,omitempty"`
+	Results []*token.Token `json:"results,omitempty"`
+	Count   int            `json:"count,omitempty"`
+	Error   *Error         `json:"error,omitempty"`
+}
+
+// Error is a recorded error. Kind identifies errors that callers match with
+// errors.Is or errors.As, so that replayed errors match the same way.
+type Error struct {
+	Kind      string    `json:"kind,omitempty"`
+	Message   string    `json:"message"`
+	ExpiredAt time.Time `json:"expired_at,omitzero"`
+
+	ValidationID string `json:"validation_id,omitempty"`
+}
+
+// kindExpired is the Kind of a recorded token.TokenExpiredError.
+const kindExpired = "expired"
+
+// sentinels maps error kinds to the errors they stand for.
+var sentinels = map[string]error{
+	"not_found":           token.ErrTokenNotFound,
+	"invalid_token":       token.ErrInvalidToken,
+	"invalid_token_type":  token.ErrInvalidTokenType,
+	"token_nil":           token.ErrTokenNil,
+	"empty_token_value":   token.ErrEmptyTokenValue,
+	"empty_validation_id": token.ErrEmptyValidationID,
+	"type_mismatch":       token.ErrTokenTypeMismatch,
+}
+
+// encodeError records err, or returns nil if err is nil.
+func encodeError(err error) *Error {
+	if err == nil {
+		return nil
+	}
+
+	e := &Error{Message: err.Error()}
+
+	var expired *token.TokenExpiredError
+	if errors.As(err, &expired) {
+		e.Kind = kindExpired
+		e.ExpiredAt = expired.ExpiredAt
+		e.ValidationID = expired.ValidationID
+		return e
+	}
+
+	for kind, sentinel := range sentinels {
+		if errors.Is(err, sentinel) {
+			e.Kind = kind
+			break
+		}
+	}
+
+	return e
+}
+
+// decode returns an error with the recorded message that matches the
+// recorded kind.
+func (e *Error) decode(c Call) error {
+	if e == nil {
+		return nil
+	}
+
+	if e.Kind == kindExpired {
+		return &token.TokenExpiredError{TokenValue: c.Value, TokenType: c.Type, ExpiredAt: e.ExpiredAt, ValidationID: e.ValidationID}
+	}
+
+	return &replayedError{message: e.Message, sentinel: sentinels[e.Kind]}
+}
+
+// replayedError carries a recorded message and unwraps to the sentinel of
+// its kind, if any.
+type replayedError struct {
+	message  string
+	sentinel error
+}
+
+// Error implements the error interface.
+func (e *replayedError) Error() string {
+	return e.message
+}
+
+// Unwrap returns the sentinel error the recorded error matched.
+func (e *replayedError) Unwrap() error {
+	return e.sentinel
+}
+
+// Save writes calls to w as JSON lines.
+func Save(w io.Writer, calls []Call) error {
+	enc := json.NewEncoder(w)
+	for i, c := range calls {
+		if err := enc.Encode(c); err != nil {
+			return fmt.Errorf("failed to encode call %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// Load reads calls written by Save.
+func Load(r io.Reader) ([]Call, error) {
+	var calls []Call
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var c Call
+		if err := json.Unmarshal(scanner.Bytes(), &c); err != nil {
+			return nil, fmt.Errorf("failed to decode call on line %d: %w", line, err)
+		}
+		calls = append(calls, c)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read recording: %w", err)
+	}
+
+	return calls, nil
+}
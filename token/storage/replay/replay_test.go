@@ -0,0 +1,149 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+	redisstorage "github.com/jaeyeom/email-validator-grpc-mcp/token/storage/redis"
+	"github.com/redis/go-redis/v9"
+)
+
+// sequenceGenerator is a deterministic TokenGenerator for tests.
+type sequenceGenerator struct {
+	next int
+}
+
+func (g *sequenceGenerator) GenerateLinkToken() (string, error) {
+	g.next++
+	return fmt.Sprintf("link-%d", g.next), nil
+}
+
+func (g *sequenceGenerator) GenerateCodeToken() (string, error) {
+	g.next++
+	return fmt.Sprintf("%06d", g.next), nil
+}
+
+// scenario drives a Manager through a typical validation and returns a
+// transcript of the outcomes.
+func scenario(ctx context.Context, storage token.Storage) []string {
+	manager := token.NewManager(storage, token.WithGenerator(&sequenceGenerator{}))
+
+	var transcript []string
+	note := func(step string, t *token.Token, err error) {
+		switch {
+		case err != nil:
+			transcript = append(transcript, fmt.Sprintf("%s: %s", step, token.ReasonOf(err)))
+		case t != nil:
+			transcript = append(transcript, fmt.Sprintf("%s: %s", step, t.Value))
+		default:
+			transcript = append(transcript, step+": ok")
+		}
+	}
+
+	link, err := manager.CreateLinkToken(ctx, "validation-1")
+	note("create link", link, err)
+	code, err := manager.CreateCodeToken(ctx, "validation-1")
+	note("create code", code, err)
+
+	t, err := manager.VerifyCodeToken(ctx, "validation-1", "999999")
+	note("wrong code", t, err)
+	t, err = manager.ExtendTokenTTL(ctx, code.Value, token.TypeCode, time.Minute)
+	note("extend code", t, err)
+	t, err = manager.CreateOrGetLinkToken(ctx, "validation-1")
+	note("create or get link", t, err)
+	t, err = manager.VerifyAndConsume(ctx, link.Value, token.TypeLink)
+	note("consume link", t, err)
+	t, err = manager.VerifyToken(ctx, link.Value, token.TypeLink)
+	note("reuse link", t, err)
+	note("invalidate", nil, manager.InvalidateValidation(ctx, "validation-1"))
+	t, err = manager.VerifyCodeToken(ctx, "validation-1", code.Value)
+	note("code after invalidate", t, err)
+
+	return transcript
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("Failed to start miniredis: %v", err)
+	}
+	t.Cleanup(mr.Close)
+
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	recorder := NewRecorder(redisstorage.New(client))
+	recorded := scenario(ctx, recorder)
+
+	var buf bytes.Buffer
+	if err := Save(&buf, recorder.Calls()); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	calls, err := Load(&buf)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(calls) != len(recorder.Calls()) {
+		t.Fatalf("Load() returned %d calls, want %d", len(calls), len(recorder.Calls()))
+	}
+
+	player := NewPlayer(calls)
+	replayed := scenario(ctx, player)
+
+	if err := player.Err(); err != nil {
+		t.Errorf("Player.Err() = %v", err)
+	}
+	if !slices.Equal(replayed, recorded) {
+		t.Errorf("replayed transcript = %q, want %q", replayed, recorded)
+	}
+}
+
+func TestPlayer_Mismatch(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	player := NewPlayer([]Call{
+		{Op: OpRetrieve, Value: "abc", Type: token.TypeLink, Error: &Error{Kind: "not_found", Message: "token not found"}},
+		{Op: OpDelete, Value: "abc", Type: token.TypeLink},
+	})
+
+	if _, err := player.Retrieve(ctx, "abc", token.TypeLink); !errors.Is(err, token.ErrTokenNotFound) {
+		t.Errorf("Retrieve() error = %v, want %v", err, token.ErrTokenNotFound)
+	}
+	if _, err := player.Consume(ctx, "abc", token.TypeLink); !errors.Is(err, ErrUnexpectedCall) {
+		t.Errorf("Consume() error = %v, want %v", err, ErrUnexpectedCall)
+	}
+
+	err := player.Err()
+	if !errors.Is(err, ErrUnexpectedCall) {
+		t.Errorf("Player.Err() = %v, want it to report the unexpected call", err)
+	}
+}
+
+func TestPlayer_Expired(t *testing.T) {
+	t.Parallel()
+
+	expiredAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	recorder := NewRecorder(nil)
+	recorder.record(Call{Op: OpRetrieve, Value: "abc"}, &token.TokenExpiredError{TokenValue: "abc", ExpiredAt: expiredAt})
+
+	player := NewPlayer(recorder.Calls())
+	_, err := player.Retrieve(context.Background(), "abc", token.TypeLink)
+
+	var expired *token.TokenExpiredError
+	if !errors.As(err, &expired) || !expired.ExpiredAt.Equal(expiredAt) {
+		t.Errorf("Retrieve() error = %v, want TokenExpiredError at %v", err, expiredAt)
+	}
+	if err := player.Err(); err != nil {
+		t.Errorf("Player.Err() = %v", err)
+	}
+}
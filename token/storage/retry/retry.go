@@ -0,0 +1,299 @@
+// Package retry provides a token storage decorator that retries transient
+// backend failures, such as those seen while Redis fails over, with
+// exponential backoff and jitter.
+package retry
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+)
+
+// Policy controls how failed calls are retried. Zero fields take their
+// value from DefaultPolicy.
+type Policy struct {
+	// MaxAttempts is the total number of attempts, including the first.
+	MaxAttempts int
+
+	// InitialBackoff is the delay before the first retry.
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the delay between attempts.
+	MaxBackoff time.Duration
+
+	// Multiplier scales the delay after each retry.
+	Multiplier float64
+
+	// Jitter is the fraction of each delay, between 0 and 1, that is
+	// randomized so that clients recovering from the same outage do not
+	// retry in lockstep.
+	Jitter float64
+
+	// Retryable reports whether an error is worth retrying. It defaults to
+	// IsTransient.
+	Retryable func(error) bool
+
+	// RetryNonIdempotent allows IncrementAttempts and ExtendTTL to be
+	// retried. A retry after a failure whose effect was applied but not
+	// acknowledged would count the attempt or extend the token twice.
+	RetryNonIdempotent bool
+}
+
+// DefaultPolicy retries up to three times over roughly a third of a second,
+// which covers a typical Redis replica promotion.
+var DefaultPolicy = Policy{
+	MaxAttempts:    4,
+	InitialBackoff: 50 * time.Millisecond,
+	MaxBackoff:     time.Second,
+	Multiplier:     2,
+	Jitter:         0.5,
+	Retryable:      IsTransient,
+}
+
+// withDefaults fills zero fields from DefaultPolicy.
+func (p Policy) withDefaults() Policy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = DefaultPolicy.MaxAttempts
+	}
+	if p.InitialBackoff <= 0 {
+		p.InitialBackoff = DefaultPolicy.InitialBackoff
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = DefaultPolicy.MaxBackoff
+	}
+	if p.Multiplier < 1 {
+		p.Multiplier = DefaultPolicy.Multiplier
+	}
+	if p.Jitter < 0 || p.Jitter > 1 {
+		p.Jitter = DefaultPolicy.Jitter
+	}
+	if p.Retryable == nil {
+		p.Retryable = IsTransient
+	}
+
+	return p
+}
+
+// backoff returns the delay before retry n, counting from zero.
+func (p Policy) backoff(n int) time.Duration {
+	d := float64(p.InitialBackoff)
+	for range n {
+		d *= p.Multiplier
+		if d >= float64(p.MaxBackoff) {
+			d = float64(p.MaxBackoff)
+			break
+		}
+	}
+
+	return time.Duration(d * (1 - p.Jitter*randFloat()))
+}
+
+// IsTransient reports whether err may succeed on retry. Outcomes that the
+// backend reported deliberately, such as a missing or expired token, and
+// context cancellation are permanent; anything else is assumed to be a
+// connectivity or availability failure.
+func IsTransient(err error) bool {
+	switch {
+	case err == nil,
+		errors.Is(err, context.Canceled),
+		errors.Is(err, context.DeadlineExceeded),
+		errors.Is(err, token.ErrTokenNotFound),
+		errors.Is(err, token.ErrInvalidToken),
+		errors.Is(err, token.ErrInvalidTokenType),
+		errors.Is(err, token.ErrInvalidTokenKeyType),
+		errors.Is(err, token.ErrTokenNil),
+		errors.Is(err, token.ErrEmptyTokenValue),
+		errors.Is(err, token.ErrEmptyValidationID),
+		errors.Is(err, token.ErrTokenTypeMismatch),
+		token.IsTokenExpiredError(err):
+		return false
+	default:
+		return true
+	}
+}
+
+// Storage wraps a token.Storage and retries its transient failures.
+type Storage struct {
+	storage token.Storage
+	policy  Policy
+	logger  *slog.Logger
+}
+
+// Option is a functional option for configuring Storage.
+type Option func(*Storage)
+
+// WithLogger sets a custom logger for Storage.
+func WithLogger(logger *slog.Logger) Option {
+	return func(s *Storage) {
+		s.logger = logger
+	}
+}
+
+// Wrap returns s decorated to retry transient failures according to p.
+// Retries stop early when the context is done or its deadline would pass
+// before the next attempt.
+func Wrap(s token.Storage, p Policy, opts ...Option) *Storage {
+	r := &Storage{
+		storage: s,
+		policy:  p.withDefaults(),
+		logger:  slog.Default(),
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// Store saves a token, retrying transient failures.
+func (s *Storage) Store(ctx context.Context, t *token.Token) error {
+	_, err := do(ctx, s, "Store", true, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, s.storage.Store(ctx, t)
+	})
+
+	return err
+}
+
+// StoreBatch saves tokens with the wrapped storage's StoreBatch when it
+// has one, and with token.StoreEach otherwise, retrying transient failures.
+func (s *Storage) StoreBatch(ctx context.Context, tokens []*token.Token) error {
+	batch, ok := s.storage.(token.BatchStorage)
+	if !ok {
+		return token.StoreEach(ctx, tokens, s.Store)
+	}
+
+	_, err := do(ctx, s, "StoreBatch", true, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, batch.StoreBatch(ctx, tokens)
+	})
+
+	return err
+}
+
+// Retrieve gets a token, retrying transient failures.
+func (s *Storage) Retrieve(ctx context.Context, tokenValue string, tokenType token.Type) (*token.Token, error) {
+	return do(ctx, s, "Retrieve", true, func(ctx context.Context) (*token.Token, error) {
+		return s.storage.Retrieve(ctx, tokenValue, tokenType)
+	})
+}
+
+// Delete removes a token, retrying transient failures.
+func (s *Storage) Delete(ctx context.Context, tokenValue string, tokenType token.Type) error {
+	_, err := do(ctx, s, "Delete", true, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, s.storage.Delete(ctx, tokenValue, tokenType)
+	})
+
+	return err
+}
+
+// DeleteBatch removes tokens with the wrapped storage's DeleteBatch when it
+// has one, and with token.DeleteEach otherwise, retrying transient failures.
+func (s *Storage) DeleteBatch(ctx context.Context, refs []token.TokenRef) error {
+	batch, ok := s.storage.(token.BatchStorage)
+	if !ok {
+		return token.DeleteEach(ctx, refs, s.Delete)
+	}
+
+	_, err := do(ctx, s, "DeleteBatch", true, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, batch.DeleteBatch(ctx, refs)
+	})
+
+	return err
+}
+
+// DeleteByValidationID removes a validation's tokens, retrying transient
+// failures.
+func (s *Storage) DeleteByValidationID(ctx context.Context, validationID string) error {
+	_, err := do(ctx, s, "DeleteByValidationID", true, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, s.storage.DeleteByValidationID(ctx, validationID)
+	})
+
+	return err
+}
+
+// Consume atomically takes a token, retrying transient failures. If an
+// earlier attempt consumed the token without acknowledging it, the retry
+// reports the token as not found, so a token is never used twice.
+func (s *Storage) Consume(ctx context.Context, tokenValue string, tokenType token.Type) (*token.Token, error) {
+	return do(ctx, s, "Consume", true, func(ctx context.Context) (*token.Token, error) {
+		return s.storage.Consume(ctx, tokenValue, tokenType)
+	})
+}
+
+// IncrementAttempts counts an attempt. It is retried only when the policy
+// allows retrying non-idempotent operations.
+func (s *Storage) IncrementAttempts(ctx context.Context, validationID string, ttl time.Duration) (int, error) {
+	return do(ctx, s, "IncrementAttempts", false, func(ctx context.Context) (int, error) {
+		return s.storage.IncrementAttempts(ctx, validationID, ttl)
+	})
+}
+
+// ExtendTTL extends a token's validity. It is retried only when the policy
+// allows retrying non-idempotent operations.
+func (s *Storage) ExtendTTL(ctx context.Context, tokenValue string, tokenType token.Type, extra time.Duration) (*token.Token, error) {
+	return do(ctx, s, "ExtendTTL", false, func(ctx context.Context) (*token.Token, error) {
+		return s.storage.ExtendTTL(ctx, tokenValue, tokenType, extra)
+	})
+}
+
+// ListByValidationID lists a validation's tokens, retrying transient
+// failures.
+func (s *Storage) ListByValidationID(ctx context.Context, validationID string) ([]*token.Token, error) {
+	return do(ctx, s, "ListByValidationID", true, func(ctx context.Context) ([]*token.Token, error) {
+		return s.storage.ListByValidationID(ctx, validationID)
+	})
+}
+
+// do calls fn until it succeeds, fails permanently, runs out of attempts,
+// or the context would end before the next attempt.
+func do[T any](ctx context.Context, s *Storage, op string, idempotent bool, fn func(context.Context) (T, error)) (T, error) {
+	attempts := s.policy.MaxAttempts
+	if !idempotent && !s.policy.RetryNonIdempotent {
+		attempts = 1
+	}
+
+	for n := 0; ; n++ {
+		v, err := fn(ctx)
+		if err == nil || n+1 >= attempts || !s.policy.Retryable(err) {
+			if err != nil && n > 0 {
+				return v, fmt.Errorf("%s failed after %d attempts: %w", op, n+1, err)
+			}
+			return v, err
+		}
+
+		delay := s.policy.backoff(n)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return v, fmt.Errorf("%s failed, no time left to retry: %w", op, err)
+		}
+
+		s.logger.Warn("retrying storage operation",
+			"operation", op,
+			"attempt", n+1,
+			"delay", delay,
+			"error", err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return v, fmt.Errorf("%s failed, context done before retry: %w: %w", op, ctx.Err(), err)
+		case <-timer.C:
+		}
+	}
+}
+
+// randFloat returns a uniformly distributed float64 in [0, 1).
+func randFloat() float64 {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return 0
+	}
+
+	return float64(binary.LittleEndian.Uint64(b[:])>>11) / (1 << 53)
+}
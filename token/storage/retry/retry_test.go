@@ -0,0 +1,228 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+	"github.com/jaeyeom/email-validator-grpc-mcp/token/storage/memory"
+)
+
+var errUnavailable = errors.New("backend unavailable")
+
+// flakyStorage fails the first failures calls to each wrapped method.
+type flakyStorage struct {
+	token.Storage
+	failures atomic.Int32
+	calls    atomic.Int32
+}
+
+func (f *flakyStorage) fail() error {
+	f.calls.Add(1)
+	if f.failures.Add(-1) >= 0 {
+		return errUnavailable
+	}
+
+	return nil
+}
+
+func (f *flakyStorage) Store(ctx context.Context, t *token.Token) error {
+	if err := f.fail(); err != nil {
+		return err
+	}
+
+	return f.Storage.Store(ctx, t)
+}
+
+func (f *flakyStorage) Retrieve(ctx context.Context, tokenValue string, tokenType token.Type) (*token.Token, error) {
+	if err := f.fail(); err != nil {
+		return nil, err
+	}
+
+	return f.Storage.Retrieve(ctx, tokenValue, tokenType)
+}
+
+func (f *flakyStorage) IncrementAttempts(ctx context.Context, validationID string, ttl time.Duration) (int, error) {
+	if err := f.fail(); err != nil {
+		return 0, err
+	}
+
+	return f.Storage.IncrementAttempts(ctx, validationID, ttl)
+}
+
+func newFlaky(failures int32) *flakyStorage {
+	f := &flakyStorage{Storage: memory.New()}
+	f.failures.Store(failures)
+
+	return f
+}
+
+var fastPolicy = Policy{
+	MaxAttempts:    3,
+	InitialBackoff: time.Millisecond,
+	MaxBackoff:     2 * time.Millisecond,
+}
+
+func newToken() *token.Token {
+	return &token.Token{
+		Value:        "abc",
+		Type:         token.TypeLink,
+		ValidUntil:   time.Now().Add(time.Hour),
+		ValidationID: "validation-123",
+	}
+}
+
+func TestStorage_Retries(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		failures  int32
+		wantErr   error
+		wantCalls int32
+	}{
+		{name: "no failures", failures: 0, wantCalls: 1},
+		{name: "recovers", failures: 2, wantCalls: 3},
+		{name: "exhausted", failures: 5, wantErr: errUnavailable, wantCalls: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			backend := newFlaky(tt.failures)
+			s := Wrap(backend, fastPolicy)
+
+			err := s.Store(context.Background(), newToken())
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Store() error = %v, want %v", err, tt.wantErr)
+			}
+			if got := backend.calls.Load(); got != tt.wantCalls {
+				t.Errorf("Store() made %d calls, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestStorage_PermanentErrors(t *testing.T) {
+	t.Parallel()
+
+	backend := newFlaky(0)
+	s := Wrap(backend, fastPolicy)
+
+	_, err := s.Retrieve(context.Background(), "missing", token.TypeLink)
+	if !errors.Is(err, token.ErrTokenNotFound) {
+		t.Errorf("Retrieve() error = %v, want %v", err, token.ErrTokenNotFound)
+	}
+	if got := backend.calls.Load(); got != 1 {
+		t.Errorf("Retrieve() made %d calls, want 1", got)
+	}
+}
+
+func TestStorage_NonIdempotent(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		policy    Policy
+		wantErr   error
+		wantCalls int32
+	}{
+		{name: "not retried by default", policy: fastPolicy, wantErr: errUnavailable, wantCalls: 1},
+		{
+			name: "retried when allowed",
+			policy: Policy{
+				MaxAttempts:        3,
+				InitialBackoff:     time.Millisecond,
+				RetryNonIdempotent: true,
+			},
+			wantCalls: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			backend := newFlaky(1)
+			s := Wrap(backend, tt.policy)
+
+			_, err := s.IncrementAttempts(context.Background(), "validation-123", time.Minute)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("IncrementAttempts() error = %v, want %v", err, tt.wantErr)
+			}
+			if got := backend.calls.Load(); got != tt.wantCalls {
+				t.Errorf("IncrementAttempts() made %d calls, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestStorage_HonorsDeadline(t *testing.T) {
+	t.Parallel()
+
+	backend := newFlaky(10)
+	s := Wrap(backend, Policy{MaxAttempts: 10, InitialBackoff: time.Second, Jitter: 0})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := s.Store(ctx, newToken())
+	if !errors.Is(err, errUnavailable) {
+		t.Errorf("Store() error = %v, want %v", err, errUnavailable)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Store() took %v, want it to give up before the deadline", elapsed)
+	}
+	if got := backend.calls.Load(); got != 1 {
+		t.Errorf("Store() made %d calls, want 1", got)
+	}
+}
+
+func TestStorage_Canceled(t *testing.T) {
+	t.Parallel()
+
+	backend := newFlaky(10)
+	s := Wrap(backend, Policy{MaxAttempts: 10, InitialBackoff: time.Second})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	err := s.Store(ctx, newToken())
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Store() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestStorage_StoreBatch(t *testing.T) {
+	t.Parallel()
+
+	backend := newFlaky(1)
+	s := Wrap(backend, fastPolicy)
+
+	tokens := []*token.Token{newToken()}
+	if err := s.StoreBatch(context.Background(), tokens); err != nil {
+		t.Fatalf("StoreBatch() error = %v", err)
+	}
+	if _, err := backend.Storage.Retrieve(context.Background(), "abc", token.TypeLink); err != nil {
+		t.Errorf("Retrieve() error = %v", err)
+	}
+}
+
+func TestPolicy_Backoff(t *testing.T) {
+	t.Parallel()
+
+	p := Policy{InitialBackoff: 10 * time.Millisecond, MaxBackoff: 50 * time.Millisecond, Multiplier: 2, Jitter: 0.5}.withDefaults()
+
+	for n, ceiling := range []time.Duration{10, 20, 40, 50, 50} {
+		ceiling *= time.Millisecond
+		got := p.backoff(n)
+		if got > ceiling || got < ceiling/2 {
+			t.Errorf("backoff(%d) = %v, want in [%v, %v]", n, got, ceiling/2, ceiling)
+		}
+	}
+}
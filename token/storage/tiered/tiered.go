@@ -0,0 +1,298 @@
+// Package tiered provides a two-tier token storage: a fast hot backend,
+// usually in-memory, in front of a durable cold backend, usually remote.
+// Writes go through to both tiers and lookups are served from the hot tier
+// when it has the token, which keeps verification latency low when the
+// durable store is a network roundtrip away.
+package tiered
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+)
+
+// DefaultMinPromoteTTL is the default validity a token read from the cold
+// tier must have left to be promoted to the hot tier.
+const DefaultMinPromoteTTL = time.Second
+
+// Storage is a read-through, write-through pair of backends. The cold tier
+// is the source of truth: every mutation is applied to it first, and fails
+// if it fails. The hot tier is updated afterwards on a best-effort basis.
+//
+// Consume, IncrementAttempts, and ListByValidationID always reach the cold
+// tier, so a token is never used twice and attempt counts are shared by
+// every process using the cold tier. Retrieve may however serve a token
+// that another process deleted or consumed until the hot copy expires, so
+// the hot tier should only be shared by processes that hold no other
+// references to the cold tier, or be bounded, for example with
+// memory.WithMaxTokens.
+type Storage struct {
+	hot    token.Storage
+	cold   token.Storage
+	logger *slog.Logger
+	clock  token.Clock
+
+	minPromoteTTL time.Duration
+
+	hits       atomic.Uint64
+	misses     atomic.Uint64
+	promotions atomic.Uint64
+}
+
+// Stats reports how often lookups were served by each tier.
+type Stats struct {
+	// Hits counts Retrieve calls served by the hot tier, and Misses those
+	// that reached the cold tier.
+	Hits   uint64
+	Misses uint64
+
+	// Promotions counts tokens copied from the cold tier to the hot tier.
+	Promotions uint64
+}
+
+// Option is a functional option for configuring Storage.
+type Option func(*Storage)
+
+// WithLogger sets a custom logger for Storage.
+func WithLogger(logger *slog.Logger) Option {
+	return func(s *Storage) {
+		s.logger = logger
+	}
+}
+
+// WithClock sets the clock used to decide promotions. It should match the
+// clock of the hot tier.
+func WithClock(clock token.Clock) Option {
+	return func(s *Storage) {
+		s.clock = clock
+	}
+}
+
+// WithMinPromoteTTL sets the validity a token read from the cold tier must
+// have left to be promoted. Tokens about to expire are not worth the hot
+// tier's space, and would mostly be served expired from it. Zero promotes
+// every valid token.
+func WithMinPromoteTTL(d time.Duration) Option {
+	return func(s *Storage) {
+		if d >= 0 {
+			s.minPromoteTTL = d
+		}
+	}
+}
+
+// New creates a tiered storage serving lookups from hot and persisting
+// tokens in cold.
+func New(hot, cold token.Storage, opts ...Option) *Storage {
+	s := &Storage{
+		hot:           hot,
+		cold:          cold,
+		logger:        slog.Default(),
+		clock:         token.SystemClock,
+		minPromoteTTL: DefaultMinPromoteTTL,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Store saves the token to the cold tier, then to the hot tier.
+func (s *Storage) Store(ctx context.Context, t *token.Token) error {
+	if err := s.cold.Store(ctx, t); err != nil {
+		return fmt.Errorf("cold store failed: %w", err)
+	}
+
+	s.storeHot(ctx, "Store", t)
+
+	return nil
+}
+
+// StoreBatch saves tokens to the cold tier, with its StoreBatch when it has
+// one and token.StoreEach otherwise, then to the hot tier. Invalid tokens
+// are rejected by the cold tier before any is stored.
+func (s *Storage) StoreBatch(ctx context.Context, tokens []*token.Token) error {
+	if batch, ok := s.cold.(token.BatchStorage); ok {
+		if err := batch.StoreBatch(ctx, tokens); err != nil {
+			return fmt.Errorf("cold store batch failed: %w", err)
+		}
+	} else if err := token.StoreEach(ctx, tokens, s.cold.Store); err != nil {
+		return fmt.Errorf("cold store failed: %w", err)
+	}
+
+	for _, t := range tokens {
+		s.storeHot(ctx, "StoreBatch", t)
+	}
+
+	return nil
+}
+
+// Retrieve returns the token from the hot tier when it has it, and reads
+// the cold tier otherwise. Tokens found in the cold tier are promoted to
+// the hot tier with their own expiry, so the hot copy never outlives the
+// token.
+func (s *Storage) Retrieve(ctx context.Context, tokenValue string, tokenType token.Type) (*token.Token, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("context error: %w", err)
+	}
+
+	t, err := s.hot.Retrieve(ctx, tokenValue, tokenType)
+	if err == nil {
+		s.hits.Add(1)
+		return t, nil
+	}
+	if !errors.Is(err, token.ErrTokenNotFound) && !token.IsTokenExpiredError(err) {
+		s.logger.Warn("hot tier retrieve failed, reading cold tier",
+			"operation", "Retrieve",
+			"error", err)
+	}
+	s.misses.Add(1)
+
+	t, err = s.cold.Retrieve(ctx, tokenValue, tokenType)
+	if err != nil {
+		return nil, fmt.Errorf("cold retrieve failed: %w", err)
+	}
+
+	if t.ValidUntil.Sub(s.clock.Now()) >= s.minPromoteTTL {
+		promoted := *t
+		s.storeHot(ctx, "Retrieve", &promoted)
+		s.promotions.Add(1)
+	}
+
+	return t, nil
+}
+
+// Delete removes the token from the cold tier and drops any hot copy.
+func (s *Storage) Delete(ctx context.Context, tokenValue string, tokenType token.Type) error {
+	defer s.deleteHot(ctx, "Delete", tokenValue, tokenType)
+
+	if err := s.cold.Delete(ctx, tokenValue, tokenType); err != nil {
+		return fmt.Errorf("cold delete failed: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteBatch removes tokens from the cold tier, with its DeleteBatch when
+// it has one and token.DeleteEach otherwise, and drops any hot copies.
+func (s *Storage) DeleteBatch(ctx context.Context, refs []token.TokenRef) error {
+	defer func() {
+		for _, ref := range refs {
+			s.deleteHot(ctx, "DeleteBatch", ref.Value, ref.Type)
+		}
+	}()
+
+	batch, ok := s.cold.(token.BatchStorage)
+	if !ok {
+		if err := token.DeleteEach(ctx, refs, s.cold.Delete); err != nil {
+			return fmt.Errorf("cold delete failed: %w", err)
+		}
+		return nil
+	}
+
+	if err := batch.DeleteBatch(ctx, refs); err != nil {
+		return fmt.Errorf("cold delete batch failed: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteByValidationID removes the validation's tokens from the cold tier,
+// then from the hot tier.
+func (s *Storage) DeleteByValidationID(ctx context.Context, validationID string) error {
+	if err := s.cold.DeleteByValidationID(ctx, validationID); err != nil {
+		return fmt.Errorf("cold delete by validation ID failed: %w", err)
+	}
+
+	if err := s.hot.DeleteByValidationID(ctx, validationID); err != nil {
+		s.logger.Warn("failed to update hot tier",
+			"operation", "DeleteByValidationID",
+			"error", err)
+	}
+
+	return nil
+}
+
+// Consume takes the token from the cold tier and drops any hot copy.
+func (s *Storage) Consume(ctx context.Context, tokenValue string, tokenType token.Type) (*token.Token, error) {
+	defer s.deleteHot(ctx, "Consume", tokenValue, tokenType)
+
+	t, err := s.cold.Consume(ctx, tokenValue, tokenType)
+	if err != nil {
+		return nil, fmt.Errorf("cold consume failed: %w", err)
+	}
+
+	return t, nil
+}
+
+// IncrementAttempts counts the attempt in the cold tier.
+func (s *Storage) IncrementAttempts(ctx context.Context, validationID string, ttl time.Duration) (int, error) {
+	n, err := s.cold.IncrementAttempts(ctx, validationID, ttl)
+	if err != nil {
+		return 0, fmt.Errorf("cold increment attempts failed: %w", err)
+	}
+
+	return n, nil
+}
+
+// ExtendTTL extends the token in the cold tier and writes the extended
+// token through to the hot tier.
+func (s *Storage) ExtendTTL(ctx context.Context, tokenValue string, tokenType token.Type, extra time.Duration) (*token.Token, error) {
+	t, err := s.cold.ExtendTTL(ctx, tokenValue, tokenType, extra)
+	if err != nil {
+		s.deleteHot(ctx, "ExtendTTL", tokenValue, tokenType)
+		return nil, fmt.Errorf("cold extend TTL failed: %w", err)
+	}
+
+	extended := *t
+	s.storeHot(ctx, "ExtendTTL", &extended)
+
+	return t, nil
+}
+
+// ListByValidationID lists the validation's tokens from the cold tier,
+// since the hot tier may hold only some of them.
+func (s *Storage) ListByValidationID(ctx context.Context, validationID string) ([]*token.Token, error) {
+	tokens, err := s.cold.ListByValidationID(ctx, validationID)
+	if err != nil {
+		return nil, fmt.Errorf("cold list failed: %w", err)
+	}
+
+	return tokens, nil
+}
+
+// Stats returns the hit, miss, and promotion counts so far.
+func (s *Storage) Stats() Stats {
+	return Stats{
+		Hits:       s.hits.Load(),
+		Misses:     s.misses.Load(),
+		Promotions: s.promotions.Load(),
+	}
+}
+
+// storeHot writes t to the hot tier. A token the hot tier fails to take is
+// dropped from it instead, so a stale copy is not served.
+func (s *Storage) storeHot(ctx context.Context, op string, t *token.Token) {
+	if err := s.hot.Store(ctx, t); err != nil {
+		s.logger.Warn("failed to update hot tier",
+			"operation", op,
+			"error", err)
+		s.deleteHot(ctx, op, t.Value, t.Type)
+	}
+}
+
+// deleteHot removes a token from the hot tier, treating a token that is
+// already absent as removed.
+func (s *Storage) deleteHot(ctx context.Context, op string, tokenValue string, tokenType token.Type) {
+	if err := s.hot.Delete(ctx, tokenValue, tokenType); err != nil && !errors.Is(err, token.ErrTokenNotFound) {
+		s.logger.Warn("failed to remove token from hot tier",
+			"operation", op,
+			"error", err)
+	}
+}
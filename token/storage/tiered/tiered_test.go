@@ -0,0 +1,170 @@
+package tiered
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+	"github.com/jaeyeom/email-validator-grpc-mcp/token/storage/memory"
+	"github.com/jaeyeom/email-validator-grpc-mcp/token/storagetest"
+)
+
+var errUnavailable = errors.New("cold tier unavailable")
+
+// coldStorage counts Retrieve calls and fails Store calls on demand.
+type coldStorage struct {
+	token.Storage
+	retrieves atomic.Int32
+	failStore atomic.Bool
+}
+
+func (c *coldStorage) Store(ctx context.Context, t *token.Token) error {
+	if c.failStore.Load() {
+		return errUnavailable
+	}
+	return c.Storage.Store(ctx, t)
+}
+
+func (c *coldStorage) Retrieve(ctx context.Context, tokenValue string, tokenType token.Type) (*token.Token, error) {
+	c.retrieves.Add(1)
+	return c.Storage.Retrieve(ctx, tokenValue, tokenType)
+}
+
+// fakeClock is a settable clock.
+type fakeClock struct {
+	now atomic.Int64
+}
+
+func (c *fakeClock) Now() time.Time {
+	return time.Unix(0, c.now.Load())
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now.Add(int64(d))
+}
+
+func setup(t *testing.T) (*Storage, token.Storage, *coldStorage, *fakeClock) {
+	t.Helper()
+
+	clock := &fakeClock{}
+	clock.now.Store(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC).UnixNano())
+
+	hot := memory.New(memory.WithClock(clock))
+	cold := &coldStorage{Storage: memory.New(memory.WithClock(clock))}
+	s := New(hot, cold, WithClock(clock), WithLogger(slog.New(slog.DiscardHandler)))
+
+	return s, hot, cold, clock
+}
+
+func TestStorage_Retrieve(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	s, hot, cold, clock := setup(t)
+
+	// Written through: served by the hot tier.
+	if err := s.Store(ctx, token.NewAt("stored", token.TypeLink, "validation-1", time.Hour, clock.Now())); err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+	if _, err := s.Retrieve(ctx, "stored", token.TypeLink); err != nil {
+		t.Fatalf("Retrieve() error = %v", err)
+	}
+	if got := cold.retrieves.Load(); got != 0 {
+		t.Errorf("cold retrieves = %d, want 0", got)
+	}
+
+	// Only in the cold tier: read through once, then promoted.
+	if err := cold.Store(ctx, token.NewAt("cold", token.TypeLink, "validation-2", time.Hour, clock.Now())); err != nil {
+		t.Fatalf("cold Store() error = %v", err)
+	}
+	for range 3 {
+		if _, err := s.Retrieve(ctx, "cold", token.TypeLink); err != nil {
+			t.Fatalf("Retrieve() error = %v", err)
+		}
+	}
+	if got := cold.retrieves.Load(); got != 1 {
+		t.Errorf("cold retrieves = %d, want 1", got)
+	}
+	if promoted, err := hot.Retrieve(ctx, "cold", token.TypeLink); err != nil {
+		t.Errorf("hot Retrieve() error = %v, want promoted token", err)
+	} else if want := clock.Now().Add(time.Hour); !promoted.ValidUntil.Equal(want) {
+		t.Errorf("promoted ValidUntil = %v, want %v", promoted.ValidUntil, want)
+	}
+
+	if stats := s.Stats(); stats.Hits != 3 || stats.Misses != 1 || stats.Promotions != 1 {
+		t.Errorf("Stats() = %+v, want 3 hits, 1 miss, 1 promotion", stats)
+	}
+}
+
+func TestStorage_PromotionIsTTLAware(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	s, hot, cold, clock := setup(t)
+
+	if err := cold.Store(ctx, token.NewAt("expiring", token.TypeCode, "validation-1", 500*time.Millisecond, clock.Now())); err != nil {
+		t.Fatalf("cold Store() error = %v", err)
+	}
+	if _, err := s.Retrieve(ctx, "expiring", token.TypeCode); err != nil {
+		t.Fatalf("Retrieve() error = %v", err)
+	}
+	if _, err := hot.Retrieve(ctx, "expiring", token.TypeCode); !errors.Is(err, token.ErrTokenNotFound) {
+		t.Errorf("hot Retrieve() error = %v, want %v", err, token.ErrTokenNotFound)
+	}
+
+	// The hot copy expires with the token.
+	if err := s.Store(ctx, token.NewAt("stored", token.TypeCode, "validation-2", time.Minute, clock.Now())); err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+	clock.Advance(2 * time.Minute)
+	if _, err := s.Retrieve(ctx, "stored", token.TypeCode); !token.IsTokenExpiredError(err) {
+		t.Errorf("Retrieve() error = %v, want TokenExpiredError", err)
+	}
+}
+
+func TestStorage_Mutations(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	s, hot, cold, clock := setup(t)
+
+	if err := s.Store(ctx, token.NewAt("abc", token.TypeLink, "validation-1", time.Hour, clock.Now())); err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+
+	extended, err := s.ExtendTTL(ctx, "abc", token.TypeLink, time.Hour)
+	if err != nil {
+		t.Fatalf("ExtendTTL() error = %v", err)
+	}
+	if got, err := hot.Retrieve(ctx, "abc", token.TypeLink); err != nil || !got.ValidUntil.Equal(extended.ValidUntil) {
+		t.Errorf("hot Retrieve() = %v, %v, want the extended token", got, err)
+	}
+
+	if _, err := s.Consume(ctx, "abc", token.TypeLink); err != nil {
+		t.Fatalf("Consume() error = %v", err)
+	}
+	if _, err := hot.Retrieve(ctx, "abc", token.TypeLink); !errors.Is(err, token.ErrTokenNotFound) {
+		t.Errorf("hot Retrieve() after Consume error = %v, want %v", err, token.ErrTokenNotFound)
+	}
+
+	// A write the cold tier refuses is not made to the hot tier either.
+	cold.failStore.Store(true)
+	if err := s.Store(ctx, token.NewAt("def", token.TypeLink, "validation-2", time.Hour, clock.Now())); !errors.Is(err, errUnavailable) {
+		t.Fatalf("Store() error = %v, want %v", err, errUnavailable)
+	}
+	if _, err := hot.Retrieve(ctx, "def", token.TypeLink); !errors.Is(err, token.ErrTokenNotFound) {
+		t.Errorf("hot Retrieve() after failed Store error = %v, want %v", err, token.ErrTokenNotFound)
+	}
+}
+
+func TestStorage_Conformance(t *testing.T) {
+	t.Parallel()
+
+	storagetest.TestStorage(t, func() token.Storage {
+		return New(memory.New(), memory.New(), WithLogger(slog.New(slog.DiscardHandler)))
+	})
+}
@@ -0,0 +1,201 @@
+// Package tracing provides a token storage decorator that wraps every
+// storage operation in a trace span. Spans are created through the small
+// Tracer interface, which an OpenTelemetry or other tracing adapter
+// implements, so this module does not depend on a tracing library.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+	"github.com/jaeyeom/email-validator-grpc-mcp/token/storage/metrics"
+)
+
+// Span attribute keys.
+const (
+	AttrBackend   = "storage.backend"
+	AttrOperation = "storage.operation"
+	AttrTokenType = "token.type"
+	AttrOutcome   = "storage.outcome"
+)
+
+// Attribute is a key-value pair attached to a span.
+type Attribute struct {
+	Key   string
+	Value string
+}
+
+// Span is a started span.
+type Span interface {
+	// End finishes the span. err is the operation's error, or nil, and
+	// attrs include the AttrOutcome classification of err.
+	End(err error, attrs ...Attribute)
+}
+
+// Tracer starts spans.
+type Tracer interface {
+	// Start begins a span named name as a child of the span in ctx, if any,
+	// and returns a context carrying the new span.
+	Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span)
+}
+
+// Storage wraps a token.Storage and traces its operations.
+type Storage struct {
+	storage token.Storage
+	tracer  Tracer
+	backend string
+}
+
+// Option is a functional option for configuring Storage.
+type Option func(*Storage)
+
+// WithBackend sets the AttrBackend attribute of every span, such as "redis"
+// or "memory", to tell backends apart in traces.
+func WithBackend(name string) Option {
+	return func(s *Storage) {
+		s.backend = name
+	}
+}
+
+// Wrap returns s decorated to trace every operation with t. Spans are named
+// "token.storage." followed by the operation, and the context passed to the
+// wrapped storage carries the span, so spans the backend creates nest under
+// it.
+func Wrap(s token.Storage, t Tracer, opts ...Option) *Storage {
+	w := &Storage{
+		storage: s,
+		tracer:  t,
+	}
+
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	return w
+}
+
+// Store saves a token.
+func (s *Storage) Store(ctx context.Context, t *token.Token) error {
+	var attrs []Attribute
+	if t != nil {
+		attrs = append(attrs, typeAttr(t.Type))
+	}
+
+	_, err := trace(ctx, s, "Store", attrs, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, s.storage.Store(ctx, t)
+	})
+
+	return err
+}
+
+// StoreBatch saves tokens with the wrapped storage's StoreBatch when it has
+// one, and with token.StoreEach otherwise.
+func (s *Storage) StoreBatch(ctx context.Context, tokens []*token.Token) error {
+	batch, ok := s.storage.(token.BatchStorage)
+	if !ok {
+		return token.StoreEach(ctx, tokens, s.Store)
+	}
+
+	_, err := trace(ctx, s, "StoreBatch", nil, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, batch.StoreBatch(ctx, tokens)
+	})
+
+	return err
+}
+
+// Retrieve gets a token.
+func (s *Storage) Retrieve(ctx context.Context, tokenValue string, tokenType token.Type) (*token.Token, error) {
+	return trace(ctx, s, "Retrieve", []Attribute{typeAttr(tokenType)}, func(ctx context.Context) (*token.Token, error) {
+		return s.storage.Retrieve(ctx, tokenValue, tokenType)
+	})
+}
+
+// Delete removes a token.
+func (s *Storage) Delete(ctx context.Context, tokenValue string, tokenType token.Type) error {
+	_, err := trace(ctx, s, "Delete", []Attribute{typeAttr(tokenType)}, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, s.storage.Delete(ctx, tokenValue, tokenType)
+	})
+
+	return err
+}
+
+// DeleteBatch removes tokens with the wrapped storage's DeleteBatch when it
+// has one, and with token.DeleteEach otherwise.
+func (s *Storage) DeleteBatch(ctx context.Context, refs []token.TokenRef) error {
+	batch, ok := s.storage.(token.BatchStorage)
+	if !ok {
+		return token.DeleteEach(ctx, refs, s.Delete)
+	}
+
+	_, err := trace(ctx, s, "DeleteBatch", nil, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, batch.DeleteBatch(ctx, refs)
+	})
+
+	return err
+}
+
+// DeleteByValidationID removes a validation's tokens.
+func (s *Storage) DeleteByValidationID(ctx context.Context, validationID string) error {
+	_, err := trace(ctx, s, "DeleteByValidationID", nil, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, s.storage.DeleteByValidationID(ctx, validationID)
+	})
+
+	return err
+}
+
+// Consume atomically takes a token.
+func (s *Storage) Consume(ctx context.Context, tokenValue string, tokenType token.Type) (*token.Token, error) {
+	return trace(ctx, s, "Consume", []Attribute{typeAttr(tokenType)}, func(ctx context.Context) (*token.Token, error) {
+		return s.storage.Consume(ctx, tokenValue, tokenType)
+	})
+}
+
+// IncrementAttempts counts a failed verification attempt.
+func (s *Storage) IncrementAttempts(ctx context.Context, validationID string, ttl time.Duration) (int, error) {
+	return trace(ctx, s, "IncrementAttempts", nil, func(ctx context.Context) (int, error) {
+		return s.storage.IncrementAttempts(ctx, validationID, ttl)
+	})
+}
+
+// ExtendTTL extends a token's validity.
+func (s *Storage) ExtendTTL(ctx context.Context, tokenValue string, tokenType token.Type, extra time.Duration) (*token.Token, error) {
+	return trace(ctx, s, "ExtendTTL", []Attribute{typeAttr(tokenType)}, func(ctx context.Context) (*token.Token, error) {
+		return s.storage.ExtendTTL(ctx, tokenValue, tokenType, extra)
+	})
+}
+
+// ListByValidationID lists a validation's tokens.
+func (s *Storage) ListByValidationID(ctx context.Context, validationID string) ([]*token.Token, error) {
+	return trace(ctx, s, "ListByValidationID", nil, func(ctx context.Context) ([]*token.Token, error) {
+		return s.storage.ListByValidationID(ctx, validationID)
+	})
+}
+
+// trace runs fn in a span for op. Token values and validation IDs are never
+// recorded, since traces are usually retained longer than tokens live.
+func trace[T any](ctx context.Context, s *Storage, op string, attrs []Attribute, fn func(context.Context) (T, error)) (T, error) {
+	attrs = append(attrs, Attribute{Key: AttrOperation, Value: op})
+	if s.backend != "" {
+		attrs = append(attrs, Attribute{Key: AttrBackend, Value: s.backend})
+	}
+
+	ctx, span := s.tracer.Start(ctx, "token.storage."+op, attrs...)
+	v, err := fn(ctx)
+	span.End(err, Attribute{Key: AttrOutcome, Value: string(metrics.OutcomeOf(err))})
+
+	return v, err
+}
+
+// typeAttr returns the AttrTokenType attribute for t.
+func typeAttr(t token.Type) Attribute {
+	switch t {
+	case token.TypeLink:
+		return Attribute{Key: AttrTokenType, Value: "link"}
+	case token.TypeCode:
+		return Attribute{Key: AttrTokenType, Value: "code"}
+	default:
+		return Attribute{Key: AttrTokenType, Value: fmt.Sprintf("unknown(%d)", t)}
+	}
+}
@@ -0,0 +1,121 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+	"github.com/jaeyeom/email-validator-grpc-mcp/token/storage/memory"
+	"github.com/jaeyeom/email-validator-grpc-mcp/token/storagetest"
+)
+
+type spanKey struct{}
+
+// recordedSpan is a finished span captured by recordingTracer.
+type recordedSpan struct {
+	name   string
+	parent string
+	attrs  []Attribute
+	err    error
+}
+
+// recordingTracer records finished spans and tracks parents via the context.
+type recordingTracer struct {
+	mu    sync.Mutex
+	spans []recordedSpan
+}
+
+type recordingSpan struct {
+	tracer *recordingTracer
+	span   recordedSpan
+}
+
+func (r *recordingTracer) Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	parent, _ := ctx.Value(spanKey{}).(string)
+	span := &recordingSpan{tracer: r, span: recordedSpan{name: name, parent: parent, attrs: attrs}}
+
+	return context.WithValue(ctx, spanKey{}, name), span
+}
+
+func (s *recordingSpan) End(err error, attrs ...Attribute) {
+	s.span.err = err
+	s.span.attrs = append(s.span.attrs, attrs...)
+
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.tracer.spans = append(s.tracer.spans, s.span)
+}
+
+// parentCapturingStorage records the span name found in each call's context.
+type parentCapturingStorage struct {
+	token.Storage
+	parent string
+}
+
+func (p *parentCapturingStorage) Retrieve(ctx context.Context, tokenValue string, tokenType token.Type) (*token.Token, error) {
+	p.parent, _ = ctx.Value(spanKey{}).(string)
+
+	return p.Storage.Retrieve(ctx, tokenValue, tokenType)
+}
+
+func TestStorage(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.WithValue(context.Background(), spanKey{}, "request")
+	tracer := &recordingTracer{}
+	backend := &parentCapturingStorage{Storage: memory.New()}
+	storage := Wrap(backend, tracer, WithBackend("memory"))
+
+	tkn := &token.Token{Value: "secret-value", Type: token.TypeCode, ValidUntil: time.Now().Add(time.Minute), ValidationID: "validation-trace"}
+	if err := storage.Store(ctx, tkn); err != nil {
+		t.Fatalf("Storage.Store() error = %v", err)
+	}
+	if _, err := storage.Retrieve(ctx, "missing", token.TypeCode); !errors.Is(err, token.ErrTokenNotFound) {
+		t.Fatalf("Storage.Retrieve() error = %v, want %v", err, token.ErrTokenNotFound)
+	}
+	if backend.parent != "token.storage.Retrieve" {
+		t.Errorf("backend saw parent span %q, want token.storage.Retrieve", backend.parent)
+	}
+
+	if len(tracer.spans) != 2 {
+		t.Fatalf("recorded %d spans, want 2", len(tracer.spans))
+	}
+
+	retrieve := tracer.spans[1]
+	if retrieve.name != "token.storage.Retrieve" || retrieve.parent != "request" {
+		t.Errorf("span = %q with parent %q, want token.storage.Retrieve with parent request", retrieve.name, retrieve.parent)
+	}
+	if !errors.Is(retrieve.err, token.ErrTokenNotFound) {
+		t.Errorf("span error = %v, want %v", retrieve.err, token.ErrTokenNotFound)
+	}
+	for _, want := range []Attribute{
+		{Key: AttrTokenType, Value: "code"},
+		{Key: AttrOperation, Value: "Retrieve"},
+		{Key: AttrBackend, Value: "memory"},
+		{Key: AttrOutcome, Value: "not_found"},
+	} {
+		if !slices.Contains(retrieve.attrs, want) {
+			t.Errorf("span attributes %v lack %v", retrieve.attrs, want)
+		}
+	}
+
+	for _, span := range tracer.spans {
+		for _, attr := range span.attrs {
+			if attr.Value == tkn.Value || attr.Value == tkn.ValidationID {
+				t.Errorf("span %q records %s = %q", span.name, attr.Key, attr.Value)
+			}
+		}
+	}
+}
+
+func TestStorage_Conformance(t *testing.T) {
+	t.Parallel()
+
+	storagetest.TestStorage(t, func() token.Storage {
+		return Wrap(memory.New(), &recordingTracer{})
+	})
+}
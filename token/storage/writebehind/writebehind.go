@@ -0,0 +1,360 @@
+// Package writebehind provides a composite token storage that writes to a
+// fast primary backend synchronously and replicates mutations to a durable
+// secondary backend asynchronously.
+package writebehind
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+)
+
+// DefaultQueueSize is the default number of mutations kept for the
+// secondary backend.
+const DefaultQueueSize = 64 * 1024
+
+// DefaultWriteTimeout is the default time allowed for each secondary write.
+const DefaultWriteTimeout = 5 * time.Second
+
+// ErrClosed is returned by Close when the storage has already been closed.
+var ErrClosed = errors.New("write-behind storage closed")
+
+// Storage serves all reads and atomic operations from the primary backend
+// and copies mutations to the secondary backend in the background, so the
+// hot path runs at primary latency. Mutations are replicated strictly in
+// the order they were made: when a secondary write fails, that mutation and
+// every later one are kept for Reconcile, so a replayed Store can never
+// resurrect a token whose Delete was replicated first.
+type Storage struct {
+	primary   token.Storage
+	secondary token.Storage
+	logger    *slog.Logger
+
+	queueSize    int
+	writeTimeout time.Duration
+
+	wake chan struct{}
+	done chan struct{}
+
+	// replicating serializes writes to the secondary backend between the
+	// worker and Reconcile.
+	replicating sync.Mutex
+
+	mu      sync.Mutex
+	closed  bool
+	queue   []operation // not yet replicated, oldest first
+	stalled bool        // the oldest mutation failed and awaits Reconcile
+	dropped int64
+}
+
+// operation is a mutation to replicate to the secondary backend.
+type operation struct {
+	ctx   context.Context
+	name  string
+	apply func(ctx context.Context, s token.Storage) error
+}
+
+// Option is a functional option for configuring Storage.
+type Option func(*Storage)
+
+// WithLogger sets a custom logger for Storage.
+func WithLogger(logger *slog.Logger) Option {
+	return func(s *Storage) {
+		s.logger = logger
+	}
+}
+
+// WithQueueSize sets how many mutations may wait for the secondary backend,
+// including those kept for Reconcile. Further mutations are dropped and
+// counted by Dropped, leaving the secondary backend out of date.
+func WithQueueSize(size int) Option {
+	return func(s *Storage) {
+		if size > 0 {
+			s.queueSize = size
+		}
+	}
+}
+
+// WithWriteTimeout sets the time allowed for each secondary write.
+func WithWriteTimeout(timeout time.Duration) Option {
+	return func(s *Storage) {
+		if timeout > 0 {
+			s.writeTimeout = timeout
+		}
+	}
+}
+
+// New creates a write-behind storage and starts its replication worker.
+// Call Close to flush queued mutations and stop the worker.
+func New(primary, secondary token.Storage, opts ...Option) *Storage {
+	s := &Storage{
+		primary:      primary,
+		secondary:    secondary,
+		logger:       slog.Default(),
+		queueSize:    DefaultQueueSize,
+		writeTimeout: DefaultWriteTimeout,
+		wake:         make(chan struct{}, 1),
+		done:         make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	go s.run()
+
+	return s
+}
+
+// Store saves the token to the primary backend and queues it for the
+// secondary backend.
+func (s *Storage) Store(ctx context.Context, t *token.Token) error {
+	if err := s.primary.Store(ctx, t); err != nil {
+		return fmt.Errorf("primary store failed: %w", err)
+	}
+
+	stored := *t
+	s.enqueue(ctx, "Store", func(ctx context.Context, b token.Storage) error {
+		return b.Store(ctx, &stored)
+	})
+
+	return nil
+}
+
+// Retrieve gets a token from the primary backend.
+func (s *Storage) Retrieve(ctx context.Context, tokenValue string, tokenType token.Type) (*token.Token, error) {
+	t, err := s.primary.Retrieve(ctx, tokenValue, tokenType)
+	if err != nil {
+		return nil, fmt.Errorf("primary retrieve failed: %w", err)
+	}
+
+	return t, nil
+}
+
+// Delete removes the token from the primary backend and queues the removal
+// for the secondary backend.
+func (s *Storage) Delete(ctx context.Context, tokenValue string, tokenType token.Type) error {
+	if err := s.primary.Delete(ctx, tokenValue, tokenType); err != nil {
+		return fmt.Errorf("primary delete failed: %w", err)
+	}
+
+	s.enqueueDelete(ctx, tokenValue, tokenType)
+
+	return nil
+}
+
+// DeleteByValidationID removes the validation's tokens from the primary
+// backend and queues the removal for the secondary backend.
+func (s *Storage) DeleteByValidationID(ctx context.Context, validationID string) error {
+	if err := s.primary.DeleteByValidationID(ctx, validationID); err != nil {
+		return fmt.Errorf("primary delete by validation ID failed: %w", err)
+	}
+
+	s.enqueue(ctx, "DeleteByValidationID", func(ctx context.Context, b token.Storage) error {
+		return b.DeleteByValidationID(ctx, validationID)
+	})
+
+	return nil
+}
+
+// Consume atomically takes the token from the primary backend and queues
+// its removal from the secondary backend.
+func (s *Storage) Consume(ctx context.Context, tokenValue string, tokenType token.Type) (*token.Token, error) {
+	t, err := s.primary.Consume(ctx, tokenValue, tokenType)
+	if err != nil {
+		return nil, fmt.Errorf("primary consume failed: %w", err)
+	}
+
+	s.enqueueDelete(ctx, tokenValue, tokenType)
+
+	return t, nil
+}
+
+// IncrementAttempts counts the attempt in the primary backend only, since
+// attempt counters are short-lived.
+func (s *Storage) IncrementAttempts(ctx context.Context, validationID string, ttl time.Duration) (int, error) {
+	n, err := s.primary.IncrementAttempts(ctx, validationID, ttl)
+	if err != nil {
+		return 0, fmt.Errorf("primary increment attempts failed: %w", err)
+	}
+
+	return n, nil
+}
+
+// ExtendTTL extends the token in the primary backend and queues the same
+// extension for the secondary backend.
+func (s *Storage) ExtendTTL(ctx context.Context, tokenValue string, tokenType token.Type, extra time.Duration) (*token.Token, error) {
+	t, err := s.primary.ExtendTTL(ctx, tokenValue, tokenType, extra)
+	if err != nil {
+		return nil, fmt.Errorf("primary extend TTL failed: %w", err)
+	}
+
+	s.enqueue(ctx, "ExtendTTL", func(ctx context.Context, b token.Storage) error {
+		_, err := b.ExtendTTL(ctx, tokenValue, tokenType, extra)
+		return err
+	})
+
+	return t, nil
+}
+
+// ListByValidationID lists tokens from the primary backend.
+func (s *Storage) ListByValidationID(ctx context.Context, validationID string) ([]*token.Token, error) {
+	tokens, err := s.primary.ListByValidationID(ctx, validationID)
+	if err != nil {
+		return nil, fmt.Errorf("primary list failed: %w", err)
+	}
+
+	return tokens, nil
+}
+
+// Pending returns the number of mutations not yet replicated.
+func (s *Storage) Pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.queue)
+}
+
+// Dropped returns the number of mutations dropped because the queue was
+// full.
+func (s *Storage) Dropped() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.dropped
+}
+
+// Reconcile retries the mutations that could not be replicated, in their
+// original order, stopping at the first that fails again, and returns how
+// many are still pending. It is meant to be run periodically by a
+// background job. Replication by the worker resumes once it catches up.
+func (s *Storage) Reconcile(ctx context.Context) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, fmt.Errorf("context error: %w", err)
+	}
+
+	before := s.Pending()
+	s.replicate(ctx)
+	remaining := s.Pending()
+
+	s.logger.Debug("write-behind reconciliation finished",
+		"replicated", max(before-remaining, 0),
+		"remaining", remaining)
+
+	return remaining, nil
+}
+
+// Close waits for queued mutations to be replicated and stops the worker.
+// Mutations that failed, and those made after Close, are kept for
+// Reconcile.
+func (s *Storage) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return ErrClosed
+	}
+	s.closed = true
+	close(s.wake)
+	s.mu.Unlock()
+
+	<-s.done
+
+	return nil
+}
+
+// enqueueDelete queues a token removal, treating a token that is already
+// absent from the secondary backend as removed.
+func (s *Storage) enqueueDelete(ctx context.Context, tokenValue string, tokenType token.Type) {
+	s.enqueue(ctx, "Delete", func(ctx context.Context, b token.Storage) error {
+		if err := b.Delete(ctx, tokenValue, tokenType); err != nil && !errors.Is(err, token.ErrTokenNotFound) {
+			return err
+		}
+		return nil
+	})
+}
+
+// enqueue queues a mutation behind all earlier ones and wakes the worker
+// unless replication is stalled. The caller's context values are kept but
+// its cancellation is not, so replication outlives the request.
+func (s *Storage) enqueue(ctx context.Context, name string, apply func(context.Context, token.Storage) error) {
+	op := operation{ctx: context.WithoutCancel(ctx), name: name, apply: apply}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.queue) >= s.queueSize {
+		s.dropped++
+		s.logger.Error("write-behind queue full, dropping mutation",
+			"operation", name,
+			"queued", len(s.queue))
+		return
+	}
+
+	s.queue = append(s.queue, op)
+	if !s.closed && !s.stalled {
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// run replicates queued mutations until Close.
+func (s *Storage) run() {
+	defer close(s.done)
+
+	for range s.wake {
+		s.replicate(context.Background())
+	}
+	s.replicate(context.Background())
+}
+
+// replicate applies queued mutations in order until the queue is empty or
+// one fails, which stalls replication until Reconcile gets past it.
+func (s *Storage) replicate(ctx context.Context) {
+	s.replicating.Lock()
+	defer s.replicating.Unlock()
+
+	for ctx.Err() == nil {
+		s.mu.Lock()
+		if len(s.queue) == 0 {
+			s.stalled = false
+			s.mu.Unlock()
+			return
+		}
+		op := s.queue[0]
+		s.mu.Unlock()
+
+		if err := s.apply(op); err != nil {
+			s.mu.Lock()
+			s.stalled = true
+			s.mu.Unlock()
+			return
+		}
+
+		s.mu.Lock()
+		s.queue[0] = operation{}
+		s.queue = s.queue[1:]
+		s.mu.Unlock()
+	}
+}
+
+// apply runs a mutation against the secondary backend.
+func (s *Storage) apply(op operation) error {
+	ctx, cancel := context.WithTimeout(op.ctx, s.writeTimeout)
+	defer cancel()
+
+	if err := op.apply(ctx, s.secondary); err != nil {
+		s.logger.Warn("write-behind replication failed",
+			"operation", op.name,
+			"error", err)
+		return err
+	}
+
+	return nil
+}
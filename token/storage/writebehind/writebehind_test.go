@@ -0,0 +1,159 @@
+package writebehind
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+	"github.com/jaeyeom/email-validator-grpc-mcp/token/storage/memory"
+)
+
+var errUnavailable = errors.New("backend unavailable")
+
+// flakyStorage fails Store calls while down is set.
+type flakyStorage struct {
+	token.Storage
+	down atomic.Bool
+}
+
+func (f *flakyStorage) Store(ctx context.Context, t *token.Token) error {
+	if f.down.Load() {
+		return errUnavailable
+	}
+
+	return f.Storage.Store(ctx, t)
+}
+
+func newToken(value string) *token.Token {
+	return &token.Token{
+		Value:        value,
+		Type:         token.TypeLink,
+		ValidUntil:   time.Now().Add(time.Hour),
+		ValidationID: "validation-" + value,
+	}
+}
+
+func TestStorage_Replicates(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	primary, secondary := memory.New(), memory.New()
+	s := New(primary, secondary)
+
+	if err := s.Store(ctx, newToken("kept")); err != nil {
+		t.Fatalf("Storage.Store() error = %v", err)
+	}
+	if err := s.Store(ctx, newToken("consumed")); err != nil {
+		t.Fatalf("Storage.Store() error = %v", err)
+	}
+	if _, err := s.Consume(ctx, "consumed", token.TypeLink); err != nil {
+		t.Fatalf("Storage.Consume() error = %v", err)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatalf("Storage.Close() error = %v", err)
+	}
+	if err := s.Close(); !errors.Is(err, ErrClosed) {
+		t.Errorf("second Storage.Close() error = %v, want %v", err, ErrClosed)
+	}
+
+	if _, err := secondary.Retrieve(ctx, "kept", token.TypeLink); err != nil {
+		t.Errorf("secondary Retrieve() error = %v", err)
+	}
+	if _, err := secondary.Retrieve(ctx, "consumed", token.TypeLink); !errors.Is(err, token.ErrTokenNotFound) {
+		t.Errorf("secondary Retrieve() of consumed token error = %v, want %v", err, token.ErrTokenNotFound)
+	}
+}
+
+func TestStorage_Reconcile(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	secondary := &flakyStorage{Storage: memory.New()}
+	secondary.down.Store(true)
+	s := New(memory.New(), secondary)
+
+	// The hot path succeeds while the secondary backend is down
+	if err := s.Store(ctx, newToken("deferred")); err != nil {
+		t.Fatalf("Storage.Store() error = %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Storage.Close() error = %v", err)
+	}
+	if _, err := s.Retrieve(ctx, "deferred", token.TypeLink); err != nil {
+		t.Errorf("Storage.Retrieve() error = %v", err)
+	}
+	if got := s.Pending(); got != 1 {
+		t.Fatalf("Storage.Pending() = %d, want 1", got)
+	}
+
+	if remaining, err := s.Reconcile(ctx); err != nil || remaining != 1 {
+		t.Errorf("Storage.Reconcile() while down = %d, %v, want 1, nil", remaining, err)
+	}
+
+	secondary.down.Store(false)
+	if remaining, err := s.Reconcile(ctx); err != nil || remaining != 0 {
+		t.Errorf("Storage.Reconcile() = %d, %v, want 0, nil", remaining, err)
+	}
+	if _, err := secondary.Retrieve(ctx, "deferred", token.TypeLink); err != nil {
+		t.Errorf("secondary Retrieve() after reconcile error = %v", err)
+	}
+}
+
+func TestStorage_ReplicatesInOrder(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	secondary := &flakyStorage{Storage: memory.New()}
+	secondary.down.Store(true)
+	s := New(memory.New(), secondary)
+
+	// The Store fails, and the Delete queued by Consume would succeed on
+	// its own; it must not be replicated before the Store.
+	if err := s.Store(ctx, newToken("consumed")); err != nil {
+		t.Fatalf("Storage.Store() error = %v", err)
+	}
+	if _, err := s.Consume(ctx, "consumed", token.TypeLink); err != nil {
+		t.Fatalf("Storage.Consume() error = %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Storage.Close() error = %v", err)
+	}
+	if got := s.Pending(); got != 2 {
+		t.Fatalf("Storage.Pending() = %d, want 2", got)
+	}
+
+	secondary.down.Store(false)
+	if remaining, err := s.Reconcile(ctx); err != nil || remaining != 0 {
+		t.Errorf("Storage.Reconcile() = %d, %v, want 0, nil", remaining, err)
+	}
+	if _, err := secondary.Retrieve(ctx, "consumed", token.TypeLink); !errors.Is(err, token.ErrTokenNotFound) {
+		t.Errorf("secondary Retrieve() of consumed token error = %v, want %v", err, token.ErrTokenNotFound)
+	}
+}
+
+func TestStorage_QueueSize(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	secondary := &flakyStorage{Storage: memory.New()}
+	secondary.down.Store(true)
+	s := New(memory.New(), secondary, WithQueueSize(2))
+	defer s.Close()
+
+	for _, value := range []string{"first", "second", "third"} {
+		if err := s.Store(ctx, newToken(value)); err != nil {
+			t.Fatalf("Storage.Store() error = %v", err)
+		}
+	}
+
+	if pending, dropped := s.Pending(), s.Dropped(); pending != 2 || dropped != 1 {
+		t.Errorf("Storage.Pending(), Dropped() = %d, %d, want 2, 1", pending, dropped)
+	}
+	if _, err := s.Retrieve(ctx, "third", token.TypeLink); err != nil {
+		t.Errorf("Storage.Retrieve() of token dropped from the queue error = %v", err)
+	}
+}
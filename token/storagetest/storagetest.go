@@ -0,0 +1,599 @@
+// Package storagetest provides an acceptance suite for token.Storage
+// implementations. Backends call TestStorage from their tests to check that
+// they follow the semantics the Manager relies on:
+//
+//	func TestConformance(t *testing.T) {
+//		storagetest.TestStorage(t, func() token.Storage {
+//			return mybackend.New(...)
+//		})
+//	}
+//
+// The suite uses the wall clock and waits for short-lived tokens to expire,
+// so backends must check expiry against real time.
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+)
+
+// shortTTL is the lifetime of tokens the suite waits to expire.
+const shortTTL = 50 * time.Millisecond
+
+// concurrency is the number of goroutines used by concurrency tests.
+const concurrency = 16
+
+// TestStorage runs the acceptance suite against storages returned by
+// newStorage, which is called once per subtest.
+func TestStorage(t *testing.T, newStorage func() token.Storage) {
+	t.Helper()
+
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s token.Storage)
+	}{
+		{"StoreRetrieve", testStoreRetrieve},
+		{"StoreInvalid", testStoreInvalid},
+		{"RetrieveNotFound", testRetrieveNotFound},
+		{"TypesAreSeparate", testTypesAreSeparate},
+		{"Delete", testDelete},
+		{"DeleteByValidationID", testDeleteByValidationID},
+		{"ListByValidationID", testListByValidationID},
+		{"Expiry", testExpiry},
+		{"Consume", testConsume},
+		{"ConcurrentConsume", testConcurrentConsume},
+		{"ConcurrentStore", testConcurrentStore},
+		{"IncrementAttempts", testIncrementAttempts},
+		{"ExtendTTL", testExtendTTL},
+		{"CanceledContext", testCanceledContext},
+		{"StoreBatch", testStoreBatch},
+		{"DeleteBatch", testDeleteBatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			tt.fn(t, newStorage())
+		})
+	}
+}
+
+// seq makes token values and validation IDs unique across subtests, so
+// factories may return storages sharing one backend.
+var seq atomic.Int64
+
+// unique returns name with a suffix unique to this process.
+func unique(name string) string {
+	return fmt.Sprintf("storagetest-%s-%d", name, seq.Add(1))
+}
+
+// newToken returns an unstored token valid for ttl.
+func newToken(tokenType token.Type, validationID string, ttl time.Duration) *token.Token {
+	now := time.Now()
+
+	return &token.Token{
+		Value:        unique("value"),
+		Type:         tokenType,
+		CreatedAt:    now,
+		ValidUntil:   now.Add(ttl),
+		ValidationID: validationID,
+	}
+}
+
+// mustStore stores tokens, failing the test on error.
+func mustStore(t *testing.T, s token.Storage, tokens ...*token.Token) {
+	t.Helper()
+
+	for _, tkn := range tokens {
+		if err := s.Store(context.Background(), tkn); err != nil {
+			t.Fatalf("Store(%q) error = %v", tkn.Value, err)
+		}
+	}
+}
+
+// checkSame reports differences between a retrieved token and the stored
+// one. Times are compared to the millisecond, which all codecs preserve.
+func checkSame(t *testing.T, op string, got, want *token.Token) {
+	t.Helper()
+
+	if got == nil {
+		t.Errorf("%s = nil, want %q", op, want.Value)
+		return
+	}
+	if got.Value != want.Value || got.Type != want.Type || got.ValidationID != want.ValidationID {
+		t.Errorf("%s = (%q, %d, %q), want (%q, %d, %q)", op,
+			got.Value, got.Type, got.ValidationID, want.Value, want.Type, want.ValidationID)
+	}
+	if d := got.ValidUntil.Sub(want.ValidUntil).Abs(); d >= time.Millisecond {
+		t.Errorf("%s ValidUntil = %v, want %v", op, got.ValidUntil, want.ValidUntil)
+	}
+	if len(got.Metadata) != 0 || len(want.Metadata) != 0 {
+		if !maps.Equal(got.Metadata, want.Metadata) {
+			t.Errorf("%s Metadata = %v, want %v", op, got.Metadata, want.Metadata)
+		}
+	}
+}
+
+// isGone reports whether err says a token is absent or expired.
+func isGone(err error) bool {
+	return errors.Is(err, token.ErrTokenNotFound) || token.IsTokenExpiredError(err)
+}
+
+// values returns the values of tokens, sorted.
+func values(tokens []*token.Token) []string {
+	out := make([]string, 0, len(tokens))
+	for _, tkn := range tokens {
+		out = append(out, tkn.Value)
+	}
+	slices.Sort(out)
+
+	return out
+}
+
+func testStoreRetrieve(t *testing.T, s token.Storage) {
+	ctx := context.Background()
+
+	link := newToken(token.TypeLink, unique("validation"), time.Hour)
+	code := newToken(token.TypeCode, link.ValidationID, time.Hour)
+	code.Metadata = map[string]string{"email_sha256": "abc", "locale": "en"}
+	mustStore(t, s, link, code)
+
+	for _, want := range []*token.Token{link, code} {
+		got, err := s.Retrieve(ctx, want.Value, want.Type)
+		if err != nil {
+			t.Fatalf("Retrieve(%q) error = %v", want.Value, err)
+		}
+		checkSame(t, "Retrieve()", got, want)
+	}
+
+	// Storing again replaces the token
+	replaced := *link
+	replaced.ValidUntil = link.ValidUntil.Add(time.Hour)
+	mustStore(t, s, &replaced)
+	got, err := s.Retrieve(ctx, link.Value, link.Type)
+	if err != nil {
+		t.Fatalf("Retrieve() after replace error = %v", err)
+	}
+	checkSame(t, "Retrieve() after replace", got, &replaced)
+}
+
+func testStoreInvalid(t *testing.T, s token.Storage) {
+	ctx := context.Background()
+	validationID := unique("validation")
+
+	noValue := newToken(token.TypeLink, validationID, time.Hour)
+	noValue.Value = ""
+	noExpiry := newToken(token.TypeLink, validationID, time.Hour)
+	noExpiry.ValidUntil = time.Time{}
+
+	tests := []struct {
+		name string
+		tkn  *token.Token
+		want error
+	}{
+		{"nil token", nil, token.ErrTokenNil},
+		{"empty value", noValue, token.ErrEmptyTokenValue},
+		{"empty validation ID", newToken(token.TypeLink, "", time.Hour), token.ErrEmptyValidationID},
+		{"missing expiry", noExpiry, token.ErrInvalidToken},
+	}
+
+	for _, tt := range tests {
+		if err := s.Store(ctx, tt.tkn); !errors.Is(err, tt.want) {
+			t.Errorf("Store() with %s error = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	if tokens, err := s.ListByValidationID(ctx, validationID); err != nil || len(tokens) != 0 {
+		t.Errorf("ListByValidationID() after invalid stores = %v, %v, want none", values(tokens), err)
+	}
+}
+
+func testRetrieveNotFound(t *testing.T, s token.Storage) {
+	if _, err := s.Retrieve(context.Background(), unique("missing"), token.TypeLink); !errors.Is(err, token.ErrTokenNotFound) {
+		t.Errorf("Retrieve() of missing token error = %v, want %v", err, token.ErrTokenNotFound)
+	}
+}
+
+func testTypesAreSeparate(t *testing.T, s token.Storage) {
+	ctx := context.Background()
+
+	link := newToken(token.TypeLink, unique("validation"), time.Hour)
+	mustStore(t, s, link)
+
+	if _, err := s.Retrieve(ctx, link.Value, token.TypeCode); !errors.Is(err, token.ErrTokenNotFound) {
+		t.Errorf("Retrieve() with other type error = %v, want %v", err, token.ErrTokenNotFound)
+	}
+	if _, err := s.Consume(ctx, link.Value, token.TypeCode); !errors.Is(err, token.ErrTokenNotFound) {
+		t.Errorf("Consume() with other type error = %v, want %v", err, token.ErrTokenNotFound)
+	}
+	if err := s.Delete(ctx, link.Value, token.TypeCode); err != nil {
+		t.Errorf("Delete() with other type error = %v", err)
+	}
+	if _, err := s.Retrieve(ctx, link.Value, token.TypeLink); err != nil {
+		t.Errorf("Retrieve() after operations on other type error = %v", err)
+	}
+}
+
+func testDelete(t *testing.T, s token.Storage) {
+	ctx := context.Background()
+
+	deleted := newToken(token.TypeLink, unique("validation"), time.Hour)
+	kept := newToken(token.TypeCode, deleted.ValidationID, time.Hour)
+	mustStore(t, s, deleted, kept)
+
+	if err := s.Delete(ctx, deleted.Value, deleted.Type); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := s.Retrieve(ctx, deleted.Value, deleted.Type); !errors.Is(err, token.ErrTokenNotFound) {
+		t.Errorf("Retrieve() after Delete() error = %v, want %v", err, token.ErrTokenNotFound)
+	}
+	if tokens, err := s.ListByValidationID(ctx, deleted.ValidationID); err != nil || !slices.Equal(values(tokens), []string{kept.Value}) {
+		t.Errorf("ListByValidationID() after Delete() = %v, %v, want [%s]", values(tokens), err, kept.Value)
+	}
+
+	// Delete is idempotent
+	if err := s.Delete(ctx, deleted.Value, deleted.Type); err != nil {
+		t.Errorf("second Delete() error = %v", err)
+	}
+}
+
+func testDeleteByValidationID(t *testing.T, s token.Storage) {
+	ctx := context.Background()
+
+	validationID := unique("validation")
+	a := newToken(token.TypeLink, validationID, time.Hour)
+	b := newToken(token.TypeCode, validationID, time.Hour)
+	other := newToken(token.TypeLink, unique("validation"), time.Hour)
+	mustStore(t, s, a, b, other)
+	if _, err := s.IncrementAttempts(ctx, validationID, time.Hour); err != nil {
+		t.Fatalf("IncrementAttempts() error = %v", err)
+	}
+
+	if err := s.DeleteByValidationID(ctx, validationID); err != nil {
+		t.Fatalf("DeleteByValidationID() error = %v", err)
+	}
+	for _, tkn := range []*token.Token{a, b} {
+		if _, err := s.Retrieve(ctx, tkn.Value, tkn.Type); !errors.Is(err, token.ErrTokenNotFound) {
+			t.Errorf("Retrieve(%q) after DeleteByValidationID() error = %v, want %v", tkn.Value, err, token.ErrTokenNotFound)
+		}
+	}
+	if _, err := s.Retrieve(ctx, other.Value, other.Type); err != nil {
+		t.Errorf("Retrieve() of another validation's token error = %v", err)
+	}
+	if tokens, err := s.ListByValidationID(ctx, validationID); err != nil || len(tokens) != 0 {
+		t.Errorf("ListByValidationID() after DeleteByValidationID() = %v, %v, want none", values(tokens), err)
+	}
+
+	// The attempt counter is reset
+	if n, err := s.IncrementAttempts(ctx, validationID, time.Hour); err != nil || n != 1 {
+		t.Errorf("IncrementAttempts() after DeleteByValidationID() = %d, %v, want 1", n, err)
+	}
+
+	if err := s.DeleteByValidationID(ctx, unique("validation")); err != nil {
+		t.Errorf("DeleteByValidationID() of unknown validation error = %v", err)
+	}
+	if err := s.DeleteByValidationID(ctx, ""); !errors.Is(err, token.ErrEmptyValidationID) {
+		t.Errorf("DeleteByValidationID(\"\") error = %v, want %v", err, token.ErrEmptyValidationID)
+	}
+}
+
+func testListByValidationID(t *testing.T, s token.Storage) {
+	ctx := context.Background()
+
+	validationID := unique("validation")
+	a := newToken(token.TypeLink, validationID, time.Hour)
+	b := newToken(token.TypeCode, validationID, time.Hour)
+	mustStore(t, s, a, b, newToken(token.TypeLink, unique("validation"), time.Hour))
+
+	tokens, err := s.ListByValidationID(ctx, validationID)
+	if err != nil {
+		t.Fatalf("ListByValidationID() error = %v", err)
+	}
+	if want := values([]*token.Token{a, b}); !slices.Equal(values(tokens), want) {
+		t.Errorf("ListByValidationID() = %v, want %v", values(tokens), want)
+	}
+
+	if tokens, err := s.ListByValidationID(ctx, unique("validation")); err != nil || len(tokens) != 0 {
+		t.Errorf("ListByValidationID() of unknown validation = %v, %v, want none", values(tokens), err)
+	}
+	if _, err := s.ListByValidationID(ctx, ""); !errors.Is(err, token.ErrEmptyValidationID) {
+		t.Errorf("ListByValidationID(\"\") error = %v, want %v", err, token.ErrEmptyValidationID)
+	}
+}
+
+func testExpiry(t *testing.T, s token.Storage) {
+	ctx := context.Background()
+
+	validationID := unique("validation")
+	retrieved := newToken(token.TypeLink, validationID, shortTTL)
+	consumed := newToken(token.TypeCode, validationID, shortTTL)
+	extended := newToken(token.TypeLink, validationID, shortTTL)
+	live := newToken(token.TypeLink, validationID, time.Hour)
+	mustStore(t, s, retrieved, consumed, extended, live)
+
+	time.Sleep(2 * shortTTL)
+
+	if _, err := s.Retrieve(ctx, retrieved.Value, retrieved.Type); !isGone(err) {
+		t.Errorf("Retrieve() of expired token error = %v, want expired or not found", err)
+	}
+	if _, err := s.Consume(ctx, consumed.Value, consumed.Type); !isGone(err) {
+		t.Errorf("Consume() of expired token error = %v, want expired or not found", err)
+	}
+	if _, err := s.ExtendTTL(ctx, extended.Value, extended.Type, time.Hour); !isGone(err) {
+		t.Errorf("ExtendTTL() of expired token error = %v, want expired or not found", err)
+	}
+
+	tokens, err := s.ListByValidationID(ctx, validationID)
+	if err != nil {
+		t.Fatalf("ListByValidationID() error = %v", err)
+	}
+	if !slices.Equal(values(tokens), []string{live.Value}) {
+		t.Errorf("ListByValidationID() = %v, want only the unexpired [%s]", values(tokens), live.Value)
+	}
+}
+
+func testConsume(t *testing.T, s token.Storage) {
+	ctx := context.Background()
+
+	tkn := newToken(token.TypeCode, unique("validation"), time.Hour)
+	mustStore(t, s, tkn)
+
+	got, err := s.Consume(ctx, tkn.Value, tkn.Type)
+	if err != nil {
+		t.Fatalf("Consume() error = %v", err)
+	}
+	checkSame(t, "Consume()", got, tkn)
+
+	if _, err := s.Consume(ctx, tkn.Value, tkn.Type); !errors.Is(err, token.ErrTokenNotFound) {
+		t.Errorf("second Consume() error = %v, want %v", err, token.ErrTokenNotFound)
+	}
+	if _, err := s.Retrieve(ctx, tkn.Value, tkn.Type); !errors.Is(err, token.ErrTokenNotFound) {
+		t.Errorf("Retrieve() after Consume() error = %v, want %v", err, token.ErrTokenNotFound)
+	}
+	if tokens, err := s.ListByValidationID(ctx, tkn.ValidationID); err != nil || len(tokens) != 0 {
+		t.Errorf("ListByValidationID() after Consume() = %v, %v, want none", values(tokens), err)
+	}
+}
+
+func testConcurrentConsume(t *testing.T, s token.Storage) {
+	ctx := context.Background()
+
+	tkn := newToken(token.TypeCode, unique("validation"), time.Hour)
+	mustStore(t, s, tkn)
+
+	var wg sync.WaitGroup
+	var succeeded atomic.Int64
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, err := s.Consume(ctx, tkn.Value, tkn.Type)
+			switch {
+			case err == nil:
+				succeeded.Add(1)
+			case !errors.Is(err, token.ErrTokenNotFound):
+				t.Errorf("concurrent Consume() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := succeeded.Load(); n != 1 {
+		t.Errorf("%d of %d concurrent Consume() calls succeeded, want exactly 1", n, concurrency)
+	}
+}
+
+func testConcurrentStore(t *testing.T, s token.Storage) {
+	ctx := context.Background()
+
+	validationID := unique("validation")
+	tokens := make([]*token.Token, concurrency)
+	for i := range tokens {
+		tokens[i] = newToken(token.TypeLink, validationID, time.Hour)
+	}
+
+	var wg sync.WaitGroup
+	for _, tkn := range tokens {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if err := s.Store(ctx, tkn); err != nil {
+				t.Errorf("concurrent Store() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	got, err := s.ListByValidationID(ctx, validationID)
+	if err != nil {
+		t.Fatalf("ListByValidationID() error = %v", err)
+	}
+	if want := values(tokens); !slices.Equal(values(got), want) {
+		t.Errorf("ListByValidationID() after concurrent stores has %d tokens, want %d", len(got), len(want))
+	}
+}
+
+func testIncrementAttempts(t *testing.T, s token.Storage) {
+	ctx := context.Background()
+
+	validationID := unique("validation")
+	for want := 1; want <= 3; want++ {
+		got, err := s.IncrementAttempts(ctx, validationID, time.Hour)
+		if err != nil {
+			t.Fatalf("IncrementAttempts() error = %v", err)
+		}
+		if got != want {
+			t.Errorf("IncrementAttempts() = %d, want %d", got, want)
+		}
+	}
+
+	if got, err := s.IncrementAttempts(ctx, unique("validation"), time.Hour); err != nil || got != 1 {
+		t.Errorf("IncrementAttempts() for another validation = %d, %v, want 1", got, err)
+	}
+
+	// Concurrent increments are all counted
+	concurrentID := unique("validation")
+	var wg sync.WaitGroup
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if _, err := s.IncrementAttempts(ctx, concurrentID, time.Hour); err != nil {
+				t.Errorf("concurrent IncrementAttempts() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if got, _ := s.IncrementAttempts(ctx, concurrentID, time.Hour); got != concurrency+1 {
+		t.Errorf("IncrementAttempts() after %d concurrent increments = %d, want %d", concurrency, got, concurrency+1)
+	}
+
+	if _, err := s.IncrementAttempts(ctx, "", time.Hour); !errors.Is(err, token.ErrEmptyValidationID) {
+		t.Errorf("IncrementAttempts(\"\") error = %v, want %v", err, token.ErrEmptyValidationID)
+	}
+}
+
+func testExtendTTL(t *testing.T, s token.Storage) {
+	ctx := context.Background()
+
+	tkn := newToken(token.TypeLink, unique("validation"), time.Hour)
+	mustStore(t, s, tkn)
+
+	extended, err := s.ExtendTTL(ctx, tkn.Value, tkn.Type, time.Hour)
+	if err != nil {
+		t.Fatalf("ExtendTTL() error = %v", err)
+	}
+
+	want := *tkn
+	want.ValidUntil = tkn.ValidUntil.Add(time.Hour)
+	checkSame(t, "ExtendTTL()", extended, &want)
+
+	got, err := s.Retrieve(ctx, tkn.Value, tkn.Type)
+	if err != nil {
+		t.Fatalf("Retrieve() after ExtendTTL() error = %v", err)
+	}
+	checkSame(t, "Retrieve() after ExtendTTL()", got, &want)
+
+	if _, err := s.ExtendTTL(ctx, unique("missing"), token.TypeLink, time.Hour); !errors.Is(err, token.ErrTokenNotFound) {
+		t.Errorf("ExtendTTL() of missing token error = %v, want %v", err, token.ErrTokenNotFound)
+	}
+}
+
+func testCanceledContext(t *testing.T, s token.Storage) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tkn := newToken(token.TypeLink, unique("validation"), time.Hour)
+
+	errs := map[string]error{
+		"Store": s.Store(ctx, tkn),
+	}
+	_, errs["Retrieve"] = s.Retrieve(ctx, tkn.Value, tkn.Type)
+	_, errs["Consume"] = s.Consume(ctx, tkn.Value, tkn.Type)
+	_, errs["ListByValidationID"] = s.ListByValidationID(ctx, tkn.ValidationID)
+	_, errs["IncrementAttempts"] = s.IncrementAttempts(ctx, tkn.ValidationID, time.Hour)
+	errs["Delete"] = s.Delete(ctx, tkn.Value, tkn.Type)
+	errs["DeleteByValidationID"] = s.DeleteByValidationID(ctx, tkn.ValidationID)
+
+	for _, op := range slices.Sorted(maps.Keys(errs)) {
+		if !errors.Is(errs[op], context.Canceled) {
+			t.Errorf("%s() with canceled context error = %v, want %v", op, errs[op], context.Canceled)
+		}
+	}
+
+	if _, err := s.Retrieve(context.Background(), tkn.Value, tkn.Type); !errors.Is(err, token.ErrTokenNotFound) {
+		t.Errorf("Retrieve() after canceled Store() error = %v, want %v", err, token.ErrTokenNotFound)
+	}
+}
+
+func testStoreBatch(t *testing.T, s token.Storage) {
+	batch, ok := s.(token.BatchStorage)
+	if !ok {
+		t.Skip("storage does not implement token.BatchStorage")
+	}
+
+	ctx := context.Background()
+	validationID := unique("validation")
+	tokens := []*token.Token{
+		newToken(token.TypeLink, validationID, time.Hour),
+		newToken(token.TypeCode, validationID, time.Hour),
+		newToken(token.TypeLink, unique("validation"), time.Hour),
+	}
+
+	if err := batch.StoreBatch(ctx, tokens); err != nil {
+		t.Fatalf("StoreBatch() error = %v", err)
+	}
+	for _, want := range tokens {
+		got, err := s.Retrieve(ctx, want.Value, want.Type)
+		if err != nil {
+			t.Fatalf("Retrieve(%q) after StoreBatch() error = %v", want.Value, err)
+		}
+		checkSame(t, "Retrieve() after StoreBatch()", got, want)
+	}
+	if got, _ := s.ListByValidationID(ctx, validationID); len(got) != 2 {
+		t.Errorf("ListByValidationID() after StoreBatch() = %v, want 2 tokens", values(got))
+	}
+
+	// An invalid token fails the whole batch
+	invalid := newToken(token.TypeLink, "", time.Hour)
+	valid := newToken(token.TypeLink, unique("validation"), time.Hour)
+	if err := batch.StoreBatch(ctx, []*token.Token{valid, invalid}); err == nil {
+		t.Error("StoreBatch() with an invalid token succeeded")
+	}
+	if _, err := s.Retrieve(ctx, valid.Value, valid.Type); !errors.Is(err, token.ErrTokenNotFound) {
+		t.Errorf("Retrieve() of token from failed batch error = %v, want %v", err, token.ErrTokenNotFound)
+	}
+}
+
+func testDeleteBatch(t *testing.T, s token.Storage) {
+	batch, ok := s.(token.BatchStorage)
+	if !ok {
+		t.Skip("storage does not implement token.BatchStorage")
+	}
+
+	ctx := context.Background()
+	validationID := unique("validation")
+	deleted := []*token.Token{
+		newToken(token.TypeLink, validationID, time.Hour),
+		newToken(token.TypeCode, unique("validation"), time.Hour),
+	}
+	kept := newToken(token.TypeCode, validationID, time.Hour)
+	mustStore(t, s, append(deleted, kept)...)
+
+	refs := []token.TokenRef{{Value: unique("missing"), Type: token.TypeLink}}
+	for _, tkn := range deleted {
+		refs = append(refs, token.TokenRef{Value: tkn.Value, Type: tkn.Type})
+	}
+	if err := batch.DeleteBatch(ctx, refs); err != nil {
+		t.Fatalf("DeleteBatch() error = %v", err)
+	}
+
+	for _, tkn := range deleted {
+		if _, err := s.Retrieve(ctx, tkn.Value, tkn.Type); !errors.Is(err, token.ErrTokenNotFound) {
+			t.Errorf("Retrieve(%q) after DeleteBatch() error = %v, want %v", tkn.Value, err, token.ErrTokenNotFound)
+		}
+	}
+	got, err := s.ListByValidationID(ctx, validationID)
+	if err != nil {
+		t.Fatalf("ListByValidationID() error = %v", err)
+	}
+	if len(got) != 1 || got[0].Value != kept.Value {
+		t.Errorf("ListByValidationID() after DeleteBatch() = %v, want [%s]", values(got), kept.Value)
+	}
+
+	if err := batch.DeleteBatch(ctx, nil); err != nil {
+		t.Errorf("DeleteBatch(nil) error = %v", err)
+	}
+}
@@ -5,10 +5,18 @@ package token
 import (
 	"context"
 	"crypto/rand"
+	"encoding/base32"
 	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"math/big"
+	"slices"
+	"strings"
 	"time"
+	"unicode"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/redact"
 )
 
 // Type represents the type of token being generated.
@@ -30,6 +38,23 @@ const DefaultCodeTokenLength = 4 // 32 bits of entropy
 // DefaultCodeCharset defines the characters used in code tokens.
 const DefaultCodeCharset = "0123456789"
 
+// Charset presets for code tokens.
+const (
+	// CharsetDigits contains the decimal digits.
+	CharsetDigits = DefaultCodeCharset
+
+	// CharsetAlnumNoAmbiguous contains upper-case letters and digits without
+	// characters that are easily confused when read or typed: 0/O, 1/I/L.
+	CharsetAlnumNoAmbiguous = "23456789ABCDEFGHJKMNPQRSTUVWXYZ"
+
+	// CharsetUpperNoAmbiguous contains upper-case letters without I, L and O.
+	CharsetUpperNoAmbiguous = "ABCDEFGHJKMNPQRSTUVWXYZ"
+)
+
+// CodeGroupSeparator separates character groups in code tokens when grouping
+// is enabled with WithCodeGrouping.
+const CodeGroupSeparator = "-"
+
 // Common errors for token storage operations.
 var (
 	ErrTokenNotFound       = errors.New("token not found")
@@ -39,24 +64,143 @@ var (
 	ErrTokenNil            = errors.New("token cannot be nil")
 	ErrEmptyTokenValue     = errors.New("token value cannot be empty")
 	ErrEmptyValidationID   = errors.New("validation ID cannot be empty")
+	ErrAttemptsExceeded    = errors.New("too many failed verification attempts")
+	ErrTokenTypeMismatch   = errors.New("token type mismatch")
+	ErrStatelessToken      = errors.New("operation not supported for stateless tokens")
+	ErrTokenPrefixMismatch = errors.New("token prefix mismatch")
+	ErrCodeNeedsValidation = errors.New("code tokens must be verified with their validation ID")
 )
 
+// LinkTokenEncoding selects how random link token bytes are rendered.
+type LinkTokenEncoding int
+
+const (
+	// EncodingBase64URL renders link tokens as unpadded URL-safe base64. It
+	// is the default and the most compact encoding.
+	EncodingBase64URL LinkTokenEncoding = iota
+	// EncodingBase58 renders link tokens with the Bitcoin base58 alphabet,
+	// which omits the lookalike characters 0, O, I, and l so tokens can be
+	// read aloud or typed without ambiguity.
+	EncodingBase58
+	// EncodingUUIDv7 renders link tokens as RFC 9562 version 7 UUIDs, which
+	// embed a millisecond timestamp and sort by creation time. The token
+	// length setting is ignored and each token carries 74 random bits.
+	EncodingUUIDv7
+	// EncodingBase32Crockford renders link tokens with Crockford's base32
+	// alphabet. Tokens are case-insensitive and tolerate the lookalikes O, I,
+	// and L on input, making them suitable for manual entry and QR codes.
+	EncodingBase32Crockford
+)
+
+// crockfordAlphabet is Crockford's base32 alphabet, which excludes I, L, O,
+// and U.
+const crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// crockfordEncoding renders link tokens for EncodingBase32Crockford.
+var crockfordEncoding = base32.NewEncoding(crockfordAlphabet).WithPadding(base32.NoPadding)
+
+// TokenPrefixer is implemented by token generators that mark tokens with a
+// fixed prefix per type. The Manager rejects presented tokens that lack the
+// expected prefix without consulting storage.
+type TokenPrefixer interface {
+	TokenPrefix(tokenType Type) string
+}
+
+// LinkTokenNormalizer is implemented by token generators whose link tokens
+// accept more than one spelling. The Manager canonicalizes incoming link
+// token values with it before looking them up.
+type LinkTokenNormalizer interface {
+	NormalizeLinkToken(value string) string
+}
+
+// CodeTokenNormalizer is implemented by token generators whose code tokens
+// accept more than one spelling. The Manager canonicalizes incoming code
+// token values with it before looking them up.
+type CodeTokenNormalizer interface {
+	NormalizeCodeToken(value string) string
+}
+
+// base58Alphabet is the Bitcoin base58 alphabet.
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// uuidv7RandomBits is the number of random bits in a version 7 UUID.
+const uuidv7RandomBits = 74
+
+// Clock provides the current time. Components accept a Clock so expiry can be
+// tested deterministically without sleeping.
+type Clock interface {
+	Now() time.Time
+}
+
+// ClockFunc adapts a function to the Clock interface.
+type ClockFunc func() time.Time
+
+// Now returns f().
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
+
+// SystemClock is the Clock backed by time.Now.
+var SystemClock Clock = ClockFunc(time.Now)
+
+// TokenGenerator produces raw token values. Generator is the default
+// implementation; deterministic or externally backed generators can be
+// plugged into a Manager with WithGenerator.
+type TokenGenerator interface {
+	GenerateLinkToken() (string, error)
+	GenerateCodeToken() (string, error)
+}
+
 // Generator provides secure token generation functionality.
 type Generator struct {
-	linkTokenLength int
-	codeTokenLength int
-	codeCharset     string
+	linkTokenLength   int
+	linkTokenEncoding LinkTokenEncoding
+	codeTokenLength   int
+	codeCharset       string
+	codeGroupSize     int
+	codeUnbiased      bool
+	linkTokenPrefix   string
+	codeTokenPrefix   string
+	clock             Clock
 }
 
 // NewGenerator creates a new Generator with secure defaults.
 func NewGenerator() *Generator {
 	return &Generator{
-		linkTokenLength: DefaultLinkTokenLength,
-		codeTokenLength: DefaultCodeTokenLength,
-		codeCharset:     DefaultCodeCharset,
+		linkTokenLength:   DefaultLinkTokenLength,
+		linkTokenEncoding: EncodingBase64URL,
+		codeTokenLength:   DefaultCodeTokenLength,
+		codeCharset:       DefaultCodeCharset,
+		codeUnbiased:      true,
+		clock:             SystemClock,
 	}
 }
 
+// WithClock sets the clock used for time-based link token encodings.
+func (g *Generator) WithClock(clock Clock) *Generator {
+	g.clock = clock
+
+	return g
+}
+
+// WithLinkTokenEncoding sets the encoding used for link tokens.
+func (g *Generator) WithLinkTokenEncoding(encoding LinkTokenEncoding) *Generator {
+	g.linkTokenEncoding = encoding
+
+	return g
+}
+
+// LinkTokenEntropyBits returns the number of random bits in each link token
+// produced by the current configuration. With b bits, the probability of any
+// collision among n tokens is approximately n^2 / 2^(b+1).
+func (g *Generator) LinkTokenEntropyBits() int {
+	if g.linkTokenEncoding == EncodingUUIDv7 {
+		return uuidv7RandomBits
+	}
+
+	return g.linkTokenLength * 8
+}
+
 // WithLinkTokenLength sets a custom link token length.
 func (g *Generator) WithLinkTokenLength(length int) *Generator {
 	g.linkTokenLength = length
@@ -80,29 +224,232 @@ func (g *Generator) WithCodeCharset(charset string) *Generator {
 	return g
 }
 
+// WithLinkTokenPrefix prepends prefix, e.g. "evl_", to generated link tokens
+// so leaked tokens are recognizable in logs and by secret scanners. The
+// prefix does not add entropy.
+func (g *Generator) WithLinkTokenPrefix(prefix string) *Generator {
+	g.linkTokenPrefix = prefix
+
+	return g
+}
+
+// WithCodeTokenPrefix prepends prefix, e.g. "evc_", to generated code tokens.
+// Grouping applies to the random part only.
+func (g *Generator) WithCodeTokenPrefix(prefix string) *Generator {
+	g.codeTokenPrefix = prefix
+
+	return g
+}
+
+// TokenPrefix returns the prefix configured for the given token type.
+func (g *Generator) TokenPrefix(tokenType Type) string {
+	switch tokenType {
+	case TypeLink:
+		return g.linkTokenPrefix
+	case TypeCode:
+		return g.codeTokenPrefix
+	default:
+		return ""
+	}
+}
+
+// WithRejectionSampling controls how random bytes are mapped to code
+// characters. When enabled (the default), bytes that would bias the
+// distribution are discarded so every charset character is equally likely.
+// Disabling it restores the previous modulo mapping.
+func (g *Generator) WithRejectionSampling(enabled bool) *Generator {
+	g.codeUnbiased = enabled
+
+	return g
+}
+
+// WithCodeGrouping splits code tokens into groups of groupSize characters
+// joined by CodeGroupSeparator, e.g. "7GH-4KP". The separator does not count
+// toward the code token length. A groupSize of zero disables grouping.
+func (g *Generator) WithCodeGrouping(groupSize int) *Generator {
+	if groupSize >= 0 {
+		g.codeGroupSize = groupSize
+	}
+
+	return g
+}
+
 // GenerateLinkToken creates a cryptographically secure random token for link
-// validation. The token is URL-safe base64 encoded.
+// validation. The token is URL-safe base64 encoded unless another encoding
+// was selected with WithLinkTokenEncoding.
 func (g *Generator) GenerateLinkToken() (string, error) {
+	value, err := g.linkTokenBody()
+	if err != nil {
+		return "", err
+	}
+
+	return g.linkTokenPrefix + value, nil
+}
+
+// linkTokenBody generates the random, encoded part of a link token.
+func (g *Generator) linkTokenBody() (string, error) {
+	if g.linkTokenEncoding == EncodingUUIDv7 {
+		return newUUIDv7(g.clock.Now())
+	}
+
 	bytes := make([]byte, g.linkTokenLength)
 	if _, err := rand.Read(bytes); err != nil {
 		return "", fmt.Errorf("failed to generate random bytes: %w", err)
 	}
 
+	switch g.linkTokenEncoding {
+	case EncodingBase58:
+		return encodeBase58(bytes), nil
+	case EncodingBase32Crockford:
+		return crockfordEncoding.EncodeToString(bytes), nil
+	}
+
 	// Use URL-safe base64 encoding without padding
 	token := base64.RawURLEncoding.EncodeToString(bytes)
 
 	return token, nil
 }
 
+// NormalizeLinkToken returns the canonical spelling of a link token value.
+// Crockford base32 tokens are upper-cased, hyphens are dropped, and the
+// lookalikes O, I, and L are mapped to 0, 1, and 1; a configured prefix is
+// matched case-insensitively and kept as configured. Other encodings are
+// case-sensitive and returned unchanged.
+func (g *Generator) NormalizeLinkToken(value string) string {
+	if g.linkTokenEncoding != EncodingBase32Crockford {
+		return value
+	}
+
+	prefix := g.linkTokenPrefix
+	if len(value) >= len(prefix) && strings.EqualFold(value[:len(prefix)], prefix) {
+		value = value[len(prefix):]
+	} else {
+		prefix = ""
+	}
+
+	return prefix + strings.Map(func(r rune) rune {
+		switch r {
+		case '-':
+			return -1
+		case 'O', 'o':
+			return '0'
+		case 'I', 'i', 'L', 'l':
+			return '1'
+		}
+
+		return unicode.ToUpper(r)
+	}, value)
+}
+
+// encodeBase58 encodes data with the Bitcoin base58 alphabet. Leading zero
+// bytes are not preserved since the input is random rather than a payload
+// that must round-trip.
+func encodeBase58(data []byte) string {
+	n := new(big.Int).SetBytes(data)
+	radix := big.NewInt(int64(len(base58Alphabet)))
+	mod := new(big.Int)
+
+	var out []byte
+	for n.Sign() > 0 {
+		n.DivMod(n, radix, mod)
+		out = append(out, base58Alphabet[mod.Int64()])
+	}
+
+	if len(out) == 0 {
+		return base58Alphabet[:1]
+	}
+
+	slices.Reverse(out)
+
+	return string(out)
+}
+
+// newUUIDv7 returns a random RFC 9562 version 7 UUID for the given time in its
+// canonical hyphenated form.
+func newUUIDv7(now time.Time) (string, error) {
+	var u [16]byte
+	if _, err := rand.Read(u[6:]); err != nil {
+		return "", fmt.Errorf("failed to generate random bytes: %w", err)
+	}
+
+	ms := uint64(now.UnixMilli())
+	u[0] = byte(ms >> 40)
+	u[1] = byte(ms >> 32)
+	u[2] = byte(ms >> 24)
+	u[3] = byte(ms >> 16)
+	u[4] = byte(ms >> 8)
+	u[5] = byte(ms)
+	u[6] = (u[6] & 0x0f) | 0x70 // Version 7
+	u[8] = (u[8] & 0x3f) | 0x80 // RFC 9562 variant
+
+	h := hex.EncodeToString(u[:])
+
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:], nil
+}
+
 // GenerateCodeToken creates a cryptographically secure random token for code
 // validation. The token consists of digits from the configured charset.
 func (g *Generator) GenerateCodeToken() (string, error) {
+	var (
+		code []byte
+		err  error
+	)
+
+	if g.codeUnbiased {
+		code, err = g.sampleCode()
+	} else {
+		code, err = g.moduloCode()
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	return g.codeTokenPrefix + g.groupCode(string(code)), nil
+}
+
+// NormalizeCodeToken returns the canonical spelling of a code token value as
+// a user may type it: whitespace and group separators are dropped and the
+// groups of WithCodeGrouping restored, and letters are folded to the case of
+// the charset unless it uses both cases. A configured prefix is matched
+// case-insensitively and kept as configured.
+func (g *Generator) NormalizeCodeToken(value string) string {
+	value = strings.TrimSpace(value)
+
+	prefix := g.codeTokenPrefix
+	if len(value) >= len(prefix) && strings.EqualFold(value[:len(prefix)], prefix) {
+		value = value[len(prefix):]
+	} else {
+		prefix = ""
+	}
+
+	stripSeparator := !strings.Contains(g.codeCharset, CodeGroupSeparator)
+	hasUpper := g.codeCharset != strings.ToLower(g.codeCharset)
+	hasLower := g.codeCharset != strings.ToUpper(g.codeCharset)
+	code := strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsSpace(r), stripSeparator && string(r) == CodeGroupSeparator:
+			return -1
+		case hasUpper && !hasLower:
+			return unicode.ToUpper(r)
+		case hasLower && !hasUpper:
+			return unicode.ToLower(r)
+		}
+		return r
+	}, value)
+
+	return prefix + g.groupCode(code)
+}
+
+// moduloCode maps each random byte to a charset character with a modulo.
+// Characters are biased unless the charset length divides 256.
+func (g *Generator) moduloCode() ([]byte, error) {
 	bytes := make([]byte, g.codeTokenLength)
 	charsetLength := len(g.codeCharset)
 
 	// Generate random bytes with the crypto/rand package
 	if _, err := rand.Read(bytes); err != nil {
-		return "", fmt.Errorf("failed to generate random bytes: %w", err)
+		return nil, fmt.Errorf("failed to generate random bytes: %w", err)
 	}
 
 	// Map random bytes to characters in the charset
@@ -112,7 +459,56 @@ func (g *Generator) GenerateCodeToken() (string, error) {
 		code[i] = g.codeCharset[int(b)%charsetLength]
 	}
 
-	return string(code), nil
+	return code, nil
+}
+
+// sampleCode maps random bytes to charset characters using rejection
+// sampling, discarding bytes at or above the largest multiple of the charset
+// length so the result is uniformly distributed.
+func (g *Generator) sampleCode() ([]byte, error) {
+	charsetLength := len(g.codeCharset)
+	limit := 256 - 256%charsetLength
+	if charsetLength > 256 {
+		limit = 256
+	}
+
+	code := make([]byte, 0, g.codeTokenLength)
+	buf := make([]byte, g.codeTokenLength*2)
+
+	for len(code) < g.codeTokenLength {
+		if _, err := rand.Read(buf); err != nil {
+			return nil, fmt.Errorf("failed to generate random bytes: %w", err)
+		}
+
+		for _, b := range buf {
+			if int(b) >= limit {
+				continue
+			}
+			code = append(code, g.codeCharset[int(b)%charsetLength])
+			if len(code) == g.codeTokenLength {
+				break
+			}
+		}
+	}
+
+	return code, nil
+}
+
+// groupCode inserts CodeGroupSeparator between groups of characters.
+func (g *Generator) groupCode(code string) string {
+	if g.codeGroupSize <= 0 || len(code) <= g.codeGroupSize {
+		return code
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(code); i += g.codeGroupSize {
+		if i > 0 {
+			b.WriteString(CodeGroupSeparator)
+		}
+		b.WriteString(code[i:min(i+g.codeGroupSize, len(code))])
+	}
+
+	return b.String()
 }
 
 // Token represents a validation token with metadata.
@@ -122,12 +518,18 @@ type Token struct {
 	CreatedAt    time.Time // When the token was created
 	ValidUntil   time.Time // When the token expires
 	ValidationID string    // ID of the validation this token is associated with
+	// Metadata carries caller-defined context such as requester IP, locale,
+	// or campaign ID, and is persisted by storage backends with the token.
+	Metadata map[string]string
 }
 
 // New creates a new Token with the given parameters.
 func New(value string, tokenType Type, validationID string, ttl time.Duration) *Token {
-	now := time.Now()
+	return NewAt(value, tokenType, validationID, ttl, time.Now())
+}
 
+// NewAt creates a new Token created at the given time.
+func NewAt(value string, tokenType Type, validationID string, ttl time.Duration, now time.Time) *Token {
 	return &Token{
 		Value:        value,
 		Type:         tokenType,
@@ -139,7 +541,12 @@ func New(value string, tokenType Type, validationID string, ttl time.Duration) *
 
 // IsExpired checks if the token has expired.
 func (t *Token) IsExpired() bool {
-	return time.Now().After(t.ValidUntil)
+	return t.IsExpiredAt(time.Now())
+}
+
+// IsExpiredAt checks if the token has expired as of the given time.
+func (t *Token) IsExpiredAt(now time.Time) bool {
+	return now.After(t.ValidUntil)
 }
 
 // ValidateToken checks if a token is valid for storage.
@@ -168,11 +575,16 @@ type TokenExpiredError struct {
 	TokenValue string
 	TokenType  Type
 	ExpiredAt  time.Time
+
+	// ValidationID is the validation of the expired token, if the storage
+	// backend reports it.
+	ValidationID string
 }
 
-// Error implements the error interface.
+// Error implements the error interface. The token value is redacted, since
+// errors are routinely logged.
 func (e *TokenExpiredError) Error() string {
-	return fmt.Sprintf("token %s of type %d expired at %s", e.TokenValue, e.TokenType, e.ExpiredAt.Format(time.RFC3339))
+	return fmt.Sprintf("token %s of type %d expired at %s", redact.Token(e.TokenValue), e.TokenType, e.ExpiredAt.Format(time.RFC3339))
 }
 
 // IsTokenExpiredError checks if an error is a TokenExpiredError.
@@ -194,6 +606,78 @@ type Storage interface {
 
 	// DeleteByValidationID removes all tokens associated with a validation ID.
 	DeleteByValidationID(ctx context.Context, validationID string) error
+
+	// Consume retrieves a token and removes it from the storage backend in a
+	// single atomic step, so concurrent callers cannot both obtain it.
+	Consume(ctx context.Context, tokenValue string, tokenType Type) (*Token, error)
+
+	// IncrementAttempts records a failed verification attempt for a
+	// validation and returns the total number of failed attempts so far.
+	// Counters are keyed by validation ID rather than token value because a
+	// wrong code cannot identify the token it was meant for. The counter
+	// expires after ttl and is cleared by DeleteByValidationID.
+	IncrementAttempts(ctx context.Context, validationID string, ttl time.Duration) (int, error)
+
+	// ExtendTTL atomically pushes back the expiry of an unexpired token by
+	// extra, updating both its ValidUntil and the backend's own expiry, and
+	// returns the updated token.
+	ExtendTTL(ctx context.Context, tokenValue string, tokenType Type, extra time.Duration) (*Token, error)
+
+	// ListByValidationID returns the unexpired tokens associated with a
+	// validation ID, in no particular order. It returns an empty slice when
+	// there are none.
+	ListByValidationID(ctx context.Context, validationID string) ([]*Token, error)
+}
+
+// TokenRef identifies a stored token.
+type TokenRef struct {
+	Value string
+	Type  Type
+}
+
+// BatchStorage is implemented by storage backends that can store or delete
+// many tokens in a single roundtrip. The Manager uses it for batch creation
+// and invalidation when available and falls back to one call per token
+// otherwise.
+type BatchStorage interface {
+	// StoreBatch saves all tokens, or none of them if any is invalid.
+	StoreBatch(ctx context.Context, tokens []*Token) error
+
+	// DeleteBatch removes the referenced tokens and their validation ID
+	// index entries. As with Delete, tokens that do not exist are ignored.
+	DeleteBatch(ctx context.Context, refs []TokenRef) error
+}
+
+// StoreEach saves tokens one at a time with store, after validating all of
+// them so that none is stored if any is invalid. It is the StoreBatch of a
+// storage that cannot store tokens in one roundtrip, and is exported for
+// storage decorators whose wrapped storage is not a BatchStorage.
+func StoreEach(ctx context.Context, tokens []*Token, store func(context.Context, *Token) error) error {
+	for i, token := range tokens {
+		if err := Validate(token); err != nil {
+			return fmt.Errorf("token %d validation failed: %w", i, err)
+		}
+	}
+
+	for _, token := range tokens {
+		if err := store(ctx, token); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DeleteEach removes the referenced tokens one at a time with del. It is
+// the DeleteBatch counterpart of StoreEach.
+func DeleteEach(ctx context.Context, refs []TokenRef, del func(ctx context.Context, tokenValue string, tokenType Type) error) error {
+	for _, ref := range refs {
+		if err := del(ctx, ref.Value, ref.Type); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 // Validate checks if a token is valid for storage.
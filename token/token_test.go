@@ -1,6 +1,9 @@
 package token
 
 import (
+	"context"
+	"errors"
+	"regexp"
 	"strings"
 	"testing"
 	"time"
@@ -114,6 +117,77 @@ func TestGenerator_GenerateCodeToken(t *testing.T) {
 	}
 }
 
+func TestGenerator_CodeGrouping(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		length    int
+		groupSize int
+		pattern   string
+	}{
+		{name: "two groups", length: 6, groupSize: 3, pattern: `^[23456789A-HJKMNP-Z]{3}-[23456789A-HJKMNP-Z]{3}$`},
+		{name: "uneven groups", length: 8, groupSize: 3, pattern: `^[23456789A-HJKMNP-Z]{3}-[23456789A-HJKMNP-Z]{3}-[23456789A-HJKMNP-Z]{2}$`},
+		{name: "group larger than code", length: 4, groupSize: 6, pattern: `^[23456789A-HJKMNP-Z]{4}$`},
+		{name: "grouping disabled", length: 6, groupSize: 0, pattern: `^[23456789A-HJKMNP-Z]{6}$`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			generator := NewGenerator().
+				WithCodeCharset(CharsetAlnumNoAmbiguous).
+				WithCodeTokenLength(tt.length).
+				WithCodeGrouping(tt.groupSize)
+
+			code, err := generator.GenerateCodeToken()
+			if err != nil {
+				t.Fatalf("GenerateCodeToken() error = %v", err)
+			}
+			if !regexp.MustCompile(tt.pattern).MatchString(code) {
+				t.Errorf("GenerateCodeToken() = %q, want match for %s", code, tt.pattern)
+			}
+		})
+	}
+}
+
+func TestGenerator_RejectionSampling(t *testing.T) {
+	t.Parallel()
+
+	// With 129 characters, modulo mapping picks the last one for a single
+	// byte value (1/256) while uniform sampling picks it 1/129 of the time.
+	charset := strings.Repeat("A", 128) + "B"
+	const draws = 32000
+
+	countLast := func(g *Generator) int {
+		t.Helper()
+
+		g = g.WithCodeCharset(charset).WithCodeTokenLength(64)
+		count := 0
+		for range draws / 64 {
+			code, err := g.GenerateCodeToken()
+			if err != nil {
+				t.Fatalf("GenerateCodeToken() error = %v", err)
+			}
+			count += strings.Count(code, "B")
+		}
+
+		return count
+	}
+
+	// Expected counts are about 248 (uniform) and 125 (modulo); the
+	// threshold sits roughly four standard deviations from either.
+	const threshold = 186
+
+	if got := countLast(NewGenerator()); got < threshold {
+		t.Errorf("rejection sampling produced %d of %d last characters, want at least %d", got, draws, threshold)
+	}
+	if got := countLast(NewGenerator().WithRejectionSampling(false)); got >= threshold {
+		t.Errorf("modulo mapping produced %d of %d last characters, want fewer than %d", got, draws, threshold)
+	}
+}
+
 func TestToken_IsExpired(t *testing.T) {
 	t.Parallel()
 
@@ -158,3 +232,278 @@ func TestToken_IsExpired(t *testing.T) {
 		})
 	}
 }
+
+func TestGenerator_LinkTokenEncodings(t *testing.T) {
+	t.Parallel()
+
+	crockfordPattern := regexp.MustCompile(`^[0-9A-HJKMNP-TV-Z]{52}$`)
+	uuidv7Pattern := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+	tests := []struct {
+		name        string
+		encoding    LinkTokenEncoding
+		entropyBits int
+		check       func(string) bool
+	}{
+		{
+			name:        "base64url",
+			encoding:    EncodingBase64URL,
+			entropyBits: DefaultLinkTokenLength * 8,
+			check: func(s string) bool {
+				return !strings.ContainsAny(s, "+/=")
+			},
+		},
+		{
+			name:        "base58",
+			encoding:    EncodingBase58,
+			entropyBits: DefaultLinkTokenLength * 8,
+			check: func(s string) bool {
+				return !strings.ContainsAny(s, "0OIl+/=-_")
+			},
+		},
+		{
+			name:        "base32 crockford",
+			encoding:    EncodingBase32Crockford,
+			entropyBits: DefaultLinkTokenLength * 8,
+			check:       crockfordPattern.MatchString,
+		},
+		{
+			name:        "uuidv7",
+			encoding:    EncodingUUIDv7,
+			entropyBits: 74,
+			check:       uuidv7Pattern.MatchString,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			generator := NewGenerator().WithLinkTokenEncoding(tt.encoding)
+
+			if got := generator.LinkTokenEntropyBits(); got != tt.entropyBits {
+				t.Errorf("LinkTokenEntropyBits() = %d, want %d", got, tt.entropyBits)
+			}
+
+			// Collision check: with at least 74 bits of entropy the chance of
+			// any duplicate among 10,000 tokens is below 1e-14.
+			seen := make(map[string]bool)
+			for i := 0; i < 10000; i++ {
+				tkn, err := generator.GenerateLinkToken()
+				if err != nil {
+					t.Fatalf("GenerateLinkToken() error = %v", err)
+				}
+				if !tt.check(tkn) {
+					t.Fatalf("GenerateLinkToken() = %q, not valid for encoding", tkn)
+				}
+				if seen[tkn] {
+					t.Fatalf("GenerateLinkToken() produced duplicate %q", tkn)
+				}
+				seen[tkn] = true
+			}
+		})
+	}
+}
+
+func TestGenerator_NormalizeLinkToken(t *testing.T) {
+	t.Parallel()
+
+	crockford := NewGenerator().WithLinkTokenEncoding(EncodingBase32Crockford)
+	if got := crockford.NormalizeLinkToken("abc-oil-7z"); got != "ABC0117Z" {
+		t.Errorf("NormalizeLinkToken() = %q, want %q", got, "ABC0117Z")
+	}
+
+	base64url := NewGenerator()
+	if got := base64url.NormalizeLinkToken("abc-oil"); got != "abc-oil" {
+		t.Errorf("NormalizeLinkToken() = %q, want unchanged", got)
+	}
+}
+
+func TestGenerator_NormalizeCodeToken(t *testing.T) {
+	t.Parallel()
+
+	grouped := NewGenerator().WithCodeCharset(CharsetAlnumNoAmbiguous).WithCodeTokenLength(6).WithCodeGrouping(3)
+	digits := NewGenerator().WithCodeTokenLength(6).WithCodeGrouping(3).WithCodeTokenPrefix("evc_")
+	mixedCase := NewGenerator().WithCodeCharset("abcABC")
+
+	tests := []struct {
+		name      string
+		generator *Generator
+		in        string
+		want      string
+	}{
+		{"canonical", grouped, "7GH-4KP", "7GH-4KP"},
+		{"without separator", grouped, "7GH4KP", "7GH-4KP"},
+		{"lower case", grouped, "7gh-4kp", "7GH-4KP"},
+		{"spaces", grouped, " 7gh 4kp ", "7GH-4KP"},
+		{"misplaced separator", grouped, "7G-H4KP", "7GH-4KP"},
+		{"prefix", digits, "EVC_123456", "evc_123-456"},
+		{"case-sensitive charset", mixedCase, "aB-c", "aBc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := tt.generator.NormalizeCodeToken(tt.in); got != tt.want {
+				t.Errorf("NormalizeCodeToken(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerator_TokenPrefixes(t *testing.T) {
+	t.Parallel()
+
+	generator := NewGenerator().
+		WithLinkTokenPrefix("evl_").
+		WithCodeTokenPrefix("evc_").
+		WithLinkTokenEncoding(EncodingBase32Crockford)
+
+	link, err := generator.GenerateLinkToken()
+	if err != nil {
+		t.Fatalf("GenerateLinkToken() error = %v", err)
+	}
+	if !strings.HasPrefix(link, "evl_") {
+		t.Errorf("GenerateLinkToken() = %q, want prefix %q", link, "evl_")
+	}
+
+	code, err := generator.GenerateCodeToken()
+	if err != nil {
+		t.Fatalf("GenerateCodeToken() error = %v", err)
+	}
+	if !strings.HasPrefix(code, "evc_") || len(code) != len("evc_")+DefaultCodeTokenLength {
+		t.Errorf("GenerateCodeToken() = %q, want prefix %q and %d random characters", code, "evc_", DefaultCodeTokenLength)
+	}
+
+	// Normalization keeps the configured prefix spelling
+	if got := generator.NormalizeLinkToken(strings.ToUpper(link[:4]) + strings.ToLower(link[4:])); got != link {
+		t.Errorf("NormalizeLinkToken() = %q, want %q", got, link)
+	}
+}
+
+func TestGenerator_UUIDv7IsTimeOrdered(t *testing.T) {
+	t.Parallel()
+
+	generator := NewGenerator().WithLinkTokenEncoding(EncodingUUIDv7)
+
+	first, err := generator.GenerateLinkToken()
+	if err != nil {
+		t.Fatalf("GenerateLinkToken() error = %v", err)
+	}
+
+	time.Sleep(2 * time.Millisecond)
+
+	second, err := generator.GenerateLinkToken()
+	if err != nil {
+		t.Fatalf("GenerateLinkToken() error = %v", err)
+	}
+
+	if first >= second {
+		t.Errorf("UUIDv7 tokens not time ordered: %q >= %q", first, second)
+	}
+}
+
+func BenchmarkGenerator_GenerateLinkToken(b *testing.B) {
+	encodings := []struct {
+		name     string
+		encoding LinkTokenEncoding
+	}{
+		{"base64url", EncodingBase64URL},
+		{"base58", EncodingBase58},
+		{"uuidv7", EncodingUUIDv7},
+	}
+
+	for _, enc := range encodings {
+		b.Run(enc.name, func(b *testing.B) {
+			generator := NewGenerator().WithLinkTokenEncoding(enc.encoding)
+
+			for b.Loop() {
+				if _, err := generator.GenerateLinkToken(); err != nil {
+					b.Fatalf("GenerateLinkToken() error = %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestToken_IsExpiredAt(t *testing.T) {
+	t.Parallel()
+
+	created := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	tkn := NewAt("test-token", TypeCode, "test-id", time.Minute, created)
+
+	if tkn.IsExpiredAt(created.Add(time.Minute)) {
+		t.Error("IsExpiredAt() reported expiry at exactly ValidUntil")
+	}
+	if !tkn.IsExpiredAt(created.Add(time.Minute + time.Nanosecond)) {
+		t.Error("IsExpiredAt() did not report expiry after ValidUntil")
+	}
+}
+
+func TestStoreEach(t *testing.T) {
+	t.Parallel()
+
+	valid := New("valid-token", TypeCode, "test-id", time.Minute)
+	invalid := New("", TypeCode, "test-id", time.Minute)
+	errStore := errors.New("store failed")
+
+	tests := []struct {
+		name       string
+		tokens     []*Token
+		storeErr   error
+		wantErr    error
+		wantStored int
+	}{
+		{"all valid", []*Token{valid, valid}, nil, nil, 2},
+		{"none stored if any is invalid", []*Token{valid, invalid}, nil, ErrEmptyTokenValue, 0},
+		{"nil token", []*Token{valid, nil}, nil, ErrTokenNil, 0},
+		{"store error stops", []*Token{valid, valid}, errStore, errStore, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			stored := 0
+			err := StoreEach(context.Background(), tt.tokens, func(context.Context, *Token) error {
+				stored++
+				return tt.storeErr
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("StoreEach() error = %v, want %v", err, tt.wantErr)
+			}
+			if stored != tt.wantStored {
+				t.Errorf("StoreEach() stored %d tokens, want %d", stored, tt.wantStored)
+			}
+		})
+	}
+}
+
+func TestDeleteEach(t *testing.T) {
+	t.Parallel()
+
+	refs := []TokenRef{{Value: "a", Type: TypeLink}, {Value: "b", Type: TypeCode}}
+	errDelete := errors.New("delete failed")
+
+	var deleted []TokenRef
+	err := DeleteEach(context.Background(), refs, func(_ context.Context, value string, typ Type) error {
+		deleted = append(deleted, TokenRef{Value: value, Type: typ})
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("DeleteEach() error = %v", err)
+	}
+	if len(deleted) != 2 || deleted[0] != refs[0] || deleted[1] != refs[1] {
+		t.Errorf("DeleteEach() deleted %v, want %v", deleted, refs)
+	}
+
+	calls := 0
+	err = DeleteEach(context.Background(), refs, func(context.Context, string, Type) error {
+		calls++
+		return errDelete
+	})
+	if !errors.Is(err, errDelete) || calls != 1 {
+		t.Errorf("DeleteEach() = %v after %d calls, want %v after 1", err, calls, errDelete)
+	}
+}
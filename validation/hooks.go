@@ -0,0 +1,41 @@
+package validation
+
+import "context"
+
+// HookFunc is called with a validation as it was left by a state change.
+type HookFunc func(ctx context.Context, v *Validation)
+
+// Hooks are callbacks a Manager invokes on validation state changes, for
+// example to send webhooks. Any field may be nil. Hooks run synchronously on
+// the calling goroutine after the change has been saved, so they should
+// return quickly and must not call back into the Manager. Slow integrations
+// can be run in the background, in order per validation, with a
+// dispatch.Dispatcher.
+type Hooks struct {
+	// OnCreated is called after Start has saved a pending validation and
+	// issued its tokens.
+	OnCreated HookFunc
+
+	// OnVerified is called after the recipient completed a validation.
+	OnVerified HookFunc
+
+	// OnExpired is called after a validation found past its expiry has been
+	// moved to StateExpired. Validations are checked for expiry when they
+	// are read, so one expiring unread is not reported until it is next
+	// read.
+	OnExpired HookFunc
+}
+
+// WithHooks sets the state change hooks for Manager.
+func WithHooks(hooks Hooks) Option {
+	return func(m *Manager) {
+		m.hooks = hooks
+	}
+}
+
+// fire calls hook if it is set, with a copy of v the hook may keep.
+func fire(ctx context.Context, hook HookFunc, v *Validation) {
+	if hook != nil {
+		hook(ctx, v.Clone())
+	}
+}
@@ -0,0 +1,426 @@
+package validation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/check/syntax"
+	"github.com/jaeyeom/email-validator-grpc-mcp/idgen"
+	"github.com/jaeyeom/email-validator-grpc-mcp/redact"
+	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+)
+
+// Request describes a validation to start.
+type Request struct {
+	Email   string
+	Channel Channel
+
+	// Methods are the types of token to issue, such as a link and a code
+	// for the recipient to choose from. A link token is issued when empty.
+	Methods []token.Type
+
+	// TTL is how long the validation may be completed in. The token
+	// manager's default TTL for each method is used when it is not
+	// positive.
+	TTL time.Duration
+}
+
+// Manager drives validations through their lifecycle and owns the tokens
+// issued for them.
+type Manager struct {
+	repo   Repository
+	tokens *token.Manager
+	ids    *idgen.Generator
+	syntax *syntax.Checker
+	logger *slog.Logger
+	clock  token.Clock
+	hooks  Hooks
+}
+
+// Option is a functional option for configuring Manager.
+type Option func(*Manager)
+
+// WithLogger sets a custom logger for Manager.
+func WithLogger(logger *slog.Logger) Option {
+	return func(m *Manager) {
+		m.logger = logger
+	}
+}
+
+// WithClock sets the clock used for validation timestamps and expiry. It
+// should match the clock of the token manager.
+func WithClock(clock token.Clock) Option {
+	return func(m *Manager) {
+		m.clock = clock
+	}
+}
+
+// WithIDGenerator sets the generator of validation IDs. The default
+// generates "val_"-prefixed UUIDv7 IDs.
+func WithIDGenerator(ids *idgen.Generator) Option {
+	return func(m *Manager) {
+		m.ids = ids
+	}
+}
+
+// WithSyntaxChecker sets the checker addresses must pass before a
+// validation starts. The default is syntax.New().
+func WithSyntaxChecker(checker *syntax.Checker) Option {
+	return func(m *Manager) {
+		m.syntax = checker
+	}
+}
+
+// NewManager creates a Manager keeping validations in repo and issuing
+// their tokens with tokens.
+func NewManager(repo Repository, tokens *token.Manager, opts ...Option) *Manager {
+	m := &Manager{
+		repo:   repo,
+		tokens: tokens,
+		ids:    idgen.New(),
+		syntax: syntax.New(),
+		logger: slog.Default(),
+		clock:  token.SystemClock,
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m
+}
+
+// Start creates a pending validation and issues its tokens, bound to the
+// email address, in the order of req.Methods. The caller delivers the tokens
+// and then calls MarkSent. The validation expires with its longest-lived
+// token.
+//
+// The address, trimmed of surrounding white space, must pass the syntax
+// checker first; otherwise the returned error wraps a *syntax.Error
+// describing the issues, and no token is issued.
+func (m *Manager) Start(ctx context.Context, req Request) (*Validation, []*token.Token, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, fmt.Errorf("context error: %w", err)
+	}
+
+	req.Email = strings.TrimSpace(req.Email)
+	if req.Email == "" {
+		return nil, nil, ErrEmptyEmail
+	}
+
+	if err := m.syntax.Check(req.Email).Err(); err != nil {
+		return nil, nil, fmt.Errorf("cannot start validation: %w", err)
+	}
+
+	channel := req.Channel
+	if channel == "" {
+		channel = ChannelEmail
+	}
+
+	id, err := m.ids.NewUnique(ctx, m.exists)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate validation ID: %w", err)
+	}
+
+	methods := req.Methods
+	if len(methods) == 0 {
+		methods = []token.Type{token.TypeLink}
+	}
+
+	tokens, err := m.issue(ctx, id, req.Email, methods, req.TTL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var expiresAt time.Time
+	for _, tkn := range tokens {
+		if tkn.ValidUntil.After(expiresAt) {
+			expiresAt = tkn.ValidUntil
+		}
+	}
+
+	now := m.clock.Now()
+	v := &Validation{
+		ID:        id,
+		Email:     req.Email,
+		Channel:   channel,
+		Methods:   slices.Clone(methods),
+		State:     StatePending,
+		CreatedAt: now,
+		UpdatedAt: now,
+		ExpiresAt: expiresAt,
+	}
+
+	if err := m.repo.Create(ctx, v); err != nil {
+		if invErr := m.tokens.InvalidateValidation(ctx, id); invErr != nil {
+			m.logger.Warn("failed to invalidate tokens of unsaved validation",
+				"validation_id", id,
+				"error", invErr)
+		}
+		return nil, nil, fmt.Errorf("failed to save validation: %w", err)
+	}
+
+	m.logger.Info("validation started",
+		"validation_id", id,
+		"email", redact.Email(req.Email),
+		"channel", channel,
+		"expires_at", v.ExpiresAt)
+
+	fire(ctx, m.hooks.OnCreated, v)
+
+	return v, tokens, nil
+}
+
+// Get returns the validation with the given ID. A validation past its
+// expiry is moved to StateExpired first.
+func (m *Manager) Get(ctx context.Context, id string) (*Validation, error) {
+	v, err := m.repo.Get(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get validation: %w", err)
+	}
+
+	if v.State.IsTerminal() || m.clock.Now().Before(v.ExpiresAt) {
+		return v, nil
+	}
+
+	expired, err := m.transition(ctx, v, StateExpired)
+	if errors.Is(err, ErrConflict) {
+		// Another transition won; report the state it left.
+		return m.Get(ctx, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	fire(ctx, m.hooks.OnExpired, expired)
+
+	return expired, nil
+}
+
+// Reissue returns tokens of the same methods for a pending or sent
+// validation, valid until it expires, so its message can be resent. The
+// link token is reused, so links in messages already sent keep working;
+// code tokens are replaced by new ones. Failed code attempts keep counting
+// against the validation.
+func (m *Manager) Reissue(ctx context.Context, id string) (*Validation, []*token.Token, error) {
+	v, err := m.Get(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if v.State.IsTerminal() {
+		return nil, nil, fmt.Errorf("%w: validation is %s", ErrInvalidTransition, v.State)
+	}
+
+	ttl := v.ExpiresAt.Sub(m.clock.Now())
+	tokens := make([]*token.Token, 0, len(v.Methods))
+	for _, method := range v.Methods {
+		if method == token.TypeLink {
+			tkn, err := m.tokens.CreateOrGetLinkTokenWithOptions(ctx, id, token.CreateOptions{TTL: ttl, Email: v.Email})
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to get link token: %w", err)
+			}
+			tokens = append(tokens, tkn)
+			continue
+		}
+
+		if err := m.tokens.InvalidateTokensOfType(ctx, id, method); err != nil {
+			return nil, nil, fmt.Errorf("failed to invalidate previous tokens: %w", err)
+		}
+		issued, err := m.issue(ctx, id, v.Email, []token.Type{method}, ttl)
+		if err != nil {
+			return nil, nil, err
+		}
+		tokens = append(tokens, issued...)
+	}
+
+	m.logger.Info("validation tokens reissued",
+		"validation_id", id)
+
+	return v, tokens, nil
+}
+
+// FindVerified returns the most recent validation of email verified within
+// the given duration, or ErrNotFound if there is none. Addresses are
+// compared after NormalizeEmail.
+func (m *Manager) FindVerified(ctx context.Context, email string, within time.Duration) (*Validation, error) {
+	validations, err := m.repo.GetByEmail(ctx, email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get validations: %w", err)
+	}
+
+	since := m.clock.Now().Add(-within)
+	for _, v := range validations {
+		if v.State == StateVerified && v.ResolvedAt.After(since) {
+			return v, nil
+		}
+	}
+
+	return nil, ErrNotFound
+}
+
+// MarkSent records that the validation's message has been sent, or sent
+// again.
+func (m *Manager) MarkSent(ctx context.Context, id string) (*Validation, error) {
+	v, err := m.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.transition(ctx, v, StateSent)
+}
+
+// MarkResent records that the validation's message is being sent again,
+// provided v, as read before deciding to resend, is still current. If the
+// validation changed since, for example because a concurrent resend was
+// recorded first, ErrConflict is returned, so each read of a validation
+// allows at most one resend.
+func (m *Manager) MarkResent(ctx context.Context, v *Validation) (*Validation, error) {
+	next, err := m.repo.RecordSend(ctx, v.ID, v.State, v.SendCount, m.clock.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to update validation: %w", err)
+	}
+
+	m.logger.Info("validation resend recorded",
+		"validation_id", v.ID,
+		"send_count", next.SendCount)
+
+	return next, nil
+}
+
+// VerifyLink completes the validation of a link token, consuming the token.
+func (m *Manager) VerifyLink(ctx context.Context, tokenValue string) (*Validation, error) {
+	tkn, err := m.tokens.VerifyAndConsume(ctx, tokenValue, token.TypeLink)
+	if err != nil {
+		return nil, fmt.Errorf("link verification failed: %w", err)
+	}
+
+	v, err := m.Get(ctx, tkn.ValidationID)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.complete(ctx, v)
+}
+
+// VerifyCode completes the validation with the given ID with a code entered
+// by the recipient. Once the token manager's attempt limit is reached, the
+// validation fails and token.ErrAttemptsExceeded is returned.
+func (m *Manager) VerifyCode(ctx context.Context, id, code string) (*Validation, error) {
+	v, err := m.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Resolved validations are rejected without spending an attempt.
+	if v.State.IsTerminal() {
+		return nil, fmt.Errorf("%w: validation is %s", ErrInvalidTransition, v.State)
+	}
+
+	if _, err := m.tokens.VerifyCodeToken(ctx, id, code); err != nil {
+		if errors.Is(err, token.ErrAttemptsExceeded) {
+			if _, tErr := m.transition(ctx, v, StateFailed); tErr != nil {
+				m.logger.Warn("failed to mark validation failed",
+					"validation_id", id,
+					"error", tErr)
+			}
+		}
+		return nil, fmt.Errorf("code verification failed: %w", err)
+	}
+
+	return m.complete(ctx, v)
+}
+
+// Cancel withdraws the validation and invalidates its tokens.
+func (m *Manager) Cancel(ctx context.Context, id string) (*Validation, error) {
+	v, err := m.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	canceled, err := m.transition(ctx, v, StateCanceled)
+	if err != nil {
+		return nil, err
+	}
+
+	m.invalidateTokens(ctx, id)
+
+	return canceled, nil
+}
+
+// complete moves v to StateVerified and invalidates its remaining tokens.
+func (m *Manager) complete(ctx context.Context, v *Validation) (*Validation, error) {
+	verified, err := m.transition(ctx, v, StateVerified)
+	if err != nil {
+		return nil, err
+	}
+
+	m.invalidateTokens(ctx, v.ID)
+
+	fire(ctx, m.hooks.OnVerified, verified)
+
+	return verified, nil
+}
+
+// transition moves v to state to, failing with ErrConflict if the stored
+// validation changed state since v was read.
+func (m *Manager) transition(ctx context.Context, v *Validation, to State) (*Validation, error) {
+	next, err := m.repo.UpdateState(ctx, v.ID, v.State, to, m.clock.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to update validation: %w", err)
+	}
+
+	m.logger.Info("validation state changed",
+		"validation_id", v.ID,
+		"from", v.State.String(),
+		"to", to.String())
+
+	return next, nil
+}
+
+// issue creates one token per method for the validation, bound to email.
+func (m *Manager) issue(ctx context.Context, id, email string, methods []token.Type, ttl time.Duration) ([]*token.Token, error) {
+	requests := make([]token.TokenRequest, len(methods))
+	for i, method := range methods {
+		requests[i] = token.TokenRequest{
+			Type:         method,
+			ValidationID: id,
+			Options:      token.CreateOptions{TTL: ttl, Email: email},
+		}
+	}
+
+	tokens, err := m.tokens.CreateTokens(ctx, requests)
+	if err != nil {
+		return nil, fmt.Errorf("failed to issue tokens: %w", err)
+	}
+
+	return tokens, nil
+}
+
+// invalidateTokens removes the validation's remaining tokens. Failures are
+// logged; the tokens expire with the validation anyway.
+func (m *Manager) invalidateTokens(ctx context.Context, id string) {
+	if err := m.tokens.InvalidateValidation(ctx, id); err != nil {
+		m.logger.Warn("failed to invalidate validation tokens",
+			"validation_id", id,
+			"error", err)
+	}
+}
+
+// exists reports whether a validation ID is in use.
+func (m *Manager) exists(ctx context.Context, id string) (bool, error) {
+	_, err := m.repo.Get(ctx, id)
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, ErrNotFound):
+		return false, nil
+	default:
+		return false, fmt.Errorf("failed to check validation ID: %w", err)
+	}
+}
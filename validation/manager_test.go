@@ -0,0 +1,312 @@
+package validation_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/check/syntax"
+	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+	"github.com/jaeyeom/email-validator-grpc-mcp/token/storage/memory"
+	"github.com/jaeyeom/email-validator-grpc-mcp/validation"
+	repomemory "github.com/jaeyeom/email-validator-grpc-mcp/validation/repository/memory"
+)
+
+// fakeClock is a settable clock.
+type fakeClock struct {
+	now atomic.Int64
+}
+
+func (c *fakeClock) Now() time.Time {
+	return time.Unix(0, c.now.Load())
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now.Add(int64(d))
+}
+
+func setup(t *testing.T, opts ...token.ManagerOption) (*validation.Manager, *fakeClock) {
+	t.Helper()
+
+	return setupWithHooks(t, validation.Hooks{}, opts...)
+}
+
+func setupWithHooks(t *testing.T, hooks validation.Hooks, opts ...token.ManagerOption) (*validation.Manager, *fakeClock) {
+	t.Helper()
+
+	clock := &fakeClock{}
+	clock.now.Store(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC).UnixNano())
+	logger := slog.New(slog.DiscardHandler)
+
+	tokens := token.NewManager(memory.New(memory.WithClock(clock), memory.WithLogger(logger)),
+		append([]token.ManagerOption{token.WithClock(clock), token.WithManagerLogger(logger)}, opts...)...)
+
+	return validation.NewManager(repomemory.New(), tokens, validation.WithClock(clock), validation.WithLogger(logger),
+		validation.WithHooks(hooks)), clock
+}
+
+func TestManager_VerifyLink(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m, clock := setup(t)
+
+	v, tokens, err := m.Start(ctx, validation.Request{Email: "user@example.com", Methods: []token.Type{token.TypeLink}, TTL: time.Hour})
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if v.State != validation.StatePending || v.Channel != validation.ChannelEmail || len(tokens) != 1 || tokens[0].ValidationID != v.ID {
+		t.Fatalf("Start() = %+v, %+v, want a pending email validation owning the token", v, tokens)
+	}
+	if want := clock.Now().Add(time.Hour); !v.ExpiresAt.Equal(want) {
+		t.Errorf("Start() ExpiresAt = %v, want %v", v.ExpiresAt, want)
+	}
+
+	clock.Advance(time.Second)
+	sent, err := m.MarkSent(ctx, v.ID)
+	if err != nil {
+		t.Fatalf("MarkSent() error = %v", err)
+	}
+	if sent.State != validation.StateSent || !sent.SentAt.Equal(clock.Now()) {
+		t.Errorf("MarkSent() = %+v, want sent now", sent)
+	}
+
+	clock.Advance(time.Second)
+	verified, err := m.VerifyLink(ctx, tokens[0].Value)
+	if err != nil {
+		t.Fatalf("VerifyLink() error = %v", err)
+	}
+	if verified.State != validation.StateVerified || !verified.ResolvedAt.Equal(clock.Now()) {
+		t.Errorf("VerifyLink() = %+v, want verified now", verified)
+	}
+
+	// The token was consumed, and a resolved validation cannot be canceled.
+	if _, err := m.VerifyLink(ctx, tokens[0].Value); !errors.Is(err, token.ErrTokenNotFound) {
+		t.Errorf("second VerifyLink() error = %v, want %v", err, token.ErrTokenNotFound)
+	}
+	if _, err := m.Cancel(ctx, v.ID); !errors.Is(err, validation.ErrInvalidTransition) {
+		t.Errorf("Cancel() after verification error = %v, want %v", err, validation.ErrInvalidTransition)
+	}
+}
+
+func TestManager_VerifyCode(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m, _ := setup(t, token.WithMaxCodeAttempts(2))
+
+	v, tokens, err := m.Start(ctx, validation.Request{Email: "user@example.com", Methods: []token.Type{token.TypeCode}})
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if _, err := m.VerifyCode(ctx, v.ID, "wrong"); !errors.Is(err, token.ErrTokenNotFound) {
+		t.Fatalf("VerifyCode() error = %v, want %v", err, token.ErrTokenNotFound)
+	}
+	verified, err := m.VerifyCode(ctx, v.ID, tokens[0].Value)
+	if err != nil {
+		t.Fatalf("VerifyCode() error = %v", err)
+	}
+	if verified.State != validation.StateVerified {
+		t.Errorf("VerifyCode() state = %v, want %v", verified.State, validation.StateVerified)
+	}
+
+	// Too many wrong codes fail the validation.
+	v, _, err = m.Start(ctx, validation.Request{Email: "other@example.com", Methods: []token.Type{token.TypeCode}})
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	for range 2 {
+		_, err = m.VerifyCode(ctx, v.ID, "wrong")
+	}
+	if !errors.Is(err, token.ErrAttemptsExceeded) {
+		t.Fatalf("VerifyCode() error = %v, want %v", err, token.ErrAttemptsExceeded)
+	}
+	if got, err := m.Get(ctx, v.ID); err != nil || got.State != validation.StateFailed {
+		t.Errorf("Get() = %+v, %v, want failed validation", got, err)
+	}
+	if _, err := m.VerifyCode(ctx, v.ID, "wrong"); !errors.Is(err, validation.ErrInvalidTransition) {
+		t.Errorf("VerifyCode() of failed validation error = %v, want %v", err, validation.ErrInvalidTransition)
+	}
+}
+
+func TestManager_LinkAndCode(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m, clock := setup(t)
+
+	v, tokens, err := m.Start(ctx, validation.Request{
+		Email:   "user@example.com",
+		Methods: []token.Type{token.TypeLink, token.TypeCode},
+		TTL:     time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if len(tokens) != 2 || tokens[0].Type != token.TypeLink || tokens[1].Type != token.TypeCode {
+		t.Fatalf("Start() tokens = %+v, want a link and a code token", tokens)
+	}
+	if len(v.Methods) != 2 {
+		t.Errorf("Start() Methods = %v, want both methods", v.Methods)
+	}
+	if want := clock.Now().Add(time.Hour); !v.ExpiresAt.Equal(want) {
+		t.Errorf("Start() ExpiresAt = %v, want %v", v.ExpiresAt, want)
+	}
+
+	// Completing the validation with the code invalidates the link.
+	if _, err := m.VerifyCode(ctx, v.ID, tokens[1].Value); err != nil {
+		t.Fatalf("VerifyCode() error = %v", err)
+	}
+	if _, err := m.VerifyLink(ctx, tokens[0].Value); !errors.Is(err, token.ErrTokenNotFound) {
+		t.Errorf("VerifyLink() after VerifyCode() error = %v, want %v", err, token.ErrTokenNotFound)
+	}
+}
+
+func TestManager_Reissue(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m, clock := setup(t)
+
+	v, tokens, err := m.Start(ctx, validation.Request{Email: "user@example.com", Methods: []token.Type{token.TypeLink, token.TypeCode}, TTL: time.Hour})
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	sent, err := m.MarkSent(ctx, v.ID)
+	if err != nil {
+		t.Fatalf("MarkSent() error = %v", err)
+	}
+
+	clock.Advance(10 * time.Minute)
+	resent, err := m.MarkResent(ctx, sent)
+	if err != nil {
+		t.Fatalf("MarkResent() error = %v", err)
+	}
+	if resent.SendCount != 2 || !resent.SentAt.Equal(clock.Now()) {
+		t.Errorf("MarkResent() = %+v, want sent twice, last now", resent)
+	}
+	if _, err := m.MarkResent(ctx, sent); !errors.Is(err, validation.ErrConflict) {
+		t.Errorf("MarkResent() of stale validation error = %v, want %v", err, validation.ErrConflict)
+	}
+
+	_, reissued, err := m.Reissue(ctx, v.ID)
+	if err != nil {
+		t.Fatalf("Reissue() error = %v", err)
+	}
+	if len(reissued) != 2 || reissued[0].Value != tokens[0].Value {
+		t.Fatalf("Reissue() tokens = %+v, want the link token %q reused", reissued, tokens[0].Value)
+	}
+	if reissued[1].Type != token.TypeCode || reissued[1].Value == tokens[1].Value || !reissued[1].ValidUntil.Equal(v.ExpiresAt) {
+		t.Fatalf("Reissue() code token = %+v, want a new code token valid until %v", reissued[1], v.ExpiresAt)
+	}
+	if _, err := m.VerifyCode(ctx, v.ID, tokens[1].Value); !errors.Is(err, token.ErrTokenNotFound) {
+		t.Errorf("VerifyCode() of replaced code error = %v, want %v", err, token.ErrTokenNotFound)
+	}
+
+	if _, err := m.VerifyLink(ctx, tokens[0].Value); err != nil {
+		t.Fatalf("VerifyLink() of the first link error = %v", err)
+	}
+	if _, _, err := m.Reissue(ctx, v.ID); !errors.Is(err, validation.ErrInvalidTransition) {
+		t.Errorf("Reissue() of verified validation error = %v, want %v", err, validation.ErrInvalidTransition)
+	}
+}
+
+func TestManager_ExpireAndCancel(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m, clock := setup(t)
+
+	expiring, tokens, err := m.Start(ctx, validation.Request{Email: "user@example.com", Methods: []token.Type{token.TypeLink}, TTL: time.Minute})
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	canceled, _, err := m.Start(ctx, validation.Request{Email: "user@example.com", Methods: []token.Type{token.TypeCode}, TTL: time.Hour})
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	clock.Advance(2 * time.Minute)
+	if got, err := m.Get(ctx, expiring.ID); err != nil || got.State != validation.StateExpired {
+		t.Errorf("Get() = %+v, %v, want expired validation", got, err)
+	}
+	if _, err := m.VerifyLink(ctx, tokens[0].Value); err == nil {
+		t.Error("VerifyLink() of expired validation succeeded")
+	}
+
+	got, err := m.Cancel(ctx, canceled.ID)
+	if err != nil {
+		t.Fatalf("Cancel() error = %v", err)
+	}
+	if got.State != validation.StateCanceled {
+		t.Errorf("Cancel() state = %v, want %v", got.State, validation.StateCanceled)
+	}
+	if _, err := m.MarkSent(ctx, canceled.ID); !errors.Is(err, validation.ErrInvalidTransition) {
+		t.Errorf("MarkSent() after Cancel() error = %v, want %v", err, validation.ErrInvalidTransition)
+	}
+
+	if _, err := m.Get(ctx, "val_missing"); !errors.Is(err, validation.ErrNotFound) {
+		t.Errorf("Get() of unknown ID error = %v, want %v", err, validation.ErrNotFound)
+	}
+	if _, _, err := m.Start(ctx, validation.Request{Methods: []token.Type{token.TypeLink}}); !errors.Is(err, validation.ErrEmptyEmail) {
+		t.Errorf("Start() without email error = %v, want %v", err, validation.ErrEmptyEmail)
+	}
+	if _, _, err := m.Start(ctx, validation.Request{Email: "user@localhost"}); !errors.Is(err, syntax.ErrInvalid) {
+		t.Errorf("Start() with invalid email error = %v, want %v", err, syntax.ErrInvalid)
+	}
+}
+
+func TestManager_Hooks(t *testing.T) {
+	t.Parallel()
+
+	var events []string
+	record := func(kind string) validation.HookFunc {
+		return func(_ context.Context, v *validation.Validation) {
+			events = append(events, kind+":"+v.State.String())
+		}
+	}
+	ctx := context.Background()
+	m, clock := setupWithHooks(t, validation.Hooks{
+		OnCreated:  record("created"),
+		OnVerified: record("verified"),
+		OnExpired:  record("expired"),
+	})
+
+	verified, tokens, err := m.Start(ctx, validation.Request{Email: "user@example.com", TTL: time.Hour})
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	expiring, _, err := m.Start(ctx, validation.Request{Email: "user@example.com", TTL: time.Minute})
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	canceled, _, err := m.Start(ctx, validation.Request{Email: "user@example.com", TTL: time.Hour})
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	if _, err := m.MarkSent(ctx, verified.ID); err != nil {
+		t.Fatalf("MarkSent() error = %v", err)
+	}
+	if _, err := m.VerifyLink(ctx, tokens[0].Value); err != nil {
+		t.Fatalf("VerifyLink() error = %v", err)
+	}
+	if _, err := m.Cancel(ctx, canceled.ID); err != nil {
+		t.Fatalf("Cancel() error = %v", err)
+	}
+	clock.Advance(2 * time.Minute)
+	for range 2 {
+		if _, err := m.Get(ctx, expiring.ID); err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+	}
+
+	want := []string{"created:PENDING", "created:PENDING", "created:PENDING", "verified:VERIFIED", "expired:EXPIRED"}
+	if !slices.Equal(events, want) {
+		t.Errorf("hooks fired %v, want %v", events, want)
+	}
+}
@@ -0,0 +1,150 @@
+// Package memory provides an in-memory implementation of the validation
+// repository, for tests and single-process deployments.
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/validation"
+)
+
+// Repository keeps validations in memory.
+type Repository struct {
+	mu          sync.RWMutex
+	validations map[string]validation.Validation
+
+	// byEmail indexes validation IDs by normalized email address.
+	byEmail map[string][]string
+}
+
+// New creates an empty in-memory repository.
+func New() *Repository {
+	return &Repository{
+		validations: make(map[string]validation.Validation),
+		byEmail:     make(map[string][]string),
+	}
+}
+
+// Create saves a new validation.
+func (r *Repository) Create(ctx context.Context, v *validation.Validation) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("context error: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.validations[v.ID]; ok {
+		return validation.ErrExists
+	}
+
+	r.validations[v.ID] = *v.Clone()
+
+	email := validation.NormalizeEmail(v.Email)
+	r.byEmail[email] = append(r.byEmail[email], v.ID)
+
+	return nil
+}
+
+// Get returns a copy of the validation with the given ID.
+func (r *Repository) Get(ctx context.Context, id string) (*validation.Validation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("context error: %w", err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	v, ok := r.validations[id]
+	if !ok {
+		return nil, validation.ErrNotFound
+	}
+
+	return v.Clone(), nil
+}
+
+// GetByEmail returns copies of the validations of an address, newest first.
+func (r *Repository) GetByEmail(ctx context.Context, email string) ([]*validation.Validation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("context error: %w", err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ids := r.byEmail[validation.NormalizeEmail(email)]
+	validations := make([]*validation.Validation, 0, len(ids))
+	for _, id := range ids {
+		v := r.validations[id]
+		validations = append(validations, v.Clone())
+	}
+
+	validation.SortNewestFirst(validations)
+
+	return validations, nil
+}
+
+// UpdateState moves a validation from one state to another.
+func (r *Repository) UpdateState(ctx context.Context, id string, from, to validation.State, at time.Time) (*validation.Validation, error) {
+	return r.update(ctx, id, from, to, at, func(*validation.Validation) bool { return true })
+}
+
+// RecordSend moves a validation to StateSent if it was sent sendCount times.
+func (r *Repository) RecordSend(ctx context.Context, id string, from validation.State, sendCount int, at time.Time) (*validation.Validation, error) {
+	return r.update(ctx, id, from, validation.StateSent, at, func(v *validation.Validation) bool {
+		return v.SendCount == sendCount
+	})
+}
+
+// update moves a validation from state from to state to if it also
+// satisfies match.
+func (r *Repository) update(ctx context.Context, id string, from, to validation.State, at time.Time, match func(*validation.Validation) bool) (*validation.Validation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("context error: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	v, ok := r.validations[id]
+	if !ok {
+		return nil, validation.ErrNotFound
+	}
+	if v.State != from || !match(&v) {
+		return nil, validation.ErrConflict
+	}
+
+	if err := v.Transition(to, at); err != nil {
+		return nil, fmt.Errorf("cannot update validation: %w", err)
+	}
+	r.validations[id] = v
+
+	return v.Clone(), nil
+}
+
+// List returns copies of the validations selected by filter, newest first.
+func (r *Repository) List(ctx context.Context, filter validation.Filter) ([]*validation.Validation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("context error: %w", err)
+	}
+
+	r.mu.RLock()
+	validations := []*validation.Validation{}
+	for _, v := range r.validations {
+		if filter.Matches(&v) {
+			validations = append(validations, v.Clone())
+		}
+	}
+	r.mu.RUnlock()
+
+	validation.SortNewestFirst(validations)
+
+	if filter.Limit > 0 && len(validations) > filter.Limit {
+		validations = validations[:filter.Limit]
+	}
+
+	return validations, nil
+}
@@ -0,0 +1,16 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/validation"
+	"github.com/jaeyeom/email-validator-grpc-mcp/validation/repositorytest"
+)
+
+func TestRepository_Conformance(t *testing.T) {
+	t.Parallel()
+
+	repositorytest.TestRepository(t, func() validation.Repository {
+		return New()
+	})
+}
@@ -0,0 +1,513 @@
+// Package redis provides a Redis-backed implementation of the validation
+// repository.
+//
+// Validations are kept as JSON records, with sorted sets indexing them by
+// email address, by state, and overall, scored by creation time. Creation
+// and state changes update a record and its indexes in one transaction.
+// Records expire a retention period after the validation does, and index
+// entries left behind by expired records are removed as they are read. So
+// that the indexes do not grow without bound, each Create also trims the
+// entries of validations created longer ago than the maximum lifetime plus
+// the retention period, and the email index of an address expires with its
+// last record.
+//
+// On Redis Cluster, the keys a transaction touches must share a slot: use a
+// key prefix that is a hash tag, such as "{validations}:".
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+	"github.com/jaeyeom/email-validator-grpc-mcp/validation"
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultRetention is the default time a validation record is kept after
+// the validation expires.
+const DefaultRetention = 30 * 24 * time.Hour
+
+// DefaultMaxLifetime is the default longest time a validation is expected
+// to stay open, from creation to expiry.
+const DefaultMaxLifetime = 7 * 24 * time.Hour
+
+// maxUpdateRetries bounds the optimistic transaction retries of Create and
+// UpdateState.
+const maxUpdateRetries = 3
+
+// listPageSize is the number of index entries List and GetByEmail read at
+// a time.
+const listPageSize = 100
+
+// Key prefixes, relative to the key prefix. They are distinct from the
+// token storage's, so both can share a namespace.
+const (
+	recordKeyPrefix = "validations:record:"
+	emailKeyPrefix  = "validations:email:"
+	stateKeyPrefix  = "validations:state:"
+	allKey          = "validations:all"
+)
+
+// states lists every state with an index.
+var states = []validation.State{
+	validation.StatePending,
+	validation.StateSent,
+	validation.StateVerified,
+	validation.StateFailed,
+	validation.StateExpired,
+	validation.StateCanceled,
+}
+
+// Repository provides a Redis-backed implementation of validation.Repository.
+type Repository struct {
+	client    redis.UniversalClient
+	logger    *slog.Logger
+	clock     token.Clock
+	prefix    string
+	retention time.Duration
+	lifetime  time.Duration
+}
+
+// Option is a functional option for configuring Repository.
+type Option func(*Repository)
+
+// WithLogger sets a custom logger for Repository.
+func WithLogger(logger *slog.Logger) Option {
+	return func(r *Repository) {
+		r.logger = logger
+	}
+}
+
+// WithClock sets the clock used to compute record TTLs.
+func WithClock(clock token.Clock) Option {
+	return func(r *Repository) {
+		r.clock = clock
+	}
+}
+
+// WithKeyPrefix namespaces every key written by Repository with prefix.
+func WithKeyPrefix(prefix string) Option {
+	return func(r *Repository) {
+		r.prefix = prefix
+	}
+}
+
+// WithRetention sets how long a validation record is kept after the
+// validation expires, for status checks and audits.
+func WithRetention(retention time.Duration) Option {
+	return func(r *Repository) {
+		if retention >= 0 {
+			r.retention = retention
+		}
+	}
+}
+
+// WithMaxLifetime sets the longest time a validation is expected to stay
+// open. Index entries of validations created longer ago than it plus the
+// retention period are trimmed, so a validation without an expiry, or
+// living longer, is then only found by ID.
+func WithMaxLifetime(lifetime time.Duration) Option {
+	return func(r *Repository) {
+		if lifetime > 0 {
+			r.lifetime = lifetime
+		}
+	}
+}
+
+// New creates a new Redis-backed validation repository.
+func New(client redis.UniversalClient, opts ...Option) *Repository {
+	r := &Repository{
+		client:    client,
+		logger:    slog.Default(),
+		clock:     token.SystemClock,
+		retention: DefaultRetention,
+		lifetime:  DefaultMaxLifetime,
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// record is the stored form of a validation.
+type record struct {
+	ID         string    `json:"id"`
+	Email      string    `json:"email"`
+	Channel    string    `json:"channel"`
+	Methods    []int     `json:"methods"`
+	State      int       `json:"state"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	SentAt     time.Time `json:"sent_at,omitzero"`
+	SendCount  int       `json:"send_count,omitempty"`
+	ResolvedAt time.Time `json:"resolved_at,omitzero"`
+}
+
+// encode serializes a validation.
+func encode(v *validation.Validation) ([]byte, error) {
+	methods := make([]int, len(v.Methods))
+	for i, method := range v.Methods {
+		methods[i] = int(method)
+	}
+
+	data, err := json.Marshal(record{
+		ID:         v.ID,
+		Email:      v.Email,
+		Channel:    string(v.Channel),
+		Methods:    methods,
+		State:      int(v.State),
+		CreatedAt:  v.CreatedAt,
+		UpdatedAt:  v.UpdatedAt,
+		ExpiresAt:  v.ExpiresAt,
+		SentAt:     v.SentAt,
+		SendCount:  v.SendCount,
+		ResolvedAt: v.ResolvedAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode validation: %w", err)
+	}
+
+	return data, nil
+}
+
+// decode deserializes a validation.
+func decode(data []byte) (*validation.Validation, error) {
+	var rec record
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, fmt.Errorf("failed to decode validation: %w", err)
+	}
+
+	methods := make([]token.Type, len(rec.Methods))
+	for i, method := range rec.Methods {
+		methods[i] = token.Type(method)
+	}
+
+	return &validation.Validation{
+		ID:         rec.ID,
+		Email:      rec.Email,
+		Channel:    validation.Channel(rec.Channel),
+		Methods:    methods,
+		State:      validation.State(rec.State),
+		CreatedAt:  rec.CreatedAt,
+		UpdatedAt:  rec.UpdatedAt,
+		ExpiresAt:  rec.ExpiresAt,
+		SentAt:     rec.SentAt,
+		SendCount:  rec.SendCount,
+		ResolvedAt: rec.ResolvedAt,
+	}, nil
+}
+
+// recordKey returns the key holding a validation record.
+func (r *Repository) recordKey(id string) string {
+	return r.prefix + recordKeyPrefix + id
+}
+
+// emailKey returns the key indexing the validations of an address.
+func (r *Repository) emailKey(email string) string {
+	return r.prefix + emailKeyPrefix + validation.NormalizeEmail(email)
+}
+
+// stateKey returns the key indexing the validations in a state.
+func (r *Repository) stateKey(state validation.State) string {
+	return r.prefix + stateKeyPrefix + state.String()
+}
+
+// ttl returns how long the record of v is kept, or zero to keep it for a
+// validation without an expiry.
+func (r *Repository) ttl(v *validation.Validation) time.Duration {
+	if v.ExpiresAt.IsZero() {
+		return 0
+	}
+
+	return max(v.ExpiresAt.Add(r.retention).Sub(r.clock.Now()), time.Second)
+}
+
+// trimScore returns the score below which index entries are trimmed,
+// formatted as an exclusive ZREMRANGEBYSCORE bound.
+func (r *Repository) trimScore() string {
+	cutoff := r.clock.Now().Add(-r.lifetime - r.retention)
+
+	return "(" + strconv.FormatInt(cutoff.UnixMicro(), 10)
+}
+
+// score orders index entries by creation time. Microseconds are exact in a
+// float64 score.
+func score(v *validation.Validation) float64 {
+	return float64(v.CreatedAt.UnixMicro())
+}
+
+// Create saves a new validation.
+func (r *Repository) Create(ctx context.Context, v *validation.Validation) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("context error: %w", err)
+	}
+
+	data, err := encode(v)
+	if err != nil {
+		return err
+	}
+
+	key := r.recordKey(v.ID)
+	emailKey := r.emailKey(v.Email)
+	member := redis.Z{Score: score(v), Member: v.ID}
+	ttl := r.ttl(v)
+	trim := r.trimScore()
+
+	txf := func(tx *redis.Tx) error {
+		n, err := tx.Exists(ctx, key).Result()
+		if err != nil {
+			return fmt.Errorf("failed to check validation: %w", err)
+		}
+		if n > 0 {
+			return validation.ErrExists
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, key, data, ttl)
+			pipe.ZAdd(ctx, emailKey, member)
+			pipe.ZAdd(ctx, r.stateKey(v.State), member)
+			pipe.ZAdd(ctx, r.prefix+allKey, member)
+
+			// The email index lives as long as its longest-lived record.
+			// EXPIRE GT treats a key without expiry as never expiring, so
+			// a new index gets its expiry from EXPIRE NX.
+			if ttl > 0 {
+				pipe.ExpireNX(ctx, emailKey, ttl)
+				pipe.ExpireGT(ctx, emailKey, ttl)
+			} else {
+				pipe.Persist(ctx, emailKey)
+			}
+
+			pipe.ZRemRangeByScore(ctx, emailKey, "-inf", trim)
+			pipe.ZRemRangeByScore(ctx, r.prefix+allKey, "-inf", trim)
+			for _, state := range states {
+				pipe.ZRemRangeByScore(ctx, r.stateKey(state), "-inf", trim)
+			}
+			return nil
+		})
+		return err
+	}
+
+	if err := r.watch(ctx, txf, key); err != nil {
+		return fmt.Errorf("failed to create validation: %w", err)
+	}
+
+	r.logger.Debug("validation created in Redis",
+		"validation_id", v.ID,
+		"state", v.State.String())
+
+	return nil
+}
+
+// Get returns the validation with the given ID.
+func (r *Repository) Get(ctx context.Context, id string) (*validation.Validation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("context error: %w", err)
+	}
+
+	data, err := r.client.Get(ctx, r.recordKey(id)).Bytes()
+	if err == redis.Nil {
+		return nil, validation.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get validation from Redis: %w", err)
+	}
+
+	return decode(data)
+}
+
+// GetByEmail returns the validations of an address, newest first.
+func (r *Repository) GetByEmail(ctx context.Context, email string) ([]*validation.Validation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("context error: %w", err)
+	}
+
+	return r.scan(ctx, r.emailKey(email), validation.Filter{})
+}
+
+// UpdateState moves a validation from one state to another.
+func (r *Repository) UpdateState(ctx context.Context, id string, from, to validation.State, at time.Time) (*validation.Validation, error) {
+	return r.update(ctx, id, from, to, at, func(*validation.Validation) bool { return true })
+}
+
+// RecordSend moves a validation to StateSent if it was sent sendCount times.
+func (r *Repository) RecordSend(ctx context.Context, id string, from validation.State, sendCount int, at time.Time) (*validation.Validation, error) {
+	return r.update(ctx, id, from, validation.StateSent, at, func(v *validation.Validation) bool {
+		return v.SendCount == sendCount
+	})
+}
+
+// update moves a validation from state from to state to if it also
+// satisfies match, in an optimistic transaction on its record.
+func (r *Repository) update(ctx context.Context, id string, from, to validation.State, at time.Time, match func(*validation.Validation) bool) (*validation.Validation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("context error: %w", err)
+	}
+
+	key := r.recordKey(id)
+
+	var updated *validation.Validation
+	txf := func(tx *redis.Tx) error {
+		data, err := tx.Get(ctx, key).Bytes()
+		if err == redis.Nil {
+			return validation.ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get validation: %w", err)
+		}
+
+		v, err := decode(data)
+		if err != nil {
+			return err
+		}
+		if v.State != from || !match(v) {
+			return validation.ErrConflict
+		}
+		if err := v.Transition(to, at); err != nil {
+			return fmt.Errorf("cannot update validation: %w", err)
+		}
+
+		data, err = encode(v)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.SetArgs(ctx, key, data, redis.SetArgs{KeepTTL: true})
+			pipe.ZRem(ctx, r.stateKey(from), id)
+			pipe.ZAdd(ctx, r.stateKey(to), redis.Z{Score: score(v), Member: id})
+			return nil
+		})
+		if err == nil {
+			updated = v
+		}
+		return err
+	}
+
+	if err := r.watch(ctx, txf, key); err != nil {
+		return nil, fmt.Errorf("failed to update validation state: %w", err)
+	}
+
+	r.logger.Debug("validation state updated in Redis",
+		"validation_id", id,
+		"from", from.String(),
+		"to", to.String())
+
+	return updated, nil
+}
+
+// List returns the validations selected by filter, newest first.
+func (r *Repository) List(ctx context.Context, filter validation.Filter) ([]*validation.Validation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("context error: %w", err)
+	}
+
+	key := r.prefix + allKey
+	if filter.State != 0 {
+		key = r.stateKey(filter.State)
+	}
+
+	return r.scan(ctx, key, filter)
+}
+
+// scan reads the validations indexed in key, newest first, keeping those
+// filter selects, up to its limit. Index entries whose record has expired
+// are removed.
+func (r *Repository) scan(ctx context.Context, key string, filter validation.Filter) ([]*validation.Validation, error) {
+	validations := []*validation.Validation{}
+
+	for start := int64(0); filter.Limit == 0 || len(validations) < filter.Limit; start += listPageSize {
+		ids, err := r.client.ZRevRange(ctx, key, start, start+listPageSize-1).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read validation index: %w", err)
+		}
+
+		page, stale, err := r.getMany(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, v := range page {
+			if filter.Matches(v) && (filter.Limit == 0 || len(validations) < filter.Limit) {
+				validations = append(validations, v)
+			}
+		}
+
+		if len(stale) > 0 {
+			if err := r.client.ZRem(ctx, key, stale...).Err(); err != nil {
+				r.logger.Warn("failed to remove expired validations from index", "error", err)
+			} else {
+				// The removed entries shifted later ones down.
+				start -= int64(len(stale))
+			}
+		}
+
+		if len(ids) < listPageSize {
+			break
+		}
+	}
+
+	validation.SortNewestFirst(validations)
+
+	return validations, nil
+}
+
+// getMany reads the records of ids with pipelined GETs and returns them in
+// order, along with the IDs whose record no longer exists.
+func (r *Repository) getMany(ctx context.Context, ids []string) ([]*validation.Validation, []any, error) {
+	cmds := make([]*redis.StringCmd, len(ids))
+	_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, id := range ids {
+			cmds[i] = pipe.Get(ctx, r.recordKey(id))
+		}
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		return nil, nil, fmt.Errorf("failed to read validations: %w", err)
+	}
+
+	validations := make([]*validation.Validation, 0, len(ids))
+	var stale []any
+	for i, cmd := range cmds {
+		data, err := cmd.Bytes()
+		switch {
+		case err == redis.Nil:
+			stale = append(stale, ids[i])
+		case err != nil:
+			return nil, nil, fmt.Errorf("failed to read validation: %w", err)
+		default:
+			v, err := decode(data)
+			if err != nil {
+				r.logger.Warn("skipping unreadable validation record",
+					"validation_id", ids[i],
+					"error", err)
+				continue
+			}
+			validations = append(validations, v)
+		}
+	}
+
+	return validations, stale, nil
+}
+
+// watch runs txf in an optimistic transaction on key, retrying when the key
+// changes concurrently.
+func (r *Repository) watch(ctx context.Context, txf func(*redis.Tx) error, key string) error {
+	for range maxUpdateRetries {
+		err := r.client.Watch(ctx, txf, key)
+		if !errors.Is(err, redis.TxFailedErr) {
+			return err
+		}
+	}
+
+	return redis.TxFailedErr
+}
@@ -0,0 +1,132 @@
+package redis
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+	"github.com/jaeyeom/email-validator-grpc-mcp/validation"
+	"github.com/jaeyeom/email-validator-grpc-mcp/validation/repositorytest"
+	"github.com/redis/go-redis/v9"
+)
+
+func setupMiniRedis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	t.Helper()
+
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("Failed to start miniredis: %v", err)
+	}
+	t.Cleanup(mr.Close)
+
+	client := redis.NewClient(&redis.Options{
+		Addr: mr.Addr(),
+	})
+	t.Cleanup(func() { _ = client.Close() })
+
+	return mr, client
+}
+
+func TestRepository_Conformance(t *testing.T) {
+	t.Parallel()
+
+	repositorytest.TestRepository(t, func() validation.Repository {
+		_, client := setupMiniRedis(t)
+		return New(client, WithKeyPrefix("ev:"), WithLogger(slog.New(slog.DiscardHandler)))
+	})
+}
+
+func TestRepository_Retention(t *testing.T) {
+	t.Parallel()
+
+	mr, client := setupMiniRedis(t)
+	ctx := context.Background()
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	repo := New(client,
+		WithClock(token.ClockFunc(func() time.Time { return now })),
+		WithRetention(24*time.Hour),
+		WithLogger(slog.New(slog.DiscardHandler)))
+
+	for i, email := range []string{"kept@example.com", "expired@example.com"} {
+		v := &validation.Validation{
+			ID:        []string{"val_kept", "val_expired"}[i],
+			Email:     email,
+			State:     validation.StatePending,
+			CreatedAt: now.Add(time.Duration(i) * time.Second),
+			ExpiresAt: now.Add(time.Duration(i+1) * time.Hour),
+		}
+		if err := repo.Create(ctx, v); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	if ttl := mr.TTL("validations:record:val_kept"); ttl != 25*time.Hour {
+		t.Errorf("record TTL = %v, want %v", ttl, 25*time.Hour)
+	}
+
+	// An expired record's index entries are dropped as they are read.
+	mr.Del("validations:record:val_expired")
+	got, err := repo.List(ctx, validation.Filter{})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(got) != 1 || got[0].ID != "val_kept" {
+		t.Errorf("List() = %v, want only val_kept", got)
+	}
+	if members, _ := mr.ZMembers("validations:all"); len(members) != 1 {
+		t.Errorf("index members = %v, want only val_kept", members)
+	}
+}
+
+func TestRepository_IndexTrim(t *testing.T) {
+	t.Parallel()
+
+	mr, client := setupMiniRedis(t)
+	ctx := context.Background()
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	repo := New(client,
+		WithClock(token.ClockFunc(func() time.Time { return now })),
+		WithRetention(24*time.Hour),
+		WithMaxLifetime(time.Hour),
+		WithLogger(slog.New(slog.DiscardHandler)))
+
+	create := func(id string, ttl time.Duration) {
+		t.Helper()
+		v := &validation.Validation{
+			ID:        id,
+			Email:     "user@example.com",
+			State:     validation.StatePending,
+			CreatedAt: now,
+			ExpiresAt: now.Add(ttl),
+		}
+		if err := repo.Create(ctx, v); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	create("val_old", time.Hour)
+	if ttl := mr.TTL("validations:email:user@example.com"); ttl != 25*time.Hour {
+		t.Errorf("email index TTL = %v, want %v", ttl, 25*time.Hour)
+	}
+
+	// A shorter-lived validation does not shorten the email index expiry
+	create("val_short", time.Minute)
+	if ttl := mr.TTL("validations:email:user@example.com"); ttl != 25*time.Hour {
+		t.Errorf("email index TTL after shorter validation = %v, want %v", ttl, 25*time.Hour)
+	}
+
+	// Past the lifetime and retention, old entries are trimmed, even those
+	// whose record has no expiry
+	client.Persist(ctx, "validations:record:val_old")
+	now = now.Add(26 * time.Hour)
+	create("val_new", time.Hour)
+
+	for _, key := range []string{"validations:all", "validations:state:PENDING", "validations:email:user@example.com"} {
+		if members, _ := mr.ZMembers(key); len(members) != 1 || members[0] != "val_new" {
+			t.Errorf("%s members = %v, want only val_new", key, members)
+		}
+	}
+}
@@ -0,0 +1,384 @@
+// Package repositorytest provides a conformance suite for
+// validation.Repository implementations, so every backend is held to the
+// same contract.
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+	"github.com/jaeyeom/email-validator-grpc-mcp/validation"
+)
+
+// TestRepository runs the conformance suite against repositories returned
+// by newRepository, which is called once per subtest. Subtests run in
+// parallel, and each uses its own IDs and addresses, so newRepository may
+// return repositories sharing one backend; List results are only checked
+// for the validations a subtest created.
+func TestRepository(t *testing.T, newRepository func() validation.Repository) {
+	t.Helper()
+
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, r validation.Repository)
+	}{
+		{"CreateGet", testCreateGet},
+		{"CreateExists", testCreateExists},
+		{"GetNotFound", testGetNotFound},
+		{"GetByEmail", testGetByEmail},
+		{"UpdateState", testUpdateState},
+		{"UpdateStateConflict", testUpdateStateConflict},
+		{"ConcurrentUpdateState", testConcurrentUpdateState},
+		{"RecordSend", testRecordSend},
+		{"List", testList},
+		{"CanceledContext", testCanceledContext},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			tt.fn(t, newRepository())
+		})
+	}
+}
+
+// seq makes IDs and addresses unique across subtests.
+var seq atomic.Int64
+
+// unique returns name with a suffix unique to this process.
+func unique(name string) string {
+	return fmt.Sprintf("repositorytest-%s-%d", name, seq.Add(1))
+}
+
+// base is the creation time of the first validation of a subtest. Times are
+// truncated to microseconds, the precision backends must preserve.
+var base = time.Now().Truncate(time.Microsecond)
+
+// newValidation returns an unsaved pending validation for email, created
+// offset after base.
+func newValidation(email string, offset time.Duration) *validation.Validation {
+	created := base.Add(offset)
+
+	return &validation.Validation{
+		ID:        unique("val"),
+		Email:     email,
+		Channel:   validation.ChannelEmail,
+		Methods:   []token.Type{token.TypeLink, token.TypeCode},
+		State:     validation.StatePending,
+		CreatedAt: created,
+		UpdatedAt: created,
+		ExpiresAt: created.Add(time.Hour),
+	}
+}
+
+// mustCreate creates validations, failing the test on error.
+func mustCreate(t *testing.T, r validation.Repository, validations ...*validation.Validation) {
+	t.Helper()
+
+	for _, v := range validations {
+		if err := r.Create(context.Background(), v); err != nil {
+			t.Fatalf("Create(%q) error = %v", v.ID, err)
+		}
+	}
+}
+
+// checkSame fails the test unless got is want.
+func checkSame(t *testing.T, what string, got, want *validation.Validation) {
+	t.Helper()
+
+	if got.ID != want.ID || got.Email != want.Email || got.Channel != want.Channel ||
+		!slices.Equal(got.Methods, want.Methods) || got.State != want.State ||
+		!got.CreatedAt.Equal(want.CreatedAt) || !got.UpdatedAt.Equal(want.UpdatedAt) ||
+		!got.ExpiresAt.Equal(want.ExpiresAt) || !got.SentAt.Equal(want.SentAt) ||
+		!got.ResolvedAt.Equal(want.ResolvedAt) || got.SendCount != want.SendCount {
+		t.Errorf("%s = %+v, want %+v", what, got, want)
+	}
+}
+
+// ids returns the IDs of validations.
+func ids(validations []*validation.Validation) []string {
+	out := make([]string, len(validations))
+	for i, v := range validations {
+		out[i] = v.ID
+	}
+
+	return out
+}
+
+// checkIDs fails the test unless validations have exactly the IDs of want,
+// in order.
+func checkIDs(t *testing.T, what string, validations []*validation.Validation, want ...*validation.Validation) {
+	t.Helper()
+
+	got := ids(validations)
+	wantIDs := ids(want)
+	if fmt.Sprint(got) != fmt.Sprint(wantIDs) {
+		t.Errorf("%s = %v, want %v", what, got, wantIDs)
+	}
+}
+
+func testCreateGet(t *testing.T, r validation.Repository) {
+	v := newValidation(unique("user")+"@example.com", 0)
+	mustCreate(t, r, v)
+
+	got, err := r.Get(context.Background(), v.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	checkSame(t, "Get()", got, v)
+
+	// The repository does not share memory with callers.
+	got.State = validation.StateCanceled
+	if again, _ := r.Get(context.Background(), v.ID); again.State != validation.StatePending {
+		t.Errorf("Get() after modifying a returned validation state = %v, want %v", again.State, validation.StatePending)
+	}
+}
+
+func testCreateExists(t *testing.T, r validation.Repository) {
+	v := newValidation(unique("user")+"@example.com", 0)
+	mustCreate(t, r, v)
+
+	if err := r.Create(context.Background(), v); !errors.Is(err, validation.ErrExists) {
+		t.Errorf("second Create() error = %v, want %v", err, validation.ErrExists)
+	}
+}
+
+func testGetNotFound(t *testing.T, r validation.Repository) {
+	if _, err := r.Get(context.Background(), unique("missing")); !errors.Is(err, validation.ErrNotFound) {
+		t.Errorf("Get() error = %v, want %v", err, validation.ErrNotFound)
+	}
+	if _, err := r.UpdateState(context.Background(), unique("missing"), validation.StatePending, validation.StateSent, base); !errors.Is(err, validation.ErrNotFound) {
+		t.Errorf("UpdateState() error = %v, want %v", err, validation.ErrNotFound)
+	}
+}
+
+func testGetByEmail(t *testing.T, r validation.Repository) {
+	ctx := context.Background()
+	email := unique("user") + "@example.com"
+	older := newValidation(email, 0)
+	newer := newValidation("  "+email+" ", time.Second)
+	other := newValidation(unique("other")+"@example.com", 2*time.Second)
+	mustCreate(t, r, older, newer, other)
+
+	got, err := r.GetByEmail(ctx, strings.ToUpper(email))
+	if err != nil {
+		t.Fatalf("GetByEmail() error = %v", err)
+	}
+	checkIDs(t, "GetByEmail()", got, newer, older)
+
+	// Equivalent addresses of a provider folding them share validations.
+	local := unique("user")
+	tagged := newValidation(local+"+signup@googlemail.com", 0)
+	mustCreate(t, r, tagged)
+	got, err = r.GetByEmail(ctx, strings.ToUpper(local)+"@gmail.com")
+	if err != nil {
+		t.Fatalf("GetByEmail() of equivalent address error = %v", err)
+	}
+	checkIDs(t, "GetByEmail() of equivalent address", got, tagged)
+
+	got, err = r.GetByEmail(ctx, unique("nobody")+"@example.com")
+	if err != nil {
+		t.Fatalf("GetByEmail() of unknown address error = %v", err)
+	}
+	if got == nil || len(got) != 0 {
+		t.Errorf("GetByEmail() of unknown address = %v, want empty slice", got)
+	}
+}
+
+func testUpdateState(t *testing.T, r validation.Repository) {
+	ctx := context.Background()
+	v := newValidation(unique("user")+"@example.com", 0)
+	mustCreate(t, r, v)
+
+	sentAt := base.Add(time.Minute)
+	sent, err := r.UpdateState(ctx, v.ID, validation.StatePending, validation.StateSent, sentAt)
+	if err != nil {
+		t.Fatalf("UpdateState() error = %v", err)
+	}
+	want := *v
+	if err := want.Transition(validation.StateSent, sentAt); err != nil {
+		t.Fatalf("Transition() error = %v", err)
+	}
+	checkSame(t, "UpdateState()", sent, &want)
+
+	got, err := r.Get(ctx, v.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	checkSame(t, "Get() after UpdateState()", got, &want)
+
+	// Resending moves a sent validation to StateSent again.
+	resentAt := sentAt.Add(time.Minute)
+	resent, err := r.UpdateState(ctx, v.ID, validation.StateSent, validation.StateSent, resentAt)
+	if err != nil {
+		t.Fatalf("UpdateState() to resend error = %v", err)
+	}
+	if err := want.Transition(validation.StateSent, resentAt); err != nil {
+		t.Fatalf("Transition() error = %v", err)
+	}
+	checkSame(t, "UpdateState() to resend", resent, &want)
+
+	if _, err := r.UpdateState(ctx, v.ID, validation.StateSent, validation.StatePending, sentAt); !errors.Is(err, validation.ErrInvalidTransition) {
+		t.Errorf("UpdateState() back to pending error = %v, want %v", err, validation.ErrInvalidTransition)
+	}
+}
+
+func testUpdateStateConflict(t *testing.T, r validation.Repository) {
+	ctx := context.Background()
+	v := newValidation(unique("user")+"@example.com", 0)
+	mustCreate(t, r, v)
+
+	if _, err := r.UpdateState(ctx, v.ID, validation.StatePending, validation.StateCanceled, base); err != nil {
+		t.Fatalf("UpdateState() error = %v", err)
+	}
+	if _, err := r.UpdateState(ctx, v.ID, validation.StatePending, validation.StateVerified, base); !errors.Is(err, validation.ErrConflict) {
+		t.Errorf("stale UpdateState() error = %v, want %v", err, validation.ErrConflict)
+	}
+}
+
+func testConcurrentUpdateState(t *testing.T, r validation.Repository) {
+	ctx := context.Background()
+	v := newValidation(unique("user")+"@example.com", 0)
+	mustCreate(t, r, v)
+
+	const workers = 10
+	var wg sync.WaitGroup
+	var succeeded atomic.Int32
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := r.UpdateState(ctx, v.ID, validation.StatePending, validation.StateVerified, base); err == nil {
+				succeeded.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := succeeded.Load(); got != 1 {
+		t.Errorf("%d concurrent UpdateState() calls succeeded, want 1", got)
+	}
+}
+
+func testRecordSend(t *testing.T, r validation.Repository) {
+	ctx := context.Background()
+	v := newValidation(unique("user")+"@example.com", 0)
+	mustCreate(t, r, v)
+
+	sentAt := base.Add(time.Minute)
+	sent, err := r.RecordSend(ctx, v.ID, validation.StatePending, 0, sentAt)
+	if err != nil {
+		t.Fatalf("RecordSend() error = %v", err)
+	}
+	want := *v
+	if err := want.Transition(validation.StateSent, sentAt); err != nil {
+		t.Fatalf("Transition() error = %v", err)
+	}
+	checkSame(t, "RecordSend()", sent, &want)
+
+	if _, err := r.RecordSend(ctx, v.ID, validation.StateSent, 0, sentAt); !errors.Is(err, validation.ErrConflict) {
+		t.Errorf("RecordSend() with stale send count error = %v, want %v", err, validation.ErrConflict)
+	}
+
+	// Of concurrent resends of the same read, only one is recorded.
+	const workers = 10
+	var wg sync.WaitGroup
+	var succeeded atomic.Int32
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := r.RecordSend(ctx, v.ID, validation.StateSent, 1, sentAt.Add(time.Minute)); err == nil {
+				succeeded.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := succeeded.Load(); got != 1 {
+		t.Errorf("%d concurrent RecordSend() calls succeeded, want 1", got)
+	}
+	if got, err := r.Get(ctx, v.ID); err != nil || got.SendCount != 2 {
+		t.Errorf("Get() after RecordSend() = %+v, %v, want sent twice", got, err)
+	}
+}
+
+func testList(t *testing.T, r validation.Repository) {
+	ctx := context.Background()
+	pending := newValidation(unique("user")+"@example.com", 0)
+	sent := newValidation(unique("user")+"@example.com", time.Second)
+	verified := newValidation(unique("user")+"@example.com", 2*time.Second)
+	mustCreate(t, r, pending, sent, verified)
+
+	if _, err := r.UpdateState(ctx, sent.ID, validation.StatePending, validation.StateSent, base); err != nil {
+		t.Fatalf("UpdateState() error = %v", err)
+	}
+	if _, err := r.UpdateState(ctx, verified.ID, validation.StatePending, validation.StateVerified, base); err != nil {
+		t.Fatalf("UpdateState() error = %v", err)
+	}
+
+	got, err := r.List(ctx, validation.Filter{State: validation.StateSent})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if !contains(got, sent) || contains(got, pending) || contains(got, verified) {
+		t.Errorf("List(sent) = %v, want %s and no other of this test", ids(got), sent.ID)
+	}
+
+	got, err = r.List(ctx, validation.Filter{})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	var mine []*validation.Validation
+	for _, v := range got {
+		if contains([]*validation.Validation{pending, sent, verified}, v) {
+			mine = append(mine, v)
+		}
+	}
+	checkIDs(t, "List() of this test's validations", mine, verified, sent, pending)
+
+	if got, err := r.List(ctx, validation.Filter{Limit: 1}); err != nil || len(got) != 1 {
+		t.Errorf("List(limit 1) = %v, %v, want 1 validation", ids(got), err)
+	}
+}
+
+// contains reports whether validations includes one with the ID of v.
+func contains(validations []*validation.Validation, v *validation.Validation) bool {
+	for _, got := range validations {
+		if got.ID == v.ID {
+			return true
+		}
+	}
+
+	return false
+}
+
+func testCanceledContext(t *testing.T, r validation.Repository) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	v := newValidation(unique("user")+"@example.com", 0)
+	if err := r.Create(ctx, v); !errors.Is(err, context.Canceled) {
+		t.Errorf("Create() error = %v, want %v", err, context.Canceled)
+	}
+	if _, err := r.Get(ctx, v.ID); !errors.Is(err, context.Canceled) {
+		t.Errorf("Get() error = %v, want %v", err, context.Canceled)
+	}
+	if _, err := r.GetByEmail(ctx, v.Email); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetByEmail() error = %v, want %v", err, context.Canceled)
+	}
+	if _, err := r.UpdateState(ctx, v.ID, validation.StatePending, validation.StateSent, base); !errors.Is(err, context.Canceled) {
+		t.Errorf("UpdateState() error = %v, want %v", err, context.Canceled)
+	}
+	if _, err := r.List(ctx, validation.Filter{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("List() error = %v, want %v", err, context.Canceled)
+	}
+}
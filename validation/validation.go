@@ -0,0 +1,242 @@
+// Package validation models email validations: the record of an address
+// being verified and the lifecycle it goes through, from the request until
+// the recipient verifies it, it expires, or it is canceled. Tokens are the
+// proof of possession a validation is completed with; a Manager issues
+// them through a token.Manager and drives the validation's state.
+package validation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/emailaddr"
+	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+)
+
+var (
+	// ErrNotFound is returned when no validation has the requested ID.
+	ErrNotFound = errors.New("validation not found")
+
+	// ErrExists is returned by Repository.Create when the ID is already in
+	// use.
+	ErrExists = errors.New("validation already exists")
+
+	// ErrConflict is returned by Repository.UpdateState when the validation
+	// changed state since it was read.
+	ErrConflict = errors.New("validation was modified concurrently")
+
+	// ErrInvalidTransition is returned when a validation cannot move to the
+	// requested state, such as verifying a canceled validation.
+	ErrInvalidTransition = errors.New("invalid validation state transition")
+
+	// ErrEmptyEmail is returned when a validation is requested without an
+	// email address.
+	ErrEmptyEmail = errors.New("email address cannot be empty")
+)
+
+// State is the lifecycle state of a validation.
+type State int
+
+// Validation states. A validation starts out pending, becomes sent once its
+// message has been handed to the delivery channel, possibly more than once,
+// and ends verified, failed, expired, or canceled.
+const (
+	// StatePending is a validation whose message has not been sent yet.
+	StatePending State = iota + 1
+	// StateSent is a validation whose message has been sent.
+	StateSent
+	// StateVerified is a validation the recipient completed.
+	StateVerified
+	// StateFailed is a validation that can no longer be completed because
+	// too many wrong codes were entered.
+	StateFailed
+	// StateExpired is a validation that was not completed in time.
+	StateExpired
+	// StateCanceled is a validation withdrawn by the requester.
+	StateCanceled
+)
+
+// String returns the name of the state, as used in logs and by the
+// repositories to key their state indexes.
+func (s State) String() string {
+	switch s {
+	case StatePending:
+		return "PENDING"
+	case StateSent:
+		return "SENT"
+	case StateVerified:
+		return "VERIFIED"
+	case StateFailed:
+		return "FAILED"
+	case StateExpired:
+		return "EXPIRED"
+	case StateCanceled:
+		return "CANCELED"
+	default:
+		return fmt.Sprintf("State(%d)", int(s))
+	}
+}
+
+// IsTerminal reports whether s is a final state, which no transition leaves.
+func (s State) IsTerminal() bool {
+	switch s {
+	case StateVerified, StateFailed, StateExpired, StateCanceled:
+		return true
+	default:
+		return false
+	}
+}
+
+// CanTransitionTo reports whether a validation in state s may move to next.
+// A pending validation may skip StateSent, since a recipient may act on a
+// message before its delivery is recorded, and a sent validation may move to
+// StateSent again when its message is resent.
+func (s State) CanTransitionTo(next State) bool {
+	switch s {
+	case StatePending:
+		return next != StatePending && next.valid()
+	case StateSent:
+		return next == StateSent || next.IsTerminal()
+	default:
+		return false
+	}
+}
+
+// valid reports whether s is one of the defined states.
+func (s State) valid() bool {
+	return s >= StatePending && s <= StateCanceled
+}
+
+// Channel is the medium a validation message is delivered through.
+type Channel string
+
+// ChannelEmail delivers validation messages by email.
+const ChannelEmail Channel = "email"
+
+// Validation is the record of one attempt to verify an email address.
+type Validation struct {
+	ID      string
+	Email   string
+	Channel Channel
+
+	// Methods are the types of the tokens issued for the validation; the
+	// recipient may complete it with any of them.
+	Methods []token.Type
+
+	State State
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	ExpiresAt time.Time
+
+	// SentAt is when the message was last sent, and ResolvedAt when the
+	// validation reached a terminal state. Both are zero until then.
+	SentAt     time.Time
+	ResolvedAt time.Time
+
+	// SendCount is the number of times the message was sent.
+	SendCount int
+}
+
+// Clone returns a copy of v that shares no memory with it.
+func (v *Validation) Clone() *Validation {
+	c := *v
+	c.Methods = slices.Clone(v.Methods)
+
+	return &c
+}
+
+// Transition moves v to state to at the given time, updating its
+// timestamps, or returns ErrInvalidTransition. Repositories use it to apply
+// UpdateState.
+func (v *Validation) Transition(to State, at time.Time) error {
+	if !v.State.CanTransitionTo(to) {
+		return fmt.Errorf("%w: %s to %s", ErrInvalidTransition, v.State, to)
+	}
+
+	v.State = to
+	v.UpdatedAt = at
+	if to == StateSent {
+		v.SentAt = at
+		v.SendCount++
+	}
+	if to.IsTerminal() {
+		v.ResolvedAt = at
+	}
+
+	return nil
+}
+
+// NormalizeEmail canonicalizes an address for lookups with
+// emailaddr.Normalize, so GetByEmail finds the validations of equivalent
+// addresses, such as those differing in case or by a Gmail "+tag". A string
+// that is not an address is only trimmed and lower-cased.
+func NormalizeEmail(email string) string {
+	if normalized, err := emailaddr.Normalize(email); err == nil {
+		return normalized
+	}
+
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// Filter selects validations to List.
+type Filter struct {
+	// State selects validations in that state; zero selects all.
+	State State
+
+	// Limit caps the number of validations returned; zero means no limit.
+	Limit int
+}
+
+// Matches reports whether v is selected by the filter's State.
+func (f Filter) Matches(v *Validation) bool {
+	return f.State == 0 || v.State == f.State
+}
+
+// SortNewestFirst orders validations by descending creation time, and by
+// descending ID among validations created at the same time. It is exported
+// for use by repository implementations.
+func SortNewestFirst(validations []*Validation) {
+	slices.SortFunc(validations, func(a, b *Validation) int {
+		if c := b.CreatedAt.Compare(a.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(b.ID, a.ID)
+	})
+}
+
+// Repository persists validations. Implementations must be safe for
+// concurrent use and must not retain or modify the validations passed to or
+// returned from them.
+type Repository interface {
+	// Create saves a new validation, or returns ErrExists if its ID is
+	// already in use.
+	Create(ctx context.Context, v *Validation) error
+
+	// Get returns the validation with the given ID, or ErrNotFound.
+	Get(ctx context.Context, id string) (*Validation, error)
+
+	// GetByEmail returns the validations of an address, compared after
+	// NormalizeEmail, newest first. It returns an empty slice when there
+	// are none.
+	GetByEmail(ctx context.Context, email string) ([]*Validation, error)
+
+	// UpdateState atomically moves the validation from state from to state
+	// to with Validation.Transition and returns the updated validation. It
+	// returns ErrConflict if the validation is no longer in state from, so
+	// concurrent transitions of one validation cannot both succeed.
+	UpdateState(ctx context.Context, id string, from, to State, at time.Time) (*Validation, error)
+
+	// RecordSend is like UpdateState with to StateSent, but also returns
+	// ErrConflict unless the validation was sent exactly sendCount times,
+	// so of concurrent resends of one validation read with the same
+	// SendCount only one is recorded.
+	RecordSend(ctx context.Context, id string, from State, sendCount int, at time.Time) (*Validation, error)
+
+	// List returns the validations selected by filter, newest first.
+	List(ctx context.Context, filter Filter) ([]*Validation, error)
+}
@@ -0,0 +1,36 @@
+package validation
+
+import "testing"
+
+func TestState_CanTransitionTo(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		from, to State
+		want     bool
+	}{
+		{StatePending, StateSent, true},
+		{StatePending, StateVerified, true},
+		{StatePending, StateCanceled, true},
+		{StatePending, StatePending, false},
+		{StatePending, State(0), false},
+		{StateSent, StateVerified, true},
+		{StateSent, StateFailed, true},
+		{StateSent, StateExpired, true},
+		{StateSent, StateSent, true},
+		{StateSent, StatePending, false},
+		{StateVerified, StateCanceled, false},
+		{StateExpired, StateVerified, false},
+		{StateCanceled, StateSent, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.from.String()+"->"+tt.to.String(), func(t *testing.T) {
+			t.Parallel()
+
+			if got := tt.from.CanTransitionTo(tt.to); got != tt.want {
+				t.Errorf("%v.CanTransitionTo(%v) = %v, want %v", tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
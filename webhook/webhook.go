@@ -0,0 +1,390 @@
+// Package webhook notifies HTTP endpoints of validation state changes. A
+// Notifier turns validation Manager hooks into JSON events,
+// validation.created, validation.verified, and validation.expired, and POSTs
+// each to the endpoints subscribed to it, signed with the endpoint's secret
+// and retried with exponential backoff.
+//
+// Deliveries block until they succeed or run out of attempts, so the hooks
+// are best run in the background with a dispatch.Dispatcher:
+//
+//	n := webhook.New(endpoints)
+//	d := dispatch.New(token.Hooks{})
+//	m := validation.NewManager(repo, tokens, validation.WithHooks(d.ValidationHooks(n.Hooks())))
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/idgen"
+	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+	"github.com/jaeyeom/email-validator-grpc-mcp/validation"
+)
+
+// Default delivery settings.
+const (
+	// DefaultMaxAttempts is the default number of delivery attempts per
+	// endpoint, including the first.
+	DefaultMaxAttempts = 5
+
+	// DefaultInitialBackoff is the default delay before the first retry;
+	// it doubles after each retry.
+	DefaultInitialBackoff = 500 * time.Millisecond
+
+	// DefaultMaxBackoff is the default cap on the delay between attempts.
+	DefaultMaxBackoff = 30 * time.Second
+
+	// DefaultTimeout is the default time allowed for each attempt.
+	DefaultTimeout = 10 * time.Second
+)
+
+// Request headers of deliveries.
+const (
+	HeaderEventID   = "Webhook-Id"
+	HeaderEventType = "Webhook-Event"
+	HeaderSignature = "Webhook-Signature"
+)
+
+var (
+	// ErrDelivery is returned when an endpoint rejects a delivery or does
+	// not accept it within the allowed attempts.
+	ErrDelivery = errors.New("webhook delivery failed")
+
+	// ErrInvalidSignature is returned by Verify when a signature does not
+	// match the payload.
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+)
+
+// EventType names a validation state change.
+type EventType string
+
+// Event types.
+const (
+	// EventCreated is sent when a validation is started.
+	EventCreated EventType = "validation.created"
+	// EventVerified is sent when the recipient completes a validation.
+	EventVerified EventType = "validation.verified"
+	// EventExpired is sent when a validation is found past its expiry; see
+	// validation.Hooks.OnExpired.
+	EventExpired EventType = "validation.expired"
+)
+
+// Event is the JSON body of a delivery. It never carries token values or
+// email addresses.
+type Event struct {
+	// ID identifies the event; receivers can use it to discard
+	// redelivered events.
+	ID        string    `json:"id"`
+	Type      EventType `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      EventData `json:"data"`
+}
+
+// EventData describes the validation an event is about, as the state change
+// left it.
+type EventData struct {
+	ValidationID string    `json:"validation_id"`
+	State        string    `json:"state"`
+	Channel      string    `json:"channel,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at,omitzero"`
+}
+
+// Endpoint is a URL events are delivered to.
+type Endpoint struct {
+	URL string
+
+	// Secret signs deliveries to the endpoint; see Sign.
+	Secret []byte
+
+	// Events are the event types delivered to the endpoint; empty means
+	// all.
+	Events []EventType
+}
+
+// subscribed reports whether the endpoint receives events of type t.
+func (e Endpoint) subscribed(t EventType) bool {
+	return len(e.Events) == 0 || slices.Contains(e.Events, t)
+}
+
+// Notifier delivers events to endpoints.
+type Notifier struct {
+	endpoints      []Endpoint
+	client         *http.Client
+	logger         *slog.Logger
+	clock          token.Clock
+	ids            *idgen.Generator
+	maxAttempts    int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	timeout        time.Duration
+}
+
+// Option is a functional option for configuring Notifier.
+type Option func(*Notifier)
+
+// WithHTTPClient sets the client deliveries are made with.
+func WithHTTPClient(client *http.Client) Option {
+	return func(n *Notifier) {
+		n.client = client
+	}
+}
+
+// WithLogger sets a custom logger for Notifier.
+func WithLogger(logger *slog.Logger) Option {
+	return func(n *Notifier) {
+		n.logger = logger
+	}
+}
+
+// WithClock sets the clock used for event and signature timestamps.
+func WithClock(clock token.Clock) Option {
+	return func(n *Notifier) {
+		n.clock = clock
+	}
+}
+
+// WithMaxAttempts sets the number of delivery attempts per endpoint,
+// including the first.
+func WithMaxAttempts(attempts int) Option {
+	return func(n *Notifier) {
+		if attempts > 0 {
+			n.maxAttempts = attempts
+		}
+	}
+}
+
+// WithBackoff sets the delay before the first retry, which doubles after
+// each retry up to maxBackoff.
+func WithBackoff(initial, maxBackoff time.Duration) Option {
+	return func(n *Notifier) {
+		if initial > 0 && maxBackoff >= initial {
+			n.initialBackoff = initial
+			n.maxBackoff = maxBackoff
+		}
+	}
+}
+
+// WithTimeout sets the time allowed for each delivery attempt.
+func WithTimeout(d time.Duration) Option {
+	return func(n *Notifier) {
+		if d > 0 {
+			n.timeout = d
+		}
+	}
+}
+
+// New creates a Notifier delivering to endpoints.
+func New(endpoints []Endpoint, opts ...Option) *Notifier {
+	n := &Notifier{
+		endpoints:      endpoints,
+		client:         http.DefaultClient,
+		logger:         slog.Default(),
+		clock:          token.SystemClock,
+		maxAttempts:    DefaultMaxAttempts,
+		initialBackoff: DefaultInitialBackoff,
+		maxBackoff:     DefaultMaxBackoff,
+		timeout:        DefaultTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(n)
+	}
+
+	n.ids = idgen.New(idgen.WithPrefix("evt_"), idgen.WithClock(n.clock))
+
+	return n
+}
+
+// Hooks returns validation Manager hooks that notify the endpoints of
+// created, verified, and expired validations. Delivery failures are logged.
+func (n *Notifier) Hooks() validation.Hooks {
+	return validation.Hooks{
+		OnCreated:  n.hook(EventCreated),
+		OnVerified: n.hook(EventVerified),
+		OnExpired:  n.hook(EventExpired),
+	}
+}
+
+// hook returns a hook notifying the endpoints of events of type t.
+func (n *Notifier) hook(t EventType) validation.HookFunc {
+	return func(ctx context.Context, v *validation.Validation) {
+		data := EventData{
+			ValidationID: v.ID,
+			State:        v.State.String(),
+			Channel:      string(v.Channel),
+			ExpiresAt:    v.ExpiresAt,
+		}
+		if err := n.Notify(ctx, t, data); err != nil {
+			n.logger.Warn("failed to deliver webhook",
+				"event", string(t),
+				"validation_id", v.ID,
+				"error", err)
+		}
+	}
+}
+
+// Notify delivers an event of type t to every endpoint subscribed to it,
+// concurrently, and returns the errors of the endpoints it could not be
+// delivered to.
+func (n *Notifier) Notify(ctx context.Context, t EventType, data EventData) error {
+	id, err := n.ids.New()
+	if err != nil {
+		return fmt.Errorf("failed to generate event ID: %w", err)
+	}
+
+	event := Event{ID: id, Type: t, CreatedAt: n.clock.Now(), Data: data}
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	for _, e := range n.endpoints {
+		if !e.subscribed(t) {
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := n.deliver(ctx, e, event, body); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// deliver POSTs an event to an endpoint, retrying failures that may be
+// transient.
+func (n *Notifier) deliver(ctx context.Context, e Endpoint, event Event, body []byte) error {
+	backoff := n.initialBackoff
+	for attempt := 1; ; attempt++ {
+		retry, err := n.post(ctx, e, event, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt == n.maxAttempts {
+			return fmt.Errorf("%w: %s after %d attempts: %w", ErrDelivery, e.URL, attempt, err)
+		}
+
+		n.logger.Debug("retrying webhook delivery",
+			"event_id", event.ID,
+			"url", e.URL,
+			"attempt", attempt,
+			"error", err)
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%w: %s: %w", ErrDelivery, e.URL, ctx.Err())
+		}
+		backoff = min(2*backoff, n.maxBackoff)
+	}
+}
+
+// post makes one delivery attempt. It reports whether a failure may be
+// transient: a network error, a 429, or a 5xx response.
+func (n *Notifier) post(ctx context.Context, e Endpoint, event Event, body []byte) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, n.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEventID, event.ID)
+	req.Header.Set(HeaderEventType, string(event.Type))
+	req.Header.Set(HeaderSignature, Sign(e.Secret, n.clock.Now(), body))
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode >= 500:
+		return true, fmt.Errorf("endpoint returned %s", resp.Status)
+	default:
+		return false, fmt.Errorf("endpoint returned %s", resp.Status)
+	}
+}
+
+// Sign returns the signature header of a delivery of body made at time at:
+// "t=<unix seconds>,v1=<hex HMAC-SHA256 of "<unix seconds>.<body>">".
+// Including the timestamp lets receivers reject replayed deliveries.
+func Sign(secret []byte, at time.Time, body []byte) string {
+	ts := strconv.FormatInt(at.Unix(), 10)
+
+	return "t=" + ts + ",v1=" + hex.EncodeToString(mac(secret, ts, body))
+}
+
+// Verify checks a signature header made by Sign for body, rejecting
+// signatures older than tolerance at now. Receivers call it with the raw
+// request body.
+func Verify(secret []byte, header string, body []byte, now time.Time, tolerance time.Duration) error {
+	var ts, sig string
+	for part := range strings.SplitSeq(header, ",") {
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "t":
+			ts = value
+		case "v1":
+			sig = value
+		}
+	}
+
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: bad timestamp", ErrInvalidSignature)
+	}
+	if age := now.Sub(time.Unix(unix, 0)); age > tolerance || age < -tolerance {
+		return fmt.Errorf("%w: timestamp outside tolerance", ErrInvalidSignature)
+	}
+
+	got, err := hex.DecodeString(sig)
+	if err != nil || !hmac.Equal(got, mac(secret, ts, body)) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+// mac returns the HMAC-SHA256 of "<ts>.<body>".
+func mac(secret []byte, ts string, body []byte) []byte {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(ts))
+	h.Write([]byte("."))
+	h.Write(body)
+
+	return h.Sum(nil)
+}
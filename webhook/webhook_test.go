@@ -0,0 +1,191 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+	"github.com/jaeyeom/email-validator-grpc-mcp/token/storage/memory"
+	"github.com/jaeyeom/email-validator-grpc-mcp/validation"
+	repomemory "github.com/jaeyeom/email-validator-grpc-mcp/validation/repository/memory"
+)
+
+var secret = []byte("webhook-secret")
+
+// receiver is an endpoint that answers with the queued status codes, then
+// 200, and records the events whose signature verifies.
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	events   []Event
+	attempts atomic.Int32
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rc.attempts.Add(1)
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	if len(rc.statuses) > 0 {
+		status := rc.statuses[0]
+		rc.statuses = rc.statuses[1:]
+		w.WriteHeader(status)
+		return
+	}
+
+	body, _ := io.ReadAll(r.Body)
+	if err := Verify(secret, r.Header.Get(HeaderSignature), body, time.Now(), time.Minute); err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var event Event
+	if err := json.Unmarshal(body, &event); err != nil || event.ID != r.Header.Get(HeaderEventID) ||
+		string(event.Type) != r.Header.Get(HeaderEventType) {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	rc.events = append(rc.events, event)
+}
+
+func (rc *receiver) received() []Event {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	return append([]Event(nil), rc.events...)
+}
+
+func newNotifier(endpoints []Endpoint, opts ...Option) *Notifier {
+	return New(endpoints, append([]Option{
+		WithLogger(slog.New(slog.DiscardHandler)),
+		WithBackoff(time.Millisecond, 4*time.Millisecond),
+	}, opts...)...)
+}
+
+func TestNotifier_Notify(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		statuses     []int
+		wantErr      bool
+		wantAttempts int32
+	}{
+		{"success", nil, false, 1},
+		{"transient failures", []int{http.StatusServiceUnavailable, http.StatusTooManyRequests}, false, 3},
+		{"too many failures", []int{500, 500, 500, 500}, true, 3},
+		{"rejected", []int{http.StatusBadRequest}, true, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			rc := &receiver{statuses: tt.statuses}
+			srv := httptest.NewServer(rc)
+			defer srv.Close()
+
+			n := newNotifier([]Endpoint{{URL: srv.URL, Secret: secret}}, WithMaxAttempts(3))
+			err := n.Notify(context.Background(), EventVerified, EventData{ValidationID: "val_1", State: "VERIFIED"})
+			if (err != nil) != tt.wantErr || (err != nil && !errors.Is(err, ErrDelivery)) {
+				t.Errorf("Notify() error = %v, want error %v", err, tt.wantErr)
+			}
+			if got := rc.attempts.Load(); got != tt.wantAttempts {
+				t.Errorf("endpoint received %d attempts, want %d", got, tt.wantAttempts)
+			}
+			if !tt.wantErr {
+				events := rc.received()
+				if len(events) != 1 || events[0].Type != EventVerified || events[0].Data.ValidationID != "val_1" {
+					t.Errorf("endpoint received %+v, want one verified event for val_1", events)
+				}
+			}
+		})
+	}
+}
+
+func TestNotifier_Hooks(t *testing.T) {
+	t.Parallel()
+
+	all := &receiver{}
+	allSrv := httptest.NewServer(all)
+	defer allSrv.Close()
+	verifiedOnly := &receiver{}
+	verifiedSrv := httptest.NewServer(verifiedOnly)
+	defer verifiedSrv.Close()
+
+	n := newNotifier([]Endpoint{
+		{URL: allSrv.URL, Secret: secret},
+		{URL: verifiedSrv.URL, Secret: secret, Events: []EventType{EventVerified}},
+	})
+	logger := slog.New(slog.DiscardHandler)
+	tokens := token.NewManager(memory.New(memory.WithLogger(logger)), token.WithManagerLogger(logger))
+	m := validation.NewManager(repomemory.New(), tokens,
+		validation.WithHooks(n.Hooks()), validation.WithLogger(logger))
+
+	ctx := context.Background()
+	v, issued, err := m.Start(ctx, validation.Request{Email: "user@example.com", Methods: []token.Type{token.TypeCode}})
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if _, err := m.VerifyCode(ctx, v.ID, issued[0].Value); err != nil {
+		t.Fatalf("VerifyCode() error = %v", err)
+	}
+
+	events := all.received()
+	if len(events) != 2 || events[0].Type != EventCreated || events[1].Type != EventVerified {
+		t.Fatalf("endpoint received %+v, want created and verified events", events)
+	}
+	if data := events[0].Data; data.ValidationID != v.ID || data.State != "PENDING" || data.Channel != "email" || data.ExpiresAt.IsZero() {
+		t.Errorf("created event data = %+v, want the pending validation %s", data, v.ID)
+	}
+	if data := events[1].Data; data.ValidationID != v.ID || data.State != "VERIFIED" {
+		t.Errorf("verified event data = %+v, want the verified validation %s", data, v.ID)
+	}
+	if got := verifiedOnly.received(); len(got) != 1 || got[0].Type != EventVerified {
+		t.Errorf("subscribed endpoint received %+v, want only the verified event", got)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1700000000, 0)
+	body := []byte(`{"id":"evt_1"}`)
+	header := Sign(secret, now, body)
+
+	tests := []struct {
+		name    string
+		secret  []byte
+		header  string
+		body    []byte
+		now     time.Time
+		wantErr bool
+	}{
+		{"valid", secret, header, body, now.Add(30 * time.Second), false},
+		{"wrong secret", []byte("other"), header, body, now, true},
+		{"tampered body", secret, header, []byte(`{"id":"evt_2"}`), now, true},
+		{"too old", secret, header, body, now.Add(2 * time.Minute), true},
+		{"malformed", secret, "v1=abc", body, now, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := Verify(tt.secret, tt.header, tt.body, tt.now, time.Minute)
+			if (err != nil) != tt.wantErr || (err != nil && !errors.Is(err, ErrInvalidSignature)) {
+				t.Errorf("Verify() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package workflow
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"net/url"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+	"github.com/jaeyeom/email-validator-grpc-mcp/validation"
+)
+
+// ErrInvalidLinkURL is returned by NewTemplateRenderer when the link base
+// URL is not an absolute URL.
+var ErrInvalidLinkURL = errors.New("link base URL must be absolute")
+
+// TokenParam is the query parameter link URLs carry the token in.
+const TokenParam = "token"
+
+// TemplateData is the data the templates of a TemplateRenderer execute
+// with. LinkURL is empty unless a link token was issued, and Code unless a
+// code token was.
+type TemplateData struct {
+	Email     string
+	LinkURL   string
+	Code      string
+	ExpiresAt time.Time
+}
+
+// Default templates of a TemplateRenderer.
+const (
+	DefaultSubjectTemplate = "Verify your email address"
+
+	DefaultTextTemplate = `Please verify your email address {{.Email}}.
+{{if .LinkURL}}
+Open this link to verify it:
+
+{{.LinkURL}}
+{{end}}{{if .Code}}
+Or enter this code: {{.Code}}
+{{end}}
+This request expires at {{.ExpiresAt.Format "2006-01-02 15:04 MST"}}. If you did not request it, ignore this message.
+`
+
+	DefaultHTMLTemplate = `<p>Please verify your email address {{.Email}}.</p>
+{{if .LinkURL}}<p><a href="{{.LinkURL}}">Verify your email address</a></p>
+{{end}}{{if .Code}}<p>Or enter this code: <strong>{{.Code}}</strong></p>
+{{end}}<p>This request expires at {{.ExpiresAt.Format "2006-01-02 15:04 MST"}}. If you did not request it, ignore this message.</p>
+`
+)
+
+// TemplateRenderer renders messages from text and HTML templates, linking
+// link tokens to a verification page.
+type TemplateRenderer struct {
+	linkURL *url.URL
+	subject *texttemplate.Template
+	text    *texttemplate.Template
+	html    *htmltemplate.Template
+}
+
+// RendererOption is a functional option for configuring TemplateRenderer.
+type RendererOption func(*TemplateRenderer)
+
+// WithSubjectTemplate replaces the subject template.
+func WithSubjectTemplate(tmpl *texttemplate.Template) RendererOption {
+	return func(r *TemplateRenderer) {
+		r.subject = tmpl
+	}
+}
+
+// WithTextTemplate replaces the plain-text body template.
+func WithTextTemplate(tmpl *texttemplate.Template) RendererOption {
+	return func(r *TemplateRenderer) {
+		r.text = tmpl
+	}
+}
+
+// WithHTMLTemplate replaces the HTML body template. A nil template renders
+// plain-text messages only.
+func WithHTMLTemplate(tmpl *htmltemplate.Template) RendererOption {
+	return func(r *TemplateRenderer) {
+		r.html = tmpl
+	}
+}
+
+// NewTemplateRenderer creates a TemplateRenderer linking link tokens to
+// linkBaseURL, with the token in the TokenParam query parameter.
+func NewTemplateRenderer(linkBaseURL string, opts ...RendererOption) (*TemplateRenderer, error) {
+	u, err := url.Parse(linkBaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidLinkURL, err)
+	}
+	if !u.IsAbs() {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidLinkURL, linkBaseURL)
+	}
+
+	r := &TemplateRenderer{
+		linkURL: u,
+		subject: texttemplate.Must(texttemplate.New("subject").Parse(DefaultSubjectTemplate)),
+		text:    texttemplate.Must(texttemplate.New("text").Parse(DefaultTextTemplate)),
+		html:    htmltemplate.Must(htmltemplate.New("html").Parse(DefaultHTMLTemplate)),
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r, nil
+}
+
+// Render renders the message for v. When several tokens of one type were
+// issued, the first is used.
+func (r *TemplateRenderer) Render(ctx context.Context, v *validation.Validation, tokens []*token.Token) (*Message, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("context error: %w", err)
+	}
+
+	data := TemplateData{
+		Email:     v.Email,
+		ExpiresAt: v.ExpiresAt,
+	}
+	for _, tkn := range tokens {
+		switch {
+		case tkn.Type == token.TypeLink && data.LinkURL == "":
+			data.LinkURL = r.link(tkn.Value)
+		case tkn.Type == token.TypeCode && data.Code == "":
+			data.Code = tkn.Value
+		}
+	}
+
+	msg := &Message{
+		ValidationID: v.ID,
+		To:           v.Email,
+	}
+
+	var buf bytes.Buffer
+	if err := r.subject.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("failed to render subject: %w", err)
+	}
+	msg.Subject = buf.String()
+
+	buf.Reset()
+	if err := r.text.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("failed to render text body: %w", err)
+	}
+	msg.Text = buf.String()
+
+	if r.html != nil {
+		buf.Reset()
+		if err := r.html.Execute(&buf, data); err != nil {
+			return nil, fmt.Errorf("failed to render HTML body: %w", err)
+		}
+		msg.HTML = buf.String()
+	}
+
+	return msg, nil
+}
+
+// link returns the verification URL of a link token.
+func (r *TemplateRenderer) link(value string) string {
+	u := *r.linkURL
+	q := u.Query()
+	q.Set(TokenParam, value)
+	u.RawQuery = q.Encode()
+
+	return u.String()
+}
@@ -0,0 +1,566 @@
+// Package workflow runs a validation end to end: it starts the validation,
+// issues its tokens, renders the message carrying them, and hands the
+// message to a Sender. Callers get back the validation ID to poll with
+// validation.Manager.Get.
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/check/disposable"
+	"github.com/jaeyeom/email-validator-grpc-mcp/check/dns"
+	"github.com/jaeyeom/email-validator-grpc-mcp/check/suggest"
+	"github.com/jaeyeom/email-validator-grpc-mcp/check/syntax"
+	"github.com/jaeyeom/email-validator-grpc-mcp/emailaddr"
+	"github.com/jaeyeom/email-validator-grpc-mcp/policy"
+	"github.com/jaeyeom/email-validator-grpc-mcp/redact"
+	"github.com/jaeyeom/email-validator-grpc-mcp/rules"
+	"github.com/jaeyeom/email-validator-grpc-mcp/score"
+	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+	"github.com/jaeyeom/email-validator-grpc-mcp/validation"
+)
+
+// Default resend limits.
+const (
+	// DefaultResendCooldown is how long after a message is sent it may be
+	// resent.
+	DefaultResendCooldown = time.Minute
+
+	// DefaultMaxResends is how many times a message may be resent.
+	DefaultMaxResends = 3
+)
+
+var (
+	// ErrResendTooSoon is returned by Resend when the cooldown since the
+	// message was last sent has not passed.
+	ErrResendTooSoon = errors.New("validation message was sent too recently")
+
+	// ErrResendLimitReached is returned by Resend when the message was
+	// already resent the maximum number of times.
+	ErrResendLimitReached = errors.New("validation message resend limit reached")
+)
+
+// Status is the outcome of StartValidation.
+type Status int
+
+// StartValidation outcomes.
+const (
+	// StatusSent is a new validation whose message was sent.
+	StatusSent Status = iota + 1
+	// StatusAlreadyVerified is an address verified within the verified
+	// window; no validation was started and no message sent.
+	StatusAlreadyVerified
+	// StatusNoMailServer is an address whose domain cannot receive mail;
+	// no validation was started and no message sent.
+	StatusNoMailServer
+	// StatusDisposable is an address at a disposable domain, rejected by
+	// WithRejectDisposable; no validation was started and no message sent.
+	StatusDisposable
+	// StatusBlocked is an address whose domain the policy set by WithPolicy
+	// rejects; no validation was started and no message sent.
+	StatusBlocked
+	// StatusDenied is an address a recipient rule set by WithRules denies;
+	// no validation was started and no message sent.
+	StatusDenied
+)
+
+// String returns the name of the status, such as "SENT".
+func (s Status) String() string {
+	switch s {
+	case StatusSent:
+		return "SENT"
+	case StatusAlreadyVerified:
+		return "ALREADY_VERIFIED"
+	case StatusNoMailServer:
+		return "NO_MAIL_SERVER"
+	case StatusDisposable:
+		return "DISPOSABLE"
+	case StatusBlocked:
+		return "BLOCKED"
+	case StatusDenied:
+		return "DENIED"
+	default:
+		return fmt.Sprintf("Status(%d)", int(s))
+	}
+}
+
+// Result is the outcome of StartValidation.
+type Result struct {
+	// ValidationID identifies the validation to poll: the new validation,
+	// or the earlier verified one for StatusAlreadyVerified. It is empty
+	// for StatusNoMailServer, StatusDisposable, StatusBlocked, and
+	// StatusDenied.
+	ValidationID string
+
+	Status Status
+
+	// HasMailServer reports whether the domain of the address can receive
+	// mail. It is nil if the domain was not checked: no checker is
+	// configured, the address was already verified, or the lookup failed.
+	HasMailServer *bool
+
+	// Disposable reports that the address is at a disposable domain of the
+	// list set by WithDisposableList.
+	Disposable bool
+
+	// Suggestion is a likely correction of a mistyped domain, from the
+	// Suggester set by WithSuggester, for the UI to offer the user.
+	Suggestion *suggest.Suggestion
+
+	// Score is the deliverability score of the address from the signals
+	// checked, if a Scorer is set by WithScorer.
+	Score *score.Result
+
+	// Rejection is the policy decision rejecting the domain, for
+	// StatusBlocked.
+	Rejection *policy.Decision
+
+	// RuleDecision is the decision of the recipient rules set by WithRules,
+	// if they were evaluated. A rules.ActionStepUp decision still sends the
+	// message; the caller asks for further proof before trusting the
+	// address.
+	RuleDecision *rules.Decision
+}
+
+// Message is a rendered validation message, ready for delivery.
+type Message struct {
+	// ValidationID identifies the validation the message belongs to.
+	ValidationID string
+
+	To      string
+	Subject string
+
+	// Text is the plain-text body, and HTML the optional HTML alternative.
+	Text string
+	HTML string
+}
+
+// Renderer builds the message delivering a validation's tokens.
+type Renderer interface {
+	// Render returns the message for v, which was issued tokens in the
+	// order of v.Methods.
+	Render(ctx context.Context, v *validation.Validation, tokens []*token.Token) (*Message, error)
+}
+
+// Sender delivers messages, typically through an email provider.
+// Implementations must be safe for concurrent use.
+type Sender interface {
+	// Send delivers msg. A nil error means the message was accepted for
+	// delivery, not that it reached the recipient.
+	Send(ctx context.Context, msg *Message) error
+}
+
+// SenderFunc adapts a function to the Sender interface.
+type SenderFunc func(ctx context.Context, msg *Message) error
+
+// Send calls f.
+func (f SenderFunc) Send(ctx context.Context, msg *Message) error {
+	return f(ctx, msg)
+}
+
+// Workflow starts validations and delivers their messages.
+type Workflow struct {
+	validations *validation.Manager
+	renderer    Renderer
+	sender      Sender
+	logger      *slog.Logger
+	clock       token.Clock
+	dnsChecker  *dns.Checker
+	disposables *disposable.List
+	suggester   *suggest.Suggester
+	scorer      *score.Scorer
+	policy      *policy.Policy
+	rules       *rules.Engine
+
+	rejectDisposable bool
+
+	resendCooldown time.Duration
+	maxResends     int
+	verifiedWindow time.Duration
+}
+
+// Option is a functional option for configuring Workflow.
+type Option func(*Workflow)
+
+// WithLogger sets a custom logger for Workflow.
+func WithLogger(logger *slog.Logger) Option {
+	return func(w *Workflow) {
+		w.logger = logger
+	}
+}
+
+// WithClock sets the clock the resend cooldown is measured with. It should
+// match the clock of the validation manager.
+func WithClock(clock token.Clock) Option {
+	return func(w *Workflow) {
+		w.clock = clock
+	}
+}
+
+// WithResendCooldown sets how long after a message is sent it may be
+// resent. Zero allows immediate resends.
+func WithResendCooldown(d time.Duration) Option {
+	return func(w *Workflow) {
+		if d >= 0 {
+			w.resendCooldown = d
+		}
+	}
+}
+
+// WithMaxResends sets how many times a message may be resent. Zero disables
+// resending.
+func WithMaxResends(n int) Option {
+	return func(w *Workflow) {
+		if n >= 0 {
+			w.maxResends = n
+		}
+	}
+}
+
+// WithVerifiedWindow makes StartValidation report StatusAlreadyVerified,
+// without sending a message, for an address verified within d, so repeat
+// signups do not cause duplicate sends. The default of zero always sends.
+func WithVerifiedWindow(d time.Duration) Option {
+	return func(w *Workflow) {
+		if d >= 0 {
+			w.verifiedWindow = d
+		}
+	}
+}
+
+// WithDNSChecker makes StartValidation check that the domain of the address
+// has a mail server before starting a validation. Addresses whose domain
+// has none are reported as StatusNoMailServer without sending a message. If
+// the lookup fails, the validation proceeds as if unchecked.
+func WithDNSChecker(checker *dns.Checker) Option {
+	return func(w *Workflow) {
+		w.dnsChecker = checker
+	}
+}
+
+// WithDisposableList makes StartValidation flag addresses at the
+// disposable domains of list in Result.Disposable. They are still sent a
+// message unless WithRejectDisposable is also given.
+func WithDisposableList(list *disposable.List) Option {
+	return func(w *Workflow) {
+		w.disposables = list
+	}
+}
+
+// WithRejectDisposable makes StartValidation report addresses flagged as
+// disposable as StatusDisposable without sending a message.
+func WithRejectDisposable() Option {
+	return func(w *Workflow) {
+		w.rejectDisposable = true
+	}
+}
+
+// WithSuggester makes StartValidation suggest corrections of mistyped
+// domains in Result.Suggestion. The message is still sent to the address as
+// given; the suggestion lets the UI ask the user to confirm or correct it.
+func WithSuggester(suggester *suggest.Suggester) Option {
+	return func(w *Workflow) {
+		w.suggester = suggester
+	}
+}
+
+// WithScorer makes StartValidation score the deliverability of addresses
+// in Result.Score from the syntax, mail server, and disposable-domain
+// signals checked. The score is informational; it does not stop a message
+// from being sent.
+func WithScorer(scorer *score.Scorer) Option {
+	return func(w *Workflow) {
+		w.scorer = scorer
+	}
+}
+
+// WithPolicy makes StartValidation report addresses whose domain p rejects
+// as StatusBlocked, with the decision in Result.Rejection, without sending
+// a message. Since p's rules can change at runtime, every call evaluates
+// the current rules.
+func WithPolicy(p *policy.Policy) Option {
+	return func(w *Workflow) {
+		w.policy = p
+	}
+}
+
+// WithRules makes StartValidation evaluate the recipient rules of e, with
+// the score of the address if WithScorer is also given, once the other
+// checks pass. Addresses a rule denies are reported as StatusDenied without
+// sending a message, and every decision is reported in Result.RuleDecision.
+func WithRules(e *rules.Engine) Option {
+	return func(w *Workflow) {
+		w.rules = e
+	}
+}
+
+// New creates a Workflow starting validations with validations, rendering
+// their messages with renderer, and delivering them with sender.
+func New(validations *validation.Manager, renderer Renderer, sender Sender, opts ...Option) *Workflow {
+	w := &Workflow{
+		validations: validations,
+		renderer:    renderer,
+		sender:      sender,
+		logger:      slog.Default(),
+		clock:       token.SystemClock,
+
+		resendCooldown: DefaultResendCooldown,
+		maxResends:     DefaultMaxResends,
+	}
+
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	return w
+}
+
+// StartValidation starts a validation of req.Email and sends the message
+// carrying its tokens. If the message cannot be rendered or sent, the
+// validation is canceled, so its tokens cannot be used, and the error is
+// returned. With WithVerifiedWindow, an address verified recently is
+// reported as StatusAlreadyVerified instead, and with WithDNSChecker, an
+// address that cannot receive mail as StatusNoMailServer. With
+// WithRejectDisposable, addresses at disposable domains are reported as
+// StatusDisposable. With WithPolicy, addresses at rejected domains are
+// reported as StatusBlocked before any other check, and with WithRules,
+// addresses a recipient rule denies as StatusDenied after all of them.
+func (w *Workflow) StartValidation(ctx context.Context, req validation.Request) (*Result, error) {
+	if decision := w.evaluatePolicy(req.Email); decision != nil && !decision.Allowed {
+		w.logger.Info("validation rejected by domain policy",
+			"email", redact.Email(req.Email),
+			"code", decision.Code)
+		return &Result{Status: StatusBlocked, Rejection: decision}, nil
+	}
+
+	if w.verifiedWindow > 0 && req.Email != "" {
+		verified, err := w.validations.FindVerified(ctx, req.Email, w.verifiedWindow)
+		switch {
+		case err == nil:
+			w.logger.Info("validation skipped for verified address",
+				"validation_id", verified.ID,
+				"email", redact.Email(req.Email))
+			return &Result{ValidationID: verified.ID, Status: StatusAlreadyVerified}, nil
+		case !errors.Is(err, validation.ErrNotFound):
+			return nil, fmt.Errorf("failed to look up verified validations: %w", err)
+		}
+	}
+
+	res := &Result{}
+	if w.suggester != nil {
+		res.Suggestion = w.suggester.Suggest(req.Email)
+	}
+
+	disposableResult := w.checkDisposable(req.Email)
+	res.Disposable = disposableResult != nil && disposableResult.Disposable
+	if res.Disposable && w.rejectDisposable {
+		w.logger.Info("validation rejected for disposable address",
+			"email", redact.Email(req.Email))
+		res.Status = StatusDisposable
+		res.Score = w.score(req.Email, nil, disposableResult)
+		return res, nil
+	}
+
+	mx := w.checkMailServer(ctx, req.Email)
+	if mx != nil {
+		res.HasMailServer = &mx.HasMailServer
+	}
+	res.Score = w.score(req.Email, mx, disposableResult)
+	if mx != nil && !mx.HasMailServer {
+		w.logger.Info("validation skipped for domain without mail server",
+			"email", redact.Email(req.Email))
+		res.Status = StatusNoMailServer
+		return res, nil
+	}
+
+	if w.rules != nil {
+		res.RuleDecision = w.rules.Evaluate(ctx, rules.Input{Address: req.Email, Score: res.Score})
+		if res.RuleDecision.Action == rules.ActionDeny {
+			w.logger.Info("validation denied by recipient rule",
+				"email", redact.Email(req.Email),
+				"rule", res.RuleDecision.Rule.Name)
+			res.Status = StatusDenied
+			return res, nil
+		}
+	}
+
+	v, tokens, err := w.validations.Start(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start validation: %w", err)
+	}
+
+	msg, err := w.renderer.Render(ctx, v, tokens)
+	if err != nil {
+		w.abandon(ctx, v.ID)
+		return nil, fmt.Errorf("failed to render validation message: %w", err)
+	}
+
+	if err := w.sender.Send(ctx, msg); err != nil {
+		w.abandon(ctx, v.ID)
+		return nil, fmt.Errorf("failed to send validation message: %w", err)
+	}
+
+	w.markSent(ctx, v)
+
+	res.ValidationID = v.ID
+	res.Status = StatusSent
+
+	return res, nil
+}
+
+// evaluatePolicy evaluates the domain of email against the policy, or
+// returns nil if there is none. Malformed addresses are not evaluated; they
+// are rejected when the validation starts.
+func (w *Workflow) evaluatePolicy(email string) *policy.Decision {
+	if w.policy == nil {
+		return nil
+	}
+
+	decision, err := w.policy.EvaluateAddress(email)
+	if err != nil {
+		return nil
+	}
+
+	return decision
+}
+
+// checkDisposable checks whether email is at a disposable domain, or
+// returns nil if it was not checked. Malformed addresses are not checked;
+// they are rejected when the validation starts.
+func (w *Workflow) checkDisposable(email string) *disposable.Result {
+	if w.disposables == nil {
+		return nil
+	}
+
+	res, err := w.disposables.Check(email)
+	if err != nil {
+		return nil
+	}
+
+	return res
+}
+
+// checkMailServer checks the mail servers of the domain of email, or
+// returns nil if they were not checked.
+func (w *Workflow) checkMailServer(ctx context.Context, email string) *dns.Result {
+	if w.dnsChecker == nil {
+		return nil
+	}
+
+	res, err := w.dnsChecker.CheckAddress(ctx, email)
+	if err != nil {
+		// Malformed addresses are rejected when the validation starts.
+		if !errors.Is(err, emailaddr.ErrInvalid) {
+			w.logger.Warn("mail server check failed",
+				"email", redact.Email(email),
+				"error", err)
+		}
+		return nil
+	}
+
+	return res
+}
+
+// score scores email from the signals checked, or returns nil without a
+// Scorer.
+func (w *Workflow) score(email string, mx *dns.Result, disposableResult *disposable.Result) *score.Result {
+	if w.scorer == nil {
+		return nil
+	}
+
+	email = strings.TrimSpace(email)
+
+	return w.scorer.Score(score.Signals{
+		Address:    email,
+		Syntax:     syntax.Check(email),
+		MailServer: mx,
+		Disposable: disposableResult,
+	})
+}
+
+// Resend sends the message of a pending or sent validation again, with its
+// link token reused and its code tokens replaced. It returns
+// ErrResendTooSoon within the resend cooldown of the last send, including
+// when a concurrent resend of the validation is recorded first, and
+// ErrResendLimitReached once the message was resent the maximum number of
+// times. The resend is recorded before the message is sent, so a message
+// that cannot be rendered or sent still counts against the cooldown and the
+// limit; the error is returned and the validation stays open.
+func (w *Workflow) Resend(ctx context.Context, id string) error {
+	v, err := w.validations.Get(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to get validation: %w", err)
+	}
+
+	if v.State.IsTerminal() {
+		return fmt.Errorf("%w: validation is %s", validation.ErrInvalidTransition, v.State)
+	}
+
+	// The first send does not count as a resend.
+	if v.SendCount > w.maxResends {
+		return fmt.Errorf("%w: sent %d times", ErrResendLimitReached, v.SendCount)
+	}
+
+	if !v.SentAt.IsZero() {
+		if wait := v.SentAt.Add(w.resendCooldown).Sub(w.clock.Now()); wait > 0 {
+			return fmt.Errorf("%w: retry in %s", ErrResendTooSoon, wait.Round(time.Second))
+		}
+	}
+
+	if _, err := w.validations.MarkResent(ctx, v); err != nil {
+		if errors.Is(err, validation.ErrConflict) {
+			return fmt.Errorf("%w: validation changed concurrently", ErrResendTooSoon)
+		}
+		return fmt.Errorf("failed to record resend: %w", err)
+	}
+
+	v, tokens, err := w.validations.Reissue(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to reissue tokens: %w", err)
+	}
+
+	msg, err := w.renderer.Render(ctx, v, tokens)
+	if err != nil {
+		return fmt.Errorf("failed to render validation message: %w", err)
+	}
+
+	if err := w.sender.Send(ctx, msg); err != nil {
+		return fmt.Errorf("failed to send validation message: %w", err)
+	}
+
+	w.logger.Info("validation message sent",
+		"validation_id", v.ID,
+		"email", redact.Email(v.Email))
+
+	return nil
+}
+
+// markSent records that the message of v was sent.
+func (w *Workflow) markSent(ctx context.Context, v *validation.Validation) {
+	if _, err := w.validations.MarkSent(ctx, v.ID); err != nil {
+		// The message is out; the recipient may still complete the
+		// validation, so only the bookkeeping is lost.
+		w.logger.Warn("failed to mark validation sent",
+			"validation_id", v.ID,
+			"error", err)
+	}
+
+	w.logger.Info("validation message sent",
+		"validation_id", v.ID,
+		"email", redact.Email(v.Email))
+}
+
+// abandon cancels a validation whose message was not sent. Failures are
+// logged; the validation expires unused anyway.
+func (w *Workflow) abandon(ctx context.Context, id string) {
+	if _, err := w.validations.Cancel(context.WithoutCancel(ctx), id); err != nil {
+		w.logger.Warn("failed to cancel unsent validation",
+			"validation_id", id,
+			"error", err)
+	}
+}
@@ -0,0 +1,559 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/netip"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jaeyeom/email-validator-grpc-mcp/check/disposable"
+	"github.com/jaeyeom/email-validator-grpc-mcp/check/dns"
+	"github.com/jaeyeom/email-validator-grpc-mcp/check/suggest"
+	"github.com/jaeyeom/email-validator-grpc-mcp/policy"
+	"github.com/jaeyeom/email-validator-grpc-mcp/rules"
+	"github.com/jaeyeom/email-validator-grpc-mcp/score"
+	"github.com/jaeyeom/email-validator-grpc-mcp/token"
+	"github.com/jaeyeom/email-validator-grpc-mcp/token/storage/memory"
+	"github.com/jaeyeom/email-validator-grpc-mcp/validation"
+	repomemory "github.com/jaeyeom/email-validator-grpc-mcp/validation/repository/memory"
+)
+
+// outbox records sent messages, failing with err when it is set.
+type outbox struct {
+	mu       sync.Mutex
+	messages []*Message
+	err      error
+}
+
+func (o *outbox) Send(_ context.Context, msg *Message) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.err != nil {
+		return o.err
+	}
+	o.messages = append(o.messages, msg)
+
+	return nil
+}
+
+// fakeClock is a settable clock.
+type fakeClock struct {
+	now atomic.Int64
+}
+
+func (c *fakeClock) Now() time.Time {
+	return time.Unix(0, c.now.Load())
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now.Add(int64(d))
+}
+
+func setup(t *testing.T, sender Sender, opts ...Option) (*Workflow, *validation.Manager, validation.Repository, *fakeClock) {
+	t.Helper()
+
+	clock := &fakeClock{}
+	clock.now.Store(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC).UnixNano())
+	logger := slog.New(slog.DiscardHandler)
+
+	tokens := token.NewManager(memory.New(memory.WithClock(clock), memory.WithLogger(logger)),
+		token.WithClock(clock), token.WithManagerLogger(logger))
+	repo := repomemory.New()
+	validations := validation.NewManager(repo, tokens, validation.WithClock(clock), validation.WithLogger(logger))
+
+	renderer, err := NewTemplateRenderer("https://example.com/verify?lang=en")
+	if err != nil {
+		t.Fatalf("NewTemplateRenderer() error = %v", err)
+	}
+
+	w := New(validations, renderer, sender,
+		append([]Option{WithClock(clock), WithLogger(logger)}, opts...)...)
+
+	return w, validations, repo, clock
+}
+
+func TestWorkflow_StartValidation(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	var sent outbox
+	w, validations, _, _ := setup(t, &sent)
+
+	res, err := w.StartValidation(ctx, validation.Request{
+		Email:   "user@example.com",
+		Methods: []token.Type{token.TypeLink, token.TypeCode},
+		TTL:     time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("StartValidation() error = %v", err)
+	}
+	if res.Status != StatusSent {
+		t.Errorf("StartValidation() status = %v, want %v", res.Status, StatusSent)
+	}
+	id := res.ValidationID
+
+	v, err := validations.Get(ctx, id)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if v.State != validation.StateSent {
+		t.Errorf("Get() state = %v, want %v", v.State, validation.StateSent)
+	}
+
+	if len(sent.messages) != 1 {
+		t.Fatalf("sent %d messages, want 1", len(sent.messages))
+	}
+	msg := sent.messages[0]
+	if msg.ValidationID != id || msg.To != "user@example.com" || msg.Subject != DefaultSubjectTemplate {
+		t.Errorf("message = %+v, want one to user@example.com for %s", msg, id)
+	}
+
+	// The message carries a working link and code; either completes the
+	// validation.
+	start := strings.Index(msg.Text, "https://")
+	if start < 0 {
+		t.Fatalf("message text has no link:\n%s", msg.Text)
+	}
+	link, err := url.Parse(strings.Fields(msg.Text[start:])[0])
+	if err != nil {
+		t.Fatalf("url.Parse() error = %v", err)
+	}
+	if link.Query().Get("lang") != "en" {
+		t.Errorf("link %s lost the base URL query", link)
+	}
+	if !strings.Contains(msg.Text, "enter this code: ") || !strings.Contains(msg.HTML, "<strong>") {
+		t.Errorf("message has no code:\n%s\n%s", msg.Text, msg.HTML)
+	}
+	verified, err := validations.VerifyLink(ctx, link.Query().Get(TokenParam))
+	if err != nil {
+		t.Fatalf("VerifyLink() error = %v", err)
+	}
+	if verified.ID != id {
+		t.Errorf("VerifyLink() ID = %s, want %s", verified.ID, id)
+	}
+}
+
+func TestWorkflow_StartValidationFailures(t *testing.T) {
+	t.Parallel()
+
+	errSend := errors.New("provider unavailable")
+
+	tests := []struct {
+		name    string
+		req     validation.Request
+		sendErr error
+		want    error
+		started int
+	}{
+		{"empty email", validation.Request{}, nil, validation.ErrEmptyEmail, 0},
+		{"send failure", validation.Request{Email: "user@example.com"}, errSend, errSend, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			sent := &outbox{err: tt.sendErr}
+			w, _, repo, _ := setup(t, sent)
+
+			res, err := w.StartValidation(ctx, tt.req)
+			if !errors.Is(err, tt.want) || res != nil {
+				t.Fatalf("StartValidation() = %+v, %v, want error %v", res, err, tt.want)
+			}
+
+			// Unsent validations are canceled.
+			got, err := repo.List(ctx, validation.Filter{})
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			if len(got) != tt.started {
+				t.Errorf("List() = %d validations, want %d", len(got), tt.started)
+			}
+			for _, v := range got {
+				if v.State != validation.StateCanceled {
+					t.Errorf("validation %s state = %v, want %v", v.ID, v.State, validation.StateCanceled)
+				}
+			}
+		})
+	}
+}
+
+func TestWorkflow_Resend(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	var sent outbox
+	w, validations, _, clock := setup(t, &sent, WithResendCooldown(time.Minute), WithMaxResends(2))
+
+	res, err := w.StartValidation(ctx, validation.Request{
+		Email:   "user@example.com",
+		Methods: []token.Type{token.TypeCode},
+		TTL:     time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("StartValidation() error = %v", err)
+	}
+	id := res.ValidationID
+
+	if err := w.Resend(ctx, id); !errors.Is(err, ErrResendTooSoon) {
+		t.Errorf("Resend() within cooldown error = %v, want %v", err, ErrResendTooSoon)
+	}
+
+	for i := range 2 {
+		clock.Advance(time.Minute)
+		if err := w.Resend(ctx, id); err != nil {
+			t.Fatalf("Resend() #%d error = %v", i+1, err)
+		}
+	}
+
+	clock.Advance(time.Minute)
+	if err := w.Resend(ctx, id); !errors.Is(err, ErrResendLimitReached) {
+		t.Errorf("Resend() past the limit error = %v, want %v", err, ErrResendLimitReached)
+	}
+
+	v, err := validations.Get(ctx, id)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if v.SendCount != 3 || len(sent.messages) != 3 {
+		t.Errorf("sent %d messages, SendCount %d, want 3", len(sent.messages), v.SendCount)
+	}
+
+	// Only the code of the last message works.
+	codes := make([]string, len(sent.messages))
+	for i, msg := range sent.messages {
+		_, code, _ := strings.Cut(msg.Text, "enter this code: ")
+		codes[i] = strings.Fields(code)[0]
+	}
+	if codes[0] == codes[2] {
+		t.Fatalf("resent code %q was not reissued", codes[2])
+	}
+	if _, err := validations.VerifyCode(ctx, id, codes[2]); err != nil {
+		t.Fatalf("VerifyCode() of the last code error = %v", err)
+	}
+
+	if err := w.Resend(ctx, id); !errors.Is(err, validation.ErrInvalidTransition) {
+		t.Errorf("Resend() of verified validation error = %v, want %v", err, validation.ErrInvalidTransition)
+	}
+}
+
+func TestWorkflow_ResendConcurrently(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	var sent outbox
+	w, validations, _, clock := setup(t, &sent, WithResendCooldown(time.Minute), WithMaxResends(5))
+
+	res, err := w.StartValidation(ctx, validation.Request{
+		Email:   "user@example.com",
+		Methods: []token.Type{token.TypeLink},
+		TTL:     time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("StartValidation() error = %v", err)
+	}
+	clock.Advance(time.Minute)
+
+	const n = 10
+	errs := make(chan error, n)
+	var wg sync.WaitGroup
+	for range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- w.Resend(ctx, res.ValidationID)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	succeeded := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			succeeded++
+		case !errors.Is(err, ErrResendTooSoon):
+			t.Errorf("concurrent Resend() error = %v, want nil or %v", err, ErrResendTooSoon)
+		}
+	}
+	if succeeded != 1 || len(sent.messages) != 2 {
+		t.Errorf("%d concurrent resends succeeded, %d messages sent, want 1 and 2", succeeded, len(sent.messages))
+	}
+
+	// The resent message carries the same link as the first.
+	if sent.messages[0].Text != sent.messages[1].Text {
+		t.Errorf("resent message %q differs from the first %q", sent.messages[1].Text, sent.messages[0].Text)
+	}
+
+	v, err := validations.Get(ctx, res.ValidationID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if v.SendCount != 2 {
+		t.Errorf("SendCount = %d, want 2", v.SendCount)
+	}
+}
+
+func TestWorkflow_VerifiedWindow(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	var sent outbox
+	w, validations, _, clock := setup(t, &sent, WithVerifiedWindow(24*time.Hour))
+	req := validation.Request{Email: "user@example.com", Methods: []token.Type{token.TypeCode}}
+
+	first, err := w.StartValidation(ctx, req)
+	if err != nil {
+		t.Fatalf("StartValidation() error = %v", err)
+	}
+
+	// An unverified address is sent a new message.
+	second, err := w.StartValidation(ctx, req)
+	if err != nil || second.Status != StatusSent || second.ValidationID == first.ValidationID {
+		t.Fatalf("StartValidation() before verification = %+v, %v, want a new sent validation", second, err)
+	}
+
+	_, code, _ := strings.Cut(sent.messages[1].Text, "enter this code: ")
+	if _, err := validations.VerifyCode(ctx, second.ValidationID, strings.Fields(code)[0]); err != nil {
+		t.Fatalf("VerifyCode() error = %v", err)
+	}
+
+	clock.Advance(time.Hour)
+	got, err := w.StartValidation(ctx, validation.Request{Email: " USER@example.com"})
+	if err != nil {
+		t.Fatalf("StartValidation() error = %v", err)
+	}
+	if got.Status != StatusAlreadyVerified || got.ValidationID != second.ValidationID || len(sent.messages) != 2 {
+		t.Errorf("StartValidation() within window = %+v after %d messages, want %s already verified without sending",
+			got, len(sent.messages), second.ValidationID)
+	}
+
+	clock.Advance(24 * time.Hour)
+	if got, err := w.StartValidation(ctx, req); err != nil || got.Status != StatusSent {
+		t.Errorf("StartValidation() after window = %+v, %v, want sent", got, err)
+	}
+}
+
+// fakeResolver serves MX records from a map. Domains missing from it do
+// not exist, and domains mapped to nil fail to resolve.
+type fakeResolver map[string][]*net.MX
+
+func (r fakeResolver) LookupMX(_ context.Context, name string) ([]*net.MX, error) {
+	records, ok := r[name]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+	if records == nil {
+		return nil, &net.DNSError{Err: "server misbehaving", Name: name, IsTemporary: true}
+	}
+
+	return records, nil
+}
+
+func (r fakeResolver) LookupNetIP(_ context.Context, _, host string) ([]netip.Addr, error) {
+	return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
+
+func TestWorkflow_DNSChecker(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	var sent outbox
+	checker := dns.New(dns.WithResolver(fakeResolver{
+		"example.com":    {{Host: "mx.example.com.", Pref: 10}},
+		"broken.example": nil,
+	}))
+	w, _, _, _ := setup(t, &sent, WithDNSChecker(checker))
+
+	got, err := w.StartValidation(ctx, validation.Request{Email: "user@example.com"})
+	if err != nil || got.Status != StatusSent || got.HasMailServer == nil || !*got.HasMailServer {
+		t.Errorf("StartValidation() with mail server = %+v, %v, want sent with HasMailServer", got, err)
+	}
+
+	got, err = w.StartValidation(ctx, validation.Request{Email: "user@missing.example"})
+	if err != nil || got.Status != StatusNoMailServer || got.ValidationID != "" || got.HasMailServer == nil || *got.HasMailServer {
+		t.Errorf("StartValidation() without mail server = %+v, %v, want %s", got, err, StatusNoMailServer)
+	}
+
+	// A failed lookup does not block the validation.
+	got, err = w.StartValidation(ctx, validation.Request{Email: "user@broken.example"})
+	if err != nil || got.Status != StatusSent || got.HasMailServer != nil {
+		t.Errorf("StartValidation() with failing lookup = %+v, %v, want sent unchecked", got, err)
+	}
+
+	if len(sent.messages) != 2 {
+		t.Errorf("%d messages sent, want 2", len(sent.messages))
+	}
+}
+
+func TestWorkflow_Disposable(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	list := disposable.New(disposable.WithoutBaseline(), disposable.WithDomains("mailinator.com"))
+
+	var sent outbox
+	w, _, _, _ := setup(t, &sent, WithDisposableList(list))
+	got, err := w.StartValidation(ctx, validation.Request{Email: "user@mailinator.com"})
+	if err != nil || got.Status != StatusSent || !got.Disposable {
+		t.Errorf("StartValidation() flagging disposable = %+v, %v, want sent and flagged", got, err)
+	}
+	got, err = w.StartValidation(ctx, validation.Request{Email: "user@example.com"})
+	if err != nil || got.Status != StatusSent || got.Disposable {
+		t.Errorf("StartValidation() of regular address = %+v, %v, want sent unflagged", got, err)
+	}
+
+	var rejected outbox
+	w, _, _, _ = setup(t, &rejected, WithDisposableList(list), WithRejectDisposable())
+	got, err = w.StartValidation(ctx, validation.Request{Email: "user@eu.mailinator.com"})
+	if err != nil || got.Status != StatusDisposable || got.ValidationID != "" || len(rejected.messages) != 0 {
+		t.Errorf("StartValidation() rejecting disposable = %+v, %v after %d messages, want %s without sending",
+			got, err, len(rejected.messages), StatusDisposable)
+	}
+}
+
+func TestWorkflow_Suggester(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	checker := dns.New(dns.WithResolver(fakeResolver{"gmail.com": {{Host: "mx.gmail.com.", Pref: 10}}}))
+
+	var sent outbox
+	w, _, _, _ := setup(t, &sent, WithSuggester(suggest.New()), WithDNSChecker(checker))
+
+	got, err := w.StartValidation(ctx, validation.Request{Email: "user@gmail.com"})
+	if err != nil || got.Suggestion != nil {
+		t.Errorf("StartValidation() of correct address = %+v, %v, want no suggestion", got, err)
+	}
+
+	got, err = w.StartValidation(ctx, validation.Request{Email: "user@gmial.com"})
+	if err != nil || got.Status != StatusNoMailServer || got.Suggestion == nil || got.Suggestion.Address != "user@gmail.com" {
+		t.Errorf("StartValidation() of mistyped address = %+v, %v, want %s suggesting user@gmail.com",
+			got, err, StatusNoMailServer)
+	}
+}
+
+func TestWorkflow_Scorer(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	checker := dns.New(dns.WithResolver(fakeResolver{
+		"example.com":    {{Host: "mx.example.com.", Pref: 10}},
+		"mailinator.com": {{Host: "mx.mailinator.com.", Pref: 10}},
+	}))
+	list := disposable.New(disposable.WithoutBaseline(), disposable.WithDomains("mailinator.com"))
+
+	var sent outbox
+	w, _, _, _ := setup(t, &sent, WithScorer(score.New()), WithDNSChecker(checker), WithDisposableList(list))
+
+	tests := []struct {
+		email  string
+		status Status
+		want   int
+	}{
+		{"user@example.com", StatusSent, 70},
+		{"support@mailinator.com", StatusSent, 15},
+		{"user@missing.example", StatusNoMailServer, 0},
+	}
+
+	for _, tt := range tests {
+		got, err := w.StartValidation(ctx, validation.Request{Email: tt.email})
+		if err != nil || got.Status != tt.status || got.Score == nil || got.Score.Score != tt.want {
+			t.Errorf("StartValidation(%q) = %+v, %v, want %s scoring %d", tt.email, got, err, tt.status, tt.want)
+		}
+	}
+}
+
+func TestWorkflow_Policy(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	p := policy.New(policy.WithLogger(slog.New(slog.DiscardHandler)))
+	if err := p.SetRules([]policy.Rule{{Pattern: "*.zip", Action: policy.ActionBlock, Reason: "abused TLD"}}); err != nil {
+		t.Fatalf("SetRules() error = %v", err)
+	}
+
+	var sent outbox
+	w, _, _, _ := setup(t, &sent, WithPolicy(p))
+
+	got, err := w.StartValidation(ctx, validation.Request{Email: "user@files.zip"})
+	if err != nil || got.Status != StatusBlocked || got.Rejection == nil || got.Rejection.Code != policy.CodeBlocked {
+		t.Errorf("StartValidation() of blocked domain = %+v, %v, want %s", got, err, StatusBlocked)
+	}
+	if got, err := w.StartValidation(ctx, validation.Request{Email: "user@example.com"}); err != nil || got.Status != StatusSent {
+		t.Errorf("StartValidation() of allowed domain = %+v, %v, want sent", got, err)
+	}
+
+	// Rule changes apply to the next call.
+	if err := p.SetDefaultAction(policy.ActionBlock); err != nil {
+		t.Fatalf("SetDefaultAction() error = %v", err)
+	}
+	got, err = w.StartValidation(ctx, validation.Request{Email: "user@example.com"})
+	if err != nil || got.Status != StatusBlocked || got.Rejection.Code != policy.CodeNotAllowed {
+		t.Errorf("StartValidation() under allowlist = %+v, %v, want %s", got, err, policy.CodeNotAllowed)
+	}
+
+	if len(sent.messages) != 1 {
+		t.Errorf("%d messages sent, want 1", len(sent.messages))
+	}
+}
+
+func TestWorkflow_Rules(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	maxScore := 30
+	e := rules.New(rules.WithLogger(slog.New(slog.DiscardHandler)))
+	if err := e.SetRules("", []rules.Rule{
+		{Name: "risky tld", Domains: []string{"*.zip"}, Action: rules.ActionDeny},
+		{Name: "role accounts", MaxScore: &maxScore, Action: rules.ActionStepUp},
+	}); err != nil {
+		t.Fatalf("SetRules() error = %v", err)
+	}
+
+	var sent outbox
+	w, _, _, _ := setup(t, &sent, WithRules(e), WithScorer(score.New()))
+
+	tests := []struct {
+		email  string
+		status Status
+		action rules.Action
+	}{
+		{"user@files.zip", StatusDenied, rules.ActionDeny},
+		{"admin@example.com", StatusSent, rules.ActionStepUp},
+		{"user@example.com", StatusSent, rules.ActionAllow},
+	}
+	for _, tt := range tests {
+		got, err := w.StartValidation(ctx, validation.Request{Email: tt.email})
+		if err != nil || got.Status != tt.status || got.RuleDecision == nil || got.RuleDecision.Action != tt.action {
+			t.Errorf("StartValidation(%q) = %+v, %v, want %s decided %s", tt.email, got, err, tt.status, tt.action)
+			continue
+		}
+		if (tt.status == StatusDenied) != (got.ValidationID == "") {
+			t.Errorf("StartValidation(%q) validation ID = %q", tt.email, got.ValidationID)
+		}
+	}
+
+	if len(sent.messages) != 2 {
+		t.Errorf("%d messages sent, want 2", len(sent.messages))
+	}
+}
+
+func TestNewTemplateRenderer_InvalidURL(t *testing.T) {
+	t.Parallel()
+
+	for _, base := range []string{"/verify", "://bad"} {
+		if _, err := NewTemplateRenderer(base); !errors.Is(err, ErrInvalidLinkURL) {
+			t.Errorf("NewTemplateRenderer(%q) error = %v, want %v", base, err, ErrInvalidLinkURL)
+		}
+	}
+}